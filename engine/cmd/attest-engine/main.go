@@ -1,20 +1,39 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/paths"
+	"github.com/attest-ai/attest/engine/internal/report"
+	"github.com/attest-ai/attest/engine/internal/selfupdate"
 	"github.com/attest-ai/attest/engine/internal/server"
+	"github.com/attest-ai/attest/engine/pkg/conformance"
 )
 
 const version = "0.5.0"
 
+// recentLogLines is how many log lines the engine keeps for debug bundles.
+const recentLogLines = 1000
+
 func main() {
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
@@ -24,14 +43,75 @@ func main() {
 		case "cache":
 			handleCacheCommand(os.Args[2:])
 			return
+		case "debug":
+			handleDebugCommand(os.Args[2:])
+			return
+		case "calibrate":
+			handleCalibrateCommand(os.Args[2:])
+			return
+		case "models":
+			handleModelsCommand(os.Args[2:])
+			return
+		case "doctor":
+			handleDoctorCommand(os.Args[2:])
+			return
+		case "update":
+			handleUpdateCommand(os.Args[2:])
+			return
+		case "conformance":
+			handleConformanceCommand(os.Args[2:])
+			return
+		case "report":
+			handleReportCommand(os.Args[2:])
+			return
+		case "annotate":
+			handleAnnotateCommand(os.Args[2:])
+			return
+		case "publish":
+			handlePublishCommand(os.Args[2:])
+			return
 		}
 	}
 
 	// Parse flags
 	logLevel := flag.String("log-level", "info", "log level: debug, info, warn, error")
 	debug := flag.Bool("debug", false, "enable debug logging (shorthand for --log-level=debug)")
+	offline := flag.Bool("offline", false, "never download model files; fail fast when they are missing (same as ATTEST_OFFLINE=1)")
+	idleTimeout := flag.Duration("idle-timeout", 0, "exit after this long without requests, e.g. 30m (0 disables)")
+	requireVersion := flag.String("require-version", "", "exit with an error if this engine is older than the given version")
+	maxTraceSize := flag.Int("max-trace-size", 0, "largest accepted trace in bytes (same as ATTEST_MAX_TRACE_SIZE; default 10 MB, at most 64 MB)")
+	maxSteps := flag.Int("max-steps-per-trace", 0, "most steps accepted in one trace (same as ATTEST_MAX_STEPS_PER_TRACE; default 10000)")
+	maxStepPayload := flag.Int("max-step-payload", 0, "largest accepted step in bytes (same as ATTEST_MAX_STEP_PAYLOAD; default 1 MB)")
+	maxDepth := flag.Int("max-sub-trace-depth", 0, "deepest accepted agent_call nesting (same as ATTEST_MAX_SUB_TRACE_DEPTH; default 5)")
+	memoryLimit := flag.Int("memory-limit-mb", 0, "soft memory limit in MB; large batches queue as it nears (same as ATTEST_MEMORY_LIMIT_MB)")
+	pprofAddr := flag.String("pprof-addr", "", "serve net/http/pprof on this address, e.g. localhost:6060 (off by default)")
+	fuzzReplay := flag.String("fuzz-replay", "", "run one fuzz input (raw bytes or a go test -fuzz corpus file) through the engine and exit")
 	flag.Parse()
 
+	if *offline {
+		os.Setenv("ATTEST_OFFLINE", "1")
+	}
+	// Trace and memory limits are read from the environment with the rest
+	// of the engine configuration; flags override it.
+	for key, v := range map[string]int{
+		"ATTEST_MAX_TRACE_SIZE":      *maxTraceSize,
+		"ATTEST_MAX_STEPS_PER_TRACE": *maxSteps,
+		"ATTEST_MAX_STEP_PAYLOAD":    *maxStepPayload,
+		"ATTEST_MAX_SUB_TRACE_DEPTH": *maxDepth,
+		"ATTEST_MEMORY_LIMIT_MB":     *memoryLimit,
+	} {
+		if v > 0 {
+			os.Setenv(key, strconv.Itoa(v))
+		}
+	}
+	if *requireVersion != "" {
+		checkRequiredVersion(*requireVersion)
+	}
+	if *fuzzReplay != "" {
+		replayFuzzInput(*fuzzReplay)
+		return
+	}
+
 	// --debug overrides --log-level
 	if *debug {
 		*logLevel = "debug"
@@ -52,16 +132,35 @@ func main() {
 		fmt.Fprintf(os.Stderr, "invalid log level: %s\n", *logLevel)
 		os.Exit(1)
 	}
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	// Recent log lines are kept in memory for debug_dump bundles.
+	logBuffer := logging.NewRingWriter(recentLogLines)
+	logger := slog.New(slog.NewJSONHandler(io.MultiWriter(os.Stderr, logBuffer), &slog.HandlerOptions{Level: level}))
+	// Evaluators and providers log through slog.Default with the request ID attached.
+	slog.SetDefault(logger)
+
+	// Move ~/.attest directories from earlier releases to their per-OS homes.
+	if moves, err := paths.Migrate(); err != nil {
+		logger.Warn("could not migrate legacy directories; they stay in use", "err", err)
+	} else {
+		for _, m := range moves {
+			logger.Info("migrated directory", "from", m.From, "to", m.To)
+		}
+	}
 
 	// Create server
 	srv := server.New(os.Stdin, os.Stdout, logger)
+	srv.SetLogBuffer(logBuffer)
+	srv.SetIdleTimeout(*idleTimeout)
 	server.RegisterBuiltinHandlers(srv)
 
 	// Handle signals
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	if *pprofAddr != "" {
+		servePprof(*pprofAddr, logger)
+	}
+
 	logger.Info("engine starting", "version", version)
 	if err := srv.Run(ctx); err != nil {
 		logger.Error("engine error", "err", err)
@@ -70,25 +169,45 @@ func main() {
 	logger.Info("engine shutdown complete")
 }
 
-// cacheDir returns the cache directory from ATTEST_CACHE_DIR env or ~/.attest/cache/.
-func cacheDir() string {
-	if dir := os.Getenv("ATTEST_CACHE_DIR"); dir != "" {
-		return dir
-	}
-	home, err := os.UserHomeDir()
+// servePprof serves the net/http/pprof handlers on addr in the background,
+// for profiling a running engine with `go tool pprof`. stdout stays the
+// protocol channel, so only the log reports where it listens.
+func servePprof(addr string, logger *slog.Logger) {
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "cannot determine home directory: %v\n", err)
-		os.Exit(1)
+		logger.Error("pprof listener failed", "addr", addr, "err", err)
+		return
 	}
-	return filepath.Join(home, ".attest", "cache")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	logger.Info("pprof listening", "addr", ln.Addr().String())
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			logger.Warn("pprof server stopped", "err", err)
+		}
+	}()
+}
+
+// cacheDir returns the cache directory from ATTEST_CACHE_DIR env or the
+// per-OS default.
+func cacheDir() string {
+	return paths.CacheDir()
 }
 
-// handleCacheCommand handles: attest-engine cache stats | cache clear
+// handleCacheCommand handles: attest-engine cache stats | cache clear [embeddings|judge] | cache warm
 func handleCacheCommand(args []string) {
 	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "usage: attest-engine cache <stats|clear>")
+		fmt.Fprintln(os.Stderr, "usage: attest-engine cache <stats|clear [embeddings|judge]|warm>")
 		os.Exit(1)
 	}
+	if args[0] == "warm" {
+		handleCacheWarm(args[1:])
+		return
+	}
 
 	dir := cacheDir()
 
@@ -129,7 +248,25 @@ func handleCacheCommand(args []string) {
 		fmt.Printf("files:     %d\n", fileCount)
 		fmt.Printf("size_bytes: %d\n", totalBytes)
 
+		usages, err := server.CacheUsages()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read cache stats: %v\n", err)
+			os.Exit(1)
+		}
+		for _, u := range usages {
+			fmt.Printf("%s: %d entries, %d bytes (limit %d bytes)\n", u.Name, u.Entries, u.Bytes, u.MaxBytes)
+		}
+
 	case "clear":
+		if len(args) > 1 {
+			n, err := server.ClearCache(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "clear %s cache: %v\n", args[1], err)
+				os.Exit(1)
+			}
+			fmt.Printf("cleared %d %s cache entries\n", n, args[1])
+			return
+		}
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
 			fmt.Println("cache directory does not exist:", dir)
 			return
@@ -158,3 +295,557 @@ func handleCacheCommand(args []string) {
 		os.Exit(1)
 	}
 }
+
+// handleCacheWarm handles: attest-engine cache warm --assertions suite.json [--traces dir/]
+func handleCacheWarm(args []string) {
+	fs := flag.NewFlagSet("cache warm", flag.ExitOnError)
+	suite := fs.String("assertions", "", "assertion suite file (JSON, or YAML for .yaml/.yml: an array or {\"assertions\": [...]})")
+	traces := fs.String("traces", "", "directory of *.json / *.jsonl traces whose targets are embedded and judged")
+	concurrency := fs.Int("concurrency", 4, "number of concurrent provider calls")
+	_ = fs.Parse(args)
+	if *suite == "" {
+		fmt.Fprintln(os.Stderr, "usage: attest-engine cache warm --assertions suite.json [--traces dir/] [--concurrency N]")
+		os.Exit(1)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	report, err := server.WarmCache(logger, *suite, *traces, *concurrency)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cache warm: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("embedded:          %d\n", report.Embedded)
+	fmt.Printf("embeddings_cached: %d\n", report.EmbeddingsCached)
+	fmt.Printf("judged:            %d\n", report.Judged)
+	fmt.Printf("judge_cached:      %d\n", report.JudgeCached)
+	fmt.Printf("skipped:           %d\n", report.Skipped)
+	fmt.Printf("duplicates:        %d\n", report.Duplicates)
+	for _, e := range report.Errors {
+		fmt.Fprintf(os.Stderr, "error: %s\n", e)
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+// handleCalibrateCommand handles: attest-engine calibrate --rubric name --dataset labeled.jsonl
+func handleCalibrateCommand(args []string) {
+	fs := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	rubric := fs.String("rubric", "default", "rubric to calibrate")
+	dataset := fs.String("dataset", "", "labeled examples: JSONL or JSON array of {\"target\", \"score\", \"criteria\"}")
+	concurrency := fs.Int("concurrency", 4, "number of concurrent judge calls")
+	dryRun := fs.Bool("dry-run", false, "report agreement and the fitted curve without saving it")
+	_ = fs.Parse(args)
+	if *dataset == "" {
+		fmt.Fprintln(os.Stderr, "usage: attest-engine calibrate --dataset labeled.jsonl [--rubric name] [--concurrency N] [--dry-run]")
+		os.Exit(1)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	report, err := server.Calibrate(logger, *rubric, *dataset, *concurrency, *dryRun)
+	if report != nil {
+		for _, e := range report.Errors {
+			fmt.Fprintf(os.Stderr, "error: %s\n", e)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "calibrate: %v\n", err)
+		os.Exit(1)
+	}
+
+	c := report.Calibration
+	fmt.Printf("rubric:         %s\n", c.Rubric)
+	fmt.Printf("model:          %s\n", c.Model)
+	fmt.Printf("examples:       %d\n", c.Examples)
+	fmt.Printf("pearson:        %.3f\n", c.Pearson)
+	fmt.Printf("mae:            %.3f\n", c.MAE)
+	fmt.Printf("calibrated_mae: %.3f\n", c.CalibratedMAE)
+	fmt.Printf("cost_usd:       %.4f\n", report.Cost)
+	fmt.Println("curve:")
+	for _, p := range c.Points {
+		fmt.Printf("  %.3f -> %.3f\n", p.Raw, p.Calibrated)
+	}
+	if report.Saved {
+		fmt.Println("saved; future judge scores for this rubric and model are calibrated")
+	} else {
+		fmt.Println("dry run; nothing saved")
+	}
+}
+
+// handleModelsCommand handles: attest-engine models download | models verify
+func handleModelsCommand(args []string) {
+	if len(args) == 0 || (args[0] != "download" && args[0] != "verify") {
+		fmt.Fprintln(os.Stderr, "usage: attest-engine models <download|verify>")
+		os.Exit(1)
+	}
+
+	var statuses []embedding.ModelStatus
+	var err error
+	if args[0] == "download" {
+		statuses, err = server.DownloadModels(context.Background())
+	} else {
+		statuses, err = server.VerifyModels()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "models %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	healthy := true
+	for _, st := range statuses {
+		fmt.Printf("%-10s %-8s %s\n", st.Name, st.Status, st.Path)
+		if st.SHA256 != "" {
+			fmt.Printf("           sha256 %s (%d bytes)\n", st.SHA256, st.Bytes)
+		}
+		switch st.Status {
+		case "mismatch":
+			fmt.Printf("           pinned %s\n", st.Pinned)
+			healthy = false
+		case "missing":
+			healthy = false
+		}
+	}
+	if !healthy {
+		fmt.Fprintln(os.Stderr, "model files are missing or do not match their pinned checksums; run `attest-engine models download` to replace them")
+		os.Exit(1)
+	}
+}
+
+// handleDoctorCommand handles: attest-engine doctor [--offline]
+func handleDoctorCommand(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	offline := fs.Bool("offline", false, "check as if ATTEST_OFFLINE=1")
+	_ = fs.Parse(args)
+	if *offline {
+		os.Setenv("ATTEST_OFFLINE", "1")
+	}
+
+	failed := false
+	for _, c := range server.Doctor(context.Background()) {
+		fmt.Printf("[%-4s] %-24s %s\n", c.Status, c.Name, c.Detail)
+		if c.Remedy != "" {
+			fmt.Printf("       %-24s fix: %s\n", "", c.Remedy)
+		}
+		if c.Status == server.DoctorFail {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// handleReportCommand handles:
+// attest-engine report --from history [--window 7d] [--namespace NAME] [--format json|markdown] [--output FILE]
+func handleReportCommand(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	from := fs.String("from", "history", "where results come from; only \"history\" (the assertion history in attest.db) is supported")
+	window := fs.String("window", "7d", "how far back to aggregate, e.g. 7d, 24h; trends compare against the window before it")
+	format := fs.String("format", "markdown", "output format: json or markdown")
+	output := fs.String("output", "", "write the report to this file instead of stdout")
+	namespace := fs.String("namespace", "", "namespace whose history is reported, as given to initialize (default the default namespace)")
+	_ = fs.Parse(args)
+
+	usage := "usage: attest-engine report --from history [--window 7d] [--namespace NAME] [--format json|markdown] [--output FILE]"
+	if *from != "history" || (*format != "json" && *format != "markdown") {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	d, err := parseWindow(*window)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		os.Exit(2)
+	}
+
+	sc, err := server.HistoryScorecard(d, *window, *namespace, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		os.Exit(1)
+	}
+	var buf strings.Builder
+	if *format == "json" {
+		data, err := report.GenerateScorecardJSON(sc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "report: %v\n", err)
+			os.Exit(1)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	} else if err := report.GenerateScorecardMarkdown(&buf, sc); err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		fmt.Print(buf.String())
+		return
+	}
+	if err := os.WriteFile(*output, []byte(buf.String()), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// handleAnnotateCommand handles:
+// attest-engine annotate --format github|gitlab [--input results.json] [--file PATH]
+func handleAnnotateCommand(args []string) {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+	format := fs.String("format", "github", "github (workflow commands) or gitlab (code quality JSON)")
+	input := fs.String("input", "-", "JSON report, JSON array of results, or evaluate_dataset sink file; - reads stdin")
+	file := fs.String("file", "", "file to annotate, e.g. the dataset whose sink is the input; sink results land on their trace's line")
+	_ = fs.Parse(args)
+	if *format != "github" && *format != "gitlab" {
+		fmt.Fprintln(os.Stderr, "usage: attest-engine annotate --format github|gitlab [--input results.json] [--file PATH]")
+		os.Exit(2)
+	}
+
+	data, err := readInput(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "annotate: %v\n", err)
+		os.Exit(1)
+	}
+	results, err := report.LoadAnnotatedResults(data, *file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "annotate: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *format == "github" {
+		err = report.GenerateGitHubAnnotations(os.Stdout, results)
+	} else {
+		defaultPath := *file
+		if defaultPath == "" && *input != "-" {
+			defaultPath = *input
+		}
+		var out []byte
+		if out, err = report.GenerateGitLabCodeQuality(results, defaultPath); err == nil {
+			_, err = fmt.Printf("%s\n", out)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "annotate: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// handlePublishCommand handles:
+// attest-engine publish [--input results.json] [--format slack|generic] [--webhook URL] [--baseline history|FILE] [--link URL] [--template FILE] [--dry-run]
+func handlePublishCommand(args []string) {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	input := fs.String("input", "-", "JSON report, JSON array of results, or evaluate_dataset sink file; - reads stdin")
+	format := fs.String("format", server.WebhookSlack, "payload format: slack ({\"text\": ...}) or generic (the summary as JSON)")
+	webhook := fs.String("webhook", "", "webhook URL to post to (default $ATTEST_WEBHOOK_URL)")
+	baseline := fs.String("baseline", "", "compare pass rates against \"history\" (the assertion history in attest.db) or an earlier results file")
+	window := fs.String("window", "7d", "how far back the history baseline reaches, e.g. 7d, 24h")
+	namespace := fs.String("namespace", "", "namespace whose history is the baseline, as given to initialize")
+	title := fs.String("title", "", "summary title (default \"Attest evaluation run\")")
+	link := fs.String("link", "", "link for each top failure; {trace_id} and {assertion_id} are replaced")
+	maxFailures := fs.Int("max-failures", 5, "how many failures to list")
+	tmplFile := fs.String("template", "", "text/template file rendering the summary (the Slack text, or the whole generic body)")
+	dryRun := fs.Bool("dry-run", false, "print the payload instead of posting it")
+	_ = fs.Parse(args)
+
+	usage := "usage: attest-engine publish [--input results.json] [--format slack|generic] [--webhook URL] [--baseline history|FILE] [--link URL] [--template FILE] [--dry-run]"
+	url := *webhook
+	if url == "" {
+		url = os.Getenv("ATTEST_WEBHOOK_URL")
+	}
+	if (*format != server.WebhookSlack && *format != server.WebhookGeneric) || (url == "" && !*dryRun) {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	data, err := readInput(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+		os.Exit(1)
+	}
+	results, err := report.LoadAnnotatedResults(data, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := report.SummaryOptions{Title: *title, LinkTemplate: *link, MaxFailures: *maxFailures}
+	switch *baseline {
+	case "":
+	case "history":
+		d, err := parseWindow(*window)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+			os.Exit(2)
+		}
+		sc, err := server.HistoryScorecard(d, *window, *namespace, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Baseline = report.BaselineFromScorecard(sc)
+	default:
+		data, err := os.ReadFile(*baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+			os.Exit(1)
+		}
+		prev, err := report.LoadAnnotatedResults(data, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: baseline: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Baseline = report.BaselineFromResults(prev)
+	}
+
+	var tmpl string
+	if *tmplFile != "" {
+		b, err := os.ReadFile(*tmplFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+			os.Exit(1)
+		}
+		tmpl = string(b)
+	}
+	payload, err := server.RunSummaryPayload(report.BuildRunSummary(results, opts), *format, tmpl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "publish: %v\n", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Printf("%s\n", payload)
+		return
+	}
+	if err := server.PublishRunSummary(context.Background(), nil, url, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// readInput reads a file, or stdin when name is "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// parseWindow parses a duration that may also be given in days, e.g. "7d".
+func parseWindow(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q: want a positive duration such as 7d or 24h", s)
+	}
+	return d, nil
+}
+
+// handleConformanceCommand handles:
+// attest-engine conformance --sdk-cmd=CMD [--scenario NAME,...] [--timeout D] [--json] [--list]
+func handleConformanceCommand(args []string) {
+	fs := flag.NewFlagSet("conformance", flag.ExitOnError)
+	sdkCmd := fs.String("sdk-cmd", "", "SDK driver command to run per scenario, split on spaces")
+	scenario := fs.String("scenario", "", "comma-separated scenarios to run (default all)")
+	timeout := fs.Duration("timeout", 10*time.Second, "how long to wait for the driver at each step")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	list := fs.Bool("list", false, "list the scenarios and exit")
+	_ = fs.Parse(args)
+
+	if *list {
+		for _, sc := range conformance.Scenarios() {
+			fmt.Printf("%-14s %s\n", sc.Name, sc.Description)
+		}
+		return
+	}
+	if *sdkCmd == "" {
+		fmt.Fprintln(os.Stderr, "Usage: attest-engine conformance --sdk-cmd=CMD [--scenario NAME,...] [--timeout D] [--json] [--list]")
+		os.Exit(2)
+	}
+	opts := conformance.Options{
+		Command: strings.Fields(*sdkCmd),
+		Timeout: *timeout,
+		Stderr:  os.Stderr,
+	}
+	if *scenario != "" {
+		opts.Scenarios = strings.Split(*scenario, ",")
+	}
+	report, err := conformance.Run(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conformance: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		for _, res := range report.Results {
+			status := "PASS"
+			if !res.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("[%s] %-14s %dms\n", status, res.Scenario, res.DurationMS)
+			for _, f := range res.Failures {
+				fmt.Printf("       %s\n", f)
+			}
+		}
+	}
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
+
+// replayFuzzInput reproduces a fuzz crasher: it prints the engine's response
+// to the input in path, or the panic and its stack, exiting 1 on failure.
+func replayFuzzInput(path string) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--fuzz-replay: %v\n", err)
+		os.Exit(1)
+	}
+	// Replays must not touch the cache on disk or download models.
+	if os.Getenv("ATTEST_CACHE_MODE") == "" {
+		os.Setenv("ATTEST_CACHE_MODE", "memory")
+	}
+	os.Setenv("ATTEST_OFFLINE", "1")
+	out, err := server.FuzzReplay(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--fuzz-replay: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(out))
+}
+
+// checkRequiredVersion exits when this engine is older than required, so an
+// SDK can refuse to run against a stale sidecar before the handshake.
+func checkRequiredVersion(required string) {
+	cmp, err := selfupdate.CompareVersions(version, required)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--require-version: %v\n", err)
+		os.Exit(1)
+	}
+	if cmp < 0 {
+		fmt.Fprintf(os.Stderr, "attest-engine %s is older than the required %s; run `attest-engine update`\n", version, required)
+		os.Exit(1)
+	}
+}
+
+// handleUpdateCommand handles: attest-engine update [--check] [--version X]
+// [--allow-downgrade] [--force]
+func handleUpdateCommand(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	check := fs.Bool("check", false, "report whether an update is available without installing it")
+	target := fs.String("version", "", "install this version instead of the latest release")
+	allowDowngrade := fs.Bool("allow-downgrade", false, "allow --version to install a version older than this one")
+	force := fs.Bool("force", false, "reinstall even if this version is already current")
+	_ = fs.Parse(args)
+
+	u, err := selfupdate.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "update: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	want := strings.TrimPrefix(*target, "v")
+	if want == "" {
+		if want, err = u.Latest(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "update: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// The latest release is read from an unsigned redirect, so it never
+	// downgrades; an older --version needs --allow-downgrade.
+	cmp, err := selfupdate.CheckTarget(version, want, *check || *target == "" || *allowDowngrade)
+	if err != nil {
+		if errors.Is(err, selfupdate.ErrDowngrade) {
+			err = fmt.Errorf("%w; pass --allow-downgrade to install it", err)
+		}
+		fmt.Fprintf(os.Stderr, "update: %v\n", err)
+		os.Exit(1)
+	}
+	if *check {
+		if cmp < 0 {
+			fmt.Printf("attest-engine %s is available (installed: %s)\n", want, version)
+		} else {
+			fmt.Printf("attest-engine %s is up to date (latest: %s)\n", version, want)
+		}
+		return
+	}
+	if (cmp > 0 && *target == "") || (cmp == 0 && !*force) {
+		fmt.Printf("attest-engine %s is up to date\n", version)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "update: locate executable: %v\n", err)
+		os.Exit(1)
+	}
+	binary, err := u.Download(ctx, want)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "update: %v\n", err)
+		os.Exit(1)
+	}
+	if err := selfupdate.Replace(exe, binary); err != nil {
+		fmt.Fprintf(os.Stderr, "update: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("updated %s from %s to %s\n", exe, version, want)
+}
+
+// handleDebugCommand handles: attest-engine debug dump --out bundle.tar.gz [...]
+func handleDebugCommand(args []string) {
+	if len(args) == 0 || args[0] != "dump" {
+		fmt.Fprintln(os.Stderr, "usage: attest-engine debug dump --out bundle.tar.gz [--assertions suite.json] [--traces dir/] [--log-file engine.log] [--yes]")
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("debug dump", flag.ExitOnError)
+	out := fs.String("out", "", "path of the tar.gz bundle to write")
+	suite := fs.String("assertions", "", "assertion suite file to include")
+	traces := fs.String("traces", "", "directory of *.json / *.jsonl traces to include (redacted)")
+	maxTraces := fs.Int("max-traces", 10, "maximum number of traces to include")
+	logFile := fs.String("log-file", "", "engine log file whose tail is included")
+	logLines := fs.Int("log-lines", recentLogLines, "number of log lines to include from --log-file")
+	yes := fs.Bool("yes", false, "write the bundle without asking for confirmation")
+	_ = fs.Parse(args[1:])
+	if *out == "" {
+		fmt.Fprintln(os.Stderr, "debug dump: --out is required")
+		os.Exit(1)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	bundle, err := server.BuildDebugBundle(logger, version, *suite, *traces, *logFile, *maxTraces, *logLines)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "debug dump: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("The debug bundle will contain:")
+	for _, e := range bundle.Manifest() {
+		fmt.Printf("  %-40s %8d bytes  %s\n", e.Name, e.Bytes, e.Description)
+	}
+	if !*yes {
+		fmt.Printf("Write these files to %s? [y/N] ", *out)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("aborted; nothing written")
+			os.Exit(1)
+		}
+	}
+	if err := bundle.WriteFile(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "debug dump: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("wrote", *out)
+}
@@ -0,0 +1,154 @@
+package assertion
+
+import (
+	"bytes"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/deprecation"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// specAlias is a deprecated name in an assertion type's spec and the name
+// that replaced it. With check set, old and new are values of spec.check;
+// otherwise they are spec field names.
+type specAlias struct {
+	assertionType string
+	check         bool
+	old, new      string
+}
+
+// specAliases lists renamed checks and spec fields. Suites using an old name
+// keep working, with a deprecated_alias notice, until deprecation.AliasSunset.
+var specAliases = []specAlias{
+	// Hand-written suites that put all_tools_called under trace. The SDKs'
+	// all_tools_called() sends the tree-wide trace_tree check instead.
+	{assertionType: types.TypeTrace, check: true, old: "all_tools_called", new: "required_tools"},
+}
+
+// aliasesByType indexes specAliases by assertion type.
+var aliasesByType = func() map[string][]specAlias {
+	m := make(map[string][]specAlias)
+	for _, a := range specAliases {
+		m[a.assertionType] = append(m[a.assertionType], a)
+	}
+	return m
+}()
+
+// Aliases returns the deprecated check names of assertionType, mapped to
+// their replacements, or nil when it has none.
+func Aliases(assertionType string) map[string]string {
+	var m map[string]string
+	for _, a := range aliasesByType[assertionType] {
+		if a.check {
+			if m == nil {
+				m = make(map[string]string)
+			}
+			m[a.old] = a.new
+		}
+	}
+	return m
+}
+
+// resolveAliases rewrites deprecated names in a's spec, and in the specs of
+// composite children, to their replacements. It returns a notice per name
+// rewritten. a.Spec is replaced, never modified in place, so a caller's copy
+// of the spec is left as it was.
+func resolveAliases(a *types.Assertion) []types.Deprecation {
+	spec, notices := rewriteSpec(a.Type, a.Spec)
+	a.Spec = spec
+	return notices
+}
+
+func rewriteSpec(assertionType string, spec json.RawMessage) (json.RawMessage, []types.Deprecation) {
+	if assertionType == types.TypeComposite {
+		return rewriteComposite(spec)
+	}
+	aliases := aliasesByType[assertionType]
+	if !mentionsAlias(spec, aliases) {
+		return spec, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(spec, &fields); err != nil {
+		// Invalid specs are reported by the evaluator.
+		return spec, nil
+	}
+	var notices []types.Deprecation
+	for _, a := range aliases {
+		if a.check {
+			var check string
+			if json.Unmarshal(fields["check"], &check) != nil || check != a.old {
+				continue
+			}
+			fields["check"], _ = json.Marshal(a.new)
+			notices = append(notices, deprecation.AliasNotice(assertionType, "check", a.old, a.new))
+			continue
+		}
+		v, ok := fields[a.old]
+		if !ok {
+			continue
+		}
+		// The new name wins when a spec sets both.
+		if _, ok := fields[a.new]; !ok {
+			fields[a.new] = v
+		}
+		delete(fields, a.old)
+		notices = append(notices, deprecation.AliasNotice(assertionType, "field", a.old, a.new))
+	}
+	if len(notices) == 0 {
+		return spec, nil
+	}
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return spec, nil
+	}
+	return rewritten, notices
+}
+
+// rewriteComposite resolves aliases in each child of a composite spec.
+func rewriteComposite(spec json.RawMessage) (json.RawMessage, []types.Deprecation) {
+	if !mentionsAlias(spec, specAliases) {
+		return spec, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(spec, &fields); err != nil {
+		return spec, nil
+	}
+	var children []map[string]json.RawMessage
+	if err := json.Unmarshal(fields["assertions"], &children); err != nil {
+		return spec, nil
+	}
+	var notices []types.Deprecation
+	for _, child := range children {
+		var childType string
+		if json.Unmarshal(child["type"], &childType) != nil {
+			continue
+		}
+		childSpec, childNotices := rewriteSpec(childType, child["spec"])
+		if len(childNotices) > 0 {
+			child["spec"] = childSpec
+			notices = append(notices, childNotices...)
+		}
+	}
+	if len(notices) == 0 {
+		return spec, nil
+	}
+	fields["assertions"], _ = json.Marshal(children)
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return spec, nil
+	}
+	return rewritten, notices
+}
+
+// mentionsAlias reports whether spec contains any old name in aliases, so
+// the specs that use none, nearly all of them, are not decoded.
+func mentionsAlias(spec json.RawMessage, aliases []specAlias) bool {
+	for _, a := range aliases {
+		if bytes.Contains(spec, []byte(a.old)) {
+			return true
+		}
+	}
+	return false
+}
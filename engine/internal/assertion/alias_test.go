@@ -0,0 +1,66 @@
+package assertion
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/deprecation"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestPipeline_ResolvesDeprecatedCheck(t *testing.T) {
+	spec := json.RawMessage(`{"check":"all_tools_called","tools":["generate"]}`)
+	assertions := []types.Assertion{
+		{AssertionID: "old", Type: types.TypeTrace, Spec: spec},
+		{AssertionID: "nested", Type: types.TypeComposite, Spec: json.RawMessage(`{"operator":"all_of","assertions":[
+			{"type":"trace","spec":{"check":"all_tools_called","tools":["missing"]}}]}`)},
+	}
+	ctx, notices := deprecation.With(context.Background())
+
+	result, err := NewPipeline(NewRegistry()).EvaluateBatchWithOptions(testTrace(), assertions, BatchOptions{Context: ctx})
+	if err != nil {
+		t.Fatalf("EvaluateBatchWithOptions: %v", err)
+	}
+	if r := result.Results[0]; r.Status != types.StatusPass {
+		t.Errorf("old: status = %s, explanation = %q", r.Status, r.Explanation)
+	}
+	if r := result.Results[1]; r.Status != types.StatusHardFail || strings.Contains(r.Explanation, "unsupported") {
+		t.Errorf("nested: status = %s, explanation = %q; want a required_tools failure", r.Status, r.Explanation)
+	}
+	if string(assertions[0].Spec) != string(spec) {
+		t.Errorf("caller's spec rewritten: %s", assertions[0].Spec)
+	}
+
+	list := notices.List()
+	if len(list) != 1 || list[0].Code != deprecation.Alias || list[0].Message != `trace check "all_tools_called" is deprecated; use "required_tools"` {
+		t.Errorf("notices = %+v, want one deprecated_alias notice", list)
+	}
+}
+
+func TestRewriteSpec_Field(t *testing.T) {
+	saved := aliasesByType
+	t.Cleanup(func() { aliasesByType = saved })
+	aliasesByType = map[string][]specAlias{
+		types.TypeContent: {{assertionType: types.TypeContent, old: "needle", new: "value"}},
+	}
+
+	tests := []struct {
+		name, spec, want string
+		notices          int
+	}{
+		{"renamed", `{"check":"contains","needle":"x"}`, `{"check":"contains","value":"x"}`, 1},
+		{"new name wins", `{"needle":"x","value":"y"}`, `{"value":"y"}`, 1},
+		{"unused", `{"check":"contains","value":"x"}`, `{"check":"contains","value":"x"}`, 0},
+		{"value not field", `{"check":"contains","value":"needle"}`, `{"check":"contains","value":"needle"}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notices := rewriteSpec(types.TypeContent, json.RawMessage(tt.spec))
+			if string(got) != tt.want || len(notices) != tt.notices {
+				t.Errorf("rewriteSpec = %s with %d notices, want %s with %d", got, len(notices), tt.want, tt.notices)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package assertion
+
+import (
+	"context"
+	"sync"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// activeContexts maps a *types.Trace under evaluation to its batch context,
+// which carries the request ID and timing recorder to evaluators and the
+// providers they call.
+var activeContexts sync.Map
+
+// bindContext registers ctx for trace for the duration of a batch and returns
+// the function that releases it. Nested or concurrent batches on the same
+// trace use the first registration.
+func bindContext(trace *types.Trace, ctx context.Context) (release func()) {
+	if _, loaded := activeContexts.LoadOrStore(trace, ctx); loaded {
+		return func() {}
+	}
+	return func() { activeContexts.Delete(trace) }
+}
+
+// batchContext returns the context registered for trace, or
+// context.Background() outside a batch.
+func batchContext(trace *types.Trace) context.Context {
+	if v, ok := activeContexts.Load(trace); ok {
+		return v.(context.Context)
+	}
+	return context.Background()
+}
@@ -0,0 +1,137 @@
+package assertion
+
+import (
+	"sort"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/codecheck"
+	"github.com/attest-ai/attest/engine/internal/assertion/sqlcheck"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// The check names each evaluator's switch accepts. A check added to an
+// evaluator must be added here too, or describe_capabilities will not report
+// it; TestCatalog_ChecksAreSupported catches names listed but not handled,
+// and TestCatalog_ListsHandledChecks names handled but not listed.
+var (
+	traceChecks = []string{
+		"contains_in_order", "exact_order", "loop_detection", "no_duplicates",
+		"required_tools", "forbidden_tools", "max_retries_per_tool",
+		"no_failed_then_abandoned_tool", "no_failed_steps", "error_handled",
+		"system_prompt_unchanged", "no_user_impersonation",
+		"context_window_under", "token_usage_reconciled",
+	}
+	traceTreeChecks = []string{
+		"agent_called", "delegation_depth", "agent_output_contains",
+		"cross_agent_data_flow", "aggregate_cost", "aggregate_tokens",
+		"follows_transitions", "aggregate_latency", "agent_ordered_before",
+		"agents_overlap", "agent_wall_time_under", "ordered_agents",
+		"all_tools_called",
+	}
+	contentChecks = []string{
+		"contains", "not_contains", "regex_match", "keyword_all", "keyword_any",
+		"forbidden", "numeric_match", "date_match", "urls_valid",
+	}
+	transcriptChecks = []string{TranscriptResolvedWithinTurns, TranscriptNoRepetition}
+	// dateRelations are the values of a date_match check's relation.
+	dateRelations = []string{DateEquals, DateBefore, DateAfter, DateWithin, DateFuture, DatePast}
+)
+
+// typeCatalog is the static part of an AssertionTypeInfo.
+type typeCatalog struct {
+	checks    []string
+	operators []string
+	options   func() map[string][]string
+}
+
+var catalog = map[string]typeCatalog{
+	types.TypeConstraint: {
+		operators: []string{"lt", "lte", "gt", "gte", "eq", "between", "str_eq", "str_ne", "bool_eq", "bool_ne", "exists", "not_exists"},
+		options: func() map[string][]string {
+			return map[string][]string{"aggregate": {"sum", "avg", "min", "max"}}
+		},
+	},
+	types.TypeTrace: {checks: traceChecks},
+	types.TypeTraceTree: {
+		checks:    traceTreeChecks,
+		operators: []string{"lte", "gte", "eq", "lt", "gt"},
+	},
+	types.TypeTemporal: {operators: []string{"always", "eventually", "never", "until"}},
+	types.TypeContent: {
+		checks: contentChecks,
+		options: func() map[string][]string {
+			return map[string][]string{"relation": dateRelations}
+		},
+	},
+	types.TypeTranscript: {checks: transcriptChecks},
+	types.TypeComposite:  {operators: []string{CompositeAllOf, CompositeAnyOf, CompositeNoneOf}},
+	types.TypeConsistency: {
+		operators: comparisonOps,
+		options: func() map[string][]string {
+			funcs := make([]string, 0, len(numFuncArity))
+			for name := range numFuncArity {
+				funcs = append(funcs, name)
+			}
+			sort.Strings(funcs)
+			return map[string][]string{"functions": funcs}
+		},
+	},
+	types.TypeCodeValid: {
+		options: func() map[string][]string {
+			return map[string][]string{"language": codecheck.Languages()}
+		},
+	},
+	types.TypeReferenceMatch: {
+		options: func() map[string][]string {
+			metrics := make([]string, 0, len(referenceMetrics))
+			for name := range referenceMetrics {
+				metrics = append(metrics, name)
+			}
+			sort.Strings(metrics)
+			return map[string][]string{"metric": metrics}
+		},
+	},
+	types.TypeSQLSafe: {
+		options: func() map[string][]string {
+			groups := make([]string, 0, len(sqlcheck.KindGroups))
+			for name := range sqlcheck.KindGroups {
+				groups = append(groups, name)
+			}
+			sort.Strings(groups)
+			return map[string][]string{"allow": groups}
+		},
+	},
+}
+
+// Describe lists every built-in assertion type in layer order, then by name,
+// with the checks, operators, and option values it accepts. Available reports
+// whether r has an evaluator for the type.
+func (r *Registry) Describe() []types.AssertionTypeInfo {
+	names := make([]string, 0, len(layerOrder))
+	for name := range layerOrder {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if layerOrder[names[i]] != layerOrder[names[j]] {
+			return layerOrder[names[i]] < layerOrder[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	infos := make([]types.AssertionTypeInfo, 0, len(names))
+	for _, name := range names {
+		c := catalog[name]
+		info := types.AssertionTypeInfo{
+			Type:      name,
+			Layer:     layerOrder[name],
+			Available: r.HasEvaluator(name),
+			Checks:    c.checks,
+			Aliases:   Aliases(name),
+			Operators: c.operators,
+		}
+		if c.options != nil {
+			info.Options = c.options()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
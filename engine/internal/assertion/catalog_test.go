@@ -0,0 +1,170 @@
+package assertion
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestCatalog_ChecksAreSupported(t *testing.T) {
+	r := NewRegistry()
+	for _, info := range r.Describe() {
+		eval, err := r.Get(info.Type)
+		if err != nil {
+			continue
+		}
+		for _, check := range info.Checks {
+			spec, _ := json.Marshal(map[string]any{"check": check, "target": "output", "value": "x"})
+			result := eval.Evaluate(testTrace(), &types.Assertion{AssertionID: "a", Type: info.Type, Spec: spec})
+			if e := strings.ToLower(result.Explanation); strings.Contains(e, "unsupported") || strings.Contains(e, "unknown content check") {
+				t.Errorf("%s check %q is listed but not handled: %s", info.Type, check, result.Explanation)
+			}
+		}
+	}
+}
+
+// switchCases parses the package's non-test sources and returns, per file,
+// the string cases of every switch on an expression ending in tag (a field
+// such as spec.Check, or a variable). Cases that are package constants are
+// resolved to their values.
+func switchCases(t *testing.T, tag string) map[string][]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := pkgs["assertion"]
+
+	consts := map[string]string{}
+	for _, f := range pkg.Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						continue
+					}
+					if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						consts[name.Name], _ = strconv.Unquote(lit.Value)
+					}
+				}
+			}
+		}
+	}
+
+	cases := map[string][]string{}
+	for path, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			sw, ok := n.(*ast.SwitchStmt)
+			if !ok {
+				return true
+			}
+			switch x := sw.Tag.(type) {
+			case *ast.SelectorExpr:
+				ok = x.Sel.Name == tag
+			case *ast.Ident:
+				ok = x.Name == tag
+			default:
+				ok = false
+			}
+			if !ok {
+				return true
+			}
+			for _, stmt := range sw.Body.List {
+				for _, e := range stmt.(*ast.CaseClause).List {
+					switch v := e.(type) {
+					case *ast.BasicLit:
+						s, _ := strconv.Unquote(v.Value)
+						cases[path] = append(cases[path], s)
+					case *ast.Ident:
+						cases[path] = append(cases[path], consts[v.Name])
+					}
+				}
+			}
+			return true
+		})
+	}
+	return cases
+}
+
+func TestCatalog_ListsHandledChecks(t *testing.T) {
+	// The file holding each evaluator's check switch.
+	listed := map[string][]string{
+		"content.go":         contentChecks,
+		"trace_check.go":     traceChecks,
+		"trace_tree_eval.go": traceTreeChecks,
+		"transcript_eval.go": transcriptChecks,
+	}
+	for path, checks := range switchCases(t, "Check") {
+		want, ok := listed[path]
+		if !ok {
+			t.Errorf("%s switches on a check but has no catalog list", path)
+			continue
+		}
+		for _, check := range checks {
+			// An empty check is a missing field, not a check name.
+			if check != "" && !slices.Contains(want, check) {
+				t.Errorf("%s handles check %q, which the catalog does not list", path, check)
+			}
+		}
+	}
+	for path, relations := range switchCases(t, "relation") {
+		for _, rel := range relations {
+			if !slices.Contains(dateRelations, rel) {
+				t.Errorf("%s handles date_match relation %q, which the catalog does not list", path, rel)
+			}
+		}
+	}
+}
+
+func TestRegistry_Describe(t *testing.T) {
+	infos := NewRegistry().Describe()
+	if len(infos) != len(layerOrder) {
+		t.Fatalf("Describe returned %d types, want %d", len(infos), len(layerOrder))
+	}
+	byType := make(map[string]types.AssertionTypeInfo, len(infos))
+	for i, info := range infos {
+		if i > 0 && info.Layer < infos[i-1].Layer {
+			t.Errorf("%s (layer %d) listed after layer %d", info.Type, info.Layer, infos[i-1].Layer)
+		}
+		byType[info.Type] = info
+	}
+
+	if !byType[types.TypeContent].Available || byType[types.TypeEmbedding].Available {
+		t.Errorf("available: content = %v, embedding = %v; want true, false",
+			byType[types.TypeContent].Available, byType[types.TypeEmbedding].Available)
+	}
+	if got := len(byType[types.TypeTrace].Checks); got != len(traceChecks) {
+		t.Errorf("trace checks = %d, want %d", got, len(traceChecks))
+	}
+	if langs := byType[types.TypeCodeValid].Options["language"]; len(langs) == 0 {
+		t.Error("code_valid lists no languages")
+	}
+	if groups := byType[types.TypeSQLSafe].Options["allow"]; strings.Join(groups, ",") != "dcl,ddl,dml" {
+		t.Errorf("sql_safe allow groups = %v", groups)
+	}
+	if metrics := byType[types.TypeReferenceMatch].Options["metric"]; strings.Join(metrics, ",") != "bleu,chrf,edit_distance,rouge_l" {
+		t.Errorf("reference_match metrics = %v", metrics)
+	}
+	if got := byType[types.TypeContent].Options["relation"]; len(got) != len(dateRelations) {
+		t.Errorf("content relations = %v, want %v", got, dateRelations)
+	}
+	if got := byType[types.TypeTranscript].Checks; len(got) != len(transcriptChecks) {
+		t.Errorf("transcript checks = %v", got)
+	}
+}
@@ -0,0 +1,123 @@
+package assertion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/codecheck"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// CodeValidEvaluator implements Layer 4 code_valid assertions: the fenced
+// code blocks of the target must be syntactically valid in their language.
+// Code is parsed, never run.
+type CodeValidEvaluator struct{}
+
+type codeValidSpec struct {
+	Target string `json:"target"`
+	// Languages limits the checked blocks; default: every supported one.
+	// Blocks in other languages are skipped.
+	Languages []string `json:"languages"`
+	// DefaultLanguage is assumed for blocks without an info string and for a
+	// target without fences, which is then checked whole.
+	DefaultLanguage string `json:"default_language"`
+	// MinBlocks is the number of checked blocks the target must contain.
+	MinBlocks int  `json:"min_blocks"`
+	Soft      bool `json:"soft"`
+}
+
+func (e *CodeValidEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec codeValidSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid code_valid spec: %v", err))
+	}
+	if spec.Target == "" {
+		spec.Target = "output.message"
+	}
+	supported := strings.Join(codecheck.Languages(), ", ")
+	enabled := make(map[string]bool)
+	for _, l := range spec.Languages {
+		lang := codecheck.Normalize(l)
+		if lang == "" {
+			return failResult(assertion, start, fmt.Sprintf("unsupported language %q (supported: %s)", l, supported))
+		}
+		enabled[lang] = true
+	}
+	if len(enabled) == 0 {
+		for _, lang := range codecheck.Languages() {
+			enabled[lang] = true
+		}
+	}
+	defaultLang := ""
+	if spec.DefaultLanguage != "" {
+		if defaultLang = codecheck.Normalize(spec.DefaultLanguage); defaultLang == "" {
+			return failResult(assertion, start, fmt.Sprintf("unsupported default_language %q (supported: %s)", spec.DefaultLanguage, supported))
+		}
+	}
+
+	text, err := ResolveTargetString(trace, spec.Target)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("target resolution failed: %v", err))
+	}
+	blocks := codecheck.Blocks(text)
+	if len(blocks) == 0 && defaultLang != "" && strings.TrimSpace(text) != "" {
+		blocks = []codecheck.Block{{Code: text, Line: 1}}
+	}
+
+	checked, skipped := 0, 0
+	var problems []string
+	for i, b := range blocks {
+		lang := defaultLang
+		if b.Info != "" {
+			lang = codecheck.Normalize(b.Info)
+		}
+		if lang == "" || !enabled[lang] {
+			skipped++
+			continue
+		}
+		checked++
+		where := fmt.Sprintf("%s block %d (line %d)", lang, i+1, b.Line)
+		if b.Unterminated {
+			problems = append(problems, fmt.Sprintf("%s: no closing fence", where))
+			continue
+		}
+		err := codecheck.Check(lang, b.Code)
+		var syn *codecheck.SyntaxError
+		switch {
+		case err == nil:
+		case errors.As(err, &syn):
+			problems = append(problems, fmt.Sprintf("%s: target line %d: %s", where, b.Line+syn.Line-1, syn.Message))
+		default:
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+		}
+	}
+	if checked < spec.MinBlocks {
+		problems = append(problems, fmt.Sprintf("found %d code blocks to check, want at least %d", checked, spec.MinBlocks))
+	}
+
+	note := ""
+	if skipped > 0 {
+		note = fmt.Sprintf(" (%d blocks in other languages skipped)", skipped)
+	}
+	if len(problems) == 0 {
+		return passResult(assertion, start, fmt.Sprintf("%d code blocks in %s parse.%s", checked, spec.Target, note))
+	}
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       0.0,
+		Explanation: fmt.Sprintf("%s has invalid code: %s%s", spec.Target, strings.Join(problems, "; "), note),
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
@@ -0,0 +1,50 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestCodeValidEvaluator(t *testing.T) {
+	evaluator := &CodeValidEvaluator{}
+	trace := func(message string) *types.Trace {
+		out, _ := json.Marshal(map[string]any{"message": message, "structured": map[string]string{"config": "a: 1\nb: [2, 3]"}})
+		return &types.Trace{TraceID: "trc_test", Output: out}
+	}
+	good := "Here you go:\n```python\ndef add(a, b):\n    return a + b\n```\nAnd the config:\n```yaml\nname: app\n```\n```rust\nfn main() {\n```"
+	bad := "Fixed:\n```py\ndef add(a, b)\n    return a + b\n```\n```json\n{\"a\": 1,}\n```"
+
+	tests := []struct {
+		name       string
+		message    string
+		spec       string
+		wantStatus string
+		wantText   string
+	}{
+		{"valid blocks", good, `{}`, types.StatusPass, "1 blocks in other languages skipped"},
+		{"syntax errors", bad, `{}`, types.StatusHardFail, "python block 1 (line 3): target line 3: expected ':'"},
+		{"json error line", bad, `{"languages":["json"]}`, types.StatusHardFail, "json block 2 (line 7): target line 7"},
+		{"languages filter", bad, `{"languages":["go"]}`, types.StatusPass, "2 blocks in other languages skipped"},
+		{"soft", bad, `{"soft":true}`, types.StatusSoftFail, ""},
+		{"min blocks", "no code here", `{"min_blocks":1}`, types.StatusHardFail, "want at least 1"},
+		{"default language for untagged", "```\nx = (1,\n```", `{"default_language":"python"}`, types.StatusHardFail, "was never closed"},
+		{"untagged skipped", "```\nx = (1,\n```", `{}`, types.StatusPass, "skipped"},
+		{"whole target", "", `{"target":"output.structured.config","default_language":"yml","min_blocks":1}`, types.StatusPass, ""},
+		{"unterminated fence", "```go\nx := 1", `{}`, types.StatusHardFail, "no closing fence"},
+		{"unknown language", good, `{"languages":["cobol"]}`, types.StatusHardFail, "unsupported language"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace(tt.message), &types.Assertion{AssertionID: "a", Type: types.TypeCodeValid, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if !strings.Contains(result.Explanation, tt.wantText) {
+				t.Errorf("explanation %q does not contain %q", result.Explanation, tt.wantText)
+			}
+		})
+	}
+}
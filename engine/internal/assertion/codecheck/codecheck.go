@@ -0,0 +1,186 @@
+// Package codecheck finds fenced code blocks in text and checks their syntax
+// without running them. Go is parsed with go/parser, JSON with encoding/json,
+// and YAML with yaml.v3. Python gets a lexical check (brackets, strings,
+// indentation, block headers) that catches the errors models commonly make
+// without implementing the grammar; built with the treesitter tag, it is also
+// parsed with the tree-sitter Python grammar.
+package codecheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Language names.
+const (
+	Go     = "go"
+	JSON   = "json"
+	Python = "python"
+	YAML   = "yaml"
+)
+
+// checkers maps each language to its syntax check.
+var checkers = map[string]func(src string) error{
+	Go:     checkGo,
+	JSON:   checkJSON,
+	Python: checkPython,
+	YAML:   checkYAML,
+}
+
+// aliases maps fence info strings to language names.
+var aliases = map[string]string{
+	"go": Go, "golang": Go,
+	"json":   JSON,
+	"python": Python, "py": Python, "python3": Python, "py3": Python,
+	"yaml": YAML, "yml": YAML,
+}
+
+// Languages returns the supported languages in sorted order.
+func Languages() []string {
+	langs := make([]string, 0, len(checkers))
+	for lang := range checkers {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Normalize returns the language named by a fence info string or alias
+// ("py", "golang", "yml"), or "" when it is not supported.
+func Normalize(tag string) string {
+	fields := strings.Fields(strings.ToLower(tag))
+	if len(fields) == 0 {
+		return ""
+	}
+	return aliases[fields[0]]
+}
+
+// SyntaxError is a syntax error at a 1-based line and column of a block.
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Check returns the first syntax error in src as a *SyntaxError, or nil.
+// The code is only parsed, never run. An unsupported language is an error
+// of another type.
+func Check(lang, src string) error {
+	check, ok := checkers[lang]
+	if !ok {
+		return fmt.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	return check(src)
+}
+
+// Block is a fenced code block.
+type Block struct {
+	// Info is the info string after the opening fence, such as "python".
+	Info string
+	Code string
+	// Line is the line of the text, 1-based, where the code starts.
+	Line int
+	// Unterminated is set when the text ends before the closing fence.
+	Unterminated bool
+}
+
+// Blocks returns the fenced code blocks of markdown text: runs of lines
+// between an opening fence of three or more backticks or tildes, indented at
+// most three spaces, and a closing fence of the same character at least as
+// long.
+func Blocks(text string) []Block {
+	var blocks []Block
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+		b := Block{Info: info, Line: i + 2, Unterminated: true}
+		var code []string
+		for i++; i < len(lines); i++ {
+			if closesFence(lines[i], fence) {
+				b.Unterminated = false
+				break
+			}
+			code = append(code, strings.TrimSuffix(lines[i], "\r"))
+		}
+		b.Code = strings.Join(code, "\n")
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func openingFence(line string) (fence, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", "", false
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return "", "", false
+	}
+	info = strings.TrimSpace(strings.TrimSuffix(trimmed[n:], "\r"))
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	return trimmed[:n], info, true
+}
+
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 || len(trimmed) < len(fence) {
+		return false
+	}
+	return strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// checkJSON requires src to be exactly one JSON value.
+func checkJSON(src string) error {
+	dec := json.NewDecoder(strings.NewReader(src))
+	var v any
+	err := dec.Decode(&v)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			return offsetError(src, int(dec.InputOffset()), "unexpected data after the JSON value")
+		}
+		return nil
+	}
+	var syn *json.SyntaxError
+	switch {
+	case errors.As(err, &syn):
+		return offsetError(src, int(syn.Offset), strings.TrimPrefix(syn.Error(), "json: "))
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return offsetError(src, len(src), "unexpected end of JSON input")
+	}
+	return offsetError(src, int(dec.InputOffset()), err.Error())
+}
+
+// offsetError positions msg at byte offset off of src. The offset of a
+// decoder error is just past the offending byte.
+func offsetError(src string, off int, msg string) *SyntaxError {
+	off = min(max(off, 1), len(src))
+	before := []byte(src[:off])
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := off - bytes.LastIndexByte(before, '\n') - 1
+	if off == len(src) && strings.HasSuffix(src, "\n") {
+		line--
+		col = len(src[:off-1]) - strings.LastIndexByte(src[:off-1], '\n') - 1
+	}
+	return &SyntaxError{Line: line, Column: max(col, 1), Message: msg}
+}
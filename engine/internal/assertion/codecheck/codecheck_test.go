@@ -0,0 +1,115 @@
+package codecheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	text := "Intro\n```python\nx = 1\n```\ntext\n~~~~ yaml title=cfg\na: 1\n```\nb: 2\n~~~~\n```\nno info\n```\n```go\nunterminated"
+	blocks := Blocks(text)
+	if len(blocks) != 4 {
+		t.Fatalf("got %d blocks, want 4: %+v", len(blocks), blocks)
+	}
+	want := []Block{
+		{Info: "python", Code: "x = 1", Line: 3},
+		{Info: "yaml title=cfg", Code: "a: 1\n```\nb: 2", Line: 7},
+		{Info: "", Code: "no info", Line: 12},
+		{Info: "go", Code: "unterminated", Line: 15, Unterminated: true},
+	}
+	for i, w := range want {
+		if blocks[i] != w {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], w)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for tag, want := range map[string]string{
+		"py": Python, "Python3": Python, "golang": Go, "yml": YAML, "json": JSON,
+		"yaml title=x": YAML, "rust": "", "": "",
+	} {
+		if got := Normalize(tag); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		lang, src string
+		wantLine  int // 0: valid
+		wantMsg   string
+	}{
+		// Go: files, declarations, and statements.
+		{Go, "package main\n\nfunc main() {\n\tprintln(1)\n}", 0, ""},
+		{Go, "// Doc.\npackage main\nfunc f() {}", 0, ""},
+		{Go, "import \"fmt\"\n\nfunc f() { fmt.Println() }", 0, ""},
+		{Go, "x := 1\nif x > 0 {\n\tx++\n}", 0, ""},
+		{Go, "func f() {\n\tx := \n}", 3, "expected operand"},
+		{Go, "x := []int{1, 2\ny := 3", 1, ""},
+		{Go, "package main\nfunc f( {}", 2, ""},
+
+		{JSON, `{"a": [1, 2, {"b": null}]}`, 0, ""},
+		{JSON, "{\n  \"a\": 1,\n  \"b\": 2,\n}", 4, "invalid character '}'"},
+		{JSON, `{"a": 1} {"b": 2}`, 1, "after the JSON value"},
+		{JSON, "[1, 2", 1, "unexpected end"},
+
+		{Python, "def f(x: int) -> int:\n    if x:\n        return {'a': 1}[x]\n    return 0\n", 0, ""},
+		{Python, "class A:\n    \"\"\"Doc\n    string.\"\"\"\n\n    def m(self):\n        pass\n", 0, ""},
+		{Python, "x = (1,\n     2)\ny = [i for i in x\n     if i]\nz = 'a' \\\n    'b'\n", 0, ""},
+		{Python, "if x: return y\nelse: pass", 0, ""},
+		{Python, "def f()\n    pass", 1, "expected ':'"},
+		{Python, "x = 1\n    y = 2", 2, "unexpected indent"},
+		{Python, "if x:\npass", 2, "expected an indented block"},
+		{Python, "if x:\n        a\n    b", 3, "unindent does not match"},
+		{Python, "s = 'abc\nt = 1", 1, "unterminated string"},
+		{Python, "x = f(1, 2]\n", 1, "does not match"},
+		{Python, "x = f(1,\n  2", 1, "was never closed"},
+		{Python, "x = \"\"\"abc\n", 1, "unterminated triple-quoted"},
+		{Python, "def f():\n", 1, "expected an indented block"},
+		{Python, "if x:\n\ty\n        z", 3, "inconsistent use of tabs"},
+		{Python, "def f(:\n    pass", 1, "invalid syntax"},
+		{Python, "x = f(a,, b)", 1, "invalid syntax"},
+		{Python, "y = x[:1]\nz = {**y}", 0, ""},
+
+		{YAML, "name: app\nitems:\n  - a\n  - b: 1\n    c: 2\nlist:\n- x\n- y\ntext: |\n  line one\n  key: not a key\nother: \"quoted\" # c\nflow: [1, {a: b}]\nmulti: 'it''s'\n", 0, ""},
+		{YAML, "a: plain value\n  continued here\nb: 1", 0, ""},
+		{YAML, "---\na: 1\n---\na: 2", 0, ""},
+		{YAML, "a:\n\tb: 1", 2, "cannot start any token"},
+		{YAML, "a: 1\n  b: 2", 2, "mapping values are not allowed"},
+		{YAML, "a:\n    b: 1\n  c: 2", 3, "did not find expected key"},
+		{YAML, "a: 1\na: 2", 2, "already defined"},
+		{YAML, "a: 1\nb\nc: 3", 2, "expected ':'"},
+		{YAML, "a: 1\n- b", 2, "did not find expected key"},
+		{YAML, "- a\nb: 1", 2, "did not find expected '-' indicator"},
+		{YAML, "a: [1, 2\nb: 3", 1, "did not find expected ',' or ']'"},
+		{YAML, "a: \"x\" y", 1, "did not find expected key"},
+		{YAML, "a: b: c", 1, "mapping values are not allowed"},
+		{YAML, "a: 'open", 1, "unexpected end of stream"},
+		{YAML, "a: [1,\n  2]\nb: c: d", 3, "mapping values are not allowed"},
+		{YAML, "x: &a 1\ny: *b", 2, "unknown anchor"},
+	}
+	for _, tt := range tests {
+		err := Check(tt.lang, tt.src)
+		if tt.wantLine == 0 {
+			if err != nil {
+				t.Errorf("Check(%s, %q): %v", tt.lang, tt.src, err)
+			}
+			continue
+		}
+		var syn *SyntaxError
+		if !errors.As(err, &syn) {
+			t.Errorf("Check(%s, %q) = %v, want a syntax error", tt.lang, tt.src, err)
+			continue
+		}
+		if syn.Line != tt.wantLine || !strings.Contains(syn.Message, tt.wantMsg) {
+			t.Errorf("Check(%s, %q) = %v, want line %d containing %q", tt.lang, tt.src, syn, tt.wantLine, tt.wantMsg)
+		}
+	}
+
+	if err := Check("rust", "fn main() {}"); err == nil {
+		t.Error("want error for an unsupported language")
+	}
+}
@@ -0,0 +1,67 @@
+package codecheck
+
+import (
+	"errors"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+)
+
+// Wrappers that let go/parser read snippets. Each sits on the snippet's first
+// line, so line numbers are unchanged and only first-line columns shift.
+const (
+	goDeclPrefix = "package p; "
+	goStmtPrefix = "package p; func _() { "
+)
+
+// checkGo parses src as a Go file, or, without a package clause, as
+// top-level declarations or as function-body statements. When neither
+// form parses, the error that comes later in the source is reported: the
+// form that read further is the one the snippet was written in.
+func checkGo(src string) error {
+	if startsWithPackage(src) {
+		return goParse(src, 0)
+	}
+	declErr := goParse(goDeclPrefix+src, len(goDeclPrefix))
+	if declErr == nil {
+		return nil
+	}
+	stmtErr := goParse(goStmtPrefix+src+"\n}", len(goStmtPrefix))
+	if stmtErr == nil {
+		return nil
+	}
+	d, s := declErr.(*SyntaxError), stmtErr.(*SyntaxError)
+	if s.Line > d.Line || (s.Line == d.Line && s.Column > d.Column) {
+		return s
+	}
+	return d
+}
+
+// goParse parses src and returns its first error, with first-line columns
+// shifted back by prefixLen.
+func goParse(src string, prefixLen int) error {
+	_, err := parser.ParseFile(token.NewFileSet(), "snippet.go", src, parser.AllErrors|parser.SkipObjectResolution)
+	if err == nil {
+		return nil
+	}
+	var list scanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return &SyntaxError{Line: 1, Message: err.Error()}
+	}
+	first := list[0]
+	col := first.Pos.Column
+	if first.Pos.Line == 1 {
+		col = max(col-prefixLen, 1)
+	}
+	return &SyntaxError{Line: first.Pos.Line, Column: col, Message: first.Msg}
+}
+
+// startsWithPackage reports whether the first token of src, after comments,
+// is the package keyword.
+func startsWithPackage(src string) bool {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", fset.Base(), len(src)), []byte(src), nil, 0)
+	_, tok, _ := s.Scan()
+	return tok == token.PACKAGE
+}
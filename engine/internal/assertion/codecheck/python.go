@@ -0,0 +1,227 @@
+package codecheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pythonBlockKeywords start compound statements, whose header must end with
+// a colon outside brackets.
+var pythonBlockKeywords = map[string]bool{
+	"if": true, "elif": true, "else": true, "for": true, "while": true,
+	"def": true, "class": true, "with": true, "try": true, "except": true,
+	"finally": true, "async": true,
+}
+
+const identChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+
+var closingBracket = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+type pyBracket struct {
+	ch        byte
+	line, col int
+}
+
+// pyIndent is an indentation width with tabs counted as 8 and as 1 column.
+// Python rejects indentation whose order differs between the two.
+type pyIndent struct{ w8, w1 int }
+
+// checkPython checks Python source lexically, then with the grammar when a
+// parser is compiled in (see PythonGrammar).
+func checkPython(src string) error {
+	if err := lexPython(src); err != nil {
+		return err
+	}
+	return parsePython(src)
+}
+
+// lexPython checks Python source the way the tokenizer does: strings and
+// brackets must be closed and match, indentation must be consistent and
+// follow block headers, and compound statement headers must have a colon.
+// It does not check the grammar, so "x = = 1" passes.
+func lexPython(src string) error {
+	p := pyChecker{indents: []pyIndent{{}}}
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		if err := p.line(i+1, strings.TrimSuffix(line, "\r")); err != nil {
+			return err
+		}
+	}
+	last := len(lines)
+	switch {
+	case p.triple != "":
+		return &SyntaxError{Line: p.tripleLine, Message: fmt.Sprintf("unterminated triple-quoted string literal (detected at line %d)", last)}
+	case len(p.brackets) > 0:
+		b := p.brackets[len(p.brackets)-1]
+		return &SyntaxError{Line: b.line, Column: b.col, Message: fmt.Sprintf("'%c' was never closed", b.ch)}
+	case p.continued:
+		return &SyntaxError{Line: last, Message: "unexpected EOF after line continuation"}
+	case p.expectIndent:
+		return &SyntaxError{Line: p.logicalLine, Message: fmt.Sprintf("expected an indented block after line %d", p.logicalLine)}
+	}
+	return nil
+}
+
+type pyChecker struct {
+	indents      []pyIndent
+	brackets     []pyBracket
+	triple       string // open triple quote, or ""
+	tripleLine   int
+	continued    bool // the previous line ended with a backslash
+	expectIndent bool // the previous logical line ended with a colon
+
+	// Per logical line.
+	logicalLine int
+	keyword     string
+	colon       bool // a colon outside brackets
+	lastSig     byte
+}
+
+func (p *pyChecker) line(n int, line string) error {
+	pos := 0
+	if p.triple == "" && len(p.brackets) == 0 && !p.continued {
+		trimmed := strings.TrimLeft(line, " \t\f")
+		if trimmed == "" || trimmed[0] == '#' {
+			return nil
+		}
+		if err := p.indent(n, line[:len(line)-len(trimmed)]); err != nil {
+			return err
+		}
+		pos = len(line) - len(trimmed)
+		p.logicalLine, p.colon, p.lastSig = n, false, 0
+		p.keyword = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, identChars))]
+	}
+	p.continued = false
+
+	for pos < len(line) {
+		if p.triple != "" {
+			end := closingQuote(line, pos, p.triple)
+			if end < 0 {
+				return nil
+			}
+			p.triple, pos, p.lastSig = "", end, '"'
+			continue
+		}
+		c := line[pos]
+		switch {
+		case c == '#':
+			pos = len(line)
+			continue
+		case c == '\\' && pos == len(line)-1:
+			p.continued = true
+			return nil
+		case c == '"' || c == '\'':
+			q := string(c)
+			if strings.HasPrefix(line[pos:], q+q+q) {
+				p.triple, p.tripleLine = q+q+q, n
+				pos += 3
+				continue
+			}
+			end := closingQuote(line, pos+1, q)
+			if end < 0 {
+				if strings.HasSuffix(line, "\\") {
+					p.continued = true
+					return nil
+				}
+				return &SyntaxError{Line: n, Column: pos + 1, Message: "unterminated string literal"}
+			}
+			pos, p.lastSig = end, c
+			continue
+		case c == '(' || c == '[' || c == '{':
+			p.brackets = append(p.brackets, pyBracket{ch: c, line: n, col: pos + 1})
+		case (c == ':' && (p.lastSig == '(' || p.lastSig == '{')) || (c == ',' && strings.IndexByte("([{,", p.lastSig) >= 0):
+			// A delimiter cannot follow an opening bracket or a comma, as in
+			// "def f(:".
+			return &SyntaxError{Line: n, Column: pos + 1, Message: "invalid syntax"}
+		case c == ')' || c == ']' || c == '}':
+			if len(p.brackets) == 0 {
+				return &SyntaxError{Line: n, Column: pos + 1, Message: fmt.Sprintf("unmatched '%c'", c)}
+			}
+			open := p.brackets[len(p.brackets)-1]
+			if open.ch != closingBracket[c] {
+				msg := fmt.Sprintf("closing parenthesis '%c' does not match opening parenthesis '%c'", c, open.ch)
+				if open.line != n {
+					msg += fmt.Sprintf(" on line %d", open.line)
+				}
+				return &SyntaxError{Line: n, Column: pos + 1, Message: msg}
+			}
+			p.brackets = p.brackets[:len(p.brackets)-1]
+		case c == ':' && len(p.brackets) == 0 && !strings.HasPrefix(line[pos:], ":="):
+			p.colon = true
+		}
+		if c != ' ' && c != '\t' && c != '\f' {
+			p.lastSig = c
+		}
+		pos++
+	}
+
+	if p.triple != "" || len(p.brackets) > 0 || p.continued {
+		return nil
+	}
+	// The logical line is complete.
+	if pythonBlockKeywords[p.keyword] && !p.colon {
+		return &SyntaxError{Line: p.logicalLine, Message: fmt.Sprintf("expected ':' after '%s' statement", p.keyword)}
+	}
+	p.expectIndent = p.lastSig == ':'
+	return nil
+}
+
+// indent checks the indentation of a logical line's first line.
+func (p *pyChecker) indent(n int, ws string) error {
+	var cur pyIndent
+	for _, c := range ws {
+		switch c {
+		case '\t':
+			cur.w8 = (cur.w8/8 + 1) * 8
+			cur.w1++
+		case ' ':
+			cur.w8++
+			cur.w1++
+		}
+	}
+	top := p.indents[len(p.indents)-1]
+	inconsistent := &SyntaxError{Line: n, Message: "inconsistent use of tabs and spaces in indentation"}
+	switch {
+	case p.expectIndent:
+		p.expectIndent = false
+		if cur.w8 <= top.w8 {
+			return &SyntaxError{Line: n, Message: fmt.Sprintf("expected an indented block after line %d", p.logicalLine)}
+		}
+		if cur.w1 <= top.w1 {
+			return inconsistent
+		}
+		p.indents = append(p.indents, cur)
+	case cur.w8 > top.w8:
+		return &SyntaxError{Line: n, Message: "unexpected indent"}
+	case cur.w8 < top.w8:
+		for len(p.indents) > 1 && cur.w8 < p.indents[len(p.indents)-1].w8 {
+			p.indents = p.indents[:len(p.indents)-1]
+		}
+		top = p.indents[len(p.indents)-1]
+		if cur.w8 != top.w8 {
+			return &SyntaxError{Line: n, Message: "unindent does not match any outer indentation level"}
+		}
+		if cur.w1 != top.w1 {
+			return inconsistent
+		}
+	case cur.w1 != top.w1:
+		return inconsistent
+	}
+	return nil
+}
+
+// closingQuote returns the index just past the quote q that closes a string
+// whose body starts at pos, skipping backslash escapes, or -1.
+func closingQuote(line string, pos int, q string) int {
+	for pos < len(line) {
+		if line[pos] == '\\' {
+			pos += 2
+			continue
+		}
+		if strings.HasPrefix(line[pos:], q) {
+			return pos + len(q)
+		}
+		pos++
+	}
+	return -1
+}
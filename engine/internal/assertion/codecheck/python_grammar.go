@@ -0,0 +1,55 @@
+//go:build treesitter
+
+package codecheck
+
+import (
+	"fmt"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// PythonGrammar indicates whether Python is checked against its grammar, not
+// only lexically.
+const PythonGrammar = true
+
+var pythonLanguage = sitter.NewLanguage(python.Language())
+
+// parsePython parses src with the tree-sitter Python grammar and returns its
+// first error or missing token.
+func parsePython(src string) error {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(pythonLanguage); err != nil {
+		return fmt.Errorf("load python grammar: %w", err)
+	}
+	tree := parser.Parse([]byte(src), nil)
+	defer tree.Close()
+	n := firstSyntaxError(tree.RootNode())
+	if n == nil {
+		return nil
+	}
+	pos := n.StartPosition()
+	msg := "invalid syntax"
+	if n.IsMissing() {
+		msg = fmt.Sprintf("expected '%s'", n.Kind())
+	}
+	return &SyntaxError{Line: int(pos.Row) + 1, Column: int(pos.Column) + 1, Message: msg}
+}
+
+// firstSyntaxError returns the first ERROR or MISSING node under n in source
+// order, or nil.
+func firstSyntaxError(n *sitter.Node) *sitter.Node {
+	if n.IsError() || n.IsMissing() {
+		return n
+	}
+	if !n.HasError() {
+		return nil
+	}
+	for i := range n.ChildCount() {
+		if e := firstSyntaxError(n.Child(i)); e != nil {
+			return e
+		}
+	}
+	return nil
+}
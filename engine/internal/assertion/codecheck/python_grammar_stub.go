@@ -0,0 +1,10 @@
+//go:build !treesitter
+
+package codecheck
+
+// PythonGrammar indicates whether Python is checked against its grammar, not
+// only lexically.
+const PythonGrammar = false
+
+// parsePython accepts everything when no Python grammar is compiled in.
+func parsePython(string) error { return nil }
@@ -0,0 +1,33 @@
+//go:build treesitter
+
+package codecheck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck_PythonGrammar(t *testing.T) {
+	tests := []struct {
+		src       string
+		line, col int // line 0: valid
+	}{
+		{"async def f():\n    async with a as b:\n        return [x async for x in b]\n", 0, 0},
+		{"match x:\n    case [1, *rest]:\n        pass\n", 0, 0},
+		{"x = = 1", 1, 5},
+		{"def f():\n    return return\n", 2, 5},
+	}
+	for _, tt := range tests {
+		err := Check(Python, tt.src)
+		if tt.line == 0 {
+			if err != nil {
+				t.Errorf("Check(%q): %v", tt.src, err)
+			}
+			continue
+		}
+		var syn *SyntaxError
+		if !errors.As(err, &syn) || syn.Line != tt.line || syn.Column != tt.col {
+			t.Errorf("Check(%q) = %v, want line %d, column %d", tt.src, err, tt.line, tt.col)
+		}
+	}
+}
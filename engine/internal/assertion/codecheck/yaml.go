@@ -0,0 +1,73 @@
+package codecheck
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLine matches the position yaml.v3 puts before a message.
+var yamlLine = regexp.MustCompile(`^line (\d+): `)
+
+// checkYAML parses every document in src with yaml.v3, which also rejects
+// duplicate mapping keys.
+func checkYAML(src string) error {
+	msg, line := parseYAML(src)
+	if msg == "" {
+		return nil
+	}
+	if line == 0 {
+		line = yamlErrorLine(src, msg)
+	}
+	return &SyntaxError{Line: line, Message: msg}
+}
+
+// parseYAML returns the first error in src without its "yaml: " prefix, and
+// its line when the error reports one reliably, else 0.
+func parseYAML(src string) (msg string, line int) {
+	dec := yaml.NewDecoder(strings.NewReader(src))
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return "", 0
+		}
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+			// Decoding errors, such as duplicate keys, carry their own line.
+			msg = typeErr.Errors[0]
+			if m := yamlLine.FindStringSubmatch(msg); m != nil {
+				line, _ = strconv.Atoi(m[1])
+				msg = msg[len(m[0]):]
+			}
+			return msg, line
+		}
+		if err != nil {
+			msg = strings.TrimPrefix(err.Error(), "yaml: ")
+			if m := yamlLine.FindStringSubmatch(msg); m != nil {
+				msg = msg[len(m[0]):]
+			}
+			return msg, 0
+		}
+	}
+}
+
+// yamlErrorLine finds the line of a syntax error. yaml.v3 reports the line
+// where the enclosing node starts, counted from 0 or 1 depending on the
+// stage that failed, so the error is placed on the first line whose prefix
+// of src fails with the same message.
+func yamlErrorLine(src, msg string) int {
+	lines := strings.SplitAfter(src, "\n")
+	prefix := ""
+	for i, l := range lines {
+		prefix += l
+		if m, _ := parseYAML(prefix); m == msg {
+			return i + 1
+		}
+	}
+	return len(lines)
+}
@@ -0,0 +1,250 @@
+package assertion
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Composite operators.
+const (
+	CompositeAllOf  = "all_of"
+	CompositeAnyOf  = "any_of"
+	CompositeNoneOf = "none_of"
+)
+
+// CompositeEvaluator combines child assertions into one result:
+//
+//   - all_of: every child passes
+//   - any_of: at least one child passes
+//   - none_of: no child passes
+//
+// Children are evaluated through the registry in layer order with the same
+// gating as a batch: L1-4 children run first, and L5-6 children run
+// concurrently only when the L1-4 results do not already decide the outcome.
+// Each child keeps its own evaluator's caching.
+type CompositeEvaluator struct {
+	registry *Registry
+}
+
+type compositeChild struct {
+	AssertionID string          `json:"assertion_id,omitempty"`
+	Type        string          `json:"type"`
+	Spec        json.RawMessage `json:"spec"`
+	// Weight scales the child's contribution to the composite score. Default 1.
+	Weight *float64 `json:"weight,omitempty"`
+}
+
+type compositeSpec struct {
+	Operator   string           `json:"operator"`
+	Assertions []compositeChild `json:"assertions"`
+	// MinScore additionally fails the composite when its weighted score is below it.
+	MinScore *float64 `json:"min_score,omitempty"`
+	Soft     bool     `json:"soft"`
+}
+
+func (e *CompositeEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	return e.evaluate(trace, assertion, nil)
+}
+
+// EvaluateWithSeed passes the batch seed on to seeded children.
+func (e *CompositeEvaluator) EvaluateWithSeed(trace *types.Trace, assertion *types.Assertion, seed int64) *types.AssertionResult {
+	return e.evaluate(trace, assertion, &seed)
+}
+
+func (e *CompositeEvaluator) evaluate(trace *types.Trace, assertion *types.Assertion, seed *int64) *types.AssertionResult {
+	start := time.Now()
+
+	var spec compositeSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid composite spec: %v", err))
+	}
+	switch spec.Operator {
+	case CompositeAllOf, CompositeAnyOf, CompositeNoneOf:
+	case "":
+		return failResult(assertion, start, "composite spec missing required field: operator")
+	default:
+		return failResult(assertion, start, fmt.Sprintf("unsupported composite operator: %s (must be all_of, any_of, or none_of)", spec.Operator))
+	}
+	if len(spec.Assertions) == 0 {
+		return failResult(assertion, start, "composite spec requires at least one child in 'assertions'")
+	}
+
+	children := make([]types.Assertion, len(spec.Assertions))
+	weights := make([]float64, len(spec.Assertions))
+	for i, c := range spec.Assertions {
+		if c.Type == "" {
+			return failResult(assertion, start, fmt.Sprintf("composite child %d missing required field: type", i))
+		}
+		weights[i] = 1
+		if c.Weight != nil {
+			if *c.Weight < 0 {
+				return failResult(assertion, start, fmt.Sprintf("composite child %d: weight must be non-negative, got %g", i, *c.Weight))
+			}
+			weights[i] = *c.Weight
+		}
+		id := c.AssertionID
+		if id == "" {
+			id = fmt.Sprintf("%s[%d]", assertion.AssertionID, i)
+		}
+		children[i] = types.Assertion{AssertionID: id, Type: c.Type, Spec: c.Spec, RequestID: assertion.RequestID}
+	}
+
+	results := e.evaluateChildren(trace, spec.Operator, children, seed)
+
+	var total, weightSum, cost float64
+	var passed, soft, hard int
+	breakdown := make([]types.AssertionResult, 0, len(results))
+	for i, r := range results {
+		if r == nil {
+			continue
+		}
+		breakdown = append(breakdown, *r)
+		cost += r.Cost
+		score := r.Score
+		if spec.Operator == CompositeNoneOf {
+			score = 1 - score
+		}
+		total += weights[i] * score
+		weightSum += weights[i]
+		switch r.Status {
+		case types.StatusPass:
+			passed++
+		case types.StatusSoftFail:
+			soft++
+		default:
+			hard++
+		}
+	}
+	score := 0.0
+	if weightSum > 0 {
+		score = total / weightSum
+	}
+	skipped := len(children) - len(breakdown)
+
+	status := types.StatusPass
+	switch spec.Operator {
+	case CompositeAllOf:
+		if hard > 0 {
+			status = types.StatusHardFail
+		} else if soft > 0 {
+			status = types.StatusSoftFail
+		}
+	case CompositeAnyOf:
+		if passed == 0 {
+			status = types.StatusHardFail
+			if soft > 0 {
+				status = types.StatusSoftFail
+			}
+		}
+	case CompositeNoneOf:
+		if passed > 0 {
+			status = types.StatusHardFail
+		}
+	}
+	var explanation strings.Builder
+	fmt.Fprintf(&explanation, "%s: %d passed, %d soft_fail, %d hard_fail", spec.Operator, passed, soft, hard)
+	if skipped > 0 {
+		fmt.Fprintf(&explanation, ", %d skipped", skipped)
+	}
+	fmt.Fprintf(&explanation, "; score %.4g", score)
+	if spec.MinScore != nil && score < *spec.MinScore {
+		status = types.StatusHardFail
+		fmt.Fprintf(&explanation, " below min_score %.4g", *spec.MinScore)
+	}
+	if status == types.StatusHardFail && spec.Soft {
+		status = types.StatusSoftFail
+	}
+	if status == types.StatusPass {
+		explanation.WriteString(".")
+	}
+
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       score,
+		Explanation: explanation.String(),
+		Cost:        cost,
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+		Children:    breakdown,
+	}
+}
+
+// evaluateChildren runs children in layer order and returns their results by
+// child index. L5-6 children are left nil (skipped) when the L1-4 results
+// already decide op.
+func (e *CompositeEvaluator) evaluateChildren(trace *types.Trace, op string, children []types.Assertion, seed *int64) []*types.AssertionResult {
+	results := make([]*types.AssertionResult, len(children))
+	layers := make([]int, len(children))
+	var late []int
+	for i := range children {
+		layers[i] = assertionLayer(&children[i])
+	}
+	decided := false
+	for layer := 0; layer <= 4; layer++ {
+		for i := range children {
+			if layers[i] != layer {
+				continue
+			}
+			results[i] = e.evaluateChild(trace, &children[i], seed)
+			switch {
+			case op == CompositeAllOf && results[i].Status == types.StatusHardFail,
+				op != CompositeAllOf && results[i].Status == types.StatusPass:
+				decided = true
+			}
+		}
+	}
+	for i := range children {
+		if layers[i] >= 5 {
+			late = append(late, i)
+		}
+	}
+	if decided || len(late) == 0 {
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for _, i := range late {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = e.evaluateChild(trace, &children[i], seed)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+func (e *CompositeEvaluator) evaluateChild(trace *types.Trace, a *types.Assertion, seed *int64) *types.AssertionResult {
+	eval, err := e.registry.Get(a.Type)
+	if err != nil {
+		return failResult(a, time.Now(), fmt.Sprintf("unknown assertion type: %s", a.Type))
+	}
+	return evaluateOne(eval, trace, a, seed)
+}
+
+// assertionLayer returns the evaluation layer of a. A composite takes the
+// highest layer among its children so that L5-6 children are gated and run
+// in the batch's concurrent phase.
+func assertionLayer(a *types.Assertion) int {
+	if a.Type != types.TypeComposite {
+		return layerOrder[a.Type]
+	}
+	layer := layerOrder[types.TypeComposite]
+	var spec struct {
+		Assertions []compositeChild `json:"assertions"`
+	}
+	if json.Unmarshal(a.Spec, &spec) != nil {
+		return layer
+	}
+	for _, c := range spec.Assertions {
+		layer = max(layer, assertionLayer(&types.Assertion{Type: c.Type, Spec: c.Spec}))
+	}
+	return layer
+}
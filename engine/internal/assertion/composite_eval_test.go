@@ -0,0 +1,154 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// countingJudge stands in for a Layer 6 evaluator and records how often it ran.
+type countingJudge struct {
+	calls  atomic.Int32
+	status string
+	score  float64
+}
+
+func (c *countingJudge) Evaluate(_ *types.Trace, a *types.Assertion) *types.AssertionResult {
+	c.calls.Add(1)
+	return &types.AssertionResult{AssertionID: a.AssertionID, Status: c.status, Score: c.score, Cost: 0.01, RequestID: a.RequestID}
+}
+
+func compositeTrace() *types.Trace {
+	return &types.Trace{
+		TraceID: "trc_composite",
+		Output:  json.RawMessage(`{"message":"Your refund has been issued."}`),
+		Steps: []types.Step{
+			{Name: "lookup_order", Type: types.StepTypeToolCall, Args: json.RawMessage(`{}`), Result: json.RawMessage(`{}`)},
+		},
+	}
+}
+
+const (
+	childContains = `{"type":"content","spec":{"target":"output.message","check":"contains","value":"refund"}}`
+	childMissing  = `{"type":"content","spec":{"target":"output.message","check":"contains","value":"sorry"}}`
+	childSoft     = `{"type":"content","spec":{"target":"output.message","check":"contains","value":"sorry","soft":true}}`
+	childTool     = `{"type":"trace","spec":{"check":"required_tools","tools":["lookup_order"]}}`
+	childJudge    = `{"type":"llm_judge","spec":{"criteria":"polite"}}`
+)
+
+func TestCompositeEvaluator(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         string
+		judgeStatus  string
+		wantStatus   string
+		wantScore    float64
+		wantChildren int
+		wantJudge    int32
+	}{
+		{"all_of pass", `{"operator":"all_of","assertions":[` + childContains + `,` + childTool + `]}`, types.StatusPass, types.StatusPass, 1, 2, 0},
+		{"all_of hard", `{"operator":"all_of","assertions":[` + childContains + `,` + childMissing + `]}`, types.StatusPass, types.StatusHardFail, 0.5, 2, 0},
+		{"all_of soft child", `{"operator":"all_of","assertions":[` + childContains + `,` + childSoft + `]}`, types.StatusPass, types.StatusSoftFail, 0.5, 2, 0},
+		{"all_of soft", `{"operator":"all_of","soft":true,"assertions":[` + childMissing + `]}`, types.StatusPass, types.StatusSoftFail, 0, 1, 0},
+		{"any_of pass", `{"operator":"any_of","assertions":[` + childMissing + `,` + childContains + `]}`, types.StatusPass, types.StatusPass, 0.5, 2, 0},
+		{"any_of fail", `{"operator":"any_of","assertions":[` + childMissing + `]}`, types.StatusPass, types.StatusHardFail, 0, 1, 0},
+		{"none_of pass", `{"operator":"none_of","assertions":[` + childMissing + `]}`, types.StatusPass, types.StatusPass, 1, 1, 0},
+		{"none_of fail", `{"operator":"none_of","assertions":[` + childContains + `,` + childMissing + `]}`, types.StatusPass, types.StatusHardFail, 0.5, 2, 0},
+		{"weighted score", `{"operator":"any_of","assertions":[{"type":"content","weight":3,"spec":{"target":"output.message","check":"contains","value":"refund"}},` + childMissing + `]}`, types.StatusPass, types.StatusPass, 0.75, 2, 0},
+		{"min_score", `{"operator":"any_of","min_score":0.8,"assertions":[` + childContains + `,` + childMissing + `]}`, types.StatusPass, types.StatusHardFail, 0.5, 2, 0},
+		{"nested", `{"operator":"all_of","assertions":[{"type":"composite","spec":{"operator":"none_of","assertions":[` + childMissing + `]}},` + childContains + `]}`, types.StatusPass, types.StatusPass, 1, 2, 0},
+		{"judge runs", `{"operator":"all_of","assertions":[` + childContains + `,` + childJudge + `]}`, types.StatusPass, types.StatusPass, 1, 2, 1},
+		{"judge gated by all_of", `{"operator":"all_of","assertions":[` + childMissing + `,` + childJudge + `]}`, types.StatusPass, types.StatusHardFail, 0, 1, 0},
+		{"judge gated by any_of", `{"operator":"any_of","assertions":[` + childJudge + `,` + childContains + `]}`, types.StatusHardFail, types.StatusPass, 1, 1, 0},
+		{"judge decides any_of", `{"operator":"any_of","assertions":[` + childMissing + `,` + childJudge + `]}`, types.StatusHardFail, types.StatusHardFail, 0, 2, 1},
+		{"unknown child type", `{"operator":"all_of","assertions":[{"type":"nope","spec":{}}]}`, types.StatusPass, types.StatusHardFail, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			judge := &countingJudge{status: tt.judgeStatus}
+			if tt.judgeStatus == types.StatusPass {
+				judge.score = 1
+			}
+			registry := NewRegistry()
+			registry.Register(types.TypeLLMJudge, judge)
+			eval, _ := registry.Get(types.TypeComposite)
+
+			a := &types.Assertion{AssertionID: "comp", Type: types.TypeComposite, Spec: json.RawMessage(tt.spec), RequestID: "req_1"}
+			result := eval.Evaluate(compositeTrace(), a)
+			if result.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if diff := result.Score - tt.wantScore; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("score = %v, want %v", result.Score, tt.wantScore)
+			}
+			if len(result.Children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(result.Children), tt.wantChildren)
+			}
+			if got := judge.calls.Load(); got != tt.wantJudge {
+				t.Errorf("judge calls = %d, want %d", got, tt.wantJudge)
+			}
+			for _, c := range result.Children {
+				if c.RequestID != "req_1" {
+					t.Errorf("child %s request_id = %q", c.AssertionID, c.RequestID)
+				}
+			}
+		})
+	}
+}
+
+func TestCompositeEvaluator_InvalidSpec(t *testing.T) {
+	tests := map[string]string{
+		`{"operator":"xor","assertions":[` + childContains + `]}`:                       "unsupported composite operator",
+		`{"assertions":[` + childContains + `]}`:                                        "missing required field: operator",
+		`{"operator":"all_of","assertions":[]}`:                                         "at least one child",
+		`{"operator":"all_of","assertions":[{"spec":{}}]}`:                              "child 0 missing required field: type",
+		`{"operator":"all_of","assertions":[{"type":"content","weight":-1,"spec":{}}]}`: "weight must be non-negative",
+	}
+	eval := &CompositeEvaluator{registry: NewRegistry()}
+	for spec, want := range tests {
+		a := &types.Assertion{AssertionID: "comp", Type: types.TypeComposite, Spec: json.RawMessage(spec)}
+		result := eval.Evaluate(compositeTrace(), a)
+		if result.Status != types.StatusHardFail || !strings.Contains(result.Explanation, want) {
+			t.Errorf("%s: got %s %q, want hard_fail containing %q", spec, result.Status, result.Explanation, want)
+		}
+	}
+}
+
+func TestCompositeEvaluator_ChildIDs(t *testing.T) {
+	spec := `{"operator":"all_of","assertions":[{"assertion_id":"named",` + childContains[1:] + `,` + childTool + `]}`
+	a := &types.Assertion{AssertionID: "comp", Type: types.TypeComposite, Spec: json.RawMessage(spec)}
+	result := (&CompositeEvaluator{registry: NewRegistry()}).Evaluate(compositeTrace(), a)
+	if len(result.Children) != 2 || result.Children[0].AssertionID != "named" || result.Children[1].AssertionID != "comp[1]" {
+		t.Errorf("children = %+v", result.Children)
+	}
+}
+
+func TestPipeline_CompositeWithJudgeRunsInLayer6(t *testing.T) {
+	judge := &countingJudge{status: types.StatusPass, score: 1}
+	registry := NewRegistry()
+	registry.Register(types.TypeLLMJudge, judge)
+	pipeline := NewPipeline(registry)
+
+	assertions := []types.Assertion{
+		{AssertionID: "comp", Type: types.TypeComposite, Spec: json.RawMessage(`{"operator":"all_of","assertions":[` + childJudge + `]}`)},
+		{AssertionID: "content", Type: types.TypeContent, Spec: json.RawMessage(`{"target":"output.message","check":"contains","value":"sorry"}`)},
+	}
+	result, err := pipeline.EvaluateBatch(compositeTrace(), assertions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The composite inherits layer 6 from its judge child, so the content
+	// hard_fail gates it.
+	if len(result.Results) != 1 || result.Results[0].AssertionID != "content" {
+		t.Fatalf("results = %+v", result.Results)
+	}
+	if judge.calls.Load() != 0 {
+		t.Errorf("judge ran %d times behind a hard_fail gate", judge.calls.Load())
+	}
+	if got := assertionLayer(&assertions[0]); got != 6 {
+		t.Errorf("assertionLayer = %d, want 6", got)
+	}
+}
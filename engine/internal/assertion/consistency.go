@@ -0,0 +1,696 @@
+package assertion
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// maxConsistencyFailures caps the element mismatches listed per relation.
+const maxConsistencyFailures = 3
+
+// ConsistencyEvaluator implements Layer 4 consistency assertions: arithmetic
+// relationships between numeric fields, such as an invoice total equal to
+// the sum of its line items, checked without a model.
+//
+// Each relation is a comparison of two arithmetic expressions over field
+// paths (output.*, input.*, metadata.*), numbers, + - * /, parentheses, and
+// the functions sum, avg, min, max, count, abs, and round. A path with []
+// reads every element of an array, so output.items[].amount is a list;
+// arithmetic on lists applies element by element, broadcasting numbers.
+type ConsistencyEvaluator struct{}
+
+type consistencySpec struct {
+	Relations []consistencyRelation `json:"relations"`
+	// Tolerance and RelativeTolerance are the defaults of each relation.
+	Tolerance         float64 `json:"tolerance"`
+	RelativeTolerance float64 `json:"relative_tolerance"`
+	Soft              bool    `json:"soft"`
+}
+
+type consistencyRelation struct {
+	Check             string   `json:"check"`
+	Tolerance         *float64 `json:"tolerance"`
+	RelativeTolerance *float64 `json:"relative_tolerance"`
+}
+
+func (e *ConsistencyEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec consistencySpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid consistency spec: %v", err))
+	}
+	if len(spec.Relations) == 0 {
+		return failResult(assertion, start, "consistency spec requires at least one relation")
+	}
+
+	env := &numEnv{trace: trace}
+	var failures []string
+	for i, rel := range spec.Relations {
+		tol, relTol := spec.Tolerance, spec.RelativeTolerance
+		if rel.Tolerance != nil {
+			tol = *rel.Tolerance
+		}
+		if rel.RelativeTolerance != nil {
+			relTol = *rel.RelativeTolerance
+		}
+		if tol < 0 || relTol < 0 {
+			return failResult(assertion, start, fmt.Sprintf("relation %d: tolerances must not be negative", i))
+		}
+		left, op, right, err := parseRelation(rel.Check)
+		if err != nil {
+			return failResult(assertion, start, fmt.Sprintf("relation %d: %v", i, err))
+		}
+		msg, err := checkRelation(env, left, op, right, tol, relTol)
+		if err != nil {
+			return failResult(assertion, start, fmt.Sprintf("relation %q: %v", rel.Check, err))
+		}
+		if msg != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", rel.Check, msg))
+		}
+	}
+
+	if len(failures) == 0 {
+		return passResult(assertion, start, fmt.Sprintf("All %d relations hold.", len(spec.Relations)))
+	}
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       1 - float64(len(failures))/float64(len(spec.Relations)),
+		Explanation: fmt.Sprintf("%d of %d relations do not hold: %s", len(failures), len(spec.Relations), strings.Join(failures, "; ")),
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+// checkRelation evaluates both sides and compares them element by element.
+// It returns a description of the mismatches, or "" when the relation holds.
+func checkRelation(env *numEnv, left numExpr, op string, right numExpr, tol, relTol float64) (string, error) {
+	l, err := left.eval(env)
+	if err != nil {
+		return "", err
+	}
+	r, err := right.eval(env)
+	if err != nil {
+		return "", err
+	}
+	n, err := broadcastLen(l, r)
+	if err != nil {
+		return "", err
+	}
+	var bad []string
+	failed := 0
+	for i := 0; i < n; i++ {
+		a, b := l.at(i), r.at(i)
+		allowed := math.Max(tol, relTol*math.Abs(b))
+		// Absorb float rounding, e.g. from 0.1 + 0.2.
+		allowed += 1e-9 * math.Max(1, math.Abs(b))
+		if compareWithin(a, op, b, allowed) {
+			continue
+		}
+		failed++
+		if len(bad) < maxConsistencyFailures {
+			desc := fmt.Sprintf("%s %s %s is false", formatFloat(a), op, formatFloat(b))
+			if l.list != nil || r.list != nil {
+				desc = fmt.Sprintf("[%d] %s", i, desc)
+			}
+			bad = append(bad, desc)
+		}
+	}
+	if failed == 0 {
+		return "", nil
+	}
+	msg := strings.Join(bad, ", ")
+	if failed > len(bad) {
+		msg += fmt.Sprintf(" and %d more", failed-len(bad))
+	}
+	if tol > 0 {
+		msg += fmt.Sprintf(" (tolerance %s)", formatFloat(tol))
+	}
+	if relTol > 0 {
+		msg += fmt.Sprintf(" (relative tolerance %s)", formatFloat(relTol))
+	}
+	return msg, nil
+}
+
+// compareWithin applies op with allowed slack: == and != treat values within
+// allowed as equal, <= and >= let the left side exceed the bound by allowed.
+func compareWithin(a float64, op string, b, allowed float64) bool {
+	switch op {
+	case "==":
+		return math.Abs(a-b) <= allowed
+	case "!=":
+		return math.Abs(a-b) > allowed
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b+allowed
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b-allowed
+	}
+	return false
+}
+
+// numValue is a number or, from a [] path, a list of numbers.
+type numValue struct {
+	scalar float64
+	list   []float64
+}
+
+func (v numValue) at(i int) float64 {
+	if v.list != nil {
+		return v.list[i]
+	}
+	return v.scalar
+}
+
+// broadcastLen returns the element count of an operation on a and b: 1 for
+// two numbers, else the length of the list(s), which must agree.
+func broadcastLen(a, b numValue) (int, error) {
+	switch {
+	case a.list != nil && b.list != nil:
+		if len(a.list) != len(b.list) {
+			return 0, fmt.Errorf("lists have different lengths: %d and %d", len(a.list), len(b.list))
+		}
+		return len(a.list), nil
+	case a.list != nil:
+		return len(a.list), nil
+	case b.list != nil:
+		return len(b.list), nil
+	}
+	return 1, nil
+}
+
+// numEnv resolves field paths against a trace, decoding each root once.
+type numEnv struct {
+	trace *types.Trace
+	roots map[string]any
+}
+
+func (env *numEnv) root(name string) (any, error) {
+	if v, ok := env.roots[name]; ok {
+		return v, nil
+	}
+	raw := env.trace.Output
+	if name == "input" {
+		raw = env.trace.Input
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("trace has no %s", name)
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("%s is not valid JSON: %v", name, err)
+	}
+	if env.roots == nil {
+		env.roots = make(map[string]any)
+	}
+	env.roots[name] = v
+	return v, nil
+}
+
+// numExpr is a parsed arithmetic expression.
+type numExpr interface {
+	eval(env *numEnv) (numValue, error)
+}
+
+type numLiteral float64
+
+func (n numLiteral) eval(*numEnv) (numValue, error) { return numValue{scalar: float64(n)}, nil }
+
+// pathSegment is a key, an index, or, with all set, every element.
+type pathSegment struct {
+	key   string
+	index int
+	isKey bool
+	all   bool
+}
+
+type numPath struct {
+	text     string
+	segments []pathSegment
+}
+
+func (p numPath) eval(env *numEnv) (numValue, error) {
+	if p.segments[0].key == "metadata" {
+		v, err := resolveConstraintField(env.trace, p.text)
+		return numValue{scalar: v}, err
+	}
+	nodes, isList, desc, err := p.resolve(env)
+	if err != nil {
+		return numValue{}, err
+	}
+	nums := make([]float64, len(nodes))
+	for i, node := range nodes {
+		switch v := node.(type) {
+		case float64:
+			nums[i] = v
+		case string:
+			// Amounts are often sent as strings to keep decimals exact.
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return numValue{}, fmt.Errorf("%s holds %q, not a number", desc, v)
+			}
+			nums[i] = f
+		default:
+			return numValue{}, fmt.Errorf("%s holds a %T, not a number", desc, node)
+		}
+	}
+	if isList {
+		return numValue{list: nums}, nil
+	}
+	return numValue{scalar: nums[0]}, nil
+}
+
+// resolve returns the JSON values p selects, whether it selects a list, and
+// its description for errors.
+func (p numPath) resolve(env *numEnv) (nodes []any, isList bool, desc string, err error) {
+	root, err := env.root(p.segments[0].key)
+	if err != nil {
+		return nil, false, "", err
+	}
+	nodes, desc = []any{root}, p.segments[0].key
+	for _, seg := range p.segments[1:] {
+		var next []any
+		for _, node := range nodes {
+			switch {
+			case seg.isKey:
+				obj, ok := node.(map[string]any)
+				if !ok {
+					return nil, false, "", fmt.Errorf("%s is not an object", desc)
+				}
+				v, ok := obj[seg.key]
+				if !ok {
+					return nil, false, "", fmt.Errorf("%s.%s not found", desc, seg.key)
+				}
+				next = append(next, v)
+			default:
+				arr, ok := node.([]any)
+				if !ok {
+					return nil, false, "", fmt.Errorf("%s is not an array", desc)
+				}
+				if seg.all {
+					next = append(next, arr...)
+					continue
+				}
+				if seg.index >= len(arr) {
+					return nil, false, "", fmt.Errorf("%s has %d elements, no index %d", desc, len(arr), seg.index)
+				}
+				next = append(next, arr[seg.index])
+			}
+		}
+		if seg.isKey {
+			desc += "." + seg.key
+		} else if seg.all {
+			desc += "[]"
+			isList = true
+		} else {
+			desc += fmt.Sprintf("[%d]", seg.index)
+		}
+		nodes = next
+	}
+	return nodes, isList, desc, nil
+}
+
+type numNeg struct{ x numExpr }
+
+func (n numNeg) eval(env *numEnv) (numValue, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return numValue{}, err
+	}
+	return mapValue(v, func(f float64) float64 { return -f }), nil
+}
+
+type numBinary struct {
+	op   byte
+	l, r numExpr
+}
+
+func (b numBinary) eval(env *numEnv) (numValue, error) {
+	l, err := b.l.eval(env)
+	if err != nil {
+		return numValue{}, err
+	}
+	r, err := b.r.eval(env)
+	if err != nil {
+		return numValue{}, err
+	}
+	n, err := broadcastLen(l, r)
+	if err != nil {
+		return numValue{}, err
+	}
+	out := make([]float64, n)
+	for i := range out {
+		x, y := l.at(i), r.at(i)
+		switch b.op {
+		case '+':
+			out[i] = x + y
+		case '-':
+			out[i] = x - y
+		case '*':
+			out[i] = x * y
+		case '/':
+			if y == 0 {
+				return numValue{}, fmt.Errorf("division by zero")
+			}
+			out[i] = x / y
+		}
+	}
+	if l.list == nil && r.list == nil {
+		return numValue{scalar: out[0]}, nil
+	}
+	return numValue{list: out}, nil
+}
+
+type numCall struct {
+	fn   string
+	args []numExpr
+}
+
+func (c numCall) eval(env *numEnv) (numValue, error) {
+	// count accepts arrays of any values, such as output.items[].
+	if p, ok := c.args[0].(numPath); ok && c.fn == "count" && p.segments[0].key != "metadata" {
+		nodes, _, _, err := p.resolve(env)
+		return numValue{scalar: float64(len(nodes))}, err
+	}
+	args := make([]numValue, len(c.args))
+	for i, a := range c.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return numValue{}, err
+		}
+		args[i] = v
+	}
+	switch c.fn {
+	case "abs":
+		return mapValue(args[0], math.Abs), nil
+	case "round":
+		scale := 1.0
+		if len(args) == 2 {
+			if args[1].list != nil {
+				return numValue{}, fmt.Errorf("round digits must be a number")
+			}
+			scale = math.Pow(10, math.Round(args[1].scalar))
+		}
+		return mapValue(args[0], func(f float64) float64 { return math.Round(f*scale) / scale }), nil
+	case "count":
+		if args[0].list == nil {
+			return numValue{scalar: 1}, nil
+		}
+		return numValue{scalar: float64(len(args[0].list))}, nil
+	}
+
+	// Aggregates accept one list, or several numbers.
+	var xs []float64
+	for _, a := range args {
+		if a.list != nil {
+			xs = append(xs, a.list...)
+		} else {
+			xs = append(xs, a.scalar)
+		}
+	}
+	if c.fn == "sum" {
+		total := 0.0
+		for _, x := range xs {
+			total += x
+		}
+		return numValue{scalar: total}, nil
+	}
+	if len(xs) == 0 {
+		return numValue{}, fmt.Errorf("%s of an empty list", c.fn)
+	}
+	acc := xs[0]
+	for _, x := range xs[1:] {
+		switch c.fn {
+		case "avg":
+			acc += x
+		case "min":
+			acc = math.Min(acc, x)
+		case "max":
+			acc = math.Max(acc, x)
+		}
+	}
+	if c.fn == "avg" {
+		acc /= float64(len(xs))
+	}
+	return numValue{scalar: acc}, nil
+}
+
+func mapValue(v numValue, f func(float64) float64) numValue {
+	if v.list == nil {
+		return numValue{scalar: f(v.scalar)}
+	}
+	out := make([]float64, len(v.list))
+	for i, x := range v.list {
+		out[i] = f(x)
+	}
+	return numValue{list: out}
+}
+
+// numFuncArity gives the minimum and maximum argument counts of each
+// function; -1 is unbounded.
+var numFuncArity = map[string][2]int{
+	"sum": {1, -1}, "avg": {1, -1}, "min": {1, -1}, "max": {1, -1},
+	"count": {1, 1}, "abs": {1, 1}, "round": {1, 2},
+}
+
+// comparisonOps lists the relation operators, two-character ones first.
+var comparisonOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseRelation splits check at its comparison operator and parses both
+// sides.
+func parseRelation(check string) (left numExpr, op string, right numExpr, err error) {
+	at := -1
+	for i := 0; i < len(check) && at < 0; i++ {
+		for _, candidate := range comparisonOps {
+			if strings.HasPrefix(check[i:], candidate) {
+				at, op = i, candidate
+				break
+			}
+		}
+	}
+	if at < 0 {
+		return nil, "", nil, fmt.Errorf("check %q has no comparison (==, !=, <, <=, >, >=)", check)
+	}
+	if left, err = parseNumExpr(check[:at]); err != nil {
+		return nil, "", nil, fmt.Errorf("left side: %v", err)
+	}
+	if right, err = parseNumExpr(check[at+len(op):]); err != nil {
+		return nil, "", nil, fmt.Errorf("right side: %v", err)
+	}
+	return left, op, right, nil
+}
+
+// numParser is a recursive-descent parser for arithmetic expressions.
+type numParser struct {
+	s   string
+	pos int
+}
+
+func parseNumExpr(s string) (numExpr, error) {
+	p := &numParser{s: s}
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos:], p.pos)
+	}
+	return e, nil
+}
+
+func (p *numParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *numParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *numParser) sum() (numExpr, error) {
+	e, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		r, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		e = numBinary{op: op, l: e, r: r}
+	}
+	return e, nil
+}
+
+func (p *numParser) product() (numExpr, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		e = numBinary{op: op, l: e, r: r}
+	}
+	return e, nil
+}
+
+func (p *numParser) unary() (numExpr, error) {
+	if p.peek() == '-' {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return numNeg{x}, nil
+	}
+	return p.primary()
+}
+
+func (p *numParser) primary() (numExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at offset %d", p.pos)
+		}
+		p.pos++
+		return e, nil
+	case c >= '0' && c <= '9' || c == '.':
+		begin := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.s[begin:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.s[begin:p.pos])
+		}
+		return numLiteral(f), nil
+	case isIdentByte(c):
+		ident := p.ident()
+		if p.peek() == '(' {
+			return p.call(ident)
+		}
+		return p.path(ident)
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", string(c), p.pos)
+}
+
+func (p *numParser) call(fn string) (numExpr, error) {
+	arity, ok := numFuncArity[fn]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s (supported: sum, avg, min, max, count, abs, round)", fn)
+	}
+	p.pos++ // '('
+	var args []numExpr
+	if p.peek() != ')' {
+		for {
+			a, err := p.sum()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing ')' after arguments of %s", fn)
+	}
+	p.pos++
+	switch {
+	case len(args) < arity[0]:
+		return nil, fmt.Errorf("%s takes at least %d arguments, got %d", fn, arity[0], len(args))
+	case arity[1] >= 0 && len(args) > arity[1]:
+		return nil, fmt.Errorf("%s takes at most %d arguments, got %d", fn, arity[1], len(args))
+	}
+	return numCall{fn: fn, args: args}, nil
+}
+
+func (p *numParser) path(root string) (numExpr, error) {
+	if root != "output" && root != "input" && root != "metadata" {
+		return nil, fmt.Errorf("unknown field %s (paths start with output, input, or metadata)", root)
+	}
+	begin := p.pos - len(root)
+	segs := []pathSegment{{key: root, isKey: true}}
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '.':
+			p.pos++
+			if p.pos >= len(p.s) || !isIdentByte(p.s[p.pos]) {
+				return nil, fmt.Errorf("missing field name after '.' at offset %d", p.pos)
+			}
+			segs = append(segs, pathSegment{key: p.ident(), isKey: true})
+			continue
+		case '[':
+			end := strings.IndexByte(p.s[p.pos:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ']' at offset %d", p.pos)
+			}
+			inner := strings.TrimSpace(p.s[p.pos+1 : p.pos+end])
+			p.pos += end + 1
+			if inner == "" {
+				segs = append(segs, pathSegment{all: true})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index [%s]", inner)
+			}
+			segs = append(segs, pathSegment{index: i})
+			continue
+		}
+		break
+	}
+	text := p.s[begin:p.pos]
+	if root == "metadata" && len(segs) != 2 {
+		return nil, fmt.Errorf("%s: metadata fields have no nested paths", text)
+	}
+	return numPath{text: text, segments: segs}, nil
+}
+
+func (p *numParser) ident() string {
+	begin := p.pos
+	for p.pos < len(p.s) && (isIdentByte(p.s[p.pos]) || p.s[p.pos] >= '0' && p.s[p.pos] <= '9') {
+		p.pos++
+	}
+	return p.s[begin:p.pos]
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
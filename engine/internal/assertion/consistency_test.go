@@ -0,0 +1,97 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestParseRelation(t *testing.T) {
+	valid := []string{
+		"output.total == sum(output.items[].amount)",
+		"output.items[].amount == output.items[].qty * output.items[].price",
+		"round(output.subtotal * (1 + output.tax_rate), 2) <= output.total",
+		"-output.discount >= -10",
+		"count(output.items[]) != 0",
+		"max(output.a, output.b, 3) > metadata.cost_usd",
+	}
+	for _, check := range valid {
+		if _, _, _, err := parseRelation(check); err != nil {
+			t.Errorf("parseRelation(%q): %v", check, err)
+		}
+	}
+	invalid := []string{
+		"output.total",
+		"output.total = 3",
+		"total == 3",
+		"output.total == sum(",
+		"output.total == median(output.items[].amount)",
+		"output.total == abs(1, 2)",
+		"output.total == (1 + 2",
+		"output.items[x].amount == 1",
+		"metadata.cost.usd == 1",
+		"output. == 1",
+	}
+	for _, check := range invalid {
+		if _, _, _, err := parseRelation(check); err == nil {
+			t.Errorf("parseRelation(%q): want error", check)
+		}
+	}
+}
+
+func TestConsistencyEvaluator(t *testing.T) {
+	evaluator := &ConsistencyEvaluator{}
+	cost := 0.25
+	trace := &types.Trace{
+		TraceID: "trc_test",
+		Input:   json.RawMessage(`{"budget": 100}`),
+		Output: json.RawMessage(`{
+			"items": [
+				{"qty": 2, "price": 10.00, "amount": 20.00},
+				{"qty": 1, "price": "5.50", "amount": 5.50},
+				{"qty": 3, "price": 0.10, "amount": 0.30}
+			],
+			"subtotal": 25.80,
+			"tax_rate": 0.1,
+			"tax": 2.58,
+			"total": 28.39,
+			"note": "paid"
+		}`),
+		Metadata: &types.TraceMetadata{CostUSD: &cost},
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+		wantText   string
+	}{
+		{"sum", `{"relations":[{"check":"output.subtotal == sum(output.items[].amount)"}]}`, types.StatusPass, ""},
+		{"line items", `{"relations":[{"check":"output.items[].amount == output.items[].qty * output.items[].price"}]}`, types.StatusPass, ""},
+		{"total off by a cent", `{"relations":[{"check":"output.total == output.subtotal + output.tax"}]}`, types.StatusHardFail, "28.39 == 28.38"},
+		{"total within tolerance", `{"relations":[{"check":"output.total == output.subtotal + output.tax"}],"tolerance":0.01}`, types.StatusPass, ""},
+		{"relative tolerance", `{"relations":[{"check":"output.total == output.subtotal + output.tax","relative_tolerance":0.001}]}`, types.StatusPass, ""},
+		{"round", `{"relations":[{"check":"output.tax == round(output.subtotal * output.tax_rate, 2)"}]}`, types.StatusPass, ""},
+		{"input and metadata", `{"relations":[{"check":"output.total <= input.budget"},{"check":"metadata.cost_usd < 1"}]}`, types.StatusPass, ""},
+		{"aggregates", `{"relations":[{"check":"count(output.items[]) == 3"},{"check":"max(output.items[].qty) == 3"},{"check":"avg(output.items[].qty) == 2"}]}`, types.StatusPass, ""},
+		{"element mismatch", `{"relations":[{"check":"output.items[].amount == output.items[].price"}],"soft":true}`, types.StatusSoftFail, "[0] 20 == 10"},
+		{"missing field", `{"relations":[{"check":"output.shipping == 0"}]}`, types.StatusHardFail, "output.shipping not found"},
+		{"not a number", `{"relations":[{"check":"output.note == 0"}]}`, types.StatusHardFail, "not a number"},
+		{"length mismatch", `{"relations":[{"check":"output.items[].qty == output.items[].qty + output.items[0].qty"},{"check":"sum(output.items[].qty) == output.items[].qty"}]}`, types.StatusHardFail, "[0] 6 == 2"},
+		{"division by zero", `{"relations":[{"check":"output.total / 0 == 1"}]}`, types.StatusHardFail, "division by zero"},
+		{"no relations", `{"relations":[]}`, types.StatusHardFail, "at least one relation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeConsistency, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if !strings.Contains(result.Explanation, tt.wantText) {
+				t.Errorf("explanation %q does not contain %q", result.Explanation, tt.wantText)
+			}
+		})
+	}
+}
@@ -3,30 +3,62 @@ package assertion
 import (
 	"github.com/segmentio/encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/attest-ai/attest/engine/internal/cache"
+	tracepkg "github.com/attest-ai/attest/engine/internal/trace"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
 // stepTypeFilterRegex matches steps[?type=='<type>'].length
 var stepTypeFilterRegex = regexp.MustCompile(`^steps\[\?type=='([^']+)'\]\.length$`)
 
-// ConstraintEvaluator implements Layer 2: numeric constraint checks.
-type ConstraintEvaluator struct{}
+// stepStatusFilterRegex matches steps[?status=='error'|'ok'].length
+var stepStatusFilterRegex = regexp.MustCompile(`^steps\[\?status=='(error|ok)'\]\.length$`)
+
+// stepPathRegex matches a step selector followed by a path into one of the step's
+// JSON sections: steps[*].<section>.<path>, steps[?name=='<name>'].<section>.<path>,
+// or steps[?type=='<type>'].<section>.<path>, where section is args, result, or metadata.
+var stepPathRegex = regexp.MustCompile(`^steps(?:\[\*\]|\[\?(name|type)=='([^']+)'\])\.(args|result|metadata)(?:\.(.+))?$`)
+
+// aggregateRegex matches <fn>(<step path>) where fn is sum, avg, min, or max.
+var aggregateRegex = regexp.MustCompile(`^(sum|avg|min|max)\((.+)\)$`)
+
+// structuredOperators are the constraint operators that compare strings and
+// booleans or test for field existence instead of comparing numbers.
+var structuredOperators = map[string]bool{
+	"str_eq":     true,
+	"str_ne":     true,
+	"bool_eq":    true,
+	"bool_ne":    true,
+	"exists":     true,
+	"not_exists": true,
+}
+
+// ConstraintEvaluator implements Layer 2: numeric, string, boolean, and existence constraint checks.
+// history is optional and only used by "threshold":"dynamic" numeric checks.
+type ConstraintEvaluator struct {
+	history *cache.HistoryStore
+}
 
 func (e *ConstraintEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
 	start := time.Now()
 
 	var spec struct {
-		Field    string   `json:"field"`
-		Operator string   `json:"operator"`
-		Value    *float64 `json:"value,omitempty"`
-		Min      *float64 `json:"min,omitempty"`
-		Max      *float64 `json:"max,omitempty"`
-		Soft     bool     `json:"soft"`
+		Field    string          `json:"field"`
+		Operator string          `json:"operator"`
+		Value    json.RawMessage `json:"value,omitempty"`
+		Min      json.RawMessage `json:"min,omitempty"`
+		Max      json.RawMessage `json:"max,omitempty"`
+		Soft     bool            `json:"soft"`
+		// Dynamic threshold fields: compare against the assertion's recorded values.
+		Threshold string  `json:"threshold"`
+		Sigma     float64 `json:"sigma"`
+		Direction string  `json:"direction"`
 	}
 	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
 		return failResult(assertion, start, fmt.Sprintf("invalid constraint spec: %v", err))
@@ -38,60 +70,119 @@ func (e *ConstraintEvaluator) Evaluate(trace *types.Trace, assertion *types.Asse
 		return failResult(assertion, start, "constraint spec missing required field: operator")
 	}
 
+	failStatus := types.StatusHardFail
+	if spec.Soft {
+		failStatus = types.StatusSoftFail
+	}
+
+	if structuredOperators[spec.Operator] {
+		passed, explanation, err := evaluateStructuredConstraint(trace, spec.Field, spec.Operator, spec.Value)
+		if err != nil {
+			return failResult(assertion, start, err.Error())
+		}
+		return constraintResult(assertion, start, passed, explanation, failStatus)
+	}
+
+	value, err := decodeThreshold(spec.Value, spec.Field, spec.Operator, "value")
+	if err != nil {
+		return failResult(assertion, start, err.Error())
+	}
+	minVal, err := decodeThreshold(spec.Min, spec.Field, spec.Operator, "min")
+	if err != nil {
+		return failResult(assertion, start, err.Error())
+	}
+	maxVal, err := decodeThreshold(spec.Max, spec.Field, spec.Operator, "max")
+	if err != nil {
+		return failResult(assertion, start, err.Error())
+	}
+
 	actualVal, err := resolveConstraintField(trace, spec.Field)
 	if err != nil {
 		return failResult(assertion, start, fmt.Sprintf("field resolution failed: %v", err))
 	}
 
-	failStatus := types.StatusHardFail
-	if spec.Soft {
-		failStatus = types.StatusSoftFail
+	if spec.Threshold == "dynamic" {
+		var static func() (bool, string)
+		if value != nil || (minVal != nil && maxVal != nil) {
+			static = func() (bool, string) {
+				passed, explanation, _ := compareNumeric(spec.Field, actualVal, spec.Operator, value, minVal, maxVal)
+				return passed, explanation
+			}
+		}
+		dyn := dynamicNumericSpec{Sigma: spec.Sigma, Direction: spec.Direction}
+		if err := dyn.resolveDirection(spec.Operator); err != nil {
+			return failResult(assertion, start, err.Error())
+		}
+		passed, explanation := evaluateDynamicNumeric(e.history, trace.TraceID, assertion.AssertionID, spec.Field, actualVal, dyn, static)
+		return constraintResult(assertion, start, passed, explanation, failStatus)
+	}
+
+	passed, explanation, err := compareNumeric(spec.Field, actualVal, spec.Operator, value, minVal, maxVal)
+	if err != nil {
+		return failResult(assertion, start, err.Error())
 	}
+	return constraintResult(assertion, start, passed, explanation, failStatus)
+}
 
+// compareNumeric applies a numeric operator to actual. value is required for
+// lt/lte/gt/gte/eq; minVal and maxVal are required for between.
+func compareNumeric(field string, actual float64, operator string, value, minVal, maxVal *float64) (bool, string, error) {
 	var passed bool
 	var explanation string
 
-	switch spec.Operator {
-	case "lt":
-		if spec.Value == nil {
-			return failResult(assertion, start, "operator 'lt' requires 'value'")
-		}
-		passed = actualVal < *spec.Value
-		explanation = fmt.Sprintf("%s = %s, constraint lt %s", spec.Field, formatFloat(actualVal), formatFloat(*spec.Value))
-	case "lte":
-		if spec.Value == nil {
-			return failResult(assertion, start, "operator 'lte' requires 'value'")
-		}
-		passed = actualVal <= *spec.Value
-		explanation = fmt.Sprintf("%s = %s, constraint lte %s", spec.Field, formatFloat(actualVal), formatFloat(*spec.Value))
-	case "gt":
-		if spec.Value == nil {
-			return failResult(assertion, start, "operator 'gt' requires 'value'")
-		}
-		passed = actualVal > *spec.Value
-		explanation = fmt.Sprintf("%s = %s, constraint gt %s", spec.Field, formatFloat(actualVal), formatFloat(*spec.Value))
-	case "gte":
-		if spec.Value == nil {
-			return failResult(assertion, start, "operator 'gte' requires 'value'")
-		}
-		passed = actualVal >= *spec.Value
-		explanation = fmt.Sprintf("%s = %s, constraint gte %s", spec.Field, formatFloat(actualVal), formatFloat(*spec.Value))
-	case "eq":
-		if spec.Value == nil {
-			return failResult(assertion, start, "operator 'eq' requires 'value'")
-		}
-		passed = actualVal == *spec.Value
-		explanation = fmt.Sprintf("%s = %s, constraint eq %s", spec.Field, formatFloat(actualVal), formatFloat(*spec.Value))
+	switch operator {
+	case "lt", "lte", "gt", "gte", "eq":
+		if value == nil {
+			return false, "", fmt.Errorf("operator '%s' requires 'value'", operator)
+		}
+		switch operator {
+		case "lt":
+			passed = actual < *value
+		case "lte":
+			passed = actual <= *value
+		case "gt":
+			passed = actual > *value
+		case "gte":
+			passed = actual >= *value
+		case "eq":
+			passed = actual == *value
+		}
+		explanation = fmt.Sprintf("%s = %s, constraint %s %s", field, formatFloat(actual), operator, formatFloat(*value))
 	case "between":
-		if spec.Min == nil || spec.Max == nil {
-			return failResult(assertion, start, "operator 'between' requires 'min' and 'max'")
+		if minVal == nil || maxVal == nil {
+			return false, "", fmt.Errorf("operator 'between' requires 'min' and 'max'")
 		}
-		passed = actualVal >= *spec.Min && actualVal <= *spec.Max
-		explanation = fmt.Sprintf("%s = %s, constraint between [%s, %s]", spec.Field, formatFloat(actualVal), formatFloat(*spec.Min), formatFloat(*spec.Max))
+		passed = actual >= *minVal && actual <= *maxVal
+		explanation = fmt.Sprintf("%s = %s, constraint between [%s, %s]", field, formatFloat(actual), formatFloat(*minVal), formatFloat(*maxVal))
 	default:
-		return failResult(assertion, start, fmt.Sprintf("unsupported operator: %s", spec.Operator))
+		return false, "", fmt.Errorf("unsupported operator: %s", operator)
 	}
+	return passed, explanation, nil
+}
+
+// decodeThreshold decodes a numeric threshold (value, min, or max). Duration strings
+// such as "1.5s" are accepted for millisecond fields and converted to milliseconds.
+// Returns nil when the threshold is absent.
+func decodeThreshold(raw json.RawMessage, field, operator, name string) (*float64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return &v, nil
+	}
+	if isDurationField(field) {
+		ms, err := decodeDurationMS(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' for %s: %v", name, field, err)
+		}
+		return &ms, nil
+	}
+	return nil, fmt.Errorf("operator '%s' requires a numeric '%s', got %s", operator, name, truncateRaw(raw))
+}
 
+// constraintResult builds the pass/fail result for a constraint check.
+func constraintResult(assertion *types.Assertion, start time.Time, passed bool, explanation, failStatus string) *types.AssertionResult {
 	if !passed {
 		return &types.AssertionResult{
 			AssertionID: assertion.AssertionID,
@@ -113,6 +204,68 @@ func (e *ConstraintEvaluator) Evaluate(trace *types.Trace, assertion *types.Asse
 	}
 }
 
+// evaluateStructuredConstraint applies a string, boolean, or existence operator to field.
+// Returns an error for malformed specs or unsupported field paths; a missing field
+// is not an error for exists/not_exists.
+func evaluateStructuredConstraint(trace *types.Trace, field, operator string, value json.RawMessage) (bool, string, error) {
+	raw, found, err := resolveConstraintRaw(trace, field)
+	if err != nil {
+		return false, "", fmt.Errorf("field resolution failed: %v", err)
+	}
+	if found && string(raw) == "null" {
+		found = false
+	}
+
+	switch operator {
+	case "exists":
+		if !found {
+			return false, fmt.Sprintf("%s does not exist, constraint exists", field), nil
+		}
+		return true, fmt.Sprintf("%s exists, constraint exists", field), nil
+	case "not_exists":
+		if found {
+			return false, fmt.Sprintf("%s = %s, constraint not_exists", field, truncateRaw(raw)), nil
+		}
+		return true, fmt.Sprintf("%s does not exist, constraint not_exists", field), nil
+	}
+
+	if len(value) == 0 || string(value) == "null" {
+		return false, "", fmt.Errorf("operator '%s' requires 'value'", operator)
+	}
+	if !found {
+		return false, "", fmt.Errorf("field resolution failed: %s is not set", field)
+	}
+
+	switch operator {
+	case "str_eq", "str_ne":
+		var want, got string
+		if err := json.Unmarshal(value, &want); err != nil {
+			return false, "", fmt.Errorf("operator '%s' requires a string 'value'", operator)
+		}
+		if err := json.Unmarshal(raw, &got); err != nil {
+			return false, "", fmt.Errorf("%s is not a string: %s", field, truncateRaw(raw))
+		}
+		passed := got == want
+		if operator == "str_ne" {
+			passed = !passed
+		}
+		return passed, fmt.Sprintf("%s = %q, constraint %s %q", field, got, operator, want), nil
+	default: // bool_eq, bool_ne
+		var want, got bool
+		if err := json.Unmarshal(value, &want); err != nil {
+			return false, "", fmt.Errorf("operator '%s' requires a boolean 'value'", operator)
+		}
+		if err := json.Unmarshal(raw, &got); err != nil {
+			return false, "", fmt.Errorf("%s is not a boolean: %s", field, truncateRaw(raw))
+		}
+		passed := got == want
+		if operator == "bool_ne" {
+			passed = !passed
+		}
+		return passed, fmt.Sprintf("%s = %t, constraint %s %t", field, got, operator, want), nil
+	}
+}
+
 // resolveConstraintField resolves a constraint field path to a float64 value.
 func resolveConstraintField(trace *types.Trace, field string) (float64, error) {
 	switch field {
@@ -134,6 +287,16 @@ func resolveConstraintField(trace *types.Trace, field string) (float64, error) {
 		}
 		return float64(*trace.Metadata.LatencyMS), nil
 
+	case "metadata.age_seconds":
+		if trace.Metadata == nil || trace.Metadata.Timestamp == nil {
+			return 0, fmt.Errorf("metadata.timestamp is not set")
+		}
+		ts, err := tracepkg.ParseTimestamp(*trace.Metadata.Timestamp)
+		if err != nil {
+			return 0, fmt.Errorf("metadata.age_seconds: %v", err)
+		}
+		return time.Since(ts).Seconds(), nil
+
 	case "steps.length":
 		return float64(len(trace.Steps)), nil
 	}
@@ -150,9 +313,227 @@ func resolveConstraintField(trace *types.Trace, field string) (float64, error) {
 		return float64(count), nil
 	}
 
+	// steps[?status=='error'|'ok'].length — steps with or without an error.
+	if m := stepStatusFilterRegex.FindStringSubmatch(field); m != nil {
+		count := 0
+		for i := range trace.Steps {
+			if (trace.Steps[i].Error != nil) == (m[1] == "error") {
+				count++
+			}
+		}
+		return float64(count), nil
+	}
+
+	// sum|avg|min|max(<step path>)
+	if m := aggregateRegex.FindStringSubmatch(field); m != nil {
+		return resolveAggregate(trace, m[1], m[2])
+	}
+
+	// steps[?name=='<name>'].<section>.<path> — first matching step.
+	if m := stepPathRegex.FindStringSubmatch(field); m != nil {
+		if m[1] != "name" {
+			return 0, fmt.Errorf("field %s selects multiple steps; wrap it in sum(), avg(), min(), or max()", field)
+		}
+		for i := range trace.Steps {
+			if trace.Steps[i].Name == m[2] {
+				return stepNumericValue(&trace.Steps[i], m[3], m[4])
+			}
+		}
+		return 0, fmt.Errorf("step not found: %s", m[2])
+	}
+
+	// output or output.<path>
+	if field == "output" || strings.HasPrefix(field, "output.") {
+		raw, err := ResolveTarget(trace, field)
+		if err != nil {
+			return 0, err
+		}
+		return parseNumeric(raw, field)
+	}
+
 	return 0, fmt.Errorf("unsupported constraint field: %s", field)
 }
 
+// resolveConstraintRaw resolves a field to its raw JSON value for string, boolean,
+// and existence checks. Supports output[.<path>], metadata.<key>, and
+// steps[?name=='<name>'].<section>[.<path>]. found is false when the field is absent;
+// err is non-nil only for unsupported field syntax.
+func resolveConstraintRaw(trace *types.Trace, field string) (raw json.RawMessage, found bool, err error) {
+	switch {
+	case field == "output":
+		return trace.Output, len(trace.Output) > 0, nil
+	case strings.HasPrefix(field, "output."):
+		raw, found = lookupDotPath(trace.Output, field[len("output."):])
+		return raw, found, nil
+	case strings.HasPrefix(field, "metadata."):
+		if trace.Metadata == nil {
+			return nil, false, nil
+		}
+		metaRaw, mErr := json.Marshal(trace.Metadata)
+		if mErr != nil {
+			return nil, false, fmt.Errorf("cannot serialize trace metadata: %v", mErr)
+		}
+		raw, found = lookupDotPath(metaRaw, field[len("metadata."):])
+		return raw, found, nil
+	}
+
+	m := stepPathRegex.FindStringSubmatch(field)
+	if m == nil {
+		return nil, false, fmt.Errorf("unsupported constraint field: %s", field)
+	}
+	if m[1] != "name" {
+		return nil, false, fmt.Errorf("field %s must select a step by name", field)
+	}
+	for i := range trace.Steps {
+		step := &trace.Steps[i]
+		if step.Name != m[2] {
+			continue
+		}
+		var section json.RawMessage
+		switch m[3] {
+		case "args":
+			section = step.Args
+		case "result":
+			section = step.Result
+		case "metadata":
+			section = step.Metadata
+		}
+		if m[4] == "" {
+			return section, len(section) > 0, nil
+		}
+		raw, found = lookupDotPath(section, m[4])
+		return raw, found, nil
+	}
+	return nil, false, nil
+}
+
+// lookupDotPath follows a dot-separated key path into a JSON object.
+// Returns found=false when any segment is missing or not an object.
+func lookupDotPath(raw json.RawMessage, path string) (json.RawMessage, bool) {
+	for _, key := range strings.Split(path, ".") {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, false
+		}
+		val, ok := obj[key]
+		if !ok {
+			return nil, false
+		}
+		raw = val
+	}
+	return raw, true
+}
+
+// resolveAggregate applies fn (sum, avg, min, max) to a numeric path across all
+// steps matched by the selector in path. Steps without the value are skipped;
+// an error is returned when no step provides it.
+func resolveAggregate(trace *types.Trace, fn, path string) (float64, error) {
+	m := stepPathRegex.FindStringSubmatch(path)
+	if m == nil {
+		return 0, fmt.Errorf("%s() argument must be a step path such as steps[*].metadata.tokens_out, got %s", fn, path)
+	}
+	filterKind, filterValue, section, subPath := m[1], m[2], m[3], m[4]
+
+	var values []float64
+	for i := range trace.Steps {
+		step := &trace.Steps[i]
+		switch filterKind {
+		case "name":
+			if step.Name != filterValue {
+				continue
+			}
+		case "type":
+			if step.Type != filterValue {
+				continue
+			}
+		}
+		v, err := stepNumericValue(step, section, subPath)
+		if err != nil {
+			continue
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("%s(%s): no matching step has a numeric value", fn, path)
+	}
+
+	result := values[0]
+	switch fn {
+	case "sum", "avg":
+		result = 0
+		for _, v := range values {
+			result += v
+		}
+		if fn == "avg" {
+			result /= float64(len(values))
+		}
+	case "min":
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+	case "max":
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+	}
+	return result, nil
+}
+
+// stepNumericValue resolves a numeric value from a step's args, result, or metadata.
+// Metadata paths use normalized StepMetadata keys first and fall back to the raw JSON.
+func stepNumericValue(step *types.Step, section, path string) (float64, error) {
+	var raw json.RawMessage
+	switch section {
+	case "args":
+		raw = step.Args
+	case "result":
+		raw = step.Result
+	case "metadata":
+		if path != "" && !strings.Contains(path, ".") {
+			meta, err := step.TypedMetadata()
+			if err != nil {
+				return 0, fmt.Errorf("step %q: %v", step.Name, err)
+			}
+			if v, ok := meta.Numeric(path); ok {
+				return v, nil
+			}
+		}
+		raw = step.Metadata
+	}
+
+	desc := fmt.Sprintf("steps[?name=='%s'].%s", step.Name, section)
+	if path == "" {
+		return parseNumeric(raw, desc)
+	}
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return 0, fmt.Errorf("cannot parse %s as object: %v", desc, err)
+	}
+	val, err := navigateDotPath(nil, root, path, desc)
+	if err != nil {
+		return 0, err
+	}
+	return parseNumeric(val, desc+"."+path)
+}
+
+// parseNumeric decodes a JSON number, reporting desc in the error when the value is not numeric.
+func parseNumeric(raw json.RawMessage, desc string) (float64, error) {
+	var v float64
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, fmt.Errorf("%s is not a number: %s", desc, truncateRaw(raw))
+	}
+	return v, nil
+}
+
+// truncateRaw returns raw JSON as a string, shortened for error messages.
+func truncateRaw(raw json.RawMessage) string {
+	const maxLen = 64
+	if len(raw) > maxLen {
+		return string(raw[:maxLen]) + "..."
+	}
+	return string(raw)
+}
+
 // formatFloat formats a float64 for display, trimming trailing zeros.
 func formatFloat(v float64) string {
 	s := strconv.FormatFloat(v, 'f', -1, 64)
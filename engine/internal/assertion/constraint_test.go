@@ -3,6 +3,7 @@ package assertion
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
@@ -12,6 +13,7 @@ func TestConstraintEvaluator(t *testing.T) {
 
 	intPtr := func(v int) *int { return &v }
 	float64Ptr := func(v float64) *float64 { return &v }
+	stringPtr := func(v string) *string { return &v }
 
 	makeTrace := func(meta *types.TraceMetadata, steps []types.Step) *types.Trace {
 		return &types.Trace{
@@ -114,6 +116,24 @@ func TestConstraintEvaluator(t *testing.T) {
 			spec:  `{"field":"metadata.latency_ms","operator":"lte","value":5000,"soft":true}`,
 			wantStatus: types.StatusSoftFail,
 		},
+		{
+			name: "age_seconds passes for a recent trace",
+			trace: makeTrace(&types.TraceMetadata{Timestamp: stringPtr(time.Now().Add(-time.Hour).Format(time.RFC3339))}, nil),
+			spec:  `{"field":"metadata.age_seconds","operator":"between","min":3500,"max":3700}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "age_seconds reads zoneless timestamps as UTC",
+			trace: makeTrace(&types.TraceMetadata{Timestamp: stringPtr(time.Now().UTC().Add(-48 * time.Hour).Format("2006-01-02 15:04:05"))}, nil),
+			spec:  `{"field":"metadata.age_seconds","operator":"lte","value":86400}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "age_seconds fails on unparseable timestamp",
+			trace: makeTrace(&types.TraceMetadata{Timestamp: stringPtr("yesterday")}, nil),
+			spec:  `{"field":"metadata.age_seconds","operator":"gte","value":0}`,
+			wantStatus: types.StatusHardFail,
+		},
 		{
 			name: "missing metadata field fails",
 			trace: makeTrace(nil, nil),
@@ -129,6 +149,24 @@ func TestConstraintEvaluator(t *testing.T) {
 			spec:  `{"field":"steps.length","operator":"eq","value":2}`,
 			wantStatus: types.StatusPass,
 		},
+		{
+			name: "error step count passes",
+			trace: makeTrace(nil, []types.Step{
+				{Name: "step1", Type: types.StepTypeToolCall, Error: &types.StepError{Message: "boom"}},
+				{Name: "step2", Type: types.StepTypeLLMCall},
+				{Name: "step3", Type: types.StepTypeToolCall, Error: &types.StepError{Message: "boom"}},
+			}),
+			spec:  `{"field":"steps[?status=='error'].length","operator":"eq","value":2}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "ok step count fails",
+			trace: makeTrace(nil, []types.Step{
+				{Name: "step1", Type: types.StepTypeToolCall, Error: &types.StepError{Message: "boom"}},
+			}),
+			spec:  `{"field":"steps[?status=='ok'].length","operator":"gte","value":1}`,
+			wantStatus: types.StatusHardFail,
+		},
 		{
 			name: "filtered step count passes",
 			trace: makeTrace(nil, []types.Step{
@@ -148,6 +186,30 @@ func TestConstraintEvaluator(t *testing.T) {
 			spec:  `{"field":"steps[?type=='tool_call'].length","operator":"gt","value":5}`,
 			wantStatus: types.StatusHardFail,
 		},
+		{
+			name: "step metadata tokens_out passes",
+			trace: makeTrace(nil, []types.Step{
+				{Name: "generate", Type: types.StepTypeLLMCall, Metadata: json.RawMessage(`{"completion_tokens":120}`)},
+			}),
+			spec:       `{"field":"steps[?name=='generate'].metadata.tokens_out","operator":"lte","value":200}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "step metadata from nested usage fails",
+			trace: makeTrace(nil, []types.Step{
+				{Name: "generate", Type: types.StepTypeLLMCall, Metadata: json.RawMessage(`{"usage":{"input_tokens":900}}`)},
+			}),
+			spec:       `{"field":"steps[?name=='generate'].metadata.tokens_in","operator":"lt","value":500}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "step metadata missing key fails",
+			trace: makeTrace(nil, []types.Step{
+				{Name: "generate", Type: types.StepTypeLLMCall, Metadata: json.RawMessage(`{"model":"gpt-4.1"}`)},
+			}),
+			spec:       `{"field":"steps[?name=='generate'].metadata.retry_count","operator":"eq","value":0}`,
+			wantStatus: types.StatusHardFail,
+		},
 		{
 			name: "unsupported field fails",
 			trace: makeTrace(nil, nil),
@@ -166,3 +228,96 @@ func TestConstraintEvaluator(t *testing.T) {
 		})
 	}
 }
+
+func TestConstraintEvaluator_NumericPathsAndAggregates(t *testing.T) {
+	evaluator := &ConstraintEvaluator{}
+
+	trace := &types.Trace{
+		TraceID: "trc_paths",
+		Output:  json.RawMessage(`{"message":"ok","confidence":0.92,"stats":{"citations":3}}`),
+		Steps: []types.Step{
+			{Name: "plan", Type: types.StepTypeLLMCall, Metadata: json.RawMessage(`{"prompt_tokens":100,"completion_tokens":40}`)},
+			{Name: "search", Type: types.StepTypeToolCall, Result: json.RawMessage(`{"hits":12}`), Metadata: json.RawMessage(`{"duration_ms":250}`)},
+			{Name: "answer", Type: types.StepTypeLLMCall, Metadata: json.RawMessage(`{"prompt_tokens":300,"completion_tokens":80}`)},
+			{Name: "search", Type: types.StepTypeToolCall, Result: json.RawMessage(`{"hits":4}`)},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"output field", `{"field":"output.confidence","operator":"gte","value":0.9}`, types.StatusPass},
+		{"nested output field", `{"field":"output.stats.citations","operator":"eq","value":3}`, types.StatusPass},
+		{"non-numeric output field", `{"field":"output.message","operator":"eq","value":1}`, types.StatusHardFail},
+		{"first named step result", `{"field":"steps[?name=='search'].result.hits","operator":"eq","value":12}`, types.StatusPass},
+		{"raw metadata fallback", `{"field":"steps[?name=='search'].metadata.duration_ms","operator":"lt","value":300}`, types.StatusPass},
+		{"type selector without aggregate", `{"field":"steps[?type=='llm_call'].metadata.tokens_out","operator":"lt","value":1000}`, types.StatusHardFail},
+		{"sum over type", `{"field":"sum(steps[?type=='llm_call'].metadata.tokens_out)","operator":"eq","value":120}`, types.StatusPass},
+		{"avg over name", `{"field":"avg(steps[?name=='search'].result.hits)","operator":"eq","value":8}`, types.StatusPass},
+		{"max over all steps", `{"field":"max(steps[*].metadata.tokens_in)","operator":"eq","value":300}`, types.StatusPass},
+		{"min over all steps", `{"field":"min(steps[*].result.hits)","operator":"eq","value":4}`, types.StatusPass},
+		{"aggregate with no values", `{"field":"sum(steps[*].metadata.retry_count)","operator":"eq","value":0}`, types.StatusHardFail},
+		{"aggregate over non-step path", `{"field":"sum(output.confidence)","operator":"eq","value":0}`, types.StatusHardFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &types.Assertion{AssertionID: "assert_path", Type: types.TypeConstraint, Spec: json.RawMessage(tt.spec)}
+			result := evaluator.Evaluate(trace, a)
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
+func TestConstraintEvaluator_StringBoolExistence(t *testing.T) {
+	evaluator := &ConstraintEvaluator{}
+
+	model := "gpt-4.1"
+	trace := &types.Trace{
+		TraceID:  "trc_struct",
+		Output:   json.RawMessage(`{"status":"completed","escalated":false,"ticket":null,"meta":{"lang":"en"}}`),
+		Metadata: &types.TraceMetadata{Model: &model},
+		Steps: []types.Step{
+			{Name: "lookup", Type: types.StepTypeToolCall, Result: json.RawMessage(`{"found":true,"region":"eu"}`)},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"str_eq passes", `{"field":"output.status","operator":"str_eq","value":"completed"}`, types.StatusPass},
+		{"str_eq fails", `{"field":"output.status","operator":"str_eq","value":"pending"}`, types.StatusHardFail},
+		{"str_ne passes", `{"field":"output.meta.lang","operator":"str_ne","value":"fr"}`, types.StatusPass},
+		{"str_eq on trace metadata", `{"field":"metadata.model","operator":"str_eq","value":"gpt-4.1"}`, types.StatusPass},
+		{"str_eq on step result", `{"field":"steps[?name=='lookup'].result.region","operator":"str_eq","value":"eu"}`, types.StatusPass},
+		{"str_eq type mismatch", `{"field":"output.escalated","operator":"str_eq","value":"false"}`, types.StatusHardFail},
+		{"str_eq non-string value", `{"field":"output.status","operator":"str_eq","value":1}`, types.StatusHardFail},
+		{"bool_eq passes", `{"field":"steps[?name=='lookup'].result.found","operator":"bool_eq","value":true}`, types.StatusPass},
+		{"bool_ne passes", `{"field":"output.escalated","operator":"bool_ne","value":true}`, types.StatusPass},
+		{"bool_eq fails", `{"field":"output.escalated","operator":"bool_eq","value":true}`, types.StatusHardFail},
+		{"exists passes", `{"field":"output.status","operator":"exists"}`, types.StatusPass},
+		{"exists fails on null", `{"field":"output.ticket","operator":"exists"}`, types.StatusHardFail},
+		{"not_exists passes on missing", `{"field":"output.error","operator":"not_exists"}`, types.StatusPass},
+		{"not_exists passes on missing step", `{"field":"steps[?name=='refund'].result","operator":"not_exists"}`, types.StatusPass},
+		{"not_exists fails", `{"field":"output.status","operator":"not_exists","soft":true}`, types.StatusSoftFail},
+		{"missing value", `{"field":"output.status","operator":"str_eq"}`, types.StatusHardFail},
+		{"unsupported field", `{"field":"input.x","operator":"exists"}`, types.StatusHardFail},
+		{"numeric operator rejects string value", `{"field":"output.status","operator":"eq","value":"completed"}`, types.StatusHardFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &types.Assertion{AssertionID: "assert_struct", Type: types.TypeConstraint, Spec: json.RawMessage(tt.spec)}
+			result := evaluator.Evaluate(trace, a)
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package assertion
 import (
 	"github.com/segmentio/encoding/json"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
@@ -14,7 +13,12 @@ import (
 const MaxRegexPatternLength = 10000
 
 // ContentEvaluator implements Layer 4 content matching assertions.
-type ContentEvaluator struct{}
+type ContentEvaluator struct {
+	// regexBudget bounds each regex_match check; zero means DefaultRegexBudget.
+	regexBudget time.Duration
+	// links checks liveness for urls_valid; nil keeps it offline.
+	links *linkChecker
+}
 
 func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
 	start := time.Now()
@@ -26,6 +30,7 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 		Values        []string `json:"values,omitempty"`
 		Soft          bool     `json:"soft"`
 		CaseSensitive bool     `json:"case_sensitive"`
+		TimeoutMS     int      `json:"timeout_ms,omitempty"`
 	}
 	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
 		return failResult(assertion, start, fmt.Sprintf("invalid content spec: %v", err))
@@ -36,6 +41,14 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 	if spec.Check == "" {
 		return failResult(assertion, start, "content spec missing required field: check")
 	}
+	switch spec.Check {
+	case "numeric_match":
+		return evaluateNumericMatch(trace, assertion, start)
+	case "date_match":
+		return evaluateDateMatch(trace, assertion, start)
+	case "urls_valid":
+		return e.evaluateURLsValid(trace, assertion, start)
+	}
 
 	targetStr, err := ResolveTargetString(trace, spec.Target)
 	if err != nil {
@@ -53,6 +66,7 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 	if spec.Soft {
 		failStatus = types.StatusSoftFail
 	}
+	excerpts := newExcerpter(targetStr, compareTarget, spec.CaseSensitive)
 
 	switch spec.Check {
 	case "contains":
@@ -64,6 +78,7 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 			Status:      failStatus,
 			Score:       0.0,
 			Explanation: fmt.Sprintf("%s does not contain '%s'.", spec.Target, spec.Value),
+			Excerpts:    excerpts.missing([]string{spec.Value}),
 			DurationMS:  time.Since(start).Milliseconds(),
 			RequestID:   assertion.RequestID,
 		}
@@ -77,6 +92,7 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 			Status:      failStatus,
 			Score:       0.0,
 			Explanation: fmt.Sprintf("%s contains '%s' but should not.", spec.Target, spec.Value),
+			Excerpts:    excerpts.found([]string{spec.Value}),
 			DurationMS:  time.Since(start).Milliseconds(),
 			RequestID:   assertion.RequestID,
 		}
@@ -86,14 +102,25 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 		if len(spec.Value) > MaxRegexPatternLength {
 			return failResult(assertion, start, fmt.Sprintf("regex pattern exceeds maximum length: %d > %d", len(spec.Value), MaxRegexPatternLength))
 		}
-		re, err := regexp.Compile(spec.Value)
+		re, err := sharedRegexCache.compile(spec.Value)
 		if err != nil {
 			return failResult(assertion, start, fmt.Sprintf("invalid regex '%s': %v", spec.Value, err))
 		}
-		if re.MatchString(targetStr) {
+		budget := e.regexBudget
+		if budget == 0 {
+			budget = DefaultRegexBudget
+		}
+		if spec.TimeoutMS > 0 {
+			budget = time.Duration(spec.TimeoutMS) * time.Millisecond
+		}
+		matched, timedOut := matchWithBudget(re, targetStr, budget)
+		if timedOut {
+			return failResult(assertion, start, fmt.Sprintf("regex '%s' exceeded time budget of %dms on %d-byte target", spec.Value, budget.Milliseconds(), len(targetStr)))
+		}
+		if matched {
 			return passResult(assertion, start, fmt.Sprintf("%s matches regex '%s'.", spec.Target, spec.Value))
 		}
-		return &types.AssertionResult{
+		ar := &types.AssertionResult{
 			AssertionID: assertion.AssertionID,
 			Status:      failStatus,
 			Score:       0.0,
@@ -101,16 +128,19 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 			DurationMS:  time.Since(start).Milliseconds(),
 			RequestID:   assertion.RequestID,
 		}
+		// The closest region is where the pattern's literal prefix, if any, occurs.
+		if prefix, _ := re.LiteralPrefix(); prefix != "" {
+			if e, ok := newExcerpter(targetStr, targetStr, true).closest(spec.Value, prefix); ok {
+				ar.Excerpts = []types.Excerpt{e}
+			}
+		}
+		return ar
 
 	case "keyword_all":
 		missing := []string{}
-		for _, kw := range spec.Values {
-			cmpKW := kw
-			if !spec.CaseSensitive {
-				cmpKW = strings.ToLower(kw)
-			}
-			if !strings.Contains(compareTarget, cmpKW) {
-				missing = append(missing, kw)
+		for i, ok := range keywordsPresent(compareTarget, spec.Values, spec.CaseSensitive) {
+			if !ok {
+				missing = append(missing, spec.Values[i])
 			}
 		}
 		if len(missing) == 0 {
@@ -121,18 +151,15 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 			Status:      failStatus,
 			Score:       float64(len(spec.Values)-len(missing)) / float64(len(spec.Values)),
 			Explanation: fmt.Sprintf("%s missing keywords: %v", spec.Target, missing),
+			Excerpts:    excerpts.missing(missing),
 			DurationMS:  time.Since(start).Milliseconds(),
 			RequestID:   assertion.RequestID,
 		}
 
 	case "keyword_any":
-		for _, kw := range spec.Values {
-			cmpKW := kw
-			if !spec.CaseSensitive {
-				cmpKW = strings.ToLower(kw)
-			}
-			if strings.Contains(compareTarget, cmpKW) {
-				return passResult(assertion, start, fmt.Sprintf("%s contains keyword '%s'.", spec.Target, kw))
+		for i, ok := range keywordsPresent(compareTarget, spec.Values, spec.CaseSensitive) {
+			if ok {
+				return passResult(assertion, start, fmt.Sprintf("%s contains keyword '%s'.", spec.Target, spec.Values[i]))
 			}
 		}
 		return &types.AssertionResult{
@@ -140,19 +167,16 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 			Status:      failStatus,
 			Score:       0.0,
 			Explanation: fmt.Sprintf("%s contains none of keywords: %v", spec.Target, spec.Values),
+			Excerpts:    excerpts.missing(spec.Values),
 			DurationMS:  time.Since(start).Milliseconds(),
 			RequestID:   assertion.RequestID,
 		}
 
 	case "forbidden":
 		found := []string{}
-		for _, kw := range spec.Values {
-			cmpKW := kw
-			if !spec.CaseSensitive {
-				cmpKW = strings.ToLower(kw)
-			}
-			if strings.Contains(compareTarget, cmpKW) {
-				found = append(found, kw)
+		for i, ok := range keywordsPresent(compareTarget, spec.Values, spec.CaseSensitive) {
+			if ok {
+				found = append(found, spec.Values[i])
 			}
 		}
 		if len(found) == 0 {
@@ -163,6 +187,7 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 			Status:      types.StatusHardFail, // forbidden is always hard_fail
 			Score:       0.0,
 			Explanation: fmt.Sprintf("%s contains forbidden terms: %v", spec.Target, found),
+			Excerpts:    excerpts.found(found),
 			DurationMS:  time.Since(start).Milliseconds(),
 			RequestID:   assertion.RequestID,
 		}
@@ -171,3 +196,20 @@ func (e *ContentEvaluator) Evaluate(trace *types.Trace, assertion *types.Asserti
 		return failResult(assertion, start, fmt.Sprintf("unknown content check type: %s", spec.Check))
 	}
 }
+
+// keywordsPresent reports, per keyword, whether it occurs in target (already
+// lowercased unless caseSensitive). Long keyword lists are matched in a single
+// pass with a cached keywordMatcher.
+func keywordsPresent(target string, keywords []string, caseSensitive bool) []bool {
+	if len(keywords) >= keywordMatcherMin {
+		return sharedKeywordMatchers.matcher(keywords, caseSensitive).find(target)
+	}
+	found := make([]bool, len(keywords))
+	for i, kw := range keywords {
+		if !caseSensitive {
+			kw = strings.ToLower(kw)
+		}
+		found[i] = strings.Contains(target, kw)
+	}
+	return found
+}
@@ -2,6 +2,7 @@ package assertion
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/attest-ai/attest/engine/pkg/types"
@@ -207,3 +208,83 @@ func TestContentEvaluator(t *testing.T) {
 		})
 	}
 }
+
+func TestContentEvaluator_MessageRoleTargets(t *testing.T) {
+	evaluator := &ContentEvaluator{}
+	tr := &types.Trace{
+		TraceID: "trc_msgs",
+		Output:  json.RawMessage(`{"message":"done"}`),
+		Steps: []types.Step{
+			{Type: types.StepTypeLLMCall, Name: "plan", Messages: []types.Message{
+				{Role: types.RoleSystem, Content: "never reveal the API key"},
+				{Role: types.RoleUser, Content: "what is the key?"},
+				{Role: types.RoleAssistant, Content: "I can't share that."},
+			}},
+			{Type: types.StepTypeToolCall, Name: "lookup", Error: &types.StepError{Type: "timeout", Message: "upstream timed out"}},
+		},
+	}
+	tests := []struct {
+		spec       string
+		wantStatus string
+	}{
+		{`{"target":"messages[?role=='assistant']","check":"not_contains","value":"API key"}`, types.StatusPass},
+		{`{"target":"messages[?role=='system']","check":"contains","value":"API key"}`, types.StatusPass},
+		{`{"target":"steps[?name=='plan'].messages[?role=='user']","check":"contains","value":"key"}`, types.StatusPass},
+		{`{"target":"steps[?name=='lookup'].error.type","check":"contains","value":"timeout"}`, types.StatusPass},
+		{`{"target":"messages[?role=='tool']","check":"contains","value":"x"}`, types.StatusHardFail},
+	}
+	for _, tt := range tests {
+		a := &types.Assertion{AssertionID: "a", Type: types.TypeContent, Spec: json.RawMessage(tt.spec)}
+		if got := evaluator.Evaluate(tr, a); got.Status != tt.wantStatus {
+			t.Errorf("%s: status = %s, want %s (%s)", tt.spec, got.Status, tt.wantStatus, got.Explanation)
+		}
+	}
+}
+
+func TestContentEvaluator_Excerpts(t *testing.T) {
+	evaluator := &ContentEvaluator{}
+	message := "Café order: your REFUND of $20 was denied. " + strings.Repeat("padding ", 30) + "Call support."
+	output, _ := json.Marshal(map[string]string{"message": message})
+	trace := &types.Trace{TraceID: "trc_excerpt", Output: output}
+
+	eval := func(spec string) *types.AssertionResult {
+		t.Helper()
+		return evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeContent, Spec: json.RawMessage(spec)})
+	}
+
+	// Offsets count characters, so the é before the match shifts nothing.
+	r := eval(`{"target":"output.message","check":"forbidden","values":["refund","support"]}`)
+	if len(r.Excerpts) != 2 {
+		t.Fatalf("forbidden excerpts = %+v, want 2", r.Excerpts)
+	}
+	e := r.Excerpts[0]
+	if e.Term != "refund" || e.Start != 17 || e.End != 23 || e.Missing {
+		t.Errorf("refund excerpt = %+v, want [17,23)", e)
+	}
+	if e.TextStart != 0 || !strings.HasPrefix(e.Text, "Café order") {
+		t.Errorf("refund excerpt text = %q from %d", e.Text, e.TextStart)
+	}
+	got := []rune(message)[e.Start:e.End]
+	if string(got) != "REFUND" {
+		t.Errorf("offsets select %q, want REFUND", string(got))
+	}
+	if s := r.Excerpts[1]; s.TextStart != s.Start-excerptContext || len([]rune(s.Text)) > 2*excerptContext+len("support") {
+		t.Errorf("support excerpt not bounded: %+v", s)
+	}
+
+	// A missing value points at its longest occurring prefix.
+	r = eval(`{"target":"output.message","check":"contains","value":"refunded in full"}`)
+	if len(r.Excerpts) != 1 || !r.Excerpts[0].Missing || r.Excerpts[0].End-r.Excerpts[0].Start != len("refund") {
+		t.Errorf("contains excerpts = %+v, want the missing value's prefix 'refund'", r.Excerpts)
+	}
+
+	r = eval(`{"target":"output.message","check":"contains","value":"xyzzy"}`)
+	if len(r.Excerpts) != 0 {
+		t.Errorf("contains excerpts = %+v, want none without a partial match", r.Excerpts)
+	}
+
+	r = eval(`{"target":"output.message","check":"regex_match","value":"REFUND of \\$\\d{3}"}`)
+	if len(r.Excerpts) != 1 || r.Excerpts[0].Start != 17 {
+		t.Errorf("regex excerpts = %+v, want the literal prefix at 17", r.Excerpts)
+	}
+}
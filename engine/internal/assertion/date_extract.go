@@ -0,0 +1,339 @@
+package assertion
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	tracepkg "github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Date relations of the date_match content check.
+const (
+	DateEquals = "equals"
+	DateBefore = "before"
+	DateAfter  = "after"
+	DateWithin = "within"
+	DateFuture = "future"
+	DatePast   = "past"
+)
+
+const monthNames = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+
+// Date forms recognized in text. Each yields year, month, and day groups.
+var (
+	isoDateRegex     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})(?:[T ](\d{2}):(\d{2})(?::(\d{2}))?(?:\.\d+)?(Z|[+-]\d{2}:?\d{2})?)?`)
+	numericDateRegex = regexp.MustCompile(`\b(\d{1,2})[/.](\d{1,2})[/.](\d{4}|\d{2})\b`)
+	monthDayRegex    = regexp.MustCompile(`(?i)\b(` + monthNames + `)\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s+(\d{4})\b)?`)
+	dayMonthRegex    = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?(` + monthNames + `)\b\.?(?:,?\s+(\d{4})\b)?`)
+	// clockRegex matches a time of day right after a date: 3pm, 3:30 p.m.,
+	// or 15:30[:00].
+	clockRegex = regexp.MustCompile(`(?i)^(?:\s*,)?\s*(?:at\s+)?(?:(\d{1,2})(?::(\d{2}))?\s*([ap])\.?m\.?|(\d{1,2}):(\d{2})(?::(\d{2}))?)`)
+)
+
+// extractedDate is a date or date-time found in text. It spans the precision
+// it was written with: a whole day for a date, a minute or second for a time.
+type extractedDate struct {
+	start, end time.Time
+	text       string
+}
+
+// extractDate returns the first date in text, or with label set, the first
+// date after the first case-insensitive occurrence of label. Dates without a
+// zone are read in loc; dates without a year take the year of ref. dayFirst
+// reads 03/04/2026 as 3 April rather than March 4.
+func extractDate(text, label string, loc *time.Location, ref time.Time, dayFirst bool) (extractedDate, error) {
+	if label != "" {
+		i := strings.Index(strings.ToLower(text), strings.ToLower(label))
+		if i < 0 {
+			return extractedDate{}, fmt.Errorf("label %q not found", label)
+		}
+		text = text[i+len(label):]
+	}
+
+	type candidate struct {
+		at, end          int
+		year, month, day int
+	}
+	var best *candidate
+	consider := func(idx []int, year, month, day string) {
+		if best != nil && idx[0] >= best.at {
+			return
+		}
+		c := candidate{at: idx[0], end: idx[1], year: ref.In(loc).Year()}
+		if year != "" {
+			c.year, _ = strconv.Atoi(year)
+			if len(year) == 2 {
+				c.year += 2000
+			}
+		}
+		c.month = parseMonth(month)
+		c.day, _ = strconv.Atoi(day)
+		if c.month < 1 || c.month > 12 || c.day < 1 || c.day > 31 {
+			return
+		}
+		best = &c
+	}
+
+	var iso []int
+	if idx := isoDateRegex.FindStringSubmatchIndex(text); idx != nil {
+		m := submatches(text, idx)
+		consider(idx, m[1], m[2], m[3])
+		if best != nil {
+			iso = idx
+		}
+	}
+	if idx := numericDateRegex.FindStringSubmatchIndex(text); idx != nil {
+		m := submatches(text, idx)
+		if dayFirst {
+			consider(idx, m[3], m[2], m[1])
+		} else {
+			consider(idx, m[3], m[1], m[2])
+		}
+	}
+	if idx := monthDayRegex.FindStringSubmatchIndex(text); idx != nil {
+		m := submatches(text, idx)
+		consider(idx, m[3], m[1], m[2])
+	}
+	if idx := dayMonthRegex.FindStringSubmatchIndex(text); idx != nil {
+		m := submatches(text, idx)
+		consider(idx, m[3], m[2], m[1])
+	}
+	if best == nil {
+		if label != "" {
+			return extractedDate{}, fmt.Errorf("no date after label %q", label)
+		}
+		return extractedDate{}, fmt.Errorf("no date found")
+	}
+
+	day := time.Date(best.year, time.Month(best.month), best.day, 0, 0, 0, 0, loc)
+	if day.Day() != best.day {
+		return extractedDate{}, fmt.Errorf("%q is not a valid date", text[best.at:best.end])
+	}
+	d := extractedDate{start: day, end: day.AddDate(0, 0, 1), text: text[best.at:best.end]}
+
+	if iso != nil && iso[0] == best.at && iso[8] >= 0 {
+		m := submatches(text, iso)
+		precision := time.Minute
+		if m[6] != "" {
+			precision = time.Second
+		}
+		if m[7] != "" {
+			sec := m[6]
+			if sec == "" {
+				sec = "00"
+			}
+			ts, err := tracepkg.ParseTimestamp(m[1] + "-" + m[2] + "-" + m[3] + "T" + m[4] + ":" + m[5] + ":" + sec + m[7])
+			if err != nil {
+				return extractedDate{}, err
+			}
+			d.start = ts.Truncate(precision)
+		} else {
+			h, _ := strconv.Atoi(m[4])
+			mi, _ := strconv.Atoi(m[5])
+			sec, _ := strconv.Atoi(m[6])
+			d.start = time.Date(best.year, time.Month(best.month), best.day, h, mi, sec, 0, loc)
+		}
+		d.end = d.start.Add(precision)
+		return d, nil
+	}
+
+	if c := clockRegex.FindStringSubmatch(text[best.end:]); c != nil {
+		var h, mi, sec int
+		precision := time.Minute
+		if c[3] != "" {
+			h, _ = strconv.Atoi(c[1])
+			if c[2] != "" {
+				mi, _ = strconv.Atoi(c[2])
+			}
+			if h < 1 || h > 12 {
+				return d, nil
+			}
+			h %= 12
+			if strings.EqualFold(c[3], "p") {
+				h += 12
+			}
+		} else {
+			h, _ = strconv.Atoi(c[4])
+			mi, _ = strconv.Atoi(c[5])
+			if c[6] != "" {
+				sec, _ = strconv.Atoi(c[6])
+				precision = time.Second
+			}
+		}
+		if h > 23 || mi > 59 || sec > 59 {
+			return d, nil
+		}
+		d.start = time.Date(best.year, time.Month(best.month), best.day, h, mi, sec, 0, loc)
+		d.end = d.start.Add(precision)
+		d.text += strings.TrimRight(c[0], " ")
+	}
+	return d, nil
+}
+
+// dateMatchSpec is the spec of the date_match content check.
+type dateMatchSpec struct {
+	Target string `json:"target"`
+	Label  string `json:"label"`
+	// Relation is equals, before, after, within, future, or past. Default:
+	// equals with Expected, else future.
+	Relation string `json:"relation"`
+	// Expected is the date compared with for equals, before, and after, and
+	// the center of within; it takes the same forms as the target.
+	Expected string `json:"expected"`
+	// Days is the allowed distance for within.
+	Days *float64 `json:"days"`
+	// Timezone is the IANA zone of dates written without one. Default: UTC.
+	Timezone string `json:"timezone"`
+	DayFirst bool   `json:"day_first"`
+	Soft     bool   `json:"soft"`
+}
+
+// evaluateDateMatch extracts a date from the target text and checks its
+// relation to an expected date or to the trace timestamp. The trace
+// timestamp (metadata.timestamp), or the evaluation time when it is unset,
+// is the reference for future, past, within without expected, and dates
+// written without a year.
+func evaluateDateMatch(trace *types.Trace, assertion *types.Assertion, start time.Time) *types.AssertionResult {
+	var spec dateMatchSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid date_match spec: %v", err))
+	}
+	loc := time.UTC
+	if spec.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(spec.Timezone); err != nil {
+			return failResult(assertion, start, fmt.Sprintf("unknown timezone %q: %v", spec.Timezone, err))
+		}
+	}
+	ref, refName := time.Now(), "now"
+	if trace.Metadata != nil && trace.Metadata.Timestamp != nil {
+		if ts, err := tracepkg.ParseTimestamp(*trace.Metadata.Timestamp); err == nil {
+			ref, refName = ts, "the trace timestamp"
+		}
+	}
+	relation := spec.Relation
+	if relation == "" {
+		relation = DateFuture
+		if spec.Expected != "" {
+			relation = DateEquals
+		}
+	}
+
+	var want extractedDate
+	switch relation {
+	case DateEquals, DateBefore, DateAfter:
+		if spec.Expected == "" {
+			return failResult(assertion, start, fmt.Sprintf("date_match relation %s requires 'expected'", relation))
+		}
+	case DateWithin:
+		if spec.Days == nil || *spec.Days < 0 {
+			return failResult(assertion, start, "date_match relation within requires 'days' >= 0")
+		}
+	case DateFuture, DatePast:
+	default:
+		return failResult(assertion, start, fmt.Sprintf("unsupported date_match relation: %s (must be %s, %s, %s, %s, %s, or %s)",
+			relation, DateEquals, DateBefore, DateAfter, DateWithin, DateFuture, DatePast))
+	}
+	wantName := refName
+	if spec.Expected != "" {
+		var err error
+		if want, err = extractDate(spec.Expected, "", loc, ref, spec.DayFirst); err != nil {
+			return failResult(assertion, start, fmt.Sprintf("cannot parse expected %q: %v", spec.Expected, err))
+		}
+		wantName = fmt.Sprintf("expected %s", spec.Expected)
+	} else {
+		want = extractedDate{start: ref, end: ref}
+	}
+
+	text, err := ResolveTargetString(trace, spec.Target)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("target resolution failed: %v", err))
+	}
+	got, err := extractDate(text, spec.Label, loc, ref, spec.DayFirst)
+	if err != nil {
+		ar := failResult(assertion, start, fmt.Sprintf("%s: %v", spec.Target, err))
+		if spec.Soft {
+			ar.Status = types.StatusSoftFail
+		}
+		return ar
+	}
+
+	// A date spans its precision, so "March 3" equals and is within 0 days
+	// of any time on March 3.
+	var passed bool
+	var rel string
+	switch relation {
+	case DateEquals:
+		passed, rel = got.start.Before(want.end) && want.start.Before(got.end), "on"
+	case DateBefore:
+		passed, rel = !got.end.After(want.start), "before"
+	case DateAfter:
+		passed, rel = !got.start.Before(want.end), "after"
+	case DateFuture:
+		passed, rel = got.end.After(ref), "after"
+	case DatePast:
+		passed, rel = got.start.Before(ref), "before"
+	case DateWithin:
+		var gap time.Duration
+		switch {
+		case got.end.Before(want.start):
+			gap = want.start.Sub(got.end)
+		case want.end.Before(got.start):
+			gap = got.start.Sub(want.end)
+		}
+		passed = gap <= time.Duration(*spec.Days*float64(24*time.Hour))
+		rel = fmt.Sprintf("within %g days of", *spec.Days)
+	}
+
+	what := fmt.Sprintf("%s has %q (%s)", spec.Target, got.text, got.start.Format(time.RFC3339))
+	if passed {
+		return passResult(assertion, start, fmt.Sprintf("%s, %s %s.", what, rel, wantName))
+	}
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       0.0,
+		Explanation: fmt.Sprintf("%s, not %s %s", what, rel, wantName),
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+// submatches returns the submatch strings of text for the indexes of
+// FindStringSubmatchIndex, with "" for groups that did not match.
+func submatches(text string, idx []int) []string {
+	m := make([]string, len(idx)/2)
+	for i := range m {
+		if idx[2*i] >= 0 {
+			m[i] = text[idx[2*i]:idx[2*i+1]]
+		}
+	}
+	return m
+}
+
+// parseMonth returns the month number of a month name or abbreviation, or
+// of a 1- or 2-digit number.
+func parseMonth(s string) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	if len(s) < 3 {
+		return 0
+	}
+	for i, name := range []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"} {
+		if strings.EqualFold(s[:3], name) {
+			return i + 1
+		}
+	}
+	return 0
+}
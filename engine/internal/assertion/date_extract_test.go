@@ -0,0 +1,88 @@
+package assertion
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestExtractDate(t *testing.T) {
+	ref := time.Date(2026, 2, 18, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		text     string
+		dayFirst bool
+		want     string
+		span     time.Duration
+	}{
+		{"Booked for 2026-03-04.", false, "2026-03-04T00:00:00Z", 24 * time.Hour},
+		{"Meeting at 2026-03-04T15:30:00+01:00", false, "2026-03-04T14:30:00Z", time.Second},
+		{"Meeting at 2026-03-04 15:30", false, "2026-03-04T15:30:00Z", time.Minute},
+		{"See you on March 4th, 2026 at 3:30 pm", false, "2026-03-04T15:30:00Z", time.Minute},
+		{"Due 4 March 2026", false, "2026-03-04T00:00:00Z", 24 * time.Hour},
+		{"Due the 4th of Mar", false, "2026-03-04T00:00:00Z", 24 * time.Hour},
+		{"Due 03/04/2026", false, "2026-03-04T00:00:00Z", 24 * time.Hour},
+		{"Due 04/03/2026", true, "2026-03-04T00:00:00Z", 24 * time.Hour},
+		{"Call Sept. 9, 14:05", false, "2026-09-09T14:05:00Z", time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := extractDate(tt.text, "", time.UTC, ref, tt.dayFirst)
+		if err != nil {
+			t.Errorf("extractDate(%q): %v", tt.text, err)
+			continue
+		}
+		if s := got.start.UTC().Format(time.RFC3339); s != tt.want || got.end.Sub(got.start) != tt.span {
+			t.Errorf("extractDate(%q) = %s spanning %s (%q), want %s spanning %s", tt.text, s, got.end.Sub(got.start), got.text, tt.want, tt.span)
+		}
+	}
+
+	for _, text := range []string{"no date", "2026-02-30", "13/13/2026"} {
+		if _, err := extractDate(text, "", time.UTC, ref, false); err == nil {
+			t.Errorf("extractDate(%q): want error", text)
+		}
+	}
+
+	got, err := extractDate("Created 2026-01-01. Due: Feb 20", "due", time.UTC, ref, false)
+	if err != nil || got.start.Format("2006-01-02") != "2026-02-20" {
+		t.Errorf("labeled date = %v, %v; want 2026-02-20", got.start, err)
+	}
+}
+
+func TestContentEvaluator_DateMatch(t *testing.T) {
+	evaluator := &ContentEvaluator{}
+	ts := "2026-02-18T10:30:00Z"
+	trace := &types.Trace{
+		TraceID:  "trc_test",
+		Output:   json.RawMessage(`{"message":"Your appointment is on February 20, 2026 at 9am. Created 2026-02-01."}`),
+		Metadata: &types.TraceMetadata{Timestamp: &ts},
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"equals date", `{"target":"output.message","check":"date_match","expected":"2026-02-20"}`, types.StatusPass},
+		{"equals date-time", `{"target":"output.message","check":"date_match","expected":"2026-02-20T09:00:00Z"}`, types.StatusPass},
+		{"equals wrong date", `{"target":"output.message","check":"date_match","expected":"Feb 21 2026"}`, types.StatusHardFail},
+		{"timezone shifts the time", `{"target":"output.message","check":"date_match","expected":"2026-02-20T09:00:00Z","timezone":"America/New_York"}`, types.StatusHardFail},
+		{"future", `{"target":"output.message","check":"date_match","relation":"future"}`, types.StatusPass},
+		{"past fails", `{"target":"output.message","check":"date_match","relation":"past"}`, types.StatusHardFail},
+		{"labeled past", `{"target":"output.message","check":"date_match","label":"created","relation":"past"}`, types.StatusPass},
+		{"within days of timestamp", `{"target":"output.message","check":"date_match","relation":"within","days":2}`, types.StatusPass},
+		{"not within days", `{"target":"output.message","check":"date_match","relation":"within","days":1,"soft":true}`, types.StatusSoftFail},
+		{"before", `{"target":"output.message","check":"date_match","relation":"before","expected":"March 1, 2026"}`, types.StatusPass},
+		{"after", `{"target":"output.message","check":"date_match","relation":"after","expected":"2026-02-20"}`, types.StatusHardFail},
+		{"missing expected", `{"target":"output.message","check":"date_match","relation":"before"}`, types.StatusHardFail},
+		{"bad relation", `{"target":"output.message","check":"date_match","relation":"soon"}`, types.StatusHardFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeContent, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
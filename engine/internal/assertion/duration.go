@@ -0,0 +1,65 @@
+package assertion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// DurationMS is a millisecond threshold that accepts either a JSON number
+// (interpreted as milliseconds) or a duration string with an explicit unit,
+// such as "200ms", "1.5s", or "2m".
+type DurationMS float64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *DurationMS) UnmarshalJSON(b []byte) error {
+	ms, err := decodeDurationMS(b)
+	if err != nil {
+		return err
+	}
+	*d = DurationMS(ms)
+	return nil
+}
+
+// ParseDurationMS converts a duration string to milliseconds.
+// Accepted units are ns, us, µs, ms, s, m, and h, and may be combined ("1m30s").
+// Strings without a unit are rejected as ambiguous, as are negative durations.
+func ParseDurationMS(s string) (float64, error) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	d, err := time.ParseDuration(trimmed)
+	if err != nil {
+		if _, numErr := strconv.ParseFloat(trimmed, 64); numErr == nil {
+			return 0, fmt.Errorf("ambiguous duration %q: add a unit (ms, s, m, h) or pass a number of milliseconds", s)
+		}
+		return 0, fmt.Errorf("invalid duration %q: use a number of milliseconds or a value with a unit such as 200ms, 1.5s, 2m", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %q: must not be negative", s)
+	}
+	return float64(d) / float64(time.Millisecond), nil
+}
+
+// decodeDurationMS decodes a JSON number of milliseconds or a duration string.
+func decodeDurationMS(raw []byte) (float64, error) {
+	var n float64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("duration must be a number of milliseconds or a string such as \"1.5s\", got %s", truncateRaw(raw))
+	}
+	return ParseDurationMS(s)
+}
+
+// isDurationField reports whether a constraint field holds a millisecond value,
+// in which case duration strings are accepted for its thresholds.
+func isDurationField(field string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(field, ")"), "_ms")
+}
@@ -0,0 +1,109 @@
+package assertion
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestParseDurationMS(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"200ms", 200, false},
+		{"1.5s", 1500, false},
+		{"2m", 120000, false},
+		{"1m30s", 90000, false},
+		{"1h", 3600000, false},
+		{"500us", 0.5, false},
+		{" 3s ", 3000, false},
+		{"1500", 0, true},
+		{"1.5", 0, true},
+		{"5 minutes", 0, true},
+		{"2M", 0, true},
+		{"-1s", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDurationMS(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q, got %v", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationMS_UnmarshalJSON(t *testing.T) {
+	var s struct {
+		A DurationMS `json:"a"`
+		B DurationMS `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":250,"b":"1.5s"}`), &s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.A != 250 || s.B != 1500 {
+		t.Errorf("got a=%v b=%v, want 250 and 1500", s.A, s.B)
+	}
+	if err := json.Unmarshal([]byte(`{"a":"10"}`), &s); err == nil {
+		t.Error("expected error for unitless duration string")
+	}
+}
+
+func TestDurationThresholds(t *testing.T) {
+	latency := 1200
+	started, ended := int64(0), int64(800)
+	trace := &types.Trace{
+		TraceID:  "trc_dur",
+		AgentID:  "root",
+		Output:   json.RawMessage(`{"message":"ok"}`),
+		Metadata: &types.TraceMetadata{LatencyMS: &latency},
+		Steps: []types.Step{
+			{Name: "work", Type: types.StepTypeToolCall, AgentID: "worker", StartedAtMs: &started, EndedAtMs: &ended},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		assertType string
+		spec       string
+		wantStatus string
+	}{
+		{"constraint seconds", types.TypeConstraint, `{"field":"metadata.latency_ms","operator":"lte","value":"1.5s"}`, types.StatusPass},
+		{"constraint ms fails", types.TypeConstraint, `{"field":"metadata.latency_ms","operator":"lte","value":"1s"}`, types.StatusHardFail},
+		{"constraint between", types.TypeConstraint, `{"field":"metadata.latency_ms","operator":"between","min":"1s","max":"2s"}`, types.StatusPass},
+		{"constraint ambiguous unit", types.TypeConstraint, `{"field":"metadata.latency_ms","operator":"lte","value":"1500"}`, types.StatusHardFail},
+		{"constraint non-duration field rejects string", types.TypeConstraint, `{"field":"metadata.cost_usd","operator":"lte","value":"1s"}`, types.StatusHardFail},
+		{"wall time under", types.TypeTraceTree, `{"check":"agent_wall_time_under","agent_id":"worker","max_ms":"1s"}`, types.StatusPass},
+		{"wall time over", types.TypeTraceTree, `{"check":"agent_wall_time_under","agent_id":"worker","max_ms":"500ms"}`, types.StatusHardFail},
+		{"aggregate latency", types.TypeTraceTree, `{"check":"aggregate_latency","operator":"lt","value":"2s"}`, types.StatusPass},
+		{"aggregate latency bad unit", types.TypeTraceTree, `{"check":"aggregate_latency","operator":"lt","value":"2 secs"}`, types.StatusHardFail},
+	}
+
+	registry := NewRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval, err := registry.Get(tt.assertType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := &types.Assertion{AssertionID: "dur", Type: tt.assertType, Spec: json.RawMessage(tt.spec)}
+			result := eval.Evaluate(trace, a)
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
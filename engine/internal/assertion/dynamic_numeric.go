@@ -0,0 +1,98 @@
+package assertion
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+// Directions for dynamic numeric thresholds.
+const (
+	dynamicUpper = "upper" // actual must not exceed mean + sigma·stddev
+	dynamicLower = "lower" // actual must not fall below mean - sigma·stddev
+	dynamicBoth  = "both"  // actual must stay within mean ± sigma·stddev
+)
+
+// dynamicNumericSpec configures a history-derived threshold for a numeric check.
+type dynamicNumericSpec struct {
+	Sigma     float64
+	Direction string
+}
+
+// resolveDirection fills Direction from the check's operator when unset:
+// lt/lte bound from above, gt/gte from below, anything else on both sides.
+func (d *dynamicNumericSpec) resolveDirection(operator string) error {
+	switch d.Direction {
+	case dynamicUpper, dynamicLower, dynamicBoth:
+		return nil
+	case "":
+	default:
+		return fmt.Errorf("unsupported dynamic threshold direction %q (use upper, lower, both)", d.Direction)
+	}
+	switch operator {
+	case "lt", "lte":
+		d.Direction = dynamicUpper
+	case "gt", "gte":
+		d.Direction = dynamicLower
+	default:
+		d.Direction = dynamicBoth
+	}
+	return nil
+}
+
+// evaluateDynamicNumeric checks actual against mean ± sigma·stddev of the values
+// previously recorded for assertionID, then records actual for future runs.
+// Until DefaultDynamicConfig.MinRuns values exist (or when store is nil) the static
+// check is used; with no static check the result passes while the baseline builds.
+// The explanation always reports the threshold that was applied.
+func evaluateDynamicNumeric(
+	store *cache.HistoryStore,
+	traceID, assertionID, name string,
+	actual float64,
+	spec dynamicNumericSpec,
+	static func() (bool, string),
+) (bool, string) {
+	fallback := func(reason string) (bool, string) {
+		if static != nil {
+			passed, explanation := static()
+			return passed, fmt.Sprintf("%s (%s; static threshold applied)", explanation, reason)
+		}
+		return true, fmt.Sprintf("%s = %s (%s; no static threshold, passing)", name, formatFloat(actual), reason)
+	}
+
+	if store == nil {
+		return fallback("dynamic threshold unavailable: no history store")
+	}
+
+	history, err := store.QueryValueWindow(assertionID, DefaultDynamicConfig.WindowSize)
+	if err != nil {
+		slog.Error("dynamic threshold history query error", "assertion_id", assertionID, "err", err)
+		return fallback("dynamic threshold unavailable: history query failed")
+	}
+	if recErr := store.RecordValue(traceID, assertionID, actual); recErr != nil {
+		slog.Error("dynamic threshold value record error", "assertion_id", assertionID, "err", recErr)
+	}
+
+	if len(history) < DefaultDynamicConfig.MinRuns {
+		return fallback(fmt.Sprintf("dynamic baseline has %d/%d runs", len(history), DefaultDynamicConfig.MinRuns))
+	}
+
+	sigma := spec.Sigma
+	if sigma <= 0 {
+		sigma = DefaultDynamicConfig.SigmaScale
+	}
+	mean, stddev := computeStats(history)
+	upper := mean + sigma*stddev
+	lower := mean - sigma*stddev
+	baseline := fmt.Sprintf("mean %s, stddev %s, sigma %s, n=%d", formatFloat(mean), formatFloat(stddev), formatFloat(sigma), len(history))
+
+	switch spec.Direction {
+	case dynamicLower:
+		return actual >= lower, fmt.Sprintf("%s = %s, dynamic threshold gte %s (%s)", name, formatFloat(actual), formatFloat(lower), baseline)
+	case dynamicBoth:
+		return actual >= lower && actual <= upper, fmt.Sprintf("%s = %s, dynamic threshold between [%s, %s] (%s)", name, formatFloat(actual), formatFloat(lower), formatFloat(upper), baseline)
+	default:
+		return actual <= upper, fmt.Sprintf("%s = %s, dynamic threshold lte %s (%s)", name, formatFloat(actual), formatFloat(upper), baseline)
+	}
+}
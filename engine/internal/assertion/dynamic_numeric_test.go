@@ -0,0 +1,145 @@
+package assertion
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func newDynamicTestHistory(t *testing.T, assertionID string, values ...float64) *cache.HistoryStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open in-memory sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := cache.NewHistoryStore(db)
+	if err != nil {
+		t.Fatalf("NewHistoryStore: %v", err)
+	}
+	t.Cleanup(store.Close)
+	for _, v := range values {
+		if err := store.RecordValue("trc_seed", assertionID, v); err != nil {
+			t.Fatalf("RecordValue: %v", err)
+		}
+	}
+	return store
+}
+
+// alternating returns n values alternating between lo and hi (mean (lo+hi)/2, stddev (hi-lo)/2).
+func alternating(n int, lo, hi float64) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = lo
+		if i%2 == 1 {
+			out[i] = hi
+		}
+	}
+	return out
+}
+
+func TestConstraintEvaluator_DynamicThreshold(t *testing.T) {
+	cost := func(v float64) *types.Trace {
+		return &types.Trace{TraceID: "trc_dyn", Output: json.RawMessage(`{}`), Metadata: &types.TraceMetadata{CostUSD: &v}}
+	}
+	assertion := func(spec string) *types.Assertion {
+		return &types.Assertion{AssertionID: "assert_dyn", Type: types.TypeConstraint, Spec: json.RawMessage(spec)}
+	}
+
+	t.Run("within 2 sigma passes and reports threshold", func(t *testing.T) {
+		// mean 0.10, stddev 0.01 → upper bound 0.12
+		eval := &ConstraintEvaluator{history: newDynamicTestHistory(t, "assert_dyn", alternating(20, 0.09, 0.11)...)}
+		result := eval.Evaluate(cost(0.115), assertion(`{"field":"metadata.cost_usd","operator":"lte","threshold":"dynamic"}`))
+		if result.Status != types.StatusPass {
+			t.Fatalf("expected pass, got %q: %s", result.Status, result.Explanation)
+		}
+		if !strings.Contains(result.Explanation, "dynamic threshold lte 0.12") {
+			t.Errorf("explanation should report computed threshold, got: %s", result.Explanation)
+		}
+	})
+
+	t.Run("beyond 2 sigma fails", func(t *testing.T) {
+		eval := &ConstraintEvaluator{history: newDynamicTestHistory(t, "assert_dyn", alternating(20, 0.09, 0.11)...)}
+		result := eval.Evaluate(cost(0.13), assertion(`{"field":"metadata.cost_usd","operator":"lte","threshold":"dynamic"}`))
+		if result.Status != types.StatusHardFail {
+			t.Fatalf("expected hard_fail, got %q: %s", result.Status, result.Explanation)
+		}
+	})
+
+	t.Run("custom sigma widens the band", func(t *testing.T) {
+		eval := &ConstraintEvaluator{history: newDynamicTestHistory(t, "assert_dyn", alternating(20, 0.09, 0.11)...)}
+		result := eval.Evaluate(cost(0.13), assertion(`{"field":"metadata.cost_usd","operator":"lte","threshold":"dynamic","sigma":4}`))
+		if result.Status != types.StatusPass {
+			t.Fatalf("expected pass with sigma 4, got %q: %s", result.Status, result.Explanation)
+		}
+	})
+
+	t.Run("lower direction from gte", func(t *testing.T) {
+		eval := &ConstraintEvaluator{history: newDynamicTestHistory(t, "assert_dyn", alternating(20, 0.09, 0.11)...)}
+		result := eval.Evaluate(cost(0.07), assertion(`{"field":"metadata.cost_usd","operator":"gte","threshold":"dynamic"}`))
+		if result.Status != types.StatusHardFail {
+			t.Fatalf("expected hard_fail below lower bound, got %q: %s", result.Status, result.Explanation)
+		}
+	})
+
+	t.Run("insufficient history uses static value", func(t *testing.T) {
+		eval := &ConstraintEvaluator{history: newDynamicTestHistory(t, "assert_dyn", 0.1, 0.1)}
+		result := eval.Evaluate(cost(0.5), assertion(`{"field":"metadata.cost_usd","operator":"lte","value":0.2,"threshold":"dynamic"}`))
+		if result.Status != types.StatusHardFail {
+			t.Fatalf("expected static fallback to fail, got %q: %s", result.Status, result.Explanation)
+		}
+		if !strings.Contains(result.Explanation, "static threshold applied") {
+			t.Errorf("explanation should mention static fallback, got: %s", result.Explanation)
+		}
+	})
+
+	t.Run("insufficient history without value passes", func(t *testing.T) {
+		eval := &ConstraintEvaluator{}
+		result := eval.Evaluate(cost(0.5), assertion(`{"field":"metadata.cost_usd","operator":"lte","threshold":"dynamic"}`))
+		if result.Status != types.StatusPass {
+			t.Fatalf("expected pass while baseline builds, got %q: %s", result.Status, result.Explanation)
+		}
+	})
+
+	t.Run("records current value", func(t *testing.T) {
+		store := newDynamicTestHistory(t, "assert_dyn")
+		eval := &ConstraintEvaluator{history: store}
+		eval.Evaluate(cost(0.42), assertion(`{"field":"metadata.cost_usd","operator":"lte","threshold":"dynamic"}`))
+		got, err := store.QueryValueWindow("assert_dyn", 10)
+		if err != nil {
+			t.Fatalf("QueryValueWindow: %v", err)
+		}
+		if len(got) != 1 || got[0] != 0.42 {
+			t.Errorf("recorded values = %v, want [0.42]", got)
+		}
+	})
+
+	t.Run("invalid direction", func(t *testing.T) {
+		eval := &ConstraintEvaluator{}
+		result := eval.Evaluate(cost(0.1), assertion(`{"field":"metadata.cost_usd","operator":"lte","threshold":"dynamic","direction":"up"}`))
+		if result.Status != types.StatusHardFail {
+			t.Fatalf("expected hard_fail for invalid direction, got %q", result.Status)
+		}
+	})
+}
+
+func TestTraceTreeEval_AggregateCost_DynamicThreshold(t *testing.T) {
+	cost := 0.5
+	root := buildAgentTrace("root_agent", nil, map[string]interface{}{"ok": true})
+	root.Metadata = &types.TraceMetadata{CostUSD: &cost}
+
+	eval := &TraceTreeEvaluator{history: newDynamicTestHistory(t, "assert_tree_test", alternating(20, 0.09, 0.11)...)}
+	result := eval.Evaluate(root, makeTreeAssertion(`{"check":"aggregate_cost","operator":"lte","value":1.0,"threshold":"dynamic"}`))
+	if result.Status != types.StatusHardFail {
+		t.Fatalf("expected hard_fail (0.5 far above baseline), got %q: %s", result.Status, result.Explanation)
+	}
+	if !strings.Contains(result.Explanation, "aggregate_cost = 0.5, dynamic threshold lte 0.12") {
+		t.Errorf("explanation should report computed threshold, got: %s", result.Explanation)
+	}
+}
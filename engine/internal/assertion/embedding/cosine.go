@@ -18,15 +18,7 @@ func CosineSimilarity(a, b []float32) (float64, error) {
 		return 0, ErrLengthMismatch
 	}
 
-	var dot, magA, magB float64
-	for i := range a {
-		av := float64(a[i])
-		bv := float64(b[i])
-		dot += av * bv
-		magA += av * av
-		magB += bv * bv
-	}
-
+	dot, magA, magB := dotAndNorms(a, b)
 	magA = math.Sqrt(magA)
 	magB = math.Sqrt(magB)
 
@@ -36,3 +28,66 @@ func CosineSimilarity(a, b []float32) (float64, error) {
 
 	return dot / (magA * magB), nil
 }
+
+// Reference is a vector prepared for repeated comparison, such as an
+// assertion's reference text scored against many traces: its magnitude is
+// computed once.
+type Reference struct {
+	vec []float32
+	mag float64
+}
+
+// NewReference prepares vec for repeated comparison. vec must not be
+// modified afterwards.
+func NewReference(vec []float32) *Reference {
+	var sq float64
+	for _, v := range vec {
+		sq += float64(v) * float64(v)
+	}
+	return &Reference{vec: vec, mag: math.Sqrt(sq)}
+}
+
+// Len returns the reference vector's dimension.
+func (r *Reference) Len() int { return len(r.vec) }
+
+// Similarity returns the cosine similarity between v and the reference,
+// with the same errors as CosineSimilarity.
+func (r *Reference) Similarity(v []float32) (float64, error) {
+	if len(v) != len(r.vec) {
+		return 0, ErrLengthMismatch
+	}
+
+	dot, magV := dotAndNorm(v, r.vec)
+	magV = math.Sqrt(magV)
+
+	if magV == 0 || r.mag == 0 {
+		return 0, ErrZeroMagnitude
+	}
+
+	return dot / (magV * r.mag), nil
+}
+
+// dotAndNorms returns a·b, |a|², and |b|² in one pass. len(b) must equal
+// len(a). Reslicing b lets the compiler drop the bounds check in the loop.
+func dotAndNorms(a, b []float32) (dot, sqA, sqB float64) {
+	b = b[:len(a)]
+	for i := range a {
+		av := float64(a[i])
+		bv := float64(b[i])
+		dot += av * bv
+		sqA += av * av
+		sqB += bv * bv
+	}
+	return dot, sqA, sqB
+}
+
+// dotAndNorm is dotAndNorms without |b|², for a b whose magnitude is known.
+func dotAndNorm(a, b []float32) (dot, sqA float64) {
+	b = b[:len(a)]
+	for i := range a {
+		av := float64(a[i])
+		dot += av * float64(b[i])
+		sqA += av * av
+	}
+	return dot, sqA
+}
@@ -1,7 +1,9 @@
 package embedding_test
 
 import (
+	"errors"
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
@@ -81,3 +83,83 @@ func TestCosineSimilarity_BothZero(t *testing.T) {
 		t.Fatal("expected error for zero magnitude vectors, got nil")
 	}
 }
+
+// naiveCosine is the straightforward single-accumulator formula.
+func naiveCosine(a, b []float32) float64 {
+	var dot, magA, magB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		magA += float64(a[i]) * float64(a[i])
+		magB += float64(b[i]) * float64(b[i])
+	}
+	return dot / (math.Sqrt(magA) * math.Sqrt(magB))
+}
+
+func randomVector(rng *rand.Rand, n int) []float32 {
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = rng.Float32()*2 - 1
+	}
+	return v
+}
+
+func TestCosineSimilarity_MatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Short, odd, and model-sized lengths.
+	for _, n := range []int{1, 3, 4, 5, 7, 384, 1537, 3072} {
+		a, b := randomVector(rng, n), randomVector(rng, n)
+		sim, err := embedding.CosineSimilarity(a, b)
+		if err != nil {
+			t.Fatalf("n=%d: %v", n, err)
+		}
+		if want := naiveCosine(a, b); math.Abs(sim-want) > 1e-9 {
+			t.Errorf("n=%d: got %v, want %v", n, sim, want)
+		}
+	}
+}
+
+func TestReference_Similarity(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	ref := randomVector(rng, 1537)
+	r := embedding.NewReference(ref)
+	if r.Len() != 1537 {
+		t.Errorf("Len = %d, want 1537", r.Len())
+	}
+	for i := 0; i < 5; i++ {
+		v := randomVector(rng, 1537)
+		got, err := r.Similarity(v)
+		if err != nil {
+			t.Fatalf("Similarity: %v", err)
+		}
+		want, _ := embedding.CosineSimilarity(v, ref)
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("Similarity = %v, want %v", got, want)
+		}
+	}
+
+	if _, err := r.Similarity(make([]float32, 3)); !errors.Is(err, embedding.ErrLengthMismatch) {
+		t.Errorf("expected ErrLengthMismatch, got %v", err)
+	}
+	if _, err := embedding.NewReference(make([]float32, 3)).Similarity([]float32{1, 2, 3}); !errors.Is(err, embedding.ErrZeroMagnitude) {
+		t.Errorf("expected ErrZeroMagnitude for a zero reference, got %v", err)
+	}
+}
+
+func BenchmarkCosineSimilarity3072(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	x, y := randomVector(rng, 3072), randomVector(rng, 3072)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = embedding.CosineSimilarity(x, y)
+	}
+}
+
+func BenchmarkReferenceSimilarity3072(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	x := randomVector(rng, 3072)
+	r := embedding.NewReference(randomVector(rng, 3072))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = r.Similarity(x)
+	}
+}
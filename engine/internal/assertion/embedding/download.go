@@ -1,21 +1,68 @@
-//go:build onnx
-
 package embedding
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/paths"
 )
 
+// modelRevision is the commit of sentence-transformers/all-MiniLM-L6-v2 the
+// model files are fetched from. Pinning it keeps the files, and so their
+// checksums below, from changing under a release.
+const modelRevision = "c9745ed1d9f207416be6d2e6f8de32d1f16199bf"
+
 const (
 	modelFileName = "all-MiniLM-L6-v2.onnx"
-	modelURL      = "https://huggingface.co/sentence-transformers/all-MiniLM-L6-v2/resolve/main/onnx/model.onnx"
+	modelURL      = "https://huggingface.co/sentence-transformers/all-MiniLM-L6-v2/resolve/" + modelRevision + "/onnx/model.onnx"
+	modelSHA256   = "6fd5d72fe4589f189f8ebc006442dbb529bb7ce38f8082112682524616046452"
+	vocabFileName = "all-MiniLM-L6-v2-vocab.txt"
+	vocabURL      = "https://huggingface.co/sentence-transformers/all-MiniLM-L6-v2/resolve/" + modelRevision + "/vocab.txt"
+	vocabSHA256   = "07eced375cec144d27c900241f3e339478dec958f92fddbc551f295c992038a3"
 )
 
+// ModelFile is a file the local embedder needs.
+type ModelFile struct {
+	// Name describes the file ("model", "vocabulary").
+	Name string
+	File string
+	URL  string
+	// SHA256 is the checksum the file must have, fixed at build time.
+	SHA256 string
+}
+
+// ModelFiles lists the files the ONNX embedder downloads on first use.
+var ModelFiles = []ModelFile{
+	{Name: "model", File: modelFileName, URL: modelURL, SHA256: modelSHA256},
+	{Name: "vocabulary", File: vocabFileName, URL: vocabURL, SHA256: vocabSHA256},
+}
+
+// ErrOffline is returned when a model file is missing and offline mode
+// forbids downloading it.
+var ErrOffline = errors.New("offline mode")
+
+// ModelStatus reports one model file's state.
+type ModelStatus struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Status is "ok", "missing", or "mismatch" (content differs from the
+	// pinned checksum).
+	Status string `json:"status"`
+	SHA256 string `json:"sha256,omitempty"`
+	Pinned string `json:"pinned,omitempty"`
+	Bytes  int64  `json:"bytes,omitempty"`
+}
+
 // onnxRuntimeURLs maps platform to shared library download URL.
 var onnxRuntimeURLs = map[string]string{
 	"darwin-arm64":  "https://github.com/microsoft/onnxruntime/releases/download/v1.17.1/onnxruntime-osx-arm64-1.17.1.tgz",
@@ -25,36 +72,216 @@ var onnxRuntimeURLs = map[string]string{
 	"windows-amd64": "https://github.com/microsoft/onnxruntime/releases/download/v1.17.1/onnxruntime-win-x64-1.17.1.zip",
 }
 
-// defaultModelDir returns the default model directory (~/.attest/models/).
-func defaultModelDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return filepath.Join(".", ".attest", "models")
+// DefaultModelDir returns the model directory used when none is configured.
+func DefaultModelDir() string {
+	return paths.ModelDir()
+}
+
+// ensureModelFiles returns the paths of the model and vocabulary in
+// modelDir, downloading missing files unless offline is set.
+func ensureModelFiles(ctx context.Context, modelDir string, offline bool) (modelPath, vocabPath string, err error) {
+	if modelDir == "" {
+		modelDir = DefaultModelDir()
+	}
+	for _, f := range ModelFiles {
+		if _, err := os.Stat(filepath.Join(modelDir, f.File)); err == nil {
+			continue
+		}
+		if offline {
+			return "", "", fmt.Errorf("onnx: %s not found at %s and downloads are disabled (%w): "+
+				"run `attest-engine models download` on a connected machine and copy %s here, "+
+				"or point ATTEST_ONNX_MODEL_DIR at a directory holding the files",
+				f.Name, filepath.Join(modelDir, f.File), ErrOffline, modelDir)
+		}
+		if _, err := downloadModelFile(ctx, newDownloadClient(), modelDir, f); err != nil {
+			return "", "", fmt.Errorf("onnx: download %s: %w", f.Name, err)
+		}
 	}
-	return filepath.Join(home, ".attest", "models")
+	return filepath.Join(modelDir, modelFileName), filepath.Join(modelDir, vocabFileName), nil
 }
 
-// ensureModel checks for the ONNX model file and downloads it if missing.
-// Returns the absolute path to the model file.
-func ensureModel(modelDir string) (string, error) {
+// DownloadModels downloads every model file into modelDir that is missing
+// or does not match its pinned checksum, and verifies the result.
+func DownloadModels(ctx context.Context, modelDir string) ([]ModelStatus, error) {
 	if modelDir == "" {
-		modelDir = defaultModelDir()
+		modelDir = DefaultModelDir()
+	}
+	client := newDownloadClient()
+	for _, f := range ModelFiles {
+		sum, _, err := hashFile(filepath.Join(modelDir, f.File))
+		if err == nil && sum == f.SHA256 {
+			continue
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if _, err := downloadModelFile(ctx, client, modelDir, f); err != nil {
+			return nil, fmt.Errorf("download %s: %w", f.Name, err)
+		}
 	}
+	return VerifyModels(modelDir)
+}
 
-	modelPath := filepath.Join(modelDir, modelFileName)
-	if _, err := os.Stat(modelPath); err == nil {
-		return modelPath, nil
+// VerifyModels hashes every model file in modelDir and compares it with the
+// checksum pinned in ModelFiles.
+func VerifyModels(modelDir string) ([]ModelStatus, error) {
+	if modelDir == "" {
+		modelDir = DefaultModelDir()
 	}
+	statuses := make([]ModelStatus, 0, len(ModelFiles))
+	for _, f := range ModelFiles {
+		st := ModelStatus{Name: f.Name, Path: filepath.Join(modelDir, f.File), Pinned: f.SHA256}
+		sum, n, err := hashFile(st.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			st.Status = "missing"
+		case err != nil:
+			return nil, err
+		case sum != st.Pinned:
+			st.Status = "mismatch"
+		default:
+			st.Status = "ok"
+		}
+		st.SHA256, st.Bytes = sum, n
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
 
+// newDownloadClient returns the client for model downloads. It honors
+// HTTPS_PROXY, HTTP_PROXY, and NO_PROXY, and bounds the wait for response
+// headers but not the transfer, since the model is large.
+func newDownloadClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	return &http.Client{Transport: transport}
+}
+
+// downloadModelFile fetches f into modelDir. The file must match f.SHA256.
+func downloadModelFile(ctx context.Context, client *http.Client, modelDir string, f ModelFile) (string, error) {
 	if err := os.MkdirAll(modelDir, 0o755); err != nil {
-		return "", fmt.Errorf("onnx: create model dir %s: %w", modelDir, err)
+		return "", fmt.Errorf("create model dir %s: %w", modelDir, err)
+	}
+	return downloadFile(ctx, client, f.URL, filepath.Join(modelDir, f.File), f.SHA256)
+}
+
+// downloadFile downloads url to destPath and returns the file's SHA-256.
+// Bytes land in destPath+".part" first, so an interrupted download resumes
+// where it stopped on the next attempt. The result must match want when
+// set, otherwise the SHA-256 advertised by the server (Hugging Face sends it
+// as X-Linked-Etag); on a mismatch the partial file is discarded.
+func downloadFile(ctx context.Context, client *http.Client, url, destPath, want string) (string, error) {
+	partPath := destPath + ".part"
+	var offset int64
+	if fi, err := os.Stat(partPath); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", url, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// The checksum header is on the hub's redirect, not the CDN's response.
+	var advertised string
+	c := *client
+	c.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if sum := advertisedSHA256(r.Response); sum != "" {
+			advertised = sum
+		}
+		return nil
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if sum := advertisedSHA256(resp); sum != "" {
+		advertised = sum
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		flags |= os.O_TRUNC // the server ignored the range; start over
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is complete or stale; verify it below.
+		flags = 0
+	default:
+		return "", fmt.Errorf("download %s: HTTP %d", url, resp.StatusCode)
 	}
 
-	if err := downloadFile(modelURL, modelPath); err != nil {
-		return "", fmt.Errorf("onnx: download model: %w", err)
+	if flags != 0 {
+		out, err := os.OpenFile(partPath, flags, 0o644)
+		if err != nil {
+			return "", fmt.Errorf("create %s: %w", partPath, err)
+		}
+		_, copyErr := io.Copy(out, resp.Body)
+		if closeErr := out.Close(); copyErr == nil {
+			copyErr = closeErr
+		}
+		if copyErr != nil {
+			// Keep the partial file for the next attempt to resume.
+			return "", fmt.Errorf("write %s: %w", partPath, copyErr)
+		}
+	}
+
+	sum, _, err := hashFile(partPath)
+	if err != nil {
+		return "", err
 	}
+	if want == "" {
+		want = advertised
+	}
+	if want != "" && sum != want {
+		os.Remove(partPath)
+		return "", fmt.Errorf("download %s: checksum mismatch: got sha256 %s, want %s", url, sum, want)
+	}
+
+	if err := os.Rename(partPath, destPath); err != nil {
+		return "", fmt.Errorf("rename %s → %s: %w", partPath, destPath, err)
+	}
+	return sum, nil
+}
+
+// advertisedSHA256 returns the SHA-256 in resp's X-Linked-Etag header, or ""
+// when there is none. Hugging Face sends it for files stored in LFS.
+func advertisedSHA256(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	etag := strings.Trim(resp.Header.Get("X-Linked-Etag"), `"`)
+	if len(etag) != sha256.Size*2 {
+		return "" // not a SHA-256, e.g. a git blob hash
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return ""
+	}
+	return strings.ToLower(etag)
+}
 
-	return modelPath, nil
+// hashFile returns path's SHA-256 and size.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
 }
 
 // onnxRuntimeLibName returns the expected shared library filename for the current platform.
@@ -69,11 +296,11 @@ func onnxRuntimeLibName() string {
 	}
 }
 
-// ensureONNXRuntime checks for the ONNX Runtime shared library and returns its path.
+// FindONNXRuntime locates the ONNX Runtime shared library and returns its path.
 // The library is expected in modelDir or a system-discoverable location.
-func ensureONNXRuntime(modelDir string) (string, error) {
+func FindONNXRuntime(modelDir string) (string, error) {
 	if modelDir == "" {
-		modelDir = defaultModelDir()
+		modelDir = DefaultModelDir()
 	}
 
 	libName := onnxRuntimeLibName()
@@ -90,6 +317,13 @@ func ensureONNXRuntime(modelDir string) (string, error) {
 		"/usr/lib/" + libName,
 		"/opt/homebrew/lib/" + libName,
 	}
+	if runtime.GOOS == "windows" {
+		// Windows has no standard library directory; DLLs are found on PATH.
+		systemPaths = nil
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			systemPaths = append(systemPaths, filepath.Join(dir, libName))
+		}
+	}
 	for _, p := range systemPaths {
 		if _, err := os.Stat(p); err == nil {
 			return p, nil
@@ -104,36 +338,3 @@ func ensureONNXRuntime(modelDir string) (string, error) {
 
 	return "", fmt.Errorf("onnx runtime: shared library not found at %s — download from %s or install via package manager", libPath, url)
 }
-
-// downloadFile downloads a URL to a local file path.
-func downloadFile(url, destPath string) error {
-	resp, err := http.Get(url) //nolint:gosec // URL is a hardcoded constant
-	if err != nil {
-		return fmt.Errorf("download %s: %w", url, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download %s: HTTP %d", url, resp.StatusCode)
-	}
-
-	tmpPath := destPath + ".tmp"
-	out, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("create %s: %w", tmpPath, err)
-	}
-
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		out.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("write %s: %w", tmpPath, err)
-	}
-	out.Close()
-
-	if err := os.Rename(tmpPath, destPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("rename %s → %s: %w", tmpPath, destPath, err)
-	}
-
-	return nil
-}
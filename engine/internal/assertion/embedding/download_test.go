@@ -0,0 +1,210 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPayload = bytes.Repeat([]byte("attest model bytes "), 1000)
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// hubServer serves testPayload at /file, behind a redirect from /resolve
+// that advertises etag like the Hugging Face hub.
+func hubServer(t *testing.T, etag string) (*httptest.Server, *atomic.Value) {
+	t.Helper()
+	var lastRange atomic.Value
+	lastRange.Store("")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/resolve", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Linked-Etag", `"`+etag+`"`)
+		http.Redirect(w, r, "/file", http.StatusFound)
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		lastRange.Store(r.Header.Get("Range"))
+		http.ServeContent(w, r, "model", time.Time{}, bytes.NewReader(testPayload))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &lastRange
+}
+
+func TestDownloadFile_VerifiesAdvertisedChecksum(t *testing.T) {
+	srv, _ := hubServer(t, sha256Hex(testPayload))
+	dest := filepath.Join(t.TempDir(), "model.onnx")
+
+	sum, err := downloadFile(context.Background(), srv.Client(), srv.URL+"/resolve", dest, "")
+	if err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if sum != sha256Hex(testPayload) {
+		t.Errorf("sum = %s, want %s", sum, sha256Hex(testPayload))
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, testPayload) {
+		t.Error("downloaded file does not match the payload")
+	}
+}
+
+func TestDownloadFile_RejectsChecksumMismatch(t *testing.T) {
+	tests := []struct {
+		name, advertised, want string
+	}{
+		{"advertised", strings.Repeat("ab", 32), ""},
+		{"pinned", "", strings.Repeat("cd", 32)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := hubServer(t, tt.advertised)
+			dest := filepath.Join(t.TempDir(), "model.onnx")
+
+			_, err := downloadFile(context.Background(), srv.Client(), srv.URL+"/resolve", dest, tt.want)
+			if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+				t.Fatalf("expected checksum mismatch, got %v", err)
+			}
+			for _, p := range []string{dest, dest + ".part"} {
+				if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("expected %s to be removed", p)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadFile_Resumes(t *testing.T) {
+	srv, lastRange := hubServer(t, sha256Hex(testPayload))
+	dest := filepath.Join(t.TempDir(), "model.onnx")
+	half := len(testPayload) / 2
+	if err := os.WriteFile(dest+".part", testPayload[:half], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := downloadFile(context.Background(), srv.Client(), srv.URL+"/resolve", dest, ""); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got := lastRange.Load().(string); got != "bytes=9500-" {
+		t.Errorf("Range = %q, want bytes=9500-", got)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, testPayload) {
+		t.Error("resumed file does not match the payload")
+	}
+}
+
+func TestEnsureModelFiles_Offline(t *testing.T) {
+	_, _, err := ensureModelFiles(context.Background(), t.TempDir(), true)
+	if !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "attest-engine models download") {
+		t.Errorf("expected instructions in %q", err)
+	}
+}
+
+func TestDownloadModelFile_VerifiesPinnedChecksum(t *testing.T) {
+	srv, _ := hubServer(t, "")
+	dir := t.TempDir()
+	f := ModelFile{Name: "model", File: modelFileName, URL: srv.URL + "/resolve", SHA256: sha256Hex(testPayload)}
+
+	if _, err := downloadModelFile(context.Background(), srv.Client(), dir, f); err != nil {
+		t.Fatalf("downloadModelFile: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, modelFileName)); !bytes.Equal(got, testPayload) {
+		t.Error("downloaded file does not match the payload")
+	}
+
+	// A file that differs from the pin is rejected, even with no advertised
+	// checksum, as a non-LFS file like the vocabulary has none.
+	os.Remove(filepath.Join(dir, modelFileName))
+	f.SHA256 = strings.Repeat("00", 32)
+	if _, err := downloadModelFile(context.Background(), srv.Client(), dir, f); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("err = %v, want a pinned checksum mismatch", err)
+	}
+}
+
+func TestModelFiles_Pinned(t *testing.T) {
+	for _, f := range ModelFiles {
+		if !strings.Contains(f.URL, "/resolve/"+modelRevision+"/") {
+			t.Errorf("%s URL %s is not pinned to revision %s", f.Name, f.URL, modelRevision)
+		}
+		if b, err := hex.DecodeString(f.SHA256); err != nil || len(b) != sha256.Size {
+			t.Errorf("%s SHA256 %q is not a SHA-256", f.Name, f.SHA256)
+		}
+	}
+}
+
+func TestVerifyModels(t *testing.T) {
+	saved := ModelFiles
+	t.Cleanup(func() { ModelFiles = saved })
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, modelFileName), testPayload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct{ pin, want string }{
+		{sha256Hex(testPayload), "ok"},
+		{strings.Repeat("00", 32), "mismatch"},
+	} {
+		ModelFiles = []ModelFile{
+			{Name: "model", File: modelFileName, SHA256: tt.pin},
+			{Name: "vocabulary", File: vocabFileName, SHA256: tt.pin},
+		}
+		statuses, err := VerifyModels(dir)
+		if err != nil {
+			t.Fatalf("VerifyModels: %v", err)
+		}
+		if statuses[0].Status != tt.want || statuses[1].Status != "missing" {
+			t.Errorf("pin %s: statuses %+v, want model %q and missing vocabulary", tt.pin[:8], statuses, tt.want)
+		}
+	}
+}
+
+func TestDownloadModels_ReplacesMismatchedFiles(t *testing.T) {
+	srv, _ := hubServer(t, "")
+	saved := ModelFiles
+	t.Cleanup(func() { ModelFiles = saved })
+	ModelFiles = []ModelFile{{Name: "model", File: modelFileName, URL: srv.URL + "/resolve", SHA256: sha256Hex(testPayload)}}
+	dir := t.TempDir()
+	path := filepath.Join(dir, modelFileName)
+	if err := os.WriteFile(path, []byte("corrupted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := DownloadModels(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("DownloadModels: %v", err)
+	}
+	if statuses[0].Status != "ok" {
+		t.Errorf("status = %q, want ok", statuses[0].Status)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, testPayload) {
+		t.Error("mismatched file was not replaced")
+	}
+}
+
+func TestAdvertisedSHA256(t *testing.T) {
+	sum := sha256Hex([]byte("x"))
+	for etag, want := range map[string]string{
+		`"` + strings.ToUpper(sum) + `"`:             sum,
+		`"da39a3ee5e6b4b0d3255bfef95601890afd80709"`: "", // git blob hash
+		"":                                   "",
+		`"` + strings.Repeat("zz", 32) + `"`: "",
+	} {
+		resp := &http.Response{Header: http.Header{"X-Linked-Etag": []string{etag}}}
+		if got := advertisedSHA256(resp); got != want {
+			t.Errorf("advertisedSHA256(%s) = %q, want %q", etag, got, want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package embedding
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"unicode/utf8"
 )
 
 // Embedder produces vector embeddings for text.
@@ -11,8 +13,56 @@ type Embedder interface {
 	Model() string
 }
 
+// BatchEmbedder is implemented by embedders that embed several texts more
+// cheaply in one call than one at a time. Vectors are returned in text order.
+type BatchEmbedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// ModelInfo is implemented by embedders that know their output dimension and
+// model revision up front. Caches use it to reject vectors from other versions.
+type ModelInfo interface {
+	Dimensions() int
+	Revision() string
+}
+
+// InputLimit is implemented by embedders whose model truncates long input.
+// MaxInputTokens is the longest input, in model tokens, embedded in full.
+type InputLimit interface {
+	MaxInputTokens() int
+}
+
+// Pricing is implemented by embedders billed per input token.
+type Pricing interface {
+	// CostPer1MTokens is the price in USD of a million input tokens.
+	CostPer1MTokens() float64
+}
+
+// EstimateCost estimates what embedding text with e costs, at four
+// characters per token. Embedders that do not implement Pricing, such as the
+// local ONNX model, cost nothing.
+func EstimateCost(e any, text string) float64 {
+	p, ok := e.(Pricing)
+	if !ok {
+		return 0
+	}
+	tokens := (utf8.RuneCountInString(text) + 3) / 4
+	return float64(tokens) * p.CostPer1MTokens() / 1_000_000
+}
+
 var errONNXNotAvailable = errors.New("onnx embedding: not compiled — rebuild with -tags onnx")
 
+// ONNX execution providers. CPU is always available; the others need an
+// onnxruntime library built with them and a matching device.
+const (
+	ExecutionProviderCPU    = "cpu"
+	ExecutionProviderCUDA   = "cuda"
+	ExecutionProviderCoreML = "coreml"
+)
+
+// ExecutionProviders lists the accepted ONNX execution providers.
+var ExecutionProviders = []string{ExecutionProviderCPU, ExecutionProviderCUDA, ExecutionProviderCoreML}
+
 // EmbedderConfig holds configuration for creating an Embedder.
 type EmbedderConfig struct {
 	Provider string
@@ -20,4 +70,11 @@ type EmbedderConfig struct {
 	APIKey   string
 	BaseURL  string
 	ModelDir string
+	// ExecutionProvider selects where the ONNX model runs (default CPU).
+	// An unusable provider falls back to CPU with a warning.
+	ExecutionProvider string
+	// Offline forbids downloading missing model files.
+	Offline bool
+	// Logger receives ONNX setup warnings. Defaults to slog.Default().
+	Logger *slog.Logger
 }
@@ -5,37 +5,52 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math"
 	"sync"
+	"time"
 
 	ort "github.com/yalue/onnxruntime_go"
+
+	"github.com/attest-ai/attest/engine/internal/timing"
 )
 
 const (
-	onnxModelName    = "all-MiniLM-L6-v2"
+	onnxModelName = "all-MiniLM-L6-v2"
+	onnxRevision  = "main"
+	// onnxTokenizer versions tokenization, which changes vectors as much as
+	// the weights do; vectors from the earlier hashing tokenizer carry the
+	// bare revision and are rejected by the cache.
+	onnxTokenizer    = "wordpiece"
 	onnxEmbeddingDim = 384
 	onnxMaxTokenLen  = 128
-	onnxBatchSize    = 1
+	// onnxBatchSize bounds the texts run through the model at once.
+	onnxBatchSize = 32
 )
 
 // ONNXAvailable indicates that the ONNX embedding provider is compiled in.
 const ONNXAvailable = true
 
-// ONNXEmbedder produces embeddings using a local ONNX model.
+// ONNXEmbedder produces embeddings using a local ONNX model. One session is
+// created up front and shared by every call.
 type ONNXEmbedder struct {
+	// mu serializes inference on the shared session.
 	mu        sync.Mutex
-	modelPath string
+	session   *ort.DynamicAdvancedSession
+	tokenizer *wordPiece
+	ep        string
 }
 
 // NewONNXEmbedder creates an Embedder backed by a local ONNX model.
-// On first use it downloads the model to cfg.ModelDir (default ~/.attest/models/).
+// On first use it downloads the model and its vocabulary to cfg.ModelDir
+// (default DefaultModelDir), unless cfg.Offline is set.
 func NewONNXEmbedder(cfg EmbedderConfig) (Embedder, error) {
 	modelDir := cfg.ModelDir
 	if modelDir == "" {
-		modelDir = defaultModelDir()
+		modelDir = DefaultModelDir()
 	}
 
-	libPath, err := ensureONNXRuntime(modelDir)
+	libPath, err := FindONNXRuntime(modelDir)
 	if err != nil {
 		return nil, fmt.Errorf("onnx embedder: %w", err)
 	}
@@ -45,30 +60,119 @@ func NewONNXEmbedder(cfg EmbedderConfig) (Embedder, error) {
 		return nil, fmt.Errorf("onnx embedder: initialize environment: %w", err)
 	}
 
-	modelPath, err := ensureModel(modelDir)
+	modelPath, vocabPath, err := ensureModelFiles(context.Background(), modelDir, cfg.Offline)
+	if err != nil {
+		return nil, fmt.Errorf("onnx embedder: %w", err)
+	}
+	tokenizer, err := loadWordPiece(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("onnx embedder: %w", err)
+	}
+
+	ep := cfg.ExecutionProvider
+	if ep == "" {
+		ep = ExecutionProviderCPU
+	}
+	session, active, fallback, err := newONNXSession(modelPath, ep)
 	if err != nil {
 		return nil, fmt.Errorf("onnx embedder: %w", err)
 	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if fallback != nil {
+		logger.Warn("onnx execution provider unavailable, using cpu", "requested", ep, "err", fallback)
+	}
+	logger.Info("onnx embedder ready", "execution_provider", active)
 
 	return &ONNXEmbedder{
-		modelPath: modelPath,
+		session:   session,
+		tokenizer: tokenizer,
+		ep:        active,
 	}, nil
 }
 
+// ExecutionProvider returns the execution provider the model runs on.
+func (e *ONNXEmbedder) ExecutionProvider() string { return e.ep }
+
 // Model returns the ONNX model name.
 func (e *ONNXEmbedder) Model() string { return onnxModelName }
 
+// Dimensions returns the embedding vector length.
+func (e *ONNXEmbedder) Dimensions() int { return onnxEmbeddingDim }
+
+// Revision returns the revision of the downloaded model weights and the
+// tokenizer version.
+func (e *ONNXEmbedder) Revision() string { return onnxRevision + "+" + onnxTokenizer }
+
+// MaxInputTokens returns the tokens embedded before truncation, excluding
+// the [CLS] and [SEP] markers.
+func (e *ONNXEmbedder) MaxInputTokens() int { return onnxMaxTokenLen - 2 }
+
+// Close releases the inference session.
+func (e *ONNXEmbedder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.session.Destroy()
+}
+
 // Embed produces a normalized embedding vector for the given text.
-func (e *ONNXEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+func (e *ONNXEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// EmbedBatch produces normalized embedding vectors for texts, running up to
+// onnxBatchSize of them through the model at once.
+func (e *ONNXEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	start := time.Now()
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	defer func() { timing.FromContext(ctx).ProviderCall("onnx", time.Since(start)) }()
+
+	vecs := make([][]float32, 0, len(texts))
+	for i := 0; i < len(texts); i += onnxBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("onnx embed: %w", err)
+		}
+		batch, err := e.run(texts[i:min(i+onnxBatchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vecs = append(vecs, batch...)
+	}
+	return vecs, nil
+}
 
-	ids, mask := tokenize(text, onnxMaxTokenLen)
-	typeIDs := make([]int64, onnxMaxTokenLen)
+// run embeds one batch, padding every text to the longest in the batch.
+func (e *ONNXEmbedder) run(texts []string) ([][]float32, error) {
+	encoded := make([][]int64, len(texts))
+	seqLen := 0
+	for i, text := range texts {
+		encoded[i] = e.tokenizer.encode(text, onnxMaxTokenLen)
+		seqLen = max(seqLen, len(encoded[i]))
+	}
 
-	shape := ort.NewShape(int64(onnxBatchSize), int64(onnxMaxTokenLen))
-	outShape := ort.NewShape(int64(onnxBatchSize), int64(onnxMaxTokenLen), int64(onnxEmbeddingDim))
+	n := len(texts)
+	ids := make([]int64, n*seqLen)
+	mask := make([]int64, n*seqLen)
+	typeIDs := make([]int64, n*seqLen)
+	for i, enc := range encoded {
+		row := ids[i*seqLen : (i+1)*seqLen]
+		for j := range row {
+			row[j] = e.tokenizer.pad
+		}
+		copy(row, enc)
+		for j := range enc {
+			mask[i*seqLen+j] = 1
+		}
+	}
 
+	shape := ort.NewShape(int64(n), int64(seqLen))
 	inputTensor, err := ort.NewTensor(shape, ids)
 	if err != nil {
 		return nil, fmt.Errorf("onnx embed: create input_ids tensor: %w", err)
@@ -87,35 +191,29 @@ func (e *ONNXEmbedder) Embed(_ context.Context, text string) ([]float32, error)
 	}
 	defer typeTensor.Destroy()
 
-	outputData := make([]float32, onnxBatchSize*onnxMaxTokenLen*onnxEmbeddingDim)
-	outputTensor, err := ort.NewTensor(outShape, outputData)
+	outputTensor, err := ort.NewEmptyTensor[float32](ort.NewShape(int64(n), int64(seqLen), int64(onnxEmbeddingDim)))
 	if err != nil {
 		return nil, fmt.Errorf("onnx embed: create output tensor: %w", err)
 	}
 	defer outputTensor.Destroy()
 
-	session, err := ort.NewAdvancedSession(
-		e.modelPath,
-		[]string{"input_ids", "attention_mask", "token_type_ids"},
-		[]string{"last_hidden_state"},
+	err = e.session.Run(
 		[]ort.Value{inputTensor, maskTensor, typeTensor},
 		[]ort.Value{outputTensor},
-		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("onnx embed: create session: %w", err)
-	}
-	defer session.Destroy()
-
-	if err := session.Run(); err != nil {
 		return nil, fmt.Errorf("onnx embed: run inference: %w", err)
 	}
 
 	rawOutput := outputTensor.GetData()
-	result := meanPool(rawOutput, mask, onnxMaxTokenLen, onnxEmbeddingDim)
-	l2Normalize(result)
-
-	return result, nil
+	rowLen := seqLen * onnxEmbeddingDim
+	vecs := make([][]float32, n)
+	for i := range vecs {
+		vec := meanPool(rawOutput[i*rowLen:(i+1)*rowLen], mask[i*seqLen:(i+1)*seqLen], seqLen, onnxEmbeddingDim)
+		l2Normalize(vec)
+		vecs[i] = vec
+	}
+	return vecs, nil
 }
 
 // meanPool computes the mean of token embeddings weighted by attention mask.
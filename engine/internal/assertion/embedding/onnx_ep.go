@@ -0,0 +1,69 @@
+//go:build onnx
+
+package embedding
+
+import (
+	"fmt"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// newONNXSession creates the inference session on the requested execution
+// provider. When the provider cannot be used, for example because the
+// onnxruntime library was built without it or no device is present, the
+// session falls back to CPU and the reason is returned as fallback.
+func newONNXSession(modelPath, ep string) (session *ort.DynamicAdvancedSession, active string, fallback error, err error) {
+	if ep != ExecutionProviderCPU {
+		session, err := newONNXSessionOn(modelPath, ep)
+		if err == nil {
+			return session, ep, nil, nil
+		}
+		fallback = err
+	}
+	session, err = newONNXSessionOn(modelPath, ExecutionProviderCPU)
+	if err != nil {
+		return nil, "", fallback, err
+	}
+	return session, ExecutionProviderCPU, fallback, nil
+}
+
+func newONNXSessionOn(modelPath, ep string) (*ort.DynamicAdvancedSession, error) {
+	var options *ort.SessionOptions
+	if ep != ExecutionProviderCPU {
+		var err error
+		if options, err = ort.NewSessionOptions(); err != nil {
+			return nil, fmt.Errorf("session options: %w", err)
+		}
+		defer options.Destroy()
+		if err := appendExecutionProvider(options, ep); err != nil {
+			return nil, fmt.Errorf("enable %s execution provider: %w", ep, err)
+		}
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(
+		modelPath,
+		[]string{"input_ids", "attention_mask", "token_type_ids"},
+		[]string{"last_hidden_state"},
+		options,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create %s session: %w", ep, err)
+	}
+	return session, nil
+}
+
+func appendExecutionProvider(options *ort.SessionOptions, ep string) error {
+	switch ep {
+	case ExecutionProviderCUDA:
+		cuda, err := ort.NewCUDAProviderOptions()
+		if err != nil {
+			return err
+		}
+		defer cuda.Destroy()
+		return options.AppendExecutionProviderCUDA(cuda)
+	case ExecutionProviderCoreML:
+		return options.AppendExecutionProviderCoreMLV2(map[string]string{})
+	default:
+		return fmt.Errorf("unknown execution provider %q", ep)
+	}
+}
@@ -8,11 +8,19 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
 )
 
 const (
 	openAIDefaultModel   = "text-embedding-3-small"
 	openAIDefaultBaseURL = "https://api.openai.com/v1"
+	// openAIMaxInputTokens is the input limit of the OpenAI embedding models.
+	openAIMaxInputTokens = 8191
+	// openAIEmbeddingProvider names the embeddings API in batch timings.
+	openAIEmbeddingProvider = "openai_embeddings"
 )
 
 // OpenAIEmbedder calls the OpenAI embeddings API.
@@ -48,6 +56,21 @@ func NewOpenAIEmbedder(cfg EmbedderConfig) (*OpenAIEmbedder, error) {
 // Model returns the embedding model name.
 func (e *OpenAIEmbedder) Model() string { return e.model }
 
+// MaxInputTokens returns the longest input the API embeds.
+func (e *OpenAIEmbedder) MaxInputTokens() int { return openAIMaxInputTokens }
+
+// openAIPricePer1M is the input price in USD per million tokens of the
+// OpenAI embedding models.
+var openAIPricePer1M = map[string]float64{
+	"text-embedding-3-small": 0.02,
+	"text-embedding-3-large": 0.13,
+	"text-embedding-ada-002": 0.10,
+}
+
+// CostPer1MTokens returns the model's input price, or 0 for a model served
+// by an OpenAI-compatible endpoint with unknown pricing.
+func (e *OpenAIEmbedder) CostPer1MTokens() float64 { return openAIPricePer1M[e.model] }
+
 type openAIEmbedRequest struct {
 	Input string `json:"input"`
 	Model string `json:"model"`
@@ -76,8 +99,13 @@ func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set("X-Client-Request-Id", id)
+	}
 
+	start := time.Now()
 	resp, err := e.client.Do(req)
+	timing.FromContext(ctx).ProviderCall(openAIEmbeddingProvider, time.Since(start))
 	if err != nil {
 		return nil, fmt.Errorf("openai embed: http: %w", err)
 	}
@@ -103,3 +131,9 @@ func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 
 	return result.Data[0].Embedding, nil
 }
+
+// ValidateModel checks that the embedding model exists and the API key can
+// use it.
+func (e *OpenAIEmbedder) ValidateModel(ctx context.Context) error {
+	return llm.CheckOpenAIModel(ctx, e.client, e.baseURL, e.apiKey, e.model)
+}
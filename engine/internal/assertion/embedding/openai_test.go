@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -70,6 +71,23 @@ func TestOpenAIEmbedder_CustomModel(t *testing.T) {
 	}
 }
 
+func TestEstimateCost(t *testing.T) {
+	text := strings.Repeat("abcd", 1000) // 1000 tokens
+	for model, want := range map[string]float64{
+		"text-embedding-3-small": 0.00002,
+		"text-embedding-3-large": 0.00013,
+		"local-compatible-model": 0,
+	} {
+		e, _ := NewOpenAIEmbedder(EmbedderConfig{APIKey: "sk-test", Model: model})
+		if got := EstimateCost(e, text); got < want-1e-12 || got > want+1e-12 {
+			t.Errorf("EstimateCost(%s) = %v, want %v", model, got, want)
+		}
+	}
+	if got := EstimateCost(struct{}{}, text); got != 0 {
+		t.Errorf("EstimateCost of an unpriced embedder = %v, want 0", got)
+	}
+}
+
 func TestOpenAIEmbedder_MissingAPIKey(t *testing.T) {
 	_, err := NewOpenAIEmbedder(EmbedderConfig{})
 	if err == nil {
@@ -0,0 +1,166 @@
+package embedding
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordChars is the longest word WordPiece splits; longer words map to [UNK].
+const maxWordChars = 100
+
+// wordPiece is the BERT uncased tokenizer used by MiniLM: basic
+// tokenization (cleanup, lowercasing, accent stripping, punctuation and CJK
+// splitting) followed by greedy longest-match WordPiece against the model's
+// vocabulary.
+type wordPiece struct {
+	vocab              map[string]int64
+	cls, sep, unk, pad int64
+}
+
+// loadWordPiece reads a vocab.txt file: one token per line, the line number
+// being its ID.
+func loadWordPiece(path string) (*wordPiece, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	var tokens []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		tokens = append(tokens, strings.TrimRight(sc.Text(), "\r"))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read vocabulary: %w", err)
+	}
+	return newWordPiece(tokens)
+}
+
+// newWordPiece builds a tokenizer from tokens in ID order.
+func newWordPiece(tokens []string) (*wordPiece, error) {
+	w := &wordPiece{vocab: make(map[string]int64, len(tokens))}
+	for id, tok := range tokens {
+		if _, dup := w.vocab[tok]; !dup {
+			w.vocab[tok] = int64(id)
+		}
+	}
+	for _, special := range []struct {
+		token string
+		id    *int64
+	}{{"[CLS]", &w.cls}, {"[SEP]", &w.sep}, {"[UNK]", &w.unk}, {"[PAD]", &w.pad}} {
+		id, ok := w.vocab[special.token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary has no %s token", special.token)
+		}
+		*special.id = id
+	}
+	return w, nil
+}
+
+// encode returns the token IDs for text wrapped in [CLS] and [SEP],
+// truncated to at most maxLen IDs.
+func (w *wordPiece) encode(text string, maxLen int) []int64 {
+	ids := []int64{w.cls}
+	for _, word := range basicTokenize(text) {
+		for _, id := range w.wordIDs(word) {
+			if len(ids) >= maxLen-1 {
+				return append(ids, w.sep)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return append(ids, w.sep)
+}
+
+// wordIDs splits one word greedily into the longest vocabulary pieces,
+// continuation pieces carrying a "##" prefix. A word that cannot be fully
+// covered is a single [UNK].
+func (w *wordPiece) wordIDs(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return []int64{w.unk}
+	}
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := w.vocab[piece]; ok {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []int64{w.unk}
+		}
+		start = end
+	}
+	return ids
+}
+
+// basicTokenize cleans, lowercases, and strips accents from text, then
+// splits it on whitespace and around punctuation and CJK characters.
+func basicTokenize(text string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || isControl(r):
+			continue
+		case unicode.Is(unicode.Mn, r):
+			continue // combining accent left by NFD
+		case isWhitespace(r):
+			b.WriteByte(' ')
+		case isPunctuation(r) || isCJK(r):
+			b.WriteByte(' ')
+			b.WriteRune(r)
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+func isWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || unicode.Is(unicode.Zs, r)
+}
+
+func isControl(r rune) bool {
+	if r == '\t' || r == '\n' || r == '\r' {
+		return false
+	}
+	return unicode.In(r, unicode.Cc, unicode.Cf)
+}
+
+// isPunctuation follows BERT: every non-alphanumeric ASCII character counts,
+// such as "$" and "^", as does any Unicode punctuation.
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isCJK reports whether r is in a CJK Unified Ideographs block, which BERT
+// tokenizes one character at a time.
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
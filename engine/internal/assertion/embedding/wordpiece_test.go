@@ -0,0 +1,114 @@
+package embedding
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testVocab = []string{
+	"[PAD]", "[UNK]", "[CLS]", "[SEP]",
+	"un", "##aff", "##able", "the", "cafe", "refund", "##s", ",", "!", "$", "5", "中", "文", "hello",
+}
+
+func newTestWordPiece(t *testing.T) *wordPiece {
+	t.Helper()
+	w, err := newWordPiece(testVocab)
+	if err != nil {
+		t.Fatalf("newWordPiece: %v", err)
+	}
+	return w
+}
+
+// pieces maps the IDs WordPiece assigns to text back to vocabulary tokens.
+func pieces(w *wordPiece, text string) []string {
+	var out []string
+	for _, word := range basicTokenize(text) {
+		for _, id := range w.wordIDs(word) {
+			out = append(out, testVocab[id])
+		}
+	}
+	return out
+}
+
+func TestBasicTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello,  World!", []string{"hello", ",", "world", "!"}},
+		{"Café\tnaïve", []string{"cafe", "naive"}},
+		{"costs $5.", []string{"costs", "$", "5", "."}},
+		{"中文abc", []string{"中", "文", "abc"}},
+		{"zero\x00width​join", []string{"zerowidthjoin"}},
+		{"line\nbreak", []string{"line", "break"}},
+	}
+	for _, tt := range tests {
+		if got := basicTokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("basicTokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordPiece_Pieces(t *testing.T) {
+	w := newTestWordPiece(t)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"unaffable", []string{"un", "##aff", "##able"}},
+		{"Refunds!", []string{"refund", "##s", "!"}},
+		{"the CAFÉ", []string{"the", "cafe"}},
+		// A word that cannot be fully covered is a single [UNK].
+		{"unknowable", []string{"[UNK]"}},
+		{"中文", []string{"中", "文"}},
+		{strings.Repeat("un", 51), []string{"[UNK]"}},
+	}
+	for _, tt := range tests {
+		if got := pieces(w, tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("pieces(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordPiece_Encode(t *testing.T) {
+	w := newTestWordPiece(t)
+
+	got := w.encode("hello, unaffable", 16)
+	want := []int64{2, 17, 11, 4, 5, 6, 3}
+	if !slices.Equal(got, want) {
+		t.Errorf("encode = %v, want %v", got, want)
+	}
+
+	// Truncation keeps [CLS] and [SEP] within maxLen.
+	got = w.encode("hello, unaffable", 4)
+	want = []int64{2, 17, 11, 3}
+	if !slices.Equal(got, want) {
+		t.Errorf("truncated encode = %v, want %v", got, want)
+	}
+}
+
+func TestWordPiece_RequiresSpecialTokens(t *testing.T) {
+	if _, err := newWordPiece([]string{"[PAD]", "[UNK]", "[CLS]", "hello"}); err == nil || !strings.Contains(err.Error(), "[SEP]") {
+		t.Errorf("expected a missing [SEP] error, got %v", err)
+	}
+}
+
+func TestLoadWordPiece(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(testVocab, "\r\n")+"\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := loadWordPiece(path)
+	if err != nil {
+		t.Fatalf("loadWordPiece: %v", err)
+	}
+	if w.cls != 2 || w.sep != 3 || w.unk != 1 || w.pad != 0 {
+		t.Errorf("special IDs = cls %d sep %d unk %d pad %d", w.cls, w.sep, w.unk, w.pad)
+	}
+	if id := w.vocab["hello"]; id != 17 {
+		t.Errorf("hello ID = %d, want 17", id)
+	}
+}
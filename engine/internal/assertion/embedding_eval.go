@@ -4,11 +4,17 @@ import (
 	"context"
 	"github.com/segmentio/encoding/json"
 	"fmt"
-	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
 	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
@@ -16,22 +22,61 @@ import (
 type EmbeddingEvaluator struct {
 	embedder embedding.Embedder
 	cache    *cache.EmbeddingCache
+	// dim is the vector length observed from fresh embeddings; used to reject
+	// stale cache entries when the embedder does not implement ModelInfo.
+	dim atomic.Int64
+
+	// refs pins reference vectors in memory, keyed by reference text, so
+	// suites that reuse a reference across many traces embed it (or read it
+	// from cache) once and reuse its precomputed magnitude.
+	refMu sync.Mutex
+	refs  map[string]*pinnedReference
+}
+
+// maxPinnedReferences bounds the pinned reference vectors: about 12 MB at
+// 3072 dimensions. References beyond it are looked up on every use.
+const maxPinnedReferences = 1024
+
+// pinnedReference is a reference vector embedded once and shared by
+// concurrent evaluations.
+type pinnedReference struct {
+	once sync.Once
+	ref  *embedding.Reference
+	err  error
 }
 
 // NewEmbeddingEvaluator creates an evaluator using the given embedder and optional cache.
 // cache may be nil to disable caching.
 func NewEmbeddingEvaluator(embedder embedding.Embedder, c *cache.EmbeddingCache) *EmbeddingEvaluator {
-	return &EmbeddingEvaluator{embedder: embedder, cache: c}
+	return &EmbeddingEvaluator{embedder: embedder, cache: c, refs: make(map[string]*pinnedReference)}
 }
 
 // embeddingSpec is the expected structure of the assertion spec JSON.
 type embeddingSpec struct {
-	Target     string  `json:"target"`
-	Reference  string  `json:"reference"`
-	Threshold  float64 `json:"threshold"`
-	Soft       bool    `json:"soft"`
+	Target    string  `json:"target"`
+	Reference string  `json:"reference"`
+	Threshold float64 `json:"threshold"`
+	Soft      bool    `json:"soft"`
+	// ChunkSize is the chunk length in words. Zero derives it from the
+	// embedder's input limit; targets that fit in one chunk are not split.
+	ChunkSize    int  `json:"chunk_size"`
+	ChunkOverlap *int `json:"chunk_overlap"`
+	// Aggregate combines per-chunk similarities: "max" (default), "mean",
+	// or "top_k" (mean of the TopK highest).
+	Aggregate string `json:"aggregate"`
+	TopK      int    `json:"top_k"`
 }
 
+const (
+	defaultChunkTopK = 3
+	// wordsPerToken approximates how many words fit in one model token, to
+	// turn an embedder's token limit into a chunk size in words.
+	wordsPerToken = 0.75
+	// maxEmbeddingChunks bounds the chunks embedded per target, and so the
+	// embedding calls a long target costs.
+	maxEmbeddingChunks = 64
+)
+
 // Evaluate runs the embedding similarity assertion against the trace.
 func (e *EmbeddingEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
 	start := time.Now()
@@ -49,27 +94,57 @@ func (e *EmbeddingEvaluator) Evaluate(trace *types.Trace, assertion *types.Asser
 	if spec.Threshold <= 0 {
 		spec.Threshold = 0.8 // sensible default
 	}
+	switch spec.Aggregate {
+	case "":
+		spec.Aggregate = "max"
+	case "max", "mean", "top_k":
+	default:
+		return failResult(assertion, start, fmt.Sprintf("invalid embedding spec: unknown aggregate %q (want max, mean, or top_k)", spec.Aggregate))
+	}
+	if spec.TopK <= 0 {
+		spec.TopK = defaultChunkTopK
+	}
 
 	targetStr, err := ResolveTargetString(trace, spec.Target)
 	if err != nil {
 		return failResult(assertion, start, fmt.Sprintf("target resolution failed: %v", err))
 	}
+	chunks, err := e.chunks(targetStr, spec)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid embedding spec: %v", err))
+	}
 
-	ctx := context.Background()
+	ctx := batchContext(trace)
 
-	targetVec, err := e.getEmbedding(ctx, targetStr)
+	chunkVecs, ref, err := e.embedAll(ctx, chunks, spec.Reference, true)
 	if err != nil {
-		return failResult(assertion, start, fmt.Sprintf("embed target: %v", err))
+		return failResult(assertion, start, err.Error())
+	}
+	if e.cache != nil && !sameDimension(chunkVecs, ref) {
+		// One side came from a cache entry written by a different model
+		// version: re-embed everything and overwrite the stale entries.
+		logging.FromContext(ctx).Warn("embedding dimension mismatch, rebuilding cache entries",
+			"target_dim", len(chunkVecs[0]), "reference_dim", ref.Len())
+		if chunkVecs, ref, err = e.embedAll(ctx, chunks, spec.Reference, false); err != nil {
+			return failResult(assertion, start, err.Error())
+		}
 	}
 
-	refVec, err := e.getEmbedding(ctx, spec.Reference)
-	if err != nil {
-		return failResult(assertion, start, fmt.Sprintf("embed reference: %v", err))
+	sims := make([]float64, len(chunkVecs))
+	for i, vec := range chunkVecs {
+		if sims[i], err = ref.Similarity(vec); err != nil {
+			return failResult(assertion, start, fmt.Sprintf("cosine similarity: %v", err))
+		}
 	}
+	sim := aggregateSimilarity(sims, spec.Aggregate, spec.TopK)
 
-	sim, err := embedding.CosineSimilarity(targetVec, refVec)
-	if err != nil {
-		return failResult(assertion, start, fmt.Sprintf("cosine similarity: %v", err))
+	var over string
+	if len(chunks) > 1 {
+		method := spec.Aggregate
+		if method == "top_k" {
+			method = fmt.Sprintf("top-%d mean", min(spec.TopK, len(chunks)))
+		}
+		over = fmt.Sprintf(" (%s over %d chunks)", method, len(chunks))
 	}
 
 	durationMS := time.Since(start).Milliseconds()
@@ -83,7 +158,7 @@ func (e *EmbeddingEvaluator) Evaluate(trace *types.Trace, assertion *types.Asser
 			AssertionID: assertion.AssertionID,
 			Status:      types.StatusPass,
 			Score:       score,
-			Explanation: fmt.Sprintf("cosine similarity %.4f >= threshold %.4f", sim, spec.Threshold),
+			Explanation: fmt.Sprintf("cosine similarity %.4f >= threshold %.4f%s", sim, spec.Threshold, over),
 			DurationMS:  durationMS,
 			RequestID:   assertion.RequestID,
 		}
@@ -97,30 +172,267 @@ func (e *EmbeddingEvaluator) Evaluate(trace *types.Trace, assertion *types.Asser
 		AssertionID: assertion.AssertionID,
 		Status:      failStatus,
 		Score:       score,
-		Explanation: fmt.Sprintf("cosine similarity %.4f < threshold %.4f", sim, spec.Threshold),
+		Explanation: fmt.Sprintf("cosine similarity %.4f < threshold %.4f%s", sim, spec.Threshold, over),
 		DurationMS:  durationMS,
 		RequestID:   assertion.RequestID,
 	}
 }
 
+// embedAll embeds every target chunk and returns them with the reference.
+func (e *EmbeddingEvaluator) embedAll(ctx context.Context, chunks []string, reference string, readCache bool) ([][]float32, *embedding.Reference, error) {
+	var vecs [][]float32
+	if len(chunks) == 1 {
+		vec, err := e.getEmbedding(ctx, chunks[0], readCache)
+		if err != nil {
+			return nil, nil, fmt.Errorf("embed target: %v", err)
+		}
+		vecs = [][]float32{vec}
+	} else {
+		var err error
+		if vecs, err = e.getEmbeddings(ctx, chunks, readCache); err != nil {
+			return nil, nil, fmt.Errorf("embed target chunks: %v", err)
+		}
+	}
+	ref, err := e.reference(ctx, reference, readCache)
+	if err != nil {
+		return nil, nil, fmt.Errorf("embed reference: %v", err)
+	}
+	return vecs, ref, nil
+}
+
+// reference returns the pinned reference for text, embedding and pinning it
+// on first use. With readCache false the reference is re-embedded and the
+// pin replaced. Failures are not pinned.
+func (e *EmbeddingEvaluator) reference(ctx context.Context, text string, readCache bool) (*embedding.Reference, error) {
+	e.refMu.Lock()
+	p := e.refs[text]
+	if p == nil || !readCache {
+		p = &pinnedReference{}
+		if _, pinned := e.refs[text]; pinned || len(e.refs) < maxPinnedReferences {
+			e.refs[text] = p
+		}
+	}
+	e.refMu.Unlock()
+
+	p.once.Do(func() {
+		var vec []float32
+		if vec, p.err = e.getEmbedding(ctx, text, readCache); p.err == nil {
+			p.ref = embedding.NewReference(vec)
+		}
+	})
+	if p.err != nil {
+		e.refMu.Lock()
+		if e.refs[text] == p {
+			delete(e.refs, text)
+		}
+		e.refMu.Unlock()
+		return nil, p.err
+	}
+	return p.ref, nil
+}
+
+func sameDimension(vecs [][]float32, ref *embedding.Reference) bool {
+	for _, v := range vecs {
+		if len(v) != ref.Len() {
+			return false
+		}
+	}
+	return true
+}
+
+// chunks splits text into overlapping word windows of spec.ChunkSize words,
+// or of the embedder's input limit when that is unset. Text that fits, or
+// any text when neither is known, is returned whole. Text that would need
+// more than maxEmbeddingChunks windows is sampled by that many, evenly
+// spaced from its start to its end.
+func (e *EmbeddingEvaluator) chunks(text string, spec embeddingSpec) ([]string, error) {
+	if spec.ChunkSize < 0 {
+		return nil, fmt.Errorf("chunk_size must not be negative, got %d", spec.ChunkSize)
+	}
+	size := spec.ChunkSize
+	if size == 0 {
+		if il, ok := e.embedder.(embedding.InputLimit); ok {
+			size = int(float64(il.MaxInputTokens()) * wordsPerToken)
+		}
+	}
+	if size <= 0 {
+		return []string{text}, nil
+	}
+	overlap := size / 10
+	if spec.ChunkOverlap != nil {
+		overlap = *spec.ChunkOverlap
+		if overlap < 0 || overlap >= size {
+			return nil, fmt.Errorf("chunk_overlap must be in [0, %d), got %d", size, overlap)
+		}
+	}
+
+	words := strings.Fields(text)
+	if len(words) <= size {
+		return []string{text}, nil
+	}
+	step := size - overlap
+	if n := 1 + (len(words)-size+step-1)/step; n > maxEmbeddingChunks {
+		chunks := make([]string, maxEmbeddingChunks)
+		last := len(words) - size
+		for i := range chunks {
+			start := i * last / (maxEmbeddingChunks - 1)
+			chunks[i] = strings.Join(words[start:start+size], " ")
+		}
+		return chunks, nil
+	}
+	var chunks []string
+	for start := 0; ; start += step {
+		end := min(start+size, len(words))
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			return chunks, nil
+		}
+	}
+}
+
+// aggregateSimilarity combines per-chunk similarities with method.
+func aggregateSimilarity(sims []float64, method string, k int) float64 {
+	switch method {
+	case "mean":
+		return mean(sims)
+	case "top_k":
+		sorted := append([]float64(nil), sims...)
+		sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+		return mean(sorted[:min(k, len(sorted))])
+	default:
+		return slices.Max(sims)
+	}
+}
+
+// EmbedWithCost returns the embedding of text, reading and filling the
+// embedding cache like the evaluator does, with the estimated cost of the
+// call: zero for a cached vector.
+func (e *EmbeddingEvaluator) EmbedWithCost(ctx context.Context, text string) ([]float32, float64, error) {
+	vec, cached, err := e.lookupEmbedding(ctx, text, true)
+	if err != nil || cached {
+		return vec, 0, err
+	}
+	return vec, embedding.EstimateCost(e.embedder, text), nil
+}
+
 // getEmbedding retrieves an embedding vector, using cache if available.
-func (e *EmbeddingEvaluator) getEmbedding(ctx context.Context, text string) ([]float32, error) {
+// With readCache false the cache is bypassed for reads but still refreshed.
+func (e *EmbeddingEvaluator) getEmbedding(ctx context.Context, text string, readCache bool) ([]float32, error) {
+	vec, _, err := e.lookupEmbedding(ctx, text, readCache)
+	return vec, err
+}
+
+// lookupEmbedding is getEmbedding, also reporting whether the vector came
+// from the cache.
+func (e *EmbeddingEvaluator) lookupEmbedding(ctx context.Context, text string, readCache bool) ([]float32, bool, error) {
 	if e.cache != nil {
+		rec := timing.FromContext(ctx)
 		h := cache.ContentHash(text)
-		if cached, err := e.cache.Get(h, e.embedder.Model()); err == nil && cached != nil {
-			return cached, nil
+		meta := e.expectedMeta()
+		if readCache {
+			cacheStart := time.Now()
+			cached, err := e.cache.GetChecked(h, e.embedder.Model(), meta)
+			rec.Cache(time.Since(cacheStart))
+			rec.CacheLookup("embedding", err == nil && cached != nil)
+			if err == nil && cached != nil {
+				return cached, true, nil
+			}
 		}
 
 		vec, err := e.embedder.Embed(ctx, text)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
-		// Best-effort cache write — do not fail on cache errors
-		if putErr := e.cache.Put(h, e.embedder.Model(), vec); putErr != nil {
-			slog.Error("embedding cache write error", "err", putErr)
+		e.store(ctx, text, vec, meta)
+		return vec, false, nil
+	}
+
+	vec, err := e.embedder.Embed(ctx, text)
+	return vec, false, err
+}
+
+// getEmbeddings is getEmbedding for several texts. When the embedder is a
+// BatchEmbedder, the texts missing from the cache are embedded in one call.
+func (e *EmbeddingEvaluator) getEmbeddings(ctx context.Context, texts []string, readCache bool) ([][]float32, error) {
+	vecs := make([][]float32, len(texts))
+	be, ok := e.embedder.(embedding.BatchEmbedder)
+	if !ok || len(texts) == 1 {
+		for i, text := range texts {
+			vec, err := e.getEmbedding(ctx, text, readCache)
+			if err != nil {
+				return nil, fmt.Errorf("text %d of %d: %w", i+1, len(texts), err)
+			}
+			vecs[i] = vec
+		}
+		return vecs, nil
+	}
+
+	meta := e.expectedMeta()
+	var missing []int
+	for i, text := range texts {
+		if e.cache != nil && readCache {
+			cacheStart := time.Now()
+			cached, err := e.cache.GetChecked(cache.ContentHash(text), e.embedder.Model(), meta)
+			rec := timing.FromContext(ctx)
+			rec.Cache(time.Since(cacheStart))
+			rec.CacheLookup("embedding", err == nil && cached != nil)
+			if err == nil && cached != nil {
+				vecs[i] = cached
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return vecs, nil
+	}
+
+	batch := make([]string, len(missing))
+	for j, i := range missing {
+		batch[j] = texts[i]
+	}
+	fresh, err := be.EmbedBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(fresh) != len(batch) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(fresh), len(batch))
+	}
+	for j, i := range missing {
+		vecs[i] = fresh[j]
+		if e.cache != nil {
+			e.store(ctx, texts[i], fresh[j], meta)
 		}
-		return vec, nil
 	}
+	return vecs, nil
+}
+
+// store records a fresh embedding's dimension and writes it to the cache.
+// Cache errors are logged, not returned.
+func (e *EmbeddingEvaluator) store(ctx context.Context, text string, vec []float32, meta cache.EmbeddingMeta) {
+	e.dim.Store(int64(len(vec)))
+	cacheStart := time.Now()
+	putErr := e.cache.PutWithRevision(cache.ContentHash(text), e.embedder.Model(), meta.Revision, vec)
+	timing.FromContext(ctx).Cache(time.Since(cacheStart))
+	if putErr != nil {
+		logging.FromContext(ctx).Error("embedding cache write error", "err", putErr)
+	}
+}
 
-	return e.embedder.Embed(ctx, text)
+// expectedMeta returns the dimension and revision cached vectors must match:
+// from the embedder when it implements ModelInfo, otherwise the dimension of
+// the most recent fresh embedding (zero, i.e. unchecked, before the first one).
+func (e *EmbeddingEvaluator) expectedMeta() cache.EmbeddingMeta {
+	if mi, ok := e.embedder.(embedding.ModelInfo); ok {
+		return cache.EmbeddingMeta{Dimension: mi.Dimensions(), Revision: mi.Revision()}
+	}
+	return cache.EmbeddingMeta{Dimension: int(e.dim.Load())}
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
 }
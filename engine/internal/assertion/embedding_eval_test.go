@@ -0,0 +1,285 @@
+package assertion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestEmbeddingEvaluator_RebuildsStaleCacheEntries(t *testing.T) {
+	c, err := cache.NewEmbeddingCache(filepath.Join(t.TempDir(), "emb.db"), 10)
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	const reference = "reference text"
+	embedder := &mockEmbedder{model: "mock-embed"}
+	// Seed a vector from an older model version with a different dimension.
+	if err := c.Put(cache.ContentHash(reference), embedder.Model(), []float32{1, 0}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	eval := NewEmbeddingEvaluator(embedder, c)
+	result := eval.Evaluate(testTrace(), &types.Assertion{
+		AssertionID: "emb",
+		Type:        types.TypeEmbedding,
+		Spec:        json.RawMessage(`{"target":"output","reference":"` + reference + `","threshold":0.5}`),
+	})
+	if result.Status != types.StatusPass {
+		t.Fatalf("expected pass after rebuilding stale entry, got %q: %s", result.Status, result.Explanation)
+	}
+
+	got, err := c.Get(cache.ContentHash(reference), embedder.Model())
+	if err != nil || len(got) != 3 {
+		t.Errorf("cache entry after rebuild = %v, %v; want 3-dim vector", got, err)
+	}
+}
+
+func TestEmbeddingEvaluator_ChunkedAggregation(t *testing.T) {
+	embedder := &mockEmbedder{model: "mock-embed", vectors: map[string][]float32{
+		"a b": {1, 0, 0},
+		"c d": {0, 1, 0},
+		"e f": {0, 0, 1},
+		"ref": {1, 0, 0},
+	}}
+	eval := NewEmbeddingEvaluator(embedder, nil)
+	trace := &types.Trace{Output: json.RawMessage(`"a b c d e f"`)}
+
+	tests := []struct {
+		aggregate string
+		wantScore float64
+		wantNote  string
+	}{
+		{`"max"`, 1, "(max over 3 chunks)"},
+		{`"mean"`, 1.0 / 3, "(mean over 3 chunks)"},
+		{`"top_k","top_k":2`, 0.5, "(top-2 mean over 3 chunks)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.aggregate, func(t *testing.T) {
+			result := eval.Evaluate(trace, &types.Assertion{
+				AssertionID: "emb-chunked",
+				Type:        types.TypeEmbedding,
+				Spec: json.RawMessage(`{"target":"output","reference":"ref","threshold":0.3,` +
+					`"chunk_size":2,"chunk_overlap":0,"aggregate":` + tt.aggregate + `}`),
+			})
+			if math.Abs(result.Score-tt.wantScore) > 1e-6 {
+				t.Errorf("score = %f, want %f (%s)", result.Score, tt.wantScore, result.Explanation)
+			}
+			if !strings.Contains(result.Explanation, tt.wantNote) {
+				t.Errorf("explanation %q missing %q", result.Explanation, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestEmbeddingEvaluator_InvalidChunkSpec(t *testing.T) {
+	eval := NewEmbeddingEvaluator(&mockEmbedder{model: "mock-embed"}, nil)
+	for _, spec := range []string{
+		`{"target":"output","reference":"ref","aggregate":"median"}`,
+		`{"target":"output","reference":"ref","chunk_size":-1}`,
+		`{"target":"output","reference":"ref","chunk_size":4,"chunk_overlap":4}`,
+	} {
+		result := eval.Evaluate(testTrace(), &types.Assertion{
+			AssertionID: "emb-invalid",
+			Type:        types.TypeEmbedding,
+			Spec:        json.RawMessage(spec),
+		})
+		if result.Status != types.StatusHardFail || !strings.Contains(result.Explanation, "invalid embedding spec") {
+			t.Errorf("spec %s: got %s: %s", spec, result.Status, result.Explanation)
+		}
+	}
+}
+
+// limitedEmbedder is a mockEmbedder with an input limit.
+type limitedEmbedder struct {
+	mockEmbedder
+	maxTokens int
+}
+
+func (l *limitedEmbedder) MaxInputTokens() int { return l.maxTokens }
+
+func TestEmbeddingEvaluator_Chunks(t *testing.T) {
+	text := "one two three four five six seven eight nine ten"
+	overlap := 1
+
+	tests := []struct {
+		name     string
+		embedder embedding.Embedder
+		spec     embeddingSpec
+		want     []string
+	}{
+		{
+			name:     "no limit keeps text whole",
+			embedder: &mockEmbedder{},
+			want:     []string{text},
+		},
+		{
+			name:     "fits in one chunk",
+			embedder: &mockEmbedder{},
+			spec:     embeddingSpec{ChunkSize: 10},
+			want:     []string{text},
+		},
+		{
+			name:     "explicit size and overlap",
+			embedder: &mockEmbedder{},
+			spec:     embeddingSpec{ChunkSize: 4, ChunkOverlap: &overlap},
+			want:     []string{"one two three four", "four five six seven", "seven eight nine ten"},
+		},
+		{
+			// 8 tokens * 0.75 = 6 words, default overlap 6/10 = 0.
+			name:     "size derived from input limit",
+			embedder: &limitedEmbedder{maxTokens: 8},
+			want:     []string{"one two three four five six", "seven eight nine ten"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEmbeddingEvaluator(tt.embedder, nil).chunks(text, tt.spec)
+			if err != nil {
+				t.Fatalf("chunks: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddingEvaluator_ChunkLimit(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	zero := 0
+	got, err := NewEmbeddingEvaluator(&mockEmbedder{}, nil).chunks(strings.Join(words, " "), embeddingSpec{ChunkSize: 5, ChunkOverlap: &zero})
+	if err != nil {
+		t.Fatalf("chunks: %v", err)
+	}
+	if len(got) != maxEmbeddingChunks {
+		t.Fatalf("got %d chunks, want %d", len(got), maxEmbeddingChunks)
+	}
+	if got[0] != "w0 w1 w2 w3 w4" || got[len(got)-1] != "w995 w996 w997 w998 w999" {
+		t.Errorf("first chunk %q, last %q; want the start and end of the text", got[0], got[len(got)-1])
+	}
+}
+
+func TestEmbeddingEvaluator_PinsReferences(t *testing.T) {
+	embedder := &mockEmbedder{model: "mock-embed"}
+	eval := NewEmbeddingEvaluator(embedder, nil)
+	a := &types.Assertion{
+		AssertionID: "emb-pinned",
+		Type:        types.TypeEmbedding,
+		Spec:        json.RawMessage(`{"target":"output","reference":"shared reference","threshold":0.5}`),
+	}
+
+	const traces = 50
+	var wg sync.WaitGroup
+	for i := 0; i < traces; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result := eval.Evaluate(testTrace(), a); result.Status != types.StatusPass {
+				t.Errorf("expected pass, got %s: %s", result.Status, result.Explanation)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// One embedding per target, and the reference only once.
+	if got := embedder.callCount.Load(); got != traces+1 {
+		t.Errorf("embed calls = %d, want %d", got, traces+1)
+	}
+}
+
+// failingEmbedder fails the first embedding of each text.
+type failingEmbedder struct {
+	mockEmbedder
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (f *failingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	f.mu.Lock()
+	first := !f.seen[text]
+	f.seen[text] = true
+	f.mu.Unlock()
+	if first {
+		return nil, errors.New("transient")
+	}
+	return f.mockEmbedder.Embed(ctx, text)
+}
+
+func TestEmbeddingEvaluator_DoesNotPinFailures(t *testing.T) {
+	embedder := &failingEmbedder{mockEmbedder: mockEmbedder{model: "mock-embed"}, seen: map[string]bool{"test": true}}
+	eval := NewEmbeddingEvaluator(embedder, nil)
+	trace := &types.Trace{Output: json.RawMessage(`"test"`)}
+	a := &types.Assertion{
+		AssertionID: "emb-retry",
+		Type:        types.TypeEmbedding,
+		Spec:        json.RawMessage(`{"target":"output","reference":"flaky reference","threshold":0.5}`),
+	}
+
+	if result := eval.Evaluate(trace, a); !strings.Contains(result.Explanation, "embed reference: transient") {
+		t.Fatalf("expected reference failure, got %s: %s", result.Status, result.Explanation)
+	}
+	if result := eval.Evaluate(trace, a); result.Status != types.StatusPass {
+		t.Errorf("expected the reference to be retried, got %s: %s", result.Status, result.Explanation)
+	}
+}
+
+// batchEmbedder records EmbedBatch calls.
+type batchEmbedder struct {
+	mockEmbedder
+	batches [][]string
+}
+
+func (b *batchEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	b.batches = append(b.batches, texts)
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vecs[i], _ = b.mockEmbedder.Embed(ctx, text)
+	}
+	return vecs, nil
+}
+
+func TestEmbeddingEvaluator_BatchesChunkMisses(t *testing.T) {
+	c, err := cache.NewEmbeddingCache(filepath.Join(t.TempDir(), "emb.db"), 10)
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	embedder := &batchEmbedder{mockEmbedder: mockEmbedder{model: "mock-embed"}}
+	if err := c.Put(cache.ContentHash("c d"), embedder.Model(), []float32{1, 0, 0}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	eval := NewEmbeddingEvaluator(embedder, c)
+	result := eval.Evaluate(&types.Trace{Output: json.RawMessage(`"a b c d e f"`)}, &types.Assertion{
+		AssertionID: "emb-batched",
+		Type:        types.TypeEmbedding,
+		Spec:        json.RawMessage(`{"target":"output","reference":"ref","chunk_size":2,"chunk_overlap":0}`),
+	})
+	if result.Status != types.StatusPass {
+		t.Fatalf("expected pass, got %s: %s", result.Status, result.Explanation)
+	}
+
+	// The cached chunk is skipped; the other two go in one batch.
+	if len(embedder.batches) != 1 || !slices.Equal(embedder.batches[0], []string{"a b", "e f"}) {
+		t.Errorf("batches = %q, want one batch of the uncached chunks", embedder.batches)
+	}
+	if got, err := c.Get(cache.ContentHash("e f"), embedder.Model()); err != nil || got == nil {
+		t.Errorf("expected batched chunk to be cached, got %v, %v", got, err)
+	}
+}
@@ -2,6 +2,7 @@ package assertion
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
 	"github.com/attest-ai/attest/engine/internal/assertion/judge"
@@ -15,6 +16,13 @@ type Evaluator interface {
 	Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult
 }
 
+// SeededEvaluator is implemented by evaluators whose results depend on sampling.
+// The pipeline calls EvaluateWithSeed instead of Evaluate when a batch seed is set.
+type SeededEvaluator interface {
+	Evaluator
+	EvaluateWithSeed(trace *types.Trace, assertion *types.Assertion, seed int64) *types.AssertionResult
+}
+
 // Registry maps assertion type strings to Evaluator implementations.
 type Registry struct {
 	evaluators map[string]Evaluator
@@ -27,7 +35,10 @@ type registryConfig struct {
 	judgeProvider  llm.Provider
 	rubrics        *judge.RubricRegistry
 	judgeCache     *cache.JudgeCache
+	calibrations   *cache.CalibrationStore
 	historyStore   *cache.HistoryStore
+	regexBudget    time.Duration
+	linkCheck      *linkChecker
 }
 
 // RegistryOption configures optional evaluators on a Registry.
@@ -50,6 +61,14 @@ func WithJudge(provider llm.Provider, rubrics *judge.RubricRegistry, c *cache.Ju
 	}
 }
 
+// WithJudgeCalibrations maps judge scores through the per-rubric calibration
+// curves in store.
+func WithJudgeCalibrations(store *cache.CalibrationStore) RegistryOption {
+	return func(cfg *registryConfig) {
+		cfg.calibrations = store
+	}
+}
+
 // WithHistory injects a HistoryStore into the registry for dynamic threshold evaluation.
 func WithHistory(store *cache.HistoryStore) RegistryOption {
 	return func(cfg *registryConfig) {
@@ -57,6 +76,15 @@ func WithHistory(store *cache.HistoryStore) RegistryOption {
 	}
 }
 
+// WithRegexBudget sets the default wall-clock budget for each regex_match
+// content check. A check's timeout_ms overrides it; a negative budget disables
+// the guard.
+func WithRegexBudget(d time.Duration) RegistryOption {
+	return func(cfg *registryConfig) {
+		cfg.regexBudget = d
+	}
+}
+
 // NewRegistry creates a registry with built-in evaluators registered.
 // Layers 1-4 are always registered. Layers 5-6 are registered when the
 // corresponding RegistryOption is provided.
@@ -64,22 +92,34 @@ func NewRegistry(opts ...RegistryOption) *Registry {
 	r := &Registry{
 		evaluators: make(map[string]Evaluator),
 	}
-	r.Register(types.TypeSchema, &SchemaEvaluator{})
-	r.Register(types.TypeConstraint, &ConstraintEvaluator{})
-	r.Register(types.TypeTrace, &TraceEvaluator{})
-	r.Register(types.TypeTraceTree, &TraceTreeEvaluator{})
-	r.Register(types.TypeContent, &ContentEvaluator{})
 
 	var cfg registryConfig
 	for _, o := range opts {
 		o(&cfg)
 	}
 
+	r.Register(types.TypeSchema, &SchemaEvaluator{})
+	r.Register(types.TypeConstraint, &ConstraintEvaluator{history: cfg.historyStore})
+	r.Register(types.TypeTrace, &TraceEvaluator{})
+	r.Register(types.TypeTraceTree, &TraceTreeEvaluator{history: cfg.historyStore})
+	r.Register(types.TypeTemporal, &TemporalEvaluator{})
+	r.Register(types.TypeContent, &ContentEvaluator{regexBudget: cfg.regexBudget, links: cfg.linkCheck})
+	r.Register(types.TypeExpression, &ExpressionEvaluator{})
+	r.Register(types.TypeComposite, &CompositeEvaluator{registry: r})
+	r.Register(types.TypeTranscript, &TranscriptEvaluator{})
+	r.Register(types.TypeReferenceMatch, &ReferenceMatchEvaluator{})
+	r.Register(types.TypeConsistency, &ConsistencyEvaluator{})
+	r.Register(types.TypeCodeValid, &CodeValidEvaluator{})
+	r.Register(types.TypeSQLSafe, &SQLSafeEvaluator{})
+
 	if cfg.embedder != nil {
 		r.Register(types.TypeEmbedding, NewEmbeddingEvaluator(cfg.embedder, cfg.embeddingCache))
 	}
 	if cfg.judgeProvider != nil && cfg.rubrics != nil {
-		r.Register(types.TypeLLMJudge, NewJudgeEvaluator(cfg.judgeProvider, cfg.rubrics, cfg.judgeCache))
+		judgeEval := NewJudgeEvaluator(cfg.judgeProvider, cfg.rubrics, cfg.judgeCache)
+		judgeEval.calibrations = cfg.calibrations
+		r.Register(types.TypeLLMJudge, judgeEval)
+		r.Register(types.TypePersonaConsistency, NewPersonaConsistencyEvaluator(cfg.judgeProvider, cfg.rubrics, cfg.judgeCache))
 	}
 
 	return r
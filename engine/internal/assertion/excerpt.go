@@ -0,0 +1,123 @@
+package assertion
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+const (
+	// excerptContext is how many characters of context an excerpt includes on
+	// each side of its region.
+	excerptContext = 80
+	// maxExcerpts bounds the excerpts attached to one result.
+	maxExcerpts = 10
+	// minPartialMatch is the shortest prefix of a missing term reported as
+	// its closest partial match.
+	minPartialMatch = 4
+)
+
+// excerpter locates terms in a target for content failure excerpts. compare
+// is the string searched: target itself, or target lowercased for a
+// case-insensitive check. Lowercasing maps each character to one character,
+// so character offsets agree between the two.
+type excerpter struct {
+	target  string
+	compare string
+	fold    bool
+}
+
+func newExcerpter(target, compare string, caseSensitive bool) *excerpter {
+	return &excerpter{target: target, compare: compare, fold: !caseSensitive}
+}
+
+// found returns excerpts of the first occurrence of each term, for terms that
+// should not have occurred.
+func (x *excerpter) found(terms []string) []types.Excerpt {
+	var out []types.Excerpt
+	for _, term := range terms {
+		if len(out) == maxExcerpts {
+			break
+		}
+		needle := x.needle(term)
+		if i := strings.Index(x.compare, needle); i >= 0 && needle != "" {
+			out = append(out, x.excerpt(term, i, i+len(needle), false))
+		}
+	}
+	return out
+}
+
+// missing returns excerpts of the closest partial match of each missing
+// term: the longest prefix of it, at least minPartialMatch characters, that
+// occurs in the target. Terms without one get no excerpt.
+func (x *excerpter) missing(terms []string) []types.Excerpt {
+	var out []types.Excerpt
+	for _, term := range terms {
+		if len(out) == maxExcerpts {
+			break
+		}
+		if e, ok := x.closest(term, x.needle(term)); ok {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// closest returns the excerpt of the longest prefix of needle, at least
+// minPartialMatch characters, that occurs in the target, reported for term.
+func (x *excerpter) closest(term, needle string) (types.Excerpt, bool) {
+	runes := []rune(needle)
+	// Prefix occurrence is monotone in length: binary search the longest.
+	lo, hi, at, n := minPartialMatch, len(runes), -1, 0
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		prefix := string(runes[:mid])
+		if i := strings.Index(x.compare, prefix); i >= 0 {
+			at, n = i, len(prefix)
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	if at < 0 {
+		return types.Excerpt{}, false
+	}
+	return x.excerpt(term, at, at+n, true), true
+}
+
+func (x *excerpter) needle(term string) string {
+	if x.fold {
+		return strings.ToLower(term)
+	}
+	return term
+}
+
+// excerpt builds the excerpt for the region [start, end) of compare, in bytes.
+func (x *excerpter) excerpt(term string, start, end int, missing bool) types.Excerpt {
+	runeStart := utf8.RuneCountInString(x.compare[:start])
+	runeEnd := runeStart + utf8.RuneCountInString(x.compare[start:end])
+	textStart := max(0, runeStart-excerptContext)
+	from := byteOffset(x.target, textStart)
+	to := from + byteOffset(x.target[from:], runeEnd+excerptContext-textStart)
+	return types.Excerpt{
+		Term:      term,
+		Start:     runeStart,
+		End:       runeEnd,
+		Missing:   missing,
+		Text:      x.target[from:to],
+		TextStart: textStart,
+	}
+}
+
+// byteOffset returns the byte offset of the n-th character of s, or len(s)
+// when s is shorter.
+func byteOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
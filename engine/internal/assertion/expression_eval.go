@@ -0,0 +1,244 @@
+package assertion
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// exprCacheSize caps the number of compiled expression programs kept for reuse.
+const exprCacheSize = 512
+
+// exprMaxNodes bounds expression size so a spec cannot compile into an
+// arbitrarily large program.
+const exprMaxNodes = 10000
+
+// ExpressionEvaluator implements expr-lang expression assertions: a boolean
+// expression and an optional numeric score expression evaluated against a
+// typed view of the trace (see exprEnv).
+type ExpressionEvaluator struct{}
+
+type expressionSpec struct {
+	Expr  string `json:"expr"`
+	Score string `json:"score,omitempty"`
+	Soft  bool   `json:"soft"`
+}
+
+// exprEnv is the view of a trace that expressions see. JSON payloads are
+// decoded to maps, slices, strings, float64s, and bools; absent metadata
+// fields read as zero values.
+type exprEnv struct {
+	TraceID  string       `expr:"trace_id"`
+	AgentID  string       `expr:"agent_id"`
+	Input    any          `expr:"input"`
+	Output   any          `expr:"output"`
+	Steps    []exprStep   `expr:"steps"`
+	Metadata exprMetadata `expr:"metadata"`
+	Tree     exprTree     `expr:"tree"`
+}
+
+type exprStep struct {
+	Name        string        `expr:"name"`
+	Type        string        `expr:"type"`
+	AgentID     string        `expr:"agent_id"`
+	Args        any           `expr:"args"`
+	Result      any           `expr:"result"`
+	Metadata    any           `expr:"metadata"`
+	StartedAtMS int64         `expr:"started_at_ms"`
+	EndedAtMS   int64         `expr:"ended_at_ms"`
+	DurationMS  int64         `expr:"duration_ms"`
+	Error       string        `expr:"error"`
+	Messages    []exprMessage `expr:"messages"`
+}
+
+type exprMessage struct {
+	Role    string `expr:"role"`
+	Content string `expr:"content"`
+	Name    string `expr:"name"`
+}
+
+type exprMetadata struct {
+	TotalTokens int     `expr:"total_tokens"`
+	CostUSD     float64 `expr:"cost_usd"`
+	LatencyMS   int     `expr:"latency_ms"`
+	Model       string  `expr:"model"`
+	Timestamp   string  `expr:"timestamp"`
+}
+
+type exprTree struct {
+	Depth       int      `expr:"depth"`
+	AgentCount  int      `expr:"agent_count"`
+	AgentIDs    []string `expr:"agent_ids"`
+	TotalTokens int      `expr:"total_tokens"`
+	CostUSD     float64  `expr:"cost_usd"`
+	LatencyMS   int      `expr:"latency_ms"`
+}
+
+// exprPrograms caches compiled programs by kind and source; programs are
+// safe for concurrent use.
+var exprPrograms = newLRUCache[*vm.Program](exprCacheSize)
+
+func compileExpr(src string, score bool) (*vm.Program, error) {
+	key := "b:" + src
+	if score {
+		key = "s:" + src
+	}
+	if p, ok := exprPrograms.get(key); ok {
+		return p, nil
+	}
+	opts := []expr.Option{expr.Env(exprEnv{}), expr.MaxNodes(exprMaxNodes)}
+	if score {
+		opts = append(opts, expr.AsFloat64())
+	} else {
+		opts = append(opts, expr.AsBool())
+	}
+	p, err := expr.Compile(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return exprPrograms.add(key, p), nil
+}
+
+func (e *ExpressionEvaluator) Evaluate(t *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec expressionSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid expression spec: %v", err))
+	}
+	if spec.Expr == "" {
+		return failResult(assertion, start, "expression spec missing required field: expr")
+	}
+	program, err := compileExpr(spec.Expr, false)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("expression does not compile: %v", err))
+	}
+	var scoreProgram *vm.Program
+	if spec.Score != "" {
+		if scoreProgram, err = compileExpr(spec.Score, true); err != nil {
+			return failResult(assertion, start, fmt.Sprintf("score expression does not compile: %v", err))
+		}
+	}
+
+	env := newExprEnv(t)
+	out, err := expr.Run(program, env)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("expression failed: %v", err))
+	}
+	passed := out.(bool)
+
+	score := 0.0
+	if passed {
+		score = 1.0
+	}
+	scoreNote := ""
+	if scoreProgram != nil {
+		v, err := expr.Run(scoreProgram, env)
+		if err != nil {
+			return failResult(assertion, start, fmt.Sprintf("score expression failed: %v", err))
+		}
+		f := v.(float64)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return failResult(assertion, start, fmt.Sprintf("score expression returned %v; scores must be finite", f))
+		}
+		score = min(max(f, 0), 1)
+		scoreNote = fmt.Sprintf(", score %.4g", score)
+	}
+
+	status := types.StatusPass
+	explanation := fmt.Sprintf("expression %q is true%s.", spec.Expr, scoreNote)
+	if !passed {
+		status = types.StatusHardFail
+		if spec.Soft {
+			status = types.StatusSoftFail
+		}
+		explanation = fmt.Sprintf("expression %q is false%s", spec.Expr, scoreNote)
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       score,
+		Explanation: explanation,
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+// newExprEnv builds the expression view of t. Payloads that are not valid
+// JSON are exposed as their raw text.
+func newExprEnv(t *types.Trace) *exprEnv {
+	env := &exprEnv{
+		TraceID: t.TraceID,
+		AgentID: t.AgentID,
+		Input:   decodeAny(t.Input),
+		Output:  decodeAny(t.Output),
+		Steps:   make([]exprStep, len(t.Steps)),
+	}
+	for i := range t.Steps {
+		s := &t.Steps[i]
+		es := exprStep{
+			Name:     s.Name,
+			Type:     s.Type,
+			AgentID:  s.AgentID,
+			Args:     decodeAny(s.Args),
+			Result:   decodeAny(s.Result),
+			Metadata: decodeAny(s.Metadata),
+			Messages: make([]exprMessage, len(s.Messages)),
+		}
+		for j, m := range s.Messages {
+			es.Messages[j] = exprMessage{Role: m.Role, Content: m.Content, Name: m.Name}
+		}
+		if s.StartedAtMs != nil {
+			es.StartedAtMS = *s.StartedAtMs
+		}
+		if s.EndedAtMs != nil {
+			es.EndedAtMS = *s.EndedAtMs
+		}
+		if s.StartedAtMs != nil && s.EndedAtMs != nil {
+			es.DurationMS = *s.EndedAtMs - *s.StartedAtMs
+		}
+		if s.Error != nil {
+			es.Error = s.Error.Message
+		}
+		env.Steps[i] = es
+	}
+	if m := t.Metadata; m != nil {
+		if m.TotalTokens != nil {
+			env.Metadata.TotalTokens = *m.TotalTokens
+		}
+		if m.CostUSD != nil {
+			env.Metadata.CostUSD = *m.CostUSD
+		}
+		if m.LatencyMS != nil {
+			env.Metadata.LatencyMS = *m.LatencyMS
+		}
+		if m.Model != nil {
+			env.Metadata.Model = *m.Model
+		}
+		if m.Timestamp != nil {
+			env.Metadata.Timestamp = *m.Timestamp
+		}
+	}
+	env.Tree.Depth = treeIndex(t).Depth()
+	env.Tree.AgentIDs = trace.AgentIDs(t)
+	env.Tree.TotalTokens, env.Tree.CostUSD, env.Tree.LatencyMS, env.Tree.AgentCount = trace.AggregateMetadata(t)
+	return env
+}
+
+func decodeAny(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func expressionTrace() *types.Trace {
+	cost, tokens := 0.04, 1200
+	child := &types.Trace{TraceID: "trc_child", AgentID: "billing", Output: json.RawMessage(`{"ok":true}`),
+		Metadata: &types.TraceMetadata{CostUSD: &cost}}
+	return &types.Trace{
+		TraceID: "trc_expr",
+		AgentID: "support",
+		Output:  json.RawMessage(`{"message":"Your refund of $20 was issued.","structured":{"refund_id":"RFD-1"}}`),
+		Steps: []types.Step{
+			{Type: types.StepTypeToolCall, Name: "lookup_order", Args: json.RawMessage(`{"order_id":"o7"}`), Result: json.RawMessage(`{"status":"delivered","amount":20}`),
+				StartedAtMs: ptrTo[int64](100), EndedAtMs: ptrTo[int64](160)},
+			{Type: types.StepTypeLLMCall, Name: "reply", Messages: []types.Message{{Role: types.RoleUser, Content: "refund please"}, {Role: types.RoleAssistant, Content: "done"}}},
+			{Type: types.StepTypeAgentCall, Name: "billing", SubTrace: child},
+		},
+		Metadata: &types.TraceMetadata{CostUSD: &cost, TotalTokens: &tokens},
+	}
+}
+
+func ptrTo[T any](v T) *T { return &v }
+
+func TestExpressionEvaluator(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+		wantScore  float64
+	}{
+		{"output field", `{"expr":"output.message contains 'refund'"}`, types.StatusPass, 1},
+		{"nested output", `{"expr":"output.structured.refund_id startsWith 'RFD-'"}`, types.StatusPass, 1},
+		{"step args and result", `{"expr":"steps[0].args.order_id == 'o7' && steps[0].result.amount <= 20"}`, types.StatusPass, 1},
+		{"step durations", `{"expr":"all(filter(steps, .type == 'tool_call'), .duration_ms < 100)"}`, types.StatusPass, 1},
+		{"messages", `{"expr":"any(steps[1].messages, .role == 'assistant' && .content == 'done')"}`, types.StatusPass, 1},
+		{"metadata", `{"expr":"metadata.total_tokens < 1000"}`, types.StatusHardFail, 0},
+		{"tree aggregates", `{"expr":"tree.depth == 1 && tree.agent_count == 2 && 'billing' in tree.agent_ids && tree.cost_usd > 0.07"}`, types.StatusPass, 1},
+		{"score expression", `{"expr":"len(steps) <= 3","score":"1 - metadata.cost_usd / 0.1"}`, types.StatusPass, 0.6},
+		{"score clamped", `{"expr":"false","score":"metadata.cost_usd * 100","soft":true}`, types.StatusSoftFail, 1},
+		{"NaN score", `{"expr":"true","score":"metadata.cost_usd / 0 - metadata.cost_usd / 0"}`, types.StatusHardFail, 0},
+		{"infinite score", `{"expr":"true","score":"metadata.cost_usd / 0"}`, types.StatusHardFail, 0},
+		{"not boolean", `{"expr":"len(steps)"}`, types.StatusHardFail, 0},
+		{"unknown field", `{"expr":"metadata.nope > 1"}`, types.StatusHardFail, 0},
+		{"missing expr", `{"score":"1"}`, types.StatusHardFail, 0},
+	}
+	eval := &ExpressionEvaluator{}
+	tr := expressionTrace()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &types.Assertion{AssertionID: "a", Type: types.TypeExpression, Spec: json.RawMessage(tt.spec)}
+			result := eval.Evaluate(tr, a)
+			if result.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if diff := result.Score - tt.wantScore; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("score = %v, want %v", result.Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestExpressionEvaluator_CompileErrorExplained(t *testing.T) {
+	a := &types.Assertion{AssertionID: "a", Type: types.TypeExpression, Spec: json.RawMessage(`{"expr":"output.message contains"}`)}
+	result := (&ExpressionEvaluator{}).Evaluate(expressionTrace(), a)
+	if !strings.Contains(result.Explanation, "does not compile") {
+		t.Errorf("explanation = %q", result.Explanation)
+	}
+}
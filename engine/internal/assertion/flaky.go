@@ -0,0 +1,83 @@
+package assertion
+
+import (
+	"math"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// FlakinessConfig holds parameters for flakiness detection and quarantine release.
+type FlakinessConfig struct {
+	WindowSize int
+	// MinRuns is the minimum history length before an assertion can be called flaky.
+	MinRuns int
+	// SwitchRate is the minimum fraction of consecutive runs that flip between
+	// pass and non-pass for an assertion to be flaky.
+	SwitchRate float64
+	// Entropy is the minimum binary entropy (bits) of the pass/non-pass split.
+	Entropy float64
+	// StableRuns is the number of consecutive passes that release an assertion
+	// from quarantine.
+	StableRuns int
+}
+
+// DefaultFlakinessConfig provides sensible defaults for flakiness detection.
+var DefaultFlakinessConfig = FlakinessConfig{
+	WindowSize: 20,
+	MinRuns:    6,
+	SwitchRate: 0.3,
+	Entropy:    0.5,
+	StableRuns: 10,
+}
+
+// AnalyzeFlakiness scores how often statuses alternate between pass and
+// non-pass. statuses are ordered most recent first, as returned by the history
+// store. An assertion is flaky when both its switch rate and the entropy of
+// its pass/non-pass split reach the configured thresholds.
+func AnalyzeFlakiness(assertionID string, statuses []string, cfg FlakinessConfig) types.FlakinessReport {
+	report := types.FlakinessReport{AssertionID: assertionID, Runs: len(statuses)}
+	if len(statuses) == 0 {
+		return report
+	}
+
+	passes := 0
+	for i, s := range statuses {
+		if s == types.StatusPass {
+			passes++
+		}
+		if i > 0 && (s == types.StatusPass) != (statuses[i-1] == types.StatusPass) {
+			report.SwitchCount++
+		}
+	}
+	report.PassRate = float64(passes) / float64(len(statuses))
+	if len(statuses) > 1 {
+		report.SwitchRate = float64(report.SwitchCount) / float64(len(statuses)-1)
+	}
+	report.Entropy = binaryEntropy(report.PassRate)
+	report.Flaky = len(statuses) >= cfg.MinRuns &&
+		report.SwitchRate >= cfg.SwitchRate &&
+		report.Entropy >= cfg.Entropy
+	return report
+}
+
+// IsStable reports whether a quarantined assertion can be released: the current
+// status and the most recent StableRuns-1 recorded statuses are all pass.
+func IsStable(current string, history []string, cfg FlakinessConfig) bool {
+	if current != types.StatusPass || len(history) < cfg.StableRuns-1 {
+		return false
+	}
+	for _, s := range history[:cfg.StableRuns-1] {
+		if s != types.StatusPass {
+			return false
+		}
+	}
+	return true
+}
+
+// binaryEntropy returns the Shannon entropy in bits of a Bernoulli(p) outcome.
+func binaryEntropy(p float64) float64 {
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	return -p*math.Log2(p) - (1-p)*math.Log2(1-p)
+}
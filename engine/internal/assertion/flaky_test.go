@@ -0,0 +1,120 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestAnalyzeFlakiness(t *testing.T) {
+	const (
+		p = types.StatusPass
+		f = types.StatusHardFail
+		s = types.StatusSoftFail
+	)
+	tests := []struct {
+		name      string
+		statuses  []string
+		wantFlaky bool
+		switches  int
+	}{
+		{"empty", nil, false, 0},
+		{"all pass", []string{p, p, p, p, p, p, p, p}, false, 0},
+		{"consistently failing", []string{f, f, f, f, f, f, f, f}, false, 0},
+		{"alternating", []string{p, f, p, f, p, f, p, f}, true, 7},
+		{"soft fail counts as non-pass", []string{p, s, p, s, p, s}, true, 5},
+		{"single regression", []string{f, f, f, p, p, p, p, p}, false, 1},
+		{"too few runs", []string{p, f, p, f}, false, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := AnalyzeFlakiness("a", tt.statuses, DefaultFlakinessConfig)
+			if r.Flaky != tt.wantFlaky {
+				t.Errorf("Flaky = %v, want %v (%+v)", r.Flaky, tt.wantFlaky, r)
+			}
+			if r.SwitchCount != tt.switches {
+				t.Errorf("SwitchCount = %d, want %d", r.SwitchCount, tt.switches)
+			}
+			if r.Runs != len(tt.statuses) {
+				t.Errorf("Runs = %d, want %d", r.Runs, len(tt.statuses))
+			}
+		})
+	}
+}
+
+func TestIsStable(t *testing.T) {
+	cfg := FlakinessConfig{StableRuns: 3}
+	pass := types.StatusPass
+	if !IsStable(pass, []string{pass, pass}, cfg) {
+		t.Error("expected stable after 3 consecutive passes")
+	}
+	if IsStable(types.StatusHardFail, []string{pass, pass}, cfg) {
+		t.Error("current failure must not be stable")
+	}
+	if IsStable(pass, []string{pass, types.StatusSoftFail}, cfg) {
+		t.Error("recent soft_fail must not be stable")
+	}
+	if IsStable(pass, []string{pass}, cfg) {
+		t.Error("insufficient history must not be stable")
+	}
+}
+
+func TestPipeline_QuarantineDowngradesHardFail(t *testing.T) {
+	store := newDynamicTestHistory(t, "q")
+	if err := store.Quarantine("q", "flaky"); err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+	pipeline := NewPipelineWithHistory(NewRegistry(WithHistory(store)), store)
+
+	cost := 0.5
+	trace := &types.Trace{TraceID: "trc_q", Output: json.RawMessage(`{}`), Metadata: &types.TraceMetadata{CostUSD: &cost}}
+	assertions := []types.Assertion{
+		{AssertionID: "q", Type: types.TypeConstraint, Spec: json.RawMessage(`{"field":"metadata.cost_usd","operator":"lte","value":0.1}`)},
+		{AssertionID: "not_q", Type: types.TypeConstraint, Spec: json.RawMessage(`{"field":"metadata.cost_usd","operator":"lte","value":0.1}`)},
+	}
+	result, err := pipeline.EvaluateBatch(trace, assertions)
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+
+	q := result.Results[0]
+	if q.Status != types.StatusSoftFail || !q.Quarantined {
+		t.Errorf("quarantined result = %q (quarantined=%v), want soft_fail marked quarantined", q.Status, q.Quarantined)
+	}
+	if !strings.HasPrefix(q.Explanation, "[quarantined] ") {
+		t.Errorf("explanation missing quarantine marker: %s", q.Explanation)
+	}
+	if r := result.Results[1]; r.Status != types.StatusHardFail || r.Quarantined {
+		t.Errorf("non-quarantined result = %q (quarantined=%v), want unmarked hard_fail", r.Status, r.Quarantined)
+	}
+}
+
+func TestPipeline_QuarantineReleasedWhenStable(t *testing.T) {
+	store := newDynamicTestHistory(t, "q")
+	if err := store.Quarantine("q", "flaky"); err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+	for i := 0; i < DefaultFlakinessConfig.StableRuns-1; i++ {
+		if err := store.Record("trc", "q", types.TypeConstraint, 1, types.StatusPass); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	pipeline := NewPipelineWithHistory(NewRegistry(), store)
+
+	cost := 0.05
+	trace := &types.Trace{TraceID: "trc_q", Output: json.RawMessage(`{}`), Metadata: &types.TraceMetadata{CostUSD: &cost}}
+	result, err := pipeline.EvaluateBatch(trace, []types.Assertion{
+		{AssertionID: "q", Type: types.TypeConstraint, Spec: json.RawMessage(`{"field":"metadata.cost_usd","operator":"lte","value":0.1}`)},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	if result.Results[0].Quarantined {
+		t.Error("stable pass should release the assertion and not be marked quarantined")
+	}
+	if q, _ := store.IsQuarantined("q"); q {
+		t.Error("assertion should have been removed from the quarantine list")
+	}
+}
@@ -0,0 +1,167 @@
+package judge
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// MinCalibrationExamples is the smallest labeled dataset Fit accepts.
+const MinCalibrationExamples = 5
+
+// CalibrationPoint maps a raw judge score to a calibrated score.
+type CalibrationPoint struct {
+	Raw        float64 `json:"raw"`
+	Calibrated float64 `json:"calibrated"`
+}
+
+// Calibration is a monotone curve mapping a rubric's raw judge scores onto
+// human-labeled scores, with the agreement statistics it was fitted from.
+type Calibration struct {
+	Rubric string             `json:"rubric"`
+	Model  string             `json:"model"`
+	Points []CalibrationPoint `json:"points"`
+	// Examples is the size of the labeled dataset.
+	Examples int `json:"examples"`
+	// Pearson and MAE compare raw judge scores to the human scores;
+	// CalibratedMAE compares calibrated scores on the same examples.
+	Pearson       float64   `json:"pearson"`
+	MAE           float64   `json:"mae"`
+	CalibratedMAE float64   `json:"calibrated_mae"`
+	FittedAt      time.Time `json:"fitted_at"`
+}
+
+// Fit fits a calibration curve from paired raw judge scores and human
+// scores by isotonic regression, so calibrated scores keep the judge's
+// ordering. Both slices hold scores in [0, 1].
+func Fit(rubric, model string, raw, human []float64) (*Calibration, error) {
+	if len(raw) != len(human) {
+		return nil, fmt.Errorf("calibration: %d judge scores for %d labels", len(raw), len(human))
+	}
+	if len(raw) < MinCalibrationExamples {
+		return nil, fmt.Errorf("calibration: need at least %d labeled examples, got %d", MinCalibrationExamples, len(raw))
+	}
+
+	c := &Calibration{
+		Rubric:   rubric,
+		Model:    model,
+		Points:   isotonic(raw, human),
+		Examples: len(raw),
+		Pearson:  Pearson(raw, human),
+		MAE:      MeanAbsError(raw, human),
+		FittedAt: time.Now().UTC(),
+	}
+	calibrated := make([]float64, len(raw))
+	for i, r := range raw {
+		calibrated[i] = c.Apply(r)
+	}
+	c.CalibratedMAE = MeanAbsError(calibrated, human)
+	return c, nil
+}
+
+// Apply maps a raw judge score through the curve, interpolating linearly
+// between points and clamping outside them.
+func (c *Calibration) Apply(raw float64) float64 {
+	pts := c.Points
+	if len(pts) == 0 {
+		return raw
+	}
+	if raw <= pts[0].Raw {
+		return pts[0].Calibrated
+	}
+	last := pts[len(pts)-1]
+	if raw >= last.Raw {
+		return last.Calibrated
+	}
+	i := sort.Search(len(pts), func(i int) bool { return pts[i].Raw >= raw })
+	lo, hi := pts[i-1], pts[i]
+	t := (raw - lo.Raw) / (hi.Raw - lo.Raw)
+	return lo.Calibrated + t*(hi.Calibrated-lo.Calibrated)
+}
+
+// Validate reports an error for a curve that is empty or not monotone.
+func (c *Calibration) Validate() error {
+	if len(c.Points) == 0 {
+		return errors.New("calibration has no points")
+	}
+	for i := 1; i < len(c.Points); i++ {
+		if c.Points[i].Raw <= c.Points[i-1].Raw || c.Points[i].Calibrated < c.Points[i-1].Calibrated {
+			return fmt.Errorf("calibration points not monotone at index %d", i)
+		}
+	}
+	return nil
+}
+
+// isotonic runs pool-adjacent-violators over the pairs sorted by raw score
+// and returns one point per pooled block: its mean raw and mean human score.
+func isotonic(raw, human []float64) []CalibrationPoint {
+	idx := make([]int, len(raw))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return raw[idx[a]] < raw[idx[b]] })
+
+	type block struct {
+		sumRaw, sumHuman float64
+		n                int
+	}
+	var blocks []block
+	for _, i := range idx {
+		blocks = append(blocks, block{raw[i], human[i], 1})
+		for len(blocks) > 1 {
+			a, b := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			// Pool ties in raw score and any decrease in the human mean.
+			if a.sumRaw/float64(a.n) < b.sumRaw/float64(b.n) && a.sumHuman/float64(a.n) <= b.sumHuman/float64(b.n) {
+				break
+			}
+			blocks = append(blocks[:len(blocks)-2], block{a.sumRaw + b.sumRaw, a.sumHuman + b.sumHuman, a.n + b.n})
+		}
+	}
+
+	points := make([]CalibrationPoint, len(blocks))
+	for i, b := range blocks {
+		points[i] = CalibrationPoint{Raw: b.sumRaw / float64(b.n), Calibrated: b.sumHuman / float64(b.n)}
+	}
+	return points
+}
+
+// Pearson returns the Pearson correlation of x and y, or 0 when either has
+// no variance.
+func Pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	if n == 0 {
+		return 0
+	}
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= n
+	my /= n
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+// MeanAbsError returns the mean absolute difference between x and y.
+func MeanAbsError(x, y []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	var sum float64
+	for i := range x {
+		sum += math.Abs(x[i] - y[i])
+	}
+	return sum / float64(len(x))
+}
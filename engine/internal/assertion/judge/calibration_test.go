@@ -0,0 +1,54 @@
+package judge
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFit_MonotoneCurve(t *testing.T) {
+	// The judge is generous: raw scores sit well above the human scores, and
+	// the 0.7/0.6 pair violates monotonicity and must be pooled.
+	raw := []float64{0.5, 0.6, 0.7, 0.8, 0.9, 1.0}
+	human := []float64{0.1, 0.4, 0.2, 0.5, 0.7, 0.9}
+	c, err := Fit("helpfulness", "gpt-4.1", raw, human)
+	if err != nil {
+		t.Fatalf("Fit: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(c.Points) != 5 || math.Abs(c.Points[1].Raw-0.65) > 1e-9 || math.Abs(c.Points[1].Calibrated-0.3) > 1e-9 {
+		t.Errorf("points = %+v, want 0.6 and 0.7 pooled at (0.65, 0.3)", c.Points)
+	}
+	if c.CalibratedMAE >= c.MAE {
+		t.Errorf("calibrated MAE %v not below raw MAE %v", c.CalibratedMAE, c.MAE)
+	}
+	if c.Pearson <= 0.8 || c.Examples != 6 {
+		t.Errorf("pearson = %v, examples = %d", c.Pearson, c.Examples)
+	}
+}
+
+func TestCalibrationApply(t *testing.T) {
+	c := &Calibration{Points: []CalibrationPoint{{0.2, 0.1}, {0.6, 0.5}, {1.0, 0.9}}}
+	tests := []struct{ raw, want float64 }{
+		{0.0, 0.1}, // clamped below
+		{0.4, 0.3}, // interpolated
+		{0.6, 0.5}, // on a point
+		{0.8, 0.7},
+		{1.0, 0.9},
+	}
+	for _, tt := range tests {
+		if got := c.Apply(tt.raw); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Apply(%v) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFit_Errors(t *testing.T) {
+	if _, err := Fit("r", "m", []float64{0.1, 0.2}, []float64{0.1, 0.2}); err == nil {
+		t.Error("expected error below MinCalibrationExamples")
+	}
+	if _, err := Fit("r", "m", make([]float64, 6), make([]float64, 5)); err == nil {
+		t.Error("expected error for mismatched lengths")
+	}
+}
@@ -0,0 +1,110 @@
+package assertion
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestJudgeEvaluator_AppliesCalibration(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	curve, _ := json.Marshal(judge.Calibration{
+		Rubric:   "default",
+		Model:    "mock-model",
+		Points:   []judge.CalibrationPoint{{Raw: 0.5, Calibrated: 0.2}, {Raw: 1.0, Calibrated: 0.8}},
+		Examples: 40,
+		Pearson:  0.9,
+	})
+	if err := store.Calibrations().Put("default", "mock-model", curve); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{{Content: `{"score": 0.75, "explanation": "ok"}`}}, nil)
+	registry := NewRegistry(
+		WithJudge(mock, judge.NewRubricRegistry(), nil),
+		WithJudgeCalibrations(store.Calibrations()),
+	)
+	eval, _ := registry.Get(types.TypeLLMJudge)
+	result := eval.Evaluate(&types.Trace{Output: json.RawMessage(`"answer"`)}, &types.Assertion{
+		AssertionID: "j1",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","threshold":0.6}`),
+	})
+
+	// 0.75 is halfway along the curve: 0.2 + 0.5*(0.8-0.2) = 0.5, below the threshold.
+	if math.Abs(result.Score-0.5) > 1e-9 || result.Status != types.StatusHardFail {
+		t.Errorf("score = %v, status = %s; want calibrated 0.5 hard_fail", result.Score, result.Status)
+	}
+	if c := result.Calibration; c == nil || c.RawScore != 0.75 || c.Examples != 40 || c.Model != "mock-model" {
+		t.Errorf("calibration = %+v", result.Calibration)
+	}
+}
+
+func TestJudgeEvaluator_RawScoreSkipsCalibrationAndUsesCache(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{{Content: `{"score": 0.9, "explanation": "good"}`, Cost: 0.01}}, nil)
+	e := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), store.Judge())
+
+	for i, wantCost := range []float64{0.01, 0} {
+		score, cost, err := e.RawScore(context.Background(), "text", "default", "")
+		if err != nil || score != 0.9 || cost != wantCost {
+			t.Errorf("call %d: RawScore = %v, %v, %v", i, score, cost, err)
+		}
+	}
+	if mock.GetCallCount() != 1 {
+		t.Errorf("provider calls = %d, want 1 (second call cached)", mock.GetCallCount())
+	}
+}
+
+func TestJudgeEvaluator_CacheKeyedByCanonicalCriteria(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: `{"score": 0.9, "explanation": "polite"}`},
+		{Content: `{"score": 0.2, "explanation": "not concise"}`},
+	}, nil)
+	eval, _ := NewRegistry(WithJudge(mock, judge.NewRubricRegistry(), store.Judge())).Get(types.TypeLLMJudge)
+	tr := &types.Trace{Output: json.RawMessage(`"answer"`)}
+
+	specs := []struct {
+		spec      string
+		wantScore float64
+	}{
+		{`{"target":"output","criteria":"polite"}`, 0.9},
+		// Same judgment, serialized differently, with a different threshold: cached.
+		{`{ "threshold": 0.5, "criteria": "polite", "target": "output" }`, 0.9},
+		// Different criteria must not reuse the polite score.
+		{`{"target":"output","criteria":"concise"}`, 0.2},
+	}
+	for i, s := range specs {
+		result := eval.Evaluate(tr, &types.Assertion{AssertionID: "j", Type: types.TypeLLMJudge, Spec: json.RawMessage(s.spec)})
+		if result.Score != s.wantScore {
+			t.Errorf("spec %d: score = %v, want %v (%s)", i, result.Score, s.wantScore, result.Explanation)
+		}
+	}
+	if mock.GetCallCount() != 2 {
+		t.Errorf("provider calls = %d, want 2", mock.GetCallCount())
+	}
+
+	// RawScore shares entries with assertions of the same criteria.
+	if score, cost, err := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), store.Judge()).RawScore(context.Background(), "answer", "default", "concise"); err != nil || score != 0.2 || cost != 0 {
+		t.Errorf("RawScore = %v, %v, %v; want the cached 0.2", score, cost, err)
+	}
+}
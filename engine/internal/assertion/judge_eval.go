@@ -4,7 +4,6 @@ import (
 	"context"
 	"github.com/segmentio/encoding/json"
 	"fmt"
-	"log/slog"
 	"os"
 	"sort"
 	"strconv"
@@ -15,6 +14,8 @@ import (
 	"github.com/attest-ai/attest/engine/internal/assertion/judge"
 	"github.com/attest-ai/attest/engine/internal/cache"
 	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
@@ -23,6 +24,8 @@ type JudgeEvaluator struct {
 	provider llm.Provider
 	rubrics  *judge.RubricRegistry
 	cache    *cache.JudgeCache
+	// calibrations maps raw scores through per-rubric curves; nil disables.
+	calibrations *cache.CalibrationStore
 }
 
 // NewJudgeEvaluator creates an evaluator using the given LLM provider, rubric registry, and optional cache.
@@ -40,6 +43,44 @@ type judgeSpec struct {
 	Soft      bool    `json:"soft"`
 	Model     string  `json:"model"`
 	MetaEval  bool    `json:"meta_eval"`
+	// FailOnLowConfidence fails a meta-evaluated assertion whose runs
+	// disagree: confidence below MinConfidence (default 1 minus
+	// metaEvalVarianceThreshold).
+	FailOnLowConfidence bool     `json:"fail_on_low_confidence"`
+	MinConfidence       *float64 `json:"min_confidence"`
+	// MaxTargetTokens, LongTarget, SummaryModel, and MaxSummaryChunks fit
+	// a long target into a token budget before judging; see planLongTarget.
+	MaxTargetTokens  int    `json:"max_target_tokens"`
+	LongTarget       string `json:"long_target"`
+	SummaryModel     string `json:"summary_model"`
+	MaxSummaryChunks int    `json:"max_summary_chunks"`
+}
+
+// judgeNeutralFields are the judge spec fields that do not change the
+// judgment: the target and model are keyed separately, the long-target
+// fields are keyed by the plan only when they apply, and the rest only
+// decide how a score is classified.
+var judgeNeutralFields = []string{"target", "rubric", "model", "threshold", "soft", "fail_on_low_confidence", "min_confidence",
+	"max_target_tokens", "long_target", "summary_model", "max_summary_chunks"}
+
+// emptySpecHash is the canonical hash of a spec with no judgment fields.
+var emptySpecHash, _ = types.CanonicalHash([]byte(`{}`))
+
+// judgeCacheRubric is the rubric component of a judge cache key. It adds to
+// rubric.CacheKey() a digest of the spec fields that change the judgment,
+// such as criteria and meta_eval, so assertions judged under different
+// criteria never share a score. Specs without such fields keep the rubric key
+// alone. The digest is of the canonical spec, so SDKs that order keys
+// differently still share entries.
+func judgeCacheRubric(rubric *judge.Rubric, spec json.RawMessage) string {
+	if len(spec) == 0 {
+		return rubric.CacheKey()
+	}
+	hash, err := types.CanonicalHash(spec, judgeNeutralFields...)
+	if err != nil || hash == emptySpecHash {
+		return rubric.CacheKey()
+	}
+	return rubric.CacheKey() + "@" + hash[:12]
 }
 
 const metaEvalRuns = 3
@@ -48,6 +89,16 @@ const metaEvalVarianceThreshold = 0.2
 
 // Evaluate runs the LLM judge assertion against the trace.
 func (e *JudgeEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	return e.evaluate(trace, assertion, nil)
+}
+
+// EvaluateWithSeed runs the judge with seeded sampling so reruns are reproducible.
+// Meta-eval run i uses seed+i so the runs still sample independently.
+func (e *JudgeEvaluator) EvaluateWithSeed(trace *types.Trace, assertion *types.Assertion, seed int64) *types.AssertionResult {
+	return e.evaluate(trace, assertion, &seed)
+}
+
+func (e *JudgeEvaluator) evaluate(trace *types.Trace, assertion *types.Assertion, seed *int64) *types.AssertionResult {
 	start := time.Now()
 
 	var spec judgeSpec
@@ -80,34 +131,69 @@ func (e *JudgeEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion
 		model = e.provider.DefaultModel()
 	}
 
-	// Check cache
-	if e.cache != nil {
+	plan, err := planLongTarget(spec, targetStr, model)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid judge spec: %v", err))
+	}
+	cacheRubric := judgeCacheRubric(rubric, assertion.Spec) + plan.cacheKey()
+
+	batchCtx := batchContext(trace)
+	rec := timing.FromContext(batchCtx)
+
+	// Check cache. The confidence policy needs the individual runs, which
+	// the cache does not keep.
+	if e.cache != nil && !spec.FailOnLowConfidence {
 		contentHash := cache.JudgeContentHash(targetStr)
-		if cached, cErr := e.cache.Get(contentHash, rubricName, model); cErr == nil && cached != nil {
+		cacheStart := time.Now()
+		cached, cErr := e.cache.Get(contentHash, cacheRubric, model)
+		rec.Cache(time.Since(cacheStart))
+		rec.CacheLookup("judge", cErr == nil && cached != nil)
+		if cErr == nil && cached != nil {
 			durationMS := time.Since(start).Milliseconds()
-			return e.buildResult(assertion, cached.Score, cached.Explanation, spec.Threshold, spec.Soft, durationMS, 0)
+			return e.buildResult(assertion, rubricName, model, cached.Score, cached.Explanation, spec.Threshold, spec.Soft, durationMS, 0)
+		}
+	}
+
+	// Fit a long target to its budget. The cache stays keyed by the full
+	// target, so a hit skips the summarization too, and the note on how the
+	// target was shortened is cached with the explanation.
+	judged := targetStr
+	var summary *types.TargetSummary
+	var note string
+	if plan != nil {
+		judged, summary = e.fitTarget(batchCtx, plan, targetStr, spec.Criteria, seed)
+		note = summaryNote(summary)
+		if summary.Method != plan.method {
+			// A summarization that fell back to truncation is cached as a
+			// truncation, so one provider error does not stick to the
+			// summarize entry.
+			fallback := &longTargetPlan{budget: plan.budget, method: summary.Method}
+			cacheRubric = judgeCacheRubric(rubric, assertion.Spec) + fallback.cacheKey()
 		}
 	}
 
 	// Build LLM request
 	timeoutSecs := judgeTimeoutSeconds()
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSecs)*time.Second)
+	ctx, cancel := context.WithTimeout(batchCtx, time.Duration(timeoutSecs)*time.Second)
 	defer cancel()
-	wrapped := judge.WrapAgentOutput(targetStr)
-	userContent := wrapped
-	if spec.Criteria != "" {
-		userContent = fmt.Sprintf("Evaluation criteria: %s\n\n%s", spec.Criteria, wrapped)
-	}
+	userContent := judgeUserContent(judged, spec.Criteria)
 
+	var result *types.AssertionResult
 	if metaEvalEnabled(spec) {
-		return e.evaluateWithMetaEval(ctx, assertion, rubric, model, userContent, spec, start, targetStr, rubricName)
+		result = e.evaluateWithMetaEval(ctx, assertion, rubric, model, userContent, spec, start, targetStr, rubricName, cacheRubric, note, seed)
+	} else {
+		result = e.evaluateSinglePass(ctx, assertion, rubric, model, userContent, spec, start, targetStr, rubricName, cacheRubric, note, seed)
 	}
-
-	return e.evaluateSinglePass(ctx, assertion, rubric, model, userContent, spec, start, targetStr, rubricName)
+	if summary != nil {
+		result.TargetSummary = summary
+		result.Cost += summary.Cost
+	}
+	return result
 }
 
 func (e *JudgeEvaluator) buildResult(
 	assertion *types.Assertion,
+	rubricName, model string,
 	score float64,
 	explanation string,
 	threshold float64,
@@ -115,6 +201,20 @@ func (e *JudgeEvaluator) buildResult(
 	durationMS int64,
 	cost float64,
 ) *types.AssertionResult {
+	var calibration *types.CalibrationInfo
+	if c := e.calibration(rubricName, model); c != nil {
+		calibration = &types.CalibrationInfo{
+			RawScore: score,
+			Rubric:   rubricName,
+			Model:    model,
+			Examples: c.Examples,
+			Pearson:  c.Pearson,
+			MAE:      c.MAE,
+			FittedAt: c.FittedAt.Format(time.RFC3339),
+		}
+		score = c.Apply(score)
+	}
+
 	status := types.StatusPass
 	if score < threshold {
 		if soft {
@@ -132,7 +232,26 @@ func (e *JudgeEvaluator) buildResult(
 		Cost:        cost,
 		DurationMS:  durationMS,
 		RequestID:   assertion.RequestID,
+		Calibration: calibration,
+	}
+}
+
+// calibration returns the stored curve for rubric and model, or nil when
+// there is none or it cannot be read. Cached judge results hold raw scores,
+// so a new curve applies to them too.
+func (e *JudgeEvaluator) calibration(rubric, model string) *judge.Calibration {
+	if e.calibrations == nil {
+		return nil
+	}
+	raw, err := e.calibrations.Get(rubric, model)
+	if err != nil || raw == nil {
+		return nil
 	}
+	var c judge.Calibration
+	if err := json.Unmarshal(raw, &c); err != nil || c.Validate() != nil {
+		return nil
+	}
+	return &c
 }
 
 // judgeTimeoutSeconds reads the judge evaluation timeout from ATTEST_JUDGE_TIMEOUT_S.
@@ -149,15 +268,17 @@ func judgeTimeoutSeconds() int {
 	return n
 }
 
-// metaEvalEnabled returns true if meta-evaluation is requested via spec or env var.
+// metaEvalEnabled returns true if meta-evaluation is requested via spec or env
+// var, or implied by the confidence policy.
 func metaEvalEnabled(spec judgeSpec) bool {
-	if spec.MetaEval {
+	if spec.MetaEval || spec.FailOnLowConfidence {
 		return true
 	}
 	return os.Getenv("ATTEST_JUDGE_META_EVAL") == "true"
 }
 
-// evaluateSinglePass runs the judge once (default behavior).
+// evaluateSinglePass runs the judge once (default behavior). note, if set,
+// starts the explanation.
 func (e *JudgeEvaluator) evaluateSinglePass(
 	ctx context.Context,
 	assertion *types.Assertion,
@@ -165,39 +286,100 @@ func (e *JudgeEvaluator) evaluateSinglePass(
 	model, userContent string,
 	spec judgeSpec,
 	start time.Time,
-	targetStr, rubricName string,
+	targetStr, rubricName, cacheRubric, note string,
+	seed *int64,
 ) *types.AssertionResult {
+	scoreResult, cost, err := e.judgeOnce(ctx, rubric, model, userContent, seed)
+	if err != nil {
+		return failResult(assertion, start, err.Error())
+	}
+	explanation := note + scoreResult.Explanation
+
+	durationMS := time.Since(start).Milliseconds()
+
+	if e.cache != nil {
+		contentHash := cache.JudgeContentHash(targetStr)
+		cacheStart := time.Now()
+		putErr := e.cache.Put(contentHash, cacheRubric, model, &cache.JudgeCacheEntry{
+			Score:       scoreResult.Score,
+			Explanation: explanation,
+		})
+		timing.FromContext(ctx).Cache(time.Since(cacheStart))
+		if putErr != nil {
+			logging.FromContext(ctx).Error("judge cache write error", "assertion_id", assertion.AssertionID, "err", putErr)
+		}
+	}
+
+	return e.buildResult(assertion, rubricName, model, scoreResult.Score, explanation, spec.Threshold, spec.Soft, durationMS, cost)
+}
+
+// judgeOnce sends one deterministic judge request and parses its score.
+func (e *JudgeEvaluator) judgeOnce(ctx context.Context, rubric *judge.Rubric, model, userContent string, seed *int64) (*judge.ScoreResult, float64, error) {
 	req := &llm.CompletionRequest{
 		Model:        model,
-		SystemPrompt: rubric.SystemPrompt,
+		SystemPrompt: rubric.Prompt(),
 		Messages:     []llm.Message{{Role: "user", Content: userContent}},
 		Temperature:  0.0,
 		MaxTokens:    256,
+		Seed:         seed,
 	}
 
 	resp, err := e.provider.Complete(ctx, req)
 	if err != nil {
-		return failResult(assertion, start, fmt.Sprintf("LLM call failed: %v", err))
+		return nil, 0, fmt.Errorf("LLM call failed: %v", err)
 	}
 
 	scoreResult, err := judge.ParseScoreResult(resp.Content)
 	if err != nil {
-		return failResult(assertion, start, fmt.Sprintf("parse judge response: %v", err))
+		return nil, resp.Cost, fmt.Errorf("parse judge response: %v", err)
 	}
+	return scoreResult, resp.Cost, nil
+}
 
-	durationMS := time.Since(start).Milliseconds()
+// RawScore judges text against a rubric with the default model and returns
+// the uncalibrated score and its cost, for fitting calibration curves.
+// Results are read from and written to the judge cache.
+func (e *JudgeEvaluator) RawScore(ctx context.Context, text, rubricName, criteria string) (float64, float64, error) {
+	rubric, err := e.rubrics.Get(rubricName)
+	if err != nil {
+		return 0, 0, err
+	}
+	model := e.provider.DefaultModel()
+	contentHash := cache.JudgeContentHash(text)
+	// Keyed as an llm_judge assertion with the same criteria would be.
+	var spec json.RawMessage
+	if criteria != "" {
+		spec, _ = json.Marshal(map[string]string{"criteria": criteria})
+	}
+	rubricKey := judgeCacheRubric(rubric, spec)
+	if e.cache != nil {
+		if cached, cErr := e.cache.Get(contentHash, rubricKey, model); cErr == nil && cached != nil {
+			return cached.Score, 0, nil
+		}
+	}
 
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(judgeTimeoutSeconds())*time.Second)
+	defer cancel()
+	scoreResult, cost, err := e.judgeOnce(timeoutCtx, rubric, model, judgeUserContent(text, criteria), nil)
+	if err != nil {
+		return 0, cost, err
+	}
 	if e.cache != nil {
-		contentHash := cache.JudgeContentHash(targetStr)
-		if putErr := e.cache.Put(contentHash, rubricName, model, &cache.JudgeCacheEntry{
+		_ = e.cache.Put(contentHash, rubricKey, model, &cache.JudgeCacheEntry{
 			Score:       scoreResult.Score,
 			Explanation: scoreResult.Explanation,
-		}); putErr != nil {
-			slog.Error("judge cache write error", "err", putErr)
-		}
+		})
 	}
+	return scoreResult.Score, cost, nil
+}
 
-	return e.buildResult(assertion, scoreResult.Score, scoreResult.Explanation, spec.Threshold, spec.Soft, durationMS, resp.Cost)
+// judgeUserContent wraps the text under evaluation, prefixed by criteria.
+func judgeUserContent(text, criteria string) string {
+	wrapped := judge.WrapAgentOutput(text)
+	if criteria == "" {
+		return wrapped
+	}
+	return fmt.Sprintf("Evaluation criteria: %s\n\n%s", criteria, wrapped)
 }
 
 // metaEvalResult holds one judge run's output.
@@ -209,7 +391,8 @@ type metaEvalResult struct {
 }
 
 // evaluateWithMetaEval runs the judge 3x concurrently, takes the median score,
-// and flags high variance in the explanation.
+// and flags high variance in the explanation. note, if set, starts the
+// explanation.
 func (e *JudgeEvaluator) evaluateWithMetaEval(
 	ctx context.Context,
 	assertion *types.Assertion,
@@ -217,7 +400,8 @@ func (e *JudgeEvaluator) evaluateWithMetaEval(
 	model, userContent string,
 	spec judgeSpec,
 	start time.Time,
-	targetStr, rubricName string,
+	targetStr, rubricName, cacheRubric, note string,
+	seed *int64,
 ) *types.AssertionResult {
 	results := make([]metaEvalResult, metaEvalRuns)
 	var wg sync.WaitGroup
@@ -226,13 +410,24 @@ func (e *JudgeEvaluator) evaluateWithMetaEval(
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
+			// A panic here would escape the pipeline's recover and stop the
+			// engine; count the run as failed instead.
+			defer func() {
+				if r := recover(); r != nil {
+					results[idx] = metaEvalResult{err: fmt.Errorf("panic: %v", r)}
+				}
+			}()
 			req := &llm.CompletionRequest{
 				Model:        model,
-				SystemPrompt: rubric.SystemPrompt,
+				SystemPrompt: rubric.Prompt(),
 				Messages:     []llm.Message{{Role: "user", Content: userContent}},
 				Temperature:  metaEvalTemperature,
 				MaxTokens:    256,
 			}
+			if seed != nil {
+				runSeed := *seed + int64(idx)
+				req.Seed = &runSeed
+			}
 
 			resp, err := e.provider.Complete(ctx, req)
 			if err != nil {
@@ -257,7 +452,7 @@ func (e *JudgeEvaluator) evaluateWithMetaEval(
 	wg.Wait()
 
 	// Collect successful results
-	var scores []float64
+	var scores, runScores []float64
 	var explanations []string
 	var totalCost float64
 	var firstErr error
@@ -270,6 +465,7 @@ func (e *JudgeEvaluator) evaluateWithMetaEval(
 			continue
 		}
 		scores = append(scores, r.score)
+		runScores = append(runScores, r.score)
 		explanations = append(explanations, fmt.Sprintf("Run %d: %s", i+1, r.explanation))
 		totalCost += r.cost
 	}
@@ -290,20 +486,51 @@ func (e *JudgeEvaluator) evaluateWithMetaEval(
 		varianceNote = fmt.Sprintf(" [HIGH VARIANCE: spread=%.2f across %d runs]", spread, len(scores))
 	}
 
-	combinedExplanation := strings.Join(explanations, " | ") + " | Median selected." + varianceNote
+	combinedExplanation := note + strings.Join(explanations, " | ") + " | Median selected." + varianceNote
 
 	durationMS := time.Since(start).Milliseconds()
 
 	// Cache the median result
 	if e.cache != nil {
 		contentHash := cache.JudgeContentHash(targetStr)
-		if putErr := e.cache.Put(contentHash, rubricName, model, &cache.JudgeCacheEntry{
+		cacheStart := time.Now()
+		putErr := e.cache.Put(contentHash, cacheRubric, model, &cache.JudgeCacheEntry{
 			Score:       medianScore,
 			Explanation: combinedExplanation,
-		}); putErr != nil {
-			slog.Error("judge cache write error", "err", putErr)
+		})
+		timing.FromContext(ctx).Cache(time.Since(cacheStart))
+		if putErr != nil {
+			logging.FromContext(ctx).Error("judge cache write error", "assertion_id", assertion.AssertionID, "err", putErr)
 		}
 	}
 
-	return e.buildResult(assertion, medianScore, combinedExplanation, spec.Threshold, spec.Soft, durationMS, totalCost)
+	result := e.buildResult(assertion, rubricName, model, medianScore, combinedExplanation, spec.Threshold, spec.Soft, durationMS, totalCost)
+	result.JudgeRuns = &types.JudgeRuns{
+		Scores:     runScores,
+		Failed:     metaEvalRuns - len(runScores),
+		Spread:     spread,
+		Confidence: 1 - spread,
+	}
+	applyConfidencePolicy(result, spec)
+	return result
+}
+
+// applyConfidencePolicy fails a passing result whose judge runs disagree when
+// the spec sets fail_on_low_confidence.
+func applyConfidencePolicy(result *types.AssertionResult, spec judgeSpec) {
+	if !spec.FailOnLowConfidence || result.Status != types.StatusPass {
+		return
+	}
+	minConfidence := 1 - metaEvalVarianceThreshold
+	if spec.MinConfidence != nil {
+		minConfidence = *spec.MinConfidence
+	}
+	if result.JudgeRuns.Confidence >= minConfidence {
+		return
+	}
+	result.Status = types.StatusHardFail
+	if spec.Soft {
+		result.Status = types.StatusSoftFail
+	}
+	result.Explanation += fmt.Sprintf(" [LOW CONFIDENCE: %.2f < %.2f]", result.JudgeRuns.Confidence, minConfidence)
 }
@@ -2,7 +2,9 @@ package assertion
 
 import (
 	"encoding/json"
+	"math"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/attest-ai/attest/engine/internal/assertion/judge"
@@ -10,6 +12,34 @@ import (
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
+func TestJudgeMeta_RunPanicIsRecovered(t *testing.T) {
+	mock := llm.NewMockProvider(nil, nil)
+	var calls atomic.Int32
+	mock.MatchFunc = func(*llm.CompletionRequest) *llm.CompletionResponse {
+		if calls.Add(1) == 1 {
+			panic("provider bug")
+		}
+		return &llm.CompletionResponse{Content: `{"score": 0.8, "explanation": "fine"}`}
+	}
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+	a := &types.Assertion{
+		AssertionID: "meta-panic",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","rubric":"default","threshold":0.4,"meta_eval":true}`),
+	}
+
+	result := evaluator.Evaluate(&types.Trace{Output: json.RawMessage(`"output"`)}, a)
+	if result.Status != types.StatusPass || result.Score != 0.8 {
+		t.Errorf("result = %+v, want the two surviving runs to pass", result)
+	}
+
+	mock.MatchFunc = func(*llm.CompletionRequest) *llm.CompletionResponse { panic("provider bug") }
+	result = evaluator.Evaluate(&types.Trace{Output: json.RawMessage(`"other output"`)}, a)
+	if result.Status != types.StatusHardFail || !strings.Contains(result.Explanation, "panic: provider bug") {
+		t.Errorf("result = %+v, want hard_fail naming the panic", result)
+	}
+}
+
 func TestJudgeMeta_MedianScore(t *testing.T) {
 	// Three responses with different scores — median should be selected
 	mock := llm.NewMockProvider([]*llm.CompletionResponse{
@@ -156,3 +186,147 @@ func TestJudgeMeta_DisabledByDefault(t *testing.T) {
 		t.Errorf("expected 1 LLM call (single pass), got %d", mock.GetCallCount())
 	}
 }
+
+func TestJudgeMeta_SeedPropagatesToRuns(t *testing.T) {
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: `{"score": 0.9, "explanation": "ok"}`, Model: "mock-model"},
+	}, nil)
+
+	registry := NewRegistry(WithJudge(mock, judge.NewRubricRegistry(), nil))
+	pipeline := NewPipeline(registry)
+
+	trace := &types.Trace{
+		TraceID: "trc_seed",
+		Output:  json.RawMessage(`{"message":"seeded output"}`),
+	}
+	assertions := []types.Assertion{{
+		AssertionID: "meta-seed",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output.message","meta_eval":true}`),
+	}}
+
+	seed := int64(42)
+	if _, err := pipeline.EvaluateBatchWithOptions(trace, assertions, BatchOptions{Seed: &seed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[int64]bool)
+	for _, req := range mock.GetRequestHistory() {
+		if req.Seed == nil {
+			t.Fatal("expected every meta-eval request to carry a seed")
+		}
+		got[*req.Seed] = true
+	}
+	for _, want := range []int64{42, 43, 44} {
+		if !got[want] {
+			t.Errorf("expected a request with seed %d, got %v", want, got)
+		}
+	}
+}
+
+func TestJudgeMeta_NoSeedByDefault(t *testing.T) {
+	mock := llm.NewMockProvider(nil, nil)
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+
+	trace := &types.Trace{Output: json.RawMessage(`"unseeded"`)}
+	a := &types.Assertion{
+		AssertionID: "meta-noseed",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output"}`),
+	}
+	evaluator.Evaluate(trace, a)
+
+	if req := mock.GetRequestHistory()[0]; req.Seed != nil {
+		t.Errorf("expected nil seed without batch seed, got %d", *req.Seed)
+	}
+}
+
+func TestJudgeMeta_JudgeRuns(t *testing.T) {
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: `{"score": 0.7, "explanation": "run one"}`, Model: "mock-model"},
+		{Content: `{"score": 0.8, "explanation": "run two"}`, Model: "mock-model"},
+		{Content: `{"score": 0.75, "explanation": "run three"}`, Model: "mock-model"},
+	}, nil)
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+
+	trace := &types.Trace{Output: json.RawMessage(`"runs"`)}
+	a := &types.Assertion{
+		AssertionID: "meta-runs",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","threshold":0.5,"meta_eval":true}`),
+	}
+	result := evaluator.Evaluate(trace, a)
+
+	runs := result.JudgeRuns
+	if runs == nil {
+		t.Fatal("expected judge_runs on a meta-eval result")
+	}
+	if len(runs.Scores) != 3 || runs.Failed != 0 {
+		t.Errorf("expected 3 scores and no failures, got %+v", runs)
+	}
+	if math.Abs(runs.Spread-0.1) > 1e-9 || math.Abs(runs.Confidence-0.9) > 1e-9 {
+		t.Errorf("expected spread 0.1 and confidence 0.9, got %+v", runs)
+	}
+	if result.Status != types.StatusPass {
+		t.Errorf("expected pass without the confidence policy, got %s", result.Status)
+	}
+}
+
+func TestJudgeMeta_SinglePassHasNoRuns(t *testing.T) {
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: `{"score": 0.9, "explanation": "single"}`, Model: "mock-model"},
+	}, nil)
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+
+	trace := &types.Trace{Output: json.RawMessage(`"single"`)}
+	a := &types.Assertion{
+		AssertionID: "meta-single",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output"}`),
+	}
+	if result := evaluator.Evaluate(trace, a); result.JudgeRuns != nil {
+		t.Errorf("expected no judge_runs for a single pass, got %+v", result.JudgeRuns)
+	}
+}
+
+func TestJudgeMeta_FailOnLowConfidence(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"hard", `{"target":"output","threshold":0.4,"fail_on_low_confidence":true}`, types.StatusHardFail},
+		{"soft", `{"target":"output","threshold":0.4,"fail_on_low_confidence":true,"soft":true}`, types.StatusSoftFail},
+		{"min confidence met", `{"target":"output","threshold":0.4,"fail_on_low_confidence":true,"min_confidence":0.3}`, types.StatusPass},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := llm.NewMockProvider([]*llm.CompletionResponse{
+				{Content: `{"score": 0.2, "explanation": "low"}`, Model: "mock-model"},
+				{Content: `{"score": 0.8, "explanation": "high"}`, Model: "mock-model"},
+				{Content: `{"score": 0.5, "explanation": "middle"}`, Model: "mock-model"},
+			}, nil)
+			evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+
+			trace := &types.Trace{Output: json.RawMessage(`"disputed"`)}
+			a := &types.Assertion{
+				AssertionID: "meta-confidence",
+				Type:        types.TypeLLMJudge,
+				Spec:        json.RawMessage(tt.spec),
+			}
+			result := evaluator.Evaluate(trace, a)
+
+			// The policy implies meta-eval: three runs, spread 0.6.
+			if mock.GetCallCount() != 3 {
+				t.Errorf("expected 3 judge calls, got %d", mock.GetCallCount())
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("expected %s, got %s: %s", tt.wantStatus, result.Status, result.Explanation)
+			}
+			flagged := strings.Contains(result.Explanation, "LOW CONFIDENCE")
+			if flagged != (tt.wantStatus != types.StatusPass) {
+				t.Errorf("unexpected LOW CONFIDENCE flag state in %q", result.Explanation)
+			}
+		})
+	}
+}
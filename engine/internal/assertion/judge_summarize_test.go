@@ -0,0 +1,229 @@
+package assertion
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// summarizingMock answers summarization requests with a short summary and
+// judge requests with a passing score.
+func summarizingMock() *llm.MockProvider {
+	mock := llm.NewMockProvider(nil, nil)
+	mock.MatchFunc = func(req *llm.CompletionRequest) *llm.CompletionResponse {
+		if strings.HasPrefix(req.SystemPrompt, summarizePrompt) {
+			return &llm.CompletionResponse{Content: "The agent booked the flight.", Model: req.Model, Cost: 0.0001}
+		}
+		return &llm.CompletionResponse{Content: `{"score": 0.9, "explanation": "good"}`, Model: req.Model, Cost: 0.01}
+	}
+	return mock
+}
+
+func longTargetTrace(words int) *types.Trace {
+	out, _ := json.Marshal(strings.Repeat("flight booked ", words))
+	return &types.Trace{Output: out}
+}
+
+func TestJudgeEvaluator_SummarizesLongTarget(t *testing.T) {
+	mock := summarizingMock()
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+	a := &types.Assertion{
+		AssertionID: "long",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","criteria":"books a flight","max_target_tokens":500,"long_target":"summarize","summary_model":"cheap"}`),
+	}
+
+	// 42000 characters: three chunks of at most summaryChunkTokens.
+	result := evaluator.Evaluate(longTargetTrace(3000), a)
+	if result.Status != types.StatusPass {
+		t.Fatalf("status = %s, explanation = %q", result.Status, result.Explanation)
+	}
+	s := result.TargetSummary
+	if s == nil {
+		t.Fatal("TargetSummary not set")
+	}
+	if s.Method != "summarize" || s.Model != "cheap" || s.Chunks != 3 || s.Rounds != 1 || s.OriginalTokens != 10500 || s.Tokens > 500 || s.Error != "" {
+		t.Errorf("TargetSummary = %+v", s)
+	}
+	if mock.GetCallCount() != 4 {
+		t.Errorf("calls = %d, want 3 summaries and 1 judgment", mock.GetCallCount())
+	}
+	if diff := s.Cost - 0.0003; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("summary cost = %v, want 0.0003", s.Cost)
+	}
+	if diff := result.Cost - 0.0103; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("cost = %v, want judge plus summary cost 0.0103", result.Cost)
+	}
+	if !strings.HasPrefix(result.Explanation, "[target summarized from ~10500") {
+		t.Errorf("explanation = %q, want a summarization note", result.Explanation)
+	}
+	judged := mock.RequestHistory[len(mock.RequestHistory)-1].Messages[0].Content
+	if !strings.Contains(judged, "The agent booked the flight.") || strings.Contains(judged, "flight booked flight booked") {
+		t.Errorf("judge saw %q, want the summary", judged)
+	}
+}
+
+func TestJudgeEvaluator_TruncatesLongTarget(t *testing.T) {
+	t.Setenv("ATTEST_JUDGE_MAX_TARGET_TOKENS", "100")
+	mock := summarizingMock()
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+	a := &types.Assertion{AssertionID: "long", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{"target":"output"}`)}
+
+	result := evaluator.Evaluate(longTargetTrace(100), a)
+	if mock.GetCallCount() != 1 {
+		t.Errorf("calls = %d, want the judgment only", mock.GetCallCount())
+	}
+	s := result.TargetSummary
+	if s == nil || s.Method != "truncate" || s.OriginalTokens != 350 || s.Tokens > 100 || s.Cost != 0 {
+		t.Fatalf("TargetSummary = %+v", s)
+	}
+	if judged := mock.LastRequest.Messages[0].Content; !strings.Contains(judged, "characters omitted") {
+		t.Errorf("judge saw %q, want a truncation marker", judged)
+	}
+
+	// A target within the budget is judged as is.
+	result = evaluator.Evaluate(longTargetTrace(10), a)
+	if result.TargetSummary != nil || strings.Contains(mock.LastRequest.Messages[0].Content, "omitted") {
+		t.Errorf("short target shortened: %+v", result.TargetSummary)
+	}
+}
+
+func TestJudgeEvaluator_SummaryFailureTruncates(t *testing.T) {
+	mock := summarizingMock()
+	mock.Errors = []error{errors.New("rate limited")}
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+	a := &types.Assertion{
+		AssertionID: "long",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","max_target_tokens":100,"long_target":"summarize"}`),
+	}
+
+	result := evaluator.Evaluate(longTargetTrace(100), a)
+	s := result.TargetSummary
+	if s == nil || s.Method != "truncate" || !strings.Contains(s.Error, "rate limited") || s.Tokens > 100 {
+		t.Fatalf("TargetSummary = %+v", s)
+	}
+	if result.Status != types.StatusPass {
+		t.Errorf("status = %s, explanation = %q", result.Status, result.Explanation)
+	}
+}
+
+func TestJudgeEvaluator_SummaryChunkLimitTruncates(t *testing.T) {
+	mock := summarizingMock()
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+	a := &types.Assertion{
+		AssertionID: "long",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","max_target_tokens":500,"long_target":"summarize","max_summary_chunks":2}`),
+	}
+
+	// Three chunks, over the limit of two: truncated without summary calls.
+	result := evaluator.Evaluate(longTargetTrace(3000), a)
+	if mock.GetCallCount() != 1 {
+		t.Errorf("calls = %d, want the judgment only", mock.GetCallCount())
+	}
+	s := result.TargetSummary
+	if s == nil || s.Method != "truncate" || s.Chunks != 3 || s.Cost != 0 || s.Tokens > 500 ||
+		s.Error != "target needs 3 chunks, over max_summary_chunks 2" {
+		t.Fatalf("TargetSummary = %+v", s)
+	}
+	if !strings.HasPrefix(result.Explanation, "[target truncated from ~10500") || !strings.Contains(result.Explanation, "max_summary_chunks 2") {
+		t.Errorf("explanation = %q, want a truncation note naming the limit", result.Explanation)
+	}
+
+	// The environment default applies when the spec sets no limit.
+	t.Setenv("ATTEST_JUDGE_MAX_SUMMARY_CHUNKS", "1")
+	a.Spec = json.RawMessage(`{"target":"output","max_target_tokens":500,"long_target":"summarize"}`)
+	result = evaluator.Evaluate(longTargetTrace(3000), a)
+	if s := result.TargetSummary; s == nil || s.Method != "truncate" || !strings.Contains(s.Error, "max_summary_chunks 1") {
+		t.Errorf("TargetSummary = %+v, want the environment limit", s)
+	}
+}
+
+func TestJudgeEvaluator_SummaryCache(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	mock := summarizingMock()
+	mock.Errors = []error{errors.New("rate limited")}
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), store.Judge())
+	a := &types.Assertion{
+		AssertionID: "long",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","max_target_tokens":100,"long_target":"summarize"}`),
+	}
+	tr := longTargetTrace(100)
+
+	// The fallback is not cached as a summarized judgment.
+	if s := evaluator.Evaluate(tr, a).TargetSummary; s == nil || s.Method != "truncate" {
+		t.Fatalf("first TargetSummary = %+v, want the truncation fallback", s)
+	}
+	cold := evaluator.Evaluate(tr, a)
+	if s := cold.TargetSummary; s == nil || s.Method != "summarize" {
+		t.Fatalf("second TargetSummary = %+v, want a summary", s)
+	}
+
+	// A cache hit keeps the note in the explanation.
+	calls := mock.GetCallCount()
+	warm := evaluator.Evaluate(tr, a)
+	if mock.GetCallCount() != calls {
+		t.Errorf("cache hit made %d calls", mock.GetCallCount()-calls)
+	}
+	if warm.Explanation != cold.Explanation || !strings.HasPrefix(warm.Explanation, "[target summarized") {
+		t.Errorf("warm explanation = %q, cold = %q", warm.Explanation, cold.Explanation)
+	}
+}
+
+func TestJudgeEvaluator_SummaryPanicTruncates(t *testing.T) {
+	mock := summarizingMock()
+	judgeFn := mock.MatchFunc
+	mock.MatchFunc = func(req *llm.CompletionRequest) *llm.CompletionResponse {
+		if strings.HasPrefix(req.SystemPrompt, summarizePrompt) {
+			panic("provider bug")
+		}
+		return judgeFn(req)
+	}
+	evaluator := NewJudgeEvaluator(mock, judge.NewRubricRegistry(), nil)
+	a := &types.Assertion{
+		AssertionID: "long",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"target":"output","max_target_tokens":100,"long_target":"summarize"}`),
+	}
+
+	result := evaluator.Evaluate(longTargetTrace(100), a)
+	if s := result.TargetSummary; s == nil || s.Method != "truncate" || !strings.Contains(s.Error, "provider bug") {
+		t.Fatalf("TargetSummary = %+v, want a truncation after the panic", s)
+	}
+}
+
+func TestPlanLongTarget_Invalid(t *testing.T) {
+	for _, spec := range []judgeSpec{{LongTarget: "drop"}, {MaxTargetTokens: -1}, {MaxSummaryChunks: -1}} {
+		if _, err := planLongTarget(spec, "text", "model"); err == nil {
+			t.Errorf("planLongTarget(%+v) = nil error", spec)
+		}
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	text := strings.Repeat("word ", 50)
+	chunks := splitChunks(text, 32)
+	if strings.Join(chunks, "") != text {
+		t.Fatal("chunks do not reassemble the text")
+	}
+	for _, c := range chunks {
+		if len([]rune(c)) > 32 {
+			t.Errorf("chunk of %d runes, want at most 32", len([]rune(c)))
+		}
+		if c != chunks[len(chunks)-1] && !strings.HasSuffix(c, " ") {
+			t.Errorf("chunk %q not split at a space", c)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package assertion
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+// keywordMatcherMin is the keyword count from which multi-keyword checks use
+// a keywordMatcher; below it, per-keyword strings.Contains is faster.
+const keywordMatcherMin = 4
+
+// keywordMatcherCacheSize caps the number of matchers kept by sharedKeywordMatchers.
+const keywordMatcherCacheSize = 256
+
+// keywordMatcher finds which of a fixed set of keywords occur in a text with a
+// single pass over it (Aho-Corasick). The automaton is a dense DFA over the
+// byte classes that appear in the keywords, so each input byte costs two
+// table lookups regardless of how many keywords there are. A matcher is
+// immutable after construction and safe for concurrent use.
+type keywordMatcher struct {
+	n       int        // number of keywords
+	class   [256]int32 // byte -> class; 0 is every byte absent from all keywords
+	classes int
+	delta   []int32   // state*classes+class -> next state
+	terms   [][]int32 // state -> indices of keywords ending exactly here
+	dict    []int32   // state -> nearest proper suffix state with terms, or -1
+	empty   []int32   // indices of empty keywords, which match any text
+}
+
+func newKeywordMatcher(keywords []string) *keywordMatcher {
+	m := &keywordMatcher{n: len(keywords)}
+	m.classes = 1
+	for _, kw := range keywords {
+		for i := 0; i < len(kw); i++ {
+			if m.class[kw[i]] == 0 {
+				m.class[kw[i]] = int32(m.classes)
+				m.classes++
+			}
+		}
+	}
+
+	// Build the trie; 0 marks a missing edge until failure links fill it.
+	m.delta = make([]int32, m.classes)
+	m.terms = [][]int32{nil}
+	for i, kw := range keywords {
+		if kw == "" {
+			m.empty = append(m.empty, int32(i))
+			continue
+		}
+		state := int32(0)
+		for j := 0; j < len(kw); j++ {
+			idx := int(state)*m.classes + int(m.class[kw[j]])
+			next := m.delta[idx]
+			if next == 0 {
+				next = int32(len(m.terms))
+				m.delta[idx] = next
+				m.delta = append(m.delta, make([]int32, m.classes)...)
+				m.terms = append(m.terms, nil)
+			}
+			state = next
+		}
+		m.terms[state] = append(m.terms[state], int32(i))
+	}
+
+	// Breadth-first: turn missing edges into failure transitions and link each
+	// state to the nearest suffix state that ends a keyword.
+	states := len(m.terms)
+	fail := make([]int32, states)
+	m.dict = make([]int32, states)
+	m.dict[0] = -1
+	queue := make([]int32, 0, states)
+	for c := 1; c < m.classes; c++ {
+		if next := m.delta[c]; next != 0 {
+			fail[next] = 0
+			m.dict[next] = -1
+			queue = append(queue, next)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for c := 1; c < m.classes; c++ {
+			idx := int(s)*m.classes + c
+			next := m.delta[idx]
+			if next == 0 {
+				m.delta[idx] = m.delta[int(fail[s])*m.classes+c]
+				continue
+			}
+			f := m.delta[int(fail[s])*m.classes+c]
+			fail[next] = f
+			if len(m.terms[f]) > 0 {
+				m.dict[next] = f
+			} else {
+				m.dict[next] = m.dict[f]
+			}
+			queue = append(queue, next)
+		}
+	}
+	return m
+}
+
+// find reports, per keyword, whether it occurs in text. The scan stops early
+// once every keyword has been seen.
+func (m *keywordMatcher) find(text string) []bool {
+	found := make([]bool, m.n)
+	remaining := m.n
+	for _, i := range m.empty {
+		found[i] = true
+		remaining--
+	}
+	state := int32(0)
+	for i := 0; i < len(text) && remaining > 0; i++ {
+		state = m.delta[int(state)*m.classes+int(m.class[text[i]])]
+		for s := state; s > 0; s = m.dict[s] {
+			for _, k := range m.terms[s] {
+				if !found[k] {
+					found[k] = true
+					remaining--
+				}
+			}
+		}
+	}
+	return found
+}
+
+// keywordMatcherCache shares matchers between evaluations of the same
+// keyword list.
+type keywordMatcherCache struct {
+	lru *lruCache[*keywordMatcher]
+}
+
+// matcher returns the matcher for keywords, lowercased unless caseSensitive,
+// building and caching it on a miss. The cache key is a hash of the keyword
+// list so large safety lists are not held twice.
+func (c *keywordMatcherCache) matcher(keywords []string, caseSensitive bool) *keywordMatcher {
+	key := keywordSpecHash(keywords, caseSensitive)
+	if m, ok := c.lru.get(key); ok {
+		return m
+	}
+	cmp := keywords
+	if !caseSensitive {
+		cmp = make([]string, len(keywords))
+		for i, kw := range keywords {
+			cmp[i] = strings.ToLower(kw)
+		}
+	}
+	return c.lru.add(key, newKeywordMatcher(cmp))
+}
+
+func keywordSpecHash(keywords []string, caseSensitive bool) string {
+	h := sha256.New()
+	if caseSensitive {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+	var n [8]byte
+	for _, kw := range keywords {
+		binary.LittleEndian.PutUint64(n[:], uint64(len(kw)))
+		h.Write(n[:])
+		h.Write([]byte(kw))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sharedKeywordMatchers serves every ContentEvaluator.
+var sharedKeywordMatchers = &keywordMatcherCache{lru: newLRUCache[*keywordMatcher](keywordMatcherCacheSize)}
@@ -0,0 +1,89 @@
+package assertion
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestKeywordMatcher_Find(t *testing.T) {
+	keywords := []string{"he", "she", "his", "hers", "", "ushers", "he", "xyz"}
+	m := newKeywordMatcher(keywords)
+
+	got := m.find("ushers")
+	want := []bool{true, true, false, true, true, true, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("keyword %q: found = %v, want %v", keywords[i], got[i], want[i])
+		}
+	}
+}
+
+func TestKeywordMatcher_AgreesWithContains(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	word := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abcde"[rng.Intn(5)]
+		}
+		return string(b)
+	}
+	for iter := 0; iter < 200; iter++ {
+		keywords := make([]string, 1+rng.Intn(20))
+		for i := range keywords {
+			keywords[i] = word(1 + rng.Intn(4))
+		}
+		text := word(rng.Intn(200))
+		found := newKeywordMatcher(keywords).find(text)
+		for i, kw := range keywords {
+			if found[i] != strings.Contains(text, kw) {
+				t.Fatalf("text %q keyword %q: found = %v, want %v", text, kw, found[i], !found[i])
+			}
+		}
+	}
+}
+
+func TestKeywordsPresent_CachesMatcherBySpec(t *testing.T) {
+	keywords := []string{"Alpha", "beta", "GAMMA", "delta"}
+	cache := &keywordMatcherCache{lru: newLRUCache[*keywordMatcher](4)}
+
+	lower := cache.matcher(keywords, false)
+	if cache.matcher(keywords, false) != lower {
+		t.Error("same keyword list built a second matcher")
+	}
+	if cache.matcher(keywords, true) == lower {
+		t.Error("case-sensitive and insensitive specs share a matcher")
+	}
+
+	got := keywordsPresent("alpha and gamma", keywords, false)
+	want := []bool{true, false, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("keyword %q: present = %v, want %v", keywords[i], got[i], want[i])
+		}
+	}
+}
+
+func BenchmarkForbiddenKeywords(b *testing.B) {
+	keywords := make([]string, 200)
+	for i := range keywords {
+		keywords[i] = fmt.Sprintf("forbidden-term-%03d", i)
+	}
+	target := strings.Repeat("the agent processed the refund request without issue. ", 10000)
+
+	b.Run("matcher", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			keywordsPresent(target, keywords, false)
+		}
+	})
+	b.Run("contains", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, kw := range keywords {
+				strings.Contains(target, strings.ToLower(kw))
+			}
+		}
+	})
+}
@@ -0,0 +1,403 @@
+package assertion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+const (
+	// DefaultLinkCheckTimeout bounds each liveness request.
+	DefaultLinkCheckTimeout = 3 * time.Second
+	// DefaultLinkChecksPerBatch caps the liveness requests of one batch.
+	DefaultLinkChecksPerBatch = 20
+	// linkCacheSize bounds the liveness results kept across batches.
+	linkCacheSize = 1024
+	// maxLinkRedirects bounds the redirects a liveness request follows.
+	maxLinkRedirects = 10
+)
+
+// errNonPublicAddr rejects a liveness request to an address that is not on
+// the public internet.
+var errNonPublicAddr = errors.New("not a public address")
+
+// nonPublicPrefixes are ranges that netip does not classify but that reach
+// the local host or network: "this network" (0.0.0.0/8, which Linux routes
+// to the host) and carrier-grade NAT (RFC 6598), which some clouds use for
+// metadata services.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// urlRegex matches a URL in prose: an http(s) URL or a bare www. host, up to
+// whitespace or a character that cannot appear unescaped in a URL.
+var urlRegex = regexp.MustCompile("(?i)\\b(?:https?://|www\\.)[^\\s<>\"'`{}|\\\\^\\[\\]]+")
+
+// LinkCheckConfig configures the liveness requests of urls_valid content
+// checks. Liveness is checked only when the registry is built
+// WithLinkCheck; otherwise urls_valid never touches the network.
+type LinkCheckConfig struct {
+	// Timeout bounds each request. Default: DefaultLinkCheckTimeout.
+	Timeout time.Duration
+	// MaxPerBatch caps the requests of one batch; further URLs are left
+	// unchecked. Default: DefaultLinkChecksPerBatch.
+	MaxPerBatch int
+	// Client sends the requests. Default: a client that dials only public
+	// addresses and re-validates every redirect. A caller-supplied client
+	// is used as is.
+	Client *http.Client
+}
+
+// WithLinkCheck enables liveness requests for urls_valid checks that set
+// check_live.
+func WithLinkCheck(cfg LinkCheckConfig) RegistryOption {
+	return func(rc *registryConfig) {
+		rc.linkCheck = newLinkChecker(cfg)
+	}
+}
+
+// linkStatus is the outcome of a liveness request.
+type linkStatus struct {
+	// dead is set when the link certainly does not resolve: the host does
+	// not exist or the server answered 404 or 410.
+	dead bool
+	// detail is the status code or error, for explanations.
+	detail string
+}
+
+// linkChecker sends HEAD requests for urls_valid checks and remembers
+// definitive answers. Timeouts and server errors are not cached.
+type linkChecker struct {
+	client      *http.Client
+	timeout     time.Duration
+	maxPerBatch int
+	cache       *lruCache[linkStatus]
+}
+
+func newLinkChecker(cfg LinkCheckConfig) *linkChecker {
+	c := &linkChecker{
+		client:      cfg.Client,
+		timeout:     cfg.Timeout,
+		maxPerBatch: cfg.MaxPerBatch,
+		cache:       newLRUCache[linkStatus](linkCacheSize),
+	}
+	if c.client == nil {
+		c.client = publicOnlyClient()
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultLinkCheckTimeout
+	}
+	if c.maxPerBatch <= 0 {
+		c.maxPerBatch = DefaultLinkChecksPerBatch
+	}
+	return c
+}
+
+// publicOnlyClient returns the default liveness client. Links come from
+// agent output, so the client must not be steered at the engine's own
+// network: every connection is checked after DNS resolution, so no name can
+// resolve past the check, and no proxy stands between the check and the
+// address dialed.
+func publicOnlyClient() *http.Client {
+	dialer := &net.Dialer{Control: dialPublicOnly}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: DefaultLinkCheckTimeout,
+		},
+		CheckRedirect: checkLinkRedirect,
+	}
+}
+
+// dialPublicOnly is a net.Dialer Control hook that refuses connections to
+// loopback, private, link-local, shared, multicast, and unspecified
+// addresses.
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(ip) {
+		return fmt.Errorf("%s: %w", ip, errNonPublicAddr)
+	}
+	return nil
+}
+
+// publicAddr reports whether ip is routable on the public internet.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLinkRedirect re-validates each redirect hop as a link of its own:
+// the dialer still checks the address, and a hop to a malformed or non-web
+// URL, or one past maxLinkRedirects, is not followed.
+func checkLinkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxLinkRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxLinkRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to %s URL", req.URL.Scheme)
+	}
+	if _, _, problem := parseLink(req.URL.String()); problem != "" {
+		return fmt.Errorf("redirect to %s: %s", req.URL.Redacted(), problem)
+	}
+	if ip, err := netip.ParseAddr(req.URL.Hostname()); err == nil && !publicAddr(ip) {
+		return fmt.Errorf("redirect to %s: %w", ip, errNonPublicAddr)
+	}
+	return nil
+}
+
+// linkQuotaKey carries the number of liveness requests made by a batch.
+type linkQuotaKey struct{}
+
+// withLinkQuota starts a fresh liveness request count for a batch.
+func withLinkQuota(ctx context.Context) context.Context {
+	return context.WithValue(ctx, linkQuotaKey{}, new(atomic.Int64))
+}
+
+// check returns the liveness of rawURL, or ok false when the batch has used
+// its request quota.
+func (c *linkChecker) check(ctx context.Context, rawURL string) (status linkStatus, ok bool) {
+	if s, hit := c.cache.get(rawURL); hit {
+		return s, true
+	}
+	used, _ := ctx.Value(linkQuotaKey{}).(*atomic.Int64)
+	if used == nil {
+		used = new(atomic.Int64)
+	}
+	if used.Add(1) > int64(c.maxPerBatch) {
+		return linkStatus{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return linkStatus{dead: true, detail: err.Error()}, true
+	}
+	req.Header.Set("User-Agent", "attest-engine link check")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return c.cache.add(rawURL, linkStatus{dead: true, detail: "host not found"}), true
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return linkStatus{detail: fmt.Sprintf("no answer within %s", c.timeout)}, true
+		}
+		if errors.Is(err, errNonPublicAddr) {
+			return c.cache.add(rawURL, linkStatus{detail: "not checked: " + errNonPublicAddr.Error()}), true
+		}
+		return linkStatus{detail: err.Error()}, true
+	}
+	resp.Body.Close()
+
+	s := linkStatus{detail: resp.Status}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		s.dead = true
+	case resp.StatusCode >= 500:
+		return s, true
+	}
+	return c.cache.add(rawURL, s), true
+}
+
+// extractURLs returns the distinct URLs in text in order of appearance, with
+// trailing punctuation and unbalanced closing parentheses trimmed.
+func extractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range urlRegex.FindAllString(text, -1) {
+		for {
+			trimmed := strings.TrimRight(u, ".,;:!?*_~'")
+			if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+				trimmed = trimmed[:len(trimmed)-1]
+			}
+			if trimmed == u {
+				break
+			}
+			u = trimmed
+		}
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// parseLink parses a URL found in text, reading a bare www. host as https,
+// and returns its lowercased host or why it is malformed.
+func parseLink(raw string) (u *url.URL, host string, problem string) {
+	full := raw
+	if !strings.Contains(strings.ToLower(raw), "://") {
+		full = "https://" + raw
+	}
+	u, err := url.Parse(full)
+	if err != nil {
+		return nil, "", "not a valid URL"
+	}
+	host = strings.ToLower(u.Hostname())
+	if host == "" {
+		return nil, "", "no host"
+	}
+	if net.ParseIP(host) != nil {
+		return u, host, ""
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(labels) < 2 {
+		return nil, "", fmt.Sprintf("host %q has no top-level domain", host)
+	}
+	for _, l := range labels {
+		if !validHostLabel(l) {
+			return nil, "", fmt.Sprintf("host %q is not a valid domain name", host)
+		}
+	}
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 || (!strings.HasPrefix(tld, "xn--") && strings.IndexFunc(tld, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0) {
+		return nil, "", fmt.Sprintf("host %q has an invalid top-level domain", host)
+	}
+	return u, host, ""
+}
+
+// validHostLabel reports whether l is a DNS label: 1 to 63 letters, digits,
+// and inner hyphens.
+func validHostLabel(l string) bool {
+	if l == "" || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+		return false
+	}
+	for _, r := range l {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// domainAllowed reports whether host is one of the domains or a subdomain
+// of one.
+func domainAllowed(host string, domains []string) bool {
+	host = strings.TrimSuffix(host, ".")
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(d, "*"), "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlsValidSpec is the spec of the urls_valid content check.
+type urlsValidSpec struct {
+	Target string `json:"target"`
+	// AllowedDomains restricts links to these domains and their subdomains.
+	AllowedDomains []string `json:"allowed_domains"`
+	// CheckLive sends a HEAD request per link when the engine allows it.
+	CheckLive bool `json:"check_live"`
+	// MinURLs is the number of links the target must contain.
+	MinURLs int  `json:"min_urls"`
+	Soft    bool `json:"soft"`
+}
+
+// evaluateURLsValid extracts the URLs in the target and fails on any that
+// is malformed, outside the allowed domains, or, with liveness checks,
+// missing.
+func (e *ContentEvaluator) evaluateURLsValid(trace *types.Trace, assertion *types.Assertion, start time.Time) *types.AssertionResult {
+	var spec urlsValidSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid urls_valid spec: %v", err))
+	}
+	text, err := ResolveTargetString(trace, spec.Target)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("target resolution failed: %v", err))
+	}
+
+	urls := extractURLs(text)
+	var bad, problems, notes []string
+	unchecked := 0
+	ctx := batchContext(trace)
+	for _, raw := range urls {
+		u, host, problem := parseLink(raw)
+		switch {
+		case problem != "":
+		case len(spec.AllowedDomains) > 0 && !domainAllowed(host, spec.AllowedDomains):
+			problem = fmt.Sprintf("domain %s is not allowed", host)
+		case spec.CheckLive && e.links != nil:
+			status, ok := e.links.check(ctx, u.String())
+			switch {
+			case !ok:
+				unchecked++
+			case status.dead:
+				problem = fmt.Sprintf("link is dead (%s)", status.detail)
+			case status.detail != "" && !strings.HasPrefix(status.detail, "2") && !strings.HasPrefix(status.detail, "3"):
+				notes = append(notes, fmt.Sprintf("%s: %s", raw, status.detail))
+			}
+		}
+		if problem != "" {
+			bad = append(bad, raw)
+			problems = append(problems, fmt.Sprintf("%s: %s", raw, problem))
+		}
+	}
+	if len(urls) < spec.MinURLs {
+		problems = append(problems, fmt.Sprintf("found %d URLs, want at least %d", len(urls), spec.MinURLs))
+	}
+
+	var extra []string
+	if spec.CheckLive && e.links == nil {
+		extra = append(extra, "liveness not checked: disabled by engine config")
+	}
+	if unchecked > 0 {
+		extra = append(extra, fmt.Sprintf("%d URLs not checked for liveness: batch limit of %d reached", unchecked, e.links.maxPerBatch))
+	}
+	if len(notes) > 0 {
+		extra = append(extra, "inconclusive: "+strings.Join(notes, "; "))
+	}
+	suffix := ""
+	if len(extra) > 0 {
+		suffix = " (" + strings.Join(extra, "; ") + ")"
+	}
+
+	if len(problems) == 0 {
+		return passResult(assertion, start, fmt.Sprintf("%s has %d valid URLs.%s", spec.Target, len(urls), suffix))
+	}
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       0.0,
+		Explanation: fmt.Sprintf("%s: %s%s", spec.Target, strings.Join(problems, "; "), suffix),
+		Excerpts:    newExcerpter(text, text, true).found(bad),
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
@@ -0,0 +1,180 @@
+package assertion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestExtractURLs(t *testing.T) {
+	text := "See https://docs.example.com/guide. Also (https://en.wikipedia.org/wiki/Go_(language)), " +
+		"[link](http://example.org/a?b=1), www.example.net! and https://docs.example.com/guide again."
+	want := []string{
+		"https://docs.example.com/guide",
+		"https://en.wikipedia.org/wiki/Go_(language)",
+		"http://example.org/a?b=1",
+		"www.example.net",
+	}
+	if got := extractURLs(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("extractURLs = %q, want %q", got, want)
+	}
+}
+
+func TestParseLink(t *testing.T) {
+	valid := []string{"https://example.com", "www.example.co.uk/path", "http://127.0.0.1:8080/x", "https://xn--bcher-kva.example/"}
+	for _, raw := range valid {
+		if _, _, problem := parseLink(raw); problem != "" {
+			t.Errorf("parseLink(%q): %s", raw, problem)
+		}
+	}
+	invalid := []string{"https://intranet/page", "https://exa_mple.com", "https://example.c0m", "https://-bad.com", "https://:80", "http://localhost/"}
+	for _, raw := range invalid {
+		if _, _, problem := parseLink(raw); problem == "" {
+			t.Errorf("parseLink(%q): want a problem", raw)
+		}
+	}
+}
+
+func TestDomainAllowed(t *testing.T) {
+	domains := []string{"example.com", "*.docs.org"}
+	for host, want := range map[string]bool{
+		"example.com":      true,
+		"api.example.com":  true,
+		"badexample.com":   false,
+		"a.docs.org":       true,
+		"docs.org":         true,
+		"example.com.evil": false,
+	} {
+		if got := domainAllowed(host, domains); got != want {
+			t.Errorf("domainAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestContentEvaluator_URLsValid(t *testing.T) {
+	evaluator := &ContentEvaluator{}
+	trace := &types.Trace{
+		TraceID: "trc_test",
+		Output:  json.RawMessage(`{"message":"Sources: https://docs.example.com/a and https://blog.other.io/b"}`),
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"syntax only", `{"target":"output.message","check":"urls_valid"}`, types.StatusPass},
+		{"allowlist", `{"target":"output.message","check":"urls_valid","allowed_domains":["example.com","other.io"]}`, types.StatusPass},
+		{"off-domain", `{"target":"output.message","check":"urls_valid","allowed_domains":["example.com"]}`, types.StatusHardFail},
+		{"off-domain soft", `{"target":"output.message","check":"urls_valid","allowed_domains":["example.com"],"soft":true}`, types.StatusSoftFail},
+		{"min urls", `{"target":"output.message","check":"urls_valid","min_urls":3}`, types.StatusHardFail},
+		{"liveness disabled", `{"target":"output.message","check":"urls_valid","check_live":true}`, types.StatusPass},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeContent, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
+func TestContentEvaluator_URLsValidLiveness(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/flaky"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	evaluator := &ContentEvaluator{links: newLinkChecker(LinkCheckConfig{Client: srv.Client(), MaxPerBatch: 2})}
+	eval := func(message string) *types.AssertionResult {
+		out, _ := json.Marshal(map[string]string{"message": message})
+		trace := &types.Trace{TraceID: "trc_test", Output: out}
+		spec := json.RawMessage(`{"target":"output.message","check":"urls_valid","check_live":true}`)
+		ctx := withLinkQuota(context.Background())
+		defer bindContext(trace, ctx)()
+		return evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeContent, Spec: spec})
+	}
+
+	if r := eval("see " + srv.URL + "/ok"); r.Status != types.StatusPass {
+		t.Errorf("live link: %s: %s", r.Status, r.Explanation)
+	}
+	if r := eval("see " + srv.URL + "/missing"); r.Status != types.StatusHardFail || !strings.Contains(r.Explanation, "404") {
+		t.Errorf("dead link: %s: %s", r.Status, r.Explanation)
+	}
+	if r := eval("see " + srv.URL + "/flaky"); r.Status != types.StatusPass || !strings.Contains(r.Explanation, "inconclusive") {
+		t.Errorf("server error should be inconclusive: %s: %s", r.Status, r.Explanation)
+	}
+
+	// Cached answers need no request; the batch quota bounds the rest.
+	requests.Store(0)
+	r := eval("see " + srv.URL + "/ok " + srv.URL + "/c " + srv.URL + "/d " + srv.URL + "/e")
+	if r.Status != types.StatusPass || !strings.Contains(r.Explanation, "1 URLs not checked") {
+		t.Errorf("quota: %s: %s", r.Status, r.Explanation)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	public := []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"}
+	for _, a := range public {
+		if !publicAddr(netip.MustParseAddr(a)) {
+			t.Errorf("publicAddr(%s) = false", a)
+		}
+	}
+	blocked := []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200",
+		"0.0.0.0", "0.1.2.3", "::1", "::", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1"}
+	for _, a := range blocked {
+		if publicAddr(netip.MustParseAddr(a)) {
+			t.Errorf("publicAddr(%s) = true", a)
+		}
+	}
+}
+
+func TestLinkChecker_RefusesNonPublicAddresses(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	c := newLinkChecker(LinkCheckConfig{})
+	status, ok := c.check(context.Background(), srv.URL+"/")
+	if !ok || status.dead || !strings.Contains(status.detail, "not a public address") {
+		t.Errorf("loopback link: %+v", status)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("requests = %d, want none", n)
+	}
+
+	for _, target := range []string{"http://169.254.169.254/latest/meta-data/", "file:///etc/passwd", "http://intranet/"} {
+		req := httptest.NewRequest(http.MethodHead, target, nil)
+		if err := checkLinkRedirect(req, []*http.Request{req}); err == nil {
+			t.Errorf("redirect to %s allowed", target)
+		}
+	}
+	req := httptest.NewRequest(http.MethodHead, "https://example.com/next", nil)
+	if err := checkLinkRedirect(req, []*http.Request{req}); err != nil {
+		t.Errorf("redirect to a public link: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package assertion
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a size-capped, concurrency-safe LRU keyed by string. Values are
+// shared between callers and must be safe for concurrent use.
+type lruCache[V any] struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // front = most recently used; values are *lruEntry[V]
+	entries map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key string
+	val V
+}
+
+func newLRUCache[V any](max int) *lruCache[V] {
+	return &lruCache[V]{
+		max:     max,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, max),
+	}
+}
+
+// get returns the value cached under key, marking it most recently used.
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*lruEntry[V]).val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// add caches val under key, evicting the least recently used entries beyond
+// the cap. If key is already present (a concurrent miss filled it first) the
+// existing value is kept and returned.
+func (c *lruCache[V]) add(key string, val V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*lruEntry[V]).val
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, val: val})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+	return val
+}
+
+// len returns the number of cached entries.
+func (c *lruCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// contains reports whether key is cached without changing its recency.
+func (c *lruCache[V]) contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key]
+	return ok
+}
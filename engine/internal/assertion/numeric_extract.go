@@ -0,0 +1,154 @@
+package assertion
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// numberRegex matches a number written in prose: an optional sign and
+// currency symbol, digits with optional thousands separators and decimals,
+// and an optional magnitude suffix (k, m, bn, thousand, million, ...) or
+// percent sign. Group 1 is the sign, 2 the digits, 3 the suffix. A sign
+// right after a letter or digit is a hyphen, as in "GPT-4" or "COVID-19";
+// extractNumber ignores it, since RE2 has no lookbehind to exclude it here.
+var numberRegex = regexp.MustCompile(`(?i)([-+−]?)\s?[$€£¥₹]?\s?(\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?|\.\d+)(?:\s?(thousand|million|billion|trillion|bn|mm|[kmbt])\b|\s?(%))?`)
+
+// magnitudes maps a lowercased number suffix to its multiplier.
+var magnitudes = map[string]float64{
+	"k": 1e3, "thousand": 1e3,
+	"m": 1e6, "mm": 1e6, "million": 1e6,
+	"b": 1e9, "bn": 1e9, "billion": 1e9,
+	"t": 1e12, "trillion": 1e12,
+}
+
+// extractedNumber is a number found in text, with the text it was read from.
+type extractedNumber struct {
+	value float64
+	text  string
+}
+
+// extractNumber returns the first number in text, or with label set, the
+// first number after the first case-insensitive occurrence of label.
+// Thousands separators are dropped and magnitude suffixes applied, so
+// "$1.2k" reads as 1200 and "3.5 million" as 3500000; "12%" reads as 12.
+func extractNumber(text, label string) (extractedNumber, error) {
+	if label != "" {
+		i := strings.Index(strings.ToLower(text), strings.ToLower(label))
+		if i < 0 {
+			return extractedNumber{}, fmt.Errorf("label %q not found", label)
+		}
+		text = text[i+len(label):]
+	}
+	m := numberRegex.FindStringSubmatchIndex(text)
+	if m == nil {
+		if label != "" {
+			return extractedNumber{}, fmt.Errorf("no number after label %q", label)
+		}
+		return extractedNumber{}, fmt.Errorf("no number found")
+	}
+	digits := strings.ReplaceAll(text[m[4]:m[5]], ",", "")
+	v, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return extractedNumber{}, fmt.Errorf("cannot parse %q: %v", text[m[0]:m[1]], err)
+	}
+	if m[6] >= 0 {
+		v *= magnitudes[strings.ToLower(text[m[6]:m[7]])]
+	}
+	begin := m[0]
+	if sign := text[m[2]:m[3]]; sign != "" {
+		if prev, _ := utf8.DecodeLastRuneInString(text[:m[2]]); unicode.IsLetter(prev) || unicode.IsDigit(prev) {
+			begin = m[3]
+		} else if sign == "-" || sign == "−" {
+			v = -v
+		}
+	}
+	return extractedNumber{value: v, text: strings.TrimSpace(text[begin:m[1]])}, nil
+}
+
+// numericMatchSpec is the spec of the numeric_match content check.
+type numericMatchSpec struct {
+	Target string `json:"target"`
+	Label  string `json:"label"`
+	// Expected is the value to compare with; ExpectedField names a
+	// constraint field (such as metadata.cost_usd or
+	// steps[?name=='calc'].result.total) to read it from instead.
+	Expected      *float64 `json:"expected"`
+	ExpectedField string   `json:"expected_field"`
+	// Tolerance is the allowed absolute difference, RelativeTolerance the
+	// allowed difference as a fraction of the expected value. Either
+	// suffices; with neither the values must be equal.
+	Tolerance         float64 `json:"tolerance"`
+	RelativeTolerance float64 `json:"relative_tolerance"`
+	Soft              bool    `json:"soft"`
+}
+
+// evaluateNumericMatch extracts a number from the target text and compares
+// it with the expected value within tolerance.
+func evaluateNumericMatch(trace *types.Trace, assertion *types.Assertion, start time.Time) *types.AssertionResult {
+	var spec numericMatchSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid numeric_match spec: %v", err))
+	}
+	if (spec.Expected == nil) == (spec.ExpectedField == "") {
+		return failResult(assertion, start, "numeric_match requires exactly one of 'expected' or 'expected_field'")
+	}
+	if spec.Tolerance < 0 || spec.RelativeTolerance < 0 {
+		return failResult(assertion, start, "numeric_match tolerances must not be negative")
+	}
+
+	var want float64
+	wantFrom := "expected"
+	if spec.Expected != nil {
+		want = *spec.Expected
+	} else {
+		v, err := resolveConstraintField(trace, spec.ExpectedField)
+		if err != nil {
+			return failResult(assertion, start, fmt.Sprintf("cannot resolve expected_field: %v", err))
+		}
+		want, wantFrom = v, spec.ExpectedField
+	}
+
+	text, err := ResolveTargetString(trace, spec.Target)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("target resolution failed: %v", err))
+	}
+	got, err := extractNumber(text, spec.Label)
+	if err != nil {
+		ar := failResult(assertion, start, fmt.Sprintf("%s: %v", spec.Target, err))
+		if spec.Soft {
+			ar.Status = types.StatusSoftFail
+		}
+		return ar
+	}
+
+	diff := math.Abs(got.value - want)
+	allowed := math.Max(spec.Tolerance, spec.RelativeTolerance*math.Abs(want))
+	// Absorb float rounding, e.g. from "1.1k" * 1000.
+	if diff <= allowed+1e-9*math.Max(1, math.Abs(want)) {
+		return passResult(assertion, start, fmt.Sprintf("%s has %s (%g), within %g of %s %g.",
+			spec.Target, got.text, got.value, allowed, wantFrom, want))
+	}
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       0.0,
+		Explanation: fmt.Sprintf("%s has %s (%g), off by %g from %s %g; allowed %g",
+			spec.Target, got.text, got.value, diff, wantFrom, want, allowed),
+		DurationMS: time.Since(start).Milliseconds(),
+		RequestID:  assertion.RequestID,
+	}
+}
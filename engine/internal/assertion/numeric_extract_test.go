@@ -0,0 +1,83 @@
+package assertion
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestExtractNumber(t *testing.T) {
+	tests := []struct {
+		text  string
+		label string
+		want  float64
+	}{
+		{"Revenue was $1.2k last week.", "", 1200},
+		{"We expect 3.5 million users", "", 3.5e6},
+		{"Total: 1,234,567.89 USD", "", 1234567.89},
+		{"a loss of -42", "", -42},
+		{"margin 12.5%", "", 12.5},
+		{"€2bn raised", "", 2e9},
+		{"Q1: 10, Q2: 20, Total: $45M", "total", 45e6},
+		{"It took 3 months", "", 3},
+		{".5 liters", "", 0.5},
+		{"GPT-4 costs $20", "", 4},
+		{"GPT-4 costs $20", "costs", 20},
+		{"COVID-19 cases rose", "", 19},
+		{"pages 5-10", "", 5},
+		{"a change of (-3%)", "", -3},
+	}
+	for _, tt := range tests {
+		got, err := extractNumber(tt.text, tt.label)
+		if err != nil {
+			t.Errorf("extractNumber(%q, %q): %v", tt.text, tt.label, err)
+			continue
+		}
+		if math.Abs(got.value-tt.want) > 1e-6*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("extractNumber(%q, %q) = %g (%q), want %g", tt.text, tt.label, got.value, got.text, tt.want)
+		}
+	}
+
+	if _, err := extractNumber("no digits here", ""); err == nil {
+		t.Error("want error without a number")
+	}
+	if _, err := extractNumber("Total: 5", "subtotal"); err == nil {
+		t.Error("want error for a missing label")
+	}
+}
+
+func TestContentEvaluator_NumericMatch(t *testing.T) {
+	evaluator := &ContentEvaluator{}
+	cost := 0.42
+	trace := &types.Trace{
+		TraceID:  "trc_test",
+		Output:   json.RawMessage(`{"message":"Subtotal: $1,180. Total revenue: $1.2k. Cost: $0.42"}`),
+		Metadata: &types.TraceMetadata{CostUSD: &cost},
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"first number exact", `{"target":"output.message","check":"numeric_match","expected":1180}`, types.StatusPass},
+		{"labeled within absolute tolerance", `{"target":"output.message","check":"numeric_match","label":"total revenue:","expected":1210,"tolerance":10}`, types.StatusPass},
+		{"labeled within relative tolerance", `{"target":"output.message","check":"numeric_match","label":"total revenue","expected":1250,"relative_tolerance":0.05}`, types.StatusPass},
+		{"outside tolerance", `{"target":"output.message","check":"numeric_match","label":"total revenue","expected":1300,"relative_tolerance":0.05}`, types.StatusHardFail},
+		{"soft outside tolerance", `{"target":"output.message","check":"numeric_match","expected":1000,"soft":true}`, types.StatusSoftFail},
+		{"expected from trace field", `{"target":"output.message","check":"numeric_match","label":"cost","expected_field":"metadata.cost_usd"}`, types.StatusPass},
+		{"missing label", `{"target":"output.message","check":"numeric_match","label":"tax","expected":1}`, types.StatusHardFail},
+		{"needs expected", `{"target":"output.message","check":"numeric_match"}`, types.StatusHardFail},
+		{"negative tolerance", `{"target":"output.message","check":"numeric_match","expected":1,"tolerance":-1}`, types.StatusHardFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeContent, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
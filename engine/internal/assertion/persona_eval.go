@@ -0,0 +1,203 @@
+package assertion
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// personaRubric is the built-in rubric persona_consistency judges with.
+const personaRubric = "persona_consistency"
+
+// defaultPersonaThreshold is the per-turn score below which a user turn
+// counts as out of character.
+const defaultPersonaThreshold = 0.7
+
+// PersonaConsistencyEvaluator implements a Layer 6 transcript check: each
+// user turn of the trace's transcript is judged against a persona, and the
+// assertion passes when every turn scores at least the threshold. Turns are
+// judged concurrently and cached individually.
+type PersonaConsistencyEvaluator struct {
+	provider llm.Provider
+	rubrics  *judge.RubricRegistry
+	cache    *cache.JudgeCache
+}
+
+// NewPersonaConsistencyEvaluator creates an evaluator using the given LLM
+// provider, rubric registry, and optional cache.
+func NewPersonaConsistencyEvaluator(provider llm.Provider, rubrics *judge.RubricRegistry, c *cache.JudgeCache) *PersonaConsistencyEvaluator {
+	return &PersonaConsistencyEvaluator{provider: provider, rubrics: rubrics, cache: c}
+}
+
+type personaSpec struct {
+	Persona   string  `json:"persona"`
+	Threshold float64 `json:"threshold"`
+	Soft      bool    `json:"soft"`
+	Model     string  `json:"model"`
+}
+
+type personaTurn struct {
+	turn        int
+	score       float64
+	explanation string
+	cost        float64
+	err         error
+}
+
+func (e *PersonaConsistencyEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	return e.evaluate(trace, assertion, nil)
+}
+
+// EvaluateWithSeed judges with seeded sampling so reruns are reproducible.
+func (e *PersonaConsistencyEvaluator) EvaluateWithSeed(trace *types.Trace, assertion *types.Assertion, seed int64) *types.AssertionResult {
+	return e.evaluate(trace, assertion, &seed)
+}
+
+func (e *PersonaConsistencyEvaluator) evaluate(trace *types.Trace, assertion *types.Assertion, seed *int64) *types.AssertionResult {
+	start := time.Now()
+
+	var spec personaSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid persona_consistency spec: %v", err))
+	}
+	if spec.Persona == "" {
+		return failResult(assertion, start, "persona_consistency spec missing required field: persona")
+	}
+	if spec.Threshold <= 0 {
+		spec.Threshold = defaultPersonaThreshold
+	}
+	if len(trace.Transcript) == 0 {
+		return failResult(assertion, start, "trace has no transcript; record the conversation in the trace's transcript field")
+	}
+	rubric, err := e.rubrics.Get(personaRubric)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("rubric not found: %v", err))
+	}
+	model := spec.Model
+	if model == "" {
+		model = e.provider.DefaultModel()
+	}
+
+	batchCtx := batchContext(trace)
+	ctx, cancel := context.WithTimeout(batchCtx, time.Duration(judgeTimeoutSeconds())*time.Second)
+	defer cancel()
+
+	var results []*personaTurn
+	var wg sync.WaitGroup
+	var history strings.Builder
+	turn := 0
+	for _, m := range trace.Transcript {
+		if m.Role == types.RoleUser {
+			turn++
+			r := &personaTurn{turn: turn}
+			results = append(results, r)
+			content := fmt.Sprintf("Persona:\n%s\n\nConversation so far:\n%s\nUser message under review (turn %d):\n%s", spec.Persona, history.String(), turn, m.Content)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// The pipeline's recover does not reach this goroutine.
+				defer func() {
+					if p := recover(); p != nil {
+						r.err = fmt.Errorf("panic: %v", p)
+					}
+				}()
+				e.judgeTurn(ctx, rubric, model, content, seed, r)
+			}()
+		}
+		fmt.Fprintf(&history, "%s: %s\n", m.Role, m.Content)
+	}
+	wg.Wait()
+	if len(results) == 0 {
+		return failResult(assertion, start, "transcript has no user turns to judge")
+	}
+
+	var cost float64
+	var worst *personaTurn
+	var failing []string
+	for _, r := range results {
+		if r.err != nil {
+			return failResult(assertion, start, fmt.Sprintf("turn %d: %v", r.turn, r.err))
+		}
+		cost += r.cost
+		if worst == nil || r.score < worst.score {
+			worst = r
+		}
+		if r.score < spec.Threshold {
+			failing = append(failing, fmt.Sprintf("%d", r.turn))
+		}
+	}
+
+	status := types.StatusPass
+	explanation := fmt.Sprintf("all %d user turns consistent with persona; lowest turn %d scored %.2f: %s", len(results), worst.turn, worst.score, worst.explanation)
+	if len(failing) > 0 {
+		status = types.StatusHardFail
+		if spec.Soft {
+			status = types.StatusSoftFail
+		}
+		explanation = fmt.Sprintf("user turns %s below threshold %.2f; turn %d scored %.2f: %s", strings.Join(failing, ", "), spec.Threshold, worst.turn, worst.score, worst.explanation)
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       worst.score,
+		Explanation: explanation,
+		Cost:        cost,
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+// judgeTurn scores one user turn, consulting the judge cache first.
+func (e *PersonaConsistencyEvaluator) judgeTurn(ctx context.Context, rubric *judge.Rubric, model, content string, seed *int64, r *personaTurn) {
+	rec := timing.FromContext(ctx)
+	contentHash := cache.JudgeContentHash(content)
+	if e.cache != nil {
+		cacheStart := time.Now()
+		cached, err := e.cache.Get(contentHash, rubric.CacheKey(), model)
+		rec.Cache(time.Since(cacheStart))
+		rec.CacheLookup("judge", err == nil && cached != nil)
+		if err == nil && cached != nil {
+			r.score, r.explanation = cached.Score, cached.Explanation
+			return
+		}
+	}
+
+	resp, err := e.provider.Complete(ctx, &llm.CompletionRequest{
+		Model:        model,
+		SystemPrompt: rubric.Prompt(),
+		Messages:     []llm.Message{{Role: "user", Content: judge.WrapAgentOutput(content)}},
+		Temperature:  0.0,
+		MaxTokens:    256,
+		Seed:         seed,
+	})
+	if err != nil {
+		r.err = fmt.Errorf("LLM call failed: %w", err)
+		return
+	}
+	sr, err := judge.ParseScoreResult(resp.Content)
+	if err != nil {
+		r.err = fmt.Errorf("parse judge response: %w", err)
+		return
+	}
+	r.score, r.explanation, r.cost = sr.Score, sr.Explanation, resp.Cost
+
+	if e.cache != nil {
+		cacheStart := time.Now()
+		putErr := e.cache.Put(contentHash, rubric.CacheKey(), model, &cache.JudgeCacheEntry{Score: sr.Score, Explanation: sr.Explanation})
+		rec.Cache(time.Since(cacheStart))
+		if putErr != nil {
+			logging.FromContext(ctx).Error("judge cache write error", "rubric", personaRubric, "err", putErr)
+		}
+	}
+}
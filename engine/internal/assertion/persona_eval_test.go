@@ -0,0 +1,63 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestPersonaConsistencyEvaluator(t *testing.T) {
+	mock := llm.NewMockProvider(nil, nil)
+	mock.MatchFunc = func(req *llm.CompletionRequest) *llm.CompletionResponse {
+		content := req.Messages[0].Content
+		if strings.Contains(content, "(turn 2):\nAs an AI language model") {
+			return &llm.CompletionResponse{Content: `{"score": 0.2, "explanation": "breaks character"}`, Cost: 0.001}
+		}
+		return &llm.CompletionResponse{Content: `{"score": 0.9, "explanation": "in character"}`, Cost: 0.001}
+	}
+	eval := NewPersonaConsistencyEvaluator(mock, judge.NewRubricRegistry(), nil)
+
+	tr := transcriptTrace("My order never arrived!", "Sorry, which order?", "As an AI language model I cannot say.", "OK.")
+	a := &types.Assertion{AssertionID: "persona", Type: types.TypePersonaConsistency, Spec: json.RawMessage(`{"persona":"An angry customer whose order is late."}`)}
+	result := eval.Evaluate(tr, a)
+	if result.Status != types.StatusHardFail || result.Score != 0.2 || !strings.Contains(result.Explanation, "user turns 2 below threshold") {
+		t.Errorf("got %s %.2f %q", result.Status, result.Score, result.Explanation)
+	}
+	if result.Cost != 0.002 {
+		t.Errorf("cost = %v, want 0.002", result.Cost)
+	}
+
+	reqs := mock.GetRequestHistory()
+	if len(reqs) != 2 {
+		t.Fatalf("judge calls = %d, want one per user turn", len(reqs))
+	}
+	for _, req := range reqs {
+		if !strings.Contains(req.Messages[0].Content, "<<<AGENT_OUTPUT_START>>>") || !strings.Contains(req.Messages[0].Content, "An angry customer") {
+			t.Errorf("judge request not wrapped with persona: %q", req.Messages[0].Content)
+		}
+		if strings.Contains(req.Messages[0].Content, "(turn 2)") && !strings.Contains(req.Messages[0].Content, "assistant: Sorry, which order?") {
+			t.Errorf("turn 2 request lacks the preceding conversation: %q", req.Messages[0].Content)
+		}
+	}
+
+	tr = transcriptTrace("My order never arrived!", "Sorry, which order?")
+	if result := eval.Evaluate(tr, a); result.Status != types.StatusPass || result.Score != 0.9 {
+		t.Errorf("consistent transcript: got %s %.2f %q", result.Status, result.Score, result.Explanation)
+	}
+
+	for spec, want := range map[string]string{
+		`{}`: "missing required field: persona",
+	} {
+		a := &types.Assertion{AssertionID: "p", Type: types.TypePersonaConsistency, Spec: json.RawMessage(spec)}
+		if result := eval.Evaluate(tr, a); !strings.Contains(result.Explanation, want) {
+			t.Errorf("%s: explanation = %q, want %q", spec, result.Explanation, want)
+		}
+	}
+	if result := eval.Evaluate(&types.Trace{TraceID: "x"}, a); !strings.Contains(result.Explanation, "no transcript") {
+		t.Errorf("no transcript: explanation = %q", result.Explanation)
+	}
+}
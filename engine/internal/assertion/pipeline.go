@@ -1,11 +1,17 @@
 package assertion
 
 import (
+	"context"
 	"github.com/segmentio/encoding/json"
 	"fmt"
+	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/deprecation"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
@@ -31,14 +37,32 @@ var layerOrder = map[string]int{
 	types.TypeConstraint: 2,
 	types.TypeTrace:      3,
 	types.TypeTraceTree:  3,
+	types.TypeTemporal:   3,
+	types.TypeTranscript: 3,
 	types.TypeContent:    4,
+	types.TypeExpression: 4,
+	types.TypeComposite:  4,
 	types.TypeEmbedding:  5,
 	types.TypeLLMJudge:   6,
+
+	types.TypeReferenceMatch:     4,
+	types.TypeConsistency:        4,
+	types.TypeCodeValid:          4,
+	types.TypeSQLSafe:            4,
+	types.TypePersonaConsistency: 6,
+}
+
+// Layer returns the layer a is evaluated in. A batch's results list its L1-4
+// assertions, in order, before its L5-6 ones.
+func Layer(a *types.Assertion) int {
+	return assertionLayer(a)
 }
 
 // EvaluateBatch evaluates all assertions against the trace in layer order.
 // L1-4 (schema, constraint, trace, content) run sequentially. L5-6 (embedding, llm_judge)
 // run concurrently after L1-4 completes. If any L1-4 assertion produces a hard_fail, L5-6 are skipped.
+// Shadow assertions always pass, so they neither gate L5-6 nor count against the budget.
+// An L5-6 assertion whose sample_rate leaves out the trace yields a skipped result.
 // Unknown assertion types produce a hard_fail result rather than aborting the batch.
 // If a BudgetTracker is set on the pipeline, soft-fail budget enforcement is applied.
 func (p *Pipeline) EvaluateBatch(trace *types.Trace, assertions []types.Assertion) (*BatchResult, error) {
@@ -49,20 +73,66 @@ func (p *Pipeline) EvaluateBatch(trace *types.Trace, assertions []types.Assertio
 // If the soft-fail budget is exceeded, the batch stops and returns a BudgetExceededError.
 // L1-4 assertions run sequentially; L5-6 fan out concurrently. Any L1-4 hard_fail gates L5-6.
 func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []types.Assertion, budget *BudgetTracker) (*BatchResult, error) {
-	sorted := make([]types.Assertion, len(assertions))
+	return p.EvaluateBatchWithOptions(trace, assertions, BatchOptions{Budget: budget})
+}
+
+// BatchOptions holds optional per-batch evaluation settings.
+type BatchOptions struct {
+	// Budget enforces the soft-fail budget when non-nil.
+	Budget *BudgetTracker
+	// Seed makes stochastic evaluators (SeededEvaluator) reproducible when non-nil.
+	Seed *int64
+	// Context carries the request ID to evaluator logs and provider calls and
+	// cancels in-flight provider calls. Defaults to context.Background().
+	Context context.Context
+}
+
+// EvaluateBatchWithOptions evaluates all assertions with the given per-batch options.
+// Ordering, gating, and budget semantics match EvaluateBatchWithBudget.
+func (p *Pipeline) EvaluateBatchWithOptions(trace *types.Trace, assertions []types.Assertion, opts BatchOptions) (*BatchResult, error) {
+	// Evaluators share decoded output and step payloads for this batch.
+	defer shareTrace(trace)()
+	batchStart := time.Now()
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec := timing.NewRecorder()
+	ctx = timing.WithRecorder(ctx, rec)
+	ctx = withLinkQuota(ctx)
+	defer bindContext(trace, ctx)()
+	log := logging.FromContext(ctx)
+	log.Debug("batch started", "trace_id", trace.TraceID, "assertions", len(assertions))
+
+	budget := opts.Budget
+	sortedBuf := assertionScratch.get(len(assertions))
+	defer assertionScratch.put(sortedBuf)
+	sorted := *sortedBuf
 	copy(sorted, assertions)
+	for i := range sorted {
+		for _, d := range resolveAliases(&sorted[i]) {
+			deprecation.Report(ctx, d)
+		}
+	}
+	layersBuf := layerScratch.get(len(sorted))
+	defer layerScratch.put(layersBuf)
+	layers := *layersBuf
+	for i := range sorted {
+		layers[i] = assertionLayer(&sorted[i])
+	}
 
 	// Insertion sort — batch sizes are small and this avoids an import of sort.
 	for i := 1; i < len(sorted); i++ {
-		for j := i; j > 0 && layerOrder[sorted[j].Type] < layerOrder[sorted[j-1].Type]; j-- {
+		for j := i; j > 0 && layers[j] < layers[j-1]; j-- {
 			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+			layers[j], layers[j-1] = layers[j-1], layers[j]
 		}
 	}
 
 	// Partition at L4/L5 boundary.
 	splitIdx := len(sorted)
-	for i, a := range sorted {
-		if layerOrder[a.Type] >= 5 {
+	for i := range sorted {
+		if layers[i] >= 5 {
 			splitIdx = i
 			break
 		}
@@ -72,6 +142,11 @@ func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []type
 	result := &BatchResult{
 		Results: make([]types.AssertionResult, 0, len(sorted)),
 	}
+	defer func() {
+		result.Timings = rec.Summary(time.Since(batchStart))
+		result.Summary = summarize(sorted, layers, result.Results, rec.CacheLookups())
+		log.Debug("batch completed", "trace_id", trace.TraceID, "results", len(result.Results), "duration_ms", result.Timings.TotalMS)
+	}()
 
 	// Phase 1: Evaluate L1-4 sequentially.
 	hardFail := false
@@ -95,8 +170,12 @@ func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []type
 			continue
 		}
 
-		ar := eval.Evaluate(trace, &l14[i])
+		evalStart := time.Now()
+		ar := evaluateOne(eval, trace, &l14[i], opts.Seed)
 		p.applyDynamicThreshold(ar, &l14[i])
+		p.applyQuarantine(ctx, ar)
+		applyShadow(ar, &l14[i])
+		rec.Evaluation(layers[i], l14[i].Type, time.Since(evalStart))
 		result.Results = append(result.Results, *ar)
 		result.TotalCost += ar.Cost
 		result.TotalDurationMS += ar.DurationMS
@@ -118,15 +197,19 @@ func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []type
 	}
 
 	// Phase 2: Evaluate L5-6 concurrently.
-	l56Results := make([]types.AssertionResult, len(l56))
-	l56Costs := make([]float64, len(l56))
-	l56Durations := make([]int64, len(l56))
+	l56Buf := resultScratch.get(len(l56))
+	defer resultScratch.put(l56Buf)
+	l56Results := *l56Buf
 	var wg sync.WaitGroup
 
 	for i := range l56 {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
+			if sampledOut(trace.TraceID, &l56[idx]) {
+				l56Results[idx] = sampledOutResult(&l56[idx])
+				return
+			}
 			eval, err := p.registry.Get(l56[idx].Type)
 			if err != nil {
 				l56Results[idx] = types.AssertionResult{
@@ -138,11 +221,13 @@ func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []type
 				}
 				return
 			}
-			ar := eval.Evaluate(trace, &l56[idx])
+			evalStart := time.Now()
+			ar := evaluateOne(eval, trace, &l56[idx], opts.Seed)
 			p.applyDynamicThreshold(ar, &l56[idx])
+			p.applyQuarantine(ctx, ar)
+			applyShadow(ar, &l56[idx])
+			rec.Evaluation(layers[splitIdx+idx], l56[idx].Type, time.Since(evalStart))
 			l56Results[idx] = *ar
-			l56Costs[idx] = ar.Cost
-			l56Durations[idx] = ar.DurationMS
 		}(i)
 	}
 
@@ -151,8 +236,8 @@ func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []type
 	// Merge L5-6 results in deterministic index order.
 	for i := range l56Results {
 		result.Results = append(result.Results, l56Results[i])
-		result.TotalCost += l56Costs[i]
-		result.TotalDurationMS += l56Durations[i]
+		result.TotalCost += l56Results[i].Cost
+		result.TotalDurationMS += l56Results[i].DurationMS
 
 		if budget != nil {
 			if budgetErr := budget.Record(&l56Results[i]); budgetErr != nil {
@@ -164,26 +249,117 @@ func (p *Pipeline) EvaluateBatchWithBudget(trace *types.Trace, assertions []type
 	return result, nil
 }
 
+// evaluateOne runs a single evaluator, routing the batch seed to SeededEvaluator implementations.
+// A panicking evaluator hard-fails its own assertion; the rest of the batch,
+// which may run on other goroutines, is unaffected.
+func evaluateOne(eval Evaluator, trace *types.Trace, a *types.Assertion, seed *int64) (ar *types.AssertionResult) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(batchContext(trace)).Error("evaluator panicked",
+				"assertion_id", a.AssertionID, "type", a.Type, "panic", r, "stack", string(debug.Stack()))
+			ar = failResult(a, start, fmt.Sprintf("internal error: %s evaluator panicked: %v", a.Type, r))
+		}
+	}()
+	if seed != nil {
+		if se, ok := eval.(SeededEvaluator); ok {
+			return se.EvaluateWithSeed(trace, a, *seed)
+		}
+	}
+	return eval.Evaluate(trace, a)
+}
+
+// nativeDynamicTypes evaluate "threshold":"dynamic" against recorded numeric
+// values themselves, so score-based reclassification must not be applied.
+var nativeDynamicTypes = map[string]bool{
+	types.TypeConstraint: true,
+	types.TypeTraceTree:  true,
+}
+
 // applyDynamicThreshold checks if the assertion spec contains "threshold":"dynamic"
 // and if so, overrides the result status using ClassifyDynamic against stored history.
+// The spec may select the strategy ("sigma" or "percentile") and tune "sigma" or "percentile".
 // No-ops when the historyStore is nil or the spec does not request dynamic classification.
 func (p *Pipeline) applyDynamicThreshold(ar *types.AssertionResult, a *types.Assertion) {
-	if p.historyStore == nil {
+	if p.historyStore == nil || nativeDynamicTypes[a.Type] {
 		return
 	}
 
 	var spec struct {
-		Threshold string `json:"threshold"`
+		Threshold  string  `json:"threshold"`
+		Strategy   string  `json:"strategy"`
+		Sigma      float64 `json:"sigma"`
+		Percentile float64 `json:"percentile"`
 	}
 	if err := json.Unmarshal(a.Spec, &spec); err != nil || spec.Threshold != "dynamic" {
 		return
 	}
 
-	history, err := p.historyStore.QueryWindow(a.AssertionID, DefaultDynamicConfig.WindowSize)
+	cfg := DefaultDynamicConfig
+	switch spec.Strategy {
+	case DynamicStrategySigma, DynamicStrategyPercentile:
+		cfg.Strategy = spec.Strategy
+	case "":
+	default:
+		// Unknown strategy: leave status unchanged rather than guess.
+		return
+	}
+	if spec.Sigma > 0 {
+		cfg.SigmaScale = spec.Sigma
+	}
+	if spec.Percentile > 0 && spec.Percentile < 100 {
+		cfg.Percentile = spec.Percentile
+	}
+
+	history, err := p.historyStore.QueryWindow(a.AssertionID, cfg.WindowSize)
 	if err != nil {
 		// Non-fatal: leave status unchanged.
 		return
 	}
 
-	ar.Status = ClassifyDynamic(ar.Score, history, DefaultDynamicConfig)
+	ar.Status = ClassifyDynamic(ar.Score, history, cfg)
+}
+
+// quarantineMarker prefixes the explanation of a quarantined hard_fail that was downgraded.
+const quarantineMarker = "[quarantined] "
+
+// applyQuarantine downgrades hard_fail results of quarantined assertions to
+// soft_fail and marks them. A quarantined assertion is released once it has
+// passed DefaultFlakinessConfig.StableRuns times in a row, counting this result.
+// No-ops when the historyStore is nil or the assertion is not quarantined.
+func (p *Pipeline) applyQuarantine(ctx context.Context, ar *types.AssertionResult) {
+	if p.historyStore == nil {
+		return
+	}
+	quarantined, err := p.historyStore.IsQuarantined(ar.AssertionID)
+	if err != nil || !quarantined {
+		return
+	}
+
+	cfg := DefaultFlakinessConfig
+	history, err := p.historyStore.QueryStatusWindow(ar.AssertionID, cfg.StableRuns)
+	if err == nil && IsStable(ar.Status, history, cfg) {
+		if err := p.historyStore.Unquarantine(ar.AssertionID); err != nil {
+			logging.FromContext(ctx).Error("quarantine release error", "assertion_id", ar.AssertionID, "err", err)
+		} else {
+			logging.FromContext(ctx).Info("assertion released from quarantine", "assertion_id", ar.AssertionID)
+			return
+		}
+	}
+
+	ar.Quarantined = true
+	if ar.Status == types.StatusHardFail {
+		ar.Status = types.StatusSoftFail
+		ar.Explanation = quarantineMarker + ar.Explanation
+	}
+}
+
+// applyShadow reports a shadow assertion's result as pass, keeping the real
+// status in ShadowStatus so it can be recorded and inspected.
+func applyShadow(ar *types.AssertionResult, a *types.Assertion) {
+	if !a.Shadow {
+		return
+	}
+	ar.ShadowStatus = ar.Status
+	ar.Status = types.StatusPass
 }
@@ -0,0 +1,81 @@
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// benchTrace returns a trace with n tool steps cycling through a few names.
+func benchTrace(n int) *types.Trace {
+	names := []string{"search", "lookup_order", "reason", "respond"}
+	steps := make([]types.Step, n)
+	for i := range steps {
+		steps[i] = types.Step{
+			Type:   types.StepTypeToolCall,
+			Name:   names[i%len(names)],
+			Args:   json.RawMessage(`{"query":"q"}`),
+			Result: json.RawMessage(`{"hits":3,"cost":0.01}`),
+		}
+	}
+	return &types.Trace{
+		TraceID: "trc_bench",
+		Output:  json.RawMessage(`{"message":"The order ORD-123 was refunded.","structured":{"score":0.9,"status":"ok"}}`),
+		Steps:   steps,
+	}
+}
+
+// benchSuite returns n L1-4 assertions spread across trace, content, and constraint checks.
+func benchSuite(n int) []types.Assertion {
+	specs := []struct {
+		typ  string
+		spec string
+	}{
+		{types.TypeTrace, `{"check":"required_tools","tools":["search","respond"]}`},
+		{types.TypeTrace, `{"check":"forbidden_tools","tools":["delete_account"]}`},
+		{types.TypeTrace, `{"check":"contains_in_order","tools":["search","respond"]}`},
+		{types.TypeTrace, `{"check":"loop_detection","tool":"search","max_repetitions":100000}`},
+		{types.TypeContent, `{"target":"output.message","check":"contains","value":"refunded"}`},
+		{types.TypeConstraint, `{"field":"steps.length","operator":"lte","value":100000}`},
+	}
+	suite := make([]types.Assertion, n)
+	for i := range suite {
+		s := specs[i%len(specs)]
+		suite[i] = types.Assertion{
+			AssertionID: fmt.Sprintf("a_%d", i),
+			Type:        s.typ,
+			Spec:        json.RawMessage(s.spec),
+		}
+	}
+	return suite
+}
+
+func BenchmarkEvaluateBatch(b *testing.B) {
+	for _, size := range []struct{ steps, assertions int }{{50, 20}, {1000, 200}} {
+		b.Run(fmt.Sprintf("steps=%d/assertions=%d", size.steps, size.assertions), func(b *testing.B) {
+			pipeline := NewPipeline(NewRegistry())
+			trace := benchTrace(size.steps)
+			suite := benchSuite(size.assertions)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := pipeline.EvaluateBatch(trace, suite); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTraceEvaluator(b *testing.B) {
+	eval := &TraceEvaluator{}
+	trace := benchTrace(1000)
+	a := &types.Assertion{AssertionID: "t", Type: types.TypeTrace, Spec: json.RawMessage(`{"check":"no_duplicates"}`)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		eval.Evaluate(trace, a)
+	}
+}
@@ -440,3 +440,48 @@ func TestPipeline_Integration_MultipleConcurrentL56(t *testing.T) {
 		t.Errorf("judge calls = %d, want >= 2", mockProvider.GetCallCount())
 	}
 }
+
+func TestPipeline_Integration_Timings(t *testing.T) {
+	mockProvider := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: `{"score": 0.9, "explanation": "Good."}`, Model: "mock-model"},
+	}, nil)
+	limited, err := llm.NewRateLimitedProvider(mockProvider, llm.DefaultRateLimiterConfig)
+	if err != nil {
+		t.Fatalf("NewRateLimitedProvider: %v", err)
+	}
+	pipeline := NewPipeline(NewRegistry(WithJudge(limited, judge.NewRubricRegistry(), nil)))
+
+	assertions := []types.Assertion{
+		{
+			AssertionID: "content-1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output","check":"contains","value":"climate"}`),
+		},
+		{
+			AssertionID: "judge-1",
+			Type:        types.TypeLLMJudge,
+			Spec:        json.RawMessage(`{"target":"output","criteria":"Is it helpful?"}`),
+		},
+	}
+
+	result, err := pipeline.EvaluateBatch(testTrace(), assertions)
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	tm := result.Timings
+	if tm == nil {
+		t.Fatal("Timings is nil")
+	}
+	if _, ok := tm.Layers["4"]; !ok {
+		t.Errorf("Layers = %v, want an entry for layer 4", tm.Layers)
+	}
+	if _, ok := tm.Evaluators[types.TypeLLMJudge]; !ok {
+		t.Errorf("Evaluators = %v, want an entry for llm_judge", tm.Evaluators)
+	}
+	if got := tm.Providers["mock"].Calls; got != 1 {
+		t.Errorf("Providers[mock].Calls = %d, want 1", got)
+	}
+	if tm.TotalMS < tm.Providers["mock"].TotalMS {
+		t.Errorf("TotalMS %.3f < provider time %.3f", tm.TotalMS, tm.Providers["mock"].TotalMS)
+	}
+}
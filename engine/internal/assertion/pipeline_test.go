@@ -178,3 +178,120 @@ func TestPipeline_EvaluateBatch_Empty(t *testing.T) {
 		t.Fatalf("expected 0 results, got %d", len(result.Results))
 	}
 }
+
+func TestPipeline_EvaluateBatch_Shadow(t *testing.T) {
+	pipeline := NewPipeline(NewRegistry())
+	trace := &types.Trace{TraceID: "trc_shadow", Output: json.RawMessage(`{"message":"hello"}`)}
+	never := 0.0
+
+	result, err := pipeline.EvaluateBatch(trace, []types.Assertion{
+		{
+			AssertionID: "shadow_content",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"goodbye"}`),
+			Shadow:      true,
+		},
+		{
+			AssertionID: "judge",
+			Type:        types.TypeLLMJudge,
+			Spec:        json.RawMessage(`{"target":"output.message","criteria":"polite"}`),
+			SampleRate:  &never,
+		},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("got %d results, want 2: a shadow failure must not gate layers 5-6", len(result.Results))
+	}
+	r := result.Results[0]
+	if r.Status != types.StatusPass || r.ShadowStatus != types.StatusHardFail || r.Score != 0 {
+		t.Errorf("shadow result = %+v, want pass with shadow_status hard_fail", r)
+	}
+}
+
+func TestPipeline_EvaluateBatch_Summary(t *testing.T) {
+	pipeline := NewPipeline(NewRegistry())
+	trace := &types.Trace{TraceID: "trc_summary", Output: json.RawMessage(`{"message":"hello"}`)}
+	never := 0.0
+
+	result, err := pipeline.EvaluateBatch(trace, []types.Assertion{
+		{
+			AssertionID: "greets",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hello"}`),
+			Tags:        []string{"tone"},
+		},
+		{
+			AssertionID: "farewell",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"goodbye","soft":true}`),
+			Tags:        []string{"tone", "closing"},
+		},
+		{
+			AssertionID: "judge",
+			Type:        types.TypeLLMJudge,
+			Spec:        json.RawMessage(`{"target":"output.message","criteria":"polite"}`),
+			SampleRate:  &never,
+		},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	s := result.Summary
+	if s == nil {
+		t.Fatal("Summary is nil")
+	}
+	if want := (types.StatusCounts{Total: 3, Pass: 1, SoftFail: 1, Skipped: 1}); s.Counts != want {
+		t.Errorf("Counts = %+v, want %+v", s.Counts, want)
+	}
+	if got := s.Layers["4"]; got.Total != 2 || got.SoftFail != 1 {
+		t.Errorf("Layers[4] = %+v, want 2 results with 1 soft_fail", got)
+	}
+	if got := s.Layers["6"]; got.Skipped != 1 {
+		t.Errorf("Layers[6] = %+v, want 1 skipped", got)
+	}
+	if got := s.Tags["tone"]; got.Total != 2 {
+		t.Errorf("Tags[tone] = %+v, want 2 results", got)
+	}
+	if got := s.Tags["closing"]; got.SoftFail != 1 {
+		t.Errorf("Tags[closing] = %+v, want 1 soft_fail", got)
+	}
+	if s.WorstFailure == nil || s.WorstFailure.AssertionID != "farewell" {
+		t.Errorf("WorstFailure = %+v, want farewell", s.WorstFailure)
+	}
+}
+
+type panickingEvaluator struct{}
+
+func (panickingEvaluator) Evaluate(*types.Trace, *types.Assertion) *types.AssertionResult {
+	panic("evaluator bug")
+}
+
+func TestPipeline_EvaluateBatch_RecoversEvaluatorPanic(t *testing.T) {
+	registry := NewRegistry()
+	// llm_judge runs on its own goroutine in the concurrent L5-6 phase.
+	registry.Register(types.TypeLLMJudge, panickingEvaluator{})
+	pipeline := NewPipeline(registry)
+
+	trace := &types.Trace{TraceID: "trc_panic", Output: json.RawMessage(`{"message":"hi"}`)}
+	result, err := pipeline.EvaluateBatch(trace, []types.Assertion{
+		{AssertionID: "ok", Type: types.TypeContent, Spec: json.RawMessage(`{"target":"output.message","check":"contains","value":"hi"}`)},
+		{AssertionID: "boom", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{}`)},
+		{AssertionID: "boom_too", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	if len(result.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(result.Results))
+	}
+	if result.Results[0].Status != types.StatusPass {
+		t.Errorf("content status = %s, want pass", result.Results[0].Status)
+	}
+	for _, r := range result.Results[1:] {
+		if r.Status != types.StatusHardFail || r.Explanation != "internal error: llm_judge evaluator panicked: evaluator bug" {
+			t.Errorf("%s = %s %q, want hard_fail from the recovered panic", r.AssertionID, r.Status, r.Explanation)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package assertion
+
+import (
+	"sync"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// maxPooledCap bounds the capacity of buffers returned to a pool so a single
+// huge batch does not pin its scratch memory for the life of the process.
+const maxPooledCap = 1 << 16
+
+// slicePool recycles scratch slices used within one call. Slices taken with
+// get must not escape the caller and are cleared by put.
+type slicePool[T any] struct {
+	p sync.Pool
+}
+
+// get returns a slice of length n, reusing a pooled backing array when one is
+// large enough.
+func (sp *slicePool[T]) get(n int) *[]T {
+	s, _ := sp.p.Get().(*[]T)
+	if s == nil {
+		s = new([]T)
+	}
+	if cap(*s) < n {
+		*s = make([]T, n)
+	}
+	*s = (*s)[:n]
+	return s
+}
+
+// put clears s and returns it to the pool.
+func (sp *slicePool[T]) put(s *[]T) {
+	if cap(*s) > maxPooledCap {
+		return
+	}
+	clear(*s)
+	*s = (*s)[:0]
+	sp.p.Put(s)
+}
+
+var (
+	// assertionScratch holds the layer-sorted copy of a batch's assertions.
+	assertionScratch slicePool[types.Assertion]
+	// layerScratch holds the evaluation layer of each sorted assertion.
+	layerScratch slicePool[int]
+	// resultScratch holds L5-6 results before they are merged in order.
+	resultScratch slicePool[types.AssertionResult]
+	// stepNameScratch holds the step names a trace assertion checks.
+	stepNameScratch slicePool[string]
+)
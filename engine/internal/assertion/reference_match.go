@@ -0,0 +1,313 @@
+package assertion
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Reference metric names.
+const (
+	MetricRougeL       = "rouge_l"
+	MetricBLEU         = "bleu"
+	MetricChrF         = "chrf"
+	MetricEditDistance = "edit_distance"
+)
+
+const (
+	// bleuMaxOrder is the longest n-gram BLEU counts.
+	bleuMaxOrder = 4
+	// chrFMaxOrder is the longest character n-gram chrF counts, and chrFBeta
+	// weighs its recall over precision.
+	chrFMaxOrder = 6
+	chrFBeta     = 2
+	// maxMetricCells bounds the dynamic-programming table of ROUGE-L and edit
+	// distance (tokens or runes of target times reference), so one long
+	// output cannot stall a batch.
+	maxMetricCells = 25_000_000
+)
+
+// ReferenceMatchEvaluator implements Layer 4 reference_match assertions:
+// classical NLG metrics between the target text and an expected reference,
+// scored in [0, 1] and compared with a threshold. Every metric is
+// deterministic and needs no model.
+//
+//   - rouge_l: F1 of the longest common subsequence of words
+//   - bleu: sentence BLEU over 1- to 4-grams of words with add-one smoothing
+//     for n > 1 and the brevity penalty
+//   - chrf: chrF (beta 2) over 1- to 6-grams of characters, ignoring
+//     whitespace
+//   - edit_distance: 1 minus the Levenshtein distance over characters,
+//     divided by the longer length
+type ReferenceMatchEvaluator struct{}
+
+type referenceMatchSpec struct {
+	Target     string   `json:"target"`
+	Reference  string   `json:"reference"`
+	References []string `json:"references"`
+	Metric     string   `json:"metric"`
+	Threshold  *float64 `json:"threshold"`
+	// CaseSensitive keeps case; texts are lowercased by default.
+	CaseSensitive bool `json:"case_sensitive"`
+	Soft          bool `json:"soft"`
+}
+
+func (e *ReferenceMatchEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec referenceMatchSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid reference_match spec: %v", err))
+	}
+	if spec.Target == "" {
+		spec.Target = "output.message"
+	}
+	if spec.Metric == "" {
+		spec.Metric = MetricRougeL
+	}
+	refs := spec.References
+	if spec.Reference != "" {
+		refs = append([]string{spec.Reference}, refs...)
+	}
+	if len(refs) == 0 {
+		return failResult(assertion, start, "reference_match spec requires 'reference' or 'references'")
+	}
+	if spec.Threshold == nil {
+		return failResult(assertion, start, "reference_match spec missing required field: threshold")
+	}
+	threshold := *spec.Threshold
+	if threshold < 0 || threshold > 1 {
+		return failResult(assertion, start, fmt.Sprintf("threshold must be in [0, 1], got %g", threshold))
+	}
+	metric, ok := referenceMetrics[spec.Metric]
+	if !ok {
+		return failResult(assertion, start, fmt.Sprintf("unsupported metric: %s (must be %s, %s, %s, or %s)",
+			spec.Metric, MetricRougeL, MetricBLEU, MetricChrF, MetricEditDistance))
+	}
+
+	text, err := ResolveTargetString(trace, spec.Target)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("cannot resolve target %s: %v", spec.Target, err))
+	}
+	if !spec.CaseSensitive {
+		text = strings.ToLower(text)
+	}
+
+	best, bestRef := -1.0, 0
+	for i, ref := range refs {
+		if !spec.CaseSensitive {
+			ref = strings.ToLower(ref)
+		}
+		score, err := metric(text, ref)
+		if err != nil {
+			return failResult(assertion, start, fmt.Sprintf("%s: %v", spec.Metric, err))
+		}
+		if score > best {
+			best, bestRef = score, i
+		}
+	}
+
+	against := "the reference"
+	if len(refs) > 1 {
+		against = fmt.Sprintf("reference %d of %d", bestRef+1, len(refs))
+	}
+	if best >= threshold {
+		return &types.AssertionResult{
+			AssertionID: assertion.AssertionID,
+			Status:      types.StatusPass,
+			Score:       best,
+			Explanation: fmt.Sprintf("%s %.3f against %s, at or above threshold %.3f.", spec.Metric, best, against, threshold),
+			DurationMS:  time.Since(start).Milliseconds(),
+			RequestID:   assertion.RequestID,
+		}
+	}
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       best,
+		Explanation: fmt.Sprintf("%s %.3f against %s, below threshold %.3f", spec.Metric, best, against, threshold),
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+var referenceMetrics = map[string]func(candidate, reference string) (float64, error){
+	MetricRougeL:       rougeL,
+	MetricBLEU:         bleu,
+	MetricChrF:         chrF,
+	MetricEditDistance: editSimilarity,
+}
+
+// metricWords splits s into words: runs of letters and digits.
+func metricWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func checkCells(a, b int) error {
+	if a > 0 && b > maxMetricCells/a {
+		return fmt.Errorf("texts too long to compare (%d x %d exceeds %d)", a, b, maxMetricCells)
+	}
+	return nil
+}
+
+// rougeL returns the ROUGE-L F1 score of candidate against reference.
+func rougeL(candidate, reference string) (float64, error) {
+	c, r := metricWords(candidate), metricWords(reference)
+	if len(c) == 0 || len(r) == 0 {
+		return emptyScore(len(c), len(r)), nil
+	}
+	if err := checkCells(len(c), len(r)); err != nil {
+		return 0, err
+	}
+	prev, cur := make([]int, len(r)+1), make([]int, len(r)+1)
+	for i := range c {
+		for j := range r {
+			if c[i] == r[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	lcs := float64(prev[len(r)])
+	if lcs == 0 {
+		return 0, nil
+	}
+	p, rec := lcs/float64(len(c)), lcs/float64(len(r))
+	return 2 * p * rec / (p + rec), nil
+}
+
+// bleu returns sentence BLEU of candidate against reference.
+func bleu(candidate, reference string) (float64, error) {
+	c, r := metricWords(candidate), metricWords(reference)
+	if len(c) == 0 || len(r) == 0 {
+		return emptyScore(len(c), len(r)), nil
+	}
+	var logSum float64
+	for n := 1; n <= bleuMaxOrder; n++ {
+		refCounts := ngramCounts(r, n)
+		matches, total := 0, 0
+		for gram, count := range ngramCounts(c, n) {
+			matches += min(count, refCounts[gram])
+			total += count
+		}
+		p := float64(matches) / float64(total)
+		if n > 1 {
+			p = float64(matches+1) / float64(total+1)
+		} else if matches == 0 {
+			return 0, nil
+		}
+		logSum += math.Log(p)
+	}
+	bp := 1.0
+	if len(c) < len(r) {
+		bp = math.Exp(1 - float64(len(r))/float64(len(c)))
+	}
+	return bp * math.Exp(logSum/bleuMaxOrder), nil
+}
+
+// ngramCounts counts the n-grams of words.
+func ngramCounts(words []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(words); i++ {
+		counts[strings.Join(words[i:i+n], "\x00")]++
+	}
+	return counts
+}
+
+// chrF returns the chrF score of candidate against reference.
+func chrF(candidate, reference string) (float64, error) {
+	strip := func(s string) []rune {
+		return []rune(strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, s))
+	}
+	c, r := strip(candidate), strip(reference)
+	if len(c) == 0 || len(r) == 0 {
+		return emptyScore(len(c), len(r)), nil
+	}
+	var precision, recall float64
+	orders := 0
+	for n := 1; n <= chrFMaxOrder; n++ {
+		if n > len(c) || n > len(r) {
+			break
+		}
+		refCounts := charNgramCounts(r, n)
+		matches, total := 0, 0
+		for gram, count := range charNgramCounts(c, n) {
+			matches += min(count, refCounts[gram])
+			total += count
+		}
+		precision += float64(matches) / float64(total)
+		recall += float64(matches) / float64(len(r)-n+1)
+		orders++
+	}
+	precision /= float64(orders)
+	recall /= float64(orders)
+	if precision == 0 && recall == 0 {
+		return 0, nil
+	}
+	b2 := float64(chrFBeta * chrFBeta)
+	return (1 + b2) * precision * recall / (b2*precision + recall), nil
+}
+
+// charNgramCounts counts the character n-grams of runes.
+func charNgramCounts(runes []rune, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(runes); i++ {
+		counts[string(runes[i:i+n])]++
+	}
+	return counts
+}
+
+// editSimilarity returns 1 minus the Levenshtein distance between candidate
+// and reference, in runes, divided by the longer length.
+func editSimilarity(candidate, reference string) (float64, error) {
+	a, b := []rune(candidate), []rune(reference)
+	if len(a) == 0 || len(b) == 0 {
+		return emptyScore(len(a), len(b)), nil
+	}
+	if err := checkCells(len(a), len(b)); err != nil {
+		return 0, err
+	}
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range a {
+		cur[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(b)])/float64(max(len(a), len(b))), nil
+}
+
+// emptyScore scores texts when either is empty: 1 when both are, else 0.
+func emptyScore(a, b int) float64 {
+	if a == 0 && b == 0 {
+		return 1
+	}
+	return 0
+}
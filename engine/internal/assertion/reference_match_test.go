@@ -0,0 +1,83 @@
+package assertion
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestReferenceMetrics(t *testing.T) {
+	tests := []struct {
+		metric    string
+		candidate string
+		reference string
+		want      float64
+	}{
+		{MetricRougeL, "the cat sat on the mat", "the cat sat on the mat", 1},
+		// LCS "the cat the mat" = 4 of 6 words each way.
+		{MetricRougeL, "the cat was on the mat", "the cat sat near the mat", 4.0 / 6},
+		{MetricRougeL, "dogs bark", "cats meow", 0},
+		{MetricBLEU, "the cat sat on the mat", "the cat sat on the mat", 1},
+		{MetricBLEU, "dogs bark", "cats meow", 0},
+		{MetricChrF, "kitten", "kitten", 1},
+		{MetricChrF, "abc", "xyz", 0},
+		{MetricEditDistance, "kitten", "sitting", 1 - 3.0/7},
+		{MetricEditDistance, "", "", 1},
+		{MetricEditDistance, "", "a", 0},
+	}
+	for _, tt := range tests {
+		got, err := referenceMetrics[tt.metric](tt.candidate, tt.reference)
+		if err != nil {
+			t.Errorf("%s(%q, %q): %v", tt.metric, tt.candidate, tt.reference, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s(%q, %q) = %.4f, want %.4f", tt.metric, tt.candidate, tt.reference, got, tt.want)
+		}
+	}
+
+	// Partial matches land strictly between 0 and 1, and a shorter
+	// candidate is penalized by BLEU's brevity penalty.
+	full, _ := bleu("the quick brown fox jumps over the lazy dog", "the quick brown fox jumps over the lazy dog")
+	short, _ := bleu("the quick brown fox", "the quick brown fox jumps over the lazy dog")
+	if !(short > 0 && short < full) {
+		t.Errorf("bleu short = %.4f, full = %.4f; want 0 < short < full", short, full)
+	}
+	if got, _ := chrF("colour", "color"); got <= 0 || got >= 1 {
+		t.Errorf("chrf(colour, color) = %.4f, want in (0, 1)", got)
+	}
+}
+
+func TestReferenceMatchEvaluator(t *testing.T) {
+	evaluator := &ReferenceMatchEvaluator{}
+	trace := &types.Trace{
+		TraceID: "trc_test",
+		Output:  json.RawMessage(`{"message":"Your refund of $89.99 has been processed.","structured":{"code":"RFD-001"}}`),
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"rouge_l passes", `{"reference":"your refund of $89.99 was processed","threshold":0.7}`, types.StatusPass},
+		{"rouge_l fails", `{"reference":"we cannot refund this order","threshold":0.7}`, types.StatusHardFail},
+		{"soft fail", `{"reference":"we cannot refund this order","threshold":0.7,"soft":true}`, types.StatusSoftFail},
+		{"best of references", `{"references":["no","Your refund of $89.99 has been processed."],"metric":"bleu","threshold":0.99}`, types.StatusPass},
+		{"case sensitive", `{"reference":"YOUR REFUND OF $89.99 HAS BEEN PROCESSED.","metric":"edit_distance","threshold":0.9,"case_sensitive":true}`, types.StatusHardFail},
+		{"custom target", `{"target":"output.structured.code","reference":"RFD-002","metric":"chrf","threshold":0.5}`, types.StatusPass},
+		{"missing threshold", `{"reference":"x"}`, types.StatusHardFail},
+		{"missing reference", `{"threshold":0.5}`, types.StatusHardFail},
+		{"unknown metric", `{"reference":"x","metric":"meteor","threshold":0.5}`, types.StatusHardFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace, &types.Assertion{AssertionID: "a", Type: types.TypeReferenceMatch, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package assertion
+
+import "regexp"
+
+// regexCacheSize caps the number of compiled patterns kept by sharedRegexCache.
+const regexCacheSize = 512
+
+// regexCache is a size-capped LRU of compiled regexps keyed by pattern.
+// Compile errors are cached too, so an invalid pattern shared across many
+// traces is rejected without recompiling. *regexp.Regexp is safe for
+// concurrent use, so cached values are shared freely.
+type regexCache struct {
+	lru *lruCache[regexEntry]
+}
+
+type regexEntry struct {
+	re  *regexp.Regexp
+	err error
+}
+
+func newRegexCache(max int) *regexCache {
+	return &regexCache{lru: newLRUCache[regexEntry](max)}
+}
+
+// compile returns the compiled pattern, compiling and caching it on a miss.
+// Compilation runs outside the lock; a concurrent miss on the same pattern
+// just compiles it twice.
+func (c *regexCache) compile(pattern string) (*regexp.Regexp, error) {
+	if e, ok := c.lru.get(pattern); ok {
+		return e.re, e.err
+	}
+	re, err := regexp.Compile(pattern)
+	e := c.lru.add(pattern, regexEntry{re: re, err: err})
+	return e.re, e.err
+}
+
+// len returns the number of cached patterns.
+func (c *regexCache) len() int {
+	return c.lru.len()
+}
+
+// sharedRegexCache serves every ContentEvaluator; suites reuse the same
+// patterns across many traces and batches.
+var sharedRegexCache = newRegexCache(regexCacheSize)
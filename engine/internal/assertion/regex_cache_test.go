@@ -0,0 +1,64 @@
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestRegexCache_ReusesCompiledPatterns(t *testing.T) {
+	c := newRegexCache(4)
+
+	a, err := c.compile(`^ORD-\d+$`)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	b, _ := c.compile(`^ORD-\d+$`)
+	if a != b {
+		t.Error("second compile returned a different *Regexp")
+	}
+
+	_, err1 := c.compile(`(unclosed`)
+	_, err2 := c.compile(`(unclosed`)
+	if err1 == nil || err1 != err2 {
+		t.Errorf("invalid pattern errors = %v, %v; want the same cached error", err1, err2)
+	}
+}
+
+func TestRegexCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRegexCache(2)
+	first, _ := c.compile("a")
+	c.compile("b")
+	c.compile("a") // a is now most recent
+	c.compile("c") // evicts b
+
+	if c.len() != 2 {
+		t.Fatalf("len = %d, want 2", c.len())
+	}
+	if again, _ := c.compile("a"); again != first {
+		t.Error("recently used pattern was evicted")
+	}
+	if c.lru.contains("b") {
+		t.Error("least recently used pattern was not evicted")
+	}
+}
+
+func BenchmarkContentEvaluator_RegexMatch(b *testing.B) {
+	eval := &ContentEvaluator{}
+	trace := benchTrace(1)
+	suite := make([]*types.Assertion, 50)
+	for i := range suite {
+		suite[i] = &types.Assertion{
+			AssertionID: fmt.Sprintf("re_%d", i),
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(fmt.Sprintf(`{"target":"output.message","check":"regex_match","value":"ORD-\\d{%d}|refunded"}`, i%5+1)),
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		eval.Evaluate(trace, suite[i%len(suite)])
+	}
+}
@@ -0,0 +1,57 @@
+package assertion
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultRegexBudget is the wall-clock budget for one regex_match check when
+// neither the spec nor the registry sets one.
+const DefaultRegexBudget = time.Second
+
+// regexGuardThreshold is the target size above which matching runs under the
+// deadline guard. RE2 matching is linear, so smaller targets finish well
+// within any budget and take the faster MatchString path.
+const regexGuardThreshold = 64 << 10
+
+// regexDeadlineStride is how many runes the guard reads between clock checks.
+const regexDeadlineStride = 4096
+
+// matchWithBudget reports whether re matches s, giving up once budget has
+// elapsed. timedOut is true when the match was abandoned; matched is then
+// meaningless. A budget <= 0 disables the guard.
+func matchWithBudget(re *regexp.Regexp, s string, budget time.Duration) (matched, timedOut bool) {
+	if budget <= 0 || len(s) < regexGuardThreshold {
+		return re.MatchString(s), false
+	}
+	r := &deadlineReader{
+		src:      strings.NewReader(s),
+		deadline: time.Now().Add(budget),
+	}
+	matched = re.MatchReader(r)
+	return matched, r.expired
+}
+
+// deadlineReader feeds a regexp one rune at a time and reports end of input
+// once its deadline passes, which stops the matcher at its next step.
+type deadlineReader struct {
+	src      *strings.Reader
+	deadline time.Time
+	n        int
+	expired  bool
+}
+
+func (r *deadlineReader) ReadRune() (rune, int, error) {
+	if r.expired {
+		return utf8.RuneError, 0, io.EOF
+	}
+	r.n++
+	if r.n%regexDeadlineStride == 0 && time.Now().After(r.deadline) {
+		r.expired = true
+		return utf8.RuneError, 0, io.EOF
+	}
+	return r.src.ReadRune()
+}
@@ -0,0 +1,59 @@
+package assertion
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestMatchWithBudget(t *testing.T) {
+	large := strings.Repeat("a", 1<<20) + "ORD-123"
+	re := regexp.MustCompile(`ORD-\d+$`)
+
+	tests := []struct {
+		name         string
+		target       string
+		budget       time.Duration
+		wantMatch    bool
+		wantTimedOut bool
+	}{
+		{"small target skips guard", "ORD-1", time.Nanosecond, true, false},
+		{"large target within budget", large, time.Minute, true, false},
+		{"large target without match", strings.Repeat("a", 1<<20), time.Minute, false, false},
+		{"guard disabled", large, -1, true, false},
+		{"large target over budget", large, time.Nanosecond, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, timedOut := matchWithBudget(re, tt.target, tt.budget)
+			if timedOut != tt.wantTimedOut {
+				t.Fatalf("timedOut = %v, want %v", timedOut, tt.wantTimedOut)
+			}
+			if !timedOut && matched != tt.wantMatch {
+				t.Errorf("matched = %v, want %v", matched, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestContentEvaluator_RegexTimeout(t *testing.T) {
+	output, _ := json.Marshal(map[string]string{"message": strings.Repeat("x", 4<<20)})
+	trace := &types.Trace{TraceID: "trc_big", Output: output}
+	assertion := &types.Assertion{
+		AssertionID: "assert_re",
+		Type:        types.TypeContent,
+		Spec:        json.RawMessage(`{"target":"output.message","check":"regex_match","value":"(x+x+)+y","timeout_ms":1}`),
+	}
+
+	result := (&ContentEvaluator{}).Evaluate(trace, assertion)
+	if result.Status != types.StatusHardFail {
+		t.Fatalf("status = %s, want hard_fail", result.Status)
+	}
+	if !strings.Contains(result.Explanation, "exceeded time budget of 1ms") {
+		t.Errorf("explanation = %q", result.Explanation)
+	}
+}
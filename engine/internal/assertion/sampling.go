@@ -0,0 +1,43 @@
+package assertion
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// SampleRate returns the fraction of traces on which a runs: its sample_rate
+// for layers 5-6 and 1 otherwise.
+func SampleRate(a *types.Assertion) float64 {
+	if a.SampleRate == nil || *a.SampleRate >= 1 || assertionLayer(a) < 5 {
+		return 1
+	}
+	return *a.SampleRate
+}
+
+// sampledOut reports whether a's sample_rate leaves it out for traceID. The
+// decision hashes the trace and assertion IDs, so re-evaluating a trace
+// samples the same assertions and a rate's fraction holds across traces.
+func sampledOut(traceID string, a *types.Assertion) bool {
+	if a.SampleRate == nil || *a.SampleRate >= 1 {
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(traceID))
+	h.Write([]byte{0})
+	h.Write([]byte(a.AssertionID))
+	return float64(h.Sum64())/math.MaxUint64 >= *a.SampleRate
+}
+
+// sampledOutResult is the result of an assertion left out by its sample_rate.
+func sampledOutResult(a *types.Assertion) types.AssertionResult {
+	return types.AssertionResult{
+		AssertionID: a.AssertionID,
+		Status:      types.StatusSkipped,
+		SkipReason:  types.SkipSampledOut,
+		Explanation: fmt.Sprintf("sampled out (sample_rate %g)", *a.SampleRate),
+		RequestID:   a.RequestID,
+	}
+}
@@ -0,0 +1,78 @@
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestPipeline_SampleRate(t *testing.T) {
+	pipeline := NewPipeline(NewRegistry())
+	rate := func(r float64) *float64 { return &r }
+	trace := &types.Trace{TraceID: "trc_sampling", Output: json.RawMessage(`{"message":"hello"}`)}
+
+	result, err := pipeline.EvaluateBatch(trace, []types.Assertion{
+		{
+			AssertionID: "content_never",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hello"}`),
+			SampleRate:  rate(0),
+		},
+		{
+			AssertionID: "embedding_never",
+			Type:        types.TypeEmbedding,
+			Spec:        json.RawMessage(`{"target":"output.message","reference":"hi","threshold":0.8}`),
+			SampleRate:  rate(0),
+		},
+		{
+			AssertionID: "embedding_always",
+			Type:        types.TypeEmbedding,
+			Spec:        json.RawMessage(`{"target":"output.message","reference":"hi","threshold":0.8}`),
+			SampleRate:  rate(1),
+		},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	byID := map[string]types.AssertionResult{}
+	for _, r := range result.Results {
+		byID[r.AssertionID] = r
+	}
+	// Layers 1-4 ignore sample_rate.
+	if r := byID["content_never"]; r.Status != types.StatusPass {
+		t.Errorf("content_never = %+v, want pass", r)
+	}
+	if r := byID["embedding_never"]; r.Status != types.StatusSkipped || r.SkipReason != types.SkipSampledOut {
+		t.Errorf("embedding_never = %+v, want skipped/sampled_out", r)
+	}
+	if r := byID["embedding_always"]; r.Status == types.StatusSkipped {
+		t.Errorf("embedding_always was skipped: %+v", r)
+	}
+}
+
+func TestSampledOut_DeterministicFraction(t *testing.T) {
+	r := 0.25
+	a := &types.Assertion{AssertionID: "judge", Type: types.TypeLLMJudge, SampleRate: &r}
+	kept := 0
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("trc_%d", i)
+		out := sampledOut(id, a)
+		if out != sampledOut(id, a) {
+			t.Fatalf("sampling of %s is not deterministic", id)
+		}
+		if !out {
+			kept++
+		}
+	}
+	if kept < 400 || kept > 600 {
+		t.Errorf("kept %d of 2000 traces at sample_rate 0.25", kept)
+	}
+	if got := SampleRate(a); got != 0.25 {
+		t.Errorf("SampleRate(llm_judge) = %v, want 0.25", got)
+	}
+	if got := SampleRate(&types.Assertion{Type: types.TypeContent, SampleRate: &r}); got != 1 {
+		t.Errorf("SampleRate(content) = %v, want 1", got)
+	}
+}
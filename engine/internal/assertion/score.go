@@ -2,6 +2,7 @@ package assertion
 
 import (
 	"math"
+	"sort"
 
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
@@ -11,6 +12,10 @@ type BatchResult struct {
 	Results         []types.AssertionResult
 	TotalCost       float64
 	TotalDurationMS int64
+	// Timings breaks down where the batch spent its time.
+	Timings *types.BatchTimings
+	// Summary aggregates Results by status, layer, and tag.
+	Summary *types.BatchSummary
 }
 
 // ScoreThresholds defines pass and soft-fail score boundaries.
@@ -45,31 +50,74 @@ func ClassifyScoreWithThreshold(score, passThreshold, softThreshold float64) str
 	}
 }
 
+// Dynamic classification strategies.
+const (
+	// DynamicStrategySigma fails scores below mean - SigmaScale*stddev of the window.
+	DynamicStrategySigma = "sigma"
+	// DynamicStrategyPercentile fails scores below the Percentile-th percentile of the window.
+	// Robust to skewed score distributions where the mean and stddev are misleading.
+	DynamicStrategyPercentile = "percentile"
+)
+
 // DynamicConfig holds parameters for dynamic threshold classification.
+// An empty Strategy is treated as DynamicStrategySigma.
 type DynamicConfig struct {
 	WindowSize int
 	SigmaScale float64
 	MinRuns    int
+	Strategy   string
+	Percentile float64
 }
 
 // DefaultDynamicConfig provides sensible defaults for dynamic classification.
-var DefaultDynamicConfig = DynamicConfig{WindowSize: 50, SigmaScale: 2.0, MinRuns: 10}
+var DefaultDynamicConfig = DynamicConfig{
+	WindowSize: 50,
+	SigmaScale: 2.0,
+	MinRuns:    10,
+	Strategy:   DynamicStrategySigma,
+	Percentile: 10,
+}
 
 // ClassifyDynamic classifies a score using a historical baseline.
 // When len(history) < cfg.MinRuns, falls back to ClassifyScore.
-// Otherwise: pass if score >= mean - sigmaScale*stddev, hard_fail otherwise.
+// Otherwise: pass if score >= DynamicThreshold(history, cfg), hard_fail otherwise.
 func ClassifyDynamic(score float64, history []float64, cfg DynamicConfig) string {
 	if len(history) < cfg.MinRuns {
 		return ClassifyScore(score)
 	}
-	mean, stddev := computeStats(history)
-	threshold := mean - cfg.SigmaScale*stddev
-	if score >= threshold {
+	if score >= DynamicThreshold(history, cfg) {
 		return types.StatusPass
 	}
 	return types.StatusHardFail
 }
 
+// DynamicThreshold returns the minimum passing score for history under cfg.Strategy:
+// mean - SigmaScale*stddev for sigma, or the Percentile-th percentile for percentile.
+func DynamicThreshold(history []float64, cfg DynamicConfig) float64 {
+	if cfg.Strategy == DynamicStrategyPercentile {
+		return percentile(history, cfg.Percentile)
+	}
+	mean, stddev := computeStats(history)
+	return mean - cfg.SigmaScale*stddev
+}
+
+// percentile returns the p-th percentile (0-100) of data using linear
+// interpolation between closest ranks. data is not modified.
+func percentile(data []float64, p float64) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(data))
+	copy(sorted, data)
+	sort.Float64s(sorted)
+
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
 // computeStats returns the mean and population standard deviation of data.
 func computeStats(data []float64) (mean, stddev float64) {
 	if len(data) == 0 {
@@ -1,6 +1,7 @@
 package assertion_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/attest-ai/attest/engine/internal/assertion"
@@ -129,3 +130,54 @@ func TestClassifyDynamic_EmptyHistory(t *testing.T) {
 		t.Errorf("ClassifyDynamic(0.9, empty) = %q, want pass", got)
 	}
 }
+
+func TestClassifyDynamic_PercentileStrategy(t *testing.T) {
+	// Skewed window: mostly 0.95 with two outliers at 0.2.
+	// sigma:      mean=0.875, stddev=0.225 → threshold 0.425
+	// percentile: p10 = 0.2 + 0.9*(0.95-0.2) = 0.875
+	history := make([]float64, 0, 20)
+	for i := 0; i < 18; i++ {
+		history = append(history, 0.95)
+	}
+	history = append(history, 0.2, 0.2)
+
+	sigmaCfg := assertion.DynamicConfig{WindowSize: 50, SigmaScale: 2.0, MinRuns: 5}
+	if got := assertion.ClassifyDynamic(0.6, history, sigmaCfg); got != types.StatusPass {
+		t.Errorf("sigma strategy: ClassifyDynamic(0.6) = %q, want pass", got)
+	}
+
+	pctCfg := assertion.DynamicConfig{WindowSize: 50, MinRuns: 5, Strategy: assertion.DynamicStrategyPercentile, Percentile: 10}
+	if got := assertion.ClassifyDynamic(0.6, history, pctCfg); got != types.StatusHardFail {
+		t.Errorf("percentile strategy: ClassifyDynamic(0.6) = %q, want hard_fail", got)
+	}
+	if got := assertion.ClassifyDynamic(0.9, history, pctCfg); got != types.StatusPass {
+		t.Errorf("percentile strategy: ClassifyDynamic(0.9) = %q, want pass", got)
+	}
+	if got := assertion.DynamicThreshold(history, pctCfg); math.Abs(got-0.875) > 1e-9 {
+		t.Errorf("DynamicThreshold(percentile p10) = %f, want 0.875", got)
+	}
+}
+
+func TestDynamicThreshold_PercentileBounds(t *testing.T) {
+	history := []float64{0.5, 0.1, 0.9, 0.3, 0.7}
+	cfg := assertion.DynamicConfig{Strategy: assertion.DynamicStrategyPercentile}
+
+	cases := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 0.1},
+		{50, 0.5},
+		{100, 0.9},
+		{25, 0.3},
+	}
+	for _, tc := range cases {
+		cfg.Percentile = tc.p
+		if got := assertion.DynamicThreshold(history, cfg); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("DynamicThreshold(p%v) = %f, want %f", tc.p, got, tc.want)
+		}
+	}
+	if history[0] != 0.5 {
+		t.Error("DynamicThreshold must not reorder the caller's history")
+	}
+}
@@ -0,0 +1,246 @@
+package assertion
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/codecheck"
+	"github.com/attest-ai/attest/engine/internal/assertion/sqlcheck"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// SQLSafeEvaluator implements Layer 4 sql_safe assertions: the SQL in the
+// target must be read-only unless writes are allowed, touch only allowed
+// tables, and filter on required columns. SQL is analyzed statically; no
+// database is involved.
+type SQLSafeEvaluator struct{}
+
+type sqlSafeSpec struct {
+	Target string `json:"target"`
+	// Allow lists statement kinds permitted beyond reads, by keyword or as
+	// the groups "dml", "ddl", and "dcl".
+	Allow []string `json:"allow"`
+	// AllowedTables limits the tables referenced. An entry without a schema
+	// matches the table in any schema; "schema.*" matches a whole schema.
+	AllowedTables []string `json:"allowed_tables"`
+	// RequiredPredicates are columns every SELECT, UPDATE, and DELETE over a
+	// table must filter on in a top-level AND conjunct of its WHERE clause.
+	// "table.column" applies only to blocks over that table.
+	RequiredPredicates []string `json:"required_predicates"`
+	Soft               bool     `json:"soft"`
+}
+
+// sqlInfoStrings are the fence info strings taken to hold SQL.
+var sqlInfoStrings = map[string]bool{
+	"sql": true, "postgresql": true, "postgres": true, "psql": true, "pgsql": true,
+	"mysql": true, "sqlite": true, "tsql": true, "plsql": true,
+}
+
+type sqlSource struct {
+	where string
+	code  string
+	line  int
+}
+
+func (e *SQLSafeEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec sqlSafeSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid sql_safe spec: %v", err))
+	}
+	if spec.Target == "" {
+		spec.Target = "output.message"
+	}
+	allowed := make(map[string]bool)
+	for _, a := range spec.Allow {
+		a = strings.ToLower(strings.TrimSpace(a))
+		switch {
+		case sqlcheck.KindGroups[a] != nil:
+			for _, k := range sqlcheck.KindGroups[a] {
+				allowed[k] = true
+			}
+		case sqlcheck.IsKind(a):
+			allowed[a] = true
+		default:
+			return failResult(assertion, start, fmt.Sprintf("unknown statement kind %q in allow", a))
+		}
+	}
+
+	text, err := ResolveTargetString(trace, spec.Target)
+	if err != nil {
+		return failResult(assertion, start, fmt.Sprintf("target resolution failed: %v", err))
+	}
+	sources := sqlSources(text)
+	if len(sources) == 0 {
+		return sqlSafeResult(assertion, start, spec, fmt.Sprintf("no SQL found in %s", spec.Target))
+	}
+
+	var problems []string
+	count := 0
+	for _, src := range sources {
+		if src.line < 0 {
+			problems = append(problems, fmt.Sprintf("%s: no closing fence", src.where))
+			continue
+		}
+		stmts, err := sqlcheck.Analyze(src.code)
+		var syn *sqlcheck.SyntaxError
+		switch {
+		case errors.As(err, &syn):
+			problems = append(problems, fmt.Sprintf("%s: target line %d: %s", src.where, src.line+syn.Line-1, syn.Message))
+			continue
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", src.where, err))
+			continue
+		case len(stmts) == 0:
+			problems = append(problems, fmt.Sprintf("%s: no SQL statement", src.where))
+			continue
+		}
+		for i, st := range stmts {
+			count++
+			where := fmt.Sprintf("statement %d", i+1)
+			if src.where != "" {
+				where = src.where + " " + where
+			}
+			problems = append(problems, checkSQLStatement(spec, allowed, where, st)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return sqlSafeResult(assertion, start, spec, fmt.Sprintf("%s has unsafe SQL: %s", spec.Target, strings.Join(problems, "; ")))
+	}
+	return passResult(assertion, start, fmt.Sprintf("%d SQL statements in %s are within policy.", count, spec.Target))
+}
+
+// sqlSources returns the SQL to analyze in text: its SQL-tagged fenced
+// blocks, else its untagged ones, else the whole text when it has no fences.
+// An unterminated block has line -1.
+func sqlSources(text string) []sqlSource {
+	blocks := codecheck.Blocks(text)
+	if len(blocks) == 0 {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []sqlSource{{code: text, line: 1}}
+	}
+	var tagged, untagged []sqlSource
+	for i, b := range blocks {
+		src := sqlSource{where: fmt.Sprintf("sql block %d (line %d)", i+1, b.Line), code: b.Code, line: b.Line}
+		if b.Unterminated {
+			src.line = -1
+		}
+		info, _, _ := strings.Cut(b.Info, " ")
+		switch {
+		case sqlInfoStrings[strings.ToLower(info)]:
+			tagged = append(tagged, src)
+		case b.Info == "":
+			untagged = append(untagged, src)
+		}
+	}
+	if len(tagged) > 0 {
+		return tagged
+	}
+	return untagged
+}
+
+// checkSQLStatement applies the spec's policies to one statement.
+func checkSQLStatement(spec sqlSafeSpec, allowed map[string]bool, where string, st sqlcheck.Statement) []string {
+	var problems []string
+	for _, kind := range st.Kinds {
+		if !sqlcheck.IsRead(kind) && !allowed[kind] {
+			problems = append(problems, fmt.Sprintf("%s: %s is not allowed", where, strings.ToUpper(strings.ReplaceAll(kind, "_", " "))))
+		}
+	}
+	if len(spec.AllowedTables) > 0 {
+		for _, table := range st.Tables {
+			if !tableMatchesAny(table, spec.AllowedTables) {
+				problems = append(problems, fmt.Sprintf("%s: table %s is not allowed", where, table))
+			}
+		}
+		// A reference the analyzer cannot name could be any table.
+		for _, ref := range st.Unresolved {
+			problems = append(problems, fmt.Sprintf("%s: table reference %s cannot be resolved", where, ref))
+		}
+	}
+	for _, b := range st.Blocks {
+		if len(b.Tables) == 0 && len(b.Unresolved) == 0 {
+			continue
+		}
+		switch b.Kind {
+		case "select", "update", "delete", sqlcheck.KindSelectInto, sqlcheck.KindSelectForUpdate:
+		default:
+			continue
+		}
+		cols := make(map[string]bool, len(b.FilterColumns))
+		for c := range b.FilterColumns {
+			cols[strings.ToLower(c)] = true
+		}
+		for _, pred := range spec.RequiredPredicates {
+			scope, col := "", pred
+			if dot := strings.LastIndexByte(pred, '.'); dot >= 0 {
+				scope, col = pred[:dot], pred[dot+1:]
+			}
+			// Unresolved references may be the scoped table.
+			if scope != "" && !anyTableMatches(b.Tables, scope) && len(b.Unresolved) == 0 {
+				continue
+			}
+			if !cols[strings.ToLower(col)] {
+				problems = append(problems, fmt.Sprintf("%s: %s on %s does not filter on %s", where,
+					strings.ToUpper(strings.ReplaceAll(b.Kind, "_", " ")), strings.Join(slices.Concat(b.Tables, b.Unresolved), ", "), col))
+			}
+		}
+	}
+	return problems
+}
+
+// tableMatches reports whether table satisfies pattern: "orders" matches
+// orders in any schema, "public.orders" only that one, and "public.*"
+// every table in public. Matching ignores case.
+func tableMatches(table, pattern string) bool {
+	table, pattern = strings.ToLower(table), strings.ToLower(pattern)
+	if schema, ok := strings.CutSuffix(pattern, ".*"); ok {
+		return strings.HasPrefix(table, schema+".")
+	}
+	if strings.Contains(pattern, ".") {
+		return table == pattern
+	}
+	return table == pattern || strings.HasSuffix(table, "."+pattern)
+}
+
+func tableMatchesAny(table string, patterns []string) bool {
+	for _, p := range patterns {
+		if tableMatches(table, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyTableMatches(tables []string, pattern string) bool {
+	for _, t := range tables {
+		if tableMatches(t, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func sqlSafeResult(assertion *types.Assertion, start time.Time, spec sqlSafeSpec, explanation string) *types.AssertionResult {
+	status := types.StatusHardFail
+	if spec.Soft {
+		status = types.StatusSoftFail
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       0.0,
+		Explanation: explanation,
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
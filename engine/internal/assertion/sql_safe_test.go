@@ -0,0 +1,66 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestSQLSafeEvaluator(t *testing.T) {
+	evaluator := &SQLSafeEvaluator{}
+	trace := func(message string) *types.Trace {
+		out, _ := json.Marshal(map[string]any{"message": message, "structured": map[string]string{"query": "SELECT id FROM orders WHERE tenant_id = 1"}})
+		return &types.Trace{TraceID: "trc_test", Output: out}
+	}
+	scoped := "Here is the query:\n```sql\nSELECT o.id, c.name\nFROM orders o JOIN customers c ON c.id = o.customer_id\nWHERE o.tenant_id = $1\n```"
+	unscoped := "```sql\nSELECT * FROM orders\nWHERE status = 'open'\n  AND customer_id IN (SELECT id FROM customers WHERE tenant_id = $1)\n```"
+	write := "```sql\nSELECT 1;\nDELETE FROM orders WHERE id = 3\n```"
+
+	tests := []struct {
+		name       string
+		message    string
+		spec       string
+		wantStatus string
+		wantText   string
+	}{
+		{"read-only passes", scoped, `{"allowed_tables":["orders","customers"],"required_predicates":["tenant_id"]}`, types.StatusPass, "1 SQL statements"},
+		{"write denied", write, `{}`, types.StatusHardFail, "sql block 1 (line 2) statement 2: DELETE is not allowed"},
+		{"write allowed by group", write, `{"allow":["dml"]}`, types.StatusPass, ""},
+		{"write allowed by kind", write, `{"allow":["delete"]}`, types.StatusPass, ""},
+		{"unknown allow", write, `{"allow":["nuke"]}`, types.StatusHardFail, "unknown statement kind"},
+		{"table not allowed", scoped, `{"allowed_tables":["orders"]}`, types.StatusHardFail, "table customers is not allowed"},
+		{"schema wildcard", "SELECT * FROM sales.orders", `{"allowed_tables":["sales.*"]}`, types.StatusPass, ""},
+		{"schema mismatch", "SELECT * FROM hr.orders", `{"allowed_tables":["sales.orders"]}`, types.StatusHardFail, "table hr.orders"},
+		{"predicate missing in outer block", unscoped, `{"required_predicates":["tenant_id"]}`, types.StatusHardFail, "SELECT on orders does not filter on tenant_id"},
+		{"scoped predicate", unscoped, `{"required_predicates":["customers.tenant_id"]}`, types.StatusPass, ""},
+		{"syntax error line", "Sure:\n\n```sql\nSELECT * FROM t WHERE a = 'x\n```", `{}`, types.StatusHardFail, "target line 4: unterminated string"},
+		{"prose", "I cannot write that query.", `{}`, types.StatusHardFail, "unrecognized statement"},
+		{"no sql", "", `{}`, types.StatusHardFail, "no SQL found"},
+		{"soft", write, `{"soft":true}`, types.StatusSoftFail, ""},
+		{"structured target", "", `{"target":"output.structured.query","required_predicates":["tenant_id"]}`, types.StatusPass, ""},
+		{"executable comment", "SELECT 1 /*!50000 ; DROP TABLE users */", `{}`, types.StatusHardFail, "executable comment"},
+		{"delete without from", "DELETE users WHERE id = 1", `{"allow":["delete"],"allowed_tables":["orders"]}`, types.StatusHardFail, "table users is not allowed"},
+		{"parenthesized table", "SELECT * FROM (users)", `{"allowed_tables":["orders"]}`, types.StatusHardFail, "table reference (users) cannot be resolved"},
+		{"predicate under or", "SELECT * FROM orders WHERE 1=1 OR tenant_id = 5", `{"required_predicates":["tenant_id"]}`, types.StatusHardFail, "does not filter on tenant_id"},
+		{"predicate on unresolved table", "SELECT * FROM (orders) WHERE status = 1", `{"required_predicates":["orders.tenant_id"]}`, types.StatusHardFail, "does not filter on tenant_id"},
+		{"table function not allowed", "SELECT * FROM dblink('host=x','SELECT * FROM secrets') AS x(a int)", `{"allowed_tables":["orders"]}`, types.StatusHardFail, "table reference dblink('host=x','SELECT * FROM secrets') cannot be resolved"},
+		{"table function needs predicate", "SELECT * FROM dblink('host=x','SELECT * FROM secrets') AS x(a int)", `{"required_predicates":["tenant_id"]}`, types.StatusHardFail, "does not filter on tenant_id"},
+		{"file reader not allowed", "SELECT * FROM read_csv('/etc/passwd')", `{"allowed_tables":["orders"]}`, types.StatusHardFail, "read_csv('/etc/passwd') cannot be resolved"},
+		{"locking read", "SELECT * FROM orders FOR UPDATE", `{}`, types.StatusHardFail, "SELECT FOR UPDATE is not allowed"},
+		{"locking read allowed", "SELECT * FROM orders FOR UPDATE", `{"allow":["select_for_update"]}`, types.StatusPass, ""},
+		{"other fences ignored", "```python\nprint(1)\n```\n```sql\nSELECT 1\n```", `{}`, types.StatusPass, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluator.Evaluate(trace(tt.message), &types.Assertion{AssertionID: "a", Type: types.TypeSQLSafe, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if !strings.Contains(result.Explanation, tt.wantText) {
+				t.Errorf("explanation %q does not contain %q", result.Explanation, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,522 @@
+// Package sqlcheck statically analyzes SQL text: it splits statements,
+// classifies each as a read or a write, lists the tables it references, and
+// finds the columns each query block's WHERE clause mentions. It needs no
+// database and tolerates dialect differences by reading SQL structurally
+// rather than against one grammar.
+package sqlcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Statement kinds beyond the leading keyword.
+const (
+	// KindSelectInto is a SELECT ... INTO, which creates a table.
+	KindSelectInto = "select_into"
+	// KindSelectForUpdate is a locking read: SELECT ... FOR UPDATE or FOR
+	// SHARE, or MySQL's LOCK IN SHARE MODE. It blocks other writers.
+	KindSelectForUpdate = "select_for_update"
+)
+
+// queryKinds are the block kinds whose table references must all resolve.
+var queryKinds = map[string]bool{
+	"select": true, KindSelectInto: true, KindSelectForUpdate: true,
+	"insert": true, "update": true, "delete": true, "merge": true, "replace": true,
+}
+
+// readKinds are the statement kinds that cannot change data or schema.
+var readKinds = map[string]bool{
+	"select": true, "values": true, "show": true, "describe": true, "desc": true, "table": true,
+}
+
+// KindGroups maps a group name to the statement kinds it covers, so an
+// allowlist may name "dml" or "ddl" rather than each keyword.
+var KindGroups = map[string][]string{
+	"dml": {"insert", "update", "delete", "merge", "replace", "upsert"},
+	"ddl": {"create", "alter", "drop", "truncate", "rename", "comment", KindSelectInto},
+	"dcl": {"grant", "revoke"},
+}
+
+// knownKinds are the leading keywords recognized as statements.
+var knownKinds = map[string]bool{
+	"select": true, "values": true, "show": true, "describe": true, "desc": true, "table": true,
+	"insert": true, "update": true, "delete": true, "merge": true, "replace": true, "upsert": true,
+	"create": true, "alter": true, "drop": true, "truncate": true, "rename": true, "comment": true,
+	"grant": true, "revoke": true,
+	"copy": true, "call": true, "exec": true, "execute": true, "pragma": true, "attach": true, "detach": true,
+	"set": true, "begin": true, "start": true, "commit": true, "rollback": true, "savepoint": true, "release": true,
+	"lock": true, "unlock": true, "vacuum": true, "analyze": true, "load": true, "use": true, "do": true,
+	"reindex": true, "cluster": true, "refresh": true, "import": true, "export": true, "kill": true,
+}
+
+// IsRead reports whether statements of kind cannot change data or schema.
+func IsRead(kind string) bool { return readKinds[kind] }
+
+// IsKind reports whether kind is a recognized statement kind.
+func IsKind(kind string) bool {
+	return knownKinds[kind] || kind == KindSelectInto || kind == KindSelectForUpdate
+}
+
+// SyntaxError is a lexical or structural error at a 1-based line and column.
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Statement is one analyzed SQL statement.
+type Statement struct {
+	// Text is the statement as written, without the trailing semicolon.
+	Text string
+	// Kind is the lower-cased leading keyword, after WITH and EXPLAIN.
+	Kind string
+	// Kinds lists every statement kind in the statement, including
+	// data-modifying CTEs and the statement an EXPLAIN wraps.
+	Kinds []string
+	// Tables lists the tables referenced, CTEs excluded, sorted.
+	Tables []string
+	// Unresolved lists table references that are not plain names, such as
+	// FROM (users), a table-valued function call, or a string or parameter
+	// where a table belongs, as written. Tables may be incomplete when it is not empty.
+	Unresolved []string
+	// Blocks are the query blocks: the statement and each subquery, CTE,
+	// and set-operation branch.
+	Blocks []Block
+}
+
+// Block is a query block: one SELECT, UPDATE, DELETE, or other statement
+// body with its own FROM and WHERE.
+type Block struct {
+	Kind string
+	// Tables lists the tables the block reads or writes directly.
+	Tables []string
+	// HasWhere is set when the block has a WHERE clause.
+	HasWhere bool
+	// WhereColumns lists the column names its WHERE clause mentions,
+	// unqualified and lower-cased unless quoted. Subqueries are excluded.
+	WhereColumns map[string]bool
+	// FilterColumns lists the WHERE columns that every row kept is filtered
+	// on: those in a top-level AND conjunct with no top-level OR. In
+	// a = 1 OR b = 2, neither is; in a = 1 AND (b = 2 OR c = 3), only a is.
+	FilterColumns map[string]bool
+	// Unresolved lists the block's table references that are not plain
+	// names; see Statement.Unresolved.
+	Unresolved []string
+}
+
+// Analyze splits sql into statements and analyzes each.
+func Analyze(sql string) ([]Statement, error) {
+	toks, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	var stmts []Statement
+	begin := 0
+	for i := 0; i <= len(toks); i++ {
+		if i < len(toks) && !(toks[i].kind == tokPunct && toks[i].text == ";") {
+			continue
+		}
+		if i > begin {
+			st, err := analyzeStatement(sql, toks[begin:i])
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, st)
+		}
+		begin = i + 1
+	}
+	return stmts, nil
+}
+
+// item is a token or a parenthesized group of items.
+type item struct {
+	tok   token
+	group []item // non-nil for a group
+	close int    // position of a group's ')'
+}
+
+func (it item) isGroup() bool { return it.group != nil }
+
+// nest builds the item tree of toks, checking that parentheses balance.
+func nest(sql string, toks []token) ([]item, error) {
+	stack := [][]item{{}}
+	var opens []token
+	for _, t := range toks {
+		switch {
+		case t.kind == tokPunct && t.text == "(":
+			stack = append(stack, []item{})
+			opens = append(opens, t)
+		case t.kind == tokPunct && t.text == ")":
+			if len(opens) == 0 {
+				return nil, posError(sql, t.pos, "unmatched ')'")
+			}
+			inner := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1] = append(stack[len(stack)-1], item{tok: opens[len(opens)-1], group: inner, close: t.pos})
+			opens = opens[:len(opens)-1]
+		default:
+			stack[len(stack)-1] = append(stack[len(stack)-1], item{tok: t})
+		}
+	}
+	if len(opens) > 0 {
+		return nil, posError(sql, opens[len(opens)-1].pos, "'(' was never closed")
+	}
+	return stack[0], nil
+}
+
+func analyzeStatement(sql string, toks []token) (Statement, error) {
+	items, err := nest(sql, toks)
+	if err != nil {
+		return Statement{}, err
+	}
+	first, last := toks[0], toks[len(toks)-1]
+	st := Statement{Text: sql[first.pos : last.pos+len(last.raw)]}
+	if first.kind != tokWord {
+		return st, posError(sql, first.pos, fmt.Sprintf("expected a statement keyword, found %q", first.raw))
+	}
+
+	a := &analyzer{sql: sql, ctes: make(map[string]bool), tables: make(map[string]bool), kinds: make(map[string]bool)}
+	st.Kind = a.statement(items)
+	if !knownKinds[st.Kind] {
+		return st, posError(sql, first.pos, fmt.Sprintf("unrecognized statement %q", first.raw))
+	}
+	st.Blocks = a.blocks
+	for _, b := range a.blocks {
+		st.Unresolved = append(st.Unresolved, b.Unresolved...)
+	}
+	for k := range a.kinds {
+		st.Kinds = append(st.Kinds, k)
+	}
+	sort.Strings(st.Kinds)
+	for t := range a.tables {
+		st.Tables = append(st.Tables, t)
+	}
+	sort.Strings(st.Tables)
+	return st, nil
+}
+
+type analyzer struct {
+	sql    string
+	ctes   map[string]bool
+	tables map[string]bool
+	kinds  map[string]bool
+	blocks []Block
+}
+
+// statement analyzes a statement or subquery body: a WITH clause, then
+// blocks split at each SELECT that does not start the body (INSERT ...
+// SELECT, UNION SELECT, CREATE ... AS SELECT). It returns the lower-cased
+// keyword of the main statement.
+func (a *analyzer) statement(items []item) string {
+	i := 0
+	if i < len(items) && !items[i].isGroup() && items[i].tok.isWord("EXPLAIN") {
+		i++
+		for i < len(items) && !items[i].isGroup() && items[i].tok.isWord("ANALYZE", "VERBOSE", "QUERY", "PLAN") {
+			i++
+		}
+	}
+	if i < len(items) && !items[i].isGroup() && items[i].tok.isWord("WITH") {
+		i = a.with(items, i+1)
+	}
+	if i >= len(items) || items[i].isGroup() {
+		return ""
+	}
+	kind := strings.ToLower(items[i].tok.text)
+	begin := i
+	for j := i + 1; j <= len(items); j++ {
+		if j < len(items) && (items[j].isGroup() || !items[j].tok.isWord("SELECT")) {
+			continue
+		}
+		a.block(items[begin:j])
+		begin = j
+	}
+	return kind
+}
+
+// with records the CTE names of a WITH clause starting at items[i] and
+// analyzes their bodies, returning the index of the main statement.
+func (a *analyzer) with(items []item, i int) int {
+	if i < len(items) && items[i].tok.isWord("RECURSIVE") {
+		i++
+	}
+	for ; i < len(items); i++ {
+		it := items[i]
+		switch {
+		case it.isGroup():
+			if startsQuery(it.group) {
+				a.statement(it.group)
+			}
+		case it.tok.isWord("SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "VALUES", "TABLE"):
+			return i
+		case it.tok.isWord("AS", "NOT", "MATERIALIZED") || (it.tok.kind == tokPunct && it.tok.text == ","):
+		case it.tok.isName() && i+1 < len(items) && (items[i+1].tok.isWord("AS") || items[i+1].isGroup()):
+			a.ctes[it.tok.name()] = true
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// startsQuery reports whether a group holds a statement rather than an
+// expression or column list.
+func startsQuery(items []item) bool {
+	return len(items) > 0 && !items[0].isGroup() &&
+		items[0].tok.isWord("SELECT", "WITH", "VALUES", "INSERT", "UPDATE", "DELETE", "MERGE", "TABLE")
+}
+
+// clause keywords that end a FROM list or WHERE clause.
+var clauseWords = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true, "ON": true, "SET": true, "VALUES": true,
+	"RETURNING": true, "WINDOW": true, "FETCH": true, "FOR": true, "SELECT": true, "QUALIFY": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "NATURAL": true,
+	"OUTER": true, "USING": true, "INTO": true, "FROM": true, "AS": true, "PARTITION": true,
+}
+
+// block analyzes one query block at the top level of items.
+func (a *analyzer) block(items []item) {
+	if len(items) == 0 {
+		return
+	}
+	b := Block{WhereColumns: make(map[string]bool), FilterColumns: make(map[string]bool)}
+	if !items[0].isGroup() {
+		b.Kind = strings.ToLower(items[0].tok.text)
+	}
+	tables := make(map[string]bool)
+	var clause string
+	var where []item
+	expectTable := false
+	for i := 0; i < len(items); i++ {
+		it := items[i]
+		if it.isGroup() {
+			if clause == "WHERE" {
+				where = append(where, it)
+			}
+			switch {
+			case startsQuery(it.group):
+				a.statement(it.group)
+			case clause == "WHERE":
+				a.whereColumns(it.group, b.WhereColumns)
+			default:
+				if expectTable && queryKinds[b.Kind] && (clause == "FROM" || clause == "JOIN" || clause == "UPDATE" || clause == "DELETE") {
+					b.Unresolved = append(b.Unresolved, a.sql[it.tok.pos:it.close+1])
+				}
+				a.nested(it.group)
+			}
+			expectTable = false
+			continue
+		}
+		t := it.tok
+		switch {
+		case t.isWord("DELETE") && i == 0:
+			// T-SQL and Oracle allow DELETE table without FROM; MySQL's
+			// DELETE t1 FROM t1 JOIN t2 names the target first.
+			clause = "DELETE"
+			expectTable = i+1 < len(items) && !items[i+1].tok.isWord("FROM")
+			continue
+		case (t.isWord("FOR") && i+1 < len(items) && items[i+1].tok.isWord("UPDATE", "SHARE", "NO", "KEY")) ||
+			(t.isWord("LOCK") && i+1 < len(items) && items[i+1].tok.isWord("IN")):
+			if b.Kind == "select" {
+				b.Kind = KindSelectForUpdate
+			}
+			clause, expectTable = t.text, false
+			continue
+		case t.isWord("FROM", "JOIN", "UPDATE", "INTO", "TABLE", "USING"):
+			clause = t.text
+			expectTable = true
+			if t.isWord("INTO") && b.Kind == "select" {
+				b.Kind = KindSelectInto
+			}
+			if t.isWord("UPDATE") && i > 0 {
+				// FOR UPDATE or ON DUPLICATE KEY UPDATE.
+				expectTable = false
+			}
+			continue
+		case t.isWord("WHERE"):
+			clause, b.HasWhere, expectTable = "WHERE", true, false
+			continue
+		case t.kind == tokWord && clauseWords[t.text]:
+			clause, expectTable = t.text, false
+			continue
+		case t.isWord("IF", "NOT", "EXISTS", "ONLY", "LATERAL", "LOW_PRIORITY", "IGNORE", "QUICK", "TEMPORARY", "TEMP"):
+			if clause == "WHERE" {
+				where = append(where, it)
+			}
+			continue
+		case t.kind == tokPunct && t.text == ",":
+			if clause == "FROM" || clause == "USING" || clause == "TABLE" {
+				expectTable = true
+			}
+			continue
+		}
+		if expectTable && queryKinds[b.Kind] && (t.kind == tokString || (t.kind == tokParam && clause != "INTO")) {
+			b.Unresolved = append(b.Unresolved, t.raw)
+			expectTable = false
+			continue
+		}
+		if expectTable && t.isName() {
+			start, name := t.pos, t.name()
+			for i+2 < len(items) && !items[i+1].isGroup() && items[i+1].tok.text == "." && items[i+2].tok.isName() {
+				name += "." + items[i+2].tok.name()
+				i += 2
+			}
+			expectTable = false
+			if i+1 < len(items) && items[i+1].isGroup() && clause != "INTO" && clause != "TABLE" {
+				// A table-valued function, such as dblink or read_csv, can
+				// read any table or file.
+				if queryKinds[b.Kind] {
+					b.Unresolved = append(b.Unresolved, a.sql[start:items[i+1].close+1])
+				}
+				continue
+			}
+			if !a.ctes[name] {
+				tables[name] = true
+				a.tables[name] = true
+			}
+			continue
+		}
+		if clause == "WHERE" {
+			where = append(where, it)
+			addColumn(items, i, b.WhereColumns)
+		}
+	}
+	filterColumns(where, b.FilterColumns)
+	for name := range tables {
+		b.Tables = append(b.Tables, name)
+	}
+	sort.Strings(b.Tables)
+	if b.Kind != "" {
+		a.kinds[b.Kind] = true
+	}
+	a.blocks = append(a.blocks, b)
+}
+
+// nested looks for subqueries in a group that is not itself one, such as
+// function arguments or an IN list.
+func (a *analyzer) nested(items []item) {
+	for _, it := range items {
+		if !it.isGroup() {
+			continue
+		}
+		if startsQuery(it.group) {
+			a.statement(it.group)
+		} else {
+			a.nested(it.group)
+		}
+	}
+}
+
+// whereColumns adds the names in a WHERE clause group to cols, analyzing
+// subqueries as their own blocks.
+func (a *analyzer) whereColumns(items []item, cols map[string]bool) {
+	for i, it := range items {
+		switch {
+		case it.isGroup() && startsQuery(it.group):
+			a.statement(it.group)
+		case it.isGroup():
+			a.whereColumns(it.group, cols)
+		default:
+			addColumn(items, i, cols)
+		}
+	}
+}
+
+// filterColumns adds to cols the columns of a WHERE clause's top-level AND
+// conjuncts that have no top-level OR, reading a conjunct that is wholly
+// parenthesized the same way. Columns in function arguments count; those
+// in other groups and in subqueries do not.
+func filterColumns(items []item, cols map[string]bool) {
+	for _, c := range splitWord(items, "AND") {
+		if len(c) == 1 && c[0].isGroup() && !startsQuery(c[0].group) {
+			filterColumns(c[0].group, cols)
+			continue
+		}
+		if len(splitWord(c, "OR")) > 1 {
+			continue
+		}
+		for i, it := range c {
+			switch {
+			case !it.isGroup():
+				addColumn(c, i, cols)
+			case i > 0 && isFunctionName(c[i-1]) && !startsQuery(it.group):
+				argColumns(it.group, cols)
+			}
+		}
+	}
+}
+
+// splitWord splits items at each top-level occurrence of the keyword word,
+// outside CASE ... END and, for AND, the one that belongs to a BETWEEN.
+func splitWord(items []item, word string) [][]item {
+	var parts [][]item
+	begin, depth, between := 0, 0, false
+	for i, it := range items {
+		switch {
+		case it.isGroup():
+		case it.tok.isWord("CASE"):
+			depth++
+		case it.tok.isWord("END") && depth > 0:
+			depth--
+		case it.tok.isWord("BETWEEN"):
+			between = true
+		case it.tok.isWord(word) && depth == 0:
+			if word == "AND" && between {
+				between = false
+				continue
+			}
+			parts = append(parts, items[begin:i])
+			begin = i + 1
+		}
+	}
+	return append(parts, items[begin:])
+}
+
+// isFunctionName reports whether it names a function when a group follows.
+func isFunctionName(it item) bool {
+	return !it.isGroup() && it.tok.isName() && !(it.tok.kind == tokWord && exprWords[it.tok.text])
+}
+
+// argColumns adds the columns in function arguments to cols.
+func argColumns(items []item, cols map[string]bool) {
+	for i, it := range items {
+		switch {
+		case !it.isGroup():
+			addColumn(items, i, cols)
+		case !startsQuery(it.group):
+			argColumns(it.group, cols)
+		}
+	}
+}
+
+// exprWords are keywords that appear in WHERE expressions and are never
+// column names there.
+var exprWords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true, "IN": true, "LIKE": true, "ILIKE": true,
+	"BETWEEN": true, "EXISTS": true, "TRUE": true, "FALSE": true, "CASE": true, "WHEN": true, "THEN": true,
+	"ELSE": true, "END": true, "ANY": true, "ALL": true, "SOME": true, "ESCAPE": true, "DISTINCT": true,
+	"INTERVAL": true, "CAST": true, "COLLATE": true, "SIMILAR": true, "REGEXP": true, "RLIKE": true,
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "CURRENT_USER": true,
+}
+
+// addColumn adds items[i] to cols when it names a column: qualifiers such
+// as the o of o.id, function names, and expression keywords are skipped.
+func addColumn(items []item, i int, cols map[string]bool) {
+	t := items[i].tok
+	if !t.isName() || (t.kind == tokWord && exprWords[t.text]) {
+		return
+	}
+	if i+1 < len(items) {
+		next := items[i+1]
+		if next.isGroup() || (next.tok.kind == tokPunct && next.tok.text == ".") {
+			return
+		}
+	}
+	cols[t.name()] = true
+}
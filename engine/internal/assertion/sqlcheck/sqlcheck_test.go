@@ -0,0 +1,182 @@
+package sqlcheck
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		sql        string
+		wantKind   string
+		wantKinds  []string
+		wantTables []string
+	}{
+		{"SELECT id, name FROM users WHERE tenant_id = $1", "select", []string{"select"}, []string{"users"}},
+		{"select * from public.orders o join Customers c on c.id = o.customer_id", "select", []string{"select"}, []string{"customers", "public.orders"}},
+		{`SELECT * FROM "Orders", items AS i, generate_series(1, 3) g`, "select", []string{"select"}, []string{"Orders", "items"}},
+		{"WITH recent AS (SELECT * FROM orders WHERE created > now() - interval '1 day') SELECT count(*) FROM recent", "select", []string{"select"}, []string{"orders"}},
+		{"SELECT a FROM t1 UNION ALL SELECT a FROM t2", "select", []string{"select"}, []string{"t1", "t2"}},
+		{"SELECT * FROM users WHERE id IN (SELECT user_id FROM bans)", "select", []string{"select"}, []string{"bans", "users"}},
+		{"INSERT INTO audit (a, b) SELECT a, b FROM events", "insert", []string{"insert", "select"}, []string{"audit", "events"}},
+		{"UPDATE accounts SET balance = 0 WHERE id = 1", "update", []string{"update"}, []string{"accounts"}},
+		{"DELETE FROM sessions", "delete", []string{"delete"}, []string{"sessions"}},
+		{"WITH gone AS (DELETE FROM t RETURNING *) SELECT * FROM gone", "select", []string{"delete", "select"}, []string{"t"}},
+		{"DROP TABLE IF EXISTS a, b", "drop", []string{"drop"}, []string{"a", "b"}},
+		{"CREATE TABLE copy AS SELECT * FROM src", "create", []string{"create", "select"}, []string{"copy", "src"}},
+		{"SELECT * INTO backup FROM users", "select", []string{KindSelectInto}, []string{"backup", "users"}},
+		{"EXPLAIN ANALYZE DELETE FROM logs", "delete", []string{"delete"}, []string{"logs"}},
+		{"SELECT * FROM t FOR UPDATE", "select", []string{KindSelectForUpdate}, []string{"t"}},
+		{"SELECT * FROM t WHERE id = 1 LOCK IN SHARE MODE", "select", []string{KindSelectForUpdate}, []string{"t"}},
+		{"DELETE users WHERE id = 1", "delete", []string{"delete"}, []string{"users"}},
+		{"DELETE t1 FROM t1 JOIN t2 ON t1.id = t2.id", "delete", []string{"delete"}, []string{"t1", "t2"}},
+		{"SELECT * FROM a JOIN b USING (id)", "select", []string{"select"}, []string{"a", "b"}},
+		{"SELECT 'DROP TABLE x; --' AS s -- DELETE FROM y", "select", []string{"select"}, nil},
+	}
+	for _, tt := range tests {
+		stmts, err := Analyze(tt.sql)
+		if err != nil {
+			t.Errorf("Analyze(%q): %v", tt.sql, err)
+			continue
+		}
+		if len(stmts) != 1 {
+			t.Errorf("Analyze(%q) = %d statements, want 1", tt.sql, len(stmts))
+			continue
+		}
+		st := stmts[0]
+		if st.Kind != tt.wantKind || !reflect.DeepEqual(st.Kinds, tt.wantKinds) || !reflect.DeepEqual(st.Tables, tt.wantTables) {
+			t.Errorf("Analyze(%q) = kind %q, kinds %v, tables %v; want %q, %v, %v",
+				tt.sql, st.Kind, st.Kinds, st.Tables, tt.wantKind, tt.wantKinds, tt.wantTables)
+		}
+	}
+}
+
+func TestAnalyze_Statements(t *testing.T) {
+	stmts, err := Analyze("SELECT 1;\n\nselect 2 ; ;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || stmts[0].Text != "SELECT 1" || stmts[1].Text != "select 2" {
+		t.Errorf("statements = %+v", stmts)
+	}
+}
+
+func TestAnalyze_WhereColumns(t *testing.T) {
+	stmts, err := Analyze(`SELECT * FROM orders o WHERE o.tenant_id = :t AND (status = 'open' OR lower("Region") LIKE 'eu%')
+		AND customer_id IN (SELECT id FROM customers WHERE active)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := stmts[0].Blocks
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	var outer, inner Block
+	for _, b := range blocks {
+		if len(b.Tables) == 1 && b.Tables[0] == "orders" {
+			outer = b
+		} else {
+			inner = b
+		}
+	}
+	if got := keys(outer.WhereColumns); !reflect.DeepEqual(got, []string{"Region", "customer_id", "status", "tenant_id"}) {
+		t.Errorf("outer where columns = %v", got)
+	}
+	if got := keys(inner.WhereColumns); !inner.HasWhere || !reflect.DeepEqual(got, []string{"active"}) {
+		t.Errorf("inner where columns = %v", got)
+	}
+}
+
+func TestAnalyze_FilterColumns(t *testing.T) {
+	tests := []struct {
+		where string
+		want  []string
+	}{
+		{"tenant_id = 5", []string{"tenant_id"}},
+		{"1=1 OR tenant_id = 5", nil},
+		{"tenant_id = 5 OR 1=1", nil},
+		{"(tenant_id = 5 OR 1=1) AND status = 'x'", []string{"status"}},
+		{"(tenant_id = 5 AND a) AND b", []string{"a", "b", "tenant_id"}},
+		{"created BETWEEN 1 AND 2 AND lower(region) = 'eu'", []string{"created", "region"}},
+		{"CASE WHEN a OR b THEN 1 END = 1 AND tenant_id = 2", []string{"a", "b", "tenant_id"}},
+		{"o.tenant_id = 1 AND id IN (SELECT x FROM y)", []string{"id", "tenant_id"}},
+	}
+	for _, tt := range tests {
+		stmts, err := Analyze("SELECT * FROM orders o WHERE " + tt.where + " ORDER BY id")
+		if err != nil {
+			t.Errorf("%s: %v", tt.where, err)
+			continue
+		}
+		// Subqueries are recorded before the block holding them.
+		blocks := stmts[0].Blocks
+		if got := keys(blocks[len(blocks)-1].FilterColumns); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WHERE %s: filter columns = %v, want %v", tt.where, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze_Unresolved(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT * FROM (users)", []string{"(users)"}},
+		{"SELECT * FROM 'users'", []string{"'users'"}},
+		{"DELETE FROM $1 WHERE id = 2", []string{"$1"}},
+		{"SELECT * FROM (SELECT * FROM users) u", nil},
+		{"SELECT * FROM dblink('host=x', 'SELECT * FROM secrets') AS x(a int)", []string{"dblink('host=x', 'SELECT * FROM secrets')"}},
+		{"SELECT * FROM t JOIN read_csv('/etc/passwd') f ON true", []string{"read_csv('/etc/passwd')"}},
+		{"SELECT * FROM pg_catalog.pg_ls_dir('.')", []string{"pg_catalog.pg_ls_dir('.')"}},
+		{"SELECT x INTO @v FROM users", nil},
+	}
+	for _, tt := range tests {
+		stmts, err := Analyze(tt.sql)
+		if err != nil {
+			t.Errorf("Analyze(%q): %v", tt.sql, err)
+			continue
+		}
+		if got := stmts[0].Unresolved; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Analyze(%q).Unresolved = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze_Errors(t *testing.T) {
+	tests := []struct {
+		sql      string
+		wantLine int
+		wantMsg  string
+	}{
+		{"SELECT * FROM t WHERE a = 'x", 1, "unterminated string"},
+		{"SELECT (1", 1, "never closed"},
+		{"SELECT 1)\n", 1, "unmatched ')'"},
+		{"SELECT 1;\nHere is the query", 2, "unrecognized statement"},
+		{"/* open", 1, "unterminated comment"},
+		{"SELECT 1 /*!50000 ; DROP TABLE users */", 1, "executable comment"},
+		{"SELECT 1 /*M! ; DROP TABLE users */", 1, "executable comment"},
+		{"(SELECT 1)", 1, "expected a statement keyword"},
+	}
+	for _, tt := range tests {
+		_, err := Analyze(tt.sql)
+		var syn *SyntaxError
+		if !errors.As(err, &syn) {
+			t.Errorf("Analyze(%q) = %v, want a syntax error", tt.sql, err)
+			continue
+		}
+		if syn.Line != tt.wantLine || !strings.Contains(syn.Message, tt.wantMsg) {
+			t.Errorf("Analyze(%q) = %v, want line %d containing %q", tt.sql, syn, tt.wantLine, tt.wantMsg)
+		}
+	}
+}
+
+func keys(m map[string]bool) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
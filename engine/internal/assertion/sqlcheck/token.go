@@ -0,0 +1,176 @@
+package sqlcheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokWord   tokenKind = iota // keyword or unquoted identifier, upper-cased in text
+	tokIdent                   // quoted identifier, text unquoted
+	tokString                  // string literal
+	tokNumber                  // numeric literal
+	tokParam                   // bind parameter: ?, $1, :name, @name
+	tokPunct                   // ( ) , ; . and operators
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// raw is the token as written.
+	raw string
+	pos int
+}
+
+// isWord reports whether t is one of the given upper-case words.
+func (t token) isWord(words ...string) bool {
+	if t.kind != tokWord {
+		return false
+	}
+	for _, w := range words {
+		if t.text == w {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns t as an identifier: quoted identifiers keep their case,
+// unquoted ones are lower-cased.
+func (t token) name() string {
+	if t.kind == tokIdent {
+		return t.text
+	}
+	return strings.ToLower(t.text)
+}
+
+func (t token) isName() bool {
+	return t.kind == tokWord || t.kind == tokIdent
+}
+
+// tokenize splits SQL into tokens, dropping whitespace and comments. A
+// MySQL executable comment is an error rather than a comment.
+func tokenize(sql string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case strings.HasPrefix(sql[i:], "/*!") || strings.HasPrefix(sql[i:], "/*M!"):
+			// MySQL and MariaDB execute the contents of these comments, so
+			// dropping them would hide SQL that runs.
+			return nil, posError(sql, i, "executable comment (/*! ... */) is not allowed")
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, posError(sql, i, "unterminated comment")
+			}
+			i += end + 4
+		case c == '\'':
+			end, err := closeQuote(sql, i, '\'')
+			if err != nil {
+				return nil, posError(sql, i, "unterminated string literal")
+			}
+			toks = append(toks, token{kind: tokString, text: sql[i+1 : end-1], raw: sql[i:end], pos: start})
+			i = end
+		case c == '"' || c == '`':
+			end, err := closeQuote(sql, i, c)
+			if err != nil {
+				return nil, posError(sql, i, "unterminated quoted identifier")
+			}
+			text := strings.ReplaceAll(sql[i+1:end-1], string([]byte{c, c}), string(c))
+			toks = append(toks, token{kind: tokIdent, text: text, raw: sql[i:end], pos: start})
+			i = end
+		case dollarTag(sql, i) != "":
+			// Dollar-quoted string: $$...$$ or $tag$...$tag$.
+			tag := dollarTag(sql, i)
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				return nil, posError(sql, i, "unterminated dollar-quoted string")
+			}
+			i += len(tag) + end + len(tag)
+			toks = append(toks, token{kind: tokString, text: sql[start+len(tag) : i-len(tag)], raw: sql[start:i], pos: start})
+		case c == '?' || ((c == '$' || c == ':' || c == '@') && i+1 < len(sql) && (isDigit(sql[i+1]) || isWordStart(sql[i+1]))):
+			i++
+			for i < len(sql) && (isWordByte(sql[i])) {
+				i++
+			}
+			toks = append(toks, token{kind: tokParam, text: sql[start:i], raw: sql[start:i], pos: start})
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.' || sql[i] == 'e' || sql[i] == 'E') {
+				i++
+			}
+			toks = append(toks, token{kind: tokNumber, text: sql[start:i], raw: sql[start:i], pos: start})
+		case isWordStart(c):
+			for i < len(sql) && isWordByte(sql[i]) {
+				i++
+			}
+			toks = append(toks, token{kind: tokWord, text: strings.ToUpper(sql[start:i]), raw: sql[start:i], pos: start})
+		default:
+			i++
+			if i < len(sql) && twoCharOps[sql[start:i+1]] {
+				i++
+			}
+			toks = append(toks, token{kind: tokPunct, text: sql[start:i], raw: sql[start:i], pos: start})
+		}
+	}
+	return toks, nil
+}
+
+// closeQuote returns the index just past the quote q that closes the
+// literal opened at i; a doubled quote is an escaped one.
+func closeQuote(sql string, i int, q byte) (int, error) {
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] != q {
+			continue
+		}
+		if j+1 < len(sql) && sql[j+1] == q {
+			j++
+			continue
+		}
+		return j + 1, nil
+	}
+	return 0, fmt.Errorf("unterminated")
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+func isWordByte(c byte) bool { return isWordStart(c) || isDigit(c) || c == '$' }
+
+var twoCharOps = map[string]bool{"<>": true, "<=": true, ">=": true, "!=": true, "||": true, "::": true, "->": true}
+
+// dollarTag returns the opening tag of a dollar-quoted string at i, such as
+// "$$" or "$body$", or "".
+func dollarTag(sql string, i int) string {
+	if sql[i] != '$' {
+		return ""
+	}
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case sql[j] == '$':
+			return sql[i : j+1]
+		case !isWordStart(sql[j]) && !(j > i+1 && isDigit(sql[j])):
+			return ""
+		}
+	}
+	return ""
+}
+
+// posError reports msg at byte offset pos of sql as a line and column.
+func posError(sql string, pos int, msg string) *SyntaxError {
+	line := strings.Count(sql[:pos], "\n") + 1
+	col := pos - strings.LastIndexByte(sql[:pos], '\n')
+	return &SyntaxError{Line: line, Column: col, Message: msg}
+}
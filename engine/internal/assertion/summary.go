@@ -0,0 +1,70 @@
+package assertion
+
+import (
+	"strconv"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// summarize aggregates a batch's results. assertions and layers are the
+// batch's assertions and their layers; results are matched to them by
+// assertion ID.
+func summarize(assertions []types.Assertion, layers []int, results []types.AssertionResult, cacheLookups map[string]types.CacheCounts) *types.BatchSummary {
+	byID := make(map[string]int, len(assertions))
+	for i := range assertions {
+		if _, ok := byID[assertions[i].AssertionID]; !ok {
+			byID[assertions[i].AssertionID] = i
+		}
+	}
+
+	s := &types.BatchSummary{
+		Layers: make(map[string]types.StatusCounts),
+		Cache:  cacheLookups,
+	}
+	for i := range results {
+		r := &results[i]
+		s.Counts.Add(r.Status)
+		s.TotalCost += r.Cost
+		if worseFailure(r, s.WorstFailure) {
+			s.WorstFailure = &types.WorstFailure{
+				AssertionID: r.AssertionID,
+				Status:      r.Status,
+				Score:       r.Score,
+				Explanation: r.Explanation,
+			}
+		}
+
+		idx, ok := byID[r.AssertionID]
+		if !ok {
+			continue
+		}
+		layer := strconv.Itoa(layers[idx])
+		c := s.Layers[layer]
+		c.Add(r.Status)
+		s.Layers[layer] = c
+		for _, tag := range assertions[idx].Tags {
+			if s.Tags == nil {
+				s.Tags = make(map[string]types.StatusCounts)
+			}
+			c := s.Tags[tag]
+			c.Add(r.Status)
+			s.Tags[tag] = c
+		}
+	}
+	return s
+}
+
+// worseFailure reports whether r is a worse failure than w: any hard_fail
+// beats a soft_fail, and a lower score beats a higher one.
+func worseFailure(r *types.AssertionResult, w *types.WorstFailure) bool {
+	if r.Status != types.StatusHardFail && r.Status != types.StatusSoftFail {
+		return false
+	}
+	if w == nil {
+		return true
+	}
+	if r.Status != w.Status {
+		return r.Status == types.StatusHardFail
+	}
+	return r.Score < w.Score
+}
@@ -12,6 +12,9 @@ import (
 // stepFilterRegex matches patterns like steps[?name=='lookup_order'].result
 var stepFilterRegex = regexp.MustCompile(`^steps\[\?name=='([^']+)'\]\.(.+)$`)
 
+// roleFilterRegex matches messages[?role=='user'], optionally after a step filter.
+var roleFilterRegex = regexp.MustCompile(`^messages\[\?role=='([^']+)'\]$`)
+
 // ResolveTarget resolves a JSONPath-like target string against a trace.
 // Returns the resolved value as json.RawMessage, or error if not found.
 //
@@ -23,6 +26,12 @@ var stepFilterRegex = regexp.MustCompile(`^steps\[\?name=='([^']+)'\]\.(.+)$`)
 //   - "steps[?name=='<name>'].args" → first matching step's args
 //   - "steps[?name=='<name>'].result" → first matching step's result
 //   - "steps[?name=='<name>'].result.<field>" → nested field in step result
+//   - "steps[?name=='<name>'].messages" → the step's messages array
+//   - "steps[?name=='<name>'].messages[?role=='<role>']" → content of the step's
+//     messages with that role, joined by newlines
+//   - "steps[?name=='<name>'].error[.<field>]" → the step's error object or a field of it
+//   - "messages[?role=='<role>']" → content of every llm_call message with that
+//     role across the trace's steps, joined by newlines
 func ResolveTarget(trace *types.Trace, target string) (json.RawMessage, error) {
 	if target == "output" {
 		return trace.Output, nil
@@ -35,6 +44,13 @@ func ResolveTarget(trace *types.Trace, target string) (json.RawMessage, error) {
 		field := m[2]
 		return resolveStepField(trace, stepName, field)
 	}
+	if m := roleFilterRegex.FindStringSubmatch(target); m != nil {
+		var msgs []types.Message
+		for i := range trace.Steps {
+			msgs = append(msgs, trace.Steps[i].Messages...)
+		}
+		return joinRoleContent(msgs, m[1], "trace")
+	}
 	return nil, fmt.Errorf("unsupported target: %s", target)
 }
 
@@ -53,11 +69,11 @@ func ResolveTargetString(trace *types.Trace, target string) (string, error) {
 
 // resolveOutputField navigates dot-separated fields into trace.Output.
 func resolveOutputField(trace *types.Trace, fieldPath string) (json.RawMessage, error) {
-	var root map[string]json.RawMessage
-	if err := json.Unmarshal(trace.Output, &root); err != nil {
+	root, err := decodeObject(trace, trace.Output)
+	if err != nil {
 		return nil, fmt.Errorf("cannot parse output as object: %v", err)
 	}
-	return navigateDotPath(root, fieldPath, "output")
+	return navigateDotPath(trace, root, fieldPath, "output")
 }
 
 // resolveStepField finds the first step with the given name and navigates into args or result.
@@ -82,23 +98,45 @@ func resolveStepField(trace *types.Trace, stepName string, fieldPath string) (js
 		topRaw = step.Args
 	case "result":
 		topRaw = step.Result
+	case "messages":
+		if len(parts) > 1 {
+			return nil, fmt.Errorf("unsupported step field: %s", fieldPath)
+		}
+		return json.Marshal(step.Messages)
+	case "error":
+		if step.Error == nil {
+			return nil, fmt.Errorf("step %s has no error", stepName)
+		}
+		raw, err := json.Marshal(step.Error)
+		if err != nil || len(parts) == 1 {
+			return raw, err
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		return navigateDotPath(nil, obj, parts[1], fmt.Sprintf("steps[?name=='%s'].error", stepName))
 	default:
-		return nil, fmt.Errorf("unsupported step field: %s (must be args or result)", topField)
+		if m := roleFilterRegex.FindStringSubmatch(fieldPath); m != nil {
+			return joinRoleContent(step.Messages, m[1], fmt.Sprintf("step %s", stepName))
+		}
+		return nil, fmt.Errorf("unsupported step field: %s (must be args, result, messages, or error)", topField)
 	}
 
 	if len(parts) == 1 {
 		return topRaw, nil
 	}
 
-	var nested map[string]json.RawMessage
-	if err := json.Unmarshal(topRaw, &nested); err != nil {
+	nested, err := decodeObject(trace, topRaw)
+	if err != nil {
 		return nil, fmt.Errorf("cannot parse step %s.%s as object: %v", stepName, topField, err)
 	}
-	return navigateDotPath(nested, parts[1], fmt.Sprintf("steps[?name=='%s'].%s", stepName, topField))
+	return navigateDotPath(trace, nested, parts[1], fmt.Sprintf("steps[?name=='%s'].%s", stepName, topField))
 }
 
-// navigateDotPath traverses a map following a dot-separated key path.
-func navigateDotPath(root map[string]json.RawMessage, path string, parentDesc string) (json.RawMessage, error) {
+// navigateDotPath traverses a map following a dot-separated key path. Nested
+// objects are decoded through trace's shared view when trace is non-nil.
+func navigateDotPath(trace *types.Trace, root map[string]json.RawMessage, path string, parentDesc string) (json.RawMessage, error) {
 	parts := strings.SplitN(path, ".", 2)
 	key := parts[0]
 
@@ -111,9 +149,24 @@ func navigateDotPath(root map[string]json.RawMessage, path string, parentDesc st
 		return val, nil
 	}
 
-	var nested map[string]json.RawMessage
-	if err := json.Unmarshal(val, &nested); err != nil {
+	nested, err := decodeObject(trace, val)
+	if err != nil {
 		return nil, fmt.Errorf("cannot parse %s.%s as object: %v", parentDesc, key, err)
 	}
-	return navigateDotPath(nested, parts[1], parentDesc+"."+key)
+	return navigateDotPath(trace, nested, parts[1], parentDesc+"."+key)
+}
+
+// joinRoleContent returns, as a JSON string, the content of msgs with role
+// joined by newlines. desc names the messages' owner in the not-found error.
+func joinRoleContent(msgs []types.Message, role, desc string) (json.RawMessage, error) {
+	var parts []string
+	for _, m := range msgs {
+		if m.Role == role {
+			parts = append(parts, m.Content)
+		}
+	}
+	if parts == nil {
+		return nil, fmt.Errorf("no %s messages in %s", role, desc)
+	}
+	return json.Marshal(strings.Join(parts, "\n"))
 }
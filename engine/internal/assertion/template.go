@@ -0,0 +1,196 @@
+package assertion
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// placeholderRe matches a "{{param}}" placeholder in a template spec string.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// paramNameRe matches a valid template parameter name.
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// TemplateRegistry holds named assertion templates. It is safe for
+// concurrent use.
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]*compiledTemplate
+}
+
+type compiledTemplate struct {
+	types.AssertionTemplate
+	spec     any
+	defaults map[string]any
+}
+
+// NewTemplateRegistry returns an empty template registry.
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{templates: make(map[string]*compiledTemplate)}
+}
+
+// Register validates t and adds it, replacing any template of the same name.
+// It reports whether a template was replaced.
+func (r *TemplateRegistry) Register(t types.AssertionTemplate) (bool, error) {
+	if t.Name == "" {
+		return false, fmt.Errorf("template missing required field: name")
+	}
+	if t.Type == "" {
+		return false, fmt.Errorf("template %q missing required field: type", t.Name)
+	}
+	ct := &compiledTemplate{AssertionTemplate: t, defaults: make(map[string]any)}
+	if err := json.Unmarshal(t.Spec, &ct.spec); err != nil {
+		return false, fmt.Errorf("template %q: invalid spec: %v", t.Name, err)
+	}
+	declared := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		if !paramNameRe.MatchString(p.Name) {
+			return false, fmt.Errorf("template %q: invalid parameter name %q", t.Name, p.Name)
+		}
+		if declared[p.Name] {
+			return false, fmt.Errorf("template %q: duplicate parameter %q", t.Name, p.Name)
+		}
+		declared[p.Name] = true
+		if len(p.Default) > 0 {
+			var v any
+			if err := json.Unmarshal(p.Default, &v); err != nil {
+				return false, fmt.Errorf("template %q: parameter %q: invalid default: %v", t.Name, p.Name, err)
+			}
+			ct.defaults[p.Name] = v
+		}
+	}
+	for _, m := range placeholderRe.FindAllStringSubmatch(string(t.Spec), -1) {
+		if !declared[m[1]] {
+			return false, fmt.Errorf("template %q: spec references undeclared parameter %q", t.Name, m[1])
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, replaced := r.templates[t.Name]
+	r.templates[t.Name] = ct
+	return replaced, nil
+}
+
+// Names returns the registered template names in sorted order.
+func (r *TemplateRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand replaces a template reference in a with the template's type and
+// spec, substituting a.Params. Assertions without a template are unchanged.
+func (r *TemplateRegistry) Expand(a *types.Assertion) error {
+	if a.Template == "" {
+		return nil
+	}
+	if a.Type != "" || (len(a.Spec) > 0 && string(a.Spec) != "null") {
+		return fmt.Errorf("assertion %q sets both template and type/spec", a.AssertionID)
+	}
+	r.mu.RLock()
+	ct, ok := r.templates[a.Template]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("assertion %q: unknown template %q", a.AssertionID, a.Template)
+	}
+
+	values := make(map[string]any, len(ct.Params))
+	for k, v := range ct.defaults {
+		values[k] = v
+	}
+	if len(a.Params) > 0 {
+		var given map[string]any
+		if err := json.Unmarshal(a.Params, &given); err != nil {
+			return fmt.Errorf("assertion %q: params must be an object: %v", a.AssertionID, err)
+		}
+		for k, v := range given {
+			if !hasParam(ct.Params, k) {
+				return fmt.Errorf("assertion %q: template %q has no parameter %q", a.AssertionID, a.Template, k)
+			}
+			values[k] = v
+		}
+	}
+	var missing []string
+	for _, p := range ct.Params {
+		if _, ok := values[p.Name]; !ok {
+			missing = append(missing, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("assertion %q: template %q missing required params: %s", a.AssertionID, a.Template, strings.Join(missing, ", "))
+	}
+
+	spec, err := json.Marshal(substitute(ct.spec, values))
+	if err != nil {
+		return fmt.Errorf("assertion %q: expand template %q: %v", a.AssertionID, a.Template, err)
+	}
+	a.Type = ct.Type
+	a.Spec = spec
+	return nil
+}
+
+// ExpandAll expands every template reference in assertions in place.
+func (r *TemplateRegistry) ExpandAll(assertions []types.Assertion) error {
+	for i := range assertions {
+		if err := r.Expand(&assertions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasParam(params []types.TemplateParam, name string) bool {
+	for _, p := range params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// substitute returns a copy of v with placeholders replaced from values. A
+// string that is exactly one placeholder takes the value itself, preserving
+// its JSON type.
+func substitute(v any, values map[string]any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = substitute(e, values)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = substitute(e, values)
+		}
+		return out
+	case string:
+		if m := placeholderRe.FindStringSubmatchIndex(v); m != nil && m[0] == 0 && m[1] == len(v) {
+			return values[v[m[2]:m[3]]]
+		}
+		return placeholderRe.ReplaceAllStringFunc(v, func(p string) string {
+			val := values[placeholderRe.FindStringSubmatch(p)[1]]
+			if s, ok := val.(string); ok {
+				return s
+			}
+			b, _ := json.Marshal(val)
+			return string(b)
+		})
+	default:
+		return v
+	}
+}
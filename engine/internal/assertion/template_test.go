@@ -0,0 +1,122 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func costUnderTemplate() types.AssertionTemplate {
+	return types.AssertionTemplate{
+		Name: "cost_under",
+		Type: types.TypeConstraint,
+		Spec: json.RawMessage(`{"field":"metadata.cost_usd","operator":"lte","value":"{{usd}}","soft":"{{soft}}"}`),
+		Params: []types.TemplateParam{
+			{Name: "usd"},
+			{Name: "soft", Default: json.RawMessage(`false`)},
+		},
+	}
+}
+
+func TestTemplateRegistry_Expand(t *testing.T) {
+	r := NewTemplateRegistry()
+	if _, err := r.Register(costUnderTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Register(types.AssertionTemplate{
+		Name:   "mentions",
+		Type:   types.TypeContent,
+		Spec:   json.RawMessage(`{"target":"output.message","check":"regex_match","value":"(?i){{ word }}s?","values":["{{word}}"]}`),
+		Params: []types.TemplateParam{{Name: "word"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		params   string
+		wantType string
+		wantSpec string
+		wantErr  string
+	}{
+		{"typed value and default", "cost_under", `{"usd":0.1}`, types.TypeConstraint, `{"field":"metadata.cost_usd","operator":"lte","soft":false,"value":0.1}`, ""},
+		{"override default", "cost_under", `{"usd":0.5,"soft":true}`, types.TypeConstraint, `{"field":"metadata.cost_usd","operator":"lte","soft":true,"value":0.5}`, ""},
+		{"embedded in string", "mentions", `{"word":"refund"}`, types.TypeContent, `{"check":"regex_match","target":"output.message","value":"(?i)refunds?","values":["refund"]}`, ""},
+		{"missing required", "cost_under", `{}`, "", "", "missing required params: usd"},
+		{"unknown param", "cost_under", `{"usd":1,"dollars":2}`, "", "", `has no parameter "dollars"`},
+		{"unknown template", "nope", `{}`, "", "", `unknown template "nope"`},
+		{"params not object", "cost_under", `[1]`, "", "", "params must be an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := types.Assertion{AssertionID: "a1", Template: tt.template, Params: json.RawMessage(tt.params)}
+			err := r.Expand(&a)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Type != tt.wantType || string(a.Spec) != tt.wantSpec {
+				t.Errorf("expanded to %s %s, want %s %s", a.Type, a.Spec, tt.wantType, tt.wantSpec)
+			}
+		})
+	}
+}
+
+func TestTemplateRegistry_ExpandedAssertionEvaluates(t *testing.T) {
+	r := NewTemplateRegistry()
+	if _, err := r.Register(costUnderTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	assertions := []types.Assertion{
+		{AssertionID: "cheap", Template: "cost_under", Params: json.RawMessage(`{"usd":0.1}`)},
+		{AssertionID: "plain", Type: types.TypeConstraint, Spec: json.RawMessage(`{"field":"metadata.cost_usd","operator":"lte","value":1}`)},
+	}
+	if err := r.ExpandAll(assertions); err != nil {
+		t.Fatal(err)
+	}
+	cost := 0.05
+	tr := &types.Trace{TraceID: "trc", Output: json.RawMessage(`{}`), Metadata: &types.TraceMetadata{CostUSD: &cost}}
+	result, err := NewPipeline(NewRegistry()).EvaluateBatch(tr, assertions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range result.Results {
+		if res.Status != types.StatusPass {
+			t.Errorf("%s: %s %s", res.AssertionID, res.Status, res.Explanation)
+		}
+	}
+}
+
+func TestTemplateRegistry_RegisterErrors(t *testing.T) {
+	tests := map[string]types.AssertionTemplate{
+		"missing required field: name": {Type: "content", Spec: json.RawMessage(`{}`)},
+		"missing required field: type": {Name: "t", Spec: json.RawMessage(`{}`)},
+		"invalid spec":                 {Name: "t", Type: "content", Spec: json.RawMessage(`{`)},
+		"invalid parameter name":       {Name: "t", Type: "content", Spec: json.RawMessage(`{}`), Params: []types.TemplateParam{{Name: "a-b"}}},
+		"duplicate parameter":          {Name: "t", Type: "content", Spec: json.RawMessage(`{}`), Params: []types.TemplateParam{{Name: "a"}, {Name: "a"}}},
+		"undeclared parameter \"x\"":   {Name: "t", Type: "content", Spec: json.RawMessage(`{"value":"{{x}}"}`)},
+		"invalid default":              {Name: "t", Type: "content", Spec: json.RawMessage(`{}`), Params: []types.TemplateParam{{Name: "a", Default: json.RawMessage(`{`)}}},
+	}
+	r := NewTemplateRegistry()
+	for want, tmpl := range tests {
+		if _, err := r.Register(tmpl); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Register(%+v) err = %v, want %q", tmpl, err, want)
+		}
+	}
+
+	replaced, err := r.Register(costUnderTemplate())
+	if err != nil || replaced {
+		t.Fatalf("first register: replaced=%v err=%v", replaced, err)
+	}
+	if replaced, _ = r.Register(costUnderTemplate()); !replaced {
+		t.Error("second register did not report replaced")
+	}
+}
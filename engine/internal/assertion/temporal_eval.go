@@ -0,0 +1,257 @@
+package assertion
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// TemporalEvaluator implements Layer 3 temporal-logic assertions: LTL-style
+// operators evaluated over a trace's step sequence.
+//
+//   - always(condition): every step satisfies condition
+//   - eventually(condition): some step satisfies condition
+//   - never(condition): no step satisfies condition; with after, no step
+//     satisfying condition follows a step satisfying after
+//   - until(condition, release): condition holds at every step before the
+//     first step satisfying release, and such a step exists
+type TemporalEvaluator struct{}
+
+// stepCondition is a predicate over one step. Every set field must match.
+type stepCondition struct {
+	Name      string `json:"name,omitempty"`
+	NameRegex string `json:"name_regex,omitempty"`
+	Type      string `json:"type,omitempty"`
+	// Args maps dot-paths into the step's args to required JSON values.
+	Args map[string]json.RawMessage `json:"args,omitempty"`
+	// Not negates the predicate.
+	Not bool `json:"not,omitempty"`
+
+	nameRe *regexp.Regexp
+	args   map[string]any
+}
+
+type temporalSpec struct {
+	Operator  string         `json:"operator"`
+	Condition *stepCondition `json:"condition"`
+	After     *stepCondition `json:"after,omitempty"`
+	Release   *stepCondition `json:"release,omitempty"`
+	// Recursive includes sub-trace steps in depth-first order.
+	Recursive bool `json:"recursive"`
+	Soft      bool `json:"soft"`
+}
+
+func (e *TemporalEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec temporalSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid temporal spec: %v", err))
+	}
+	if spec.Operator == "" {
+		return failResult(assertion, start, "temporal spec missing required field: operator")
+	}
+	if spec.Condition == nil {
+		return failResult(assertion, start, "temporal spec missing required field: condition")
+	}
+	for _, c := range []*stepCondition{spec.Condition, spec.After, spec.Release} {
+		if c == nil {
+			continue
+		}
+		if err := c.compile(); err != nil {
+			return failResult(assertion, start, fmt.Sprintf("invalid temporal condition: %v", err))
+		}
+	}
+
+	steps := temporalSteps(trace, spec.Recursive)
+	match := func(c *stepCondition, i int) bool { return c.matches(trace, steps[i]) }
+
+	var passed bool
+	var explanation string
+	switch spec.Operator {
+	case "always":
+		passed, explanation = true, fmt.Sprintf("always: all %d steps satisfy %s.", len(steps), spec.Condition)
+		for i := range steps {
+			if !match(spec.Condition, i) {
+				passed, explanation = false, fmt.Sprintf("always: step %d %q does not satisfy %s", i, steps[i].Name, spec.Condition)
+				break
+			}
+		}
+
+	case "eventually":
+		passed, explanation = false, fmt.Sprintf("eventually: no step of %d satisfies %s", len(steps), spec.Condition)
+		for i := range steps {
+			if match(spec.Condition, i) {
+				passed, explanation = true, fmt.Sprintf("eventually: step %d %q satisfies %s.", i, steps[i].Name, spec.Condition)
+				break
+			}
+		}
+
+	case "never":
+		passed, explanation = checkNever(steps, spec, match)
+
+	case "until":
+		if spec.Release == nil {
+			return failResult(assertion, start, "until requires 'release'")
+		}
+		passed, explanation = false, fmt.Sprintf("until: no step satisfies release %s", spec.Release)
+		for i := range steps {
+			if match(spec.Release, i) {
+				passed, explanation = true, fmt.Sprintf("until: %s held for all %d steps before step %d %q satisfied %s.", spec.Condition, i, i, steps[i].Name, spec.Release)
+				break
+			}
+			if !match(spec.Condition, i) {
+				explanation = fmt.Sprintf("until: step %d %q does not satisfy %s before release %s", i, steps[i].Name, spec.Condition, spec.Release)
+				break
+			}
+		}
+
+	default:
+		return failResult(assertion, start, fmt.Sprintf("unsupported temporal operator: %s (must be always, eventually, never, or until)", spec.Operator))
+	}
+
+	status, score := types.StatusPass, 1.0
+	if !passed {
+		status, score = types.StatusHardFail, 0.0
+		if spec.Soft {
+			status = types.StatusSoftFail
+		}
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       score,
+		Explanation: explanation,
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+func checkNever(steps []*types.Step, spec temporalSpec, match func(*stepCondition, int) bool) (bool, string) {
+	if spec.After == nil {
+		for i := range steps {
+			if match(spec.Condition, i) {
+				return false, fmt.Sprintf("never: step %d %q satisfies %s", i, steps[i].Name, spec.Condition)
+			}
+		}
+		return true, fmt.Sprintf("never: no step satisfies %s.", spec.Condition)
+	}
+	trigger := -1
+	for i := range steps {
+		if trigger >= 0 && match(spec.Condition, i) {
+			return false, fmt.Sprintf("never: step %d %q satisfies %s after step %d %q satisfied %s",
+				i, steps[i].Name, spec.Condition, trigger, steps[trigger].Name, spec.After)
+		}
+		if trigger < 0 && match(spec.After, i) {
+			trigger = i
+		}
+	}
+	return true, fmt.Sprintf("never: no step satisfying %s follows a step satisfying %s.", spec.Condition, spec.After)
+}
+
+// temporalSteps returns the trace's steps, including sub-trace steps
+// depth-first after their agent_call step when recursive is set.
+func temporalSteps(t *types.Trace, recursive bool) []*types.Step {
+	steps := make([]*types.Step, 0, len(t.Steps))
+	var walk func(t *types.Trace)
+	walk = func(t *types.Trace) {
+		for i := range t.Steps {
+			steps = append(steps, &t.Steps[i])
+			if recursive && t.Steps[i].Type == types.StepTypeAgentCall && t.Steps[i].SubTrace != nil {
+				walk(t.Steps[i].SubTrace)
+			}
+		}
+	}
+	walk(t)
+	return steps
+}
+
+func (c *stepCondition) compile() error {
+	if c.Name == "" && c.NameRegex == "" && c.Type == "" && len(c.Args) == 0 {
+		return fmt.Errorf("condition must set at least one of name, name_regex, type, or args")
+	}
+	if c.NameRegex != "" {
+		re, err := sharedRegexCache.compile(c.NameRegex)
+		if err != nil {
+			return fmt.Errorf("name_regex %q: %v", c.NameRegex, err)
+		}
+		c.nameRe = re
+	}
+	c.args = make(map[string]any, len(c.Args))
+	for path, raw := range c.Args {
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return fmt.Errorf("args.%s: %v", path, err)
+		}
+		c.args[path] = v
+	}
+	return nil
+}
+
+func (c *stepCondition) matches(trace *types.Trace, s *types.Step) bool {
+	return c.Not != c.matchesPositive(trace, s)
+}
+
+func (c *stepCondition) matchesPositive(trace *types.Trace, s *types.Step) bool {
+	if c.Name != "" && s.Name != c.Name {
+		return false
+	}
+	if c.nameRe != nil && !c.nameRe.MatchString(s.Name) {
+		return false
+	}
+	if c.Type != "" && s.Type != c.Type {
+		return false
+	}
+	if len(c.args) == 0 {
+		return true
+	}
+	root, err := decodeObject(trace, s.Args)
+	if err != nil {
+		return false
+	}
+	for path, want := range c.args {
+		raw, err := navigateDotPath(trace, root, path, "args")
+		if err != nil {
+			return false
+		}
+		var got any
+		if json.Unmarshal(raw, &got) != nil || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the condition for explanations, e.g. not(name=refund, type=tool_call).
+func (c *stepCondition) String() string {
+	var parts []string
+	if c.Name != "" {
+		parts = append(parts, "name="+c.Name)
+	}
+	if c.NameRegex != "" {
+		parts = append(parts, "name~"+c.NameRegex)
+	}
+	if c.Type != "" {
+		parts = append(parts, "type="+c.Type)
+	}
+	paths := make([]string, 0, len(c.Args))
+	for path := range c.Args {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		parts = append(parts, "args."+path+"="+string(c.Args[path]))
+	}
+	body := "(" + strings.Join(parts, ", ") + ")"
+	if c.Not {
+		return "not" + body
+	}
+	return body
+}
@@ -0,0 +1,73 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func temporalTrace() *types.Trace {
+	step := func(typ, name, args string) types.Step {
+		return types.Step{Type: typ, Name: name, Args: json.RawMessage(args)}
+	}
+	sub := &types.Trace{TraceID: "trc_sub", Steps: []types.Step{
+		step(types.StepTypeToolCall, "issue_refund", `{"amount":20}`),
+	}}
+	return &types.Trace{
+		TraceID: "trc_temporal",
+		Steps: []types.Step{
+			step(types.StepTypeToolCall, "authenticate", `{"user":"u1"}`),
+			step(types.StepTypeToolCall, "lookup_order", `{"order":{"id":"o7"}}`),
+			step(types.StepTypeLLMCall, "decide", `{}`),
+			step(types.StepTypeToolCall, "deny_refund", `{"reason":"late"}`),
+			{Type: types.StepTypeAgentCall, Name: "billing", SubTrace: sub},
+		},
+	}
+}
+
+func TestTemporalEvaluator(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"always type", `{"operator":"always","condition":{"type":"llm_call","not":true}}`, types.StatusHardFail},
+		{"always regex", `{"operator":"always","condition":{"name_regex":"^[a-z_]+$"}}`, types.StatusPass},
+		{"eventually args", `{"operator":"eventually","condition":{"name":"lookup_order","args":{"order.id":"o7"}}}`, types.StatusPass},
+		{"eventually args mismatch", `{"operator":"eventually","condition":{"name":"lookup_order","args":{"order.id":"o8"}}}`, types.StatusHardFail},
+		{"never", `{"operator":"never","condition":{"name":"delete_account"}}`, types.StatusPass},
+		{"never after holds at top level", `{"operator":"never","condition":{"name":"issue_refund"},"after":{"name":"deny_refund"}}`, types.StatusPass},
+		{"never after violated in sub-trace", `{"operator":"never","condition":{"name":"issue_refund"},"after":{"name":"deny_refund"},"recursive":true}`, types.StatusHardFail},
+		{"never after numeric arg", `{"operator":"never","condition":{"args":{"amount":20}},"after":{"name":"authenticate"},"recursive":true,"soft":true}`, types.StatusSoftFail},
+		{"until", `{"operator":"until","condition":{"type":"tool_call"},"release":{"type":"llm_call"}}`, types.StatusPass},
+		{"until violated", `{"operator":"until","condition":{"name":"authenticate"},"release":{"type":"llm_call"}}`, types.StatusHardFail},
+		{"until never released", `{"operator":"until","condition":{"type":"tool_call"},"release":{"name":"escalate"}}`, types.StatusHardFail},
+		{"until missing release", `{"operator":"until","condition":{"type":"tool_call"}}`, types.StatusHardFail},
+		{"empty condition", `{"operator":"always","condition":{}}`, types.StatusHardFail},
+		{"bad regex", `{"operator":"always","condition":{"name_regex":"("}}`, types.StatusHardFail},
+		{"unknown operator", `{"operator":"sometimes","condition":{"name":"x"}}`, types.StatusHardFail},
+	}
+	eval := &TemporalEvaluator{}
+	tr := temporalTrace()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &types.Assertion{AssertionID: "a", Type: types.TypeTemporal, Spec: json.RawMessage(tt.spec)}
+			result := eval.Evaluate(tr, a)
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
+func TestTemporalEvaluator_ExplainsViolation(t *testing.T) {
+	a := &types.Assertion{AssertionID: "a", Type: types.TypeTemporal, Spec: json.RawMessage(
+		`{"operator":"never","condition":{"name":"issue_refund"},"after":{"name":"deny_refund"},"recursive":true}`)}
+	result := (&TemporalEvaluator{}).Evaluate(temporalTrace(), a)
+	want := `never: step 5 "issue_refund" satisfies (name=issue_refund) after step 3 "deny_refund" satisfied (name=deny_refund)`
+	if !strings.Contains(result.Explanation, want) {
+		t.Errorf("explanation = %q, want %q", result.Explanation, want)
+	}
+}
@@ -1,11 +1,16 @@
 package assertion
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/segmentio/encoding/json"
 	"fmt"
+	"math"
+	"regexp"
 	"strings"
 	"time"
 
+	tracepkg "github.com/attest-ai/attest/engine/internal/trace"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
@@ -20,6 +25,16 @@ func (e *TraceEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion
 		Tools          []string `json:"tools,omitempty"`
 		Tool           string   `json:"tool,omitempty"`
 		MaxRepetitions int      `json:"max_repetitions,omitempty"`
+		MaxRetries     *int     `json:"max_retries,omitempty"`
+		FallbackTools  []string `json:"fallback_tools,omitempty"`
+		Phrases        []string `json:"phrases,omitempty"`
+		Hash           string   `json:"hash,omitempty"`
+		Labels         []string `json:"labels,omitempty"`
+		MaxTokens      *int     `json:"max_tokens,omitempty"`
+		// Tolerance and RelativeTolerance bound token_usage_reconciled's
+		// difference, in tokens and as a fraction of total_tokens.
+		Tolerance         int     `json:"tolerance,omitempty"`
+		RelativeTolerance float64 `json:"relative_tolerance,omitempty"`
 		Soft           bool     `json:"soft"`
 	}
 	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
@@ -34,9 +49,11 @@ func (e *TraceEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion
 		failStatus = types.StatusSoftFail
 	}
 
-	stepNames := make([]string, len(trace.Steps))
-	for i, s := range trace.Steps {
-		stepNames[i] = s.Name
+	namesBuf := stepNameScratch.get(len(trace.Steps))
+	defer stepNameScratch.put(namesBuf)
+	stepNames := *namesBuf
+	for i := range trace.Steps {
+		stepNames[i] = trace.Steps[i].Name
 	}
 
 	var explanation string
@@ -79,6 +96,55 @@ func (e *TraceEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion
 		}
 		passed, explanation = checkForbiddenTools(stepNames, spec.Tools)
 
+	case "max_retries_per_tool":
+		if spec.MaxRetries == nil || *spec.MaxRetries < 0 {
+			return failResult(assertion, start, "max_retries_per_tool requires 'max_retries' >= 0")
+		}
+		passed, explanation = checkMaxRetriesPerTool(trace, toolFilter(spec.Tool, spec.Tools), *spec.MaxRetries)
+
+	case "no_failed_then_abandoned_tool":
+		passed, explanation = checkNoFailedThenAbandoned(trace, toolFilter(spec.Tool, spec.Tools))
+
+	case "no_failed_steps":
+		passed, explanation = checkNoFailedSteps(trace)
+
+	case "error_handled":
+		phrases := spec.Phrases
+		if len(phrases) == 0 {
+			phrases = defaultApologyPhrases
+		}
+		passed, explanation = checkErrorHandled(trace, toolFilter(spec.Tool, spec.Tools), spec.FallbackTools, phrases)
+
+	case "system_prompt_unchanged":
+		want := strings.ToLower(strings.TrimPrefix(spec.Hash, "sha256:"))
+		if len(want) != sha256.Size*2 {
+			return failResult(assertion, start, "system_prompt_unchanged requires 'hash', a hex SHA-256")
+		}
+		passed, explanation = checkSystemPromptUnchanged(trace, want)
+
+	case "no_user_impersonation":
+		labels := spec.Labels
+		if len(labels) == 0 {
+			labels = defaultUserLabels
+		}
+		passed, explanation = checkNoUserImpersonation(trace, labels)
+
+	case "context_window_under":
+		if spec.MaxTokens == nil || *spec.MaxTokens <= 0 {
+			return failResult(assertion, start, "context_window_under requires 'max_tokens' > 0")
+		}
+		passed, explanation = checkContextWindowUnder(trace, *spec.MaxTokens)
+
+	case "token_usage_reconciled":
+		if spec.Tolerance < 0 || spec.RelativeTolerance < 0 {
+			return failResult(assertion, start, "token_usage_reconciled requires 'tolerance' and 'relative_tolerance' >= 0")
+		}
+		var err error
+		passed, explanation, err = checkTokenUsageReconciled(trace, spec.Tolerance, spec.RelativeTolerance)
+		if err != nil {
+			return failResult(assertion, start, err.Error())
+		}
+
 	default:
 		return failResult(assertion, start, fmt.Sprintf("unsupported check type: %s", spec.Check))
 	}
@@ -184,13 +250,10 @@ func checkNoDuplicates(stepNames []string) (bool, string) {
 
 // checkRequiredTools verifies that all listed tools appear at least once.
 func checkRequiredTools(stepNames []string, tools []string) (bool, string) {
-	nameSet := make(map[string]bool, len(stepNames))
-	for _, name := range stepNames {
-		nameSet[name] = true
-	}
+	found := toolHits(stepNames, tools)
 	var missing []string
 	for _, tool := range tools {
-		if !nameSet[tool] {
+		if !found[tool] {
 			missing = append(missing, tool)
 		}
 	}
@@ -202,13 +265,10 @@ func checkRequiredTools(stepNames []string, tools []string) (bool, string) {
 
 // checkForbiddenTools verifies that none of the listed tools appear in the trace.
 func checkForbiddenTools(stepNames []string, tools []string) (bool, string) {
-	nameSet := make(map[string]bool, len(stepNames))
-	for _, name := range stepNames {
-		nameSet[name] = true
-	}
+	hits := toolHits(stepNames, tools)
 	var found []string
 	for _, tool := range tools {
-		if nameSet[tool] {
+		if hits[tool] {
 			found = append(found, tool)
 		}
 	}
@@ -217,3 +277,353 @@ func checkForbiddenTools(stepNames []string, tools []string) (bool, string) {
 	}
 	return true, fmt.Sprintf("none of the forbidden tools %v found in trace.", tools)
 }
+
+// toolHits reports which of tools appear in stepNames. The set is sized by the
+// (short) tool list rather than the (possibly long) step list.
+func toolHits(stepNames []string, tools []string) map[string]bool {
+	hits := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		hits[tool] = false
+	}
+	for _, name := range stepNames {
+		if _, ok := hits[name]; ok {
+			hits[name] = true
+		}
+	}
+	return hits
+}
+
+// toolFilter returns the tools a retry check is limited to, or nil for all.
+func toolFilter(tool string, tools []string) map[string]bool {
+	if tool == "" && len(tools) == 0 {
+		return nil
+	}
+	filter := make(map[string]bool, len(tools)+1)
+	if tool != "" {
+		filter[tool] = true
+	}
+	for _, t := range tools {
+		filter[t] = true
+	}
+	return filter
+}
+
+// toolRetryGroups returns the retry groups of tool_call steps, limited to
+// filter when it is non-nil.
+func toolRetryGroups(trace *types.Trace, filter map[string]bool) []tracepkg.RetryGroup {
+	var groups []tracepkg.RetryGroup
+	for _, g := range tracepkg.RetryGroups(trace) {
+		if g.Type == types.StepTypeToolCall && (filter == nil || filter[g.Name]) {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// checkMaxRetriesPerTool verifies that no tool call was retried more than
+// maxRetries times. Retries are the steps linked by retry_of or attempt.
+func checkMaxRetriesPerTool(trace *types.Trace, filter map[string]bool, maxRetries int) (bool, string) {
+	var over []string
+	most := 0
+	for _, g := range toolRetryGroups(trace, filter) {
+		most = max(most, g.Retries())
+		if g.Retries() > maxRetries {
+			over = append(over, fmt.Sprintf("%q at step %d (%d retries)", g.Name, g.Attempts[0], g.Retries()))
+		}
+	}
+	if len(over) > 0 {
+		return false, fmt.Sprintf("tool calls retried more than max_retries %d: %s", maxRetries, strings.Join(over, ", "))
+	}
+	return true, fmt.Sprintf("no tool call retried more than %d times (most: %d).", maxRetries, most)
+}
+
+// checkNoFailedThenAbandoned verifies that every failed tool call was retried
+// until an attempt succeeded: the final attempt of each tool call must have
+// no error.
+func checkNoFailedThenAbandoned(trace *types.Trace, filter map[string]bool) (bool, string) {
+	var abandoned []string
+	for _, g := range toolRetryGroups(trace, filter) {
+		last := &trace.Steps[g.Last()]
+		if last.Error != nil {
+			abandoned = append(abandoned, fmt.Sprintf("%q at step %d after %d attempts (%s)", g.Name, g.Last(), len(g.Attempts), last.Error.Message))
+		}
+	}
+	if len(abandoned) > 0 {
+		return false, fmt.Sprintf("tool calls failed and were abandoned: %s", strings.Join(abandoned, ", "))
+	}
+	return true, "every failed tool call was retried until it succeeded."
+}
+
+// defaultApologyPhrases mark an output that tells the user a step failed.
+var defaultApologyPhrases = []string{
+	"sorry", "apologize", "apologies", "unfortunately",
+	"unable to", "not able to", "could not", "couldn't", "can't", "cannot",
+}
+
+// checkNoFailedSteps verifies that no top-level step carries an error.
+func checkNoFailedSteps(trace *types.Trace) (bool, string) {
+	var failed []string
+	for i := range trace.Steps {
+		if e := trace.Steps[i].Error; e != nil {
+			failed = append(failed, fmt.Sprintf("%q at step %d (%s)", trace.Steps[i].Name, i, e.Message))
+		}
+	}
+	if len(failed) > 0 {
+		return false, fmt.Sprintf("%d failed steps: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return true, "no steps failed."
+}
+
+// checkErrorHandled verifies that every tool call whose final attempt failed
+// was handled: a later fallback tool call succeeded, or the output
+// acknowledges the failure with one of phrases. Without fallbackTools any
+// other tool counts as a fallback.
+func checkErrorHandled(trace *types.Trace, filter map[string]bool, fallbackTools, phrases []string) (bool, string) {
+	fallbacks := toolFilter("", fallbackTools)
+	output := strings.ToLower(outputText(trace))
+	acknowledged := ""
+	for _, p := range phrases {
+		if p != "" && strings.Contains(output, strings.ToLower(p)) {
+			acknowledged = p
+			break
+		}
+	}
+
+	var unhandled, handled []string
+	for _, g := range toolRetryGroups(trace, filter) {
+		last := g.Last()
+		if trace.Steps[last].Error == nil {
+			continue
+		}
+		fallback := ""
+		for j := last + 1; j < len(trace.Steps) && fallback == ""; j++ {
+			s := &trace.Steps[j]
+			if s.Type != types.StepTypeToolCall || s.Error != nil || s.Name == g.Name {
+				continue
+			}
+			if fallbacks == nil || fallbacks[s.Name] {
+				fallback = s.Name
+			}
+		}
+		switch {
+		case fallback != "":
+			handled = append(handled, fmt.Sprintf("%q by fallback %q", g.Name, fallback))
+		case acknowledged != "":
+			handled = append(handled, fmt.Sprintf("%q by output mentioning %q", g.Name, acknowledged))
+		default:
+			unhandled = append(unhandled, fmt.Sprintf("%q at step %d", g.Name, last))
+		}
+	}
+	if len(unhandled) > 0 {
+		return false, fmt.Sprintf("failed tool calls with no fallback tool and no acknowledgement in output: %s", strings.Join(unhandled, ", "))
+	}
+	if len(handled) == 0 {
+		return true, "no tool calls failed."
+	}
+	return true, fmt.Sprintf("failed tool calls handled: %s.", strings.Join(handled, ", "))
+}
+
+// outputText returns output.message when it is a string, else the raw output.
+func outputText(trace *types.Trace) string {
+	if raw, err := ResolveTarget(trace, "output.message"); err == nil {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+	}
+	return string(trace.Output)
+}
+
+// llmCallSteps returns the indices of the llm_call steps that carry messages.
+func llmCallSteps(trace *types.Trace) []int {
+	var idx []int
+	for i := range trace.Steps {
+		if trace.Steps[i].Type == types.StepTypeLLMCall && len(trace.Steps[i].Messages) > 0 {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// systemPromptHash returns the hex SHA-256 of a step's system messages,
+// joined by newlines, and whether it has any.
+func systemPromptHash(msgs []types.Message) (string, bool) {
+	var parts []string
+	for _, m := range msgs {
+		if m.Role == types.RoleSystem {
+			parts = append(parts, m.Content)
+		}
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// checkSystemPromptUnchanged verifies that every llm_call step sends a system
+// prompt whose SHA-256 is want. Mismatches report the hash found so a
+// deliberate change can be pinned.
+func checkSystemPromptUnchanged(trace *types.Trace, want string) (bool, string) {
+	steps := llmCallSteps(trace)
+	if len(steps) == 0 {
+		return false, "no llm_call steps with messages in trace."
+	}
+	var changed []string
+	for _, i := range steps {
+		got, ok := systemPromptHash(trace.Steps[i].Messages)
+		switch {
+		case !ok:
+			changed = append(changed, fmt.Sprintf("%q at step %d has no system message", trace.Steps[i].Name, i))
+		case got != want:
+			changed = append(changed, fmt.Sprintf("%q at step %d has system prompt sha256:%s", trace.Steps[i].Name, i, got))
+		}
+	}
+	if len(changed) > 0 {
+		return false, fmt.Sprintf("system prompt differs from sha256:%s: %s", want, strings.Join(changed, ", "))
+	}
+	return true, fmt.Sprintf("system prompt of %d llm_call steps matches sha256:%s.", len(steps), want)
+}
+
+// defaultUserLabels are the role labels an assistant message must not use to
+// speak as the user.
+var defaultUserLabels = []string{"user", "human"}
+
+// userLabelRegex matches a line that opens a turn labeled with one of labels,
+// such as "User:", "**Human:**", "[user]:", or "### User:", and chat
+// template role tokens such as "<|user|>" and "<|im_start|>user".
+func userLabelRegex(labels []string) *regexp.Regexp {
+	quoted := make([]string, len(labels))
+	for i, l := range labels {
+		quoted[i] = regexp.QuoteMeta(l)
+	}
+	alt := strings.Join(quoted, "|")
+	return regexp.MustCompile(`(?im)^[\s>*#_\[]*(?:` + alt + `)[\]*_]*[ \t]*:|<\|?(?:` + alt + `)\|?>|<\|im_start\|>[ \t]*(?:` + alt + `)\b`)
+}
+
+// checkNoUserImpersonation verifies that no assistant message, in llm_call
+// steps or the transcript, contains a turn labeled as the user.
+func checkNoUserImpersonation(trace *types.Trace, labels []string) (bool, string) {
+	re := userLabelRegex(labels)
+	var found []string
+	scan := func(where string, msgs []types.Message) {
+		for j, m := range msgs {
+			if m.Role != types.RoleAssistant {
+				continue
+			}
+			if loc := re.FindStringIndex(m.Content); loc != nil {
+				found = append(found, fmt.Sprintf("%s message %d (%q)", where, j, strings.TrimSpace(m.Content[loc[0]:loc[1]])))
+			}
+		}
+	}
+	for _, i := range llmCallSteps(trace) {
+		scan(fmt.Sprintf("%q at step %d", trace.Steps[i].Name, i), trace.Steps[i].Messages)
+	}
+	scan("transcript", trace.Transcript)
+	if len(found) > 0 {
+		return false, fmt.Sprintf("assistant messages speak as the user: %s", strings.Join(found, ", "))
+	}
+	return true, "no assistant message speaks as the user."
+}
+
+// checkContextWindowUnder verifies that the prompt of every llm_call step is
+// at most maxTokens. The prompt size is the step's input token count from
+// its metadata, else an estimate of four characters per token over its
+// messages other than the final assistant reply.
+func checkContextWindowUnder(trace *types.Trace, maxTokens int) (bool, string) {
+	steps := llmCallSteps(trace)
+	if len(steps) == 0 {
+		return false, "no llm_call steps with messages in trace."
+	}
+	var over []string
+	largest, estimated := 0, false
+	for _, i := range steps {
+		step := &trace.Steps[i]
+		tokens, exact := promptTokens(step)
+		estimated = estimated || !exact
+		largest = max(largest, tokens)
+		if tokens > maxTokens {
+			note := ""
+			if !exact {
+				note = " estimated"
+			}
+			over = append(over, fmt.Sprintf("%q at step %d (%d%s tokens)", step.Name, i, tokens, note))
+		}
+	}
+	note := ""
+	if estimated {
+		note = " (some counts estimated from message length)"
+	}
+	if len(over) > 0 {
+		return false, fmt.Sprintf("prompts over %d tokens: %s%s", maxTokens, strings.Join(over, ", "), note)
+	}
+	return true, fmt.Sprintf("largest prompt is %d tokens, within %d%s.", largest, maxTokens, note)
+}
+
+// promptTokens returns the input token count of an llm_call step and
+// whether it was reported rather than estimated.
+func promptTokens(step *types.Step) (int, bool) {
+	if meta, err := types.ParseStepMetadata(step.Metadata); err == nil && meta.TokensIn != nil {
+		return *meta.TokensIn, true
+	}
+	msgs := step.Messages
+	if n := len(msgs); n > 0 && msgs[n-1].Role == types.RoleAssistant {
+		msgs = msgs[:n-1]
+	}
+	chars := 0
+	for _, m := range msgs {
+		chars += len([]rune(m.Content))
+	}
+	return (chars + 3) / 4, false
+}
+
+// checkTokenUsageReconciled verifies that metadata.total_tokens equals the
+// sum of tokens_in and tokens_out over the top-level steps, within the larger
+// of tolerance and relativeTolerance × total_tokens. Sub-traces report their
+// own totals and are not included.
+func checkTokenUsageReconciled(trace *types.Trace, tolerance int, relativeTolerance float64) (bool, string, error) {
+	if trace.Metadata == nil || trace.Metadata.TotalTokens == nil {
+		return false, "metadata.total_tokens is not set.", nil
+	}
+	total := *trace.Metadata.TotalTokens
+	sum, reporting, llmCalls, silent := 0, 0, 0, 0
+	for i := range trace.Steps {
+		step := &trace.Steps[i]
+		meta, err := step.TypedMetadata()
+		if err != nil {
+			return false, "", fmt.Errorf("step %d (%q): %v", i, step.Name, err)
+		}
+		if step.Type == types.StepTypeLLMCall {
+			llmCalls++
+		}
+		if meta.TokensIn == nil && meta.TokensOut == nil {
+			if step.Type == types.StepTypeLLMCall {
+				silent++
+			}
+			continue
+		}
+		reporting++
+		if meta.TokensIn != nil {
+			sum += *meta.TokensIn
+		}
+		if meta.TokensOut != nil {
+			sum += *meta.TokensOut
+		}
+	}
+	if reporting == 0 {
+		return false, fmt.Sprintf("metadata.total_tokens is %d but no step reports token usage.", total), nil
+	}
+
+	allowed := max(float64(tolerance), relativeTolerance*float64(total))
+	diff := total - sum
+	note := ""
+	if silent > 0 {
+		note = fmt.Sprintf(" (%d of %d llm_call steps report no tokens)", silent, llmCalls)
+	}
+	if math.Abs(float64(diff)) > allowed {
+		return false, fmt.Sprintf("metadata.total_tokens is %d but %d steps report %d tokens (off by %+d, tolerance %s)%s.",
+			total, reporting, sum, diff, formatFloat(allowed), note), nil
+	}
+	return true, fmt.Sprintf("metadata.total_tokens %d matches the %d tokens reported by %d steps (off by %+d, tolerance %s)%s.",
+		total, sum, reporting, diff, formatFloat(allowed), note), nil
+}
@@ -1,9 +1,13 @@
 package assertion
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/attest-ai/attest/engine/internal/trace"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
@@ -186,3 +190,289 @@ func TestTraceEvaluator(t *testing.T) {
 		})
 	}
 }
+
+func TestTraceEvaluator_Retries(t *testing.T) {
+	evaluator := &TraceEvaluator{}
+	failed := &types.StepError{Message: "timeout"}
+	one := 1
+
+	tests := []struct {
+		name       string
+		steps      []types.Step
+		spec       string
+		wantStatus string
+	}{
+		{
+			name: "max_retries_per_tool passes within limit",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "search", Attempt: 2},
+			},
+			spec:       `{"check":"max_retries_per_tool","max_retries":1}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "max_retries_per_tool fails over limit via retry_of",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed, RetryOf: new(int)},
+				{Type: types.StepTypeLLMCall, Name: "think"},
+				{Type: types.StepTypeToolCall, Name: "search", RetryOf: &one},
+			},
+			spec:       `{"check":"max_retries_per_tool","max_retries":1}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "max_retries_per_tool ignores other tools",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search"},
+				{Type: types.StepTypeToolCall, Name: "search", Attempt: 2},
+				{Type: types.StepTypeToolCall, Name: "search", Attempt: 3},
+				{Type: types.StepTypeToolCall, Name: "lookup"},
+			},
+			spec:       `{"check":"max_retries_per_tool","tool":"lookup","max_retries":0}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "repeated calls without retry fields are not retries",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search"},
+				{Type: types.StepTypeToolCall, Name: "search"},
+			},
+			spec:       `{"check":"max_retries_per_tool","max_retries":0}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name:       "max_retries_per_tool requires max_retries",
+			steps:      []types.Step{{Type: types.StepTypeToolCall, Name: "search"}},
+			spec:       `{"check":"max_retries_per_tool"}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "no_failed_then_abandoned_tool passes when a retry succeeds",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "search", Attempt: 2},
+			},
+			spec:       `{"check":"no_failed_then_abandoned_tool"}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "no_failed_then_abandoned_tool fails when every attempt fails",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed, Attempt: 2},
+				{Type: types.StepTypeLLMCall, Name: "apologize"},
+			},
+			spec:       `{"check":"no_failed_then_abandoned_tool"}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "no_failed_then_abandoned_tool fails on a single failed call",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+			},
+			spec:       `{"check":"no_failed_then_abandoned_tool","tools":["search"]}`,
+			wantStatus: types.StatusHardFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &types.Trace{TraceID: "trc_test", SchemaVersion: 2, Output: json.RawMessage(`{"message":"ok"}`), Steps: tt.steps}
+			trace.Normalize(tr)
+			result := evaluator.Evaluate(tr, &types.Assertion{AssertionID: "assert_test", Type: types.TypeTrace, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
+func TestTraceEvaluator_ErrorPaths(t *testing.T) {
+	evaluator := &TraceEvaluator{}
+	failed := &types.StepError{Type: "timeout", Message: "search timed out"}
+
+	tests := []struct {
+		name       string
+		steps      []types.Step
+		output     string
+		spec       string
+		wantStatus string
+	}{
+		{
+			name:       "no_failed_steps passes",
+			steps:      []types.Step{{Type: types.StepTypeToolCall, Name: "search"}},
+			spec:       `{"check":"no_failed_steps"}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "no_failed_steps fails even when a retry succeeded",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "search", Attempt: 2},
+			},
+			spec:       `{"check":"no_failed_steps"}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "error_handled passes with a fallback tool",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "cached_search"},
+			},
+			output:     "Here are your results.",
+			spec:       `{"check":"error_handled"}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "error_handled passes with an apology",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+			},
+			output:     "Sorry, search is unavailable right now.",
+			spec:       `{"check":"error_handled"}`,
+			wantStatus: types.StatusPass,
+		},
+		{
+			name: "error_handled fails when the fallback is not listed",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "send_email"},
+			},
+			output:     "Done!",
+			spec:       `{"check":"error_handled","fallback_tools":["cached_search"]}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "error_handled fails when a failed fallback is all there is",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+				{Type: types.StepTypeToolCall, Name: "cached_search", Error: failed},
+			},
+			output:     "Here are your results.",
+			spec:       `{"check":"error_handled","tools":["search"]}`,
+			wantStatus: types.StatusHardFail,
+		},
+		{
+			name: "error_handled uses custom phrases",
+			steps: []types.Step{
+				{Type: types.StepTypeToolCall, Name: "search", Error: failed},
+			},
+			output:     "Search is down; try again later.",
+			spec:       `{"check":"error_handled","phrases":["try again later"]}`,
+			wantStatus: types.StatusPass,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, _ := json.Marshal(map[string]string{"message": tt.output})
+			tr := &types.Trace{TraceID: "trc_test", SchemaVersion: 2, Output: output, Steps: tt.steps}
+			trace.Normalize(tr)
+			result := evaluator.Evaluate(tr, &types.Assertion{AssertionID: "assert_test", Type: types.TypeTrace, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
+func TestTraceEvaluator_RoleHygiene(t *testing.T) {
+	evaluator := &TraceEvaluator{}
+	const system = "You are a support agent."
+	sum := sha256.Sum256([]byte(system))
+	pinned := hex.EncodeToString(sum[:])
+	call := func(system, reply string, metadata string) types.Step {
+		step := types.Step{Type: types.StepTypeLLMCall, Name: "chat", Messages: []types.Message{
+			{Role: types.RoleUser, Content: "Where is my order?"},
+			{Role: types.RoleAssistant, Content: reply},
+		}}
+		if system != "" {
+			step.Messages = append([]types.Message{{Role: types.RoleSystem, Content: system}}, step.Messages...)
+		}
+		if metadata != "" {
+			step.Metadata = json.RawMessage(metadata)
+		}
+		return step
+	}
+
+	tests := []struct {
+		name       string
+		steps      []types.Step
+		spec       string
+		wantStatus string
+		wantText   string
+	}{
+		{"system prompt matches", []types.Step{call(system, "ok", ""), call(system, "ok", "")}, `{"check":"system_prompt_unchanged","hash":"sha256:` + pinned + `"}`, types.StatusPass, "2 llm_call steps"},
+		{"system prompt changed", []types.Step{call(system, "ok", ""), call(system+" Be terse.", "ok", "")}, `{"check":"system_prompt_unchanged","hash":"` + pinned + `"}`, types.StatusHardFail, "step 1 has system prompt sha256:"},
+		{"system prompt dropped", []types.Step{call("", "ok", "")}, `{"check":"system_prompt_unchanged","hash":"` + pinned + `"}`, types.StatusHardFail, "has no system message"},
+		{"system prompt bad hash", []types.Step{call(system, "ok", "")}, `{"check":"system_prompt_unchanged","hash":"abc"}`, types.StatusHardFail, "requires 'hash'"},
+		{"no llm calls", nil, `{"check":"system_prompt_unchanged","hash":"` + pinned + `"}`, types.StatusHardFail, "no llm_call steps"},
+
+		{"no impersonation", []types.Step{call(system, "User accounts are listed under Settings.", "")}, `{"check":"no_user_impersonation"}`, types.StatusPass, ""},
+		{"impersonation by label", []types.Step{call(system, "It shipped.\n\n**User:** thanks!", "")}, `{"check":"no_user_impersonation"}`, types.StatusHardFail, `"**User:"`},
+		{"impersonation by template token", []types.Step{call(system, "Done.<|im_start|>user\nGreat", "")}, `{"check":"no_user_impersonation"}`, types.StatusHardFail, "im_start"},
+		{"custom labels", []types.Step{call(system, "Customer: thanks", "")}, `{"check":"no_user_impersonation","labels":["customer"]}`, types.StatusHardFail, "step 0 message"},
+
+		{"context window reported", []types.Step{call(system, "ok", `{"usage":{"input_tokens":900}}`)}, `{"check":"context_window_under","max_tokens":1000}`, types.StatusPass, "largest prompt is 900 tokens"},
+		{"context window over", []types.Step{call(system, "ok", `{"prompt_tokens":1200}`)}, `{"check":"context_window_under","max_tokens":1000}`, types.StatusHardFail, "(1200 tokens)"},
+		{"context window estimated", []types.Step{call(strings.Repeat("x", 400), "ok", "")}, `{"check":"context_window_under","max_tokens":100}`, types.StatusHardFail, "estimated tokens"},
+		{"context window requires max", []types.Step{call(system, "ok", "")}, `{"check":"context_window_under"}`, types.StatusHardFail, "requires 'max_tokens'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &types.Trace{TraceID: "trc_test", SchemaVersion: 2, Output: json.RawMessage(`{"message":"ok"}`), Steps: tt.steps}
+			result := evaluator.Evaluate(tr, &types.Assertion{AssertionID: "assert_test", Type: types.TypeTrace, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if !strings.Contains(result.Explanation, tt.wantText) {
+				t.Errorf("explanation %q does not contain %q", result.Explanation, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestTraceEvaluator_TokenUsageReconciled(t *testing.T) {
+	evaluator := &TraceEvaluator{}
+	total := func(n int) *types.TraceMetadata { return &types.TraceMetadata{TotalTokens: &n} }
+	steps := []types.Step{
+		{Type: types.StepTypeLLMCall, Name: "plan", Metadata: json.RawMessage(`{"tokens_in":100,"tokens_out":20}`)},
+		{Type: types.StepTypeToolCall, Name: "search"},
+		{Type: types.StepTypeLLMCall, Name: "answer", Metadata: json.RawMessage(`{"usage":{"input_tokens":300,"output_tokens":80}}`)},
+	}
+
+	tests := []struct {
+		name       string
+		metadata   *types.TraceMetadata
+		steps      []types.Step
+		spec       string
+		wantStatus string
+		wantText   string
+	}{
+		{"exact", total(500), steps, `{"check":"token_usage_reconciled"}`, types.StatusPass, "matches the 500 tokens reported by 2 steps"},
+		{"off", total(520), steps, `{"check":"token_usage_reconciled"}`, types.StatusHardFail, "off by +20, tolerance 0"},
+		{"absolute tolerance", total(520), steps, `{"check":"token_usage_reconciled","tolerance":20}`, types.StatusPass, ""},
+		{"relative tolerance", total(480), steps, `{"check":"token_usage_reconciled","relative_tolerance":0.05}`, types.StatusPass, "tolerance 24"},
+		{"bogus total", total(50000), steps, `{"check":"token_usage_reconciled","relative_tolerance":0.1}`, types.StatusHardFail, "but 2 steps report 500 tokens"},
+		{"silent llm call", total(120), steps[:1:1], `{"check":"token_usage_reconciled"}`, types.StatusPass, ""},
+		{"silent llm call noted", total(120), append(steps[:2:2], types.Step{Type: types.StepTypeLLMCall, Name: "answer"}), `{"check":"token_usage_reconciled"}`, types.StatusPass, "1 of 2 llm_call steps report no tokens"},
+		{"no total", nil, steps, `{"check":"token_usage_reconciled"}`, types.StatusHardFail, "metadata.total_tokens is not set"},
+		{"no step tokens", total(500), steps[1:2], `{"check":"token_usage_reconciled"}`, types.StatusHardFail, "no step reports token usage"},
+		{"bad step metadata", total(500), []types.Step{{Type: types.StepTypeLLMCall, Name: "plan", Metadata: json.RawMessage(`{"tokens_in":"many"}`)}}, `{"check":"token_usage_reconciled"}`, types.StatusHardFail, "must be an integer"},
+		{"negative tolerance", total(500), steps, `{"check":"token_usage_reconciled","tolerance":-1}`, types.StatusHardFail, "requires 'tolerance'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &types.Trace{TraceID: "trc_test", SchemaVersion: 2, Output: json.RawMessage(`{"message":"ok"}`), Steps: tt.steps, Metadata: tt.metadata}
+			result := evaluator.Evaluate(tr, &types.Assertion{AssertionID: "assert_test", Type: types.TypeTrace, Spec: json.RawMessage(tt.spec)})
+			if result.Status != tt.wantStatus {
+				t.Errorf("got status %q, want %q; explanation: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+			if !strings.Contains(result.Explanation, tt.wantText) {
+				t.Errorf("explanation %q does not contain %q", result.Explanation, tt.wantText)
+			}
+		})
+	}
+}
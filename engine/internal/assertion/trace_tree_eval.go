@@ -3,15 +3,20 @@ package assertion
 import (
 	"github.com/segmentio/encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/attest-ai/attest/engine/internal/cache"
 	"github.com/attest-ai/attest/engine/internal/trace"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
 // TraceTreeEvaluator implements cross-agent trace tree assertions.
-type TraceTreeEvaluator struct{}
+// history is optional and only used by "threshold":"dynamic" aggregate checks.
+type TraceTreeEvaluator struct {
+	history *cache.HistoryStore
+}
 
 func (e *TraceTreeEvaluator) Evaluate(t *types.Trace, assertion *types.Assertion) *types.AssertionResult {
 	start := time.Now()
@@ -45,13 +50,13 @@ func (e *TraceTreeEvaluator) Evaluate(t *types.Trace, assertion *types.Assertion
 	case "cross_agent_data_flow":
 		passed, explanation = checkCrossAgentDataFlow(t, assertion.Spec)
 	case "aggregate_cost":
-		passed, explanation = checkAggregateCostCheck(t, assertion.Spec)
+		passed, explanation = e.checkAggregate(t, assertion, checkAggregateCostCheck)
 	case "aggregate_tokens":
-		passed, explanation = checkAggregateTokensCheck(t, assertion.Spec)
+		passed, explanation = e.checkAggregate(t, assertion, checkAggregateTokensCheck)
 	case "follows_transitions":
 		passed, explanation = checkFollowsTransitions(t, assertion.Spec)
 	case "aggregate_latency":
-		passed, explanation = checkAggregateLatencyCheck(t, assertion.Spec)
+		passed, explanation = e.checkAggregate(t, assertion, checkAggregateLatencyCheck)
 	case "agent_ordered_before":
 		passed, explanation = checkAgentOrderedBefore(t, assertion.Spec)
 	case "agents_overlap":
@@ -60,6 +65,8 @@ func (e *TraceTreeEvaluator) Evaluate(t *types.Trace, assertion *types.Assertion
 		passed, explanation = checkAgentWallTimeUnder(t, assertion.Spec)
 	case "ordered_agents":
 		passed, explanation = checkOrderedAgents(t, assertion.Spec)
+	case "all_tools_called":
+		passed, explanation = checkAllToolsCalled(t, assertion.Spec)
 	default:
 		return failResult(assertion, start, fmt.Sprintf("unsupported trace_tree check: %s", base.Check))
 	}
@@ -91,13 +98,45 @@ func checkAgentCalled(t *types.Trace, spec json.RawMessage) (bool, string) {
 	if s.AgentID == "" {
 		return false, "agent_called requires 'agent_id'"
 	}
-	found := trace.FindAgentByID(t, s.AgentID)
+	found := treeIndex(t).AgentByID(s.AgentID)
 	if found == nil {
 		return false, fmt.Sprintf("agent %q was not called in the trace tree", s.AgentID)
 	}
 	return true, fmt.Sprintf("agent %q was called in the trace tree.", s.AgentID)
 }
 
+// checkAllToolsCalled is required_tools over every trace in the tree: each
+// listed tool must name a step in the root or in any sub-agent's trace.
+func checkAllToolsCalled(t *types.Trace, spec json.RawMessage) (bool, string) {
+	var s struct {
+		Tools []string `json:"tools"`
+	}
+	if err := json.Unmarshal(spec, &s); err != nil {
+		return false, fmt.Sprintf("all_tools_called: invalid spec: %v", err)
+	}
+	if len(s.Tools) == 0 {
+		return false, "all_tools_called requires 'tools'"
+	}
+	var names []string
+	trace.WalkTree(t, func(sub *types.Trace, _ int) bool {
+		for _, step := range sub.Steps {
+			names = append(names, step.Name)
+		}
+		return true
+	})
+	found := toolHits(names, s.Tools)
+	var missing []string
+	for _, tool := range s.Tools {
+		if !found[tool] {
+			missing = append(missing, tool)
+		}
+	}
+	if len(missing) > 0 {
+		return false, fmt.Sprintf("tools not called anywhere in the trace tree: %v", missing)
+	}
+	return true, fmt.Sprintf("all tools called in the trace tree: %v.", s.Tools)
+}
+
 func checkDelegationDepth(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
 		MaxDepth int `json:"max_depth"`
@@ -108,7 +147,7 @@ func checkDelegationDepth(t *types.Trace, spec json.RawMessage) (bool, string) {
 	if s.MaxDepth <= 0 {
 		return false, "delegation_depth requires 'max_depth' > 0"
 	}
-	depth := trace.TreeDepth(t)
+	depth := treeIndex(t).Depth()
 	if depth > s.MaxDepth {
 		return false, fmt.Sprintf("delegation depth %d exceeds max_depth %d", depth, s.MaxDepth)
 	}
@@ -131,7 +170,7 @@ func checkAgentOutputContains(t *types.Trace, spec json.RawMessage) (bool, strin
 		return false, "agent_output_contains requires 'value'"
 	}
 
-	found := trace.FindAgentByID(t, s.AgentID)
+	found := treeIndex(t).AgentByID(s.AgentID)
 	if found == nil {
 		return false, fmt.Sprintf("agent %q not found in trace tree", s.AgentID)
 	}
@@ -162,11 +201,11 @@ func checkCrossAgentDataFlow(t *types.Trace, spec json.RawMessage) (bool, string
 		return false, "cross_agent_data_flow requires 'from_agent', 'to_agent', and 'field'"
 	}
 
-	fromTrace := trace.FindAgentByID(t, s.FromAgent)
+	fromTrace := treeIndex(t).AgentByID(s.FromAgent)
 	if fromTrace == nil {
 		return false, fmt.Sprintf("from_agent %q not found in trace tree", s.FromAgent)
 	}
-	toTrace := trace.FindAgentByID(t, s.ToAgent)
+	toTrace := treeIndex(t).AgentByID(s.ToAgent)
 	if toTrace == nil {
 		return false, fmt.Sprintf("to_agent %q not found in trace tree", s.ToAgent)
 	}
@@ -196,6 +235,45 @@ func checkCrossAgentDataFlow(t *types.Trace, spec json.RawMessage) (bool, string
 	return true, fmt.Sprintf("field %q flows from agent %q to agent %q.", s.Field, s.FromAgent, s.ToAgent)
 }
 
+// checkAggregate runs an aggregate_* check, switching to a history-derived
+// threshold when the spec sets "threshold":"dynamic". The static check is kept
+// as the fallback while the baseline builds, but only if the spec has a value.
+func (e *TraceTreeEvaluator) checkAggregate(t *types.Trace, assertion *types.Assertion, static func(*types.Trace, json.RawMessage) (bool, string)) (bool, string) {
+	var s struct {
+		Check     string          `json:"check"`
+		Operator  string          `json:"operator"`
+		Value     json.RawMessage `json:"value"`
+		Threshold string          `json:"threshold"`
+		Sigma     float64         `json:"sigma"`
+		Direction string          `json:"direction"`
+	}
+	if err := json.Unmarshal(assertion.Spec, &s); err != nil || s.Threshold != "dynamic" {
+		return static(t, assertion.Spec)
+	}
+
+	dyn := dynamicNumericSpec{Sigma: s.Sigma, Direction: s.Direction}
+	if err := dyn.resolveDirection(s.Operator); err != nil {
+		return false, fmt.Sprintf("%s: %v", s.Check, err)
+	}
+
+	var fallback func() (bool, string)
+	if len(s.Value) > 0 && s.Operator != "" {
+		fallback = func() (bool, string) { return static(t, assertion.Spec) }
+	}
+
+	totalTokens, totalCostUSD, totalLatencyMS, _ := trace.AggregateMetadata(t)
+	var actual float64
+	switch s.Check {
+	case "aggregate_cost":
+		actual = totalCostUSD
+	case "aggregate_tokens":
+		actual = float64(totalTokens)
+	default:
+		actual = float64(totalLatencyMS)
+	}
+	return evaluateDynamicNumeric(e.history, t.TraceID, assertion.AssertionID, s.Check, actual, dyn, fallback)
+}
+
 func checkAggregateCostCheck(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
 		Operator string  `json:"operator"`
@@ -228,8 +306,8 @@ func checkAggregateTokensCheck(t *types.Trace, spec json.RawMessage) (bool, stri
 
 func checkAggregateLatencyCheck(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
-		Operator string  `json:"operator"`
-		Value    float64 `json:"value"`
+		Operator string     `json:"operator"`
+		Value    DurationMS `json:"value"`
 	}
 	if err := json.Unmarshal(spec, &s); err != nil {
 		return false, fmt.Sprintf("aggregate_latency: invalid spec: %v", err)
@@ -238,12 +316,12 @@ func checkAggregateLatencyCheck(t *types.Trace, spec json.RawMessage) (bool, str
 		return false, "aggregate_latency requires 'operator'"
 	}
 	_, _, totalLatencyMS, _ := trace.AggregateMetadata(t)
-	return applyNumericOperator("aggregate_latency", float64(totalLatencyMS), s.Operator, s.Value)
+	return applyNumericOperator("aggregate_latency", float64(totalLatencyMS), s.Operator, float64(s.Value))
 }
 
 func checkFollowsTransitions(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
-		Transitions [][]string `json:"transitions"`
+		Transitions []json.RawMessage `json:"transitions"`
 	}
 	if err := json.Unmarshal(spec, &s); err != nil {
 		return false, fmt.Sprintf("follows_transitions: invalid spec: %v", err)
@@ -252,34 +330,47 @@ func checkFollowsTransitions(t *types.Trace, spec json.RawMessage) (bool, string
 		return false, "follows_transitions requires non-empty 'transitions'"
 	}
 
-	// Build allowed set from transitions spec.
-	type pair struct{ parent, child string }
-	allowed := make(map[pair]struct{}, len(s.Transitions))
-	for _, tr := range s.Transitions {
-		if len(tr) != 2 {
-			return false, fmt.Sprintf("follows_transitions: each transition must be [parent, child], got %v", tr)
+	rules := make([]*transitionRule, len(s.Transitions))
+	for i, raw := range s.Transitions {
+		rule, err := parseTransitionRule(raw)
+		if err != nil {
+			return false, fmt.Sprintf("follows_transitions: %v", err)
 		}
-		allowed[pair{tr[0], tr[1]}] = struct{}{}
+		rules[i] = rule
 	}
 
-	// Collect actual delegation pairs from the trace tree.
+	// Collect actual delegation pairs from the trace tree. An agent's role is
+	// the agent_role of the agent_call step that spawned it; the root has none.
 	var violations []string
-	var collectDelegations func(t *types.Trace)
-	collectDelegations = func(t *types.Trace) {
-		parentID := t.AgentID
+	counts := make([]int, len(rules))
+	var collectDelegations func(t *types.Trace, role string)
+	collectDelegations = func(t *types.Trace, role string) {
+		parent := transitionEnd{id: t.AgentID, role: role}
 		for i := range t.Steps {
 			step := &t.Steps[i]
 			if step.Type == types.StepTypeAgentCall && step.SubTrace != nil {
-				childID := step.SubTrace.AgentID
-				p := pair{parentID, childID}
-				if _, ok := allowed[p]; !ok {
-					violations = append(violations, fmt.Sprintf("%s -> %s", parentID, childID))
+				child := transitionEnd{id: step.SubTrace.AgentID, role: step.AgentRole}
+				allowed := false
+				for ri, rule := range rules {
+					if rule.from.match(parent) && rule.to.match(child) {
+						allowed = true
+						counts[ri]++
+					}
 				}
-				collectDelegations(step.SubTrace)
+				if !allowed {
+					violations = append(violations, fmt.Sprintf("%s -> %s", parent.id, child.id))
+				}
+				collectDelegations(step.SubTrace, step.AgentRole)
 			}
 		}
 	}
-	collectDelegations(t)
+	collectDelegations(t, "")
+
+	for ri, rule := range rules {
+		if rule.maxCount > 0 && counts[ri] > rule.maxCount {
+			violations = append(violations, fmt.Sprintf("%s -> %s occurred %d times (max_count %d)", rule.from.raw, rule.to.raw, counts[ri], rule.maxCount))
+		}
+	}
 
 	if len(violations) > 0 {
 		return false, fmt.Sprintf("follows_transitions: disallowed delegation(s): %s", strings.Join(violations, ", "))
@@ -287,6 +378,96 @@ func checkFollowsTransitions(t *types.Trace, spec json.RawMessage) (bool, string
 	return true, "follows_transitions: all delegations match allowed transitions."
 }
 
+// transitionRule allows delegations whose parent matches from and child
+// matches to, at most maxCount times when maxCount > 0.
+type transitionRule struct {
+	from, to agentPattern
+	maxCount int
+}
+
+// parseTransitionRule accepts [from, to] or {"from", "to", "max_count"}.
+func parseTransitionRule(raw json.RawMessage) (*transitionRule, error) {
+	var from, to string
+	var maxCount int
+	var pair []string
+	if err := json.Unmarshal(raw, &pair); err == nil {
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("each transition must be [parent, child], got %v", pair)
+		}
+		from, to = pair[0], pair[1]
+	} else {
+		var obj struct {
+			From     string `json:"from"`
+			To       string `json:"to"`
+			MaxCount int    `json:"max_count"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil || obj.From == "" || obj.To == "" {
+			return nil, fmt.Errorf("each transition must be [parent, child] or {\"from\", \"to\", \"max_count\"}, got %s", raw)
+		}
+		if obj.MaxCount < 0 {
+			return nil, fmt.Errorf("transition %s -> %s: max_count must be >= 0", obj.From, obj.To)
+		}
+		from, to, maxCount = obj.From, obj.To, obj.MaxCount
+	}
+	rule := &transitionRule{maxCount: maxCount}
+	var err error
+	if rule.from, err = parseAgentPattern(from); err != nil {
+		return nil, err
+	}
+	if rule.to, err = parseAgentPattern(to); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// transitionEnd is one side of a delegation.
+type transitionEnd struct {
+	id, role string
+}
+
+// agentPattern matches an agent by exact ID, any agent ("*"), agent-ID regex
+// ("re:<pattern>", anchored), or agent role ("role:<role>").
+type agentPattern struct {
+	raw  string
+	id   string
+	role string
+	re   *regexp.Regexp
+	any  bool
+}
+
+func parseAgentPattern(p string) (agentPattern, error) {
+	switch {
+	case p == "*":
+		return agentPattern{raw: p, any: true}, nil
+	case strings.HasPrefix(p, "re:"):
+		re, err := regexp.Compile("^(?:" + p[len("re:"):] + ")$")
+		if err != nil {
+			return agentPattern{}, fmt.Errorf("invalid agent pattern %q: %v", p, err)
+		}
+		return agentPattern{raw: p, re: re}, nil
+	case strings.HasPrefix(p, "role:"):
+		if p == "role:" {
+			return agentPattern{}, fmt.Errorf("invalid agent pattern %q: empty role", p)
+		}
+		return agentPattern{raw: p, role: p[len("role:"):]}, nil
+	default:
+		return agentPattern{raw: p, id: p}, nil
+	}
+}
+
+func (p agentPattern) match(e transitionEnd) bool {
+	switch {
+	case p.any:
+		return true
+	case p.re != nil:
+		return p.re.MatchString(e.id)
+	case p.role != "":
+		return e.role == p.role
+	default:
+		return e.id == p.id
+	}
+}
+
 func checkAgentOrderedBefore(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
 		AgentA string `json:"agent_a"`
@@ -299,8 +480,8 @@ func checkAgentOrderedBefore(t *types.Trace, spec json.RawMessage) (bool, string
 		return false, "agent_ordered_before requires 'agent_a' and 'agent_b'"
 	}
 
-	stepsA := trace.CollectStepsByAgentID(t, s.AgentA)
-	stepsB := trace.CollectStepsByAgentID(t, s.AgentB)
+	stepsA := treeIndex(t).StepsByAgentID(s.AgentA)
+	stepsB := treeIndex(t).StepsByAgentID(s.AgentB)
 
 	if len(stepsA) == 0 {
 		return false, fmt.Sprintf("agent_ordered_before: no steps found for agent_a %q", s.AgentA)
@@ -349,8 +530,8 @@ func checkAgentsOverlap(t *types.Trace, spec json.RawMessage) (bool, string) {
 		return false, "agents_overlap requires 'agent_a' and 'agent_b'"
 	}
 
-	stepsA := trace.CollectStepsByAgentID(t, s.AgentA)
-	stepsB := trace.CollectStepsByAgentID(t, s.AgentB)
+	stepsA := treeIndex(t).StepsByAgentID(s.AgentA)
+	stepsB := treeIndex(t).StepsByAgentID(s.AgentB)
 
 	if len(stepsA) == 0 {
 		return false, fmt.Sprintf("agents_overlap: no steps found for agent_a %q", s.AgentA)
@@ -395,8 +576,8 @@ func checkAgentsOverlap(t *types.Trace, spec json.RawMessage) (bool, string) {
 
 func checkAgentWallTimeUnder(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
-		AgentID string  `json:"agent_id"`
-		MaxMS   float64 `json:"max_ms"`
+		AgentID string     `json:"agent_id"`
+		MaxMS   DurationMS `json:"max_ms"`
 	}
 	if err := json.Unmarshal(spec, &s); err != nil {
 		return false, fmt.Sprintf("agent_wall_time_under: invalid spec: %v", err)
@@ -408,7 +589,7 @@ func checkAgentWallTimeUnder(t *types.Trace, spec json.RawMessage) (bool, string
 		return false, "agent_wall_time_under requires 'max_ms' > 0"
 	}
 
-	steps := trace.CollectStepsByAgentID(t, s.AgentID)
+	steps := treeIndex(t).StepsByAgentID(s.AgentID)
 	if len(steps) == 0 {
 		return false, fmt.Sprintf("agent_wall_time_under: no steps found for agent_id %q", s.AgentID)
 	}
@@ -421,7 +602,7 @@ func checkAgentWallTimeUnder(t *types.Trace, spec json.RawMessage) (bool, string
 		totalMS += *step.EndedAtMs - *step.StartedAtMs
 	}
 
-	if float64(totalMS) >= s.MaxMS {
+	if float64(totalMS) >= float64(s.MaxMS) {
 		return false, fmt.Sprintf("agent_wall_time_under: agent %q total wall time %d ms >= max_ms %.4g", s.AgentID, totalMS, s.MaxMS)
 	}
 	return true, fmt.Sprintf("agent_wall_time_under: agent %q total wall time %d ms < max_ms %.4g.", s.AgentID, totalMS, s.MaxMS)
@@ -430,6 +611,13 @@ func checkAgentWallTimeUnder(t *types.Trace, spec json.RawMessage) (bool, string
 func checkOrderedAgents(t *types.Trace, spec json.RawMessage) (bool, string) {
 	var s struct {
 		Groups [][]string `json:"groups"`
+		// AllowMissing skips agents without steps, and groups with none present.
+		AllowMissing bool `json:"allow_missing"`
+		// Strict (default true) requires each group to end strictly before the
+		// next starts; false also accepts equal boundary timestamps.
+		Strict *bool `json:"strict"`
+		// MinDurationsMS holds a minimum span for each group, by index.
+		MinDurationsMS []DurationMS `json:"min_durations_ms"`
 	}
 	if err := json.Unmarshal(spec, &s); err != nil {
 		return false, fmt.Sprintf("ordered_agents: invalid spec: %v", err)
@@ -437,15 +625,22 @@ func checkOrderedAgents(t *types.Trace, spec json.RawMessage) (bool, string) {
 	if len(s.Groups) < 2 {
 		return false, "ordered_agents requires at least 2 groups"
 	}
+	if len(s.MinDurationsMS) > len(s.Groups) {
+		return false, fmt.Sprintf("ordered_agents: min_durations_ms has %d entries for %d groups", len(s.MinDurationsMS), len(s.Groups))
+	}
+	strict := s.Strict == nil || *s.Strict
 
 	// For each group, compute max ended_at_ms (all agents in group must complete).
-	// For each consecutive pair, max ended of group[i] < min started of group[i+1].
+	// For each consecutive pair, max ended of group[i] < min started of group[i+1]
+	// (<= when not strict).
 	type groupBounds struct {
+		index      int
 		maxEnded   int64
 		minStarted int64
 	}
 
-	bounds := make([]groupBounds, len(s.Groups))
+	bounds := make([]groupBounds, 0, len(s.Groups))
+	var skipped []int
 	for gi, group := range s.Groups {
 		if len(group) == 0 {
 			return false, fmt.Sprintf("ordered_agents: group %d is empty", gi)
@@ -453,8 +648,11 @@ func checkOrderedAgents(t *types.Trace, spec json.RawMessage) (bool, string) {
 		var maxEnded int64 = -1
 		var minStarted int64 = -1
 		for _, agentID := range group {
-			steps := trace.CollectStepsByAgentID(t, agentID)
+			steps := treeIndex(t).StepsByAgentID(agentID)
 			if len(steps) == 0 {
+				if s.AllowMissing {
+					continue
+				}
 				return false, fmt.Sprintf("ordered_agents: no steps found for agent %q in group %d", agentID, gi)
 			}
 			for _, step := range steps {
@@ -469,14 +667,27 @@ func checkOrderedAgents(t *types.Trace, spec json.RawMessage) (bool, string) {
 				}
 			}
 		}
-		bounds[gi] = groupBounds{maxEnded: maxEnded, minStarted: minStarted}
+		if minStarted == -1 {
+			skipped = append(skipped, gi)
+			continue
+		}
+		if gi < len(s.MinDurationsMS) {
+			if span := maxEnded - minStarted; float64(span) < float64(s.MinDurationsMS[gi]) {
+				return false, fmt.Sprintf("ordered_agents: group %d ran %d ms, below min_durations_ms %.4g", gi, span, s.MinDurationsMS[gi])
+			}
+		}
+		bounds = append(bounds, groupBounds{index: gi, maxEnded: maxEnded, minStarted: minStarted})
 	}
 
 	for i := 0; i < len(bounds)-1; i++ {
-		if bounds[i].maxEnded >= bounds[i+1].minStarted {
-			return false, fmt.Sprintf("ordered_agents: group %d max ended (%d ms) is not before group %d min started (%d ms)", i, bounds[i].maxEnded, i+1, bounds[i+1].minStarted)
+		a, b := bounds[i], bounds[i+1]
+		if a.maxEnded > b.minStarted || (strict && a.maxEnded == b.minStarted) {
+			return false, fmt.Sprintf("ordered_agents: group %d max ended (%d ms) is not before group %d min started (%d ms)", a.index, a.maxEnded, b.index, b.minStarted)
 		}
 	}
+	if len(skipped) > 0 {
+		return true, fmt.Sprintf("ordered_agents: %d present groups are sequentially ordered; skipped groups without steps: %v.", len(bounds), skipped)
+	}
 	return true, fmt.Sprintf("ordered_agents: all %d groups are sequentially ordered.", len(s.Groups))
 }
 
@@ -60,6 +60,34 @@ func TestTraceTreeEval_AgentCalled_NotFound(t *testing.T) {
 	}
 }
 
+func TestTraceTreeEval_AllToolsCalled(t *testing.T) {
+	search := types.Step{Type: types.StepTypeToolCall, Name: "search"}
+	write := types.Step{Type: types.StepTypeToolCall, Name: "write_file"}
+	grandchild := buildAgentTrace("writer", nil, nil, write)
+	child := buildAgentTrace("researcher", nil, nil, buildAgentStep(grandchild))
+	root := buildAgentTrace("root_agent", nil, nil, search, buildAgentStep(child))
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"root and nested tools", `{"check":"all_tools_called","tools":["search","write_file"]}`, types.StatusPass},
+		{"tool only in sub-trace", `{"check":"all_tools_called","tools":["write_file"]}`, types.StatusPass},
+		{"missing tool", `{"check":"all_tools_called","tools":["search","deploy"]}`, types.StatusHardFail},
+		{"no tools", `{"check":"all_tools_called"}`, types.StatusHardFail},
+	}
+	eval := &TraceTreeEvaluator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := eval.Evaluate(root, makeTreeAssertion(tt.spec))
+			if result.Status != tt.want {
+				t.Errorf("status = %q, want %q: %s", result.Status, tt.want, result.Explanation)
+			}
+		})
+	}
+}
+
 func TestTraceTreeEval_DelegationDepth_Pass(t *testing.T) {
 	child := buildAgentTrace("child_agent", nil, map[string]interface{}{"x": 1})
 	root := buildAgentTrace("root_agent", nil, map[string]interface{}{"ok": true}, buildAgentStep(child))
@@ -472,6 +500,75 @@ func TestTraceTreeEval_OrderedAgents_TooFewGroups(t *testing.T) {
 	}
 }
 
+func TestTraceTreeEval_OrderedAgents_Options(t *testing.T) {
+	// agent_a 100–200ms, agent_b 200–300ms (touching boundary), agent_d 400–450ms; agent_c absent.
+	root := buildAgentTrace("root_agent", nil, map[string]interface{}{"ok": true},
+		buildTimedStep("agent_a", 100, 200),
+		buildTimedStep("agent_b", 200, 300),
+		buildTimedStep("agent_d", 400, 450),
+	)
+	tests := []struct {
+		name       string
+		spec       string
+		wantStatus string
+	}{
+		{"equal boundary rejected by default", `{"check":"ordered_agents","groups":[["agent_a"],["agent_b"]]}`, types.StatusHardFail},
+		{"equal boundary accepted when not strict", `{"check":"ordered_agents","groups":[["agent_a"],["agent_b"]],"strict":false}`, types.StatusPass},
+		{"missing agent fails by default", `{"check":"ordered_agents","groups":[["agent_b"],["agent_c"],["agent_d"]]}`, types.StatusHardFail},
+		{"missing group skipped", `{"check":"ordered_agents","groups":[["agent_b"],["agent_c"],["agent_d"]],"allow_missing":true}`, types.StatusPass},
+		{"missing agent in present group skipped", `{"check":"ordered_agents","groups":[["agent_b","agent_c"],["agent_d"]],"allow_missing":true}`, types.StatusPass},
+		{"min duration met", `{"check":"ordered_agents","groups":[["agent_b"],["agent_d"]],"min_durations_ms":[100,"50ms"]}`, types.StatusPass},
+		{"min duration not met", `{"check":"ordered_agents","groups":[["agent_b"],["agent_d"]],"min_durations_ms":[0,"1s"]}`, types.StatusHardFail},
+		{"too many min durations", `{"check":"ordered_agents","groups":[["agent_b"],["agent_d"]],"min_durations_ms":[0,0,0]}`, types.StatusHardFail},
+	}
+	eval := &TraceTreeEvaluator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := eval.Evaluate(root, makeTreeAssertion(tt.spec))
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
+func TestTraceTreeEval_FollowsTransitions(t *testing.T) {
+	// orchestrator -> worker_1, worker_2 (role "worker"); worker_1 -> search
+	search := buildAgentTrace("search", nil, map[string]interface{}{"ok": true})
+	w1 := buildAgentTrace("worker_1", nil, map[string]interface{}{"ok": true}, buildAgentStep(search))
+	w2 := buildAgentTrace("worker_2", nil, map[string]interface{}{"ok": true})
+	s1, s2 := buildAgentStep(w1), buildAgentStep(w2)
+	s1.AgentRole, s2.AgentRole = "worker", "worker"
+	root := buildAgentTrace("orchestrator", nil, map[string]interface{}{"ok": true}, s1, s2)
+
+	tests := []struct {
+		name        string
+		transitions string
+		wantStatus  string
+	}{
+		{"exact pairs", `[["orchestrator","worker_1"],["orchestrator","worker_2"],["worker_1","search"]]`, types.StatusPass},
+		{"missing pair", `[["orchestrator","worker_1"],["orchestrator","worker_2"]]`, types.StatusHardFail},
+		{"wildcard child", `[["orchestrator","*"],["worker_1","search"]]`, types.StatusPass},
+		{"wildcard parent", `[["*","*"]]`, types.StatusPass},
+		{"regex", `[["orchestrator","re:worker_\\d+"],["re:worker_.*","search"]]`, types.StatusPass},
+		{"regex is anchored", `[["orchestrator","re:worker"],["worker_1","search"]]`, types.StatusHardFail},
+		{"role", `[["orchestrator","role:worker"],["role:worker","search"]]`, types.StatusPass},
+		{"max_count within", `[{"from":"orchestrator","to":"role:worker","max_count":2},["*","search"]]`, types.StatusPass},
+		{"max_count exceeded", `[{"from":"orchestrator","to":"*","max_count":1},["*","search"]]`, types.StatusHardFail},
+		{"invalid regex", `[["orchestrator","re:("]]`, types.StatusHardFail},
+		{"invalid object", `[{"from":"orchestrator"}]`, types.StatusHardFail},
+	}
+	eval := &TraceTreeEvaluator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := eval.Evaluate(root, makeTreeAssertion(`{"check":"follows_transitions","transitions":`+tt.transitions+`}`))
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q: %s", result.Status, tt.wantStatus, result.Explanation)
+			}
+		})
+	}
+}
+
 func TestTraceTreeEval_AggregateLatency(t *testing.T) {
 	latency1 := 200
 	latency2 := 150
@@ -0,0 +1,82 @@
+package assertion
+
+import (
+	"github.com/segmentio/encoding/json"
+	"sync"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// traceView memoizes JSON objects decoded from a trace's raw payloads so that
+// every evaluator in a batch shares one decode of a large output or step
+// result instead of each re-parsing it, and the tree index built for
+// trace_tree checks. Decoded maps are shared and must be treated as read-only.
+type traceView struct {
+	mu      sync.Mutex
+	objects map[rawKey]viewEntry
+
+	indexOnce sync.Once
+	index     *trace.TreeIndex
+}
+
+// rawKey identifies a raw payload by the address and length of its bytes;
+// payloads are never mutated while a batch is being evaluated.
+type rawKey struct {
+	ptr *byte
+	n   int
+}
+
+type viewEntry struct {
+	obj map[string]json.RawMessage
+	err error
+}
+
+// activeViews maps a *types.Trace under evaluation to its traceView.
+var activeViews sync.Map
+
+// shareTrace registers a traceView for t for the duration of a batch and
+// returns the function that releases it. Nested or concurrent batches on the
+// same trace reuse the first registration.
+func shareTrace(t *types.Trace) (release func()) {
+	v := &traceView{objects: make(map[rawKey]viewEntry)}
+	if _, loaded := activeViews.LoadOrStore(t, v); loaded {
+		return func() {}
+	}
+	return func() { activeViews.Delete(t) }
+}
+
+// decodeObject decodes raw as a JSON object, reusing an earlier decode of the
+// same bytes when t is registered by shareTrace.
+func decodeObject(t *types.Trace, raw json.RawMessage) (map[string]json.RawMessage, error) {
+	v, ok := activeViews.Load(t)
+	if !ok || len(raw) == 0 {
+		var obj map[string]json.RawMessage
+		err := json.Unmarshal(raw, &obj)
+		return obj, err
+	}
+	view := v.(*traceView)
+	key := rawKey{ptr: &raw[0], n: len(raw)}
+
+	view.mu.Lock()
+	defer view.mu.Unlock()
+	if e, ok := view.objects[key]; ok {
+		return e.obj, e.err
+	}
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(raw, &obj)
+	view.objects[key] = viewEntry{obj: obj, err: err}
+	return obj, err
+}
+
+// treeIndex returns the agent index of t's tree, built once per batch when t
+// is registered by shareTrace and on every call otherwise.
+func treeIndex(t *types.Trace) *trace.TreeIndex {
+	v, ok := activeViews.Load(t)
+	if !ok {
+		return trace.NewTreeIndex(t)
+	}
+	view := v.(*traceView)
+	view.indexOnce.Do(func() { view.index = trace.NewTreeIndex(t) })
+	return view.index
+}
@@ -0,0 +1,69 @@
+package assertion
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestDecodeObject_SharedWithinBatch(t *testing.T) {
+	trace := &types.Trace{Output: json.RawMessage(`{"message":"hi","structured":{"n":1}}`)}
+
+	a, err := decodeObject(trace, trace.Output)
+	if err != nil {
+		t.Fatalf("decodeObject: %v", err)
+	}
+	b, _ := decodeObject(trace, trace.Output)
+	if reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer() {
+		t.Error("decodes outside a batch should not be shared")
+	}
+
+	release := shareTrace(trace)
+	a, _ = decodeObject(trace, trace.Output)
+	b, _ = decodeObject(trace, trace.Output)
+	if reflect.ValueOf(a).Pointer() != reflect.ValueOf(b).Pointer() {
+		t.Error("decodes within a batch should share one map")
+	}
+	// A nested registration does not release the outer one.
+	shareTrace(trace)()
+	c, _ := decodeObject(trace, trace.Output)
+	if reflect.ValueOf(a).Pointer() != reflect.ValueOf(c).Pointer() {
+		t.Error("nested release dropped the outer view")
+	}
+	release()
+
+	if _, ok := activeViews.Load(trace); ok {
+		t.Error("view still registered after release")
+	}
+}
+
+func TestTreeIndex_SharedWithinBatch(t *testing.T) {
+	trace := &types.Trace{TraceID: "trc_root", AgentID: "root"}
+
+	if treeIndex(trace) == treeIndex(trace) {
+		t.Error("indexes outside a batch should not be shared")
+	}
+	release := shareTrace(trace)
+	defer release()
+	if treeIndex(trace) != treeIndex(trace) {
+		t.Error("indexes within a batch should be built once")
+	}
+}
+
+func TestResolveTarget_SharedViewSameResults(t *testing.T) {
+	trace := testTrace()
+	trace.Output = json.RawMessage(`{"message":"hi","structured":{"a":{"b":"deep"}}}`)
+	defer shareTrace(trace)()
+
+	for i := 0; i < 2; i++ {
+		got, err := ResolveTargetString(trace, "output.structured.a.b")
+		if err != nil || got != "deep" {
+			t.Fatalf("run %d: got %q, %v", i, got, err)
+		}
+	}
+	if _, err := ResolveTarget(trace, "output.missing"); err == nil {
+		t.Error("expected error for missing field")
+	}
+}
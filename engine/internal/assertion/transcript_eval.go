@@ -0,0 +1,229 @@
+package assertion
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Transcript check names.
+const (
+	TranscriptResolvedWithinTurns = "resolved_within_turns"
+	TranscriptNoRepetition        = "no_repetition_across_turns"
+)
+
+// defaultRepetitionThreshold is the similarity at or above which two turns
+// count as a repetition.
+const defaultRepetitionThreshold = 0.9
+
+// TranscriptEvaluator implements Layer 3 checks over a trace's multi-turn
+// transcript:
+//
+//   - resolved_within_turns: a message matching keywords or pattern appears
+//     within the first turns turns
+//   - no_repetition_across_turns: no turn's messages are a near-repeat of an
+//     earlier turn's, by word-frequency cosine similarity
+type TranscriptEvaluator struct{}
+
+type transcriptSpec struct {
+	Check string `json:"check"`
+	// Role selects the messages checked. Default: assistant.
+	Role string `json:"role"`
+	Soft bool   `json:"soft"`
+
+	// resolved_within_turns
+	Turns    int      `json:"turns"`
+	Keywords []string `json:"keywords"`
+	Pattern  string   `json:"pattern"`
+
+	// no_repetition_across_turns
+	Threshold *float64 `json:"threshold"`
+}
+
+// transcriptTurn holds the messages of one turn: a user message and the
+// messages that follow it up to the next user message.
+type transcriptTurn struct {
+	messages []types.Message
+}
+
+// content joins the content of the turn's messages with the given role.
+func (t transcriptTurn) content(role string) string {
+	var parts []string
+	for _, m := range t.messages {
+		if m.Role == role {
+			parts = append(parts, m.Content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// splitTurns groups a transcript into turns. Messages before the first user
+// message belong to turn 1.
+func splitTurns(transcript []types.Message) []transcriptTurn {
+	var turns []transcriptTurn
+	hasUser := false
+	for _, m := range transcript {
+		if len(turns) == 0 || (m.Role == types.RoleUser && hasUser) {
+			turns = append(turns, transcriptTurn{})
+			hasUser = false
+		}
+		if m.Role == types.RoleUser {
+			hasUser = true
+		}
+		turns[len(turns)-1].messages = append(turns[len(turns)-1].messages, m)
+	}
+	return turns
+}
+
+func (e *TranscriptEvaluator) Evaluate(trace *types.Trace, assertion *types.Assertion) *types.AssertionResult {
+	start := time.Now()
+
+	var spec transcriptSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return failResult(assertion, start, fmt.Sprintf("invalid transcript spec: %v", err))
+	}
+	if spec.Role == "" {
+		spec.Role = types.RoleAssistant
+	}
+	if len(trace.Transcript) == 0 {
+		return failResult(assertion, start, "trace has no transcript; record the conversation in the trace's transcript field")
+	}
+	turns := splitTurns(trace.Transcript)
+
+	var passed bool
+	var score float64
+	var explanation string
+	switch spec.Check {
+	case TranscriptResolvedWithinTurns:
+		if spec.Turns <= 0 {
+			return failResult(assertion, start, "resolved_within_turns requires 'turns' > 0")
+		}
+		if len(spec.Keywords) == 0 && spec.Pattern == "" {
+			return failResult(assertion, start, "resolved_within_turns requires 'keywords' or 'pattern'")
+		}
+		var re *regexp.Regexp
+		if spec.Pattern != "" {
+			var err error
+			if re, err = sharedRegexCache.compile(spec.Pattern); err != nil {
+				return failResult(assertion, start, fmt.Sprintf("invalid pattern %q: %v", spec.Pattern, err))
+			}
+		}
+		passed, explanation = checkResolvedWithinTurns(turns, spec, re)
+		if passed {
+			score = 1
+		}
+
+	case TranscriptNoRepetition:
+		threshold := defaultRepetitionThreshold
+		if spec.Threshold != nil {
+			threshold = *spec.Threshold
+		}
+		if threshold <= 0 || threshold > 1 {
+			return failResult(assertion, start, fmt.Sprintf("threshold must be in (0, 1], got %g", threshold))
+		}
+		passed, score, explanation = checkNoRepetition(turns, spec.Role, threshold)
+
+	case "":
+		return failResult(assertion, start, "transcript spec missing required field: check")
+	default:
+		return failResult(assertion, start, fmt.Sprintf("unsupported transcript check: %s (must be %s or %s)", spec.Check, TranscriptResolvedWithinTurns, TranscriptNoRepetition))
+	}
+
+	status := types.StatusPass
+	if !passed {
+		status = types.StatusHardFail
+		if spec.Soft {
+			status = types.StatusSoftFail
+		}
+	}
+	return &types.AssertionResult{
+		AssertionID: assertion.AssertionID,
+		Status:      status,
+		Score:       score,
+		Explanation: explanation,
+		DurationMS:  time.Since(start).Milliseconds(),
+		RequestID:   assertion.RequestID,
+	}
+}
+
+func checkResolvedWithinTurns(turns []transcriptTurn, spec transcriptSpec, re *regexp.Regexp) (bool, string) {
+	for i, turn := range turns {
+		for _, m := range turn.messages {
+			if m.Role != spec.Role {
+				continue
+			}
+			if re != nil && re.MatchString(m.Content) {
+				return resolvedAt(i+1, spec.Turns, fmt.Sprintf("pattern %q", spec.Pattern))
+			}
+			lower := strings.ToLower(m.Content)
+			for _, kw := range spec.Keywords {
+				if strings.Contains(lower, strings.ToLower(kw)) {
+					return resolvedAt(i+1, spec.Turns, fmt.Sprintf("keyword %q", kw))
+				}
+			}
+		}
+	}
+	return false, fmt.Sprintf("not resolved: no %s message in %d turns matches", spec.Role, len(turns))
+}
+
+func resolvedAt(turn, limit int, what string) (bool, string) {
+	if turn > limit {
+		return false, fmt.Sprintf("resolved at turn %d by %s, after the %d-turn limit", turn, what, limit)
+	}
+	return true, fmt.Sprintf("resolved at turn %d of %d by %s.", turn, limit, what)
+}
+
+func checkNoRepetition(turns []transcriptTurn, role string, threshold float64) (bool, float64, string) {
+	vectors := make([]map[string]float64, len(turns))
+	for i, turn := range turns {
+		vectors[i] = wordVector(turn.content(role))
+	}
+	worst, worstI, worstJ := 0.0, -1, -1
+	for j := range vectors {
+		for i := 0; i < j; i++ {
+			if sim := wordCosine(vectors[i], vectors[j]); sim > worst {
+				worst, worstI, worstJ = sim, i, j
+			}
+		}
+	}
+	score := 1 - worst
+	if worst >= threshold {
+		return false, score, fmt.Sprintf("%s turn %d repeats turn %d (similarity %.2f >= %.2f)", role, worstJ+1, worstI+1, worst, threshold)
+	}
+	if worstI < 0 {
+		return true, score, fmt.Sprintf("no repeated %s turns across %d turns.", role, len(turns))
+	}
+	return true, score, fmt.Sprintf("no repeated %s turns across %d turns; most similar are turns %d and %d (%.2f).", role, len(turns), worstI+1, worstJ+1, worst)
+}
+
+// wordVector counts the lowercase words of s.
+func wordVector(s string) map[string]float64 {
+	v := make(map[string]float64)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		v[w]++
+	}
+	return v
+}
+
+func wordCosine(a, b map[string]float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for w, x := range a {
+		dot += x * b[w]
+		na += x * x
+	}
+	for _, y := range b {
+		nb += y * y
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,81 @@
+package assertion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func transcriptTrace(msgs ...string) *types.Trace {
+	tr := &types.Trace{TraceID: "trc_transcript", Output: json.RawMessage(`{}`)}
+	for i, m := range msgs {
+		role := types.RoleUser
+		if i%2 == 1 {
+			role = types.RoleAssistant
+		}
+		tr.Transcript = append(tr.Transcript, types.Message{Role: role, Content: m})
+	}
+	return tr
+}
+
+func TestSplitTurns(t *testing.T) {
+	transcript := []types.Message{
+		{Role: types.RoleSystem, Content: "be nice"},
+		{Role: types.RoleUser, Content: "hi"},
+		{Role: types.RoleAssistant, Content: "hello"},
+		{Role: types.RoleTool, Content: "{}"},
+		{Role: types.RoleAssistant, Content: "done"},
+		{Role: types.RoleUser, Content: "thanks"},
+	}
+	turns := splitTurns(transcript)
+	if len(turns) != 2 || len(turns[0].messages) != 5 || len(turns[1].messages) != 1 {
+		t.Fatalf("turns = %+v", turns)
+	}
+	if got := turns[0].content(types.RoleAssistant); got != "hello\ndone" {
+		t.Errorf("turn 1 assistant content = %q", got)
+	}
+}
+
+func TestTranscriptEvaluator(t *testing.T) {
+	tr := transcriptTrace(
+		"My order never arrived.",
+		"I'm sorry to hear that. Can you share the order number?",
+		"It's 7.",
+		"I'm sorry to hear that. Can you share the order number please?",
+		"I already did!",
+		"Your refund has been issued.",
+	)
+	tests := []struct {
+		name       string
+		trace      *types.Trace
+		spec       string
+		wantStatus string
+		wantText   string
+	}{
+		{"resolved in time", tr, `{"check":"resolved_within_turns","turns":3,"keywords":["REFUND"]}`, types.StatusPass, "resolved at turn 3 of 3"},
+		{"resolved too late", tr, `{"check":"resolved_within_turns","turns":2,"keywords":["refund"]}`, types.StatusHardFail, "after the 2-turn limit"},
+		{"resolved by pattern", tr, `{"check":"resolved_within_turns","turns":1,"pattern":"order (number|id)"}`, types.StatusPass, "resolved at turn 1"},
+		{"user role", tr, `{"check":"resolved_within_turns","turns":3,"role":"user","keywords":["already"]}`, types.StatusPass, "turn 3"},
+		{"never resolved", tr, `{"check":"resolved_within_turns","turns":5,"keywords":["escalate"],"soft":true}`, types.StatusSoftFail, "not resolved"},
+		{"repetition", tr, `{"check":"no_repetition_across_turns"}`, types.StatusHardFail, "assistant turn 2 repeats turn 1"},
+		{"repetition below threshold", tr, `{"check":"no_repetition_across_turns","threshold":0.99}`, types.StatusPass, "most similar are turns 1 and 2"},
+		{"no repetition", transcriptTrace("a", "Hello there.", "b", "Your refund is on its way."), `{"check":"no_repetition_across_turns"}`, types.StatusPass, "no repeated assistant turns"},
+		{"no transcript", &types.Trace{TraceID: "x"}, `{"check":"no_repetition_across_turns"}`, types.StatusHardFail, "no transcript"},
+		{"missing turns", tr, `{"check":"resolved_within_turns","keywords":["x"]}`, types.StatusHardFail, "requires 'turns'"},
+		{"missing keywords", tr, `{"check":"resolved_within_turns","turns":1}`, types.StatusHardFail, "requires 'keywords' or 'pattern'"},
+		{"bad threshold", tr, `{"check":"no_repetition_across_turns","threshold":2}`, types.StatusHardFail, "threshold must be"},
+		{"unknown check", tr, `{"check":"vibes"}`, types.StatusHardFail, "unsupported transcript check"},
+	}
+	eval := &TranscriptEvaluator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &types.Assertion{AssertionID: "a", Type: types.TypeTranscript, Spec: json.RawMessage(tt.spec)}
+			result := eval.Evaluate(tt.trace, a)
+			if result.Status != tt.wantStatus || !strings.Contains(result.Explanation, tt.wantText) {
+				t.Errorf("got %s %q, want %s containing %q", result.Status, result.Explanation, tt.wantStatus, tt.wantText)
+			}
+		})
+	}
+}
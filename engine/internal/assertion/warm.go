@@ -0,0 +1,243 @@
+package assertion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// WarmReport summarizes a cache warming run.
+type WarmReport struct {
+	// Embedded counts texts embedded and written to the cache.
+	Embedded int `json:"embedded"`
+	// EmbeddingsCached counts texts whose embedding was already cached.
+	EmbeddingsCached int `json:"embeddings_cached"`
+	// Judged counts judge results computed and written to the cache.
+	Judged int `json:"judged"`
+	// JudgeCached counts judge results that were already cached.
+	JudgeCached int `json:"judge_cached"`
+	// Skipped counts (trace, assertion) pairs whose target does not resolve.
+	Skipped int `json:"skipped"`
+	// Duplicates counts assertions skipped because an earlier one in the
+	// suite checks the same thing under another ID.
+	Duplicates int      `json:"duplicates"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// WarmCache pre-computes embeddings and judge results so later evaluations
+// are served from cache. Every embedding reference is embedded; with traces,
+// each embedding and llm_judge assertion is also run against every trace
+// whose target resolves. Assertions identical to an earlier one apart from
+// their ID are warmed once. Other assertion types are ignored. Work runs on up
+// to concurrency goroutines (minimum 1).
+func WarmCache(reg *Registry, assertions []types.Assertion, traces []*types.Trace, concurrency int) *WarmReport {
+	report := &WarmReport{}
+	var mu sync.Mutex
+	fail := func(format string, args ...any) {
+		mu.Lock()
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+
+	var embedder *EmbeddingEvaluator
+	if ev, err := reg.Get(types.TypeEmbedding); err == nil {
+		embedder, _ = ev.(*EmbeddingEvaluator)
+	}
+	var judge *JudgeEvaluator
+	if ev, err := reg.Get(types.TypeLLMJudge); err == nil {
+		judge, _ = ev.(*JudgeEvaluator)
+	}
+
+	var jobs []func()
+	seenText := make(map[string]bool)
+	embedText := func(text string) {
+		if seenText[text] {
+			return
+		}
+		seenText[text] = true
+		jobs = append(jobs, func() {
+			cached, err := embedder.warm(context.Background(), text)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				report.Errors = append(report.Errors, fmt.Sprintf("embed %q: %v", truncateForReport(text), err))
+			case cached:
+				report.EmbeddingsCached++
+			default:
+				report.Embedded++
+			}
+		})
+	}
+
+	missingEmbedder, missingJudge := false, false
+	seen := make(map[string]bool)
+	for i := range assertions {
+		a := &assertions[i]
+		if a.Type != types.TypeEmbedding && a.Type != types.TypeLLMJudge {
+			continue
+		}
+		// Suites often repeat a check under several IDs; warm it once.
+		if fp, err := a.Fingerprint(); err == nil {
+			if seen[fp] {
+				report.Duplicates++
+				continue
+			}
+			seen[fp] = true
+		}
+		switch a.Type {
+		case types.TypeEmbedding:
+			if embedder == nil {
+				missingEmbedder = true
+				continue
+			}
+			var spec embeddingSpec
+			if err := json.Unmarshal(a.Spec, &spec); err != nil {
+				fail("%s: invalid embedding spec: %v", a.AssertionID, err)
+				continue
+			}
+			if spec.Reference != "" {
+				embedText(spec.Reference)
+			}
+			for _, t := range traces {
+				target, err := ResolveTargetString(t, spec.Target)
+				if err != nil || target == "" {
+					report.Skipped++
+					continue
+				}
+				chunks, err := embedder.chunks(target, spec)
+				if err != nil {
+					fail("%s: invalid embedding spec: %v", a.AssertionID, err)
+					break
+				}
+				for _, chunk := range chunks {
+					embedText(chunk)
+				}
+			}
+
+		case types.TypeLLMJudge:
+			if judge == nil {
+				missingJudge = true
+				continue
+			}
+			for _, t := range traces {
+				jobs = append(jobs, func() {
+					cached, skipped, err := judge.warm(t, a)
+					mu.Lock()
+					defer mu.Unlock()
+					switch {
+					case err != nil:
+						report.Errors = append(report.Errors, fmt.Sprintf("%s on trace %s: %v", a.AssertionID, t.TraceID, err))
+					case skipped:
+						report.Skipped++
+					case cached:
+						report.JudgeCached++
+					default:
+						report.Judged++
+					}
+				})
+			}
+		}
+	}
+	if missingEmbedder {
+		fail("embedding assertions present but no embedding provider is configured")
+	}
+	if missingJudge {
+		fail("llm_judge assertions present but no judge provider is configured")
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	queue := make(chan func())
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	return report
+}
+
+// warm ensures text's embedding is cached, reporting whether it already was.
+func (e *EmbeddingEvaluator) warm(ctx context.Context, text string) (cached bool, err error) {
+	if e.cache == nil {
+		return false, fmt.Errorf("embedding cache is not available")
+	}
+	vec, err := e.cache.GetChecked(cache.ContentHash(text), e.embedder.Model(), e.expectedMeta())
+	if err == nil && vec != nil {
+		return true, nil
+	}
+	_, err = e.getEmbedding(ctx, text, false)
+	return false, err
+}
+
+// warm ensures the judge result for assertion against trace is cached. skipped
+// is true when the target does not resolve in trace; cached is true when the
+// result was already present.
+func (e *JudgeEvaluator) warm(trace *types.Trace, assertion *types.Assertion) (cached, skipped bool, err error) {
+	if e.cache == nil {
+		return false, false, fmt.Errorf("judge cache is not available")
+	}
+	var spec judgeSpec
+	if err := json.Unmarshal(assertion.Spec, &spec); err != nil {
+		return false, false, fmt.Errorf("invalid judge spec: %w", err)
+	}
+	target, err := ResolveTargetString(trace, spec.Target)
+	if err != nil || target == "" {
+		return false, true, nil
+	}
+	rubricName := spec.Rubric
+	if rubricName == "" {
+		rubricName = "default"
+	}
+	rubric, err := e.rubrics.Get(rubricName)
+	if err != nil {
+		return false, false, err
+	}
+	model := spec.Model
+	if model == "" {
+		model = e.provider.DefaultModel()
+	}
+
+	plan, err := planLongTarget(spec, target, model)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid judge spec: %w", err)
+	}
+
+	hash := cache.JudgeContentHash(target)
+	rubricKey := judgeCacheRubric(rubric, assertion.Spec) + plan.cacheKey()
+	if entry, err := e.cache.Get(hash, rubricKey, model); err == nil && entry != nil {
+		return true, false, nil
+	}
+	result := e.Evaluate(trace, assertion)
+	if entry, err := e.cache.Get(hash, rubricKey, model); err == nil && entry != nil {
+		return false, false, nil
+	}
+	return false, false, fmt.Errorf("judge result not cached: %s", result.Explanation)
+}
+
+// truncateForReport shortens text for inclusion in a warm report error.
+func truncateForReport(text string) string {
+	const max = 60
+	r := []rune(text)
+	if len(r) <= max {
+		return text
+	}
+	return string(r[:max]) + "..."
+}
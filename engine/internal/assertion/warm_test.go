@@ -0,0 +1,70 @@
+package assertion
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestWarmCache_PopulatesEmbeddingAndJudgeCaches(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	embedder := &mockEmbedder{model: "mock-embed"}
+	provider := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: `{"score": 0.9, "explanation": "good"}`, Model: "mock-model"},
+	}, nil)
+	reg := NewRegistry(
+		WithEmbedding(embedder, store.Embeddings()),
+		WithJudge(provider, judge.NewRubricRegistry(), store.Judge()),
+	)
+
+	assertions := []types.Assertion{
+		{AssertionID: "emb_1", Type: types.TypeEmbedding, Spec: json.RawMessage(`{"target":"output","reference":"ref"}`)},
+		{AssertionID: "emb_2", Type: types.TypeEmbedding, Spec: json.RawMessage(`{"target":"output","reference":"ref"}`)},
+		{AssertionID: "judge_1", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{"target":"output","criteria":"helpful"}`)},
+		{AssertionID: "judge_2", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{"criteria": "helpful", "target": "output"}`)},
+		{AssertionID: "judge_missing", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{"target":"steps[name=absent].result"}`)},
+		{AssertionID: "schema_1", Type: types.TypeSchema, Spec: json.RawMessage(`{}`)},
+	}
+	traces := []*types.Trace{testTrace()}
+
+	report := WarmCache(reg, assertions, traces, 2)
+	if len(report.Errors) != 0 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+	// "ref" and the trace output are each embedded once despite two assertions.
+	if report.Embedded != 2 || report.Judged != 1 || report.Skipped != 1 {
+		t.Errorf("report = %+v, want 2 embedded, 1 judged, 1 skipped", report)
+	}
+	// emb_2 and judge_2 repeat emb_1 and judge_1.
+	if report.Duplicates != 2 {
+		t.Errorf("duplicates = %d, want 2", report.Duplicates)
+	}
+
+	calls := embedder.callCount.Load()
+	report = WarmCache(reg, assertions, traces, 2)
+	if report.EmbeddingsCached != 2 || report.JudgeCached != 1 || report.Embedded != 0 || report.Judged != 0 {
+		t.Errorf("second run report = %+v, want everything cached", report)
+	}
+	if embedder.callCount.Load() != calls || provider.CallCount != 1 {
+		t.Errorf("second run made provider calls: embed %d→%d, judge %d", calls, embedder.callCount.Load(), provider.CallCount)
+	}
+}
+
+func TestWarmCache_ReportsMissingProviders(t *testing.T) {
+	report := WarmCache(NewRegistry(), []types.Assertion{
+		{AssertionID: "emb", Type: types.TypeEmbedding, Spec: json.RawMessage(`{"target":"output","reference":"ref"}`)},
+		{AssertionID: "judge", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{"target":"output"}`)},
+	}, nil, 1)
+	if len(report.Errors) != 2 {
+		t.Errorf("errors = %v, want one per missing provider", report.Errors)
+	}
+}
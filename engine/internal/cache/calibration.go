@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CalibrationStore persists one judge calibration curve per rubric and
+// model. Curves are stored as the caller's JSON encoding.
+type CalibrationStore struct {
+	db     *sql.DB
+	writer *sqliteWriter
+}
+
+// Put stores curve for rubric and model, replacing any earlier curve.
+func (c *CalibrationStore) Put(rubric, model string, curve []byte) error {
+	now := time.Now().UnixNano()
+	return c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(
+			`INSERT OR REPLACE INTO judge_calibrations (rubric, model, curve, created_at) VALUES (?, ?, ?, ?)`,
+			rubric, model, string(curve), now,
+		)
+		if err != nil {
+			return fmt.Errorf("put calibration: %w", err)
+		}
+		return nil
+	})
+}
+
+// Get returns the curve for rubric and model, or nil when none is stored.
+func (c *CalibrationStore) Get(rubric, model string) ([]byte, error) {
+	var curve string
+	err := c.db.QueryRow(
+		`SELECT curve FROM judge_calibrations WHERE rubric = ? AND model = ?`,
+		rubric, model,
+	).Scan(&curve)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get calibration: %w", err)
+	}
+	return []byte(curve), nil
+}
+
+// Delete removes the curve for rubric and model, if any.
+func (c *CalibrationStore) Delete(rubric, model string) error {
+	return c.writer.exec(func(db *sql.DB) error {
+		if _, err := db.Exec(`DELETE FROM judge_calibrations WHERE rubric = ? AND model = ?`, rubric, model); err != nil {
+			return fmt.Errorf("delete calibration: %w", err)
+		}
+		return nil
+	})
+}
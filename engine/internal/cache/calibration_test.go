@@ -0,0 +1,34 @@
+package cache
+
+import "testing"
+
+func TestCalibrationStore_PutGetDelete(t *testing.T) {
+	s, err := OpenMemoryStore(StoreConfig{})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer s.Close()
+	c := s.Calibrations()
+
+	if got, err := c.Get("helpfulness", "gpt-4.1"); err != nil || got != nil {
+		t.Fatalf("Get on empty store = %q, %v", got, err)
+	}
+	if err := c.Put("helpfulness", "gpt-4.1", []byte(`{"v":1}`)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := c.Put("helpfulness", "gpt-4.1", []byte(`{"v":2}`)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got, _ := c.Get("helpfulness", "gpt-4.1"); string(got) != `{"v":2}` {
+		t.Errorf("Get = %q, want the replacement curve", got)
+	}
+	if got, _ := c.Get("helpfulness", "gpt-4.1-mini"); got != nil {
+		t.Errorf("Get for another model = %q, want nil", got)
+	}
+	if err := c.Delete("helpfulness", "gpt-4.1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, _ := c.Get("helpfulness", "gpt-4.1"); got != nil {
+		t.Errorf("Get after Delete = %q", got)
+	}
+}
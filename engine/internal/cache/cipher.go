@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the length in bytes of a cache encryption key (AES-256).
+const KeySize = 32
+
+// Cipher encrypts cache entries at rest with AES-256-GCM. Each sealed value
+// is bound to the key of the row or remote entry it is stored under, so an
+// entry copied to another row fails to open.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns a Cipher for a KeySize-byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("cache key is %d bytes, want %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cache key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cache key: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// ParseKey decodes a KeySize-byte key written as base64 or hex.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if key, err := decode(s); err == nil && len(key) == KeySize {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("cache key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// Seal encrypts plain for storage under id, returning nonce || ciphertext.
+func (c *Cipher) Seal(plain []byte, id string) []byte {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plain)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("cache cipher: read nonce: %v", err))
+	}
+	return c.aead.Seal(nonce, nonce, plain, []byte(id))
+}
+
+// Open decrypts a value sealed under id. It fails when the value was sealed
+// with another key or under another id, or has been tampered with.
+func (c *Cipher) Open(sealed []byte, id string) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(sealed) < n+c.aead.Overhead() {
+		return nil, errors.New("sealed cache entry is truncated")
+	}
+	plain, err := c.aead.Open(nil, sealed[:n], sealed[n:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("open sealed cache entry: %w", err)
+	}
+	return plain, nil
+}
+
+// encryptedRemote seals values on their way to a remote tier and opens them
+// on the way back. Values that fail to open are reported as errors, which the
+// caches count and treat as misses.
+type encryptedRemote struct {
+	RemoteCache
+	cipher *Cipher
+}
+
+func (r *encryptedRemote) Get(key string) ([]byte, error) {
+	b, err := r.RemoteCache.Get(key)
+	if err != nil || b == nil {
+		return b, err
+	}
+	return r.cipher.Open(b, key)
+}
+
+func (r *encryptedRemote) SetIfAbsent(key string, value []byte) (bool, error) {
+	return r.RemoteCache.SetIfAbsent(key, r.cipher.Seal(value, key))
+}
@@ -0,0 +1,140 @@
+package cache_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+func testCipher(t *testing.T, fill byte) *cache.Cipher {
+	t.Helper()
+	c, err := cache.NewCipher(bytes.Repeat([]byte{fill}, cache.KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	return c
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, cache.KeySize)
+	for _, s := range []string{
+		base64.StdEncoding.EncodeToString(key),
+		base64.RawStdEncoding.EncodeToString(key) + "\n",
+		"0707070707070707070707070707070707070707070707070707070707070707",
+	} {
+		got, err := cache.ParseKey(s)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("ParseKey(%q) = %x, %v", s, got, err)
+		}
+	}
+	if _, err := cache.ParseKey("too-short"); err == nil {
+		t.Error("ParseKey accepted a short key")
+	}
+}
+
+func TestStore_EncryptsAtRest(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "attest.db")
+	open := func(c *cache.Cipher) *cache.Store {
+		t.Helper()
+		store, err := cache.OpenStore(dbPath, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10, Cipher: c})
+		if err != nil {
+			t.Fatalf("OpenStore: %v", err)
+		}
+		return store
+	}
+	const secret = "the customer's card number is on file"
+
+	store := open(testCipher(t, 1))
+	if err := store.Embeddings().Put("h", "m", []float32{1, 2}); err != nil {
+		t.Fatalf("embeddings Put: %v", err)
+	}
+	if err := store.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 0.9, Explanation: secret}); err != nil {
+		t.Fatalf("judge Put: %v", err)
+	}
+	if e, err := store.Judge().Get("h", "rubric", "m"); err != nil || e == nil || e.Explanation != secret {
+		t.Fatalf("judge Get = %+v, %v; want the explanation back", e, err)
+	}
+	if v, err := store.Embeddings().Get("h", "m"); err != nil || len(v) != 2 || v[1] != 2 {
+		t.Fatalf("embeddings Get = %v, %v; want [1 2]", v, err)
+	}
+	store.Close()
+
+	db, err := cache.OpenDB(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	var raw []byte
+	if err := db.QueryRow(`SELECT explanation FROM judge_cache`).Scan(&raw); err != nil {
+		t.Fatalf("read raw explanation: %v", err)
+	}
+	db.Close()
+	if bytes.Contains(raw, []byte("card number")) {
+		t.Error("judge explanation stored in plaintext")
+	}
+
+	// Without the key entries are a miss; a wrong key discards them.
+	store = open(nil)
+	if e, _ := store.Judge().Get("h", "rubric", "m"); e != nil {
+		t.Errorf("judge Get without key = %+v, want miss", e)
+	}
+	store.Close()
+	store = open(testCipher(t, 2))
+	defer store.Close()
+	if v, _ := store.Embeddings().Get("h", "m"); v != nil {
+		t.Errorf("embeddings Get with wrong key = %v, want miss", v)
+	}
+	if e, _ := store.Judge().Get("h", "rubric", "m"); e != nil {
+		t.Errorf("judge Get with wrong key = %+v, want miss", e)
+	}
+}
+
+func TestStore_EncryptionPurgesPlaintext(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "attest.db")
+	store, err := cache.OpenStore(dbPath, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	if err := store.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 1, Explanation: "plain"}); err != nil {
+		t.Fatalf("judge Put: %v", err)
+	}
+	store.Close()
+
+	store, err = cache.OpenStore(dbPath, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10, Cipher: testCipher(t, 1)})
+	if err != nil {
+		t.Fatalf("reopen with key: %v", err)
+	}
+	defer store.Close()
+	if stats, _ := store.Judge().Stats(); stats.Entries != 0 {
+		t.Errorf("judge entries after enabling encryption = %d, want 0", stats.Entries)
+	}
+}
+
+func TestStore_EncryptsRemoteTier(t *testing.T) {
+	remote := newMapRemote()
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10, Remote: remote, Cipher: testCipher(t, 1)})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	if err := store.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 1, Explanation: "sensitive"}); err != nil {
+		t.Fatalf("judge Put: %v", err)
+	}
+	for key, v := range remote.data {
+		if bytes.Contains(v, []byte("sensitive")) {
+			t.Errorf("remote entry %s stored in plaintext", key)
+		}
+	}
+
+	// Another worker holding the key reads the entry through the remote tier.
+	other, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10, Remote: remote, Cipher: testCipher(t, 1)})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer other.Close()
+	if e, err := other.Judge().Get("h", "rubric", "m"); err != nil || e == nil || e.Explanation != "sensitive" {
+		t.Errorf("remote judge Get = %+v, %v; want the explanation back", e, err)
+	}
+}
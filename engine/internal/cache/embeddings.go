@@ -7,145 +7,158 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
-	"sync"
 	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-const (
-	// lruFlushInterval is how often deferred LRU writes are flushed to SQLite.
-	lruFlushInterval = 5 * time.Second
-	// lruFlushThreshold triggers a flush when the pending map reaches this size.
-	lruFlushThreshold = 64
-)
-
-// lruKey is the composite key for deferred LRU writes.
-type lruKey struct {
-	contentHash string
-	model       string
-}
-
 // EmbeddingCache is an LRU-evicting SQLite-backed cache for embedding vectors.
 type EmbeddingCache struct {
-	db    *sql.DB
-	maxMB int
-
-	// Deferred LRU writes: buffer accessed_at updates and flush periodically.
-	pendingLRU sync.Map    // map[lruKey]int64 (UnixNano)
-	pendingLen atomic.Int64
-	stopFlush  chan struct{}
-	flushDone  chan struct{}
+	db     *sql.DB
+	writer *sqliteWriter
+	maxMB  int
+	// owned is true when the cache opened db itself and must close it.
+	owned bool
+
+	// lru buffers accessed_at updates and flushes them periodically.
+	lru *deferredLRU
+
+	rejected atomic.Int64
+
+	// ns scopes entries to the namespace set by SetNamespace.
+	ns namespace
+
+	// cipher, when set, encrypts vectors at rest.
+	cipher *Cipher
+
+	// remote is the optional shared tier consulted on local misses.
+	remote       RemoteCache
+	remoteHits   atomic.Int64
+	remoteErrors atomic.Int64
 }
 
 // CacheStats reports current usage of the embedding cache.
 type CacheStats struct {
 	Entries    int
 	TotalBytes int64
+	// Rejected counts entries discarded as stale or corrupt since the cache was opened.
+	Rejected int64
+	// RemoteHits counts local misses served by the remote tier.
+	RemoteHits int64
+	// RemoteErrors counts remote tier failures, which are treated as misses.
+	RemoteErrors int64
 }
 
 // NewEmbeddingCache opens (or creates) an embedding cache at dbPath.
 // maxMB sets the maximum size in megabytes before LRU eviction triggers.
+// The cache owns its database handle; use Store to share attest.db.
 func NewEmbeddingCache(dbPath string, maxMB int) (*EmbeddingCache, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := OpenDB(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite: %w", err)
+		return nil, err
 	}
-
-	if _, err := db.Exec(`PRAGMA journal_mode=WAL`); err != nil {
+	if err := Migrate(db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("set WAL mode: %w", err)
+		return nil, err
 	}
 
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS embeddings (
-			content_hash TEXT NOT NULL,
-			model        TEXT NOT NULL,
-			vector       BLOB NOT NULL,
-			created_at   INTEGER NOT NULL,
-			accessed_at  INTEGER NOT NULL,
-			PRIMARY KEY (content_hash, model)
-		)
-	`); err != nil {
+	w := newSQLiteWriter(db)
+	c, err := newEmbeddingCache(db, w, maxMB)
+	if err != nil {
+		w.close()
 		db.Close()
-		return nil, fmt.Errorf("create table: %w", err)
+		return nil, err
 	}
+	c.owned = true
+	return c, nil
+}
 
-	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_accessed ON embeddings(accessed_at)`); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("create index: %w", err)
+// newEmbeddingCache wraps an already-migrated database, verifies cache
+// integrity, and starts the deferred LRU flush loop.
+func newEmbeddingCache(db *sql.DB, w *sqliteWriter, maxMB int) (*EmbeddingCache, error) {
+	c := &EmbeddingCache{
+		db:     db,
+		writer: w,
+		maxMB:  maxMB,
 	}
 
-	c := &EmbeddingCache{
-		db:        db,
-		maxMB:     maxMB,
-		stopFlush: make(chan struct{}),
-		flushDone: make(chan struct{}),
+	if _, err := c.CheckIntegrity(); err != nil {
+		return nil, fmt.Errorf("integrity check: %w", err)
 	}
 
-	go c.flushLoop()
+	c.lru = newDeferredLRU(w,
+		`UPDATE embeddings SET accessed_at = ? WHERE content_hash = ? AND model = ?`,
+		func(k lruKey, ts int64) []any { return []any{ts, k.contentHash, k.model} },
+	)
 
 	return c, nil
 }
 
-// flushLoop periodically writes buffered accessed_at updates to SQLite.
-func (c *EmbeddingCache) flushLoop() {
-	defer close(c.flushDone)
-	ticker := time.NewTicker(lruFlushInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			c.FlushLRU()
-		case <-c.stopFlush:
-			c.FlushLRU()
-			return
-		}
-	}
-}
-
-// FlushLRU writes all pending accessed_at updates to SQLite in a single transaction.
-func (c *EmbeddingCache) FlushLRU() {
-	if c.pendingLen.Load() == 0 {
-		return
+// CheckIntegrity verifies the cache database and removes unusable entries.
+// When SQLite reports structural corruption the embeddings table is dropped and
+// rebuilt empty; otherwise rows whose blob length does not match their stored
+// dimension are deleted. Returns the number of entries removed.
+func (c *EmbeddingCache) CheckIntegrity() (int64, error) {
+	var result string
+	if err := c.db.QueryRow(`PRAGMA quick_check`).Scan(&result); err != nil || result != "ok" {
+		return c.rebuild()
 	}
 
-	// Collect and clear pending entries.
-	type entry struct {
-		key lruKey
-		ts  int64
-	}
-	var entries []entry
-	c.pendingLRU.Range(func(k, v any) bool {
-		entries = append(entries, entry{key: k.(lruKey), ts: v.(int64)})
-		c.pendingLRU.Delete(k)
-		return true
+	var n int64
+	err := c.writer.exec(func(db *sql.DB) error {
+		res, err := db.Exec(`
+			DELETE FROM embeddings
+			WHERE encrypted = 0 AND (
+			      LENGTH(vector) % 4 != 0
+			   OR LENGTH(vector) = 0
+			   OR (dimension > 0 AND LENGTH(vector) != dimension * 4))
+		`)
+		if err != nil {
+			return err
+		}
+		n, _ = res.RowsAffected()
+		return nil
 	})
-	c.pendingLen.Store(0)
-
-	if len(entries) == 0 {
-		return
-	}
-
-	tx, err := c.db.Begin()
 	if err != nil {
-		return
+		return c.rebuild()
 	}
+	c.rejected.Add(n)
+	return n, nil
+}
 
-	stmt, err := tx.Prepare(`UPDATE embeddings SET accessed_at = ? WHERE content_hash = ? AND model = ?`)
-	if err != nil {
-		tx.Rollback()
-		return
+// rebuild drops and recreates the embeddings table, discarding all entries.
+func (c *EmbeddingCache) rebuild() (int64, error) {
+	var n int64
+	_ = c.db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&n)
+	if err := c.writer.exec(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DROP TABLE IF EXISTS embeddings`); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("drop corrupt embeddings table: %w", err)
+		}
+		if err := replayTableMigrations(tx, "embeddings"); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}); err != nil {
+		return 0, err
 	}
-	defer stmt.Close()
+	c.rejected.Add(n)
+	return n, nil
+}
 
-	for _, e := range entries {
-		_, _ = stmt.Exec(e.ts, e.key.contentHash, e.key.model)
-	}
+// SetNamespace scopes later reads and writes to ns, so projects sharing
+// attest.db do not serve each other's vectors. "" is the default namespace.
+func (c *EmbeddingCache) SetNamespace(ns string) { c.ns.set(ns) }
 
-	_ = tx.Commit()
+// FlushLRU writes all pending accessed_at updates to SQLite in a single transaction.
+func (c *EmbeddingCache) FlushLRU() {
+	c.lru.flush()
 }
 
 // ContentHash returns the SHA-256 hex digest of the given text.
@@ -154,49 +167,152 @@ func ContentHash(text string) string {
 	return hex.EncodeToString(sum[:])
 }
 
+// EmbeddingMeta describes the model version that produced a vector. Zero
+// fields are unknown and not checked.
+type EmbeddingMeta struct {
+	Dimension int
+	Revision  string
+}
+
 // Get retrieves a cached vector for the given content and model.
 // Returns (nil, nil) on cache miss.
 func (c *EmbeddingCache) Get(contentHash, model string) ([]float32, error) {
+	return c.GetChecked(contentHash, model, EmbeddingMeta{})
+}
+
+// GetChecked retrieves a cached vector, rejecting it when its stored dimension
+// or revision differs from want, or when the stored blob is corrupt. Rejected
+// entries are deleted and reported as a miss (nil, nil) so the caller re-embeds;
+// so are encrypted entries that fail to open under the cache key. Encrypted
+// entries read without a cache key are a miss but are kept. On a local miss the remote tier, if configured, is consulted and a hit is
+// written through to SQLite.
+func (c *EmbeddingCache) GetChecked(contentHash, model string, want EmbeddingMeta) ([]float32, error) {
+	contentHash = c.ns.key(contentHash)
+	vec, err := c.getLocal(contentHash, model, want)
+	if err != nil || vec != nil || c.remote == nil {
+		return vec, err
+	}
+	return c.getRemote(contentHash, model, want), nil
+}
+
+func (c *EmbeddingCache) getLocal(contentHash, model string, want EmbeddingMeta) ([]float32, error) {
 	row := c.db.QueryRow(
-		`SELECT vector FROM embeddings WHERE content_hash = ? AND model = ?`,
+		`SELECT vector, dimension, revision, encrypted FROM embeddings WHERE content_hash = ? AND model = ?`,
 		contentHash, model,
 	)
 
 	var blob []byte
-	if err := row.Scan(&blob); err != nil {
+	var got EmbeddingMeta
+	var encrypted bool
+	if err := row.Scan(&blob, &got.Dimension, &got.Revision, &encrypted); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("get embedding: %w", err)
 	}
+	if encrypted && c.cipher == nil {
+		// Written by an engine holding the cache key; unreadable without it.
+		return nil, nil
+	}
 
-	// Buffer accessed_at update instead of writing to SQLite on every Get.
-	key := lruKey{contentHash: contentHash, model: model}
-	c.pendingLRU.Store(key, time.Now().UnixNano())
-	n := c.pendingLen.Add(1)
-	if n >= lruFlushThreshold {
-		go c.FlushLRU()
+	var err error
+	if encrypted {
+		blob, err = c.cipher.Open(blob, embeddingRowID(contentHash, model))
 	}
+	var vec []float32
+	if err == nil {
+		vec, err = blobToVector(blob)
+	}
+	stale := err != nil ||
+		(got.Dimension > 0 && len(vec) != got.Dimension) ||
+		(want.Dimension > 0 && len(vec) != want.Dimension) ||
+		(want.Revision != "" && got.Revision != want.Revision)
+	if stale {
+		c.rejected.Add(1)
+		if delErr := c.writer.exec(func(db *sql.DB) error {
+			_, err := db.Exec(
+				`DELETE FROM embeddings WHERE content_hash = ? AND model = ?`,
+				contentHash, model,
+			)
+			return err
+		}); delErr != nil {
+			return nil, fmt.Errorf("delete stale embedding: %w", delErr)
+		}
+		return nil, nil
+	}
+
+	// Buffer accessed_at update instead of writing to SQLite on every Get.
+	c.lru.touch(lruKey{contentHash: contentHash, model: model})
 
-	return blobToVector(blob)
+	return vec, nil
+}
+
+// getRemote fetches a vector from the remote tier and writes it through to
+// SQLite. Remote failures and invalid entries are reported as a miss.
+func (c *EmbeddingCache) getRemote(contentHash, model string, want EmbeddingMeta) []float32 {
+	b, err := c.remote.Get(embeddingRemoteKey(contentHash, model, want.Revision))
+	if err != nil {
+		c.remoteErrors.Add(1)
+		return nil
+	}
+	if b == nil {
+		return nil
+	}
+	vec, err := blobToVector(b)
+	if err != nil || len(vec) == 0 || (want.Dimension > 0 && len(vec) != want.Dimension) {
+		c.rejected.Add(1)
+		return nil
+	}
+	c.remoteHits.Add(1)
+	_ = c.putLocal(contentHash, model, want.Revision, vec)
+	return vec
 }
 
 // Put stores a vector for the given content and model, then evicts if over size limit.
 func (c *EmbeddingCache) Put(contentHash, model string, vector []float32) error {
+	return c.PutWithRevision(contentHash, model, "", vector)
+}
+
+// PutWithRevision stores a vector tagged with the producing model revision and
+// its dimension, then evicts if over size limit. With a remote tier configured
+// the vector is also published there unless another worker got there first;
+// remote failures are counted but not returned.
+func (c *EmbeddingCache) PutWithRevision(contentHash, model, revision string, vector []float32) error {
+	contentHash = c.ns.key(contentHash)
+	if err := c.putLocal(contentHash, model, revision, vector); err != nil {
+		return err
+	}
+	if c.remote != nil {
+		if _, err := c.remote.SetIfAbsent(embeddingRemoteKey(contentHash, model, revision), vectorToBlob(vector)); err != nil {
+			c.remoteErrors.Add(1)
+		}
+	}
+	return c.evictIfNeeded()
+}
+
+func (c *EmbeddingCache) putLocal(contentHash, model, revision string, vector []float32) error {
 	blob := vectorToBlob(vector)
+	encrypted := c.cipher != nil
+	if encrypted {
+		blob = c.cipher.Seal(blob, embeddingRowID(contentHash, model))
+	}
 	now := time.Now().UnixNano()
 
-	_, err := c.db.Exec(
-		`INSERT INTO embeddings(content_hash, model, vector, created_at, accessed_at)
-		 VALUES(?, ?, ?, ?, ?)
-		 ON CONFLICT(content_hash, model) DO UPDATE SET vector=excluded.vector, accessed_at=excluded.accessed_at`,
-		contentHash, model, blob, now, now,
-	)
+	err := c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(
+			`INSERT INTO embeddings(content_hash, model, vector, dimension, revision, encrypted, created_at, accessed_at)
+			 VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(content_hash, model) DO UPDATE SET
+			   vector=excluded.vector, dimension=excluded.dimension, revision=excluded.revision,
+			   encrypted=excluded.encrypted, accessed_at=excluded.accessed_at`,
+			contentHash, model, blob, len(vector), revision, encrypted, now, now,
+		)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("put embedding: %w", err)
 	}
-
-	return c.evictIfNeeded()
+	return nil
 }
 
 // Evict removes the least-recently-used entries until the cache is under maxMB.
@@ -211,22 +327,32 @@ func (c *EmbeddingCache) Stats() (*CacheStats, error) {
 	if err := row.Scan(&stats.Entries, &stats.TotalBytes); err != nil {
 		return nil, fmt.Errorf("stats query: %w", err)
 	}
+	stats.Rejected = c.rejected.Load()
+	stats.RemoteHits = c.remoteHits.Load()
+	stats.RemoteErrors = c.remoteErrors.Load()
 	return &stats, nil
 }
 
 // Clear removes all cached entries.
 func (c *EmbeddingCache) Clear() error {
-	if _, err := c.db.Exec(`DELETE FROM embeddings`); err != nil {
+	if err := c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(`DELETE FROM embeddings`)
+		return err
+	}); err != nil {
 		return fmt.Errorf("clear cache: %w", err)
 	}
 	return nil
 }
 
-// Close flushes pending LRU writes, stops the background flush loop,
-// and releases the database connection.
+// Close flushes pending LRU writes and stops the background flush loop. When
+// the cache owns its database it also stops the writer (checkpointing the WAL)
+// and releases the connection; a cache handed out by Store leaves that to Store.Close.
 func (c *EmbeddingCache) Close() error {
-	close(c.stopFlush)
-	<-c.flushDone
+	c.lru.close()
+	if !c.owned {
+		return nil
+	}
+	c.writer.close()
 	return c.db.Close()
 }
 
@@ -261,10 +387,13 @@ func (c *EmbeddingCache) evictIfNeeded() error {
 	}
 
 	// Pure SQL batch eviction: delete LRU rows without loading into Go.
-	_, err := c.db.Exec(
-		`DELETE FROM embeddings WHERE rowid IN (SELECT rowid FROM embeddings ORDER BY accessed_at ASC LIMIT ?)`,
-		deleteCount,
-	)
+	err := c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(
+			`DELETE FROM embeddings WHERE rowid IN (SELECT rowid FROM embeddings ORDER BY accessed_at ASC LIMIT ?)`,
+			deleteCount,
+		)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("evict delete: %w", err)
 	}
@@ -272,6 +401,11 @@ func (c *EmbeddingCache) evictIfNeeded() error {
 	return nil
 }
 
+// embeddingRowID binds a sealed vector to its row.
+func embeddingRowID(contentHash, model string) string {
+	return "emb:" + model + ":" + contentHash
+}
+
 // vectorToBlob encodes []float32 as little-endian bytes.
 func vectorToBlob(v []float32) []byte {
 	buf := make([]byte, len(v)*4)
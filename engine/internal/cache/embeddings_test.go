@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"database/sql"
 	"path/filepath"
 	"testing"
 
@@ -190,3 +191,131 @@ func TestContentHash_Distinct(t *testing.T) {
 		t.Error("ContentHash should differ for different inputs")
 	}
 }
+
+func TestEmbeddingCache_RejectsDimensionAndRevisionMismatch(t *testing.T) {
+	c := newTestCache(t, 10)
+	hash := cache.ContentHash("hello")
+	model := "mini"
+
+	if err := c.PutWithRevision(hash, model, "v1", []float32{0.1, 0.2, 0.3}); err != nil {
+		t.Fatalf("PutWithRevision: %v", err)
+	}
+
+	got, err := c.GetChecked(hash, model, cache.EmbeddingMeta{Dimension: 3, Revision: "v1"})
+	if err != nil || len(got) != 3 {
+		t.Fatalf("matching GetChecked = %v, %v; want 3-dim vector", got, err)
+	}
+
+	got, err = c.GetChecked(hash, model, cache.EmbeddingMeta{Revision: "v2"})
+	if err != nil || got != nil {
+		t.Fatalf("revision mismatch GetChecked = %v, %v; want miss", got, err)
+	}
+	// The stale entry is deleted, so even an unchecked read misses now.
+	if got, _ := c.Get(hash, model); got != nil {
+		t.Errorf("stale entry should have been deleted, got %v", got)
+	}
+
+	if err := c.Put(hash, model, []float32{0.1, 0.2}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got, _ := c.GetChecked(hash, model, cache.EmbeddingMeta{Dimension: 384}); got != nil {
+		t.Errorf("dimension mismatch should miss, got %v", got)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Rejected != 2 {
+		t.Errorf("Rejected = %d, want 2", stats.Rejected)
+	}
+}
+
+func TestEmbeddingCache_CorruptEntryRecovery(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	c, err := cache.NewEmbeddingCache(dbPath, 10)
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache: %v", err)
+	}
+	hash := cache.ContentHash("corrupt me")
+	if err := c.Put(hash, "m", []float32{1, 2, 3}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := c.Put(cache.ContentHash("fine"), "m", []float32{1, 2, 3}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.Exec(`UPDATE embeddings SET vector = X'0102030405' WHERE content_hash = ?`, hash); err != nil {
+		t.Fatalf("corrupt row: %v", err)
+	}
+
+	// A corrupt blob is reported as a miss, not an error.
+	got, err := c.Get(hash, "m")
+	if err != nil || got != nil {
+		t.Fatalf("Get corrupt = %v, %v; want miss", got, err)
+	}
+
+	// Corrupt again and let the integrity check on reopen clean it up.
+	if err := c.Put(hash, "m", []float32{1, 2, 3}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := raw.Exec(`UPDATE embeddings SET vector = X'0000000000000000' WHERE content_hash = ?`, hash); err != nil {
+		t.Fatalf("corrupt row: %v", err)
+	}
+	c.Close()
+
+	c, err = cache.NewEmbeddingCache(dbPath, 10)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer c.Close()
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Entries != 1 || stats.Rejected != 1 {
+		t.Errorf("after reopen Entries=%d Rejected=%d, want 1 and 1", stats.Entries, stats.Rejected)
+	}
+}
+
+func TestEmbeddingCache_MigratesLegacySchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	if _, err := raw.Exec(`
+		CREATE TABLE embeddings (
+			content_hash TEXT NOT NULL,
+			model        TEXT NOT NULL,
+			vector       BLOB NOT NULL,
+			created_at   INTEGER NOT NULL,
+			accessed_at  INTEGER NOT NULL,
+			PRIMARY KEY (content_hash, model)
+		);
+		INSERT INTO embeddings VALUES ('h', 'm', X'0000803F', 0, 0);
+	`); err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+	raw.Close()
+
+	c, err := cache.NewEmbeddingCache(dbPath, 10)
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache on legacy db: %v", err)
+	}
+	defer c.Close()
+
+	got, err := c.Get("h", "m")
+	if err != nil || len(got) != 1 || got[0] != 1 {
+		t.Fatalf("legacy Get = %v, %v; want [1]", got, err)
+	}
+	if err := c.PutWithRevision("h2", "m", "r1", []float32{1, 2}); err != nil {
+		t.Fatalf("PutWithRevision on migrated schema: %v", err)
+	}
+}
@@ -13,44 +13,43 @@ import (
 // HistoryStore is a SQLite-backed store for assertion result history.
 type HistoryStore struct {
 	db           *sql.DB
+	writer       *sqliteWriter
+	ownsWriter   bool
 	insertCount  atomic.Int64
 	pruneMaxRows int
 	pruneMaxDays int
+	// ns scopes rows to the namespace set by SetNamespace.
+	ns namespace
 }
 
-// NewHistoryStore creates the assertion_history table and index if they don't exist,
-// then returns a HistoryStore backed by the provided *sql.DB.
+// NewHistoryStore migrates db to the current schema and returns a HistoryStore
+// backed by it. Open db with OpenDB so every pooled connection gets a busy
+// timeout. Writes are serialized through a single writer goroutine; call Close
+// to drain it. Use Store to share attest.db with the other caches.
 func NewHistoryStore(db *sql.DB) (*HistoryStore, error) {
 	if _, err := db.Exec(`PRAGMA journal_mode=WAL`); err != nil {
 		return nil, fmt.Errorf("set WAL mode: %w", err)
 	}
-
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS assertion_history (
-			id             INTEGER PRIMARY KEY AUTOINCREMENT,
-			trace_id       TEXT    NOT NULL,
-			assertion_id   TEXT    NOT NULL,
-			assertion_type TEXT    NOT NULL,
-			score          REAL    NOT NULL,
-			status         TEXT    NOT NULL,
-			created_at     INTEGER NOT NULL
-		)
-	`); err != nil {
-		return nil, fmt.Errorf("create assertion_history table: %w", err)
+	if _, err := db.Exec(fmt.Sprintf(`PRAGMA busy_timeout=%d`, busyTimeoutMS)); err != nil {
+		return nil, fmt.Errorf("set busy timeout: %w", err)
 	}
-
-	if _, err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_assertion_history_id_ts
-		ON assertion_history (assertion_id, created_at)
-	`); err != nil {
-		return nil, fmt.Errorf("create assertion_history index: %w", err)
+	if err := Migrate(db); err != nil {
+		return nil, err
 	}
 
+	h := newHistoryStore(db, newSQLiteWriter(db))
+	h.ownsWriter = true
+	return h, nil
+}
+
+// newHistoryStore wraps an already-migrated database.
+func newHistoryStore(db *sql.DB, w *sqliteWriter) *HistoryStore {
 	return &HistoryStore{
 		db:           db,
+		writer:       w,
 		pruneMaxRows: defaultHistoryMaxRows,
 		pruneMaxDays: defaultHistoryMaxAgeDays,
-	}, nil
+	}
 }
 
 const (
@@ -65,13 +64,55 @@ func (h *HistoryStore) SetPruneConfig(maxRows, maxAgeDays int) {
 	h.pruneMaxDays = maxAgeDays
 }
 
+// SetNamespace scopes later reads and writes to ns, so projects sharing
+// attest.db keep separate history, drift statistics, and quarantine lists.
+// "" is the default namespace, which holds rows written before namespaces
+// existed.
+func (h *HistoryStore) SetNamespace(ns string) { h.ns.set(ns) }
+
+// Namespace returns the namespace set by SetNamespace.
+func (h *HistoryStore) Namespace() string { return h.ns.get() }
+
+// Close drains queued writes and checkpoints the WAL. It does not close the
+// *sql.DB, which is owned by the caller. No-op for a store handed out by Store.
+func (h *HistoryStore) Close() {
+	if h.ownsWriter {
+		h.writer.close()
+	}
+}
+
+// exec runs a single write statement on the writer goroutine.
+func (h *HistoryStore) exec(query string, args ...any) error {
+	return h.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(query, args...)
+		return err
+	})
+}
+
 // Record inserts a single assertion result row into assertion_history.
 // Every 100th insert triggers a background prune using the configured limits.
 func (h *HistoryStore) Record(traceID, assertionID, assertionType string, score float64, status string) error {
-	_, err := h.db.Exec(
-		`INSERT INTO assertion_history (trace_id, assertion_id, assertion_type, score, status, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		traceID, assertionID, assertionType, score, status, time.Now().UnixNano(),
+	return h.RecordAt(traceID, assertionID, assertionType, score, status, 0, 1, time.Now())
+}
+
+// RecordAt is Record with the evaluation's cost in USD, the assertion's
+// sample rate, and an explicit creation time, for sampled assertions and for
+// replaying writes that failed earlier. A sampleRate outside (0, 1] is
+// recorded as 1.
+func (h *HistoryStore) RecordAt(traceID, assertionID, assertionType string, score float64, status string, cost, sampleRate float64, at time.Time) error {
+	return h.RecordInNamespace(h.Namespace(), traceID, assertionID, assertionType, score, status, cost, sampleRate, at)
+}
+
+// RecordInNamespace is RecordAt into an explicit namespace, for replaying a
+// write queued under a namespace other than the current one.
+func (h *HistoryStore) RecordInNamespace(ns, traceID, assertionID, assertionType string, score float64, status string, cost, sampleRate float64, at time.Time) error {
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	err := h.exec(
+		`INSERT INTO assertion_history (namespace, trace_id, assertion_id, assertion_type, score, status, cost, sample_rate, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ns, traceID, assertionID, assertionType, score, status, cost, sampleRate, at.UnixNano(),
 	)
 	if err != nil {
 		return fmt.Errorf("record assertion history: %w", err)
@@ -87,23 +128,43 @@ func (h *HistoryStore) Record(traceID, assertionID, assertionType string, score
 }
 
 // Prune removes stale and excess rows from assertion_history.
-// It deletes rows older than maxAgeDays and, per assertion_id, keeps only the
-// maxRows most recent rows.
+// It deletes rows older than maxAgeDays and, per namespace and assertion_id,
+// keeps only the maxRows most recent rows.
 func (h *HistoryStore) Prune(maxRows int, maxAgeDays int) error {
 	cutoff := time.Now().AddDate(0, 0, -maxAgeDays).UnixNano()
-	if _, err := h.db.Exec(
+	if err := h.exec(
 		`DELETE FROM assertion_history WHERE created_at < ?`,
 		cutoff,
 	); err != nil {
 		return fmt.Errorf("prune by age: %w", err)
 	}
 
-	// Per assertion_id, delete rows not in the most-recent maxRows set.
-	if _, err := h.db.Exec(
+	if err := h.exec(
+		`DELETE FROM assertion_values WHERE created_at < ?`,
+		cutoff,
+	); err != nil {
+		return fmt.Errorf("prune values by age: %w", err)
+	}
+
+	// Per namespace and assertion_id, delete rows not in the most-recent maxRows set.
+	if err := h.exec(
+		`DELETE FROM assertion_values
+		 WHERE id NOT IN (
+		   SELECT id FROM assertion_values a2
+		   WHERE a2.namespace = assertion_values.namespace AND a2.assertion_id = assertion_values.assertion_id
+		   ORDER BY a2.created_at DESC
+		   LIMIT ?
+		 )`,
+		maxRows,
+	); err != nil {
+		return fmt.Errorf("prune values by row count: %w", err)
+	}
+
+	if err := h.exec(
 		`DELETE FROM assertion_history
 		 WHERE id NOT IN (
 		   SELECT id FROM assertion_history a2
-		   WHERE a2.assertion_id = assertion_history.assertion_id
+		   WHERE a2.namespace = assertion_history.namespace AND a2.assertion_id = assertion_history.assertion_id
 		   ORDER BY a2.created_at DESC
 		   LIMIT ?
 		 )`,
@@ -120,10 +181,10 @@ func (h *HistoryStore) Prune(maxRows int, maxAgeDays int) error {
 func (h *HistoryStore) QueryWindow(assertionID string, windowSize int) ([]float64, error) {
 	rows, err := h.db.Query(
 		`SELECT score FROM assertion_history
-		 WHERE assertion_id = ?
+		 WHERE namespace = ? AND assertion_id = ?
 		 ORDER BY created_at DESC
 		 LIMIT ?`,
-		assertionID, windowSize,
+		h.Namespace(), assertionID, windowSize,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query window: %w", err)
@@ -146,11 +207,16 @@ func (h *HistoryStore) QueryWindow(assertionID string, windowSize int) ([]float6
 
 // Stats computes the mean, population standard deviation, and count of all scores
 // for the given assertionID. Returns zero values when no rows exist.
+// A row recorded at sample rate r stands for 1/r traces, so the mean and
+// stddev are weighted by 1/sample_rate; count is the number of rows.
 // Uses a single query with the statistical identity: stddev = sqrt(avg(x^2) - avg(x)^2).
 func (h *HistoryStore) Stats(assertionID string) (mean float64, stddev float64, count int, err error) {
 	row := h.db.QueryRow(
-		`SELECT COUNT(*), COALESCE(AVG(score), 0.0), COALESCE(AVG(score * score), 0.0) FROM assertion_history WHERE assertion_id = ?`,
-		assertionID,
+		`SELECT COUNT(*),
+		        COALESCE(SUM(score / sample_rate) / SUM(1.0 / sample_rate), 0.0),
+		        COALESCE(SUM(score * score / sample_rate) / SUM(1.0 / sample_rate), 0.0)
+		 FROM assertion_history WHERE namespace = ? AND assertion_id = ?`,
+		h.Namespace(), assertionID,
 	)
 	var avgSq float64
 	if err = row.Scan(&count, &mean, &avgSq); err != nil {
@@ -168,3 +234,186 @@ func (h *HistoryStore) Stats(assertionID string) (mean float64, stddev float64,
 	stddev = math.Sqrt(variance)
 	return mean, stddev, count, nil
 }
+
+// RecordValue stores the raw numeric measurement behind an assertion (e.g. the
+// aggregate cost a constraint compared against) for dynamic numeric thresholds.
+// Values share the pruning limits of score history.
+func (h *HistoryStore) RecordValue(traceID, assertionID string, value float64) error {
+	err := h.exec(
+		`INSERT INTO assertion_values (namespace, trace_id, assertion_id, value, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		h.Namespace(), traceID, assertionID, value, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("record assertion value: %w", err)
+	}
+	return nil
+}
+
+// QueryValueWindow returns the last windowSize measured values for the given
+// assertionID, ordered by created_at DESC (most recent first).
+func (h *HistoryStore) QueryValueWindow(assertionID string, windowSize int) ([]float64, error) {
+	rows, err := h.db.Query(
+		`SELECT value FROM assertion_values
+		 WHERE namespace = ? AND assertion_id = ?
+		 ORDER BY created_at DESC
+		 LIMIT ?`,
+		h.Namespace(), assertionID, windowSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query value window: %w", err)
+	}
+	defer rows.Close()
+
+	var values []float64
+	for rows.Next() {
+		var v float64
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan value: %w", err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query value window rows: %w", err)
+	}
+	return values, nil
+}
+
+// QueryStatusWindow returns the last windowSize statuses for the given assertionID,
+// ordered by created_at DESC (most recent first).
+func (h *HistoryStore) QueryStatusWindow(assertionID string, windowSize int) ([]string, error) {
+	rows, err := h.db.Query(
+		`SELECT status FROM assertion_history
+		 WHERE namespace = ? AND assertion_id = ?
+		 ORDER BY created_at DESC
+		 LIMIT ?`,
+		h.Namespace(), assertionID, windowSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query status window: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("scan status: %w", err)
+		}
+		statuses = append(statuses, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query status window rows: %w", err)
+	}
+	return statuses, nil
+}
+
+// HistorySummary aggregates one assertion's history rows in a time window.
+// PassRate and MeanScore are weighted by 1/sample_rate like Stats; Cost is
+// the sum of the evaluations' cost in USD.
+type HistorySummary struct {
+	AssertionID   string
+	AssertionType string
+	Runs          int
+	PassRate      float64
+	MeanScore     float64
+	Cost          float64
+}
+
+// Summaries returns a HistorySummary for every assertion with rows created
+// in [from, to), sorted by assertion_id.
+func (h *HistoryStore) Summaries(from, to time.Time) ([]HistorySummary, error) {
+	rows, err := h.db.Query(
+		`SELECT assertion_id, MAX(assertion_type), COUNT(*),
+		        SUM(CASE WHEN status = 'pass' THEN 1.0 / sample_rate ELSE 0 END) / SUM(1.0 / sample_rate),
+		        SUM(score / sample_rate) / SUM(1.0 / sample_rate),
+		        SUM(cost)
+		 FROM assertion_history
+		 WHERE namespace = ? AND created_at >= ? AND created_at < ?
+		 GROUP BY assertion_id
+		 ORDER BY assertion_id`,
+		h.Namespace(), from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []HistorySummary
+	for rows.Next() {
+		var s HistorySummary
+		if err := rows.Scan(&s.AssertionID, &s.AssertionType, &s.Runs, &s.PassRate, &s.MeanScore, &s.Cost); err != nil {
+			return nil, fmt.Errorf("scan summary: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query summaries rows: %w", err)
+	}
+	return out, nil
+}
+
+// AssertionIDs returns every assertion_id with recorded history, sorted ascending.
+func (h *HistoryStore) AssertionIDs() ([]string, error) {
+	return h.queryStrings(`SELECT DISTINCT assertion_id FROM assertion_history WHERE namespace = ? ORDER BY assertion_id`, h.Namespace())
+}
+
+// Quarantine marks assertionID as quarantined. Re-quarantining updates the reason.
+func (h *HistoryStore) Quarantine(assertionID, reason string) error {
+	err := h.exec(
+		`INSERT INTO assertion_quarantine (namespace, assertion_id, reason, created_at)
+		 VALUES (?, ?, ?, ?)
+		 ON CONFLICT(namespace, assertion_id) DO UPDATE SET reason = excluded.reason`,
+		h.Namespace(), assertionID, reason, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("quarantine assertion: %w", err)
+	}
+	return nil
+}
+
+// Unquarantine removes assertionID from the quarantine list. No-op if absent.
+func (h *HistoryStore) Unquarantine(assertionID string) error {
+	if err := h.exec(`DELETE FROM assertion_quarantine WHERE namespace = ? AND assertion_id = ?`, h.Namespace(), assertionID); err != nil {
+		return fmt.Errorf("unquarantine assertion: %w", err)
+	}
+	return nil
+}
+
+// IsQuarantined reports whether assertionID is on the quarantine list.
+func (h *HistoryStore) IsQuarantined(assertionID string) (bool, error) {
+	var n int
+	if err := h.db.QueryRow(
+		`SELECT COUNT(*) FROM assertion_quarantine WHERE namespace = ? AND assertion_id = ?`,
+		h.Namespace(), assertionID,
+	).Scan(&n); err != nil {
+		return false, fmt.Errorf("query quarantine: %w", err)
+	}
+	return n > 0, nil
+}
+
+// QuarantinedIDs returns every quarantined assertion_id, sorted ascending.
+func (h *HistoryStore) QuarantinedIDs() ([]string, error) {
+	return h.queryStrings(`SELECT assertion_id FROM assertion_quarantine WHERE namespace = ? ORDER BY assertion_id`, h.Namespace())
+}
+
+func (h *HistoryStore) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query rows: %w", err)
+	}
+	return out, nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/attest-ai/attest/engine/internal/cache"
 	_ "modernc.org/sqlite"
@@ -21,6 +22,7 @@ func newTestHistoryStore(t *testing.T) *cache.HistoryStore {
 	if err != nil {
 		t.Fatalf("NewHistoryStore: %v", err)
 	}
+	t.Cleanup(store.Close)
 	return store
 }
 
@@ -96,6 +98,73 @@ func TestHistoryStore_Stats(t *testing.T) {
 	}
 }
 
+func TestHistoryStore_StatsWeightsSampleRate(t *testing.T) {
+	store := newTestHistoryStore(t)
+
+	// 0.2 recorded at full rate, 0.8 at rate 0.25: the sampled score stands
+	// for four traces, so the mean is (0.2 + 4*0.8) / 5 = 0.68.
+	if err := store.Record("trace-1", "assert-sampled", "llm_judge", 0.2, "pass"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := store.RecordAt("trace-2", "assert-sampled", "llm_judge", 0.8, "pass", 0, 0.25, time.Now()); err != nil {
+		t.Fatalf("RecordAt: %v", err)
+	}
+
+	mean, stddev, count, err := store.Stats("assert-sampled")
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if math.Abs(mean-0.68) > 1e-9 {
+		t.Errorf("mean = %f, want 0.68", mean)
+	}
+	// E[X^2] = (0.04 + 4*0.64) / 5 = 0.52; variance = 0.52 - 0.68^2 = 0.0576
+	if math.Abs(stddev-0.24) > 1e-9 {
+		t.Errorf("stddev = %f, want 0.24", stddev)
+	}
+}
+
+func TestHistoryStore_Summaries(t *testing.T) {
+	store := newTestHistoryStore(t)
+	now := time.Now()
+	rows := []struct {
+		id     string
+		score  float64
+		status string
+		cost   float64
+		rate   float64
+		at     time.Time
+	}{
+		{"judge", 1.0, "pass", 0.01, 1, now.Add(-time.Hour)},
+		{"judge", 0.2, "hard_fail", 0.01, 0.5, now.Add(-2 * time.Hour)},
+		{"judge", 0.9, "pass", 0.01, 1, now.Add(-48 * time.Hour)}, // outside the window
+		{"schema", 1.0, "pass", 0, 1, now.Add(-time.Minute)},
+	}
+	for _, r := range rows {
+		if err := store.RecordAt("trace", r.id, "llm_judge", r.score, r.status, r.cost, r.rate, r.at); err != nil {
+			t.Fatalf("RecordAt: %v", err)
+		}
+	}
+
+	got, err := store.Summaries(now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Summaries: %v", err)
+	}
+	if len(got) != 2 || got[0].AssertionID != "judge" || got[1].AssertionID != "schema" {
+		t.Fatalf("summaries = %+v, want judge and schema", got)
+	}
+	// The failure at rate 0.5 stands for two traces: 1 pass in 3.
+	j := got[0]
+	if j.Runs != 2 || math.Abs(j.PassRate-1.0/3) > 1e-9 || math.Abs(j.MeanScore-1.4/3) > 1e-9 || math.Abs(j.Cost-0.02) > 1e-9 {
+		t.Errorf("judge summary = %+v", j)
+	}
+	if j.AssertionType != "llm_judge" {
+		t.Errorf("assertion type = %q", j.AssertionType)
+	}
+}
+
 func TestHistoryStore_EmptyHistoryReturnsZeroValues(t *testing.T) {
 	store := newTestHistoryStore(t)
 
@@ -142,3 +211,89 @@ func TestHistoryStore_MultipleAssertionIDsIsolated(t *testing.T) {
 		t.Errorf("assert-B scores = %v, want [0.3]", bScores)
 	}
 }
+
+func TestHistoryStore_RecordAndQueryValueWindow(t *testing.T) {
+	store := newTestHistoryStore(t)
+
+	for _, v := range []float64{0.02, 0.03, 0.04} {
+		if err := store.RecordValue("trace-1", "assert-val", v); err != nil {
+			t.Fatalf("RecordValue: %v", err)
+		}
+	}
+	if err := store.RecordValue("trace-1", "assert-other", 9); err != nil {
+		t.Fatalf("RecordValue: %v", err)
+	}
+
+	got, err := store.QueryValueWindow("assert-val", 2)
+	if err != nil {
+		t.Fatalf("QueryValueWindow: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("QueryValueWindow returned %d values, want 2", len(got))
+	}
+	if got[0] != 0.04 || got[1] != 0.03 {
+		t.Errorf("QueryValueWindow = %v, want [0.04 0.03] (most recent first)", got)
+	}
+}
+
+func TestHistoryStore_Quarantine(t *testing.T) {
+	store := newTestHistoryStore(t)
+
+	if err := store.Quarantine("b", "flaky"); err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+	if err := store.Quarantine("a", "manual"); err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+	// Re-quarantining is idempotent.
+	if err := store.Quarantine("a", "manual again"); err != nil {
+		t.Fatalf("Quarantine (repeat): %v", err)
+	}
+
+	ids, err := store.QuarantinedIDs()
+	if err != nil {
+		t.Fatalf("QuarantinedIDs: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("QuarantinedIDs = %v, want [a b]", ids)
+	}
+
+	if err := store.Unquarantine("a"); err != nil {
+		t.Fatalf("Unquarantine: %v", err)
+	}
+	if q, _ := store.IsQuarantined("a"); q {
+		t.Error("a should no longer be quarantined")
+	}
+	if q, _ := store.IsQuarantined("b"); !q {
+		t.Error("b should still be quarantined")
+	}
+}
+
+func TestHistoryStore_QueryStatusWindowAndIDs(t *testing.T) {
+	store := newTestHistoryStore(t)
+
+	for _, s := range []string{"pass", "hard_fail", "soft_fail"} {
+		if err := store.Record("trace-1", "assert-s", "constraint", 0, s); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := store.Record("trace-1", "assert-a", "constraint", 1, "pass"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	got, err := store.QueryStatusWindow("assert-s", 2)
+	if err != nil {
+		t.Fatalf("QueryStatusWindow: %v", err)
+	}
+	if len(got) != 2 || got[0] != "soft_fail" || got[1] != "hard_fail" {
+		t.Errorf("QueryStatusWindow = %v, want [soft_fail hard_fail]", got)
+	}
+
+	ids, err := store.AssertionIDs()
+	if err != nil {
+		t.Fatalf("AssertionIDs: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "assert-a" || ids[1] != "assert-s" {
+		t.Errorf("AssertionIDs = %v, want [assert-a assert-s]", ids)
+	}
+}
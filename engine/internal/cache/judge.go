@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -16,47 +17,68 @@ type JudgeCacheEntry struct {
 	Explanation string
 }
 
+// judgeEntryOverhead approximates the bytes a judge_cache row takes beyond
+// its explanation: the hashes, rubric, model, score, and timestamps.
+const judgeEntryOverhead = 100
+
 // JudgeCache is an LRU-evicting SQLite-backed cache for LLM judge results.
 type JudgeCache struct {
-	db    *sql.DB
-	maxMB int
+	db     *sql.DB
+	writer *sqliteWriter
+	maxMB  int
+	// owned is true when the cache opened db itself and must close it.
+	owned bool
+
+	// lru buffers accessed_at updates and flushes them periodically.
+	lru *deferredLRU
+
+	// ns scopes entries to the namespace set by SetNamespace.
+	ns namespace
+
+	// cipher, when set, encrypts explanations at rest.
+	cipher *Cipher
+
+	// remote is the optional shared tier consulted on local misses.
+	remote       RemoteCache
+	remoteHits   atomic.Int64
+	remoteErrors atomic.Int64
 }
 
 // NewJudgeCache opens (or creates) a judge cache at dbPath.
 // maxMB sets the maximum size in megabytes before LRU eviction triggers.
+// The cache owns its database handle; use Store to share attest.db.
 func NewJudgeCache(dbPath string, maxMB int) (*JudgeCache, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := OpenDB(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite: %w", err)
+		return nil, err
 	}
-
-	if _, err := db.Exec(`PRAGMA journal_mode=WAL`); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("set WAL mode: %w", err)
-	}
-
-	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS judge_cache (
-			content_hash TEXT NOT NULL,
-			rubric       TEXT NOT NULL,
-			model        TEXT NOT NULL,
-			score        REAL NOT NULL,
-			explanation  TEXT NOT NULL,
-			created_at   INTEGER NOT NULL,
-			accessed_at  INTEGER NOT NULL,
-			PRIMARY KEY (content_hash, rubric, model)
-		)
-	`); err != nil {
+	if err := Migrate(db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("create table: %w", err)
+		return nil, err
 	}
 
-	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_judge_accessed ON judge_cache(accessed_at)`); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("create index: %w", err)
+	c := newJudgeCache(db, newSQLiteWriter(db), maxMB)
+	c.owned = true
+	return c, nil
+}
+
+// newJudgeCache wraps an already-migrated database and starts the deferred
+// LRU flush loop.
+func newJudgeCache(db *sql.DB, w *sqliteWriter, maxMB int) *JudgeCache {
+	return &JudgeCache{
+		db:     db,
+		writer: w,
+		maxMB:  maxMB,
+		lru: newDeferredLRU(w,
+			`UPDATE judge_cache SET accessed_at = ? WHERE content_hash = ? AND rubric = ? AND model = ?`,
+			func(k lruKey, ts int64) []any { return []any{ts, k.contentHash, k.rubric, k.model} },
+		),
 	}
+}
 
-	return &JudgeCache{db: db, maxMB: maxMB}, nil
+// judgeRowID binds a sealed explanation to its row.
+func judgeRowID(contentHash, rubric, model string) string {
+	return "judge:" + model + ":" + rubric + ":" + contentHash
 }
 
 // JudgeContentHash returns the SHA-256 hex digest of the agent output text.
@@ -66,76 +88,208 @@ func JudgeContentHash(agentOutput string) string {
 }
 
 // Get retrieves a cached judge result for the given content, rubric, and model.
-// Returns (nil, nil) on cache miss.
+// On a local miss the remote tier, if configured, is consulted and a hit is
+// written through to SQLite. Returns (nil, nil) on cache miss.
 func (c *JudgeCache) Get(contentHash, rubric, model string) (*JudgeCacheEntry, error) {
+	contentHash = c.ns.key(contentHash)
+	entry, err := c.getLocal(contentHash, rubric, model)
+	if err != nil || entry != nil || c.remote == nil {
+		return entry, err
+	}
+	return c.getRemote(contentHash, rubric, model), nil
+}
+
+func (c *JudgeCache) getLocal(contentHash, rubric, model string) (*JudgeCacheEntry, error) {
 	row := c.db.QueryRow(
-		`SELECT score, explanation FROM judge_cache WHERE content_hash = ? AND rubric = ? AND model = ?`,
+		`SELECT score, explanation, encrypted FROM judge_cache WHERE content_hash = ? AND rubric = ? AND model = ?`,
 		contentHash, rubric, model,
 	)
 
 	var entry JudgeCacheEntry
-	if err := row.Scan(&entry.Score, &entry.Explanation); err != nil {
+	var explanation []byte
+	var encrypted bool
+	if err := row.Scan(&entry.Score, &explanation, &encrypted); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("get judge result: %w", err)
 	}
+	if encrypted {
+		if c.cipher == nil {
+			// Written by an engine holding the cache key; unreadable without it.
+			return nil, nil
+		}
+		plain, err := c.cipher.Open(explanation, judgeRowID(contentHash, rubric, model))
+		if err != nil {
+			// Sealed under another key: drop it so the result is judged again.
+			if delErr := c.writer.exec(func(db *sql.DB) error {
+				_, err := db.Exec(
+					`DELETE FROM judge_cache WHERE content_hash = ? AND rubric = ? AND model = ?`,
+					contentHash, rubric, model,
+				)
+				return err
+			}); delErr != nil {
+				return nil, fmt.Errorf("delete unreadable judge result: %w", delErr)
+			}
+			return nil, nil
+		}
+		explanation = plain
+	}
+	entry.Explanation = string(explanation)
 
-	// Update LRU timestamp
-	_, _ = c.db.Exec(
-		`UPDATE judge_cache SET accessed_at = ? WHERE content_hash = ? AND rubric = ? AND model = ?`,
-		time.Now().UnixNano(), contentHash, rubric, model,
-	)
+	// Buffer accessed_at update instead of writing to SQLite on every Get.
+	c.lru.touch(lruKey{contentHash: contentHash, rubric: rubric, model: model})
 
 	return &entry, nil
 }
 
-// Put stores a judge result, then evicts if over size limit.
+// getRemote fetches a judge result from the remote tier and writes it through
+// to SQLite. Remote failures and undecodable entries are reported as a miss.
+func (c *JudgeCache) getRemote(contentHash, rubric, model string) *JudgeCacheEntry {
+	b, err := c.remote.Get(judgeRemoteKey(contentHash, rubric, model))
+	if err != nil {
+		c.remoteErrors.Add(1)
+		return nil
+	}
+	if b == nil {
+		return nil
+	}
+	entry, err := decodeRemoteJudge(b)
+	if err != nil {
+		c.remoteErrors.Add(1)
+		return nil
+	}
+	c.remoteHits.Add(1)
+	_ = c.putLocal(contentHash, rubric, model, entry)
+	return entry
+}
+
+// Put stores a judge result, then evicts if over size limit. With a remote
+// tier configured the result is published there with set-if-absent; when
+// another worker already published a result for the same key, the local entry
+// is replaced with the remote one so every worker reports the same verdict.
+// Remote failures are counted but not returned.
 func (c *JudgeCache) Put(contentHash, rubric, model string, entry *JudgeCacheEntry) error {
+	contentHash = c.ns.key(contentHash)
+	if err := c.putLocal(contentHash, rubric, model, entry); err != nil {
+		return err
+	}
+	if c.remote != nil {
+		c.publishRemote(contentHash, rubric, model, entry)
+	}
+	return c.evictIfNeeded()
+}
+
+// publishRemote writes entry to the remote tier, adopting the existing remote
+// value locally when this worker lost the race to populate the key.
+func (c *JudgeCache) publishRemote(contentHash, rubric, model string, entry *JudgeCacheEntry) {
+	key := judgeRemoteKey(contentHash, rubric, model)
+	b, err := encodeRemoteJudge(entry)
+	if err != nil {
+		c.remoteErrors.Add(1)
+		return
+	}
+	stored, err := c.remote.SetIfAbsent(key, b)
+	if err != nil {
+		c.remoteErrors.Add(1)
+		return
+	}
+	if stored {
+		return
+	}
+	winner, err := c.remote.Get(key)
+	if err != nil || winner == nil {
+		return
+	}
+	if w, err := decodeRemoteJudge(winner); err == nil {
+		_ = c.putLocal(contentHash, rubric, model, w)
+	}
+}
+
+func (c *JudgeCache) putLocal(contentHash, rubric, model string, entry *JudgeCacheEntry) error {
+	var explanation any = entry.Explanation
+	encrypted := c.cipher != nil
+	if encrypted {
+		explanation = c.cipher.Seal([]byte(entry.Explanation), judgeRowID(contentHash, rubric, model))
+	}
 	now := time.Now().UnixNano()
 
-	_, err := c.db.Exec(
-		`INSERT INTO judge_cache(content_hash, rubric, model, score, explanation, created_at, accessed_at)
-		 VALUES(?, ?, ?, ?, ?, ?, ?)
-		 ON CONFLICT(content_hash, rubric, model) DO UPDATE SET score=excluded.score, explanation=excluded.explanation, accessed_at=excluded.accessed_at`,
-		contentHash, rubric, model, entry.Score, entry.Explanation, now, now,
-	)
+	err := c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(
+			`INSERT INTO judge_cache(content_hash, rubric, model, score, explanation, encrypted, created_at, accessed_at)
+			 VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(content_hash, rubric, model) DO UPDATE SET
+			   score=excluded.score, explanation=excluded.explanation, encrypted=excluded.encrypted, accessed_at=excluded.accessed_at`,
+			contentHash, rubric, model, entry.Score, explanation, encrypted, now, now,
+		)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("put judge result: %w", err)
 	}
+	return nil
+}
 
+// SetNamespace scopes later reads and writes to ns, so projects sharing
+// attest.db do not serve each other's verdicts. "" is the default namespace.
+func (c *JudgeCache) SetNamespace(ns string) { c.ns.set(ns) }
+
+// FlushLRU writes all pending accessed_at updates to SQLite in a single transaction.
+func (c *JudgeCache) FlushLRU() {
+	c.lru.flush()
+}
+
+// Evict removes the least-recently-used entries until the cache is under maxMB.
+func (c *JudgeCache) Evict() error {
 	return c.evictIfNeeded()
 }
 
-// Stats returns current cache statistics.
+// Stats returns current cache statistics. TotalBytes is the size counted
+// against maxMB: each explanation plus a fixed per-entry overhead.
 func (c *JudgeCache) Stats() (*CacheStats, error) {
-	row := c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(LENGTH(explanation)), 0) FROM judge_cache`)
+	row := c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(LENGTH(explanation) + ?), 0) FROM judge_cache`, judgeEntryOverhead)
 	var stats CacheStats
 	if err := row.Scan(&stats.Entries, &stats.TotalBytes); err != nil {
 		return nil, fmt.Errorf("judge cache stats: %w", err)
 	}
+	stats.RemoteHits = c.remoteHits.Load()
+	stats.RemoteErrors = c.remoteErrors.Load()
 	return &stats, nil
 }
 
 // Clear removes all cached entries.
 func (c *JudgeCache) Clear() error {
-	if _, err := c.db.Exec(`DELETE FROM judge_cache`); err != nil {
+	if err := c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(`DELETE FROM judge_cache`)
+		return err
+	}); err != nil {
 		return fmt.Errorf("clear judge cache: %w", err)
 	}
 	return nil
 }
 
-// Close releases the database connection.
+// Close flushes pending LRU writes and stops the background flush loop. When
+// the cache owns its database it also stops the writer (checkpointing the WAL)
+// and releases the connection; a cache handed out by Store leaves that to Store.Close.
 func (c *JudgeCache) Close() error {
+	c.lru.close()
+	if !c.owned {
+		return nil
+	}
+	c.writer.close()
 	return c.db.Close()
 }
 
 func (c *JudgeCache) evictIfNeeded() error {
+	// Flush pending LRU writes before eviction so accessed_at values are current.
+	c.FlushLRU()
+
 	maxBytes := int64(c.maxMB) * 1024 * 1024
 
-	row := c.db.QueryRow(`SELECT COALESCE(SUM(LENGTH(explanation) + 100), 0) FROM judge_cache`)
+	row := c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(LENGTH(explanation) + ?), 0) FROM judge_cache`, judgeEntryOverhead)
+	var totalCount int64
 	var totalBytes int64
-	if err := row.Scan(&totalBytes); err != nil {
+	if err := row.Scan(&totalCount, &totalBytes); err != nil {
 		return fmt.Errorf("evict size check: %w", err)
 	}
 
@@ -143,43 +297,29 @@ func (c *JudgeCache) evictIfNeeded() error {
 		return nil
 	}
 
-	rows, err := c.db.Query(
-		`SELECT content_hash, rubric, model, LENGTH(explanation) + 100 FROM judge_cache ORDER BY accessed_at ASC`,
-	)
-	if err != nil {
-		return fmt.Errorf("evict query: %w", err)
-	}
-	defer rows.Close()
-
-	type entry struct {
-		hash   string
-		rubric string
-		model  string
-		size   int64
-	}
-	var entries []entry
-	for rows.Next() {
-		var e entry
-		if err := rows.Scan(&e.hash, &e.rubric, &e.model, &e.size); err != nil {
-			return fmt.Errorf("evict scan: %w", err)
-		}
-		entries = append(entries, e)
+	// Estimate how many rows to delete from the average entry size.
+	avgSize := totalBytes / totalCount
+	excess := totalBytes - maxBytes
+	deleteCount := excess / avgSize
+	if deleteCount < 1 {
+		deleteCount = 1
 	}
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("evict rows: %w", err)
+	// Add 10% headroom to avoid repeated small evictions.
+	deleteCount = deleteCount + deleteCount/10
+	if deleteCount > totalCount {
+		deleteCount = totalCount
 	}
 
-	for _, e := range entries {
-		if totalBytes <= maxBytes {
-			break
-		}
-		if _, err := c.db.Exec(
-			`DELETE FROM judge_cache WHERE content_hash = ? AND rubric = ? AND model = ?`,
-			e.hash, e.rubric, e.model,
-		); err != nil {
-			return fmt.Errorf("evict delete: %w", err)
-		}
-		totalBytes -= e.size
+	// Pure SQL batch eviction: delete LRU rows without loading into Go.
+	err := c.writer.exec(func(db *sql.DB) error {
+		_, err := db.Exec(
+			`DELETE FROM judge_cache WHERE rowid IN (SELECT rowid FROM judge_cache ORDER BY accessed_at ASC LIMIT ?)`,
+			deleteCount,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("evict delete: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,96 @@
+package cache_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+func newTestJudgeCache(t *testing.T, maxMB int) *cache.JudgeCache {
+	t.Helper()
+	c, err := cache.NewJudgeCache(filepath.Join(t.TempDir(), "test.db"), maxMB)
+	if err != nil {
+		t.Fatalf("NewJudgeCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestJudgeCache_StatsAndClear(t *testing.T) {
+	c := newTestJudgeCache(t, 10)
+	for _, s := range []string{"a", "b"} {
+		entry := &cache.JudgeCacheEntry{Score: 0.5, Explanation: "because " + s}
+		if err := c.Put(cache.JudgeContentHash(s), "rubric", "model", entry); err != nil {
+			t.Fatalf("Put %s: %v", s, err)
+		}
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Entries != 2 {
+		t.Errorf("entries: got %d, want 2", stats.Entries)
+	}
+	if stats.TotalBytes <= int64(len("because a")*2) {
+		t.Errorf("total bytes %d should include per-entry overhead", stats.TotalBytes)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	stats, _ = c.Stats()
+	if stats.Entries != 0 {
+		t.Errorf("entries after clear: got %d, want 0", stats.Entries)
+	}
+}
+
+func TestJudgeCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTestJudgeCache(t, 1)
+	// Two 400KB explanations fit in 1MB; a third pushes one out.
+	big := strings.Repeat("x", 400<<10)
+	put := func(s string) {
+		t.Helper()
+		entry := &cache.JudgeCacheEntry{Score: 1, Explanation: big}
+		if err := c.Put(cache.JudgeContentHash(s), "rubric", "model", entry); err != nil {
+			t.Fatalf("Put %s: %v", s, err)
+		}
+	}
+
+	put("a")
+	put("b")
+	// Reading a makes b the least recently used entry.
+	if got, err := c.Get(cache.JudgeContentHash("a"), "rubric", "model"); err != nil || got == nil {
+		t.Fatalf("Get a: %v, %v", got, err)
+	}
+	put("c")
+
+	for s, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		got, err := c.Get(cache.JudgeContentHash(s), "rubric", "model")
+		if err != nil {
+			t.Fatalf("Get %s: %v", s, err)
+		}
+		if (got != nil) != want {
+			t.Errorf("entry %s cached = %v, want %v", s, got != nil, want)
+		}
+	}
+}
+
+func TestJudgeCache_Eviction(t *testing.T) {
+	// maxMB=0 means every insert evicts.
+	c := newTestJudgeCache(t, 0)
+	for _, s := range []string{"a", "b", "c"} {
+		if err := c.Put(cache.JudgeContentHash(s), "rubric", "model", &cache.JudgeCacheEntry{Score: 1}); err != nil {
+			t.Fatalf("Put %s: %v", s, err)
+		}
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("expected 0 entries with maxMB=0, got %d", stats.Entries)
+	}
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// Keychain service and account under which the cache key is stored.
+const (
+	keychainService = "attest-engine"
+	keychainAccount = "cache-key"
+)
+
+// KeychainKey reads the cache key from the OS keychain: the login keychain
+// on macOS (security) or the Secret Service on Linux (secret-tool). Store it
+// with
+//
+//	security add-generic-password -s attest-engine -a cache-key -w <key>
+//	secret-tool store --label "attest cache key" service attest-engine account cache-key
+func KeychainKey() ([]byte, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+	default:
+		return nil, fmt.Errorf("no OS keychain support on %s; set ATTEST_CACHE_KEY to the key itself", runtime.GOOS)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("read cache key from keychain: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return ParseKey(string(out))
+}
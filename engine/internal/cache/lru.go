@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// lruFlushInterval is how often deferred LRU writes are flushed to SQLite.
+	lruFlushInterval = 5 * time.Second
+	// lruFlushThreshold triggers a flush when the pending map reaches this size.
+	lruFlushThreshold = 64
+)
+
+// lruKey is the composite key for deferred LRU writes. rubric is empty for
+// embeddings.
+type lruKey struct {
+	contentHash string
+	rubric      string
+	model       string
+}
+
+// deferredLRU buffers accessed_at updates and flushes them periodically in
+// one transaction, instead of writing to SQLite on every cache hit.
+type deferredLRU struct {
+	writer *sqliteWriter
+	// update sets accessed_at for one entry; args maps a key and timestamp
+	// to its parameters.
+	update string
+	args   func(k lruKey, ts int64) []any
+
+	pending    sync.Map // map[lruKey]int64 (UnixNano)
+	pendingLen atomic.Int64
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// newDeferredLRU starts the flush loop; call close to stop it.
+func newDeferredLRU(w *sqliteWriter, update string, args func(k lruKey, ts int64) []any) *deferredLRU {
+	l := &deferredLRU{
+		writer: w,
+		update: update,
+		args:   args,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go l.loop()
+	return l
+}
+
+// touch records an access to k, flushing early once enough are pending.
+func (l *deferredLRU) touch(k lruKey) {
+	l.pending.Store(k, time.Now().UnixNano())
+	if l.pendingLen.Add(1) >= lruFlushThreshold {
+		go l.flush()
+	}
+}
+
+func (l *deferredLRU) loop() {
+	defer close(l.done)
+	ticker := time.NewTicker(lruFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.flush()
+		case <-l.stop:
+			l.flush()
+			return
+		}
+	}
+}
+
+// flush writes all pending accessed_at updates to SQLite in a single transaction.
+func (l *deferredLRU) flush() {
+	if l.pendingLen.Load() == 0 {
+		return
+	}
+
+	// Collect and clear pending entries.
+	type entry struct {
+		key lruKey
+		ts  int64
+	}
+	var entries []entry
+	l.pending.Range(func(k, v any) bool {
+		entries = append(entries, entry{key: k.(lruKey), ts: v.(int64)})
+		l.pending.Delete(k)
+		return true
+	})
+	l.pendingLen.Store(0)
+
+	if len(entries) == 0 {
+		return
+	}
+
+	_ = l.writer.exec(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		stmt, err := tx.Prepare(l.update)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		defer stmt.Close()
+
+		for _, e := range entries {
+			_, _ = stmt.Exec(l.args(e.key, e.ts)...)
+		}
+
+		return tx.Commit()
+	})
+}
+
+// close flushes pending writes and stops the flush loop.
+func (l *deferredLRU) close() {
+	close(l.stop)
+	<-l.done
+}
@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a single versioned schema change to attest.db.
+// Migrations must be idempotent: databases created before schema_migrations
+// existed already contain some of these tables and are brought up to date by
+// replaying every migration.
+type migration struct {
+	version int
+	name    string
+	// table is the table the migration creates or alters; used to replay a
+	// table's migrations when it is rebuilt.
+	table string
+	up    func(tx sqlExecer) error
+}
+
+// sqlExecer is satisfied by *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// migrations lists every schema change in order. Append new migrations with
+// the next version number; never edit or reorder applied ones.
+var migrations = []migration{
+	{1, "create assertion_history", "assertion_history", execAll(`
+		CREATE TABLE IF NOT EXISTS assertion_history (
+			id             INTEGER PRIMARY KEY AUTOINCREMENT,
+			trace_id       TEXT    NOT NULL,
+			assertion_id   TEXT    NOT NULL,
+			assertion_type TEXT    NOT NULL,
+			score          REAL    NOT NULL,
+			status         TEXT    NOT NULL,
+			created_at     INTEGER NOT NULL
+		)`, `
+		CREATE INDEX IF NOT EXISTS idx_assertion_history_id_ts
+		ON assertion_history (assertion_id, created_at)`,
+	)},
+	{2, "create embeddings", "embeddings", execAll(`
+		CREATE TABLE IF NOT EXISTS embeddings (
+			content_hash TEXT NOT NULL,
+			model        TEXT NOT NULL,
+			vector       BLOB NOT NULL,
+			created_at   INTEGER NOT NULL,
+			accessed_at  INTEGER NOT NULL,
+			PRIMARY KEY (content_hash, model)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_accessed ON embeddings(accessed_at)`,
+	)},
+	{3, "create judge_cache", "judge_cache", execAll(`
+		CREATE TABLE IF NOT EXISTS judge_cache (
+			content_hash TEXT NOT NULL,
+			rubric       TEXT NOT NULL,
+			model        TEXT NOT NULL,
+			score        REAL NOT NULL,
+			explanation  TEXT NOT NULL,
+			created_at   INTEGER NOT NULL,
+			accessed_at  INTEGER NOT NULL,
+			PRIMARY KEY (content_hash, rubric, model)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_judge_accessed ON judge_cache(accessed_at)`,
+	)},
+	{4, "create assertion_values", "assertion_values", execAll(`
+		CREATE TABLE IF NOT EXISTS assertion_values (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			trace_id     TEXT    NOT NULL,
+			assertion_id TEXT    NOT NULL,
+			value        REAL    NOT NULL,
+			created_at   INTEGER NOT NULL
+		)`, `
+		CREATE INDEX IF NOT EXISTS idx_assertion_values_id_ts
+		ON assertion_values (assertion_id, created_at)`,
+	)},
+	{5, "create assertion_quarantine", "assertion_quarantine", execAll(`
+		CREATE TABLE IF NOT EXISTS assertion_quarantine (
+			assertion_id TEXT    PRIMARY KEY,
+			reason       TEXT    NOT NULL,
+			created_at   INTEGER NOT NULL
+		)`,
+	)},
+	{6, "add embeddings dimension and revision", "embeddings", func(tx sqlExecer) error {
+		if err := addColumnIfMissing(tx, "embeddings", "dimension", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+		return addColumnIfMissing(tx, "embeddings", "revision", "TEXT NOT NULL DEFAULT ''")
+	}},
+	{7, "create judge_calibrations", "judge_calibrations", execAll(`
+		CREATE TABLE IF NOT EXISTS judge_calibrations (
+			rubric     TEXT    NOT NULL,
+			model      TEXT    NOT NULL,
+			curve      TEXT    NOT NULL,
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (rubric, model)
+		)`,
+	)},
+	{8, "add assertion_history sample_rate", "assertion_history", func(tx sqlExecer) error {
+		return addColumnIfMissing(tx, "assertion_history", "sample_rate", "REAL NOT NULL DEFAULT 1")
+	}},
+	{9, "add embeddings encrypted", "embeddings", func(tx sqlExecer) error {
+		return addColumnIfMissing(tx, "embeddings", "encrypted", "INTEGER NOT NULL DEFAULT 0")
+	}},
+	{10, "add judge_cache encrypted", "judge_cache", func(tx sqlExecer) error {
+		return addColumnIfMissing(tx, "judge_cache", "encrypted", "INTEGER NOT NULL DEFAULT 0")
+	}},
+	{11, "add assertion_history cost", "assertion_history", func(tx sqlExecer) error {
+		return addColumnIfMissing(tx, "assertion_history", "cost", "REAL NOT NULL DEFAULT 0")
+	}},
+	{12, "add assertion_history namespace", "assertion_history", func(tx sqlExecer) error {
+		if err := addColumnIfMissing(tx, "assertion_history", "namespace", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_assertion_history_ns_id_ts
+			ON assertion_history (namespace, assertion_id, created_at)`)
+		return err
+	}},
+	{13, "add assertion_values namespace", "assertion_values", func(tx sqlExecer) error {
+		if err := addColumnIfMissing(tx, "assertion_values", "namespace", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_assertion_values_ns_id_ts
+			ON assertion_values (namespace, assertion_id, created_at)`)
+		return err
+	}},
+	// The quarantine key becomes (namespace, assertion_id), which SQLite can
+	// only change by rebuilding the table.
+	{14, "add assertion_quarantine namespace", "assertion_quarantine", func(tx sqlExecer) error {
+		if ok, err := hasColumn(tx, "assertion_quarantine", "namespace"); err != nil || ok {
+			return err
+		}
+		return execAll(`
+			CREATE TABLE assertion_quarantine_new (
+				namespace    TEXT    NOT NULL DEFAULT '',
+				assertion_id TEXT    NOT NULL,
+				reason       TEXT    NOT NULL,
+				created_at   INTEGER NOT NULL,
+				PRIMARY KEY (namespace, assertion_id)
+			)`,
+			`INSERT INTO assertion_quarantine_new (assertion_id, reason, created_at)
+			 SELECT assertion_id, reason, created_at FROM assertion_quarantine`,
+			`DROP TABLE assertion_quarantine`,
+			`ALTER TABLE assertion_quarantine_new RENAME TO assertion_quarantine`,
+		)(tx)
+	}},
+}
+
+// Migrate brings db up to the latest schema version, applying each pending
+// migration in its own transaction and recording it in schema_migrations.
+func Migrate(db *sql.DB) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT    NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_migrations table: %w", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): begin: %w", m.version, m.name, err)
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		// OR IGNORE: another process may have applied the same migration concurrently.
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.name, time.Now().UnixNano(),
+		); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s): record: %w", m.version, m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d (%s): commit: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest applied migration version, or 0 for a
+// database that has not been migrated.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return v, nil
+}
+
+// LatestSchemaVersion returns the schema version Migrate brings a database to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// replayTableMigrations re-runs every migration for table, recreating it with
+// the current schema after it has been dropped.
+func replayTableMigrations(tx sqlExecer, table string) error {
+	for _, m := range migrations {
+		if m.table != table {
+			continue
+		}
+		if err := m.up(tx); err != nil {
+			return fmt.Errorf("replay migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// execAll returns a migration step that executes each statement in order.
+func execAll(stmts ...string) func(tx sqlExecer) error {
+	return func(tx sqlExecer) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnIfMissing adds column to table unless it already exists.
+func addColumnIfMissing(db sqlExecer, table, column, decl string) error {
+	ok, err := hasColumn(db, table, column)
+	if err != nil || ok {
+		return err
+	}
+	if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// hasColumn reports whether table has column.
+func hasColumn(db sqlExecer, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid     int
+			name    string
+			typ     string
+			notNull int
+			dflt    sql.NullString
+			pk      int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, fmt.Errorf("inspect %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("inspect %s: %w", table, err)
+	}
+	return false, nil
+}
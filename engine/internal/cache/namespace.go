@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// MaxNamespaceLength bounds a namespace name.
+const MaxNamespaceLength = 64
+
+// ValidateNamespace reports whether ns may name a namespace: up to
+// MaxNamespaceLength letters, digits, '.', '_', and '-'. The empty string is
+// the default namespace.
+func ValidateNamespace(ns string) error {
+	if len(ns) > MaxNamespaceLength {
+		return fmt.Errorf("namespace is %d characters; at most %d are allowed", len(ns), MaxNamespaceLength)
+	}
+	for _, r := range ns {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return fmt.Errorf("namespace %q contains %q; use letters, digits, '.', '_', and '-'", ns, r)
+		}
+	}
+	return nil
+}
+
+// namespace holds the namespace a cache or history store reads and writes,
+// so one attest.db can serve several projects without mixing their entries.
+type namespace struct {
+	v atomic.Pointer[string]
+}
+
+func (n *namespace) set(ns string) { n.v.Store(&ns) }
+
+func (n *namespace) get() string {
+	if p := n.v.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// key scopes a cache content hash to the namespace. The default namespace
+// leaves it unchanged, so entries written before namespaces existed still hit.
+func (n *namespace) key(contentHash string) string {
+	if ns := n.get(); ns != "" {
+		return ns + "/" + contentHash
+	}
+	return contentHash
+}
@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redisPoolSize bounds the number of idle connections kept for reuse.
+	redisPoolSize = 8
+	// redisTimeout bounds dialing and each command round trip.
+	redisTimeout = 2 * time.Second
+	// redisDefaultPrefix namespaces attest keys in a shared Redis.
+	redisDefaultPrefix = "attest:"
+)
+
+// errRedisClosed is returned by commands issued after Close.
+var errRedisClosed = errors.New("redis cache closed")
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// RedisCache is a RemoteCache that speaks the Redis protocol (RESP2), so it
+// works with Redis, Valkey, KeyDB, and managed equivalents. Entries are
+// written with SET NX and expire after the configured TTL.
+type RedisCache struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      *tls.Config
+	prefix   string
+	ttl      time.Duration
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedisCache connects to the server at rawURL, of the form
+// redis://[user:password@]host[:port][/db][?prefix=attest:]. Use rediss:// for
+// TLS. A ttl of zero stores entries without expiry. The server is pinged
+// before returning so a misconfigured URL fails fast.
+func NewRedisCache(rawURL string, ttl time.Duration) (*RedisCache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	c := &RedisCache{prefix: redisDefaultPrefix, ttl: ttl}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		c.tls = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("unsupported redis url scheme %q (want redis or rediss)", u.Scheme)
+	}
+
+	c.addr = u.Host
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		if c.db, err = strconv.Atoi(p); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", p)
+		}
+	}
+	if u.Query().Has("prefix") {
+		c.prefix = u.Query().Get("prefix")
+	}
+
+	if _, err := c.do("PING"); err != nil {
+		return nil, fmt.Errorf("ping redis %s: %w", c.addr, err)
+	}
+	return c, nil
+}
+
+// Get returns the value at key, or (nil, nil) when it is absent.
+func (c *RedisCache) Get(key string) ([]byte, error) {
+	reply, err := c.do("GET", c.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("redis GET: unexpected reply %T", reply)
+	}
+}
+
+// SetIfAbsent stores value at key with SET NX, reporting whether it was stored.
+func (c *RedisCache) SetIfAbsent(key string, value []byte) (bool, error) {
+	args := []string{"SET", c.prefix + key, string(value), "NX"}
+	if c.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10))
+	}
+	reply, err := c.do(args...)
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+// Close releases all idle connections. Commands issued afterwards fail.
+func (c *RedisCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// do sends one command and reads its reply. Connections that fail mid-command
+// are discarded; connections that returned an error reply are still reusable.
+func (c *RedisCache) do(args ...string) (any, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(redisTimeout))
+	if err := writeCommand(conn, args); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply, err := readReply(conn.r)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	c.release(conn)
+	return reply, err
+}
+
+// conn returns an idle connection or dials a new one.
+func (c *RedisCache) conn() (*redisConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errRedisClosed
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+	return c.dial()
+}
+
+func (c *RedisCache) release(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= redisPoolSize {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// dial opens a connection and performs AUTH and SELECT as configured.
+func (c *RedisCache) dial() (*redisConn, error) {
+	d := &net.Dialer{Timeout: redisTimeout}
+	var nc net.Conn
+	var err error
+	if c.tls != nil {
+		nc, err = tls.DialWithDialer(d, "tcp", c.addr, c.tls)
+	} else {
+		nc, err = d.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial redis: %w", err)
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	_ = conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	var setup [][]string
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []string{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if err := writeCommand(conn, args); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if _, err := readReply(conn.r); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis %s: %w", args[0], err)
+		}
+	}
+	return conn, nil
+}
+
+// writeCommand encodes args as a RESP array of bulk strings.
+func writeCommand(w io.Writer, args []string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readReply decodes one RESP2 reply: simple strings as string, integers as
+// int64, bulk strings as []byte, arrays as []any, and nulls as nil. Error
+// replies are returned as redisError.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line[1:])
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
@@ -0,0 +1,181 @@
+package cache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+// fakeRedis is a minimal RESP server supporting PING, AUTH, SELECT, GET, and
+// SET with NX/PX.
+type fakeRedis struct {
+	password string
+
+	mu   sync.Mutex
+	data map[string]string
+	ttls map[string]string
+	dbs  []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeRedis{password: password, data: map[string]string{}, ttls: map[string]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		f.mu.Lock()
+		switch cmd {
+		case "AUTH":
+			if args[len(args)-1] == f.password {
+				authed = true
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		case "SELECT":
+			f.dbs = append(f.dbs, args[1])
+			io.WriteString(conn, "+OK\r\n")
+		case "GET":
+			if v, ok := f.data[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				io.WriteString(conn, "$-1\r\n")
+			}
+		case "SET":
+			if _, ok := f.data[args[1]]; ok {
+				io.WriteString(conn, "$-1\r\n")
+				break
+			}
+			f.data[args[1]] = args[2]
+			for i := 3; i+1 < len(args); i++ {
+				if strings.ToUpper(args[i]) == "PX" {
+					f.ttls[args[1]] = args[i+1]
+				}
+			}
+			io.WriteString(conn, "+OK\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisCache_SetIfAbsentAndGet(t *testing.T) {
+	f, addr := startFakeRedis(t, "s3cret")
+	rc, err := cache.NewRedisCache("redis://:s3cret@"+addr+"/2?prefix=ci:", time.Hour)
+	if err != nil {
+		t.Fatalf("NewRedisCache: %v", err)
+	}
+	defer rc.Close()
+
+	if v, err := rc.Get("k"); err != nil || v != nil {
+		t.Fatalf("Get missing = %q, %v; want (nil, nil)", v, err)
+	}
+	binary := []byte{0, 1, '\r', '\n', 255}
+	stored, err := rc.SetIfAbsent("k", binary)
+	if err != nil || !stored {
+		t.Fatalf("first SetIfAbsent = %v, %v; want stored", stored, err)
+	}
+	stored, err = rc.SetIfAbsent("k", []byte("other"))
+	if err != nil || stored {
+		t.Fatalf("second SetIfAbsent = %v, %v; want not stored", stored, err)
+	}
+	v, err := rc.Get("k")
+	if err != nil || string(v) != string(binary) {
+		t.Fatalf("Get = %q, %v; want %q", v, err, binary)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.data["ci:k"]; !ok {
+		t.Errorf("keys = %v, want prefixed key ci:k", f.data)
+	}
+	if f.ttls["ci:k"] != "3600000" {
+		t.Errorf("PX = %q, want 3600000", f.ttls["ci:k"])
+	}
+	if len(f.dbs) == 0 || f.dbs[0] != "2" {
+		t.Errorf("SELECT calls = %v, want db 2", f.dbs)
+	}
+}
+
+func TestRedisCache_ConnectErrors(t *testing.T) {
+	_, addr := startFakeRedis(t, "s3cret")
+	if _, err := cache.NewRedisCache("redis://:wrong@"+addr, 0); err == nil {
+		t.Error("expected error for wrong password")
+	}
+	if _, err := cache.NewRedisCache("s3://bucket", 0); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+
+	rc, err := cache.NewRedisCache("redis://:s3cret@"+addr, 0)
+	if err != nil {
+		t.Fatalf("NewRedisCache: %v", err)
+	}
+	rc.Close()
+	if _, err := rc.Get("k"); err == nil {
+		t.Error("expected error after Close")
+	}
+}
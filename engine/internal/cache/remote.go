@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RemoteCache is a shared key-value tier consulted before the local SQLite
+// cache so that many workers can reuse each other's embeddings and judge
+// results. Implementations must be safe for concurrent use.
+type RemoteCache interface {
+	// Get returns the value stored at key, or (nil, nil) when the key is absent.
+	Get(key string) ([]byte, error)
+	// SetIfAbsent stores value at key only when no value exists yet, so the
+	// first worker to populate a key wins and concurrent writers never
+	// overwrite each other. Reports whether value was stored.
+	SetIfAbsent(key string, value []byte) (bool, error)
+	Close() error
+}
+
+// embeddingRemoteKey is the remote key for a vector. The revision is part of
+// the key so workers running different model revisions never share vectors.
+func embeddingRemoteKey(contentHash, model, revision string) string {
+	return fmt.Sprintf("emb:%s:%s:%s", model, revision, contentHash)
+}
+
+// judgeRemoteKey is the remote key for a judge result.
+func judgeRemoteKey(contentHash, rubric, model string) string {
+	return fmt.Sprintf("judge:%s:%s:%s", model, rubric, contentHash)
+}
+
+// remoteJudgeEntry is the JSON encoding of a judge result in the remote tier.
+type remoteJudgeEntry struct {
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+}
+
+func encodeRemoteJudge(entry *JudgeCacheEntry) ([]byte, error) {
+	return json.Marshal(remoteJudgeEntry{Score: entry.Score, Explanation: entry.Explanation})
+}
+
+func decodeRemoteJudge(b []byte) (*JudgeCacheEntry, error) {
+	var e remoteJudgeEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, fmt.Errorf("decode remote judge entry: %w", err)
+	}
+	return &JudgeCacheEntry{Score: e.Score, Explanation: e.Explanation}, nil
+}
@@ -0,0 +1,134 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+// mapRemote is an in-process RemoteCache shared between stores in a test.
+type mapRemote struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+}
+
+func newMapRemote() *mapRemote { return &mapRemote{data: map[string][]byte{}} }
+
+func (m *mapRemote) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.data[key], nil
+}
+
+func (m *mapRemote) SetIfAbsent(key string, value []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return false, m.err
+	}
+	if _, ok := m.data[key]; ok {
+		return false, nil
+	}
+	m.data[key] = append([]byte(nil), value...)
+	return true, nil
+}
+
+func (m *mapRemote) Close() error { return nil }
+
+// openWorker opens an in-memory store standing in for one CI worker.
+func openWorker(t *testing.T, remote cache.RemoteCache) *cache.Store {
+	t.Helper()
+	s, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10, Remote: remote})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestEmbeddingCache_RemoteTierWriteThrough(t *testing.T) {
+	remote := newMapRemote()
+	a, b := openWorker(t, remote), openWorker(t, remote)
+
+	if err := a.Embeddings().PutWithRevision("h", "m", "r1", []float32{1, 2, 3}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	vec, err := b.Embeddings().GetChecked("h", "m", cache.EmbeddingMeta{Dimension: 3, Revision: "r1"})
+	if err != nil || len(vec) != 3 || vec[2] != 3 {
+		t.Fatalf("GetChecked = %v, %v; want remote hit", vec, err)
+	}
+	stats, _ := b.Embeddings().Stats()
+	if stats.Entries != 1 || stats.RemoteHits != 1 {
+		t.Errorf("stats = %+v, want 1 local entry written through and 1 remote hit", stats)
+	}
+
+	// A different revision is keyed separately and misses.
+	vec, err = b.Embeddings().GetChecked("h", "m", cache.EmbeddingMeta{Revision: "r2"})
+	if err != nil || vec != nil {
+		t.Errorf("GetChecked(r2) = %v, %v; want miss", vec, err)
+	}
+	// A dimension mismatch is rejected.
+	vec, _ = openWorker(t, remote).Embeddings().GetChecked("h", "m", cache.EmbeddingMeta{Dimension: 4, Revision: "r1"})
+	if vec != nil {
+		t.Errorf("GetChecked(dim 4) = %v, want miss", vec)
+	}
+}
+
+func TestJudgeCache_RemoteFirstWriterWins(t *testing.T) {
+	remote := newMapRemote()
+	a, b := openWorker(t, remote), openWorker(t, remote)
+
+	if err := a.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 0.9, Explanation: "first"}); err != nil {
+		t.Fatalf("Put a: %v", err)
+	}
+	if err := b.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 0.1, Explanation: "second"}); err != nil {
+		t.Fatalf("Put b: %v", err)
+	}
+
+	// b lost the race and adopts a's verdict locally.
+	got, err := b.Judge().Get("h", "rubric", "m")
+	if err != nil || got == nil || got.Score != 0.9 || got.Explanation != "first" {
+		t.Fatalf("b Get = %+v, %v; want first writer's entry", got, err)
+	}
+
+	c := openWorker(t, remote)
+	got, err = c.Judge().Get("h", "rubric", "m")
+	if err != nil || got == nil || got.Score != 0.9 {
+		t.Fatalf("c Get = %+v, %v; want remote hit", got, err)
+	}
+	if stats, _ := c.Judge().Stats(); stats.RemoteHits != 1 || stats.Entries != 1 {
+		t.Errorf("stats = %+v, want 1 remote hit written through", stats)
+	}
+}
+
+func TestRemoteTier_FailuresFallBackToLocal(t *testing.T) {
+	remote := newMapRemote()
+	remote.err = errors.New("connection refused")
+	s := openWorker(t, remote)
+
+	if err := s.Embeddings().Put("h", "m", []float32{1}); err != nil {
+		t.Fatalf("Put with failing remote: %v", err)
+	}
+	if vec, err := s.Embeddings().Get("h", "m"); err != nil || len(vec) != 1 {
+		t.Errorf("local Get = %v, %v; want local hit", vec, err)
+	}
+	if vec, err := s.Embeddings().Get("other", "m"); err != nil || vec != nil {
+		t.Errorf("Get miss = %v, %v; want (nil, nil)", vec, err)
+	}
+	if err := s.Judge().Put("h", "r", "m", &cache.JudgeCacheEntry{Score: 1}); err != nil {
+		t.Fatalf("judge Put with failing remote: %v", err)
+	}
+
+	es, _ := s.Embeddings().Stats()
+	js, _ := s.Judge().Stats()
+	if es.RemoteErrors != 2 || js.RemoteErrors != 1 {
+		t.Errorf("remote errors = %d/%d, want 2/1", es.RemoteErrors, js.RemoteErrors)
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// busyTimeoutMS is how long a connection waits on a locked database before
+	// returning SQLITE_BUSY.
+	busyTimeoutMS = 5000
+	// writeQueueSize bounds the number of writes waiting for the writer goroutine.
+	writeQueueSize = 256
+	// writeEnqueueTimeout is how long a write waits for queue space before failing.
+	writeEnqueueTimeout = 30 * time.Second
+	// walCheckpointInterval is how often the writer checkpoints the WAL into the database.
+	walCheckpointInterval = 30 * time.Second
+	// busyRetries is how many times a write is retried after SQLITE_BUSY.
+	busyRetries = 5
+)
+
+// errWriterClosed is returned by writes submitted after the store was closed.
+var errWriterClosed = errors.New("sqlite writer closed")
+
+// OpenDB opens a SQLite database at path with WAL journaling and a busy
+// timeout applied to every pooled connection.
+func OpenDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	return db, nil
+}
+
+// CheckDatabase opens the database at path read-only, without migrating it,
+// and runs SQLite's full integrity check. It returns the problems found
+// (none for a healthy database) and the applied schema version.
+func CheckDatabase(path string) (problems []string, version int, err error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, 0, fmt.Errorf("open sqlite: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, 0, fmt.Errorf("integrity check: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("integrity check: %w", err)
+	}
+	if len(problems) > 0 {
+		return problems, 0, nil
+	}
+
+	var hasMigrations int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&hasMigrations); err != nil {
+		return nil, 0, fmt.Errorf("read schema: %w", err)
+	}
+	if hasMigrations == 0 {
+		return nil, 0, nil
+	}
+	version, err = SchemaVersion(db)
+	return nil, version, err
+}
+
+// sqliteDSN appends the connection pragmas to path. Pragmas in the DSN run on
+// every new connection, unlike a one-off PRAGMA exec on the pool.
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)", path, sep, busyTimeoutMS)
+}
+
+// writeJob is a unit of work for the writer goroutine.
+type writeJob struct {
+	fn   func(db *sql.DB) error
+	done chan error
+}
+
+// sqliteWriter serializes all writes to a database through one goroutine so
+// concurrent callers queue instead of contending for the SQLite write lock.
+// It also checkpoints the WAL periodically and on close.
+type sqliteWriter struct {
+	db   *sql.DB
+	jobs chan writeJob
+	stop chan struct{}
+	done chan struct{}
+
+	// mu guards closed; exec holds the read lock while enqueueing so no job
+	// can be queued after close has started draining.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newSQLiteWriter(db *sql.DB) *sqliteWriter {
+	w := &sqliteWriter{
+		db:   db,
+		jobs: make(chan writeJob, writeQueueSize),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// exec runs fn on the writer goroutine and returns its error. It blocks while
+// the queue is full, failing after writeEnqueueTimeout rather than dropping the write.
+// fn must not call exec itself.
+func (w *sqliteWriter) exec(fn func(db *sql.DB) error) error {
+	job := writeJob{fn: fn, done: make(chan error, 1)}
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return errWriterClosed
+	}
+	timer := time.NewTimer(writeEnqueueTimeout)
+	select {
+	case w.jobs <- job:
+	case <-timer.C:
+		w.mu.RUnlock()
+		return fmt.Errorf("sqlite write queue full after %s", writeEnqueueTimeout)
+	}
+	timer.Stop()
+	w.mu.RUnlock()
+	return <-job.done
+}
+
+func (w *sqliteWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(walCheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case job := <-w.jobs:
+			job.done <- w.withRetry(job.fn)
+		case <-ticker.C:
+			_, _ = w.db.Exec(`PRAGMA wal_checkpoint(PASSIVE)`)
+		case <-w.stop:
+			// Drain writes accepted before close so none are lost.
+			for {
+				select {
+				case job := <-w.jobs:
+					job.done <- w.withRetry(job.fn)
+				default:
+					_, _ = w.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+					return
+				}
+			}
+		}
+	}
+}
+
+// withRetry runs fn, retrying with exponential backoff while SQLite reports
+// the database as busy (e.g. another process holds the write lock).
+func (w *sqliteWriter) withRetry(fn func(db *sql.DB) error) error {
+	backoff := 10 * time.Millisecond
+	var err error
+	for attempt := 0; attempt <= busyRetries; attempt++ {
+		if err = fn(w.db); err == nil || !isBusy(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return err
+}
+
+// close stops the writer after draining queued writes and checkpointing the WAL.
+func (w *sqliteWriter) close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.stop)
+	w.mu.Unlock()
+	<-w.done
+}
+
+// isBusy reports whether err is a SQLite lock contention error.
+func isBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "SQLITE_BUSY") || strings.Contains(msg, "database is locked")
+}
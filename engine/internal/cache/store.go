@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// StoreConfig sets the size limits of the caches handed out by Store.
+type StoreConfig struct {
+	EmbeddingMaxMB int
+	JudgeMaxMB     int
+	// Remote is an optional shared tier for embeddings and judge results. It
+	// is consulted before SQLite, and SQLite acts as a write-through second
+	// tier. Store.Close closes it.
+	Remote RemoteCache
+	// Cipher, when set, encrypts vectors and judge explanations at rest, in
+	// SQLite and in the remote tier. Opening the store with it discards
+	// entries written unencrypted.
+	Cipher *Cipher
+}
+
+// Store owns the single *sql.DB for attest.db. It runs schema migrations on
+// open, serializes all writes through one writer goroutine, and hands out the
+// embedding cache, judge cache, calibration store, and history store that
+// share the handle.
+type Store struct {
+	db           *sql.DB
+	writer       *sqliteWriter
+	remote       RemoteCache
+	embeddings   *EmbeddingCache
+	judge        *JudgeCache
+	calibrations *CalibrationStore
+	history      *HistoryStore
+}
+
+// OpenStore opens (or creates) attest.db at path and migrates it to the
+// current schema version.
+func OpenStore(path string, cfg StoreConfig) (*Store, error) {
+	db, err := OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	return newStore(db, cfg)
+}
+
+// OpenMemoryStore returns a Store backed by a private in-memory SQLite
+// database, for ephemeral runs that must not touch disk. Contents are lost on
+// Close. The pool is limited to one connection because each connection to
+// ":memory:" would otherwise see its own empty database.
+func OpenMemoryStore(cfg StoreConfig) (*Store, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("open in-memory sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	return newStore(db, cfg)
+}
+
+// newStore migrates db and builds the sub-stores around it. db is closed on error.
+func newStore(db *sql.DB, cfg StoreConfig) (*Store, error) {
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	w := newSQLiteWriter(db)
+	if cfg.Cipher != nil {
+		if err := purgePlaintext(w); err != nil {
+			w.close()
+			db.Close()
+			return nil, err
+		}
+	}
+	embeddings, err := newEmbeddingCache(db, w, cfg.EmbeddingMaxMB)
+	if err != nil {
+		w.close()
+		db.Close()
+		return nil, fmt.Errorf("open embedding cache: %w", err)
+	}
+	remote := cfg.Remote
+	if remote != nil && cfg.Cipher != nil {
+		remote = &encryptedRemote{RemoteCache: remote, cipher: cfg.Cipher}
+	}
+	embeddings.remote = remote
+	embeddings.cipher = cfg.Cipher
+	judge := newJudgeCache(db, w, cfg.JudgeMaxMB)
+	judge.remote = remote
+	judge.cipher = cfg.Cipher
+
+	return &Store{
+		db:           db,
+		writer:       w,
+		remote:       remote,
+		embeddings:   embeddings,
+		judge:        judge,
+		calibrations: &CalibrationStore{db: db, writer: w},
+		history:      newHistoryStore(db, w),
+	}, nil
+}
+
+// purgePlaintext deletes cache entries written before encryption was
+// enabled, then vacuums so their content does not linger in free pages.
+func purgePlaintext(w *sqliteWriter) error {
+	err := w.exec(func(db *sql.DB) error {
+		var n int64
+		for _, table := range []string{"embeddings", "judge_cache"} {
+			res, err := db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE encrypted = 0`, table))
+			if err != nil {
+				return err
+			}
+			deleted, _ := res.RowsAffected()
+			n += deleted
+		}
+		if n == 0 {
+			return nil
+		}
+		_, err := db.Exec(`VACUUM`)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("purge unencrypted cache entries: %w", err)
+	}
+	return nil
+}
+
+// Embeddings returns the embedding cache backed by the shared handle.
+func (s *Store) Embeddings() *EmbeddingCache { return s.embeddings }
+
+// Judge returns the judge cache backed by the shared handle.
+func (s *Store) Judge() *JudgeCache { return s.judge }
+
+// Calibrations returns the judge calibration store backed by the shared handle.
+func (s *Store) Calibrations() *CalibrationStore { return s.calibrations }
+
+// History returns the history store backed by the shared handle.
+func (s *Store) History() *HistoryStore { return s.history }
+
+// SetNamespace scopes the embedding cache, judge cache, and history store to
+// ns, so projects sharing attest.db keep separate entries and statistics.
+// Calibrations are shared. "" is the default namespace.
+func (s *Store) SetNamespace(ns string) {
+	s.embeddings.SetNamespace(ns)
+	s.judge.SetNamespace(ns)
+	s.history.SetNamespace(ns)
+}
+
+// Trim releases what an idle engine does not need: it evicts both caches to
+// their size limits, prunes history, truncates the WAL, and frees SQLite's
+// page cache.
+func (s *Store) Trim() error {
+	var errs []error
+	if err := s.embeddings.evictIfNeeded(); err != nil {
+		errs = append(errs, fmt.Errorf("trim embeddings: %w", err))
+	}
+	if err := s.judge.evictIfNeeded(); err != nil {
+		errs = append(errs, fmt.Errorf("trim judge cache: %w", err))
+	}
+	if err := s.history.Prune(s.history.pruneMaxRows, s.history.pruneMaxDays); err != nil {
+		errs = append(errs, fmt.Errorf("trim history: %w", err))
+	}
+	err := s.writer.exec(func(db *sql.DB) error {
+		if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			return err
+		}
+		_, err := db.Exec(`PRAGMA shrink_memory`)
+		return err
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("trim wal: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the caches' flush loops, drains queued writes, checkpoints the
+// WAL, and closes the database and remote tier.
+func (s *Store) Close() error {
+	_ = s.embeddings.Close()
+	_ = s.judge.Close()
+	if s.remote != nil {
+		_ = s.remote.Close()
+	}
+	s.writer.close()
+	return s.db.Close()
+}
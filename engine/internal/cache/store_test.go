@@ -0,0 +1,292 @@
+package cache_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+func TestStore_SharedHandleRoundTrip(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "attest.db")
+	cfg := cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10}
+
+	store, err := cache.OpenStore(dbPath, cfg)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	if err := store.Embeddings().Put("h", "m", []float32{1, 2}); err != nil {
+		t.Fatalf("embeddings Put: %v", err)
+	}
+	if err := store.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 0.9, Explanation: "ok"}); err != nil {
+		t.Fatalf("judge Put: %v", err)
+	}
+	if err := store.History().Record("trc", "a", "constraint", 1, "pass"); err != nil {
+		t.Fatalf("history Record: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	store, err = cache.OpenStore(dbPath, cfg)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	if v, err := store.Embeddings().Get("h", "m"); err != nil || len(v) != 2 {
+		t.Errorf("embeddings Get = %v, %v; want 2-dim vector", v, err)
+	}
+	if e, err := store.Judge().Get("h", "rubric", "m"); err != nil || e == nil || e.Score != 0.9 {
+		t.Errorf("judge Get = %+v, %v; want score 0.9", e, err)
+	}
+	if _, _, n, err := store.History().Stats("a"); err != nil || n != 1 {
+		t.Errorf("history count = %d, %v; want 1", n, err)
+	}
+}
+
+func TestMigrate_VersionedAndIdempotent(t *testing.T) {
+	db, err := cache.OpenDB(filepath.Join(t.TempDir(), "attest.db"))
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	defer db.Close()
+
+	if err := cache.Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	v1, err := cache.SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if v1 == 0 {
+		t.Fatal("SchemaVersion = 0 after Migrate")
+	}
+
+	if err := cache.Migrate(db); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&rows); err != nil {
+		t.Fatalf("count schema_migrations: %v", err)
+	}
+	if rows != v1 {
+		t.Errorf("schema_migrations rows = %d, want %d (one per version)", rows, v1)
+	}
+}
+
+func TestMigrate_AdoptsUnversionedDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "attest.db")
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	// Schema as written by engines that predate schema_migrations.
+	if _, err := raw.Exec(`
+		CREATE TABLE assertion_history (
+			id             INTEGER PRIMARY KEY AUTOINCREMENT,
+			trace_id       TEXT    NOT NULL,
+			assertion_id   TEXT    NOT NULL,
+			assertion_type TEXT    NOT NULL,
+			score          REAL    NOT NULL,
+			status         TEXT    NOT NULL,
+			created_at     INTEGER NOT NULL
+		);
+		INSERT INTO assertion_history (trace_id, assertion_id, assertion_type, score, status, created_at)
+		VALUES ('trc', 'legacy', 'constraint', 0.5, 'pass', 1);
+		CREATE TABLE assertion_quarantine (
+			assertion_id TEXT    PRIMARY KEY,
+			reason       TEXT    NOT NULL,
+			created_at   INTEGER NOT NULL
+		);
+		INSERT INTO assertion_quarantine (assertion_id, reason, created_at) VALUES ('legacy', 'flaky', 1);
+	`); err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+	raw.Close()
+
+	store, err := cache.OpenStore(dbPath, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenStore on legacy db: %v", err)
+	}
+	defer store.Close()
+
+	if _, _, n, err := store.History().Stats("legacy"); err != nil || n != 1 {
+		t.Errorf("legacy history count = %d, %v; want 1", n, err)
+	}
+	if err := store.History().RecordValue("trc", "legacy", 1); err != nil {
+		t.Errorf("RecordValue on migrated db: %v", err)
+	}
+	// Quarantine entries survive the rebuild keyed by namespace.
+	if q, err := store.History().IsQuarantined("legacy"); err != nil || !q {
+		t.Errorf("legacy quarantine = %v, %v; want quarantined", q, err)
+	}
+}
+
+func TestStore_Namespaces(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	history := store.History()
+
+	if err := store.Judge().Put("h", "rubric", "m", &cache.JudgeCacheEntry{Score: 0.9, Explanation: "default"}); err != nil {
+		t.Fatalf("judge Put: %v", err)
+	}
+	if err := store.Embeddings().Put("h", "m", []float32{1, 0}); err != nil {
+		t.Fatalf("embedding Put: %v", err)
+	}
+	if err := history.Record("trc", "a", "constraint", 1, "pass"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := history.Quarantine("a", "flaky"); err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+
+	store.SetNamespace("team-b")
+	if e, _ := store.Judge().Get("h", "rubric", "m"); e != nil {
+		t.Errorf("judge entry leaked into another namespace: %+v", e)
+	}
+	if v, _ := store.Embeddings().Get("h", "m"); v != nil {
+		t.Errorf("embedding leaked into another namespace: %v", v)
+	}
+	if _, _, n, _ := history.Stats("a"); n != 0 {
+		t.Errorf("history count in team-b = %d, want 0", n)
+	}
+	if q, _ := history.IsQuarantined("a"); q {
+		t.Error("quarantine leaked into another namespace")
+	}
+	for _, s := range []float64{0, 0} {
+		if err := history.Record("trc", "a", "constraint", s, "hard_fail"); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if mean, _, n, _ := history.Stats("a"); n != 2 || mean != 0 {
+		t.Errorf("team-b stats = (%v, %d), want (0, 2)", mean, n)
+	}
+
+	store.SetNamespace("")
+	if e, _ := store.Judge().Get("h", "rubric", "m"); e == nil || e.Explanation != "default" {
+		t.Errorf("default judge entry = %+v", e)
+	}
+	if mean, _, n, _ := history.Stats("a"); n != 1 || mean != 1 {
+		t.Errorf("default stats = (%v, %d), want (1, 1)", mean, n)
+	}
+	if ids, _ := history.QuarantinedIDs(); len(ids) != 1 || ids[0] != "a" {
+		t.Errorf("default quarantine = %v, want [a]", ids)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	for _, ns := range []string{"", "team-a", "proj.v2_eu"} {
+		if err := cache.ValidateNamespace(ns); err != nil {
+			t.Errorf("ValidateNamespace(%q) = %v", ns, err)
+		}
+	}
+	for _, ns := range []string{"team/a", "a b", "ünï", string(make([]byte, cache.MaxNamespaceLength+1))} {
+		if err := cache.ValidateNamespace(ns); err == nil {
+			t.Errorf("ValidateNamespace(%q) accepted", ns)
+		}
+	}
+}
+
+func TestOpenMemoryStore(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(gid int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				if err := store.Embeddings().Put(cache.ContentHash(fmt.Sprintf("%d-%d", gid, i)), "m", []float32{1}); err != nil {
+					t.Errorf("Put: %v", err)
+				}
+				if err := store.History().Record("trc", fmt.Sprintf("a-%d", gid), "constraint", 1, "pass"); err != nil {
+					t.Errorf("Record: %v", err)
+				}
+				if _, err := store.History().QueryWindow(fmt.Sprintf("a-%d", gid), 5); err != nil {
+					t.Errorf("QueryWindow: %v", err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	stats, err := store.Embeddings().Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Entries != 40 {
+		t.Errorf("entries = %d, want 40", stats.Entries)
+	}
+
+	// Each memory store is private.
+	other, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer other.Close()
+	if s, _ := other.Embeddings().Stats(); s.Entries != 0 {
+		t.Errorf("second memory store has %d entries, want 0", s.Entries)
+	}
+}
+
+func TestCheckDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "attest.db")
+	store, err := cache.OpenStore(dbPath, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	store.Close()
+
+	problems, version, err := cache.CheckDatabase(dbPath)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("healthy database: problems %v, err %v", problems, err)
+	}
+	if version != cache.LatestSchemaVersion() {
+		t.Errorf("version = %d, want %d", version, cache.LatestSchemaVersion())
+	}
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("this is not a sqlite database, just some bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if problems, _, err := cache.CheckDatabase(garbage); err == nil && len(problems) == 0 {
+		t.Error("garbage file passed the integrity check")
+	}
+}
+
+func TestStore_Trim(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "attest.db")
+	store, err := cache.OpenStore(dbPath, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	defer store.Close()
+	for i := 0; i < 50; i++ {
+		if err := store.Embeddings().Put(fmt.Sprintf("h%d", i), "m", make([]float32, 256)); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	if err := store.Trim(); err != nil {
+		t.Fatalf("Trim: %v", err)
+	}
+	if info, err := os.Stat(dbPath + "-wal"); err == nil && info.Size() != 0 {
+		t.Errorf("WAL is %d bytes after Trim, want truncated", info.Size())
+	}
+	if v, err := store.Embeddings().Get("h0", "m"); err != nil || len(v) != 256 {
+		t.Errorf("Get after Trim = %d floats, %v", len(v), err)
+	}
+}
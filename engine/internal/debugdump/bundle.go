@@ -0,0 +1,188 @@
+// Package debugdump packages engine diagnostics into a tar.gz bundle for
+// support tickets: recent logs, recent traces with their text redacted,
+// assertion specs, engine configuration with secrets stripped, cache
+// statistics, and version information.
+package debugdump
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Sources is everything a bundle can include. Nil or empty fields are omitted.
+type Sources struct {
+	EngineVersion string
+	// Logs holds recent engine log lines, oldest first.
+	Logs []string
+	// Traces are redacted before they are written.
+	Traces     []*types.Trace
+	Assertions []types.Assertion
+	// Environ is the process environment (os.Environ form); only ATTEST_*
+	// variables are kept, with secrets stripped.
+	Environ        []string
+	EmbeddingCache *cache.CacheStats
+	JudgeCache     *cache.CacheStats
+	StartedAt      time.Time
+}
+
+// Bundle is a built, not yet written, debug bundle.
+type Bundle struct {
+	files []file
+	now   time.Time
+}
+
+type file struct {
+	name string
+	desc string
+	data []byte
+}
+
+// Build assembles the bundle contents from src.
+func Build(src Sources) (*Bundle, error) {
+	b := &Bundle{now: time.Now().UTC()}
+
+	version := map[string]any{
+		"engine_version": src.EngineVersion,
+		"go_version":     runtime.Version(),
+		"os":             runtime.GOOS,
+		"arch":           runtime.GOARCH,
+		"generated_at":   b.now.Format(time.RFC3339),
+	}
+	if !src.StartedAt.IsZero() {
+		version["uptime_seconds"] = int64(b.now.Sub(src.StartedAt).Seconds())
+	}
+	if err := b.addJSON("version.json", "engine version, Go runtime, and platform", version); err != nil {
+		return nil, err
+	}
+	if err := b.addJSON("config.json", "ATTEST_* environment variables; secrets redacted", SanitizeEnv(src.Environ)); err != nil {
+		return nil, err
+	}
+
+	if src.EmbeddingCache != nil || src.JudgeCache != nil {
+		stats := map[string]*cache.CacheStats{}
+		if src.EmbeddingCache != nil {
+			stats["embeddings"] = src.EmbeddingCache
+		}
+		if src.JudgeCache != nil {
+			stats["judge"] = src.JudgeCache
+		}
+		if err := b.addJSON("cache_stats.json", "embedding and judge cache statistics", stats); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(src.Logs) > 0 {
+		var sb strings.Builder
+		for _, line := range src.Logs {
+			sb.WriteString(strings.TrimRight(line, "\n"))
+			sb.WriteByte('\n')
+		}
+		b.add("logs.jsonl", fmt.Sprintf("last %d engine log lines", len(src.Logs)), []byte(sb.String()))
+	}
+
+	if len(src.Assertions) > 0 {
+		if err := b.addJSON("assertions.json", fmt.Sprintf("%d assertion specs", len(src.Assertions)), src.Assertions); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, t := range src.Traces {
+		name := fmt.Sprintf("traces/%03d_%s.json", i+1, safeName(t.TraceID))
+		if err := b.addJSON(name, "trace with input, output, and step text redacted", RedactTrace(t)); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Manifest lists the files the bundle will contain.
+func (b *Bundle) Manifest() []types.DebugDumpEntry {
+	entries := make([]types.DebugDumpEntry, len(b.files))
+	for i, f := range b.files {
+		entries[i] = types.DebugDumpEntry{Name: f.name, Description: f.desc, Bytes: len(f.data)}
+	}
+	return entries
+}
+
+// WriteTarGz writes the bundle, preceded by manifest.json, as a gzipped tarball.
+func (b *Bundle) WriteTarGz(w io.Writer) error {
+	manifest, err := json.MarshalIndent(b.Manifest(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("debug dump: marshal manifest: %w", err)
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := append([]file{{name: "manifest.json", data: manifest}}, b.files...)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    "attest-debug/" + f.name,
+			Mode:    0o600,
+			Size:    int64(len(f.data)),
+			ModTime: b.now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("debug dump: write %s: %w", f.name, err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("debug dump: write %s: %w", f.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("debug dump: close tar: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("debug dump: close gzip: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes the bundle to path, readable only by the current user.
+func (b *Bundle) WriteFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("debug dump: %w", err)
+	}
+	if err := b.WriteTarGz(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (b *Bundle) add(name, desc string, data []byte) {
+	b.files = append(b.files, file{name: name, desc: desc, data: data})
+}
+
+func (b *Bundle) addJSON(name, desc string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("debug dump: marshal %s: %w", name, err)
+	}
+	b.add(name, desc, data)
+	return nil
+}
+
+// safeName maps an ID to a string usable as a file name.
+func safeName(id string) string {
+	if id == "" {
+		return "trace"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, id)
+}
@@ -0,0 +1,175 @@
+package debugdump
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// RedactTrace returns a copy of t in which every string in the input, output,
+// step args, step results, and step metadata, every step and transcript
+// message content and user action value, and every step error message is
+// replaced by a length marker, in sub-traces too.
+// Structure, keys, numbers, step names and types, and trace metadata are kept
+// so support can still see the shape of the run.
+func RedactTrace(t *types.Trace) *types.Trace {
+	if t == nil {
+		return nil
+	}
+	out := *t
+	out.Input = redactJSON(t.Input)
+	out.Output = redactJSON(t.Output)
+	out.Transcript = redactMessages(t.Transcript)
+	out.Steps = make([]types.Step, len(t.Steps))
+	for i, s := range t.Steps {
+		s.Args = redactJSON(s.Args)
+		s.Result = redactJSON(s.Result)
+		s.Metadata = redactJSON(s.Metadata)
+		s.Messages = redactMessages(s.Messages)
+		if s.Error != nil {
+			e := *s.Error
+			e.Message = redactString(e.Message)
+			s.Error = &e
+		}
+		s.SubTrace = RedactTrace(s.SubTrace)
+		out.Steps[i] = s
+	}
+	return &out
+}
+
+// redactMessages returns a copy of msgs with every content and action value
+// redacted. Roles, tool names, and action types are kept.
+func redactMessages(msgs []types.Message) []types.Message {
+	if msgs == nil {
+		return nil
+	}
+	out := make([]types.Message, len(msgs))
+	for i, m := range msgs {
+		m.Content = redactString(m.Content)
+		m.Actions = redactActions(m.Actions)
+		out[i] = m
+	}
+	return out
+}
+
+// redactActions returns a copy of actions with file names and contents, form
+// fields, and selected options redacted.
+func redactActions(actions []types.UserAction) []types.UserAction {
+	if actions == nil {
+		return nil
+	}
+	out := make([]types.UserAction, len(actions))
+	for i, a := range actions {
+		if a.File != nil {
+			f := *a.File
+			f.Name = redactString(f.Name)
+			f.Content = redactString(f.Content)
+			a.File = &f
+		}
+		if a.Fields != nil {
+			fields := make(map[string]string, len(a.Fields))
+			for k, v := range a.Fields {
+				fields[k] = redactString(v)
+			}
+			a.Fields = fields
+		}
+		a.Option = redactString(a.Option)
+		out[i] = a
+	}
+	return out
+}
+
+// redactString replaces a non-empty s with its length marker.
+func redactString(s string) string {
+	if s == "" {
+		return s
+	}
+	return redactValue(s).(string)
+}
+
+// redactJSON replaces every string value in raw (object keys excepted).
+// Unparseable input is replaced wholesale.
+func redactJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return json.RawMessage(`"[redacted: unparseable]"`)
+	}
+	data, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return json.RawMessage(`"[redacted]"`)
+	}
+	return data
+}
+
+func redactValue(v any) any {
+	switch x := v.(type) {
+	case string:
+		return fmt.Sprintf("[redacted: %d chars]", len([]rune(x)))
+	case []any:
+		for i := range x {
+			x[i] = redactValue(x[i])
+		}
+		return x
+	case map[string]any:
+		for k := range x {
+			x[k] = redactValue(x[k])
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// secretMarkers flag environment variable names whose values are never exported.
+var secretMarkers = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "CREDENTIAL"}
+
+// SanitizeEnv returns the ATTEST_* variables in environ with secret values
+// replaced by "[redacted]" and passwords stripped from URLs.
+func SanitizeEnv(environ []string) map[string]string {
+	out := make(map[string]string)
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, "ATTEST_") {
+			continue
+		}
+		out[name] = sanitizeValue(name, value)
+	}
+	return out
+}
+
+func sanitizeValue(name, value string) string {
+	upper := strings.ToUpper(name)
+	for _, m := range secretMarkers {
+		if strings.Contains(upper, m) {
+			if value == "" {
+				return ""
+			}
+			return "[redacted]"
+		}
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+		}
+	}
+	if q := u.Query(); len(q) > 0 {
+		for k := range q {
+			if sanitizeValue(k, "x") != "x" {
+				q.Set(k, "redacted")
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,102 @@
+// Package deprecation collects the deprecation notices a request triggers,
+// such as an old trace schema_version, so the engine can return them in the
+// response's warnings array and SDKs can show migration guidance before the
+// deprecated form is removed.
+package deprecation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Codes of the deprecations the engine reports. SchemaVersion matches the
+// trace warning code for the same condition.
+const (
+	SchemaVersion = "deprecated_schema_version"
+	Alias         = "deprecated_alias"
+)
+
+// SchemaVersionSunset is the first engine version that rejects traces below
+// the current schema_version.
+const SchemaVersionSunset = "1.0.0"
+
+// SchemaVersionNotice is the notice for a trace at schema_version version,
+// older than current.
+func SchemaVersionNotice(version, current int) types.Deprecation {
+	return types.Deprecation{
+		Code:          SchemaVersion,
+		Message:       fmt.Sprintf("trace schema_version %d is deprecated; emit schema_version %d traces", version, current),
+		SunsetVersion: SchemaVersionSunset,
+	}
+}
+
+// AliasSunset is the first engine version that rejects deprecated check and
+// spec field names.
+const AliasSunset = "1.0.0"
+
+// AliasNotice is the notice for a deprecated name in an assertionType spec.
+// what is "check" for a renamed check value and "field" for a renamed spec
+// field.
+func AliasNotice(assertionType, what, old, replacement string) types.Deprecation {
+	return types.Deprecation{
+		Code:          Alias,
+		Message:       fmt.Sprintf("%s %s %q is deprecated; use %q", assertionType, what, old, replacement),
+		SunsetVersion: AliasSunset,
+	}
+}
+
+// Notices collects the deprecations reported during one request. It is safe
+// for concurrent use, and every method is a no-op on a nil Notices so call
+// sites need not check.
+type Notices struct {
+	mu   sync.Mutex
+	list []types.Deprecation
+	seen map[types.Deprecation]bool
+}
+
+type noticesKey struct{}
+
+// With returns a copy of ctx carrying a new, empty Notices.
+func With(ctx context.Context) (context.Context, *Notices) {
+	n := &Notices{seen: make(map[types.Deprecation]bool)}
+	return context.WithValue(ctx, noticesKey{}, n), n
+}
+
+// FromContext returns the Notices carried by ctx, or nil.
+func FromContext(ctx context.Context) *Notices {
+	n, _ := ctx.Value(noticesKey{}).(*Notices)
+	return n
+}
+
+// Report records d on the Notices carried by ctx. Identical notices are
+// reported once per request.
+func Report(ctx context.Context, d types.Deprecation) {
+	FromContext(ctx).Add(d)
+}
+
+// Add records d unless an identical notice was already recorded.
+func (n *Notices) Add(d types.Deprecation) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.seen[d] {
+		return
+	}
+	n.seen[d] = true
+	n.list = append(n.list, d)
+}
+
+// List returns the recorded notices in the order they were first reported.
+func (n *Notices) List() []types.Deprecation {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]types.Deprecation(nil), n.list...)
+}
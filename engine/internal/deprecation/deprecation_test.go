@@ -0,0 +1,40 @@
+package deprecation
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNotices_DedupesAndKeepsOrder(t *testing.T) {
+	ctx, notices := With(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Report(ctx, SchemaVersionNotice(1, 2))
+		}()
+	}
+	wg.Wait()
+	Report(ctx, SchemaVersionNotice(0, 2))
+
+	list := notices.List()
+	if len(list) != 2 {
+		t.Fatalf("got %d notices, want 2: %+v", len(list), list)
+	}
+	if list[0].Message != "trace schema_version 1 is deprecated; emit schema_version 2 traces" || list[0].SunsetVersion != SchemaVersionSunset {
+		t.Errorf("first notice = %+v", list[0])
+	}
+	if list[1].Code != SchemaVersion {
+		t.Errorf("second notice code = %q", list[1].Code)
+	}
+}
+
+func TestReport_WithoutNotices(t *testing.T) {
+	// Reporting outside a request is a no-op.
+	Report(context.Background(), SchemaVersionNotice(1, 2))
+	if FromContext(context.Background()).List() != nil {
+		t.Error("nil Notices returned a list")
+	}
+}
@@ -3,8 +3,11 @@ package llm
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"sync"
 	"time"
+
+	"github.com/attest-ai/attest/engine/internal/timing"
 )
 
 // MockProvider implements Provider with configurable responses for testing.
@@ -18,6 +21,12 @@ type MockProvider struct {
 	ReplayMode       bool
 	SimulatedLatency time.Duration
 	MatchFunc        func(*CompletionRequest) *CompletionResponse
+	// ResponseLatencies delays the answer to call i by
+	// ResponseLatencies[i % len], after SimulatedLatency. Rules use their
+	// own Delay instead.
+	ResponseLatencies []time.Duration
+
+	rules []*MockRule
 }
 
 // NewMockProvider creates a MockProvider cycling through the given responses.
@@ -36,6 +45,9 @@ func (m *MockProvider) Name() string        { return "mock" }
 func (m *MockProvider) DefaultModel() string { return "mock-model" }
 
 func (m *MockProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	start := time.Now()
+	defer func() { timing.FromContext(ctx).ProviderCall(m.Name(), time.Since(start)) }()
+
 	m.mu.Lock()
 	latency := m.SimulatedLatency
 	m.mu.Unlock()
@@ -48,6 +60,21 @@ func (m *MockProvider) Complete(ctx context.Context, req *CompletionRequest) (*C
 		}
 	}
 
+	resp, delay, err := m.next(req)
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return resp, err
+}
+
+// next records req and picks its outcome and scripted delay. Precedence:
+// Errors and FailAt for the call index, then rules, then MatchFunc, then
+// Responses.
+func (m *MockProvider) next(req *CompletionRequest) (*CompletionResponse, time.Duration, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -58,27 +85,45 @@ func (m *MockProvider) Complete(ctx context.Context, req *CompletionRequest) (*C
 
 	// Return error if configured for this call index
 	if idx < len(m.Errors) && m.Errors[idx] != nil {
-		return nil, m.Errors[idx]
+		return nil, 0, m.Errors[idx]
+	}
+
+	// Rules take priority over MatchFunc and index-based selection
+	for _, r := range m.rules {
+		if r.remaining != 0 && r.matches(req) {
+			if r.remaining > 0 {
+				r.remaining--
+			}
+			if r.err != nil {
+				return nil, r.latency, r.err
+			}
+			return r.response, r.latency, nil
+		}
+	}
+
+	var delay time.Duration
+	if len(m.ResponseLatencies) > 0 {
+		delay = m.ResponseLatencies[idx%len(m.ResponseLatencies)]
 	}
 
 	// MatchFunc takes priority over index-based selection
 	if m.MatchFunc != nil {
 		if resp := m.MatchFunc(req); resp != nil {
-			return resp, nil
+			return resp, delay, nil
 		}
 	}
 
 	// ReplayMode: consume responses exactly once
 	if m.ReplayMode {
 		if idx >= len(m.Responses) {
-			return nil, fmt.Errorf("mock provider: all %d responses exhausted at call %d", len(m.Responses), idx)
+			return nil, 0, fmt.Errorf("mock provider: all %d responses exhausted at call %d", len(m.Responses), idx)
 		}
-		return m.Responses[idx], nil
+		return m.Responses[idx], delay, nil
 	}
 
 	// Default cycling behavior
 	if len(m.Responses) > 0 {
-		return m.Responses[idx%len(m.Responses)], nil
+		return m.Responses[idx%len(m.Responses)], delay, nil
 	}
 
 	// Default response
@@ -89,7 +134,94 @@ func (m *MockProvider) Complete(ctx context.Context, req *CompletionRequest) (*C
 		OutputTokens: 10,
 		Cost:         0.001,
 		DurationMS:   50,
-	}, nil
+	}, delay, nil
+}
+
+// FailAt makes the call with the given zero-based index fail with err.
+func (m *MockProvider) FailAt(call int, err error) *MockProvider {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.Errors) <= call {
+		m.Errors = append(m.Errors, nil)
+	}
+	m.Errors[call] = err
+	return m
+}
+
+// MockRule scripts the provider's answer to requests matching a role and
+// content pattern. Create rules with On and configure them with Respond,
+// RespondWith, Fail, Delay, and Times. Rules are tried in the order they were
+// created, and the first live match wins.
+type MockRule struct {
+	role      string
+	pattern   *regexp.Regexp
+	response  *CompletionResponse
+	err       error
+	latency   time.Duration
+	remaining int // < 0: unlimited
+}
+
+// On adds a rule matching requests where a message with the given role has
+// content matching the regular expression pattern. Role "system" matches the
+// system prompt; an empty role matches any message or the system prompt. On
+// panics if pattern does not compile. The rule answers with a default
+// response until configured.
+func (m *MockProvider) On(role, pattern string) *MockRule {
+	r := &MockRule{
+		role:      role,
+		pattern:   regexp.MustCompile(pattern),
+		response:  &CompletionResponse{Content: `{"score": 0.5, "explanation": "default mock response"}`, Model: "mock-model"},
+		remaining: -1,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, r)
+	return r
+}
+
+// Respond answers matching requests with content.
+func (r *MockRule) Respond(content string) *MockRule {
+	r.response = &CompletionResponse{Content: content, Model: "mock-model"}
+	return r
+}
+
+// RespondWith answers matching requests with resp.
+func (r *MockRule) RespondWith(resp *CompletionResponse) *MockRule {
+	r.response = resp
+	return r
+}
+
+// Fail answers matching requests with err.
+func (r *MockRule) Fail(err error) *MockRule {
+	r.err = err
+	return r
+}
+
+// Delay holds each answer for d, honoring context cancellation.
+func (r *MockRule) Delay(d time.Duration) *MockRule {
+	r.latency = d
+	return r
+}
+
+// Times retires the rule after n matches.
+func (r *MockRule) Times(n int) *MockRule {
+	r.remaining = n
+	return r
+}
+
+func (r *MockRule) matches(req *CompletionRequest) bool {
+	if (r.role == "" || r.role == "system") && r.pattern.MatchString(req.SystemPrompt) {
+		return true
+	}
+	if r.role == "system" {
+		return false
+	}
+	for _, msg := range req.Messages {
+		if (r.role == "" || msg.Role == r.role) && r.pattern.MatchString(msg.Content) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetCallCount returns the number of times Complete has been called.
@@ -190,3 +190,81 @@ func TestMockProviderSimulatedLatencyContextCancel(t *testing.T) {
 		t.Fatal("expected context cancellation error, got nil")
 	}
 }
+
+func TestMockProviderRules(t *testing.T) {
+	p := NewMockProvider([]*CompletionResponse{{Content: "fallback"}}, nil)
+	p.On("system", `(?i)judge`).Respond(`{"score": 1}`)
+	p.On("user", `refund`).Respond("first refund").Times(1)
+	p.On("user", `refund`).Respond("later refund")
+
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		req  *CompletionRequest
+		want string
+	}{
+		{"system prompt", &CompletionRequest{SystemPrompt: "You are a Judge."}, `{"score": 1}`},
+		{"first match", &CompletionRequest{Messages: []Message{{Role: "user", Content: "I want a refund"}}}, "first refund"},
+		{"rule exhausted", &CompletionRequest{Messages: []Message{{Role: "user", Content: "refund please"}}}, "later refund"},
+		{"wrong role", &CompletionRequest{Messages: []Message{{Role: "assistant", Content: "refund issued"}}}, "fallback"},
+	}
+	for _, tt := range tests {
+		resp, err := p.Complete(ctx, tt.req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if resp.Content != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, resp.Content, tt.want)
+		}
+	}
+}
+
+func TestMockProviderRuleFailAndDelay(t *testing.T) {
+	errRate := errors.New("rate limited")
+	p := NewMockProvider(nil, nil)
+	p.On("", `slow`).Delay(30 * time.Millisecond).Respond("done")
+	p.On("", `flaky`).Fail(errRate)
+
+	ctx := context.Background()
+	start := time.Now()
+	resp, err := p.Complete(ctx, &CompletionRequest{Messages: []Message{{Role: "user", Content: "slow path"}}})
+	if err != nil || resp.Content != "done" {
+		t.Fatalf("got %v, %v; want done", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("elapsed %v < rule delay", elapsed)
+	}
+
+	if _, err := p.Complete(ctx, &CompletionRequest{SystemPrompt: "flaky"}); !errors.Is(err, errRate) {
+		t.Errorf("err = %v, want %v", err, errRate)
+	}
+}
+
+func TestMockProviderFailAt(t *testing.T) {
+	errBoom := errors.New("boom")
+	p := NewMockProvider([]*CompletionResponse{{Content: "ok"}}, nil).FailAt(2, errBoom)
+	p.On("", ".").Respond("rule")
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_, err := p.Complete(ctx, &CompletionRequest{SystemPrompt: "x"})
+		if (i == 2) != errors.Is(err, errBoom) {
+			t.Errorf("call %d: err = %v", i, err)
+		}
+	}
+}
+
+func TestMockProviderResponseLatencies(t *testing.T) {
+	p := NewMockProvider([]*CompletionResponse{{Content: "fast"}, {Content: "slow"}}, nil)
+	p.ResponseLatencies = []time.Duration{0, 5 * time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if resp, err := p.Complete(ctx, &CompletionRequest{}); err != nil || resp.Content != "fast" {
+		t.Fatalf("first call = %v, %v", resp, err)
+	}
+	if _, err := p.Complete(ctx, &CompletionRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second call err = %v, want deadline exceeded", err)
+	}
+}
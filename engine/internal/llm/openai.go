@@ -8,6 +8,9 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
 )
 
 const (
@@ -58,6 +61,7 @@ type openAIChatRequest struct {
 	Messages    []openAIChatMessage `json:"messages"`
 	Temperature float64             `json:"temperature,omitempty"`
 	MaxTokens   int                 `json:"max_tokens,omitempty"`
+	Seed        *int64              `json:"seed,omitempty"`
 }
 
 type openAIChatResponse struct {
@@ -95,6 +99,7 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (
 		Messages:    messages,
 		Temperature: req.Temperature,
 		MaxTokens:   req.MaxTokens,
+		Seed:        req.Seed,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -109,13 +114,18 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if id := logging.RequestID(ctx); id != "" {
+		httpReq.Header.Set("X-Client-Request-Id", id)
+	}
 
 	httpResp, err := p.client.Do(httpReq)
 	if err != nil {
+		timing.FromContext(ctx).ProviderCall(p.Name(), time.Since(start))
 		return nil, fmt.Errorf("openai complete: http: %w", err)
 	}
 	defer httpResp.Body.Close()
 	durationMS := time.Since(start).Milliseconds()
+	timing.FromContext(ctx).ProviderCall(p.Name(), time.Since(start))
 
 	raw, err := io.ReadAll(httpResp.Body)
 	if err != nil {
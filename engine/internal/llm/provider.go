@@ -15,6 +15,9 @@ type CompletionRequest struct {
 	Messages     []Message
 	Temperature  float64
 	MaxTokens    int
+	// Seed requests deterministic sampling when non-nil. Providers that do not
+	// support seeded sampling ignore it.
+	Seed *int64
 }
 
 // CompletionResponse holds the result of a completion call.
@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"golang.org/x/time/rate"
+
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/timing"
 )
 
 // RateLimiterConfig configures the token-bucket rate limiter.
@@ -67,18 +70,23 @@ func (r *RateLimitedProvider) DefaultModel() string { return r.inner.DefaultMode
 // Complete waits for a rate limit token then calls the inner provider.
 // On transient failure it retries with exponential backoff up to MaxRetries.
 func (r *RateLimitedProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	rec := timing.FromContext(ctx)
 	var lastErr error
 	for attempt := 0; attempt <= r.cfg.MaxRetries; attempt++ {
+		waitStart := time.Now()
 		if attempt > 0 {
 			backoff := r.backoff(attempt)
 			select {
 			case <-time.After(backoff):
 			case <-ctx.Done():
+				rec.RateLimitWait(time.Since(waitStart))
 				return nil, fmt.Errorf("rate limited provider: context cancelled during backoff: %w", ctx.Err())
 			}
 		}
 
-		if err := r.limiter.Wait(ctx); err != nil {
+		err := r.limiter.Wait(ctx)
+		rec.RateLimitWait(time.Since(waitStart))
+		if err != nil {
 			return nil, fmt.Errorf("rate limiter wait: %w", err)
 		}
 
@@ -87,6 +95,10 @@ func (r *RateLimitedProvider) Complete(ctx context.Context, req *CompletionReque
 			return resp, nil
 		}
 		lastErr = err
+		if attempt < r.cfg.MaxRetries {
+			logging.FromContext(ctx).Warn("provider call failed, retrying",
+				"provider", r.Name(), "attempt", attempt+1, "err", err)
+		}
 	}
 	return nil, fmt.Errorf("rate limited provider: all %d retries exhausted: %w", r.cfg.MaxRetries, lastErr)
 }
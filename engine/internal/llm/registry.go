@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Capability is a kind of call a registered provider can serve.
+type Capability string
+
+const (
+	// CapabilityChat marks providers that implement Provider (judge, simulation).
+	CapabilityChat Capability = "chat"
+	// CapabilityEmbed marks providers that implement Embedder (layer 5).
+	CapabilityEmbed Capability = "embed"
+)
+
+// ErrUnsupportedCapability is returned when a registered provider cannot serve
+// the requested capability.
+var ErrUnsupportedCapability = errors.New("capability not supported by provider")
+
+// Embedder produces vector embeddings for text. It has the same method set as
+// embedding.Embedder, so registry embedders plug into layer 5 unchanged.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	Model() string
+}
+
+// ProviderConfig is the configuration shared by every capability of one
+// provider: credentials and endpoint once, a model per capability.
+type ProviderConfig struct {
+	APIKey  string
+	BaseURL string
+	// ChatModel and EmbedModel select models; empty uses the provider default.
+	ChatModel  string
+	EmbedModel string
+}
+
+// ProviderFactory constructs a provider's capabilities. A nil constructor
+// means the provider lacks that capability.
+type ProviderFactory struct {
+	Chat  func(cfg ProviderConfig) (Provider, error)
+	Embed func(cfg ProviderConfig) (Embedder, error)
+}
+
+// Registry maps provider names to factories, so one configured provider can
+// back both the judge and the embedding layer.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]ProviderFactory
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]ProviderFactory)}
+}
+
+// Register adds or replaces the factory for name.
+func (r *Registry) Register(name string, f ProviderFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[name] = f
+}
+
+// Capabilities returns what the named provider can serve, or nil when it is
+// not registered.
+func (r *Registry) Capabilities(name string) []Capability {
+	r.mu.RLock()
+	f, ok := r.factories[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	caps := []Capability{}
+	if f.Chat != nil {
+		caps = append(caps, CapabilityChat)
+	}
+	if f.Embed != nil {
+		caps = append(caps, CapabilityEmbed)
+	}
+	return caps
+}
+
+// Supports reports whether the named provider can serve capability c.
+func (r *Registry) Supports(name string, c Capability) bool {
+	for _, have := range r.Capabilities(name) {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the sorted names of the providers that can serve c.
+func (r *Registry) Names(c Capability) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var names []string
+	for name, f := range r.factories {
+		if (c == CapabilityChat && f.Chat != nil) || (c == CapabilityEmbed && f.Embed != nil) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chat constructs the named provider's chat capability.
+func (r *Registry) Chat(name string, cfg ProviderConfig) (Provider, error) {
+	f, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Chat == nil {
+		return nil, fmt.Errorf("provider %q: %s: %w", name, CapabilityChat, ErrUnsupportedCapability)
+	}
+	return f.Chat(cfg)
+}
+
+// Embedder constructs the named provider's embedding capability.
+func (r *Registry) Embedder(name string, cfg ProviderConfig) (Embedder, error) {
+	f, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Embed == nil {
+		return nil, fmt.Errorf("provider %q: %s: %w", name, CapabilityEmbed, ErrUnsupportedCapability)
+	}
+	return f.Embed(cfg)
+}
+
+func (r *Registry) lookup(name string) (ProviderFactory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.factories[name]
+	if !ok {
+		return ProviderFactory{}, fmt.Errorf("provider %q is not registered", name)
+	}
+	return f, nil
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type stubEmbedder struct{ model string }
+
+func (e stubEmbedder) Embed(context.Context, string) ([]float32, error) { return []float32{1}, nil }
+func (e stubEmbedder) Model() string                                    { return e.model }
+
+func TestRegistryCapabilities(t *testing.T) {
+	r := NewRegistry()
+	r.Register("both", ProviderFactory{
+		Chat:  func(ProviderConfig) (Provider, error) { return NewMockProvider(nil, nil), nil },
+		Embed: func(cfg ProviderConfig) (Embedder, error) { return stubEmbedder{cfg.EmbedModel}, nil },
+	})
+	r.Register("chat-only", ProviderFactory{
+		Chat: func(ProviderConfig) (Provider, error) { return NewMockProvider(nil, nil), nil },
+	})
+
+	if got := r.Capabilities("both"); !reflect.DeepEqual(got, []Capability{CapabilityChat, CapabilityEmbed}) {
+		t.Errorf("Capabilities(both) = %v", got)
+	}
+	if got := r.Capabilities("missing"); got != nil {
+		t.Errorf("Capabilities(missing) = %v, want nil", got)
+	}
+	if got := r.Names(CapabilityChat); !reflect.DeepEqual(got, []string{"both", "chat-only"}) {
+		t.Errorf("Names(chat) = %v", got)
+	}
+	if got := r.Names(CapabilityEmbed); !reflect.DeepEqual(got, []string{"both"}) {
+		t.Errorf("Names(embed) = %v", got)
+	}
+
+	e, err := r.Embedder("both", ProviderConfig{EmbedModel: "small"})
+	if err != nil || e.Model() != "small" {
+		t.Errorf("Embedder(both) = %v, %v", e, err)
+	}
+	if _, err := r.Embedder("chat-only", ProviderConfig{}); !errors.Is(err, ErrUnsupportedCapability) {
+		t.Errorf("Embedder(chat-only) err = %v, want ErrUnsupportedCapability", err)
+	}
+	if _, err := r.Chat("missing", ProviderConfig{}); err == nil {
+		t.Error("Chat(missing): expected error")
+	}
+}
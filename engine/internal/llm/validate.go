@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrModelUnavailable marks a definitive validation failure: the model does
+// not exist or the credentials cannot use it. Other validation errors, such
+// as network failures, are transient and say nothing about the configuration.
+var ErrModelUnavailable = errors.New("model unavailable")
+
+// ModelValidator is implemented by providers and embedders that can confirm
+// their configured model exists and is accessible before first use.
+type ModelValidator interface {
+	ValidateModel(ctx context.Context) error
+}
+
+// ValidateModel checks that the provider's default model exists.
+func (p *OpenAIProvider) ValidateModel(ctx context.Context) error {
+	return CheckOpenAIModel(ctx, p.client, p.baseURL, p.apiKey, p.model)
+}
+
+// ValidateModel delegates to the inner provider when it is a ModelValidator.
+func (r *RateLimitedProvider) ValidateModel(ctx context.Context) error {
+	if v, ok := r.inner.(ModelValidator); ok {
+		return v.ValidateModel(ctx)
+	}
+	return nil
+}
+
+// CheckOpenAIModel retrieves model from an OpenAI-compatible models endpoint.
+// A 401, 403, or 404 response wraps ErrModelUnavailable.
+func CheckOpenAIModel(ctx context.Context, client *http.Client, baseURL, apiKey, model string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/models/"+url.PathEscape(model), nil)
+	if err != nil {
+		return fmt.Errorf("openai models: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("openai models: http: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg := resp.Status
+	if json.Unmarshal(raw, &body) == nil && body.Error != nil && body.Error.Message != "" {
+		msg = body.Error.Message
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("model %q not found: %s: %w", model, msg, ErrModelUnavailable)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("model %q not accessible with the configured API key: %s: %w", model, msg, ErrModelUnavailable)
+	default:
+		return fmt.Errorf("openai models: HTTP %d: %s", resp.StatusCode, msg)
+	}
+}
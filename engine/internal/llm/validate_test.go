@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOpenAIModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/models/gpt-4.1":
+			if r.Header.Get("Authorization") != "Bearer good" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error": {"message": "Incorrect API key provided"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id": "gpt-4.1", "object": "model"}`))
+		case "/models/flaky":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"message": "The model does not exist"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		key, model  string
+		wantErr     bool
+		unavailable bool
+	}{
+		{"exists", "good", "gpt-4.1", false, false},
+		{"missing model", "good", "gpt-9", true, true},
+		{"bad key", "bad", "gpt-4.1", true, true},
+		{"server error", "good", "flaky", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := NewOpenAIProvider(tt.key, tt.model, srv.URL)
+			err := p.ValidateModel(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrModelUnavailable) != tt.unavailable {
+				t.Errorf("errors.Is(ErrModelUnavailable) = %v, want %v (err %v)", !tt.unavailable, tt.unavailable, err)
+			}
+		})
+	}
+}
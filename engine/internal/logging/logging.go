@@ -0,0 +1,46 @@
+// Package logging carries the per-call request ID through contexts so log
+// entries written by the server, the assertion pipeline, and LLM or embedding
+// providers for one JSON-RPC call can be correlated.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// RequestIDKey is the slog attribute key for the request ID.
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random request ID of the form "req_<16 hex digits>".
+func NewRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return "req_" + hex.EncodeToString(b[:])
+}
+
+// WithRequestID returns a copy of ctx carrying id. An empty id leaves ctx unchanged.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger, tagged with ctx's request ID when
+// it carries one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With(RequestIDKey, id)
+	}
+	return slog.Default()
+}
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewRequestID_Unique(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if a == b {
+		t.Errorf("two request IDs collided: %s", a)
+	}
+	if len(a) != len("req_")+16 || !strings.HasPrefix(a, "req_") {
+		t.Errorf("request ID %q has unexpected form", a)
+	}
+}
+
+func TestFromContext_TagsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	FromContext(WithRequestID(context.Background(), "req_abc")).Info("hello")
+	FromContext(context.Background()).Info("untagged")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"request_id":"req_abc"`) {
+		t.Errorf("tagged entry = %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("untagged entry = %s", lines[1])
+	}
+	if WithRequestID(context.Background(), "") != context.Background() {
+		t.Error("empty id should leave ctx unchanged")
+	}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// RingWriter is an io.Writer that keeps the last N lines written to it, for
+// inclusion in debug bundles. Pair it with the engine's log handler through
+// io.MultiWriter. It is safe for concurrent use.
+type RingWriter struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int
+	full    bool
+	partial []byte
+}
+
+// NewRingWriter returns a RingWriter holding up to n lines (minimum 1).
+func NewRingWriter(n int) *RingWriter {
+	if n < 1 {
+		n = 1
+	}
+	return &RingWriter{lines: make([]string, n)}
+}
+
+// Write records each complete line in p; a trailing partial line is held
+// until its newline arrives.
+func (r *RingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := p
+	if len(r.partial) > 0 {
+		data = append(r.partial, p...)
+		r.partial = nil
+	}
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		r.push(string(data[:i]))
+		data = data[i+1:]
+	}
+	if len(data) > 0 {
+		r.partial = append([]byte(nil), data...)
+	}
+	return len(p), nil
+}
+
+// ReadFrom records every line read from src.
+func (r *RingWriter) ReadFrom(src io.Reader) (int64, error) {
+	var total int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		total += int64(n)
+		_, _ = r.Write(buf[:n])
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func (r *RingWriter) push(line string) {
+	r.lines[r.next] = line
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Lines returns the retained lines, oldest first.
+func (r *RingWriter) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	out := make([]string, 0, len(r.lines))
+	out = append(out, r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRingWriter_KeepsLastLines(t *testing.T) {
+	r := NewRingWriter(3)
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(r, "line %d\n", i)
+	}
+	// A line split across writes is recorded once complete.
+	r.Write([]byte("line "))
+	if got, want := r.Lines(), []string{"line 3", "line 4", "line 5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %v, want %v", got, want)
+	}
+	r.Write([]byte("6\n"))
+	if got, want := r.Lines(), []string{"line 4", "line 5", "line 6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %v, want %v", got, want)
+	}
+
+	tail := NewRingWriter(2)
+	if _, err := tail.ReadFrom(strings.NewReader("a\nb\nc\n")); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if got, want := tail.Lines(), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tail Lines = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+// Package paths resolves where the engine keeps its files on each OS.
+//
+//	           cache                        models                        config
+//	Linux      $XDG_CACHE_HOME/attest/cache $XDG_CACHE_HOME/attest/models $XDG_CONFIG_HOME/attest
+//	macOS      ~/Library/Caches/attest/...  ~/Library/Caches/attest/...   ~/Library/Application Support/attest
+//	Windows    %LOCALAPPDATA%\attest\cache  %LOCALAPPDATA%\attest\models  %APPDATA%\attest
+//
+// ATTEST_CACHE_DIR, ATTEST_ONNX_MODEL_DIR, and ATTEST_CONFIG_DIR override
+// them. Earlier releases kept everything under ~/.attest; until Migrate moves
+// those directories, they are still used.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Dirs is one set of engine directories.
+type Dirs struct {
+	Cache  string
+	Models string
+	Config string
+}
+
+// Defaults returns the engine directories for the running OS, ignoring
+// overrides and legacy directories.
+func Defaults() Dirs {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return defaults(runtime.GOOS, home, os.Getenv)
+}
+
+func defaults(goos, home string, getenv func(string) string) Dirs {
+	envOr := func(key, fallback string) string {
+		// XDG and Windows variables must be absolute to be honored.
+		if v := getenv(key); v != "" && (filepath.IsAbs(v) || isWindowsAbs(v)) {
+			return v
+		}
+		return fallback
+	}
+	var cacheBase, configDir string
+	switch goos {
+	case "windows":
+		cacheBase = filepath.Join(envOr("LOCALAPPDATA", filepath.Join(home, "AppData", "Local")), "attest")
+		configDir = filepath.Join(envOr("APPDATA", filepath.Join(home, "AppData", "Roaming")), "attest")
+	case "darwin":
+		cacheBase = filepath.Join(home, "Library", "Caches", "attest")
+		configDir = filepath.Join(home, "Library", "Application Support", "attest")
+	default:
+		cacheBase = filepath.Join(envOr("XDG_CACHE_HOME", filepath.Join(home, ".cache")), "attest")
+		configDir = filepath.Join(envOr("XDG_CONFIG_HOME", filepath.Join(home, ".config")), "attest")
+	}
+	return Dirs{
+		Cache:  filepath.Join(cacheBase, "cache"),
+		Models: filepath.Join(cacheBase, "models"),
+		Config: configDir,
+	}
+}
+
+// isWindowsAbs reports whether p starts with a drive letter, so tests of the
+// Windows layout pass on other OSes.
+func isWindowsAbs(p string) bool {
+	return len(p) >= 3 && p[1] == ':' && (p[2] == '\\' || p[2] == '/')
+}
+
+// Legacy returns the pre-0.6 directories under ~/.attest. Config files had
+// no default location then, so Config is empty.
+func Legacy() Dirs {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	root := filepath.Join(home, ".attest")
+	return Dirs{
+		Cache:  filepath.Join(root, "cache"),
+		Models: filepath.Join(root, "models"),
+	}
+}
+
+// CacheDir returns the cache directory.
+func CacheDir() string {
+	return resolve("ATTEST_CACHE_DIR", Defaults().Cache, Legacy().Cache)
+}
+
+// ModelDir returns the ONNX model directory.
+func ModelDir() string {
+	return resolve("ATTEST_ONNX_MODEL_DIR", Defaults().Models, Legacy().Models)
+}
+
+// ConfigDir returns the directory searched for config files such as
+// rubrics.json and templates.json.
+func ConfigDir() string {
+	return resolve("ATTEST_CONFIG_DIR", Defaults().Config, Legacy().Config)
+}
+
+// ConfigFile returns the path of name in ConfigDir, or "" if it does not exist.
+func ConfigFile(name string) string {
+	path := filepath.Join(ConfigDir(), name)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// resolve returns the override in env, else dir, unless dir does not exist
+// yet and the unmigrated legacy directory does.
+func resolve(env, dir, legacy string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	if legacy != "" && !exists(dir) && exists(legacy) {
+		return legacy
+	}
+	return dir
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Move is one directory Migrate moved.
+type Move struct {
+	From string
+	To   string
+}
+
+// Migrate moves the legacy cache and model directories to their per-OS
+// locations, skipping any with an override set or whose destination already
+// exists. A directory that cannot be moved, e.g. across filesystems, stays
+// in use where it is.
+func Migrate() ([]Move, error) {
+	defs, legacy := Defaults(), Legacy()
+	var moves []Move
+	var errs []error
+	for _, m := range []struct{ env, from, to string }{
+		{"ATTEST_CACHE_DIR", legacy.Cache, defs.Cache},
+		{"ATTEST_ONNX_MODEL_DIR", legacy.Models, defs.Models},
+	} {
+		if os.Getenv(m.env) != "" || !exists(m.from) || exists(m.to) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(m.to), 0o755); err != nil {
+			errs = append(errs, fmt.Errorf("migrate %s: %w", m.from, err))
+			continue
+		}
+		if err := os.Rename(m.from, m.to); err != nil {
+			errs = append(errs, fmt.Errorf("migrate %s to %s: %w", m.from, m.to, err))
+			continue
+		}
+		moves = append(moves, Move{From: m.from, To: m.to})
+	}
+	return moves, errors.Join(errs...)
+}
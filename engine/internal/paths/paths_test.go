@@ -0,0 +1,129 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDefaults_PerOS(t *testing.T) {
+	env := map[string]string{
+		"LOCALAPPDATA":    `C:\Users\ada\AppData\Local`,
+		"APPDATA":         `C:\Users\ada\AppData\Roaming`,
+		"XDG_CACHE_HOME":  "/xdg/cache",
+		"XDG_CONFIG_HOME": "relative/config",
+	}
+	getenv := func(k string) string { return env[k] }
+
+	linux := defaults("linux", "/home/ada", getenv)
+	if want := filepath.Join("/xdg/cache", "attest", "cache"); linux.Cache != want {
+		t.Errorf("linux cache = %q, want %q", linux.Cache, want)
+	}
+	if want := filepath.Join("/xdg/cache", "attest", "models"); linux.Models != want {
+		t.Errorf("linux models = %q, want %q", linux.Models, want)
+	}
+	// A relative XDG path is ignored, as the spec requires.
+	if want := filepath.Join("/home/ada", ".config", "attest"); linux.Config != want {
+		t.Errorf("linux config = %q, want %q", linux.Config, want)
+	}
+
+	windows := defaults("windows", `C:\Users\ada`, getenv)
+	if want := filepath.Join(env["LOCALAPPDATA"], "attest", "cache"); windows.Cache != want {
+		t.Errorf("windows cache = %q, want %q", windows.Cache, want)
+	}
+	if want := filepath.Join(env["APPDATA"], "attest"); windows.Config != want {
+		t.Errorf("windows config = %q, want %q", windows.Config, want)
+	}
+
+	darwin := defaults("darwin", "/Users/ada", getenv)
+	if want := filepath.Join("/Users/ada", "Library", "Caches", "attest", "cache"); darwin.Cache != want {
+		t.Errorf("darwin cache = %q, want %q", darwin.Cache, want)
+	}
+}
+
+// fakeHome points the home and XDG directories at a temp dir.
+func fakeHome(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses HOME and XDG_CACHE_HOME")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "xdg-cache"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("ATTEST_CACHE_DIR", "")
+	t.Setenv("ATTEST_ONNX_MODEL_DIR", "")
+	t.Setenv("ATTEST_CONFIG_DIR", "")
+	return home
+}
+
+func TestCacheDir_LegacyUntilMigrated(t *testing.T) {
+	home := fakeHome(t)
+	legacy := filepath.Join(home, ".attest", "cache")
+	if err := os.MkdirAll(legacy, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "attest.db"), []byte("db"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := CacheDir(); got != legacy {
+		t.Fatalf("before migration CacheDir = %q, want legacy %q", got, legacy)
+	}
+
+	moves, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	want := Defaults().Cache
+	if len(moves) != 1 || moves[0].From != legacy || moves[0].To != want {
+		t.Fatalf("moves = %+v", moves)
+	}
+	if got := CacheDir(); got != want {
+		t.Errorf("after migration CacheDir = %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(want, "attest.db")); err != nil || string(data) != "db" {
+		t.Errorf("migrated db = %q, %v", data, err)
+	}
+
+	// Nothing left to move.
+	if moves, err := Migrate(); err != nil || len(moves) != 0 {
+		t.Errorf("second Migrate = %+v, %v", moves, err)
+	}
+}
+
+func TestMigrate_SkipsOverrides(t *testing.T) {
+	home := fakeHome(t)
+	legacy := filepath.Join(home, ".attest", "models")
+	if err := os.MkdirAll(legacy, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	custom := filepath.Join(home, "models")
+	t.Setenv("ATTEST_ONNX_MODEL_DIR", custom)
+
+	moves, err := Migrate()
+	if err != nil || len(moves) != 0 {
+		t.Fatalf("Migrate = %+v, %v", moves, err)
+	}
+	if got := ModelDir(); got != custom {
+		t.Errorf("ModelDir = %q, want %q", got, custom)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	fakeHome(t)
+	if got := ConfigFile("rubrics.json"); got != "" {
+		t.Fatalf("ConfigFile with no file = %q", got)
+	}
+	dir := ConfigDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "rubrics.json")
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := ConfigFile("rubrics.json"); got != path {
+		t.Errorf("ConfigFile = %q, want %q", got, path)
+	}
+}
@@ -0,0 +1,181 @@
+package report
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// AnnotatedResult is an assertion result located in a file for CI
+// annotations. Line is 1-based; 0 annotates the file as a whole.
+type AnnotatedResult struct {
+	Result  types.AssertionResult
+	TraceID string
+	File    string
+	Line    int
+}
+
+// LoadAnnotatedResults reads results to annotate from a JSON report
+// ({"results": [...]}), a JSON array of results, or evaluate_dataset sink
+// lines, locating each in file. Sink results are placed on their trace's
+// dataset line, and a sink line that could not be evaluated becomes a hard
+// failure.
+func LoadAnnotatedResults(data []byte, file string) ([]AnnotatedResult, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var results []types.AssertionResult
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("parse results array: %w", err)
+		}
+		return locate(results, "", file, 0), nil
+	}
+
+	// A JSON report and sink lines are both a sequence of objects; only
+	// sink lines carry a line number.
+	var out []AnnotatedResult
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for n := 1; ; n++ {
+		var rec types.DatasetResultLine
+		if err := dec.Decode(&rec); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		if rec.Error != "" {
+			out = append(out, AnnotatedResult{
+				Result:  types.AssertionResult{AssertionID: "invalid_trace", Status: types.StatusHardFail, Explanation: rec.Error},
+				TraceID: rec.TraceID,
+				File:    file,
+				Line:    rec.Line,
+			})
+			continue
+		}
+		out = append(out, locate(rec.Results, rec.TraceID, file, rec.Line)...)
+	}
+}
+
+func locate(results []types.AssertionResult, traceID, file string, line int) []AnnotatedResult {
+	out := make([]AnnotatedResult, len(results))
+	for i, r := range results {
+		out[i] = AnnotatedResult{Result: r, TraceID: traceID, File: file, Line: line}
+	}
+	return out
+}
+
+// annotationLevel returns the GitHub annotation level for a status, or ""
+// when the result needs no annotation.
+func annotationLevel(status string) string {
+	switch status {
+	case types.StatusHardFail:
+		return "error"
+	case types.StatusSoftFail:
+		return "warning"
+	}
+	return ""
+}
+
+// annotationMessage describes a failed result for an annotation.
+func annotationMessage(r AnnotatedResult) string {
+	msg := fmt.Sprintf("%s: %s", r.Result.Status, r.Result.Explanation)
+	if r.TraceID != "" {
+		msg = fmt.Sprintf("%s (trace %s)", msg, r.TraceID)
+	}
+	return msg
+}
+
+// GenerateGitHubAnnotations writes a GitHub Actions workflow command for
+// each failed result: ::error for hard failures and ::warning for soft ones.
+func GenerateGitHubAnnotations(w io.Writer, results []AnnotatedResult) error {
+	for _, r := range results {
+		level := annotationLevel(r.Result.Status)
+		if level == "" {
+			continue
+		}
+		var props []string
+		if r.File != "" {
+			props = append(props, "file="+escapeGitHubProperty(r.File))
+			if r.Line > 0 {
+				props = append(props, fmt.Sprintf("line=%d", r.Line))
+			}
+		}
+		props = append(props, "title="+escapeGitHubProperty("attest: "+r.Result.AssertionID))
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", level, strings.Join(props, ","), escapeGitHubData(annotationMessage(r))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeGitHubData(s string) string     { return githubDataEscaper.Replace(s) }
+func escapeGitHubProperty(s string) string { return githubPropertyEscaper.Replace(s) }
+
+// CodeQualityIssue is one entry of a GitLab code quality report.
+type CodeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    CodeQualityLocation `json:"location"`
+}
+
+// CodeQualityLocation places a code quality issue in a file.
+type CodeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines CodeQualityLines `json:"lines"`
+}
+
+// CodeQualityLines is the line range of a code quality issue.
+type CodeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// GenerateGitLabCodeQuality generates a GitLab code quality report with an
+// issue for each failed result: critical for hard failures and minor for
+// soft ones. GitLab requires a location, so results without a file are
+// placed on line 1 of defaultPath.
+func GenerateGitLabCodeQuality(results []AnnotatedResult, defaultPath string) ([]byte, error) {
+	issues := []CodeQualityIssue{}
+	for _, r := range results {
+		severity := ""
+		switch r.Result.Status {
+		case types.StatusHardFail:
+			severity = "critical"
+		case types.StatusSoftFail:
+			severity = "minor"
+		default:
+			continue
+		}
+		path, line := r.File, r.Line
+		if path == "" {
+			path = defaultPath
+		}
+		if line <= 0 {
+			line = 1
+		}
+		sum := sha256.Sum256([]byte(strings.Join([]string{r.Result.AssertionID, r.TraceID, path, fmt.Sprint(line)}, "\x00")))
+		issues = append(issues, CodeQualityIssue{
+			Description: annotationMessage(r),
+			CheckName:   "attest/" + r.Result.AssertionID,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    severity,
+			Location:    CodeQualityLocation{Path: path, Lines: CodeQualityLines{Begin: line}},
+		})
+	}
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal code quality report: %w", err)
+	}
+	return data, nil
+}
@@ -22,6 +22,7 @@ type JSONSummary struct {
 	Passed   int `json:"passed"`
 	SoftFail int `json:"soft_fail"`
 	HardFail int `json:"hard_fail"`
+	Skipped  int `json:"skipped,omitempty"`
 }
 
 // GenerateJSONReport generates a structured JSON report from assertion results.
@@ -41,6 +42,8 @@ func GenerateJSONReport(results []types.AssertionResult, totalCost float64, tota
 			summary.SoftFail++
 		case types.StatusHardFail:
 			summary.HardFail++
+		case types.StatusSkipped:
+			summary.Skipped++
 		}
 	}
 
@@ -17,6 +17,7 @@ type JUnitTestSuite struct {
 	Name     string          `xml:"name,attr"`
 	Tests    int             `xml:"tests,attr"`
 	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr,omitempty"`
 	Errors   int             `xml:"errors,attr"`
 	Time     string          `xml:"time,attr"`
 	Cases    []JUnitTestCase `xml:"testcase"`
@@ -27,6 +28,7 @@ type JUnitTestCase struct {
 	ClassName string        `xml:"classname,attr"`
 	Time      string        `xml:"time,attr"`
 	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
 	SystemOut string        `xml:"system-out,omitempty"`
 }
 
@@ -36,9 +38,13 @@ type JUnitFailure struct {
 	Content string `xml:",chardata"`
 }
 
+type JUnitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
 // GenerateJUnitXML generates a JUnit XML report from assertion results.
 func GenerateJUnitXML(results []types.AssertionResult, totalDurationMS int64) ([]byte, error) {
-	var failures int
+	var failures, skipped int
 	var cases []JUnitTestCase
 
 	for _, result := range results {
@@ -59,6 +65,9 @@ func GenerateJUnitXML(results []types.AssertionResult, totalDurationMS int64) ([
 				Type:    failureType,
 				Content: result.Status,
 			}
+		} else if result.Status == types.StatusSkipped {
+			skipped++
+			testCase.Skipped = &JUnitSkipped{Message: result.Explanation}
 		} else if result.Status == types.StatusPass {
 			testCase.SystemOut = result.Explanation
 		}
@@ -70,6 +79,7 @@ func GenerateJUnitXML(results []types.AssertionResult, totalDurationMS int64) ([
 		Name:     "attest",
 		Tests:    len(results),
 		Failures: failures,
+		Skipped:  skipped,
 		Errors:   0,
 		Time:     formatDuration(totalDurationMS),
 		Cases:    cases,
@@ -103,6 +103,8 @@ func statusIcon(status string) string {
 		return ":warning:"
 	case types.StatusHardFail:
 		return ":x:"
+	case types.StatusSkipped:
+		return ":fast_forward:"
 	default:
 		return ":grey_question:"
 	}
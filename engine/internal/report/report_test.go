@@ -6,7 +6,9 @@ import (
 	"encoding/xml"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
@@ -510,3 +512,264 @@ func compareWithGoldenJSON(t *testing.T, actual []byte, goldenPath string) {
 			goldenPath, string(goldenNorm), string(actualNorm))
 	}
 }
+
+func TestBuildScorecard(t *testing.T) {
+	to := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	current := []ScorecardStats{
+		{AssertionID: "tone", AssertionType: "llm_judge", Runs: 10, PassRate: 0.6, MeanScore: 0.62, Cost: 0.05},
+		{AssertionID: "cost", AssertionType: "constraint", Runs: 10, PassRate: 1, MeanScore: 1},
+		{AssertionID: "format", AssertionType: "schema", Runs: 4, PassRate: 1, MeanScore: 1},
+		{AssertionID: "facts", AssertionType: "llm_judge", Runs: 8, PassRate: 0.9, MeanScore: 0.91, Cost: 0.04},
+	}
+	previous := []ScorecardStats{
+		{AssertionID: "tone", Runs: 12, PassRate: 0.9, MeanScore: 0.88},
+		{AssertionID: "cost", Runs: 9, PassRate: 1, MeanScore: 0.99},
+		{AssertionID: "facts", Runs: 5, PassRate: 0.6, MeanScore: 0.7},
+		{AssertionID: "retired", Runs: 3, PassRate: 1, MeanScore: 1},
+	}
+	sc := BuildScorecard(current, previous, []string{"tone"}, "7d", to.Add(-7*24*time.Hour), to)
+
+	wantTrends := map[string]string{"cost": TrendFlat, "facts": TrendUp, "format": TrendNew, "tone": TrendDown}
+	if len(sc.Assertions) != len(wantTrends) {
+		t.Fatalf("got %d entries, want %d", len(sc.Assertions), len(wantTrends))
+	}
+	for i, e := range sc.Assertions {
+		if i > 0 && sc.Assertions[i-1].AssertionID > e.AssertionID {
+			t.Errorf("entries not sorted: %s before %s", sc.Assertions[i-1].AssertionID, e.AssertionID)
+		}
+		if e.Trend != wantTrends[e.AssertionID] {
+			t.Errorf("%s trend = %s, want %s", e.AssertionID, e.Trend, wantTrends[e.AssertionID])
+		}
+		if (e.PreviousMeanScore == nil) != (e.Trend == TrendNew) {
+			t.Errorf("%s previous mean score = %v with trend %s", e.AssertionID, e.PreviousMeanScore, e.Trend)
+		}
+	}
+	want := ScorecardSummary{Assertions: 4, Runs: 32, Improved: 1, Regressed: 1, TotalCost: 0.09}
+	if got := sc.Summary; got.Assertions != want.Assertions || got.Runs != want.Runs || got.Improved != want.Improved ||
+		got.Regressed != want.Regressed || got.TotalCost < 0.0899 || got.TotalCost > 0.0901 {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+	if sc.From != "2026-03-01T00:00:00Z" || sc.To != "2026-03-08T00:00:00Z" {
+		t.Errorf("window = %s to %s", sc.From, sc.To)
+	}
+
+	data, err := GenerateScorecardJSON(sc)
+	if err != nil {
+		t.Fatalf("GenerateScorecardJSON: %v", err)
+	}
+	var decoded Scorecard
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode scorecard: %v", err)
+	}
+	if decoded.Window != "7d" || len(decoded.Assertions) != 4 || decoded.Assertions[3].PreviousPassRate == nil || *decoded.Assertions[3].PreviousPassRate != 0.9 {
+		t.Errorf("decoded scorecard = %+v", decoded)
+	}
+
+	var md bytes.Buffer
+	if err := GenerateScorecardMarkdown(&md, sc); err != nil {
+		t.Fatalf("GenerateScorecardMarkdown: %v", err)
+	}
+	for _, want := range []string{
+		"## Attest Scorecard (last 7d)",
+		"**Assertions:** 4 — 32 runs, 1 improved, 1 regressed",
+		"| `tone` (quarantined) | llm_judge | 10 | 60.0% | 0.620 | ↓ from 0.880 | $0.050000 |",
+		"| `format` | schema | 4 | 100.0% | 1.000 | new | $0.000000 |",
+		"| `facts` | llm_judge | 8 | 90.0% | 0.910 | ↑ from 0.700 |",
+		"| `cost` | constraint | 10 | 100.0% | 1.000 | → from 0.990 |",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+}
+
+func TestGenerateScorecardMarkdown_Empty(t *testing.T) {
+	var md bytes.Buffer
+	sc := BuildScorecard(nil, nil, nil, "24h", time.Now().Add(-24*time.Hour), time.Now())
+	if err := GenerateScorecardMarkdown(&md, sc); err != nil {
+		t.Fatalf("GenerateScorecardMarkdown: %v", err)
+	}
+	if !strings.Contains(md.String(), "_No assertion history in this window._") {
+		t.Errorf("markdown = %q", md.String())
+	}
+}
+
+func TestLoadAnnotatedResults(t *testing.T) {
+	results := []types.AssertionResult{
+		{AssertionID: "schema", Status: types.StatusPass},
+		{AssertionID: "tone", Status: types.StatusHardFail, Explanation: "too curt"},
+	}
+	array, _ := json.Marshal(results)
+	jsonReport, _ := GenerateJSONReport(results, 0, 0)
+	sink := `{"line":3,"trace_id":"trc_a","results":[{"assertion_id":"tone","status":"soft_fail","explanation":"meh"}],"total_cost":0}
+{"line":4,"error":"trace_id is required","total_cost":0}
+`
+	tests := []struct {
+		name      string
+		data      string
+		wantCount int
+		wantLast  AnnotatedResult
+	}{
+		{"array", string(array), 2, AnnotatedResult{Result: results[1], File: "suite.json"}},
+		{"json report", string(jsonReport), 2, AnnotatedResult{Result: results[1], File: "suite.json"}},
+		{"sink", sink, 2, AnnotatedResult{
+			Result: types.AssertionResult{AssertionID: "invalid_trace", Status: types.StatusHardFail, Explanation: "trace_id is required"},
+			File:   "suite.json", Line: 4,
+		}},
+		{"empty", "", 0, AnnotatedResult{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadAnnotatedResults([]byte(tt.data), "suite.json")
+			if err != nil {
+				t.Fatalf("LoadAnnotatedResults: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("got %d results, want %d", len(got), tt.wantCount)
+			}
+			if tt.wantCount > 0 {
+				last := got[len(got)-1]
+				if last.Result.AssertionID != tt.wantLast.Result.AssertionID || last.Result.Status != tt.wantLast.Result.Status ||
+					last.File != tt.wantLast.File || last.Line != tt.wantLast.Line {
+					t.Errorf("last = %+v, want %+v", last, tt.wantLast)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadAnnotatedResults([]byte(`{"line":1}`+"\nnot json"), "f"); err == nil {
+		t.Error("want error for a malformed record")
+	}
+}
+
+func TestGenerateGitHubAnnotations(t *testing.T) {
+	results := []AnnotatedResult{
+		{Result: types.AssertionResult{AssertionID: "schema", Status: types.StatusPass}},
+		{Result: types.AssertionResult{AssertionID: "tone", Status: types.StatusHardFail, Explanation: "50% off:\nrude"}, TraceID: "trc_a", File: "data/set,1.jsonl", Line: 7},
+		{Result: types.AssertionResult{AssertionID: "cost", Status: types.StatusSoftFail, Explanation: "over budget"}},
+	}
+	var buf bytes.Buffer
+	if err := GenerateGitHubAnnotations(&buf, results); err != nil {
+		t.Fatalf("GenerateGitHubAnnotations: %v", err)
+	}
+	want := "::error file=data/set%2C1.jsonl,line=7,title=attest%3A tone::hard_fail: 50%25 off:%0Arude (trace trc_a)\n" +
+		"::warning title=attest%3A cost::soft_fail: over budget\n"
+	if buf.String() != want {
+		t.Errorf("annotations =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestGenerateGitLabCodeQuality(t *testing.T) {
+	results := []AnnotatedResult{
+		{Result: types.AssertionResult{AssertionID: "schema", Status: types.StatusPass}},
+		{Result: types.AssertionResult{AssertionID: "tone", Status: types.StatusHardFail, Explanation: "rude"}, TraceID: "trc_a", File: "data.jsonl", Line: 7},
+		{Result: types.AssertionResult{AssertionID: "tone", Status: types.StatusSoftFail, Explanation: "curt"}, TraceID: "trc_b", File: "data.jsonl", Line: 8},
+		{Result: types.AssertionResult{AssertionID: "cost", Status: types.StatusSoftFail, Explanation: "over budget"}},
+	}
+	data, err := GenerateGitLabCodeQuality(results, "suite.json")
+	if err != nil {
+		t.Fatalf("GenerateGitLabCodeQuality: %v", err)
+	}
+	var issues []CodeQualityIssue
+	if err := json.Unmarshal(data, &issues); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(issues) != 3 {
+		t.Fatalf("got %d issues, want 3", len(issues))
+	}
+	first := issues[0]
+	if first.Severity != "critical" || first.CheckName != "attest/tone" || first.Location.Path != "data.jsonl" ||
+		first.Location.Lines.Begin != 7 || first.Description != "hard_fail: rude (trace trc_a)" {
+		t.Errorf("first issue = %+v", first)
+	}
+	if issues[2].Severity != "minor" || issues[2].Location.Path != "suite.json" || issues[2].Location.Lines.Begin != 1 {
+		t.Errorf("last issue = %+v", issues[2])
+	}
+	if first.Fingerprint == issues[1].Fingerprint {
+		t.Error("fingerprints of different traces collide")
+	}
+
+	empty, _ := GenerateGitLabCodeQuality(nil, "x")
+	if string(empty) != "[]" {
+		t.Errorf("empty report = %s, want []", empty)
+	}
+}
+
+func TestBuildRunSummary(t *testing.T) {
+	results := []AnnotatedResult{
+		{Result: types.AssertionResult{AssertionID: "schema", Status: types.StatusPass, Cost: 0.01}, TraceID: "t1"},
+		{Result: types.AssertionResult{AssertionID: "schema", Status: types.StatusHardFail, Explanation: "missing field"}, TraceID: "t2"},
+		{Result: types.AssertionResult{AssertionID: "judge", Status: types.StatusSoftFail, Explanation: "weak answer"}, TraceID: "t3"},
+		{Result: types.AssertionResult{AssertionID: "judge", Status: types.StatusPass}, TraceID: "t4"},
+		{Result: types.AssertionResult{AssertionID: "skipped", Status: types.StatusSkipped}, TraceID: "t5"},
+	}
+	s := BuildRunSummary(results, SummaryOptions{
+		Baseline:     map[string]float64{"schema": 1.0, "judge": 0.52},
+		LinkTemplate: "https://ci.example.com/traces/{trace_id}",
+	})
+
+	if s.Total != 4 || s.Passed != 2 || s.HardFail != 1 || s.SoftFail != 1 || s.PassRate != 0.5 || s.TotalCost != 0.01 {
+		t.Errorf("summary counts = %+v", s)
+	}
+	// judge dropped by 0.02, under the threshold; schema by 0.5.
+	if len(s.Regressions) != 1 || s.Regressions[0].AssertionID != "schema" {
+		t.Errorf("regressions = %+v, want schema only", s.Regressions)
+	}
+	if len(s.TopFailures) != 2 || s.TopFailures[0].Status != types.StatusHardFail {
+		t.Fatalf("top failures = %+v, want the hard failure first", s.TopFailures)
+	}
+	if got := s.TopFailures[0].Link; got != "https://ci.example.com/traces/t2" {
+		t.Errorf("link = %q", got)
+	}
+
+	text, err := RenderSummary(DefaultSlackTemplate, s)
+	if err != nil {
+		t.Fatalf("RenderSummary: %v", err)
+	}
+	for _, want := range []string{"50.0% passed (2/4", "`schema` 100.0% → 50.0%", "<https://ci.example.com/traces/t2|t2>: missing field"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("slack text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestRenderSummary_EscapesSlack(t *testing.T) {
+	results := []AnnotatedResult{{
+		Result:  types.AssertionResult{AssertionID: "a<b>", Status: types.StatusHardFail, Explanation: "<!channel> see <https://evil.example|docs> & more"},
+		TraceID: "t 1/2?x&y",
+	}}
+	s := BuildRunSummary(results, SummaryOptions{LinkTemplate: "https://ci.example.com/traces/{trace_id}?assertion={assertion_id}"})
+	if got, want := s.TopFailures[0].Link, "https://ci.example.com/traces/t%201%2F2%3Fx&y?assertion=a%3Cb%3E"; got != want {
+		t.Errorf("link = %q, want %q", got, want)
+	}
+
+	text, err := RenderSummary(DefaultSlackTemplate, s)
+	if err != nil {
+		t.Fatalf("RenderSummary: %v", err)
+	}
+	if strings.Contains(text, "<!") || strings.Contains(text, "<https://evil") {
+		t.Errorf("slack text not escaped:\n%s", text)
+	}
+	for _, want := range []string{"`a&lt;b&gt;`", "&lt;!channel&gt; see &lt;https://evil.example|docs&gt; &amp; more", "|t 1/2?x&amp;y>"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("slack text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestBuildRunSummary_NoBaseline(t *testing.T) {
+	s := BuildRunSummary(nil, SummaryOptions{MaxFailures: 1})
+	if s.HasBaseline || s.Total != 0 {
+		t.Errorf("summary = %+v", s)
+	}
+	text, err := RenderSummary(DefaultSlackTemplate, s)
+	if err != nil {
+		t.Fatalf("RenderSummary: %v", err)
+	}
+	if strings.Contains(text, "baseline") {
+		t.Errorf("text mentions a baseline without one:\n%s", text)
+	}
+	if _, err := RenderSummary("{{.Missing}}", s); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,190 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// Trend directions of a scorecard entry against the previous window.
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+	// TrendNew marks an assertion with no runs in the previous window.
+	TrendNew = "new"
+)
+
+// TrendThreshold is the change in mean score below which a trend is flat.
+const TrendThreshold = 0.02
+
+// ScorecardStats is one assertion's aggregated history in a window.
+type ScorecardStats struct {
+	AssertionID   string
+	AssertionType string
+	Runs          int
+	PassRate      float64
+	MeanScore     float64
+	Cost          float64
+}
+
+// Scorecard summarizes each assertion's results across the runs in a window.
+type Scorecard struct {
+	Version     string           `json:"version"`
+	GeneratedAt string           `json:"generated_at"`
+	Window      string           `json:"window"`
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Summary     ScorecardSummary `json:"summary"`
+	Assertions  []ScorecardEntry `json:"assertions"`
+}
+
+// ScorecardSummary totals a scorecard.
+type ScorecardSummary struct {
+	Assertions int     `json:"assertions"`
+	Runs       int     `json:"runs"`
+	Improved   int     `json:"improved"`
+	Regressed  int     `json:"regressed"`
+	TotalCost  float64 `json:"total_cost"`
+}
+
+// ScorecardEntry is one assertion's row. The Previous fields are from the
+// window of equal length before this one and are unset when it had no runs.
+type ScorecardEntry struct {
+	AssertionID       string   `json:"assertion_id"`
+	AssertionType     string   `json:"assertion_type"`
+	Runs              int      `json:"runs"`
+	PassRate          float64  `json:"pass_rate"`
+	MeanScore         float64  `json:"mean_score"`
+	Cost              float64  `json:"cost"`
+	PreviousPassRate  *float64 `json:"previous_pass_rate,omitempty"`
+	PreviousMeanScore *float64 `json:"previous_mean_score,omitempty"`
+	Trend             string   `json:"trend"`
+	Quarantined       bool     `json:"quarantined,omitempty"`
+}
+
+// BuildScorecard builds a scorecard from the stats of the window [from, to)
+// and of the window before it. window labels the window, e.g. "7d".
+// Assertions with no runs in the current window are left out.
+func BuildScorecard(current, previous []ScorecardStats, quarantined []string, window string, from, to time.Time) *Scorecard {
+	prev := make(map[string]ScorecardStats, len(previous))
+	for _, s := range previous {
+		prev[s.AssertionID] = s
+	}
+	quarantine := make(map[string]bool, len(quarantined))
+	for _, id := range quarantined {
+		quarantine[id] = true
+	}
+
+	sc := &Scorecard{
+		Version:     "1.0",
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Window:      window,
+		From:        from.UTC().Format(time.RFC3339),
+		To:          to.UTC().Format(time.RFC3339),
+		Assertions:  []ScorecardEntry{},
+	}
+	for _, s := range current {
+		e := ScorecardEntry{
+			AssertionID:   s.AssertionID,
+			AssertionType: s.AssertionType,
+			Runs:          s.Runs,
+			PassRate:      s.PassRate,
+			MeanScore:     s.MeanScore,
+			Cost:          s.Cost,
+			Trend:         TrendNew,
+			Quarantined:   quarantine[s.AssertionID],
+		}
+		if p, ok := prev[s.AssertionID]; ok && p.Runs > 0 {
+			e.PreviousPassRate, e.PreviousMeanScore = &p.PassRate, &p.MeanScore
+			switch delta := s.MeanScore - p.MeanScore; {
+			case delta >= TrendThreshold:
+				e.Trend = TrendUp
+				sc.Summary.Improved++
+			case delta <= -TrendThreshold:
+				e.Trend = TrendDown
+				sc.Summary.Regressed++
+			default:
+				e.Trend = TrendFlat
+			}
+		}
+		sc.Summary.Runs += s.Runs
+		sc.Summary.TotalCost += s.Cost
+		sc.Assertions = append(sc.Assertions, e)
+	}
+	sort.Slice(sc.Assertions, func(i, j int) bool { return sc.Assertions[i].AssertionID < sc.Assertions[j].AssertionID })
+	sc.Summary.Assertions = len(sc.Assertions)
+	return sc
+}
+
+// GenerateScorecardJSON encodes a scorecard as indented JSON.
+func GenerateScorecardJSON(sc *Scorecard) ([]byte, error) {
+	data, err := json.MarshalIndent(sc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal scorecard: %w", err)
+	}
+	return data, nil
+}
+
+// GenerateScorecardMarkdown writes a scorecard as a Markdown table, for PR
+// comments and dashboards.
+func GenerateScorecardMarkdown(w io.Writer, sc *Scorecard) error {
+	if _, err := fmt.Fprintf(w, "## Attest Scorecard (last %s)\n\n", sc.Window); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "**Window:** %s to %s\n\n", sc.From, sc.To); err != nil {
+		return err
+	}
+	s := sc.Summary
+	if _, err := fmt.Fprintf(w, "**Assertions:** %d — %d runs, %d improved, %d regressed\n\n",
+		s.Assertions, s.Runs, s.Improved, s.Regressed); err != nil {
+		return err
+	}
+	if s.TotalCost > 0 {
+		if _, err := fmt.Fprintf(w, "**Cost:** $%.6f\n\n", s.TotalCost); err != nil {
+			return err
+		}
+	}
+
+	if len(sc.Assertions) == 0 {
+		_, err := fmt.Fprintln(w, "_No assertion history in this window._")
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "| Assertion | Type | Runs | Pass rate | Mean score | Trend | Cost |"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "|-----------|------|------|-----------|------------|-------|------|"); err != nil {
+		return err
+	}
+	for _, e := range sc.Assertions {
+		id := fmt.Sprintf("`%s`", e.AssertionID)
+		if e.Quarantined {
+			id += " (quarantined)"
+		}
+		if _, err := fmt.Fprintf(w, "| %s | %s | %d | %.1f%% | %.3f | %s | $%.6f |\n",
+			id, e.AssertionType, e.Runs, e.PassRate*100, e.MeanScore, trendCell(e), e.Cost); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trendCell renders an entry's trend as an arrow with the previous mean score.
+func trendCell(e ScorecardEntry) string {
+	var arrow string
+	switch e.Trend {
+	case TrendUp:
+		arrow = "↑"
+	case TrendDown:
+		arrow = "↓"
+	case TrendFlat:
+		arrow = "→"
+	default:
+		return "new"
+	}
+	return fmt.Sprintf("%s from %.3f", arrow, *e.PreviousMeanScore)
+}
@@ -0,0 +1,240 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// RegressionThreshold is the drop in pass rate against the baseline at
+// which an assertion counts as regressed.
+const RegressionThreshold = 0.05
+
+// RunSummary summarizes one evaluation run for publishing to chat or a
+// webhook.
+type RunSummary struct {
+	Title     string  `json:"title"`
+	Total     int     `json:"total"`
+	Passed    int     `json:"passed"`
+	SoftFail  int     `json:"soft_fail"`
+	HardFail  int     `json:"hard_fail"`
+	PassRate  float64 `json:"pass_rate"`
+	TotalCost float64 `json:"total_cost"`
+	// Assertions holds each assertion's pass rate in this run, sorted by ID.
+	Assertions []AssertionSummary `json:"assertions"`
+	// Regressions are the assertions whose pass rate fell by at least
+	// RegressionThreshold against the baseline, worst first.
+	Regressions []AssertionSummary `json:"regressions"`
+	// TopFailures are the first failures, hard failures before soft ones.
+	TopFailures []FailureSummary `json:"top_failures"`
+	// HasBaseline is set when a baseline was compared against.
+	HasBaseline bool `json:"has_baseline"`
+}
+
+// AssertionSummary is one assertion's pass rate in a run and, when known,
+// in the baseline.
+type AssertionSummary struct {
+	AssertionID      string   `json:"assertion_id"`
+	Runs             int      `json:"runs"`
+	PassRate         float64  `json:"pass_rate"`
+	BaselinePassRate *float64 `json:"baseline_pass_rate,omitempty"`
+}
+
+// FailureSummary is one failed result, with a link to it when a link
+// template was given.
+type FailureSummary struct {
+	AssertionID string `json:"assertion_id"`
+	TraceID     string `json:"trace_id,omitempty"`
+	Status      string `json:"status"`
+	Explanation string `json:"explanation"`
+	Link        string `json:"link,omitempty"`
+}
+
+// SummaryOptions configures BuildRunSummary.
+type SummaryOptions struct {
+	Title string
+	// Baseline maps assertion IDs to their baseline pass rate; nil skips
+	// the regression comparison.
+	Baseline map[string]float64
+	// LinkTemplate builds failure links; {trace_id} and {assertion_id} are
+	// replaced by the failure's, URL-escaped.
+	LinkTemplate string
+	// MaxFailures bounds TopFailures; 0 means 5.
+	MaxFailures int
+}
+
+// BuildRunSummary summarizes results. Skipped results are not counted.
+func BuildRunSummary(results []AnnotatedResult, opts SummaryOptions) *RunSummary {
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = 5
+	}
+	s := &RunSummary{Title: opts.Title, HasBaseline: opts.Baseline != nil, Assertions: []AssertionSummary{}, Regressions: []AssertionSummary{}, TopFailures: []FailureSummary{}}
+	if s.Title == "" {
+		s.Title = "Attest evaluation run"
+	}
+
+	type tally struct{ runs, passed int }
+	tallies := make(map[string]*tally)
+	var hard, soft []FailureSummary
+	for _, r := range results {
+		res := r.Result
+		if res.Status == types.StatusSkipped {
+			continue
+		}
+		s.Total++
+		s.TotalCost += res.Cost
+		t := tallies[res.AssertionID]
+		if t == nil {
+			t = &tally{}
+			tallies[res.AssertionID] = t
+		}
+		t.runs++
+		f := FailureSummary{AssertionID: res.AssertionID, TraceID: r.TraceID, Status: res.Status, Explanation: res.Explanation, Link: failureLink(opts.LinkTemplate, r)}
+		switch res.Status {
+		case types.StatusPass:
+			s.Passed++
+			t.passed++
+		case types.StatusSoftFail:
+			s.SoftFail++
+			soft = append(soft, f)
+		case types.StatusHardFail:
+			s.HardFail++
+			hard = append(hard, f)
+		}
+	}
+	if s.Total > 0 {
+		s.PassRate = float64(s.Passed) / float64(s.Total)
+	}
+	for _, f := range append(hard, soft...) {
+		if len(s.TopFailures) == opts.MaxFailures {
+			break
+		}
+		s.TopFailures = append(s.TopFailures, f)
+	}
+
+	for id, t := range tallies {
+		a := AssertionSummary{AssertionID: id, Runs: t.runs, PassRate: float64(t.passed) / float64(t.runs)}
+		if base, ok := opts.Baseline[id]; ok {
+			a.BaselinePassRate = &base
+			if base-a.PassRate >= RegressionThreshold {
+				s.Regressions = append(s.Regressions, a)
+			}
+		}
+		s.Assertions = append(s.Assertions, a)
+	}
+	sort.Slice(s.Assertions, func(i, j int) bool { return s.Assertions[i].AssertionID < s.Assertions[j].AssertionID })
+	sort.Slice(s.Regressions, func(i, j int) bool {
+		di := *s.Regressions[i].BaselinePassRate - s.Regressions[i].PassRate
+		dj := *s.Regressions[j].BaselinePassRate - s.Regressions[j].PassRate
+		if di != dj {
+			return di > dj
+		}
+		return s.Regressions[i].AssertionID < s.Regressions[j].AssertionID
+	})
+	return s
+}
+
+// BaselineFromResults returns each assertion's pass rate in results, for
+// comparing a run against an earlier one.
+func BaselineFromResults(results []AnnotatedResult) map[string]float64 {
+	s := BuildRunSummary(results, SummaryOptions{})
+	baseline := make(map[string]float64, len(s.Assertions))
+	for _, a := range s.Assertions {
+		baseline[a.AssertionID] = a.PassRate
+	}
+	return baseline
+}
+
+// BaselineFromScorecard returns each assertion's pass rate in a scorecard.
+func BaselineFromScorecard(sc *Scorecard) map[string]float64 {
+	baseline := make(map[string]float64, len(sc.Assertions))
+	for _, e := range sc.Assertions {
+		baseline[e.AssertionID] = e.PassRate
+	}
+	return baseline
+}
+
+// failureLink fills the {trace_id} and {assertion_id} placeholders of tmpl,
+// path-escaped before the query and query-escaped after it.
+func failureLink(tmpl string, r AnnotatedResult) string {
+	if tmpl == "" {
+		return ""
+	}
+	fill := func(part string, escape func(string) string) string {
+		return strings.NewReplacer(
+			"{trace_id}", escape(r.TraceID),
+			"{assertion_id}", escape(r.Result.AssertionID),
+		).Replace(part)
+	}
+	path, query, ok := strings.Cut(tmpl, "?")
+	link := fill(path, url.PathEscape)
+	if ok {
+		link += "?" + fill(query, url.QueryEscape)
+	}
+	return link
+}
+
+// DefaultSlackTemplate renders a RunSummary as Slack mrkdwn. Every string
+// from the run passes through slack, since agent output reaches the
+// explanations.
+const DefaultSlackTemplate = `*{{slack .Title}}*: {{percent .PassRate}} passed ({{.Passed}}/{{.Total}}, {{.HardFail}} hard fail, {{.SoftFail}} soft fail){{if gt .TotalCost 0.0}}, cost ${{printf "%.4f" .TotalCost}}{{end}}
+{{- if .HasBaseline}}
+{{if .Regressions}}*Regressions vs baseline:*{{range .Regressions}}
+• ` + "`{{slack .AssertionID}}`" + ` {{percent .BaselinePassRate}} → {{percent .PassRate}}{{end}}{{else}}No regressions vs baseline.{{end}}
+{{- end}}
+{{- if .TopFailures}}
+*Top failures:*{{range .TopFailures}}
+• ` + "`{{slack .AssertionID}}`" + `{{if .TraceID}} on {{if .Link}}<{{slack .Link}}|{{slack .TraceID}}>{{else}}{{slack .TraceID}}{{end}}{{else if .Link}} <{{slack .Link}}|details>{{end}}: {{slack (truncate .Explanation 140)}}{{end}}
+{{- end}}`
+
+// slackEscaper escapes the characters Slack mrkdwn gives meaning to, so text
+// cannot open a <!channel> mention or a link.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var summaryFuncs = template.FuncMap{
+	"percent": func(v any) string {
+		switch f := v.(type) {
+		case float64:
+			return fmt.Sprintf("%.1f%%", f*100)
+		case *float64:
+			if f != nil {
+				return fmt.Sprintf("%.1f%%", *f*100)
+			}
+		}
+		return "n/a"
+	},
+	"truncate": func(s string, n int) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n-1]) + "…"
+		}
+		return s
+	},
+	"slack": slackEscaper.Replace,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// RenderSummary executes a text/template against s. Besides the standard
+// functions, templates may use percent (a rate as "92.5%"), truncate (a
+// string to at most n runes), slack (a string escaped for Slack mrkdwn), and
+// json (a value as JSON).
+func RenderSummary(tmpl string, s *RunSummary) (string, error) {
+	t, err := template.New("summary").Funcs(summaryFuncs).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parse summary template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s); err != nil {
+		return "", fmt.Errorf("render summary template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,342 @@
+// Package selfupdate replaces the attest-engine binary with a published
+// release. A release's checksums-sha256.txt must carry a valid Ed25519
+// signature (checksums-sha256.txt.sig) from the release key and name the
+// requested version, and the downloaded binary must match its SHA-256 in that
+// file, before anything on disk is touched. Naming the version in the signed
+// file keeps an older release from being served in place of a newer one.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultReleaseURL is where releases are published. ATTEST_UPDATE_URL
+// overrides it, e.g. with an internal mirror laid out the same way.
+const DefaultReleaseURL = "https://github.com/attest-framework/attest/releases"
+
+// PublicKey is the base64 Ed25519 public key release checksum files are
+// signed with. Release builds set it with
+//
+//	-ldflags "-X github.com/attest-ai/attest/engine/internal/selfupdate.PublicKey=<key>"
+//
+// A build without it cannot update itself.
+var PublicKey string
+
+// maxBinaryBytes bounds a downloaded engine binary.
+const maxBinaryBytes = 512 << 20
+
+const (
+	checksumsFile = "checksums-sha256.txt"
+	signatureFile = checksumsFile + ".sig"
+	// versionLine starts the line of the checksum file naming its release,
+	// as in "# attest-engine v0.6.0". sha256sum -c skips it.
+	versionLine = "# attest-engine v"
+)
+
+// ErrNoPublicKey is returned when the build carries no release signing key.
+var ErrNoPublicKey = errors.New("this build has no release signing key; download the release manually")
+
+// ErrDowngrade is returned by CheckTarget for a version older than the
+// installed one when downgrades were not allowed.
+var ErrDowngrade = errors.New("refusing to downgrade")
+
+// Updater fetches and verifies releases.
+type Updater struct {
+	// ReleaseURL is the release base URL: <ReleaseURL>/latest redirects to
+	// <ReleaseURL>/tag/v<version>, and assets live under
+	// <ReleaseURL>/download/v<version>/.
+	ReleaseURL string
+	PublicKey  ed25519.PublicKey
+	Client     *http.Client
+	GOOS       string
+	GOARCH     string
+}
+
+// New returns an Updater for the running platform using the release URL from
+// ATTEST_UPDATE_URL, or DefaultReleaseURL, and the build's PublicKey.
+func New() (*Updater, error) {
+	if PublicKey == "" {
+		return nil, ErrNoPublicKey
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key in this build")
+	}
+	base := os.Getenv("ATTEST_UPDATE_URL")
+	if base == "" {
+		base = DefaultReleaseURL
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	return &Updater{
+		ReleaseURL: strings.TrimRight(base, "/"),
+		PublicKey:  ed25519.PublicKey(key),
+		Client:     &http.Client{Transport: transport},
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+	}, nil
+}
+
+// AssetName returns the release asset holding the engine for goos/goarch.
+func AssetName(goos, goarch string) string {
+	name := "attest-engine-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Latest returns the newest released version, without the leading "v". The
+// redirect it is read from is not signed, so the caller must not install a
+// version older than the running one on its word; see CheckTarget.
+func (u *Updater) Latest(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.ReleaseURL+"/latest", nil)
+	if err != nil {
+		return "", err
+	}
+	// Read the redirect instead of following it to the HTML release page.
+	client := *u.Client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("check latest release: %w", err)
+	}
+	resp.Body.Close()
+
+	loc := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || loc == "" {
+		return "", fmt.Errorf("check latest release: %s did not redirect to a release (status %d)", req.URL, resp.StatusCode)
+	}
+	tag := path.Base(loc)
+	if !strings.HasPrefix(tag, "v") {
+		return "", fmt.Errorf("check latest release: unexpected release tag %q", tag)
+	}
+	return strings.TrimPrefix(tag, "v"), nil
+}
+
+// Download fetches the engine binary of version for the Updater's platform
+// and verifies it against the release's signed checksum file, which must name
+// version.
+func (u *Updater) Download(ctx context.Context, version string) ([]byte, error) {
+	version = strings.TrimPrefix(version, "v")
+	base := u.ReleaseURL + "/download/v" + version + "/"
+	sums, err := u.get(ctx, base+checksumsFile, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := u.get(ctx, base+signatureFile, 1<<10)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(u.PublicKey, sums, sig); err != nil {
+		return nil, err
+	}
+	switch signed, ok := signedVersion(sums); {
+	case !ok:
+		return nil, fmt.Errorf("%s of v%s does not name its release version", checksumsFile, version)
+	case signed != version:
+		return nil, fmt.Errorf("%s served for v%s is signed for v%s", checksumsFile, version, signed)
+	}
+
+	asset := AssetName(u.GOOS, u.GOARCH)
+	want, ok := parseChecksums(sums)[asset]
+	if !ok {
+		return nil, fmt.Errorf("release v%s has no build for %s/%s", version, u.GOOS, u.GOARCH)
+	}
+	binary, err := u.get(ctx, base+asset, maxBinaryBytes)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("%s: sha256 %s does not match the signed checksum %s", asset, got, want)
+	}
+	return binary, nil
+}
+
+func (u *Updater) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "attest-engine")
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("download %s: larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// verifySignature checks sig, raw or base64, over the checksum file.
+func verifySignature(key ed25519.PublicKey, sums, sig []byte) error {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+		if err != nil {
+			return fmt.Errorf("%s: not an Ed25519 signature", signatureFile)
+		}
+		sig = decoded
+	}
+	if len(sig) != ed25519.SignatureSize || !ed25519.Verify(key, sums, sig) {
+		return fmt.Errorf("%s: signature does not match the release key", checksumsFile)
+	}
+	return nil
+}
+
+// signedVersion returns the version named on the checksum file's version
+// line, without the leading "v".
+func signedVersion(data []byte) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), versionLine); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// CheckTarget compares the installed version with target, returning -1, 0,
+// or +1 as CompareVersions does. A target older than current is ErrDowngrade
+// unless allowDowngrade is set.
+func CheckTarget(current, target string, allowDowngrade bool) (int, error) {
+	cmp, err := CompareVersions(current, target)
+	if err != nil {
+		return 0, err
+	}
+	if cmp > 0 && !allowDowngrade {
+		return cmp, fmt.Errorf("%w from %s to %s", ErrDowngrade, current, target)
+	}
+	return cmp, nil
+}
+
+// parseChecksums reads sha256sum output ("<hash>  <file>" lines) into a map
+// keyed by file name.
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return sums
+}
+
+// Replace atomically swaps the executable at exe for binary. On Windows, where
+// a running executable cannot be overwritten, the old binary is moved aside to
+// exe+".old" first and removed on a best-effort basis.
+func Replace(exe string, binary []byte) error {
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".attest-engine-update-*")
+	if err != nil {
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("replace %s: %w", exe, err)
+		}
+		if err := os.Rename(tmp.Name(), exe); err != nil {
+			os.Rename(old, exe)
+			return fmt.Errorf("replace %s: %w", exe, err)
+		}
+		os.Remove(old)
+		return nil
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// CompareVersions compares dotted numeric versions such as "0.5.0" or
+// "v1.2.3-rc1", returning -1, 0, or +1. A pre-release sorts before its
+// release; pre-release labels are otherwise not ordered.
+func CompareVersions(a, b string) (int, error) {
+	an, apre, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bn, bpre, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < max(len(an), len(bn)); i++ {
+		var x, y int
+		if i < len(an) {
+			x = an[i]
+		}
+		if i < len(bn) {
+			y = bn[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case apre && !bpre:
+		return -1, nil
+	case !apre && bpre:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func parseVersion(v string) ([]int, bool, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	core, _, pre := strings.Cut(s, "-")
+	var parts []int
+	for _, p := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false, fmt.Errorf("invalid version %q", v)
+		}
+		parts = append(parts, n)
+	}
+	return parts, pre, nil
+}
@@ -0,0 +1,174 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// release serves a fake release v0.6.0 for linux/amd64 whose checksum file,
+// signed with priv, lists signed's hash; the asset itself serves served.
+func release(t *testing.T, priv ed25519.PrivateKey, signed, served []byte) *httptest.Server {
+	t.Helper()
+	return releaseSignedAs(t, priv, "# attest-engine v0.6.0\n", signed, served)
+}
+
+// releaseSignedAs is release with header as the checksum file's first line.
+func releaseSignedAs(t *testing.T, priv ed25519.PrivateKey, header string, signed, served []byte) *httptest.Server {
+	t.Helper()
+	sum := sha256.Sum256(signed)
+	sums := header + fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), AssetName("linux", "amd64"))
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(sums)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/releases/tag/v0.6.0", http.StatusFound)
+	})
+	mux.HandleFunc("/releases/download/v0.6.0/", func(w http.ResponseWriter, r *http.Request) {
+		switch filepath.Base(r.URL.Path) {
+		case checksumsFile:
+			w.Write([]byte(sums))
+		case signatureFile:
+			w.Write([]byte(sig + "\n"))
+		case AssetName("linux", "amd64"):
+			w.Write(served)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testUpdater(srv *httptest.Server, pub ed25519.PublicKey, goos string) *Updater {
+	return &Updater{ReleaseURL: srv.URL + "/releases", PublicKey: pub, Client: srv.Client(), GOOS: goos, GOARCH: "amd64"}
+}
+
+func TestUpdater_LatestAndDownload(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	srv := release(t, priv, []byte("new engine"), []byte("new engine"))
+	u := testUpdater(srv, pub, "linux")
+
+	latest, err := u.Latest(context.Background())
+	if err != nil || latest != "0.6.0" {
+		t.Fatalf("Latest = %q, %v", latest, err)
+	}
+	binary, err := u.Download(context.Background(), latest)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if string(binary) != "new engine" {
+		t.Errorf("binary = %q", binary)
+	}
+
+	if _, err := testUpdater(srv, pub, "plan9").Download(context.Background(), latest); err == nil || !strings.Contains(err.Error(), "no build") {
+		t.Errorf("missing platform: err = %v", err)
+	}
+}
+
+func TestUpdater_RejectsBadSignature(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	srv := release(t, priv, []byte("new engine"), []byte("new engine"))
+
+	_, err := testUpdater(srv, otherPub, "linux").Download(context.Background(), "0.6.0")
+	if err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Fatalf("err = %v, want signature error", err)
+	}
+}
+
+func TestUpdater_RejectsTamperedBinary(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	// The asset was swapped after the checksum file was signed.
+	srv := release(t, priv, []byte("new engine"), []byte("evil engine"))
+
+	_, err := testUpdater(srv, pub, "linux").Download(context.Background(), "0.6.0")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("err = %v, want checksum mismatch", err)
+	}
+}
+
+func TestUpdater_RejectsReplayedRelease(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	for header, want := range map[string]string{
+		// An older release's validly signed files served as v0.6.0.
+		"# attest-engine v0.5.0\n": "signed for v0.5.0",
+		"":                         "does not name its release version",
+	} {
+		srv := releaseSignedAs(t, priv, header, []byte("old engine"), []byte("old engine"))
+		_, err := testUpdater(srv, pub, "linux").Download(context.Background(), "v0.6.0")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("header %q: err = %v, want %q", header, err, want)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	if cmp, err := CheckTarget("0.5.0", "0.6.0", false); cmp != -1 || err != nil {
+		t.Errorf("upgrade: %d, %v", cmp, err)
+	}
+	if _, err := CheckTarget("0.6.0", "0.5.0", false); !errors.Is(err, ErrDowngrade) {
+		t.Errorf("downgrade: err = %v, want ErrDowngrade", err)
+	}
+	if cmp, err := CheckTarget("0.6.0", "0.5.0", true); cmp != 1 || err != nil {
+		t.Errorf("allowed downgrade: %d, %v", cmp, err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "attest-engine")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replace(exe, []byte("new")); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("exe = %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.5.0", "0.5.0", 0},
+		{"0.5.0", "0.6.0", -1},
+		{"v1.0.0", "0.9.9", 1},
+		{"0.10.0", "0.9.0", 1},
+		{"1.0", "1.0.0", 0},
+		{"1.0.0-rc1", "1.0.0", -1},
+	}
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, %v; want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	if _, err := CompareVersions("0.5.x", "0.5.0"); err == nil {
+		t.Error("invalid version accepted")
+	}
+}
+
+func TestNew_RequiresPublicKey(t *testing.T) {
+	if _, err := New(); err != ErrNoPublicKey {
+		t.Fatalf("err = %v, want ErrNoPublicKey", err)
+	}
+}
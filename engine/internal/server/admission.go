@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+const (
+	// defaultMaxInFlightTraceBytes bounds the trace bytes evaluated at once
+	// when no memory limit is set.
+	defaultMaxInFlightTraceBytes = 256 << 20
+	// defaultAdmissionTimeout is how long a request waits in the queue
+	// before it fails with a retryable TIMEOUT.
+	defaultAdmissionTimeout = 60 * time.Second
+	// memoryWatermarkPercent of the memory limit stops admitting new work
+	// while other work is still in flight.
+	memoryWatermarkPercent = 80
+)
+
+// admission queues evaluations of large traces so that concurrent batches
+// cannot together exhaust memory. A request is admitted when its trace bytes
+// fit under maxInFlight and memory use is below the watermark; otherwise it
+// waits for earlier requests to finish. A request is always admitted when
+// nothing else is in flight, so no single trace can wait forever.
+type admission struct {
+	mu          sync.Mutex
+	maxInFlight int64
+	watermark   int64 // 0 disables the memory check
+	limit       int64 // 0 when the runtime has no memory limit
+	timeout     time.Duration
+	inFlight    int64
+	wake        chan struct{} // closed and replaced on every release
+	queued      int
+	queuedTotal int64
+	timedOut    int64
+	// usage reports the memory counted against the limit; replaced in tests.
+	usage func() int64
+}
+
+// buildAdmission applies ATTEST_MEMORY_LIMIT_MB as the runtime's soft memory
+// limit and sizes admission from it: by default a quarter of the limit may
+// be in-flight trace bytes (ATTEST_MAX_INFLIGHT_TRACE_BYTES), and new work
+// waits while memory use is above 80% of it. Without a limit, in-flight
+// trace bytes are capped at 256 MB. GOMEMLIMIT is honored when the variable
+// is unset.
+func buildAdmission(logger *slog.Logger) *admission {
+	if mb := envInt("ATTEST_MEMORY_LIMIT_MB", 0); mb > 0 {
+		debug.SetMemoryLimit(int64(mb) << 20)
+		logger.Info("memory limit configured", "limit_mb", mb)
+	}
+	a := &admission{
+		maxInFlight: defaultMaxInFlightTraceBytes,
+		timeout:     time.Duration(envInt("ATTEST_ADMISSION_TIMEOUT_MS", int(defaultAdmissionTimeout/time.Millisecond))) * time.Millisecond,
+		wake:        make(chan struct{}),
+		usage:       memoryInUse,
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		a.limit = limit
+		a.watermark = limit / 100 * memoryWatermarkPercent
+		a.maxInFlight = limit / 4
+	}
+	if n := envInt("ATTEST_MAX_INFLIGHT_TRACE_BYTES", 0); n > 0 {
+		a.maxInFlight = int64(n)
+	}
+	return a
+}
+
+// acquire waits until n trace bytes may be evaluated and returns the func
+// that releases them. It fails with a retryable TIMEOUT when the wait
+// exceeds the admission timeout or ctx ends.
+func (a *admission) acquire(ctx context.Context, n int) (release func(), rpcErr *types.RPCError) {
+	size := int64(n)
+	a.mu.Lock()
+	if a.admits(size) {
+		a.inFlight += size
+		a.mu.Unlock()
+		return a.releaser(size), nil
+	}
+	a.queued++
+	a.queuedTotal++
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+	start := time.Now()
+	for {
+		wake := a.wake
+		a.mu.Unlock()
+		select {
+		case <-wake:
+		case <-timer.C:
+			return nil, a.giveUp(size, time.Since(start))
+		case <-ctx.Done():
+			return nil, a.giveUp(size, time.Since(start))
+		}
+		a.mu.Lock()
+		if a.admits(size) {
+			a.queued--
+			a.inFlight += size
+			a.mu.Unlock()
+			return a.releaser(size), nil
+		}
+	}
+}
+
+// admits reports whether size more bytes fit; a.mu must be held.
+func (a *admission) admits(size int64) bool {
+	if a.inFlight == 0 {
+		return true
+	}
+	if a.inFlight+size > a.maxInFlight {
+		return false
+	}
+	return a.watermark == 0 || a.usage() < a.watermark
+}
+
+func (a *admission) releaser(size int64) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			a.inFlight -= size
+			close(a.wake)
+			a.wake = make(chan struct{})
+			a.mu.Unlock()
+		})
+	}
+}
+
+func (a *admission) giveUp(size int64, waited time.Duration) *types.RPCError {
+	a.mu.Lock()
+	a.queued--
+	a.timedOut++
+	a.mu.Unlock()
+	return types.NewRPCError(
+		types.ErrTimeout,
+		fmt.Sprintf("engine busy: a %d-byte trace waited %s for memory", size, waited.Round(time.Millisecond)),
+		types.ErrTypeTimeout,
+		true,
+		"Retry later, send fewer large batches at once, or raise ATTEST_MEMORY_LIMIT_MB or ATTEST_MAX_INFLIGHT_TRACE_BYTES.",
+	)
+}
+
+// metrics reports memory use and the admission queue.
+func (a *admission) metrics() types.MemoryMetrics {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/heap/objects:bytes"},
+		{Name: "/gc/cycles/total:gc-cycles"},
+	}
+	metrics.Read(samples)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return types.MemoryMetrics{
+		UsedBytes:             a.usage(),
+		HeapBytes:             int64(samples[0].Value.Uint64()),
+		LimitBytes:            a.limit,
+		WatermarkBytes:        a.watermark,
+		GCCycles:              int64(samples[1].Value.Uint64()),
+		InFlightTraceBytes:    a.inFlight,
+		MaxInFlightTraceBytes: a.maxInFlight,
+		Queued:                a.queued,
+		QueuedTotal:           a.queuedTotal,
+		TimedOut:              a.timedOut,
+	}
+}
+
+// memoryInUse returns the memory the Go runtime counts against its memory
+// limit: everything mapped, less heap memory returned to the OS.
+func memoryInUse() int64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	return int64(samples[0].Value.Uint64() - samples[1].Value.Uint64())
+}
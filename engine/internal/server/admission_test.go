@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func newTestAdmission(maxInFlight int64, timeout time.Duration) *admission {
+	return &admission{
+		maxInFlight: maxInFlight,
+		timeout:     timeout,
+		wake:        make(chan struct{}),
+		usage:       func() int64 { return 0 },
+	}
+}
+
+func TestAdmission_QueuesOverCap(t *testing.T) {
+	a := newTestAdmission(100, 5*time.Second)
+	ctx := context.Background()
+
+	release1, rpcErr := a.acquire(ctx, 80)
+	if rpcErr != nil {
+		t.Fatalf("first acquire: %+v", rpcErr)
+	}
+	admitted := make(chan func())
+	go func() {
+		release2, rpcErr := a.acquire(ctx, 50)
+		if rpcErr != nil {
+			t.Errorf("second acquire: %+v", rpcErr)
+		}
+		admitted <- release2
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("second request admitted over the in-flight cap")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if m := a.metrics(); m.Queued != 1 || m.InFlightTraceBytes != 80 {
+		t.Errorf("metrics while queued = %+v, want 1 queued and 80 bytes in flight", m)
+	}
+
+	release1()
+	release1() // releasing twice is harmless
+	select {
+	case release2 := <-admitted:
+		release2()
+	case <-time.After(time.Second):
+		t.Fatal("second request not admitted after release")
+	}
+	if m := a.metrics(); m.Queued != 0 || m.QueuedTotal != 1 || m.InFlightTraceBytes != 0 {
+		t.Errorf("metrics after = %+v", m)
+	}
+}
+
+func TestAdmission_AdmitsOversizedWhenIdle(t *testing.T) {
+	a := newTestAdmission(100, time.Second)
+	release, rpcErr := a.acquire(context.Background(), 1000)
+	if rpcErr != nil {
+		t.Fatalf("acquire: %+v", rpcErr)
+	}
+	release()
+}
+
+func TestAdmission_WatermarkAndTimeout(t *testing.T) {
+	a := newTestAdmission(1000, 50*time.Millisecond)
+	a.watermark = 500
+	used := int64(0)
+	a.usage = func() int64 { return used }
+
+	release, _ := a.acquire(context.Background(), 10)
+	defer release()
+	used = 600
+
+	_, rpcErr := a.acquire(context.Background(), 10)
+	if rpcErr == nil || rpcErr.Code != types.ErrTimeout || rpcErr.Data == nil || !rpcErr.Data.Retryable {
+		t.Fatalf("acquire above watermark = %+v, want retryable TIMEOUT", rpcErr)
+	}
+	if m := a.metrics(); m.TimedOut != 1 || m.Queued != 0 {
+		t.Errorf("metrics = %+v, want 1 timed out", m)
+	}
+
+	used = 100
+	release2, rpcErr := a.acquire(context.Background(), 10)
+	if rpcErr != nil {
+		t.Fatalf("acquire below watermark: %+v", rpcErr)
+	}
+	release2()
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Roles a session can hold. Without ATTEST_AUTH_TOKENS every session is an
+// admin.
+const (
+	// RoleAdmin may call every method.
+	RoleAdmin = "admin"
+	// RoleEvaluate may evaluate and read, but not touch the engine's
+	// filesystem, change state shared with other clients, or pull
+	// diagnostics.
+	RoleEvaluate = "evaluate"
+)
+
+// evaluateMethods are the methods open to RoleEvaluate. Methods not listed,
+// including any added later, are admin-only until listed here.
+// evaluate_dataset reads and writes client-chosen paths, and
+// register_template changes templates for every client, so both stay
+// admin-only.
+var evaluateMethods = map[string]bool{
+	"initialize":            true,
+	"shutdown":              true,
+	"describe_capabilities": true,
+	"evaluate_batch":        true,
+	"begin_trace":           true,
+	"append_trace_chunk":    true,
+	"end_trace":             true,
+	"submit_plugin_result":  true,
+	"get_metrics":           true,
+	"validate_trace_tree":   true,
+	"query_drift":           true,
+	"query_flaky":           true,
+	"generate_user_message": true,
+	"run_simulation":        true,
+	"run_simulation_batch":  true,
+}
+
+// authPolicy maps auth tokens to roles for an engine shared by several
+// clients. A nil policy disables authentication.
+type authPolicy struct {
+	tokens []authToken
+}
+
+type authToken struct {
+	token string
+	role  string
+}
+
+// parseAuthTokens parses ATTEST_AUTH_TOKENS: comma-separated role:token
+// pairs, e.g. "admin:s3cret,evaluate:ci-token". Returns nil for "".
+func parseAuthTokens(v string) (*authPolicy, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	p := &authPolicy{}
+	for _, entry := range strings.Split(v, ",") {
+		role, token, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || token == "" {
+			return nil, fmt.Errorf("entry %q is not role:token", entry)
+		}
+		if role != RoleAdmin && role != RoleEvaluate {
+			return nil, fmt.Errorf("unknown role %q: want %s or %s", role, RoleAdmin, RoleEvaluate)
+		}
+		p.tokens = append(p.tokens, authToken{token: token, role: role})
+	}
+	return p, nil
+}
+
+// buildAuthPolicy reads ATTEST_AUTH_TOKENS. An invalid value is fatal, since
+// silently starting without authentication would expose a shared engine.
+func buildAuthPolicy(logger *slog.Logger) *authPolicy {
+	p, err := parseAuthTokens(os.Getenv("ATTEST_AUTH_TOKENS"))
+	if err != nil {
+		logger.Error("ATTEST_AUTH_TOKENS is invalid", "err", err)
+		fmt.Fprintf(os.Stderr, "fatal: ATTEST_AUTH_TOKENS: %v\n", err)
+		os.Exit(1)
+	}
+	if p != nil {
+		logger.Info("authentication enabled", "tokens", len(p.tokens))
+	}
+	return p
+}
+
+// authenticate returns the role of token, or "" when it matches none. Every
+// token is compared in constant time so timing does not reveal a prefix.
+func (p *authPolicy) authenticate(token string) string {
+	role := ""
+	for _, t := range p.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.token), []byte(token)) == 1 && role == "" {
+			role = t.role
+		}
+	}
+	return role
+}
+
+// allows reports whether role may call method. A nil policy allows all.
+func (p *authPolicy) allows(role, method string) bool {
+	if p == nil || method == "initialize" {
+		return true
+	}
+	switch role {
+	case RoleAdmin:
+		return true
+	case RoleEvaluate:
+		return evaluateMethods[method]
+	}
+	return false
+}
+
+// authRole is the role initialize reports: role with authentication
+// enabled, "" without it.
+func authRole(p *authPolicy, role string) string {
+	if p == nil {
+		return ""
+	}
+	return role
+}
+
+// permissionDenied is the error for a call the session's role does not allow.
+func permissionDenied(role, what string) *types.RPCError {
+	if role == "" {
+		return types.NewRPCError(
+			types.ErrPermissionDenied,
+			"permission denied: "+what,
+			types.ErrTypePermissionDenied,
+			false,
+			"call initialize with an auth_token first",
+		)
+	}
+	return types.NewRPCError(
+		types.ErrPermissionDenied,
+		fmt.Sprintf("permission denied: %s requires the %s role", what, RoleAdmin),
+		types.ErrTypePermissionDenied,
+		false,
+		fmt.Sprintf("this session authenticated as %s; use an admin token", role),
+	)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestParseAuthTokens(t *testing.T) {
+	p, err := parseAuthTokens(" admin:root:with-colon , evaluate:ci-token")
+	if err != nil {
+		t.Fatalf("parseAuthTokens: %v", err)
+	}
+	if got := p.authenticate("root:with-colon"); got != RoleAdmin {
+		t.Errorf("admin token role = %q", got)
+	}
+	if got := p.authenticate("ci-token"); got != RoleEvaluate {
+		t.Errorf("evaluate token role = %q", got)
+	}
+	if got := p.authenticate("ci"); got != "" {
+		t.Errorf("prefix of a token authenticated as %q", got)
+	}
+
+	if p, err := parseAuthTokens(""); p != nil || err != nil {
+		t.Errorf("empty value = %v, %v; want authentication disabled", p, err)
+	}
+	for _, bad := range []string{"admin", "admin:", "owner:x"} {
+		if _, err := parseAuthTokens(bad); err == nil {
+			t.Errorf("parseAuthTokens(%q) accepted", bad)
+		}
+	}
+}
+
+func TestAuthPolicy_Allows(t *testing.T) {
+	var disabled *authPolicy
+	if !disabled.allows("", "debug_dump") {
+		t.Error("nil policy should allow every method")
+	}
+	p := &authPolicy{}
+	cases := []struct {
+		role, method string
+		want         bool
+	}{
+		{"", "initialize", true},
+		{"", "evaluate_batch", false},
+		{RoleEvaluate, "evaluate_batch", true},
+		{RoleEvaluate, "update_quarantine", false},
+		{RoleEvaluate, "debug_dump", false},
+		{RoleEvaluate, "evaluate_dataset", false},
+		{RoleEvaluate, "register_template", false},
+		{RoleEvaluate, "some_future_method", false},
+		{RoleAdmin, "update_quarantine", true},
+	}
+	for _, c := range cases {
+		if got := p.allows(c.role, c.method); got != c.want {
+			t.Errorf("allows(%q, %q) = %v, want %v", c.role, c.method, got, c.want)
+		}
+	}
+}
+
+func TestServer_AuthRoles(t *testing.T) {
+	t.Setenv("ATTEST_AUTH_TOKENS", "admin:root-token,evaluate:ci-token")
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+
+	t.Run("invalid token", func(t *testing.T) {
+		stdin, stdout, _ := newTestServer(t)
+		params := initializeParams()
+		params.AuthToken = "guess"
+		sendRequest(t, stdin, 1, "initialize", params)
+		if resp := readResponse(t, stdout); resp.Error == nil || resp.Error.Code != types.ErrPermissionDenied {
+			t.Fatalf("initialize with a bad token: %+v", resp.Error)
+		}
+		sendRequest(t, stdin, 2, "get_metrics", nil)
+		if resp := readResponse(t, stdout); resp.Error == nil || resp.Error.Code != types.ErrPermissionDenied {
+			t.Errorf("get_metrics without a role: %+v", resp.Error)
+		}
+	})
+
+	t.Run("evaluate role", func(t *testing.T) {
+		stdin, stdout, _ := newTestServer(t)
+		params := initializeParams()
+		params.AuthToken = "ci-token"
+		sendRequest(t, stdin, 1, "initialize", params)
+		resp := readResponse(t, stdout)
+		var init types.InitializeResult
+		if resp.Error != nil || json.Unmarshal(resp.Result, &init) != nil || init.Role != RoleEvaluate {
+			t.Fatalf("initialize: %s %+v", resp.Result, resp.Error)
+		}
+		sendRequest(t, stdin, 2, "get_metrics", nil)
+		if resp := readResponse(t, stdout); resp.Error != nil {
+			t.Errorf("get_metrics: %+v", resp.Error)
+		}
+		sendRequest(t, stdin, 3, "update_quarantine", types.UpdateQuarantineParams{})
+		if resp := readResponse(t, stdout); resp.Error == nil || resp.Error.Code != types.ErrPermissionDenied {
+			t.Errorf("update_quarantine: %+v", resp.Error)
+		}
+		sendRequest(t, stdin, 4, "query_flaky", types.QueryFlakyParams{Quarantine: true})
+		if resp := readResponse(t, stdout); resp.Error == nil || resp.Error.Code != types.ErrPermissionDenied {
+			t.Errorf("query_flaky with quarantine: %+v", resp.Error)
+		}
+	})
+
+	t.Run("admin role", func(t *testing.T) {
+		stdin, stdout, _ := newTestServer(t)
+		params := initializeParams()
+		params.AuthToken = "root-token"
+		sendRequest(t, stdin, 1, "initialize", params)
+		if resp := readResponse(t, stdout); resp.Error != nil {
+			t.Fatalf("initialize: %+v", resp.Error)
+		}
+		sendRequest(t, stdin, 2, "update_quarantine", types.UpdateQuarantineParams{})
+		if resp := readResponse(t, stdout); resp.Error != nil && resp.Error.Code == types.ErrPermissionDenied {
+			t.Errorf("update_quarantine denied to admin: %+v", resp.Error)
+		}
+	})
+}
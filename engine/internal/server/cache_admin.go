@@ -0,0 +1,90 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+)
+
+// CacheUsage reports one cache's size against its limit.
+type CacheUsage struct {
+	Name     string
+	Entries  int
+	Bytes    int64
+	MaxBytes int64
+}
+
+// openDiskStore opens attest.db in the cache directory, returning nil when
+// it does not exist yet.
+func openDiskStore() (*cache.Store, cache.StoreConfig, error) {
+	cfg, err := cacheStoreConfig()
+	if err != nil {
+		return nil, cfg, err
+	}
+	path := filepath.Join(cacheDirectory(), "attest.db")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, cfg, nil
+	}
+	store, err := cache.OpenStore(path, cfg)
+	if err != nil {
+		return nil, cfg, fmt.Errorf("open %s: %w", path, err)
+	}
+	return store, cfg, nil
+}
+
+// CacheUsages reports the embedding and judge caches in attest.db, or
+// nothing when the database does not exist yet.
+func CacheUsages() ([]CacheUsage, error) {
+	store, cfg, err := openDiskStore()
+	if err != nil || store == nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	emb, err := store.Embeddings().Stats()
+	if err != nil {
+		return nil, err
+	}
+	judge, err := store.Judge().Stats()
+	if err != nil {
+		return nil, err
+	}
+	return []CacheUsage{
+		{Name: "embeddings", Entries: emb.Entries, Bytes: emb.TotalBytes, MaxBytes: int64(cfg.EmbeddingMaxMB) << 20},
+		{Name: "judge", Entries: judge.Entries, Bytes: judge.TotalBytes, MaxBytes: int64(cfg.JudgeMaxMB) << 20},
+	}, nil
+}
+
+// ClearCache removes every entry of the named cache ("embeddings" or
+// "judge") from attest.db, leaving the other cache and history intact, and
+// returns how many entries it removed.
+func ClearCache(name string) (int, error) {
+	store, _, err := openDiskStore()
+	if err != nil || store == nil {
+		return 0, err
+	}
+	defer store.Close()
+
+	var stats *cache.CacheStats
+	var clear func() error
+	switch name {
+	case "embeddings":
+		stats, err = store.Embeddings().Stats()
+		clear = store.Embeddings().Clear
+	case "judge":
+		stats, err = store.Judge().Stats()
+		clear = store.Judge().Clear
+	default:
+		return 0, fmt.Errorf("unknown cache %q: want embeddings or judge", name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := clear(); err != nil {
+		return 0, err
+	}
+	return stats.Entries, nil
+}
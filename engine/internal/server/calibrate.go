@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+)
+
+// CalibrationExample is one labeled example: a text to judge and the score
+// a human gave it, in [0, 1].
+type CalibrationExample struct {
+	Target   string  `json:"target"`
+	Score    float64 `json:"score"`
+	Criteria string  `json:"criteria,omitempty"`
+}
+
+// CalibrationReport is the outcome of `attest-engine calibrate`.
+type CalibrationReport struct {
+	Calibration *judge.Calibration
+	Judged      int
+	Cost        float64
+	// Saved is false for a dry run.
+	Saved  bool
+	Errors []string
+}
+
+// Calibrate judges every example in the labeled dataset at datasetPath with
+// rubric, fits a calibration curve against the human scores, and, unless
+// dryRun is set, stores it so the engine maps future judge scores for the
+// rubric and the configured judge model through it. It backs
+// `attest-engine calibrate`.
+func Calibrate(logger *slog.Logger, rubric, datasetPath string, concurrency int, dryRun bool) (*CalibrationReport, error) {
+	examples, err := loadCalibrationDataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	store := openCacheStore(logger)
+	if store == nil {
+		return nil, errors.New("cache database unavailable; see log for details")
+	}
+	defer store.Close()
+
+	judgeProvider, _, err := buildJudgeProvider(logger, builtinProviders())
+	if err != nil {
+		return nil, err
+	}
+	if judgeProvider == nil {
+		return nil, errors.New("no judge provider configured; set ATTEST_OPENAI_API_KEY")
+	}
+	rubrics := buildRubricRegistry(logger)
+	if _, err := rubrics.Get(rubric); err != nil {
+		return nil, err
+	}
+	evaluator := assertion.NewJudgeEvaluator(judgeProvider, rubrics, store.Judge())
+
+	report, raw, human := calibrateExamples(context.Background(), examples, concurrency, func(ctx context.Context, ex CalibrationExample) (float64, float64, error) {
+		return evaluator.RawScore(ctx, ex.Target, rubric, ex.Criteria)
+	})
+	if report.Judged == 0 {
+		return report, errors.New("no example could be judged")
+	}
+	report.Calibration, err = judge.Fit(rubric, judgeProvider.DefaultModel(), raw, human)
+	if err != nil {
+		return report, err
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	curve, err := json.Marshal(report.Calibration)
+	if err != nil {
+		return report, fmt.Errorf("encode calibration: %w", err)
+	}
+	if err := store.Calibrations().Put(rubric, report.Calibration.Model, curve); err != nil {
+		return report, err
+	}
+	report.Saved = true
+	return report, nil
+}
+
+// calibrateExamples scores every example with at most concurrency calls in
+// flight and returns the raw judge scores paired with the human scores.
+// Failed examples are reported and left out of the pairs.
+func calibrateExamples(
+	ctx context.Context,
+	examples []CalibrationExample,
+	concurrency int,
+	score func(ctx context.Context, ex CalibrationExample) (raw, cost float64, err error),
+) (report *CalibrationReport, raw, human []float64) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	scores := make([]float64, len(examples))
+	costs := make([]float64, len(examples))
+	errs := make([]error, len(examples))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range examples {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			scores[i], costs[i], errs[i] = score(ctx, examples[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report = &CalibrationReport{}
+	for i, err := range errs {
+		report.Cost += costs[i]
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("example %d: %v", i+1, err))
+			continue
+		}
+		report.Judged++
+		raw = append(raw, scores[i])
+		human = append(human, examples[i].Score)
+	}
+	return report, raw, human
+}
+
+// loadCalibrationDataset reads labeled examples from a JSON array or JSONL
+// file. Every example needs a target and a score in [0, 1].
+func loadCalibrationDataset(path string) ([]CalibrationExample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read dataset: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+
+	var examples []CalibrationExample
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &examples); err != nil {
+			return nil, fmt.Errorf("parse dataset: %w", err)
+		}
+	} else {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		line := 0
+		for sc.Scan() {
+			line++
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			var ex CalibrationExample
+			if err := json.Unmarshal(sc.Bytes(), &ex); err != nil {
+				return nil, fmt.Errorf("parse dataset line %d: %w", line, err)
+			}
+			examples = append(examples, ex)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("read dataset: %w", err)
+		}
+	}
+
+	for i, ex := range examples {
+		if ex.Target == "" {
+			return nil, fmt.Errorf("example %d: target is required", i+1)
+		}
+		if ex.Score < 0 || ex.Score > 1 {
+			return nil, fmt.Errorf("example %d: score %v is outside [0, 1]", i+1, ex.Score)
+		}
+	}
+	return examples, nil
+}
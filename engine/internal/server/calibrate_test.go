@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCalibrationDataset(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "labels.jsonl")
+	writeFile(t, jsonl, `{"target": "Refund issued.", "score": 0.9}
+
+{"target": "No idea.", "score": 0.1, "criteria": "answers the question"}
+`)
+	examples, err := loadCalibrationDataset(jsonl)
+	if err != nil || len(examples) != 2 || examples[1].Criteria != "answers the question" {
+		t.Fatalf("loadCalibrationDataset = %+v, %v", examples, err)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	writeFile(t, bad, `[{"target": "x", "score": 7}]`)
+	if _, err := loadCalibrationDataset(bad); err == nil {
+		t.Error("expected error for score outside [0, 1]")
+	}
+}
+
+func TestCalibrateExamples_SkipsFailures(t *testing.T) {
+	examples := []CalibrationExample{
+		{Target: "a", Score: 0.2},
+		{Target: "fail", Score: 0.5},
+		{Target: "c", Score: 0.8},
+	}
+	report, raw, human := calibrateExamples(context.Background(), examples, 2, func(_ context.Context, ex CalibrationExample) (float64, float64, error) {
+		if ex.Target == "fail" {
+			return 0, 0.01, errors.New("judge unavailable")
+		}
+		return ex.Score + 0.1, 0.01, nil
+	})
+	if report.Judged != 2 || len(report.Errors) != 1 || report.Cost != 0.03 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(raw) != 2 || raw[1] != 0.9 || human[1] != 0.8 {
+		t.Errorf("raw = %v, human = %v", raw, human)
+	}
+
+	report, raw, _ = calibrateExamples(context.Background(), examples, 2, func(_ context.Context, ex CalibrationExample) (float64, float64, error) {
+		if ex.Target == "fail" {
+			panic("judge bug")
+		}
+		return ex.Score, 0, nil
+	})
+	if report.Judged != 2 || len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "panic: judge bug") || len(raw) != 2 {
+		t.Errorf("report after panic = %+v", report)
+	}
+}
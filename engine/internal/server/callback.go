@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// methodKey marks a line that may be a request rather than a response.
+var methodKey = []byte(`"method"`)
+
+// CallError is returned by Server.Call when the SDK answers with a JSON-RPC
+// error.
+type CallError struct {
+	Method string
+	Err    *types.RPCError
+}
+
+func (e *CallError) Error() string {
+	if e.Err.Data != nil && e.Err.Data.Detail != "" {
+		return fmt.Sprintf("%s returned error %d: %s: %s", e.Method, e.Err.Code, e.Err.Message, e.Err.Data.Detail)
+	}
+	return fmt.Sprintf("%s returned error %d: %s", e.Method, e.Err.Code, e.Err.Message)
+}
+
+// errServerStopped is returned by Call when the input stream ends before the
+// SDK answers.
+var errServerStopped = errors.New("engine input closed before the SDK answered")
+
+// Call sends a JSON-RPC request to the SDK on the response stream and waits
+// for the SDK's response with the same id, decoding its result into result.
+// Engine-initiated ids are numbered independently of SDK request ids; the two
+// directions are told apart by the presence of "method". Call returns when
+// ctx is done, without waiting for a late response, which is then dropped.
+func (s *Server) Call(ctx context.Context, method string, params, result any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal %s params: %w", method, err)
+	}
+
+	ch := make(chan *types.Response, 1)
+	s.callsMu.Lock()
+	if s.callsClosed {
+		s.callsMu.Unlock()
+		return errServerStopped
+	}
+	s.nextCallID++
+	id := s.nextCallID
+	s.calls[id] = ch
+	s.callsMu.Unlock()
+	signal(s.callStarted)
+	defer func() {
+		s.callsMu.Lock()
+		delete(s.calls, id)
+		s.callsMu.Unlock()
+	}()
+
+	s.writeNotification(&types.Request{
+		JSONRPC:   "2.0",
+		ID:        id,
+		Method:    method,
+		Params:    raw,
+		RequestID: logging.RequestID(ctx),
+	})
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	case resp, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("%s: %w", method, errServerStopped)
+		}
+		if resp.Error != nil {
+			return &CallError{Method: method, Err: resp.Error}
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// routeResponse delivers line to the pending Call it answers and reports
+// whether line was a response. Responses to unknown or abandoned calls are
+// logged and dropped; JSON-RPC forbids answering them.
+func (s *Server) routeResponse(line []byte) bool {
+	s.callsMu.Lock()
+	pending := len(s.calls)
+	s.callsMu.Unlock()
+	// A line without "method" cannot be a request; only pay for a full
+	// parse of the rest while calls are outstanding.
+	if pending == 0 && bytes.Contains(line, methodKey) {
+		return false
+	}
+	// Too deep to decode safely; dispatch answers it with a parse error.
+	if trace.ExceedsNesting(line, s.maxJSONDepth) {
+		return false
+	}
+
+	var msg struct {
+		ID     int64           `json:"id"`
+		Method string          `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  *types.RPCError `json:"error"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil || msg.Method != "" {
+		return false
+	}
+	if msg.Result == nil && msg.Error == nil {
+		return false
+	}
+
+	s.callsMu.Lock()
+	ch, ok := s.calls[msg.ID]
+	delete(s.calls, msg.ID)
+	s.callsMu.Unlock()
+	if !ok {
+		s.logger.Warn("dropping response to unknown or abandoned call", "id", msg.ID)
+		return true
+	}
+	ch <- &types.Response{JSONRPC: "2.0", ID: msg.ID, Result: msg.Result, Error: msg.Error}
+	return true
+}
+
+// callsPending reports whether any Call is waiting for the SDK.
+func (s *Server) callsPending() bool {
+	s.callsMu.Lock()
+	defer s.callsMu.Unlock()
+	return len(s.calls) > 0
+}
+
+// closeCalls fails every pending Call once the input stream ends.
+func (s *Server) closeCalls() {
+	s.callsMu.Lock()
+	defer s.callsMu.Unlock()
+	s.callsClosed = true
+	for id, ch := range s.calls {
+		close(ch)
+		delete(s.calls, id)
+	}
+}
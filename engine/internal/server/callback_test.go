@@ -0,0 +1,324 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// newSimulationServer starts a server with the simulation methods backed by a mock
+// simulated user, and a fake SDK that answers agent_invoke calls with agent.
+// Responses to the test's own requests are delivered on the returned channel.
+func newSimulationServer(t *testing.T, agent func(p types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError)) (io.Writer, <-chan *types.Response) {
+	t.Helper()
+
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv := New(stdinR, stdoutW, logger)
+	provider := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: "follow-up 1", Model: "mock-model"},
+		{Content: "follow-up 2", Model: "mock-model"},
+	}, nil)
+	srv.RegisterHandler("initialize", handleInitialize(nil, &modelChecks{}, trace.DefaultLimits, nil, nil))
+	pipeline := assertion.NewPipeline(assertion.NewRegistry())
+	templates := assertion.NewTemplateRegistry()
+	srv.RegisterHandler("run_simulation", handleRunSimulation(provider, pipeline, templates, srv.Call))
+	srv.RegisterHandler("run_simulation_batch", handleRunSimulationBatch(provider, pipeline, templates, srv.Call))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(func() {
+		cancel()
+		stdinW.Close()
+		stdoutR.Close()
+	})
+	go func() {
+		_ = srv.Run(ctx)
+		stdoutW.Close()
+	}()
+
+	responses := make(chan *types.Response, 4)
+	go func() {
+		scanner := bufio.NewScanner(stdoutR)
+		for scanner.Scan() {
+			var req types.Request
+			if err := json.Unmarshal(scanner.Bytes(), &req); err == nil && req.Method == "agent_invoke" {
+				var p types.AgentInvokeParams
+				_ = json.Unmarshal(req.Params, &p)
+				result, rpcErr := agent(p)
+				if result == nil && rpcErr == nil {
+					continue // never answer
+				}
+				resp := &types.Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+				if result != nil {
+					resp.Result, _ = json.Marshal(result)
+				}
+				data, _ := json.Marshal(resp)
+				_, _ = stdinW.Write(append(data, '\n'))
+				continue
+			}
+			var resp types.Response
+			if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+				t.Errorf("unmarshal response: %v", err)
+				return
+			}
+			responses <- &resp
+		}
+	}()
+
+	sendRequest(t, stdinW, 1, "initialize", initializeParams())
+	if resp := <-responses; resp.Error != nil {
+		t.Fatalf("initialize failed: %+v", resp.Error)
+	}
+	return stdinW, responses
+}
+
+func TestRunSimulation_DrivesSDKAgent(t *testing.T) {
+	var calls []types.AgentInvokeParams
+	stdin, responses := newSimulationServer(t, func(p types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		calls = append(calls, p)
+		return &types.AgentInvokeResult{Response: "agent reply to " + p.Message}, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{
+		Persona:       types.SimulatePersona{Name: "u", SystemPrompt: "You are a customer."},
+		InitialPrompt: "hello",
+		MaxTurns:      3,
+		AgentContext:  json.RawMessage(`{"agent":"support"}`),
+	})
+	resp := <-responses
+	if resp.Error != nil {
+		t.Fatalf("run_simulation error: %+v", resp.Error)
+	}
+	var result types.RunSimulationResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+
+	if result.TotalTurns != 3 || result.StoppedBy != "max_turns" {
+		t.Errorf("TotalTurns = %d, StoppedBy = %q; want 3, max_turns", result.TotalTurns, result.StoppedBy)
+	}
+	if len(result.Transcript) != 6 {
+		t.Fatalf("len(Transcript) = %d, want 6", len(result.Transcript))
+	}
+	if got := result.Transcript[3].Content; got != "agent reply to follow-up 1" {
+		t.Errorf("Transcript[3] = %q", got)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("agent_invoke calls = %d, want 3", len(calls))
+	}
+	for i, c := range calls {
+		if c.Turn != i+1 || c.SimulationID != result.SimulationID || string(c.AgentContext) != `{"agent":"support"}` {
+			t.Errorf("call %d = %+v", i, c)
+		}
+	}
+}
+
+func TestRunSimulation_PipelinedRequests(t *testing.T) {
+	// The SDK sends another request before answering agent_invoke; reading
+	// must continue so the answer reaches the blocked sequential handler.
+	var stdin io.Writer
+	var once sync.Once
+	stdin, responses := newSimulationServer(t, func(p types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		once.Do(func() {
+			sendRequest(t, stdin, 3, "no_such_method", nil)
+			sendRequest(t, stdin, 4, "no_such_method", nil)
+		})
+		return &types.AgentInvokeResult{Response: "ok"}, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{InitialPrompt: "hello", MaxTurns: 2})
+	var ids []int64
+	for len(ids) < 3 {
+		select {
+		case resp := <-responses:
+			if resp.ID == 2 && resp.Error != nil {
+				t.Fatalf("run_simulation error: %+v", resp.Error)
+			}
+			ids = append(ids, resp.ID)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("engine stalled; responses so far: %v", ids)
+		}
+	}
+	if ids[0] != 2 || ids[1] != 3 || ids[2] != 4 {
+		t.Errorf("response ids = %v, want [2 3 4]", ids)
+	}
+}
+
+func TestRunSimulation_AgentError(t *testing.T) {
+	stdin, responses := newSimulationServer(t, func(types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		return nil, &types.RPCError{Code: -32000, Message: "agent crashed"}
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{InitialPrompt: "hello", MaxTurns: 2})
+	resp := <-responses
+	if resp.Error == nil {
+		t.Fatal("expected error when the agent fails")
+	}
+	if resp.Error.Code != types.ErrEngineError || !strings.Contains(resp.Error.Message, "agent crashed") {
+		t.Errorf("Error = %+v", resp.Error)
+	}
+}
+
+func TestRunSimulation_AgentTimeout(t *testing.T) {
+	stdin, responses := newSimulationServer(t, func(types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		return nil, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{InitialPrompt: "hello", MaxTurns: 2, AgentTimeoutMS: 20})
+	resp := <-responses
+	if resp.Error == nil {
+		t.Fatal("expected timeout error")
+	}
+	if resp.Error.Code != types.ErrTimeout {
+		t.Errorf("Error.Code = %d, want %d", resp.Error.Code, types.ErrTimeout)
+	}
+}
+
+func TestRunSimulation_InvalidTurnBudget(t *testing.T) {
+	stdin, responses := newSimulationServer(t, func(types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		t.Error("agent called despite invalid params")
+		return &types.AgentInvokeResult{}, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{InitialPrompt: "hello", MaxTurns: maxSimulationTurns + 1})
+	resp := <-responses
+	if resp.Error == nil || resp.Error.Code != types.ErrAssertionError {
+		t.Fatalf("Error = %+v, want ASSERTION_ERROR", resp.Error)
+	}
+}
+
+func TestRunSimulationBatch_Aggregates(t *testing.T) {
+	stdin, responses := newSimulationServer(t, func(p types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		if strings.HasSuffix(p.SimulationID, "-0") {
+			return &types.AgentInvokeResult{Response: "resolved"}, nil
+		}
+		return &types.AgentInvokeResult{Response: "working on it"}, nil
+	})
+
+	params := types.RunSimulationBatchParams{
+		RunSimulationParams: types.RunSimulationParams{InitialPrompt: "hello", MaxTurns: 2, StopKeywords: []string{"resolved"}},
+		Runs:                3,
+		Personas:            []types.SimulatePersona{{Name: "a"}, {Name: "b"}},
+		Concurrency:         2,
+	}
+	sendRequest(t, stdin, 2, "run_simulation_batch", params)
+	resp := <-responses
+	if resp.Error != nil {
+		t.Fatalf("run_simulation_batch error: %+v", resp.Error)
+	}
+	var result types.RunSimulationBatchResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+
+	if result.Succeeded != 1 || result.Failed != 2 || result.Errored != 0 {
+		t.Errorf("succeeded/failed/errored = %d/%d/%d, want 1/2/0", result.Succeeded, result.Failed, result.Errored)
+	}
+	if result.Turns.Min != 1 || result.Turns.Max != 2 || result.Turns.Counts[2] != 2 {
+		t.Errorf("Turns = %+v", result.Turns)
+	}
+	if len(result.Runs) != 3 || result.Runs[1].Persona != "b" || result.Runs[2].Persona != "a" {
+		t.Errorf("Runs = %+v", result.Runs)
+	}
+	if result.Worst == nil || result.Worst.SimulationID != result.Runs[1].SimulationID || len(result.Worst.Transcript) != 4 {
+		t.Errorf("Worst = %+v, want run 1 with 2 turns", result.Worst)
+	}
+}
+
+func TestRunSimulation_InvalidContextWindow(t *testing.T) {
+	stdin, responses := newSimulationServer(t, func(types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		return &types.AgentInvokeResult{}, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{
+		InitialPrompt: "hello",
+		MaxTurns:      2,
+		ContextWindow: &types.SimulationContextWindow{Summarize: true},
+	})
+	resp := <-responses
+	if resp.Error == nil || !strings.Contains(resp.Error.Message, "keep_turns") {
+		t.Fatalf("Error = %+v, want keep_turns error", resp.Error)
+	}
+}
+
+func TestRunSimulation_UserActions(t *testing.T) {
+	var calls []types.AgentInvokeParams
+	stdin, responses := newSimulationServer(t, func(p types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		calls = append(calls, p)
+		return &types.AgentInvokeResult{Response: "thanks"}, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{
+		Persona:        types.SimulatePersona{Name: "u", Actions: []string{types.ActionFillForm}},
+		InitialPrompt:  "signing up",
+		InitialActions: []types.UserAction{{Type: types.ActionFillForm, Form: "signup", Fields: map[string]string{"email": "a@b.c"}}},
+		MaxTurns:       1,
+	})
+	resp := <-responses
+	if resp.Error != nil {
+		t.Fatalf("run_simulation error: %+v", resp.Error)
+	}
+	if len(calls) != 1 || len(calls[0].Actions) != 1 || calls[0].Actions[0].Fields["email"] != "a@b.c" {
+		t.Errorf("agent_invoke calls = %+v, want the fill_form action", calls)
+	}
+
+	sendRequest(t, stdin, 3, "run_simulation", types.RunSimulationParams{
+		Persona:       types.SimulatePersona{Name: "u", Actions: []string{"drag_drop"}},
+		InitialPrompt: "hello",
+		MaxTurns:      1,
+	})
+	resp = <-responses
+	if resp.Error == nil || resp.Error.Code != types.ErrAssertionError {
+		t.Fatalf("Error = %+v, want ASSERTION_ERROR for unsupported action", resp.Error)
+	}
+}
+
+func TestRunSimulation_TurnAssertionsAbort(t *testing.T) {
+	stdin, responses := newSimulationServer(t, func(p types.AgentInvokeParams) (*types.AgentInvokeResult, *types.RPCError) {
+		if p.Turn == 2 {
+			return &types.AgentInvokeResult{Response: "I refuse to help you"}, nil
+		}
+		return &types.AgentInvokeResult{Response: "happy to help"}, nil
+	})
+
+	sendRequest(t, stdin, 2, "run_simulation", types.RunSimulationParams{
+		InitialPrompt: "hello",
+		MaxTurns:      5,
+		TurnAssertions: []types.Assertion{
+			{AssertionID: "no_refusal", Type: types.TypeContent, Spec: json.RawMessage(`{"target":"output.message","check":"not_contains","value":"refuse"}`)},
+			{AssertionID: "has_history", Type: types.TypeTranscript, Spec: json.RawMessage(`{"check":"no_repetition_across_turns","soft":true}`)},
+		},
+	})
+	resp := <-responses
+	if resp.Error != nil {
+		t.Fatalf("run_simulation error: %+v", resp.Error)
+	}
+	var result types.RunSimulationResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if result.StoppedBy != "assertion_failed:no_refusal" || result.TotalTurns != 2 {
+		t.Errorf("StoppedBy = %q after %d turns, want assertion_failed:no_refusal after 2", result.StoppedBy, result.TotalTurns)
+	}
+	if len(result.TurnResults) != 2 || result.TurnResults[1].Turn != 2 || len(result.TurnResults[1].Results) != 2 {
+		t.Fatalf("TurnResults = %+v", result.TurnResults)
+	}
+	for _, r := range result.TurnResults[1].Results {
+		if r.AssertionID == "no_refusal" && r.Status != types.StatusHardFail {
+			t.Errorf("no_refusal on turn 2 = %s, want hard_fail", r.Status)
+		}
+	}
+}
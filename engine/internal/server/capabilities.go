@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// capabilityTypes maps a provider-backed capability to the assertion types
+// that become unavailable when its model fails validation.
+var capabilityTypes = map[string][]string{
+	"embedding": {types.TypeEmbedding},
+	"llm_judge": {types.TypeLLMJudge, types.TypePersonaConsistency},
+}
+
+// handleDescribeCapabilities reports the engine's full feature matrix. Unlike
+// the other methods it may be called before initialize, so an SDK can pick
+// what to send before opening a session.
+func handleDescribeCapabilities(s *Server, registry *assertion.Registry, caps []string, checks *modelChecks, limits trace.Limits, providers []types.ProviderInfo) Handler {
+	return func(ctx context.Context, session *Session, _ json.RawMessage) (any, *types.RPCError) {
+		providerErrors := checks.results(ctx)
+		failed := make(map[string]bool)
+		for _, e := range providerErrors {
+			for _, t := range capabilityTypes[e.Capability] {
+				failed[t] = true
+			}
+		}
+		assertionTypes := registry.Describe()
+		for i := range assertionTypes {
+			if failed[assertionTypes[i].Type] {
+				assertionTypes[i].Available = false
+			}
+		}
+		if providers == nil {
+			providers = []types.ProviderInfo{}
+		}
+
+		return &types.DescribeCapabilitiesResult{
+			EngineVersion:      engineVersion,
+			ProtocolVersion:    protocolVersion,
+			MinProtocolVersion: minProtocolVersion,
+			Capabilities:       withoutFailed(caps, providerErrors),
+			AssertionTypes:     assertionTypes,
+			Limits: types.EngineLimits{
+				MaxConcurrentRequests: 1,
+				MaxTraceSizeBytes:     limits.MaxTraceSize,
+				MaxStepsPerTrace:      limits.MaxStepsPerTrace,
+				MaxStepPayloadBytes:   limits.MaxStepPayload,
+				MaxSubTraceDepth:      limits.MaxSubTraceDepth,
+				MaxAssertionIDLength:  MaxAssertionIDLength,
+			},
+			Providers:      providers,
+			ProviderErrors: providerErrors,
+			Methods:        s.methods(session.Role()),
+			Encodings:      []string{types.EncodingJSON, types.EncodingJSONGzip},
+		}, nil
+	}
+}
+
+// methods returns the registered methods role may call, sorted.
+func (s *Server) methods(role string) []string {
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		if s.auth.allows(role, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func describeCapabilities(t *testing.T, authToken string) *types.DescribeCapabilitiesResult {
+	t.Helper()
+	stdin, stdout, _ := newTestServer(t)
+	if authToken != "" {
+		params := initializeParams()
+		params.AuthToken = authToken
+		sendRequest(t, stdin, 1, "initialize", params)
+		if resp := readResponse(t, stdout); resp.Error != nil {
+			t.Fatalf("initialize: %+v", resp.Error)
+		}
+	}
+	sendRequest(t, stdin, 2, "describe_capabilities", nil)
+	resp := readResponse(t, stdout)
+	if resp.Error != nil {
+		t.Fatalf("describe_capabilities: %+v", resp.Error)
+	}
+	var result types.DescribeCapabilitiesResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	return &result
+}
+
+func TestServer_DescribeCapabilities(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+
+	// Callable before initialize when authentication is disabled.
+	result := describeCapabilities(t, "")
+	if result.EngineVersion != engineVersion || result.ProtocolVersion != protocolVersion || result.MinProtocolVersion != minProtocolVersion {
+		t.Errorf("versions = %s %d %d", result.EngineVersion, result.ProtocolVersion, result.MinProtocolVersion)
+	}
+	if result.Limits.MaxAssertionIDLength != MaxAssertionIDLength || result.Limits.MaxTraceSizeBytes == 0 {
+		t.Errorf("limits = %+v", result.Limits)
+	}
+	if !slices.Contains(result.Encodings, types.EncodingJSONGzip) {
+		t.Errorf("encodings = %v", result.Encodings)
+	}
+	for _, m := range []string{"initialize", "evaluate_batch", "describe_capabilities", "update_quarantine"} {
+		if !slices.Contains(result.Methods, m) {
+			t.Errorf("methods %v missing %s", result.Methods, m)
+		}
+	}
+
+	var traceInfo *types.AssertionTypeInfo
+	for i := range result.AssertionTypes {
+		if result.AssertionTypes[i].Type == types.TypeTrace {
+			traceInfo = &result.AssertionTypes[i]
+		}
+	}
+	if traceInfo == nil || !traceInfo.Available || traceInfo.Layer != 3 || !slices.Contains(traceInfo.Checks, "no_duplicates") {
+		t.Errorf("trace = %+v", traceInfo)
+	}
+}
+
+func TestServer_DescribeCapabilities_Role(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	t.Setenv("ATTEST_AUTH_TOKENS", "admin:root-token,evaluate:ci-token")
+
+	methods := describeCapabilities(t, "ci-token").Methods
+	if slices.Contains(methods, "update_quarantine") || !slices.Contains(methods, "evaluate_batch") {
+		t.Errorf("evaluate role methods = %v", methods)
+	}
+	if methods := describeCapabilities(t, "root-token").Methods; !slices.Contains(methods, "update_quarantine") {
+		t.Errorf("admin role methods = %v", methods)
+	}
+}
@@ -0,0 +1,532 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/internal/deprecation"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// datasetCheckpointEvery is how many traces are evaluated between checkpoint
+// writes; an interrupted run re-evaluates at most this many.
+const datasetCheckpointEvery = 100
+
+// datasetCheckpoint records how far a dataset run got. The sink is truncated
+// to SinkBytes on resume, dropping lines written after the checkpoint.
+type datasetCheckpoint struct {
+	DatasetHash string  `json:"dataset_hash"`
+	Sink        string  `json:"sink"`
+	NextLine    int     `json:"next_line"`
+	SinkBytes   int64   `json:"sink_bytes"`
+	Traces      int     `json:"traces"`
+	Failed      int     `json:"failed"`
+	Errors      int     `json:"errors"`
+	TotalCost   float64 `json:"total_cost"`
+
+	Duplicates     int     `json:"duplicates,omitempty"`
+	NearDuplicates int     `json:"near_duplicates,omitempty"`
+	SavedCost      float64 `json:"saved_cost,omitempty"`
+}
+
+// Sink errors, reported before the sink is opened, so a misdirected sink
+// never truncates an existing file.
+var (
+	errSinkIsDataset = errors.New("sink is the dataset file")
+	errSinkExists    = errors.New("sink already exists and no checkpoint covers it")
+)
+
+// datasetRun evaluates a dataset file line by line, streaming results to the
+// sink and checkpointing progress under checkpointDir.
+type datasetRun struct {
+	pipeline      *assertion.Pipeline
+	assertions    []types.Assertion
+	dataset       string
+	sink          string
+	checkpointDir string
+	restart       bool
+	seed          *int64
+	dedup         *dedupIndex // nil disables dedup
+	limits        trace.Limits
+	rates         trace.CurrencyRates
+}
+
+// hash hashes the dataset contents together with the assertions and dedup
+// settings, so a run resumes only against the same inputs.
+func (r *datasetRun) hash() (string, error) {
+	f, err := os.Open(r.dataset)
+	if err != nil {
+		return "", fmt.Errorf("open dataset: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("read dataset: %w", err)
+	}
+	// Canonical, so an SDK that re-serializes the same assertions with
+	// different key order or whitespace still resumes the run.
+	spec, err := json.Marshal(r.assertions)
+	if err == nil {
+		spec, err = types.CanonicalJSON(spec)
+	}
+	if err != nil {
+		return "", fmt.Errorf("encode assertions: %w", err)
+	}
+	h.Write([]byte{0})
+	h.Write(spec)
+	if r.dedup != nil {
+		fmt.Fprintf(h, "\x00dedup=%s:%g", r.dedup.mode, r.dedup.threshold)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+func (r *datasetRun) checkpointPath(hash string) string {
+	return filepath.Join(r.checkpointDir, hash+".json")
+}
+
+// loadCheckpoint returns the checkpoint to resume from, or a fresh one when
+// there is none, restart is set, or the sink no longer holds its lines.
+func (r *datasetRun) loadCheckpoint(hash string) *datasetCheckpoint {
+	fresh := &datasetCheckpoint{DatasetHash: hash, Sink: r.sink}
+	if r.restart {
+		return fresh
+	}
+	data, err := os.ReadFile(r.checkpointPath(hash))
+	if err != nil {
+		return fresh
+	}
+	var cp datasetCheckpoint
+	if json.Unmarshal(data, &cp) != nil || cp.DatasetHash != hash || cp.Sink != r.sink {
+		return fresh
+	}
+	if info, err := os.Stat(r.sink); err != nil || info.Size() < cp.SinkBytes {
+		return fresh
+	}
+	return &cp
+}
+
+// checkSink refuses a sink that is the dataset itself, and an existing,
+// non-empty sink that cp does not resume unless restart is set.
+func (r *datasetRun) checkSink(cp *datasetCheckpoint) error {
+	sinkInfo, err := os.Stat(r.sink)
+	if err != nil {
+		return nil
+	}
+	if dataInfo, err := os.Stat(r.dataset); err == nil && os.SameFile(sinkInfo, dataInfo) {
+		return errSinkIsDataset
+	}
+	if cp.NextLine == 0 && cp.SinkBytes == 0 && sinkInfo.Size() > 0 && !r.restart {
+		return fmt.Errorf("%w: %s", errSinkExists, r.sink)
+	}
+	return nil
+}
+
+// saveCheckpoint writes cp atomically.
+func (r *datasetRun) saveCheckpoint(cp *datasetCheckpoint) error {
+	if err := os.MkdirAll(r.checkpointDir, 0o755); err != nil {
+		return fmt.Errorf("create checkpoint dir: %w", err)
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	path := r.checkpointPath(cp.DatasetHash)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// run evaluates the lines not covered by the checkpoint. When ctx is
+// cancelled it checkpoints the lines written so far and returns ctx.Err().
+func (r *datasetRun) run(ctx context.Context) (*types.EvaluateDatasetResult, error) {
+	hash, err := r.hash()
+	if err != nil {
+		return nil, err
+	}
+	cp := r.loadCheckpoint(hash)
+	if err := r.checkSink(cp); err != nil {
+		return nil, err
+	}
+	resumed := cp.Traces
+	// Results of unique traces before the checkpoint, for the dedup index.
+	var prior map[int]*types.DatasetResultLine
+	if r.dedup != nil && cp.NextLine > 0 {
+		if prior, err = loadUnique(r.sink, cp.SinkBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	in, err := os.Open(r.dataset)
+	if err != nil {
+		return nil, fmt.Errorf("open dataset: %w", err)
+	}
+	defer in.Close()
+	sinkFile, err := os.OpenFile(r.sink, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open sink: %w", err)
+	}
+	defer sinkFile.Close()
+	if err := sinkFile.Truncate(cp.SinkBytes); err != nil {
+		return nil, fmt.Errorf("truncate sink: %w", err)
+	}
+	if _, err := sinkFile.Seek(cp.SinkBytes, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek sink: %w", err)
+	}
+	sink := bufio.NewWriter(sinkFile)
+
+	// checkpoint flushes the sink so SinkBytes covers every line counted.
+	checkpoint := func() error {
+		if err := sink.Flush(); err != nil {
+			return fmt.Errorf("write sink: %w", err)
+		}
+		return r.saveCheckpoint(cp)
+	}
+	if resumed > 0 {
+		logging.FromContext(ctx).Info("resuming dataset run", "dataset_hash", hash, "traces_done", resumed)
+	}
+
+	reader := bufio.NewReader(in)
+	for lineNo, sinceCheckpoint := 1, 0; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return nil, fmt.Errorf("read dataset: %w", readErr)
+		}
+		if lineNo <= cp.NextLine {
+			if rep := prior[lineNo]; rep != nil {
+				cp.TotalCost += r.reindex(ctx, line, rep)
+			}
+		} else if len(bytes.TrimSpace(line)) > 0 {
+			out, saved := r.evaluateLine(ctx, lineNo, line)
+			// A line evaluated while the run was being cancelled may hold
+			// provider errors; leave it to the resumed run.
+			if err := ctx.Err(); err != nil {
+				if cpErr := checkpoint(); cpErr != nil {
+					return nil, cpErr
+				}
+				return nil, err
+			}
+			data, err := json.Marshal(out)
+			if err != nil {
+				return nil, fmt.Errorf("encode result: %w", err)
+			}
+			sink.Write(data)
+			sink.WriteByte('\n')
+			cp.SinkBytes += int64(len(data) + 1)
+			cp.Traces++
+			cp.TotalCost += out.TotalCost
+			if out.DuplicateOf > 0 {
+				cp.Duplicates++
+				if out.Similarity > 0 {
+					cp.NearDuplicates++
+				}
+				cp.SavedCost += saved
+			}
+			switch {
+			case out.Error != "":
+				cp.Errors++
+			case hasHardFail(out.Results):
+				cp.Failed++
+			}
+			sinceCheckpoint++
+		}
+		if lineNo > cp.NextLine {
+			cp.NextLine = lineNo
+		}
+		if sinceCheckpoint >= datasetCheckpointEvery {
+			if err := checkpoint(); err != nil {
+				return nil, err
+			}
+			sinceCheckpoint = 0
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	if err := checkpoint(); err != nil {
+		return nil, err
+	}
+
+	result := &types.EvaluateDatasetResult{
+		DatasetHash: hash,
+		Sink:        r.sink,
+		Traces:      cp.Traces,
+		Resumed:     resumed,
+		Failed:      cp.Failed,
+		Errors:      cp.Errors,
+		TotalCost:   cp.TotalCost,
+	}
+	if r.dedup != nil {
+		result.Dedup = &types.DedupSummary{
+			Duplicates:     cp.Duplicates,
+			NearDuplicates: cp.NearDuplicates,
+			SavedCost:      cp.SavedCost,
+		}
+	}
+	return result, nil
+}
+
+// evaluateLine evaluates one dataset line, reporting a line that is not a
+// valid trace in the result's Error rather than failing the run. A duplicate
+// reuses its original's results; saved is what they cost the original.
+func (r *datasetRun) evaluateLine(ctx context.Context, lineNo int, line []byte) (out *types.DatasetResultLine, saved float64) {
+	out = &types.DatasetResultLine{Line: lineNo}
+	if rpcErr := r.limits.CheckNesting(line); rpcErr != nil {
+		out.Error = rpcErr.Message
+		return out, 0
+	}
+	var t types.Trace
+	if err := json.Unmarshal(line, &t); err != nil {
+		out.Error = fmt.Sprintf("invalid trace: %v", err)
+		return out, 0
+	}
+	out.TraceID = t.TraceID
+	trace.Normalize(&t)
+	if rpcErr := r.limits.Validate(&t, len(line)); rpcErr != nil {
+		out.Error = rpcErr.Message
+		return out, 0
+	}
+	r.rates.Convert(&t)
+	if t.SchemaVersion < trace.CurrentSchemaVersion {
+		deprecation.Report(ctx, deprecation.SchemaVersionNotice(t.SchemaVersion, trace.CurrentSchemaVersion))
+	}
+
+	var key dedupKey
+	dedup := r.dedup != nil
+	if dedup {
+		var err error
+		if key, err = r.dedup.key(&t); err != nil {
+			logging.FromContext(ctx).Warn("dedup skipped", "line", lineNo, "err", err)
+			dedup = false
+		} else if rep := r.dedup.lookupExact(key); rep != nil {
+			return duplicateLine(lineNo, t.TraceID, rep), resultsCost(rep.Results)
+		} else {
+			r.dedup.embed(ctx, &t, &key)
+			if rep, sim := r.dedup.lookupNear(key); rep != nil {
+				if out, saved := r.evaluateNearDuplicate(ctx, lineNo, &t, rep, sim, key.cost); out != nil {
+					return out, saved
+				}
+			}
+		}
+	}
+
+	result, err := r.pipeline.EvaluateBatchWithOptions(&t, r.assertions, assertion.BatchOptions{
+		Seed:    r.seed,
+		Context: ctx,
+	})
+	if err != nil {
+		out.Error = fmt.Sprintf("evaluation failed: %v", err)
+		return out, 0
+	}
+	out.Results = result.Results
+	out.TotalCost = result.TotalCost + key.cost
+	if dedup {
+		r.dedup.add(key, out)
+	}
+	return out, 0
+}
+
+// evaluateNearDuplicate evaluates the L1-4 assertions of t, a near duplicate
+// of rep, and reuses rep's L5-6 results: a similar trace can differ in what
+// the deterministic checks see, but is not worth judging again. When t fails
+// an L1-4 assertion its L5-6 results are left out, as a full evaluation
+// gates them. It returns nil when rep has no L5-6 results to reuse, and t is
+// evaluated in full instead.
+func (r *datasetRun) evaluateNearDuplicate(ctx context.Context, lineNo int, t *types.Trace, rep *types.DatasetResultLine, similarity, embedCost float64) (*types.DatasetResultLine, float64) {
+	var deterministic []types.Assertion
+	for i := range r.assertions {
+		if assertion.Layer(&r.assertions[i]) < 5 {
+			deterministic = append(deterministic, r.assertions[i])
+		}
+	}
+	if len(rep.Results) <= len(deterministic) {
+		return nil, 0
+	}
+
+	out := &types.DatasetResultLine{
+		Line:        lineNo,
+		TraceID:     t.TraceID,
+		TotalCost:   embedCost,
+		DuplicateOf: rep.Line,
+		Similarity:  similarity,
+	}
+	result, err := r.pipeline.EvaluateBatchWithOptions(t, deterministic, assertion.BatchOptions{
+		Seed:    r.seed,
+		Context: ctx,
+	})
+	if err != nil {
+		out.Error = fmt.Sprintf("evaluation failed: %v", err)
+		return out, 0
+	}
+	out.TotalCost += result.TotalCost
+	out.Results = result.Results
+	if hasHardFail(result.Results) {
+		return out, 0
+	}
+	reused := rep.Results[len(deterministic):]
+	out.Results = append(out.Results, reused...)
+	return out, resultsCost(reused)
+}
+
+// reindex adds a unique trace evaluated before the checkpoint to the dedup
+// index, so traces after it still match it. It returns the cost of embedding
+// the trace again.
+func (r *datasetRun) reindex(ctx context.Context, line []byte, rep *types.DatasetResultLine) float64 {
+	var t types.Trace
+	if r.limits.CheckNesting(line) != nil || json.Unmarshal(line, &t) != nil {
+		return 0
+	}
+	trace.Normalize(&t)
+	key, err := r.dedup.key(&t)
+	if err != nil {
+		return 0
+	}
+	r.dedup.embed(ctx, &t, &key)
+	r.dedup.add(key, rep)
+	return key.cost
+}
+
+func hasHardFail(results []types.AssertionResult) bool {
+	for i := range results {
+		if results[i].Status == types.StatusHardFail {
+			return true
+		}
+	}
+	return false
+}
+
+func handleEvaluateDataset(pipeline *assertion.Pipeline, templates *assertion.TemplateRegistry, limits trace.Limits, rates trace.CurrencyRates, embedder textEmbedder) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"evaluate_dataset called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.EvaluateDatasetParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				"invalid evaluate_dataset params",
+				types.ErrTypeAssertionError,
+				false,
+				err.Error(),
+			)
+		}
+		if p.Dataset == "" || p.Sink == "" {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				"evaluate_dataset requires dataset and sink",
+				types.ErrTypeAssertionError,
+				false,
+				"Pass the path of a JSONL trace file as dataset and of the results file as sink.",
+			)
+		}
+		var dedup *dedupIndex
+		switch p.Dedup {
+		case "":
+		case dedupExact:
+			dedup = newDedupIndex(p.Dedup, 0, nil)
+		case dedupNear:
+			if embedder == nil {
+				return nil, types.NewRPCError(
+					types.ErrProviderError,
+					"near-duplicate dedup requires an embedding provider",
+					types.ErrTypeProviderError,
+					false,
+					"Configure an embedding provider, or use \"dedup\": \"exact\".",
+				)
+			}
+			if p.NearDupThreshold < 0 || p.NearDupThreshold > 1 {
+				return nil, types.NewRPCError(
+					types.ErrAssertionError,
+					fmt.Sprintf("near_dup_threshold %g outside [0, 1]", p.NearDupThreshold),
+					types.ErrTypeAssertionError,
+					false,
+					"Omit near_dup_threshold for the default of 0.98.",
+				)
+			}
+			dedup = newDedupIndex(p.Dedup, p.NearDupThreshold, embedder)
+		default:
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("unknown dedup mode %q", p.Dedup),
+				types.ErrTypeAssertionError,
+				false,
+				"Use \"exact\" or \"near\", or omit dedup.",
+			)
+		}
+		if err := templates.ExpandAll(p.Assertions); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("template expansion failed: %v", err),
+				types.ErrTypeAssertionError,
+				false,
+				"Register the template with register_template or ATTEST_TEMPLATES, and pass every required param.",
+			)
+		}
+
+		run := &datasetRun{
+			pipeline:      pipeline,
+			assertions:    p.Assertions,
+			dataset:       p.Dataset,
+			sink:          p.Sink,
+			checkpointDir: filepath.Join(cacheDirectory(), "datasets"),
+			restart:       p.Restart,
+			seed:          p.Seed,
+			dedup:         dedup,
+			limits:        limits,
+			rates:         rates,
+		}
+		result, err := run.run(ctx)
+		switch {
+		case errors.Is(err, errSinkIsDataset), errors.Is(err, errSinkExists):
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("evaluate_dataset failed: %v", err),
+				types.ErrTypeEngineError,
+				false,
+				"Write results to a new sink file, or set restart to overwrite an earlier run's sink.",
+			)
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return nil, types.NewRPCError(
+				types.ErrTimeout,
+				"evaluate_dataset interrupted",
+				types.ErrTypeTimeout,
+				true,
+				"Progress was checkpointed; call evaluate_dataset again with the same dataset, assertions, and sink to resume.",
+			)
+		case err != nil:
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("evaluate_dataset failed: %v", err),
+				types.ErrTypeEngineError,
+				false,
+				"Check that the dataset is readable and the sink's directory is writable.",
+			)
+		}
+		session.IncrementAssertions((result.Traces - result.Resumed) * len(p.Assertions))
+		return result, nil
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Dedup modes for evaluate_dataset.
+const (
+	dedupExact = "exact"
+	dedupNear  = "near"
+)
+
+const (
+	// defaultNearDupThreshold is the cosine similarity at which two traces
+	// count as near duplicates.
+	defaultNearDupThreshold = 0.98
+	// nearDupWindow bounds how many recent unique traces a trace is compared
+	// against, keeping near-duplicate detection linear in the dataset size.
+	nearDupWindow = 1000
+)
+
+// textEmbedder embeds text for near-duplicate detection, with the call's
+// estimated cost.
+type textEmbedder interface {
+	EmbedWithCost(ctx context.Context, text string) ([]float32, float64, error)
+}
+
+// dedupIndex remembers the results of unique traces so duplicates can reuse
+// them instead of being evaluated again: all of them for an exact duplicate,
+// the L5-6 ones for a near duplicate.
+type dedupIndex struct {
+	mode      string
+	threshold float64
+	embedder  textEmbedder // set in near mode
+
+	exact map[[sha256.Size]byte]*types.DatasetResultLine
+	near  []nearDupEntry // the most recent nearDupWindow unique traces
+}
+
+type nearDupEntry struct {
+	vec  []float32
+	line *types.DatasetResultLine
+}
+
+func newDedupIndex(mode string, threshold float64, embedder textEmbedder) *dedupIndex {
+	if threshold <= 0 {
+		threshold = defaultNearDupThreshold
+	}
+	return &dedupIndex{
+		mode:      mode,
+		threshold: threshold,
+		embedder:  embedder,
+		exact:     make(map[[sha256.Size]byte]*types.DatasetResultLine),
+	}
+}
+
+// dedupKey identifies a trace for dedup: exact hashes everything but the
+// trace ID; vec embeds its input and output in near mode, at cost.
+type dedupKey struct {
+	exact [sha256.Size]byte
+	vec   []float32
+	cost  float64
+}
+
+// key computes t's exact dedup key.
+func (d *dedupIndex) key(t *types.Trace) (dedupKey, error) {
+	var k dedupKey
+	anon := *t
+	anon.TraceID = ""
+	data, err := json.Marshal(&anon)
+	if err != nil {
+		return k, fmt.Errorf("hash trace: %w", err)
+	}
+	k.exact = sha256.Sum256(data)
+	return k, nil
+}
+
+// embed sets k's vector in near mode, adding the embedding's cost to k. A
+// failed embedding is logged and leaves vec nil, so the trace is only
+// matched exactly.
+func (d *dedupIndex) embed(ctx context.Context, t *types.Trace, k *dedupKey) {
+	if d.mode != dedupNear {
+		return
+	}
+	vec, cost, err := d.embedder.EmbedWithCost(ctx, string(t.Input)+"\n"+string(t.Output))
+	if err != nil {
+		logging.FromContext(ctx).Warn("near-duplicate embedding failed", "trace_id", t.TraceID, "err", err)
+		return
+	}
+	k.vec, k.cost = vec, k.cost+cost
+}
+
+// lookupExact returns the unique trace k is identical to, or nil.
+func (d *dedupIndex) lookupExact(k dedupKey) *types.DatasetResultLine {
+	return d.exact[k.exact]
+}
+
+// lookupNear returns the unique trace most similar to k at or above the
+// threshold, with the similarity, or nil.
+func (d *dedupIndex) lookupNear(k dedupKey) (*types.DatasetResultLine, float64) {
+	if k.vec == nil {
+		return nil, 0
+	}
+	var best *types.DatasetResultLine
+	bestSim := d.threshold
+	for _, e := range d.near {
+		sim, err := embedding.CosineSimilarity(k.vec, e.vec)
+		if err == nil && sim >= bestSim {
+			best, bestSim = e.line, sim
+		}
+	}
+	// Rounding can put the similarity of equal vectors just above 1.
+	return best, min(bestSim, 1)
+}
+
+// add records the results of a unique trace.
+func (d *dedupIndex) add(k dedupKey, line *types.DatasetResultLine) {
+	d.exact[k.exact] = line
+	if k.vec != nil {
+		if len(d.near) == nearDupWindow {
+			d.near = d.near[1:]
+		}
+		d.near = append(d.near, nearDupEntry{vec: k.vec, line: line})
+	}
+}
+
+// duplicateLine fans rep's results out to an exact duplicate, which costs
+// nothing.
+func duplicateLine(lineNo int, traceID string, rep *types.DatasetResultLine) *types.DatasetResultLine {
+	return &types.DatasetResultLine{
+		Line:        lineNo,
+		TraceID:     traceID,
+		Results:     rep.Results,
+		DuplicateOf: rep.Line,
+	}
+}
+
+// resultsCost sums the cost of results: what a duplicate reusing them saved.
+func resultsCost(results []types.AssertionResult) float64 {
+	var cost float64
+	for i := range results {
+		cost += results[i].Cost
+	}
+	return cost
+}
+
+// loadUnique reads the unique traces' result lines from the first n bytes of
+// the sink, to rebuild the index when a run resumes.
+func loadUnique(sink string, n int64) (map[int]*types.DatasetResultLine, error) {
+	f, err := os.Open(sink)
+	if err != nil {
+		return nil, fmt.Errorf("open sink: %w", err)
+	}
+	defer f.Close()
+	unique := make(map[int]*types.DatasetResultLine)
+	reader := bufio.NewReader(io.LimitReader(f, n))
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			var line types.DatasetResultLine
+			if json.Unmarshal(data, &line) == nil && line.Error == "" && line.DuplicateOf == 0 {
+				unique[line.Line] = &line
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return unique, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read sink: %w", err)
+		}
+	}
+}
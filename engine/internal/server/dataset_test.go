@@ -0,0 +1,378 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func newDatasetRun(t *testing.T) *datasetRun {
+	t.Helper()
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "traces.jsonl")
+	lines := []string{
+		`{"trace_id":"trc_1","output":{"message":"hello there"}}`,
+		``,
+		`{"trace_id":"trc_2","output":{"message":"goodbye"}}`,
+		`not json`,
+		`{"trace_id":"trc_3","output":{"message":"hello again"}}`,
+	}
+	if err := os.WriteFile(dataset, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &datasetRun{
+		pipeline: assertion.NewPipeline(assertion.NewRegistry()),
+		assertions: []types.Assertion{{
+			AssertionID: "greets",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hello"}`),
+		}},
+		dataset:       dataset,
+		sink:          filepath.Join(dir, "results.jsonl"),
+		checkpointDir: filepath.Join(dir, "checkpoints"),
+	}
+}
+
+func readSink(t *testing.T, path string) []types.DatasetResultLine {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out []types.DatasetResultLine
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var l types.DatasetResultLine
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			t.Fatalf("sink line %q: %v", sc.Text(), err)
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func TestDatasetRun_StreamsResults(t *testing.T) {
+	run := newDatasetRun(t)
+	result, err := run.run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Traces != 4 || result.Failed != 1 || result.Errors != 1 || result.Resumed != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	lines := readSink(t, run.sink)
+	if len(lines) != 4 {
+		t.Fatalf("sink has %d lines, want 4", len(lines))
+	}
+	if lines[1].Line != 3 || lines[1].TraceID != "trc_2" || lines[1].Results[0].Status != types.StatusHardFail {
+		t.Errorf("line for trc_2 = %+v", lines[1])
+	}
+	if lines[2].Line != 4 || lines[2].Error == "" {
+		t.Errorf("invalid line = %+v", lines[2])
+	}
+
+	// A finished run is not evaluated again.
+	again, err := run.run(context.Background())
+	if err != nil || again.Resumed != 4 || again.Traces != 4 || len(readSink(t, run.sink)) != 4 {
+		t.Errorf("second run = %+v, %v", again, err)
+	}
+}
+
+func TestDatasetRun_ResumesAfterInterruption(t *testing.T) {
+	run := newDatasetRun(t)
+	if _, err := run.run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	full := readSink(t, run.sink)
+
+	// Simulate a crash after line 1 was checkpointed and part of another line
+	// was written.
+	hash, err := run.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := json.Marshal(full[0])
+	if err := os.WriteFile(run.sink, append(append(first, '\n'), `{"line":3,"trace_`...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cp := &datasetCheckpoint{DatasetHash: hash, Sink: run.sink, NextLine: 1, SinkBytes: int64(len(first) + 1), Traces: 1}
+	if err := run.saveCheckpoint(cp); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := run.run(context.Background())
+	if err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	if result.Resumed != 1 || result.Traces != 4 || result.Failed != 1 || result.Errors != 1 {
+		t.Errorf("resumed result = %+v", result)
+	}
+	lines := readSink(t, run.sink)
+	if len(lines) != 4 || lines[0].TraceID != "trc_1" || lines[3].TraceID != "trc_3" {
+		t.Errorf("resumed sink = %+v", lines)
+	}
+
+	// restart ignores the checkpoint.
+	run.restart = true
+	if result, err := run.run(context.Background()); err != nil || result.Resumed != 0 || result.Traces != 4 {
+		t.Errorf("restarted run = %+v, %v", result, err)
+	}
+}
+
+func TestDatasetRun_ProtectsExistingFiles(t *testing.T) {
+	run := newDatasetRun(t)
+	data, err := os.ReadFile(run.dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A sink that is the dataset, even by another name, is refused.
+	run.sink = run.dataset
+	run.restart = true
+	if _, err := run.run(context.Background()); !errors.Is(err, errSinkIsDataset) {
+		t.Errorf("err = %v, want errSinkIsDataset", err)
+	}
+	link := filepath.Join(filepath.Dir(run.dataset), "link.jsonl")
+	if err := os.Link(run.dataset, link); err == nil {
+		run.sink = link
+		if _, err := run.run(context.Background()); !errors.Is(err, errSinkIsDataset) {
+			t.Errorf("hard link: err = %v, want errSinkIsDataset", err)
+		}
+	}
+	if got, _ := os.ReadFile(run.dataset); string(got) != string(data) {
+		t.Fatal("dataset was modified")
+	}
+
+	// An unrelated existing sink is kept unless restart is set.
+	run.sink = filepath.Join(filepath.Dir(run.dataset), "existing.jsonl")
+	run.restart = false
+	if err := os.WriteFile(run.sink, []byte("keep me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run.run(context.Background()); !errors.Is(err, errSinkExists) {
+		t.Errorf("err = %v, want errSinkExists", err)
+	}
+	if got, _ := os.ReadFile(run.sink); string(got) != "keep me\n" {
+		t.Errorf("existing sink = %q, want it untouched", got)
+	}
+	run.restart = true
+	if result, err := run.run(context.Background()); err != nil || result.Traces != 4 {
+		t.Errorf("restarted run = %+v, %v", result, err)
+	}
+}
+
+func TestDatasetRun_HashIgnoresSpecSerialization(t *testing.T) {
+	run := newDatasetRun(t)
+	before, err := run.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.assertions[0].Spec = json.RawMessage(`{ "value": "hello", "check": "contains", "target": "output.message" }`)
+	if after, _ := run.hash(); after != before {
+		t.Errorf("hash changed with key order: %s vs %s", before, after)
+	}
+	run.assertions[0].Spec = json.RawMessage(`{"target":"output.message","check":"contains","value":"bye"}`)
+	if after, _ := run.hash(); after == before {
+		t.Error("hash unchanged after the spec changed")
+	}
+}
+
+func TestDatasetRun_CancelledCheckpoints(t *testing.T) {
+	run := newDatasetRun(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := run.run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("run with cancelled context = %v, want context.Canceled", err)
+	}
+	hash, _ := run.hash()
+	if _, err := os.Stat(run.checkpointPath(hash)); err != nil {
+		t.Errorf("no checkpoint after cancellation: %v", err)
+	}
+}
+
+// fakeTextEmbedder embeds text by whether it mentions "hello" or "hi", at
+// 0.001 a call.
+type fakeTextEmbedder struct{ calls int }
+
+func (f *fakeTextEmbedder) EmbedWithCost(_ context.Context, text string) ([]float32, float64, error) {
+	f.calls++
+	switch {
+	case strings.Contains(text, "hello"):
+		return []float32{1, 0.01}, 0.001, nil
+	case strings.Contains(text, "hi"):
+		return []float32{1, 0.02}, 0.001, nil
+	}
+	return []float32{0, 1}, 0.001, nil
+}
+
+// countingJudge passes every trace at a cost of 0.01, counting its calls.
+type countingJudge struct{ calls int }
+
+func (j *countingJudge) Evaluate(_ *types.Trace, a *types.Assertion) *types.AssertionResult {
+	j.calls++
+	return &types.AssertionResult{AssertionID: a.AssertionID, Status: types.StatusPass, Score: 1, Cost: 0.01}
+}
+
+func writeDataset(t *testing.T, run *datasetRun, lines ...string) {
+	t.Helper()
+	if err := os.WriteFile(run.dataset, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDatasetRun_ExactDedup(t *testing.T) {
+	run := newDatasetRun(t)
+	writeDataset(t, run,
+		`{"trace_id":"trc_1","output":{"message":"hello"}}`,
+		`{"trace_id":"trc_2","output":{"message":"goodbye"}}`,
+		`{"trace_id":"trc_3","output":{"message":"hello"}}`,
+		`{"trace_id":"trc_4","output":{"message":"goodbye"}}`,
+	)
+	run.dedup = newDedupIndex(dedupExact, 0, nil)
+	result, err := run.run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Traces != 4 || result.Failed != 2 || result.Dedup == nil || result.Dedup.Duplicates != 2 || result.Dedup.NearDuplicates != 0 {
+		t.Errorf("result = %+v, dedup %+v", result, result.Dedup)
+	}
+	lines := readSink(t, run.sink)
+	if lines[2].DuplicateOf != 1 || lines[2].TraceID != "trc_3" || lines[2].Results[0].Status != types.StatusPass {
+		t.Errorf("duplicate line = %+v", lines[2])
+	}
+	if lines[3].DuplicateOf != 2 || lines[3].Results[0].Status != types.StatusHardFail {
+		t.Errorf("duplicate line = %+v", lines[3])
+	}
+
+	// After resuming from line 2, line 3 still matches line 1.
+	hash, _ := run.hash()
+	first, _ := json.Marshal(lines[0])
+	second, _ := json.Marshal(lines[1])
+	sink := append(append(append(first, '\n'), second...), '\n')
+	if err := os.WriteFile(run.sink, sink, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run.dedup = newDedupIndex(dedupExact, 0, nil)
+	if err := run.saveCheckpoint(&datasetCheckpoint{DatasetHash: hash, Sink: run.sink, NextLine: 2, SinkBytes: int64(len(sink)), Traces: 2, Failed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	result, err = run.run(context.Background())
+	if err != nil || result.Resumed != 2 || result.Dedup.Duplicates != 2 {
+		t.Errorf("resumed = %+v (dedup %+v), %v", result, result.Dedup, err)
+	}
+}
+
+func TestDatasetRun_NearDedup(t *testing.T) {
+	run := newDatasetRun(t)
+	writeDataset(t, run,
+		`{"trace_id":"trc_1","output":{"message":"hello"}}`,
+		`{"trace_id":"trc_2","output":{"message":"hi"}}`,
+		`{"trace_id":"trc_3","output":{"message":"goodbye"}}`,
+		`{"trace_id":"trc_4","output":{"message":"hello"}}`,
+	)
+	judge := &countingJudge{}
+	registry := assertion.NewRegistry()
+	registry.Register(types.TypeLLMJudge, judge)
+	run.pipeline = assertion.NewPipeline(registry)
+	run.assertions = append(run.assertions, types.Assertion{
+		AssertionID: "helpful",
+		Type:        types.TypeLLMJudge,
+		Spec:        json.RawMessage(`{"rubric":"helpfulness"}`),
+	})
+	embedder := &fakeTextEmbedder{}
+	run.dedup = newDedupIndex(dedupNear, 0, embedder)
+	result, err := run.run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Dedup.Duplicates != 2 || result.Dedup.NearDuplicates != 1 {
+		t.Errorf("dedup = %+v", result.Dedup)
+	}
+	// trc_1 is judged; trc_2 and trc_3 fail the content check, which gates
+	// the judgment; trc_4 is an exact duplicate.
+	if judge.calls != 1 {
+		t.Errorf("judge calls = %d, want 1", judge.calls)
+	}
+	// The exact duplicate is matched before embedding.
+	if embedder.calls != 3 {
+		t.Errorf("embedding calls = %d, want 3", embedder.calls)
+	}
+	if diff := result.TotalCost - 0.013; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("total cost = %v, want one judgment and three embeddings", result.TotalCost)
+	}
+	if diff := result.Dedup.SavedCost - 0.01; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("saved cost = %v, want the exact duplicate's judgment", result.Dedup.SavedCost)
+	}
+
+	lines := readSink(t, run.sink)
+	near := lines[1]
+	if near.DuplicateOf != 1 || near.Similarity < 0.98 || near.Similarity >= 1 {
+		t.Errorf("near duplicate = %+v", near)
+	}
+	// "hi" does not contain "hello": its own content result, not trc_1's pass.
+	if len(near.Results) != 1 || near.Results[0].AssertionID != "greets" || near.Results[0].Status != types.StatusHardFail {
+		t.Errorf("near duplicate results = %+v, want its own content failure", near.Results)
+	}
+	if lines[2].DuplicateOf != 0 {
+		t.Errorf("distinct trace marked duplicate: %+v", lines[2])
+	}
+	if lines[3].DuplicateOf != 1 || lines[3].Similarity != 0 || len(lines[3].Results) != 2 {
+		t.Errorf("exact duplicate = %+v", lines[3])
+	}
+}
+
+func TestDatasetRun_NearDedupReusesJudgments(t *testing.T) {
+	run := newDatasetRun(t)
+	writeDataset(t, run,
+		`{"trace_id":"trc_1","output":{"message":"hello"}}`,
+		`{"trace_id":"trc_2","output":{"message":"hello hi"}}`,
+	)
+	judge := &countingJudge{}
+	registry := assertion.NewRegistry()
+	registry.Register(types.TypeLLMJudge, judge)
+	run.pipeline = assertion.NewPipeline(registry)
+	run.assertions = []types.Assertion{
+		{AssertionID: "helpful", Type: types.TypeLLMJudge, Spec: json.RawMessage(`{"rubric":"helpfulness"}`)},
+		{AssertionID: "greets", Type: types.TypeContent, Spec: json.RawMessage(`{"target":"output.message","check":"contains","value":"hi"}`)},
+	}
+	run.dedup = newDedupIndex(dedupNear, 0, &fakeTextEmbedder{})
+	result, err := run.run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	lines := readSink(t, run.sink)
+	// trc_1 fails "hi", so no judgment to reuse: trc_2 is evaluated in full.
+	if judge.calls != 1 || lines[1].DuplicateOf != 0 || result.Dedup.Duplicates != 0 {
+		t.Errorf("judge calls = %d, line = %+v", judge.calls, lines[1])
+	}
+
+	// A third trace near trc_2, which was judged, reuses that judgment.
+	writeDataset(t, run,
+		`{"trace_id":"trc_1","output":{"message":"hello"}}`,
+		`{"trace_id":"trc_2","output":{"message":"hello hi"}}`,
+		`{"trace_id":"trc_3","output":{"message":"hello hi!"}}`,
+	)
+	judge.calls = 0
+	run.restart = true
+	run.dedup = newDedupIndex(dedupNear, 0, &fakeTextEmbedder{})
+	if _, err := run.run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	lines = readSink(t, run.sink)
+	near := lines[2]
+	if judge.calls != 1 || near.DuplicateOf != 2 || len(near.Results) != 2 {
+		t.Fatalf("judge calls = %d, near duplicate = %+v", judge.calls, near)
+	}
+	if near.Results[0].AssertionID != "greets" || near.Results[0].Status != types.StatusPass || near.Results[1].AssertionID != "helpful" {
+		t.Errorf("near duplicate results = %+v, want its own content result then the reused judgment", near.Results)
+	}
+}
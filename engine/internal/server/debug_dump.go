@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/debugdump"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// defaultDebugTraces is how many recent traces a debug bundle includes by default.
+const defaultDebugTraces = 10
+
+// recentBatches keeps the traces and assertion specs of the last few
+// evaluate_batch calls for debug bundles. Retention is opt-in: capacity is set
+// by ATTEST_DEBUG_RECENT_TRACES (default 0, nothing retained), since every
+// retained trace stays resident until it is evicted.
+type recentBatches struct {
+	mu      sync.Mutex
+	max     int
+	batches []recentBatch // oldest first
+}
+
+type recentBatch struct {
+	trace      *types.Trace
+	assertions []types.Assertion
+}
+
+func newRecentBatches(max int) *recentBatches {
+	return &recentBatches{max: max}
+}
+
+// add records a batch, dropping the oldest beyond capacity.
+func (r *recentBatches) add(trace *types.Trace, assertions []types.Assertion) {
+	if r == nil || r.max <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, recentBatch{trace: trace, assertions: assertions})
+	if over := len(r.batches) - r.max; over > 0 {
+		r.batches = append(r.batches[:0], r.batches[over:]...)
+	}
+}
+
+// snapshot returns up to n most recent traces (oldest first) and the specs of
+// every assertion they were evaluated against, latest spec per assertion ID.
+func (r *recentBatches) snapshot(n int) ([]*types.Trace, []types.Assertion) {
+	if r == nil {
+		return nil, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batches := r.batches
+	if len(batches) > n {
+		batches = batches[len(batches)-n:]
+	}
+	traces := make([]*types.Trace, 0, len(batches))
+	index := make(map[string]int)
+	var assertions []types.Assertion
+	for _, b := range batches {
+		traces = append(traces, b.trace)
+		for _, a := range b.assertions {
+			if i, ok := index[a.AssertionID]; ok {
+				assertions[i] = a
+				continue
+			}
+			index[a.AssertionID] = len(assertions)
+			assertions = append(assertions, a)
+		}
+	}
+	return traces, assertions
+}
+
+// debugDumpDir returns the directory debug_dump writes bundles to:
+// ATTEST_DEBUG_DUMP_DIR, else "dumps" under the cache directory.
+func debugDumpDir() string {
+	if dir := os.Getenv("ATTEST_DEBUG_DUMP_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(cacheDirectory(), "dumps")
+}
+
+// debugSources collects bundle inputs that do not depend on the caller.
+func debugSources(store *cache.Store, version string, startedAt time.Time) debugdump.Sources {
+	src := debugdump.Sources{
+		EngineVersion: version,
+		Environ:       os.Environ(),
+		StartedAt:     startedAt,
+	}
+	if store != nil {
+		if stats, err := store.Embeddings().Stats(); err == nil {
+			src.EmbeddingCache = stats
+		}
+		if stats, err := store.Judge().Stats(); err == nil {
+			src.JudgeCache = stats
+		}
+	}
+	return src
+}
+
+// handleDebugDump builds a debug bundle from recent engine activity. Without
+// confirm it only returns the manifest, so the user can review exactly what
+// would be shared before the bundle is written.
+func handleDebugDump(recent *recentBatches, logs *logging.RingWriter, store *cache.Store, startedAt time.Time) Handler {
+	return func(_ context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"debug_dump called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.DebugDumpParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrEngineError,
+					"invalid debug_dump params",
+					types.ErrTypeEngineError,
+					false,
+					err.Error(),
+				)
+			}
+		}
+		if p.Confirm && p.OutputPath == "" {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				"debug_dump requires output_path when confirm is true",
+				types.ErrTypeEngineError,
+				false,
+				"set output_path to the tar.gz file to write",
+			)
+		}
+		// Bundles go only to the dump directory, never to a caller-chosen path.
+		if p.OutputPath != "" && (filepath.Base(p.OutputPath) != p.OutputPath || !filepath.IsLocal(p.OutputPath)) {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("debug_dump output_path %q is not a file name", p.OutputPath),
+				types.ErrTypeEngineError,
+				false,
+				"set output_path to a file name such as attest-debug.tar.gz; bundles are written to ATTEST_DEBUG_DUMP_DIR",
+			)
+		}
+		maxTraces := p.MaxTraces
+		if maxTraces <= 0 {
+			maxTraces = defaultDebugTraces
+		}
+
+		src := debugSources(store, engineVersion, startedAt)
+		src.Traces, src.Assertions = recent.snapshot(maxTraces)
+		if logs != nil {
+			src.Logs = logs.Lines()
+		}
+		bundle, err := debugdump.Build(src)
+		if err != nil {
+			return nil, types.NewRPCError(types.ErrEngineError, err.Error(), types.ErrTypeEngineError, false, "")
+		}
+
+		result := &types.DebugDumpResult{Manifest: bundle.Manifest()}
+		if !p.Confirm {
+			return result, nil
+		}
+		dir := debugDumpDir()
+		path := filepath.Join(dir, p.OutputPath)
+		err = os.MkdirAll(dir, 0o700)
+		if err == nil {
+			err = bundle.WriteFile(path)
+		}
+		if err != nil {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("debug_dump failed: %v", err),
+				types.ErrTypeEngineError,
+				false,
+				"check that ATTEST_DEBUG_DUMP_DIR is writable",
+			)
+		}
+		result.Written = true
+		result.Path = path
+		return result, nil
+	}
+}
+
+// BuildDebugBundle assembles a debug bundle outside a running engine for
+// `attest-engine debug dump`: configuration, cache statistics, version info,
+// and optionally an assertion suite, traces from tracesDir (last maxTraces),
+// and the tail of a log file.
+func BuildDebugBundle(logger *slog.Logger, version, suitePath, tracesDir, logPath string, maxTraces, maxLogLines int) (*debugdump.Bundle, error) {
+	store := openCacheStore(logger)
+	if store != nil {
+		defer store.Close()
+	}
+	src := debugSources(store, version, time.Time{})
+
+	if suitePath != "" {
+		assertions, err := loadSuite(suitePath)
+		if err != nil {
+			return nil, err
+		}
+		src.Assertions = assertions
+	}
+	if tracesDir != "" {
+		traces, err := loadTraces(tracesDir, buildTraceLimits(logger))
+		if err != nil {
+			return nil, err
+		}
+		if len(traces) > maxTraces {
+			traces = traces[len(traces)-maxTraces:]
+		}
+		src.Traces = traces
+	}
+	if logPath != "" {
+		lines, err := tailLines(logPath, maxLogLines)
+		if err != nil {
+			return nil, err
+		}
+		src.Logs = lines
+	}
+	return debugdump.Build(src)
+}
+
+// tailLines returns the last n lines of the file at path.
+func tailLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read log file: %w", err)
+	}
+	defer f.Close()
+	ring := logging.NewRingWriter(n)
+	if _, err := ring.ReadFrom(f); err != nil {
+		return nil, fmt.Errorf("read log file: %w", err)
+	}
+	return ring.Lines(), nil
+}
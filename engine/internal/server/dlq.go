@@ -0,0 +1,327 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+const (
+	// dlqMaxPending bounds the in-memory retry queue.
+	dlqMaxPending = 1000
+	// dlqRetryInterval is how often queued writes are retried.
+	dlqRetryInterval = 2 * time.Second
+	// History writes that fail this many retries are spilled to disk;
+	// notifications are dropped.
+	dlqHistoryAttempts      = 5
+	dlqNotificationAttempts = 3
+	// dlqMaxSpillBytes bounds the spillover file; history writes beyond it
+	// are dropped.
+	dlqMaxSpillBytes = 16 << 20
+)
+
+// historyWrite is one assertion_history row awaiting a retry.
+type historyWrite struct {
+	Namespace     string    `json:"namespace,omitempty"`
+	TraceID       string    `json:"trace_id"`
+	AssertionID   string    `json:"assertion_id"`
+	AssertionType string    `json:"assertion_type"`
+	Score         float64   `json:"score"`
+	Status        string    `json:"status"`
+	Cost          float64   `json:"cost,omitempty"`
+	SampleRate    float64   `json:"sample_rate,omitempty"`
+	At            time.Time `json:"at"`
+}
+
+// dlqEntry is a queued history write or encoded notification.
+type dlqEntry struct {
+	history      *historyWrite
+	notification []byte
+	attempts     int
+}
+
+// deadLetterQueue retries history writes and notification writes that
+// failed, e.g. under SQLite lock contention, instead of losing them. History
+// writes that keep failing, or that overflow the queue, are spilled to an
+// NDJSON file and replayed once writes succeed again or the engine restarts;
+// notifications are only retried in memory, since a later session could not
+// use them.
+type deadLetterQueue struct {
+	history   *cache.HistoryStore // nil without a history store
+	write     func([]byte) error  // sends an encoded notification
+	spillPath string              // "" disables spillover
+	logger    *slog.Logger
+
+	mu                 sync.Mutex
+	pending            []*dlqEntry
+	historyCounts      types.DeadLetterCounts
+	notificationCounts types.DeadLetterCounts
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newDeadLetterQueue starts a queue that retries in the background until
+// close. History writes spilled by an earlier run are queued for replay.
+func newDeadLetterQueue(history *cache.HistoryStore, write func([]byte) error, spillPath string, logger *slog.Logger) *deadLetterQueue {
+	q := &deadLetterQueue{
+		history:   history,
+		write:     write,
+		spillPath: spillPath,
+		logger:    logger,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	q.replaySpill()
+	go q.run()
+	return q
+}
+
+// recordHistory writes a history row, queueing it for retry on failure.
+// cost is the evaluation's cost in USD, and sampleRate the assertion's
+// sample_rate, 1 when it is not sampled.
+func (q *deadLetterQueue) recordHistory(traceID, assertionID, assertionType string, score float64, status string, cost, sampleRate float64) {
+	w := &historyWrite{Namespace: q.history.Namespace(), TraceID: traceID, AssertionID: assertionID, AssertionType: assertionType, Score: score, Status: status, Cost: cost, SampleRate: sampleRate, At: time.Now()}
+	err := q.history.RecordInNamespace(w.Namespace, w.TraceID, w.AssertionID, w.AssertionType, w.Score, w.Status, w.Cost, w.SampleRate, w.At)
+	if err == nil {
+		return
+	}
+	q.logger.Warn("history write failed, queued for retry", "assertion_id", assertionID, "err", err)
+	q.mu.Lock()
+	q.historyCounts.Failed++
+	q.mu.Unlock()
+	q.enqueue(&dlqEntry{history: w})
+}
+
+// addNotification queues an encoded notification whose write failed.
+func (q *deadLetterQueue) addNotification(data []byte, err error) {
+	q.logger.Warn("notification write failed, queued for retry", "err", err)
+	q.mu.Lock()
+	q.notificationCounts.Failed++
+	q.mu.Unlock()
+	q.enqueue(&dlqEntry{notification: data})
+}
+
+func (q *deadLetterQueue) enqueue(e *dlqEntry) {
+	q.mu.Lock()
+	if len(q.pending) < dlqMaxPending {
+		q.pending = append(q.pending, e)
+		q.mu.Unlock()
+		return
+	}
+	q.mu.Unlock()
+	q.giveUp([]*dlqEntry{e})
+}
+
+// giveUp spills history writes to disk and drops notifications.
+func (q *deadLetterQueue) giveUp(entries []*dlqEntry) {
+	var writes []*historyWrite
+	var dropped int64
+	for _, e := range entries {
+		if e.history != nil {
+			writes = append(writes, e.history)
+		} else {
+			dropped++
+		}
+	}
+	spilled, err := q.spill(writes)
+	if err != nil {
+		q.logger.Error("history spillover failed, writes dropped", "count", len(writes)-spilled, "err", err)
+	}
+	q.mu.Lock()
+	q.notificationCounts.Dropped += dropped
+	q.historyCounts.Spilled += int64(spilled)
+	q.historyCounts.Dropped += int64(len(writes) - spilled)
+	q.mu.Unlock()
+}
+
+func (q *deadLetterQueue) run() {
+	defer close(q.done)
+	ticker := time.NewTicker(dlqRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.retry(true)
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// retry attempts every queued write once. With replay set, a round that
+// recovers history writes and empties the queue brings spilled writes back
+// for the next round, since the database is evidently accepting writes.
+func (q *deadLetterQueue) retry(replay bool) {
+	q.mu.Lock()
+	batch := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	var failed, exhausted []*dlqEntry
+	var historyOK, notificationOK int64
+	for _, e := range batch {
+		var err error
+		if e.history != nil {
+			w := e.history
+			err = q.history.RecordInNamespace(w.Namespace, w.TraceID, w.AssertionID, w.AssertionType, w.Score, w.Status, w.Cost, w.SampleRate, w.At)
+		} else {
+			err = q.write(e.notification)
+		}
+		switch {
+		case err == nil && e.history != nil:
+			historyOK++
+		case err == nil:
+			notificationOK++
+		default:
+			e.attempts++
+			limit := dlqNotificationAttempts
+			if e.history != nil {
+				limit = dlqHistoryAttempts
+			}
+			if e.attempts >= limit {
+				exhausted = append(exhausted, e)
+			} else {
+				failed = append(failed, e)
+			}
+		}
+	}
+
+	q.mu.Lock()
+	q.historyCounts.Recovered += historyOK
+	q.notificationCounts.Recovered += notificationOK
+	q.pending = append(failed, q.pending...)
+	empty := len(q.pending) == 0
+	q.mu.Unlock()
+
+	if len(exhausted) > 0 {
+		q.giveUp(exhausted)
+	} else if replay && empty && historyOK > 0 {
+		q.replaySpill()
+	}
+}
+
+// spill appends writes to the spillover file, returning how many it wrote.
+func (q *deadLetterQueue) spill(writes []*historyWrite) (int, error) {
+	if len(writes) == 0 {
+		return 0, nil
+	}
+	if q.spillPath == "" {
+		return 0, errors.New("no spillover file in memory cache mode")
+	}
+	var size int64
+	if info, err := os.Stat(q.spillPath); err == nil {
+		size = info.Size()
+	}
+	var buf bytes.Buffer
+	n := 0
+	for _, w := range writes {
+		line, err := json.Marshal(w)
+		if err != nil {
+			continue
+		}
+		if size+int64(buf.Len()+len(line)+1) > dlqMaxSpillBytes {
+			break
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		n++
+	}
+	f, err := os.OpenFile(q.spillPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("open spillover: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("write spillover: %w", err)
+	}
+	if n < len(writes) {
+		return n, fmt.Errorf("spillover file reached %d bytes", dlqMaxSpillBytes)
+	}
+	return n, nil
+}
+
+// replaySpill moves spilled history writes back into the queue, leaving
+// those that do not fit in the file.
+func (q *deadLetterQueue) replaySpill() {
+	if q.spillPath == "" || q.history == nil {
+		return
+	}
+	data, err := os.ReadFile(q.spillPath)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	if err := os.Remove(q.spillPath); err != nil {
+		q.logger.Warn("cannot replay history spillover", "path", q.spillPath, "err", err)
+		return
+	}
+
+	var writes []*historyWrite
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var w historyWrite
+		if json.Unmarshal(sc.Bytes(), &w) == nil {
+			writes = append(writes, &w)
+		}
+	}
+	q.mu.Lock()
+	room := min(dlqMaxPending-len(q.pending), len(writes))
+	for _, w := range writes[:room] {
+		q.pending = append(q.pending, &dlqEntry{history: w})
+	}
+	q.mu.Unlock()
+	if rest := writes[room:]; len(rest) > 0 {
+		if _, err := q.spill(rest); err != nil {
+			q.logger.Error("history spillover failed, writes dropped", "err", err)
+		}
+	}
+	if room > 0 {
+		q.logger.Info("replaying spilled history writes", "count", room)
+	}
+}
+
+// close stops retrying, makes a last attempt at the queued writes, and
+// spills the history writes still failing.
+func (q *deadLetterQueue) close() {
+	q.closeOnce.Do(func() {
+		close(q.stop)
+		<-q.done
+		q.retry(false)
+		q.mu.Lock()
+		rest := q.pending
+		q.pending = nil
+		q.mu.Unlock()
+		q.giveUp(rest)
+	})
+}
+
+// metrics returns the queue's counters.
+func (q *deadLetterQueue) metrics() types.DeadLetterMetrics {
+	q.mu.Lock()
+	m := types.DeadLetterMetrics{History: q.historyCounts, Notifications: q.notificationCounts}
+	for _, e := range q.pending {
+		if e.history != nil {
+			m.History.Pending++
+		} else {
+			m.Notifications.Pending++
+		}
+	}
+	q.mu.Unlock()
+	if q.spillPath != "" {
+		if info, err := os.Stat(q.spillPath); err == nil {
+			m.SpillBytes = info.Size()
+		}
+	}
+	return m
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func openHistory(t *testing.T, path string) *cache.Store {
+	t.Helper()
+	store, err := cache.OpenStore(path, cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	return store
+}
+
+func TestDeadLetterQueue_SpillsAndReplaysHistory(t *testing.T) {
+	dir := t.TempDir()
+	spill := filepath.Join(dir, "dead_letter.ndjson")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// A closed store rejects every write.
+	broken := openHistory(t, filepath.Join(dir, "broken.db"))
+	broken.Close()
+	q := newDeadLetterQueue(broken.History(), nil, spill, logger)
+	q.recordHistory("trc_1", "a1", "constraint", 0.5, types.StatusPass, 0, 1)
+	q.recordHistory("trc_2", "a1", "constraint", 0.7, types.StatusPass, 0, 1)
+
+	m := q.metrics()
+	if m.History.Failed != 2 || m.History.Pending != 2 {
+		t.Fatalf("after failures: %+v", m.History)
+	}
+	q.close()
+	m = q.metrics()
+	if m.History.Spilled != 2 || m.History.Pending != 0 || m.SpillBytes == 0 {
+		t.Fatalf("after close: %+v, spill %d bytes", m.History, m.SpillBytes)
+	}
+
+	// The next engine replays the spilled writes into a healthy store.
+	healthy := openHistory(t, filepath.Join(dir, "attest.db"))
+	defer healthy.Close()
+	q = newDeadLetterQueue(healthy.History(), nil, spill, logger)
+	defer q.close()
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Errorf("spill file still present after replay: %v", err)
+	}
+	q.retry(true)
+	if m := q.metrics(); m.History.Recovered != 2 || m.History.Pending != 0 {
+		t.Fatalf("after replay: %+v", m.History)
+	}
+	mean, _, count, err := healthy.History().Stats("a1")
+	if err != nil || count != 2 || mean < 0.59 || mean > 0.61 {
+		t.Errorf("replayed history: mean %v, count %d, err %v", mean, count, err)
+	}
+}
+
+func TestDeadLetterQueue_RetriesNotifications(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	fails := 1
+	var written [][]byte
+	write := func(data []byte) error {
+		if fails > 0 {
+			fails--
+			return errors.New("broken pipe")
+		}
+		written = append(written, data)
+		return nil
+	}
+	q := newDeadLetterQueue(nil, write, "", logger)
+	defer q.close()
+
+	q.addNotification([]byte(`{"method":"drift_alert"}`), errors.New("broken pipe"))
+	q.retry(true) // fails again
+	q.retry(true)
+	if len(written) != 1 {
+		t.Fatalf("written %d notifications, want 1", len(written))
+	}
+	if m := q.metrics(); m.Notifications.Failed != 1 || m.Notifications.Recovered != 1 || m.Notifications.Pending != 0 {
+		t.Errorf("metrics = %+v", m.Notifications)
+	}
+
+	// Notifications that keep failing are dropped, never spilled.
+	fails = dlqNotificationAttempts
+	q.addNotification([]byte(`{}`), errors.New("broken pipe"))
+	for i := 0; i < dlqNotificationAttempts; i++ {
+		q.retry(true)
+	}
+	if m := q.metrics(); m.Notifications.Dropped != 1 || m.Notifications.Pending != 0 {
+		t.Errorf("after exhausting retries: %+v", m.Notifications)
+	}
+}
+
+func TestServer_GetMetrics(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	stdin, stdout, _ := newTestServer(t)
+	sendRequest(t, stdin, 1, "initialize", initializeParams())
+	_ = readResponse(t, stdout)
+
+	sendRequest(t, stdin, 2, "get_metrics", map[string]any{})
+	resp := readResponse(t, stdout)
+	if resp.Error != nil {
+		t.Fatalf("get_metrics: %+v", resp.Error)
+	}
+	var result types.GetMetricsResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if result.UptimeS <= 0 || result.DeadLetter.History.Failed != 0 {
+		t.Errorf("metrics = %+v", result)
+	}
+	if m := result.Memory; m.UsedBytes <= 0 || m.HeapBytes <= 0 || m.MaxInFlightTraceBytes <= 0 {
+		t.Errorf("memory metrics = %+v", m)
+	}
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/llm"
+)
+
+// Doctor check statuses.
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+	DoctorSkip = "skip"
+)
+
+// DoctorCheck is the outcome of one `attest-engine doctor` check. Remedy
+// says how to fix a warning or failure.
+type DoctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Remedy string
+}
+
+// Doctor checks the engine's environment: the cache directory and database,
+// every configured provider's key and models, the ONNX runtime, and the
+// local model files. It backs `attest-engine doctor`.
+func Doctor(ctx context.Context) []DoctorCheck {
+	protocol := DoctorCheck{Name: "protocol", Status: DoctorOK}
+	if minProtocolVersion == protocolVersion {
+		protocol.Detail = fmt.Sprintf("engine speaks protocol version %d", protocolVersion)
+	} else {
+		protocol.Detail = fmt.Sprintf("engine speaks protocol versions %d through %d", minProtocolVersion, protocolVersion)
+	}
+	checks := []DoctorCheck{protocol}
+	checks = append(checks, checkCacheDir(), checkCacheDB())
+	checks = append(checks, checkProviders(ctx, builtinProviders())...)
+	checks = append(checks, checkONNXRuntime(), checkModelFiles())
+	return checks
+}
+
+func checkCacheDir() DoctorCheck {
+	c := DoctorCheck{Name: "cache_dir"}
+	if os.Getenv("ATTEST_CACHE_MODE") == "memory" {
+		c.Status, c.Detail = DoctorSkip, "ATTEST_CACHE_MODE=memory; nothing is written to disk"
+		return c
+	}
+	dir := cacheDirectory()
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		var f *os.File
+		if f, err = os.CreateTemp(dir, ".doctor-*"); err == nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		c.Status, c.Detail = DoctorFail, fmt.Sprintf("%s is not writable: %v", dir, err)
+		c.Remedy = "fix the directory's permissions or set ATTEST_CACHE_DIR to a writable directory"
+		return c
+	}
+	c.Status, c.Detail = DoctorOK, dir+" is writable"
+	return c
+}
+
+func checkCacheDB() DoctorCheck {
+	c := DoctorCheck{Name: "cache_db"}
+	if os.Getenv("ATTEST_CACHE_MODE") == "memory" {
+		c.Status, c.Detail = DoctorSkip, "ATTEST_CACHE_MODE=memory"
+		return c
+	}
+	path := filepath.Join(cacheDirectory(), "attest.db")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		c.Status, c.Detail = DoctorOK, path+" does not exist yet; it is created on first use"
+		return c
+	}
+	problems, version, err := cache.CheckDatabase(path)
+	switch {
+	case err != nil:
+		c.Status, c.Detail = DoctorFail, fmt.Sprintf("%s cannot be read: %v", path, err)
+		c.Remedy = "run `attest-engine cache clear` to delete the cache database; it is rebuilt on next start"
+	case len(problems) > 0:
+		c.Status, c.Detail = DoctorFail, fmt.Sprintf("%s is corrupt: %s", path, strings.Join(problems, "; "))
+		c.Remedy = "run `attest-engine cache clear` to delete the cache database; it is rebuilt on next start"
+	case version > cache.LatestSchemaVersion():
+		c.Status = DoctorWarn
+		c.Detail = fmt.Sprintf("%s has schema version %d, newer than this engine's %d", path, version, cache.LatestSchemaVersion())
+		c.Remedy = "upgrade attest-engine, or set ATTEST_CACHE_DIR to a separate directory for this version"
+	default:
+		c.Status, c.Detail = DoctorOK, fmt.Sprintf("%s passed the integrity check (schema version %d)", path, version)
+	}
+	return c
+}
+
+// checkProviders validates the key and models of every provider that has an
+// API key or base URL set.
+func checkProviders(ctx context.Context, providers *llm.Registry) []DoctorCheck {
+	var checks []DoctorCheck
+	for _, capability := range []llm.Capability{llm.CapabilityChat, llm.CapabilityEmbed} {
+		for _, name := range providers.Names(capability) {
+			cfg := providerConfig(name)
+			if cfg.APIKey == "" && cfg.BaseURL == "" {
+				continue
+			}
+			keyVar := "ATTEST_" + strings.ToUpper(name) + "_API_KEY"
+			c := DoctorCheck{Name: fmt.Sprintf("provider %s (%s)", name, capability)}
+			model, validator, err := providerValidator(providers, name, capability, cfg)
+			if err != nil {
+				c.Status, c.Detail = DoctorFail, err.Error()
+				c.Remedy = fmt.Sprintf("set %s", keyVar)
+				checks = append(checks, c)
+				continue
+			}
+			if validator == nil {
+				c.Status, c.Detail = DoctorSkip, fmt.Sprintf("model %s cannot be validated ahead of use", model)
+				checks = append(checks, c)
+				continue
+			}
+
+			checkCtx, cancel := context.WithTimeout(ctx, modelCheckTimeout)
+			err = validator.ValidateModel(checkCtx)
+			cancel()
+			key := maskKey(cfg.APIKey)
+			switch {
+			case err == nil:
+				c.Status, c.Detail = DoctorOK, fmt.Sprintf("key %s can use model %s", key, model)
+			case errors.Is(err, llm.ErrModelUnavailable):
+				c.Status, c.Detail = DoctorFail, fmt.Sprintf("key %s: %v", key, err)
+				c.Remedy = fmt.Sprintf("check %s and the model name (%s)", keyVar, modelVar(capability))
+			default:
+				c.Status, c.Detail = DoctorFail, fmt.Sprintf("provider unreachable: %v", err)
+				c.Remedy = fmt.Sprintf("check network access, HTTPS_PROXY, and ATTEST_%s_BASE_URL", strings.ToUpper(name))
+			}
+			checks = append(checks, c)
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, DoctorCheck{
+			Name:   "providers",
+			Status: DoctorWarn,
+			Detail: "no LLM provider is configured; llm_judge, simulation, and remote embeddings are unavailable",
+			Remedy: "set ATTEST_OPENAI_API_KEY",
+		})
+	}
+	return checks
+}
+
+// providerValidator builds the provider's client for capability and returns
+// its model and, when it supports validation, its validator.
+func providerValidator(providers *llm.Registry, name string, capability llm.Capability, cfg llm.ProviderConfig) (string, llm.ModelValidator, error) {
+	var client any
+	var model string
+	switch capability {
+	case llm.CapabilityChat:
+		p, err := providers.Chat(name, cfg)
+		if err != nil {
+			return "", nil, err
+		}
+		client, model = p, p.DefaultModel()
+	case llm.CapabilityEmbed:
+		e, err := providers.Embedder(name, cfg)
+		if err != nil {
+			return "", nil, err
+		}
+		client, model = e, e.Model()
+	default:
+		return "", nil, fmt.Errorf("unknown capability %q", capability)
+	}
+	validator, _ := client.(llm.ModelValidator)
+	return model, validator, nil
+}
+
+func modelVar(c llm.Capability) string {
+	if c == llm.CapabilityEmbed {
+		return "ATTEST_EMBEDDING_MODEL"
+	}
+	return "ATTEST_JUDGE_MODEL"
+}
+
+// maskKey shows only enough of an API key to tell keys apart.
+func maskKey(key string) string {
+	switch {
+	case key == "":
+		return "(none)"
+	case len(key) <= 12:
+		return "****"
+	default:
+		return key[:3] + "…" + key[len(key)-4:]
+	}
+}
+
+func checkONNXRuntime() DoctorCheck {
+	c := DoctorCheck{Name: "onnx_runtime"}
+	if !embedding.ONNXAvailable {
+		c.Status, c.Detail = DoctorSkip, "local embeddings are not compiled in (build with -tags onnx)"
+		return c
+	}
+	path, err := embedding.FindONNXRuntime(modelDir())
+	if err != nil {
+		c.Status, c.Detail = DoctorFail, err.Error()
+		c.Remedy = "install ONNX Runtime with your package manager or copy the shared library into " + modelDir()
+		return c
+	}
+	c.Status, c.Detail = DoctorOK, "found "+path
+	return c
+}
+
+func checkModelFiles() DoctorCheck {
+	c := DoctorCheck{Name: "onnx_models"}
+	if !embedding.ONNXAvailable {
+		c.Status, c.Detail = DoctorSkip, "local embeddings are not compiled in"
+		return c
+	}
+	statuses, err := embedding.VerifyModels(modelDir())
+	if err != nil {
+		c.Status, c.Detail = DoctorFail, err.Error()
+		c.Remedy = "make the files in " + modelDir() + " readable"
+		return c
+	}
+
+	c.Status = DoctorOK
+	var details []string
+	for _, st := range statuses {
+		details = append(details, st.Name+" "+st.Status)
+		switch st.Status {
+		case "mismatch":
+			c.Status = DoctorFail
+			c.Remedy = "run `attest-engine models download` to replace the mismatched files"
+		case "missing":
+			if c.Status == DoctorFail {
+				continue
+			}
+			if offline() {
+				c.Status = DoctorFail
+				c.Remedy = "ATTEST_OFFLINE=1 forbids downloads: run `attest-engine models download` on a connected machine and copy " + modelDir() + " here"
+			} else {
+				c.Status = DoctorWarn
+				c.Remedy = "files are downloaded on first use; run `attest-engine models download` to fetch them now"
+			}
+		}
+	}
+	c.Detail = strings.Join(details, ", ") + " in " + modelDir()
+	return c
+}
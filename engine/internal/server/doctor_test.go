@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func findCheck(t *testing.T, checks []DoctorCheck, name string) DoctorCheck {
+	t.Helper()
+	for _, c := range checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %q check in %+v", name, checks)
+	return DoctorCheck{}
+}
+
+func TestDoctor_CacheChecks(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ATTEST_CACHE_DIR", dir)
+	t.Setenv("ATTEST_CACHE_MODE", "")
+
+	checks := Doctor(context.Background())
+	if c := findCheck(t, checks, "cache_dir"); c.Status != DoctorOK {
+		t.Errorf("cache_dir = %+v", c)
+	}
+	if c := findCheck(t, checks, "cache_db"); c.Status != DoctorOK {
+		t.Errorf("missing cache_db = %+v", c)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "attest.db"), []byte("not a database, only some plain text bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := checkCacheDB()
+	if c.Status != DoctorFail || !strings.Contains(c.Remedy, "cache clear") {
+		t.Errorf("corrupt cache_db = %+v", c)
+	}
+}
+
+func TestDoctor_ProviderRejectsKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided"}}`))
+	}))
+	defer srv.Close()
+	const key = "sk-test-0123456789abcdef"
+	t.Setenv("ATTEST_OPENAI_API_KEY", key)
+	t.Setenv("ATTEST_OPENAI_BASE_URL", srv.URL)
+
+	checks := checkProviders(context.Background(), builtinProviders())
+	c := findCheck(t, checks, "provider openai (chat)")
+	if c.Status != DoctorFail || !strings.Contains(c.Remedy, "ATTEST_OPENAI_API_KEY") {
+		t.Errorf("check = %+v", c)
+	}
+	for _, c := range checks {
+		if strings.Contains(c.Detail, key) {
+			t.Errorf("%s leaks the API key: %s", c.Name, c.Detail)
+		}
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"":                             "(none)",
+		"short":                        "****",
+		"sk-proj-abcdefghijklmnop1234": "sk-…1234",
+	} {
+		if got := maskKey(key); got != want {
+			t.Errorf("maskKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
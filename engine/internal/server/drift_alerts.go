@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// driftAlerter sends drift_alert notifications for dynamic-threshold
+// assertions that hard-fail, at most one per assertion per interval.
+type driftAlerter struct {
+	notifier Notifier
+	// minSigma suppresses alerts whose score deviates from the history mean
+	// by fewer standard deviations.
+	minSigma float64
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last map[string]*alertState
+}
+
+type alertState struct {
+	sent       time.Time
+	suppressed int
+}
+
+// newDriftAlerter configures drift alerts from ATTEST_DRIFT_ALERTS ("0"
+// disables them), ATTEST_DRIFT_ALERT_MIN_SIGMA (default 0: every hard fail),
+// and ATTEST_DRIFT_ALERT_INTERVAL_S (default 60; 0 disables rate limiting).
+// It returns nil when alerts are disabled.
+func newDriftAlerter(notifier Notifier) *driftAlerter {
+	if os.Getenv("ATTEST_DRIFT_ALERTS") == "0" {
+		return nil
+	}
+	var minSigma float64
+	if v, err := strconv.ParseFloat(os.Getenv("ATTEST_DRIFT_ALERT_MIN_SIGMA"), 64); err == nil && v > 0 {
+		minSigma = v
+	}
+	return &driftAlerter{
+		notifier: notifier,
+		minSigma: minSigma,
+		interval: time.Duration(envInt("ATTEST_DRIFT_ALERT_INTERVAL_S", 60)) * time.Second,
+		now:      time.Now,
+		last:     make(map[string]*alertState),
+	}
+}
+
+// observe sends a drift_alert for ar when it is a dynamic hard fail that
+// clears the sigma threshold and the assertion is not rate limited.
+func (a *driftAlerter) observe(ctx context.Context, history *cache.HistoryStore, traceID string, ar *types.AssertionResult) {
+	if a == nil || ar.Status != types.StatusHardFail {
+		return
+	}
+	mean, stddev, count, err := history.Stats(ar.AssertionID)
+	if err != nil {
+		logging.FromContext(ctx).Warn("drift alert stats failed", "assertion_id", ar.AssertionID, "err", err)
+		return
+	}
+	deviation := ar.Score - mean
+	var z float64
+	if stddev > 0 {
+		z = deviation / stddev
+	}
+	if a.minSigma > 0 && math.Abs(z) < a.minSigma {
+		return
+	}
+
+	a.mu.Lock()
+	st := a.last[ar.AssertionID]
+	if st == nil {
+		st = &alertState{}
+		a.last[ar.AssertionID] = st
+	}
+	now := a.now()
+	if a.interval > 0 && !st.sent.IsZero() && now.Sub(st.sent) < a.interval {
+		st.suppressed++
+		a.mu.Unlock()
+		return
+	}
+	suppressed := st.suppressed
+	st.sent, st.suppressed = now, 0
+	a.mu.Unlock()
+
+	a.notifier.Notify("drift_alert", types.DriftAlert{
+		DriftReport: types.DriftReport{
+			AssertionID: ar.AssertionID,
+			Mean:        mean,
+			Stddev:      stddev,
+			Count:       count,
+			LatestScore: ar.Score,
+			Deviation:   deviation,
+			Status:      "drift_detected",
+		},
+		TraceID:    traceID,
+		ZScore:     z,
+		Suppressed: suppressed,
+	})
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []types.DriftAlert
+}
+
+func (n *recordingNotifier) Notify(method string, params any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if method == "drift_alert" {
+		n.sent = append(n.sent, params.(types.DriftAlert))
+	}
+}
+
+func driftHistory(t *testing.T, assertionID string, scores ...float64) *cache.HistoryStore {
+	t.Helper()
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, s := range scores {
+		if err := store.History().Record("trc", assertionID, "embedding", s, types.StatusPass); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	return store.History()
+}
+
+func TestDriftAlerter_RateLimitsPerAssertion(t *testing.T) {
+	t.Setenv("ATTEST_DRIFT_ALERT_INTERVAL_S", "60")
+	history := driftHistory(t, "a1", 0.9, 0.8, 0.9, 0.8)
+	n := &recordingNotifier{}
+	alerts := newDriftAlerter(n)
+	now := time.Unix(1_700_000_000, 0)
+	alerts.now = func() time.Time { return now }
+
+	fail := &types.AssertionResult{AssertionID: "a1", Status: types.StatusHardFail, Score: 0.2}
+	alerts.observe(context.Background(), history, "trc_1", fail)
+	alerts.observe(context.Background(), history, "trc_2", fail)
+	alerts.observe(context.Background(), history, "trc_3", &types.AssertionResult{AssertionID: "a1", Status: types.StatusPass, Score: 0.9})
+	if len(n.sent) != 1 {
+		t.Fatalf("sent %d alerts within the interval, want 1", len(n.sent))
+	}
+	first := n.sent[0]
+	if first.TraceID != "trc_1" || first.Count != 4 || first.ZScore > -10 || first.Status != "drift_detected" {
+		t.Errorf("alert = %+v", first)
+	}
+
+	now = now.Add(61 * time.Second)
+	alerts.observe(context.Background(), history, "trc_4", fail)
+	if len(n.sent) != 2 || n.sent[1].Suppressed != 1 {
+		t.Fatalf("after the interval: %+v", n.sent)
+	}
+}
+
+func TestDriftAlerter_MinSigma(t *testing.T) {
+	t.Setenv("ATTEST_DRIFT_ALERT_MIN_SIGMA", "3")
+	history := driftHistory(t, "a1", 0.9, 0.7, 0.9, 0.7)
+	n := &recordingNotifier{}
+	alerts := newDriftAlerter(n)
+
+	// Mean 0.8, stddev 0.1: 0.6 is 2 sigma out, 0.4 is 4.
+	alerts.observe(context.Background(), history, "trc", &types.AssertionResult{AssertionID: "a1", Status: types.StatusHardFail, Score: 0.6})
+	if len(n.sent) != 0 {
+		t.Fatalf("alerted below the sigma threshold: %+v", n.sent)
+	}
+	alerts.observe(context.Background(), history, "trc", &types.AssertionResult{AssertionID: "a1", Status: types.StatusHardFail, Score: 0.4})
+	if len(n.sent) != 1 {
+		t.Fatalf("sent %d alerts, want 1", len(n.sent))
+	}
+}
+
+func TestDriftAlerter_Disabled(t *testing.T) {
+	t.Setenv("ATTEST_DRIFT_ALERTS", "0")
+	if a := newDriftAlerter(&recordingNotifier{}); a != nil {
+		t.Fatal("alerter enabled with ATTEST_DRIFT_ALERTS=0")
+	}
+	// A nil alerter is a no-op.
+	var a *driftAlerter
+	a.observe(context.Background(), nil, "trc", &types.AssertionResult{Status: types.StatusHardFail})
+}
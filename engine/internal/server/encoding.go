@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// gzipWriters reuses compressors across responses. BestSpeed suits a
+// transient IPC payload: it keeps most of the size reduction of repetitive
+// trace and result JSON at a fraction of the CPU cost.
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	},
+}
+
+// decodeParams returns the JSON params of a request sent with the
+// json+gzip encoding. A JSON string holds the base64 of the gzipped params
+// and is expanded up to limit bytes; any other value is plain JSON and is
+// returned unchanged, so small requests need not be compressed.
+func decodeParams(params json.RawMessage, limit int) (json.RawMessage, error) {
+	if len(params) == 0 || params[0] != '"' {
+		return params, nil
+	}
+	var encoded string
+	if err := json.Unmarshal(params, &encoded); err != nil {
+		return nil, err
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("params are not base64: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("params are not gzip: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(io.LimitReader(zr, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress params: %w", err)
+	}
+	if len(raw) > limit {
+		return nil, fmt.Errorf("decompressed params exceed %d bytes", limit)
+	}
+	return raw, nil
+}
+
+// encodeResult gzips a JSON result and returns it as a JSON string of base64.
+func encodeResult(result json.RawMessage) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(&buf)
+	if _, err := zw.Write(result); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// fuzzSkippedMethods are left unregistered on fuzzing servers: they read or
+// write files named in their params, wait on calls to the SDK, or end the
+// session.
+var fuzzSkippedMethods = []string{
+	"evaluate_dataset",
+	"debug_dump",
+	"debug_profile",
+	"generate_user_message",
+	"run_simulation",
+	"run_simulation_batch",
+	"shutdown",
+}
+
+// fuzzCorpusHeader starts the corpus files go test -fuzz writes.
+const fuzzCorpusHeader = "go test fuzz v1\n"
+
+// newFuzzServer returns an initialized server with the built-in handlers,
+// minus fuzzSkippedMethods, whose output is discarded.
+func newFuzzServer() (*Server, error) {
+	s := New(strings.NewReader(""), io.Discard, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	RegisterBuiltinHandlers(s)
+	for _, method := range fuzzSkippedMethods {
+		delete(s.handlers, method)
+	}
+	init := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"sdk_name":"attest-fuzz","sdk_version":"0.0.0","protocol_version":1,"required_capabilities":[],"preferred_encoding":"json"}}`
+	if resp := s.dispatch(context.Background(), []byte(init)); resp.Error != nil {
+		return nil, fmt.Errorf("initialize: %s", resp.Error.Message)
+	}
+	return s, nil
+}
+
+// fuzzDispatch dispatches one request line and checks the invariants every
+// response must hold, whatever the input.
+func (s *Server) fuzzDispatch(ctx context.Context, line []byte) (*types.Response, error) {
+	resp := s.dispatch(ctx, line)
+	if resp == nil {
+		return nil, errors.New("dispatch returned no response")
+	}
+	if resp.JSONRPC != "2.0" {
+		return resp, fmt.Errorf("response jsonrpc is %q", resp.JSONRPC)
+	}
+	if (resp.Result == nil) == (resp.Error == nil) {
+		return resp, errors.New("response must carry exactly one of result and error")
+	}
+	if resp.Error != nil && (resp.Error.Code == 0 || resp.Error.Message == "") {
+		return resp, fmt.Errorf("error response without code or message: %+v", resp.Error)
+	}
+	// dispatch recovers panics so the engine survives them, answering with
+	// "internal error handling <method>: ..."; fuzzing must still report them.
+	if resp.Error != nil && strings.HasPrefix(resp.Error.Message, "internal error handling ") {
+		return resp, fmt.Errorf("request panicked: %s", resp.Error.Message)
+	}
+	if _, err := json.Marshal(resp); err != nil {
+		return resp, fmt.Errorf("response does not marshal: %w", err)
+	}
+	return resp, nil
+}
+
+// FuzzReplay runs one fuzz input through the same paths as the engine's fuzz
+// targets: as a request line on a fresh server, and as a trace. input is
+// either a corpus file written by go test -fuzz, whose first value is used,
+// or the raw bytes. It returns the response line. A panic is returned as an
+// error carrying its stack, so a minimized crasher reproduces outside go
+// test.
+func FuzzReplay(input []byte) (out []byte, err error) {
+	if bytes.HasPrefix(input, []byte(fuzzCorpusHeader)) {
+		if input, err = decodeFuzzCorpus(input); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	s, err := newFuzzServer()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.fuzzDispatch(context.Background(), input)
+	if err != nil {
+		return nil, err
+	}
+	if out, err = json.Marshal(resp); err != nil {
+		return nil, err
+	}
+	// Traces are fuzzed on their own too; parse errors are expected.
+	_, _ = parseTrace(input, trace.DefaultLimits)
+	return out, nil
+}
+
+// decodeFuzzCorpus returns the first value of a go test -fuzz corpus file,
+// which holds one Go literal per line, such as []byte("...") or string("...").
+func decodeFuzzCorpus(data []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimPrefix(string(data), fuzzCorpusHeader), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		open := strings.IndexByte(line, '(')
+		if open < 0 || !strings.HasSuffix(line, ")") {
+			return nil, fmt.Errorf("fuzz corpus: malformed value %q", line)
+		}
+		switch kind := line[:open]; kind {
+		case "[]byte", "string":
+		default:
+			return nil, fmt.Errorf("fuzz corpus: unsupported value type %s", kind)
+		}
+		v, err := strconv.Unquote(line[open+1 : len(line)-1])
+		if err != nil {
+			return nil, fmt.Errorf("fuzz corpus: %w", err)
+		}
+		return []byte(v), nil
+	}
+	return nil, errors.New("fuzz corpus: no values")
+}
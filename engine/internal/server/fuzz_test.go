@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// deepBatch is an evaluate_batch request whose output nests 3M arrays deep,
+// past what the decoder's recursion can survive.
+var deepBatch = `{"jsonrpc":"2.0","id":18,"method":"evaluate_batch","params":{"trace":{"trace_id":"t","output":` +
+	strings.Repeat("[", 3<<20) + strings.Repeat("]", 3<<20) + `},"assertions":[]}}`
+
+// fuzzSeeds cover malformed envelopes, truncated UTF-8, deeply nested specs,
+// and oversized fields.
+var fuzzSeeds = []string{
+	``,
+	`{`,
+	`null`,
+	`[]`,
+	`{"jsonrpc":"2.0"}`,
+	`{"jsonrpc":"2.0","id":"x","method":"get_metrics"}`,
+	`{"jsonrpc":"2.0","id":1e400,"method":"get_metrics","params":{}}`,
+	`{"jsonrpc":"2.0","id":2,"method":"get_metrics","params":null}`,
+	`{"jsonrpc":"2.0","id":3,"method":"initialize","params":{"protocol_version":-1}}`,
+	`{"jsonrpc":"2.0","id":4,"method":"evaluate_batch","params":{"trace":{"trace_id":"t","output":{"message":"hi"},"steps":[]},"assertions":[{"assertion_id":"a","type":"content","spec":{"target":"output.message","check":"contains","value":"hi"}}]}}`,
+	`{"jsonrpc":"2.0","id":5,"method":"evaluate_batch","params":{"trace":{"trace_id":"t","output":{"message":"` + "\xe2\x82" + `"}},"assertions":[{"assertion_id":"a","type":"content","spec":{"target":"output.message","check":"regex","value":"(a+)+$"}}]}}`,
+	`{"jsonrpc":"2.0","id":6,"method":"evaluate_batch","params":{"trace":{"trace_id":"t"},"assertions":[{"assertion_id":"a","type":"schema","spec":{"target":"output","schema":` + strings.Repeat(`{"items":`, 500) + `{}` + strings.Repeat(`}`, 500) + `}}]}}`,
+	`{"jsonrpc":"2.0","id":7,"method":"evaluate_batch","params":{"trace":{"trace_id":"t"},"assertions":[{"assertion_id":"` + strings.Repeat("a", 4096) + `","type":"constraint","spec":{"field":"metadata.cost_usd","operator":"lte","value":1}}]}}`,
+	`{"jsonrpc":"2.0","id":8,"method":"evaluate_batch","params":{"trace_ref":7,"assertions":[]}}`,
+	`{"jsonrpc":"2.0","id":9,"method":"evaluate_batch","params":{"traces":[{"trace_id":"a","parent_trace_id":"a"}],"assertions":[]}}`,
+	`{"jsonrpc":"2.0","id":10,"method":"validate_trace_tree","params":{"trace":{"trace_id":"t","steps":[{"type":"agent_call","name":"a","sub_trace":{"steps":[{"type":"agent_call"}]}}]}}}`,
+	`{"jsonrpc":"2.0","id":11,"method":"begin_trace","params":{"size_bytes":-1}}`,
+	`{"jsonrpc":"2.0","id":12,"method":"append_trace_chunk","params":{"upload_id":"upl_1","index":-1,"data":"!!","sha256":""}}`,
+	`{"jsonrpc":"2.0","id":13,"method":"register_template","params":{"name":"t","type":"content","params":["x"],"spec":{"value":"{{x}}"}}}`,
+	`{"jsonrpc":"2.0","id":14,"method":"query_drift","params":{"assertion_ids":[""],"window":-5}}`,
+	`{"jsonrpc":"2.0","id":15,"method":"query_flaky","params":{"assertion_ids":null,"window":1000000000}}`,
+	`{"jsonrpc":"2.0","id":16,"method":"submit_plugin_result","params":{"assertion_id":"a","result":{"status":"???","score":1e308}}}`,
+	`{"jsonrpc":"2.0","id":17,"method":"update_quarantine","params":{"assertion_id":"","quarantined":true}}`,
+	deepBatch,
+}
+
+var (
+	fuzzServerOnce sync.Once
+	fuzzServer     *Server
+	fuzzServerErr  error
+)
+
+// FuzzDispatch feeds hostile request lines to an initialized server. Run
+// with:
+//
+//	go test ./internal/server -run '^$' -fuzz FuzzDispatch
+//
+// Crashers are written to testdata/fuzz/FuzzDispatch and replay as regular
+// tests; attest-engine --fuzz-replay reproduces them outside go test.
+func FuzzDispatch(f *testing.F) {
+	f.Setenv("ATTEST_CACHE_MODE", "memory")
+	f.Setenv("ATTEST_OFFLINE", "1")
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	// One server per fuzzing process: building one per input would leak
+	// its background goroutines.
+	fuzzServerOnce.Do(func() { fuzzServer, fuzzServerErr = newFuzzServer() })
+	if fuzzServerErr != nil {
+		f.Fatal(fuzzServerErr)
+	}
+
+	f.Fuzz(func(t *testing.T, line []byte) {
+		if _, err := fuzzServer.fuzzDispatch(context.Background(), line); err != nil {
+			t.Fatalf("%v\ninput: %q", err, line)
+		}
+	})
+}
+
+func TestDispatch_DeepNesting(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	t.Setenv("ATTEST_OFFLINE", "1")
+	s, err := newFuzzServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.fuzzDispatch(context.Background(), []byte(deepBatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error == nil || resp.Error.Code != -32700 || !strings.Contains(resp.Error.Data.Detail, "nesting exceeds maximum depth") {
+		t.Fatalf("response error = %+v, want a parse error for the nesting depth", resp.Error)
+	}
+}
+
+func TestFuzzReplay(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	t.Setenv("ATTEST_OFFLINE", "1")
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"raw", `{"jsonrpc":"2.0","id":2,"method":"get_metrics","params":{}}`, `"result":{`},
+		{"corpus file", "go test fuzz v1\n[]byte(\"{\\\"jsonrpc\\\":\\\"2.0\\\",\\\"id\\\":3,\\\"method\\\":\\\"nope\\\"}\")\n", `"code":-32601`},
+		{"corpus file raw string", "go test fuzz v1\n[]byte(`{`)\n", `"code":-32700`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := FuzzReplay([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("FuzzReplay: %v", err)
+			}
+			if !strings.Contains(string(out), tt.want) {
+				t.Errorf("response %s does not contain %s", out, tt.want)
+			}
+		})
+	}
+
+	if _, err := FuzzReplay([]byte("go test fuzz v1\nint(3)\n")); err == nil {
+		t.Error("want error for unsupported corpus value type")
+	}
+}
@@ -2,20 +2,23 @@ package server
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"github.com/segmentio/encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/attest-ai/attest/engine/internal/assertion"
 	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
-	"github.com/attest-ai/attest/engine/internal/assertion/judge"
 	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/deprecation"
 	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/paths"
 	"github.com/attest-ai/attest/engine/internal/simulation"
 	"github.com/attest-ai/attest/engine/internal/trace"
 	"github.com/attest-ai/attest/engine/pkg/types"
@@ -32,7 +35,15 @@ const (
 // RegisterBuiltinHandlers registers the built-in JSON-RPC handlers on s.
 // It reads ATTEST_* env vars to configure Layer 5/6 providers and caches.
 func RegisterBuiltinHandlers(s *Server) {
-	opts, caps, judgeProvider, historyStore := buildRegistryOptions(s.logger)
+	limits := buildTraceLimits(s.logger)
+	rates := buildCurrencyRates(s.logger)
+	s.SetMaxLineSize(limits.MaxTraceSize)
+	s.SetMaxJSONDepth(limits.MaxJSONDepth)
+	s.SetMaxResponseSize(envInt("ATTEST_MAX_RESPONSE_SIZE", defaultMaxResponseSize))
+	store := openCacheStore(s.logger)
+	opts, caps, judgeProvider, historyStore, probes, providers := buildRegistryOptions(s.logger, store)
+	checks := newModelChecks(s.logger, probes)
+	go checks.results(context.Background())
 	registry := assertion.NewRegistry(opts...)
 
 	var pipeline *assertion.Pipeline
@@ -44,29 +55,102 @@ func RegisterBuiltinHandlers(s *Server) {
 
 	// Wire BudgetTracker from ATTEST_BUDGET_MAX_COST env var (nil when unset).
 	budget := buildBudgetTracker(s.logger)
+	templates := buildTemplateRegistry(s.logger)
+
+	// Trim caches and truncate the WAL once the engine has been idle for
+	// ATTEST_IDLE_TRIM_S seconds (default 60; 0 disables).
+	if trimAfter := envInt("ATTEST_IDLE_TRIM_S", 60); store != nil && trimAfter > 0 {
+		s.OnIdle(time.Duration(trimAfter)*time.Second, func() {
+			if err := store.Trim(); err != nil {
+				s.logger.Warn("idle cache trim failed", "err", err)
+				return
+			}
+			s.logger.Debug("idle cache trim complete")
+		})
+	}
+
+	// Failed history and notification writes are retried from a bounded
+	// queue; history writes spill next to the cache database.
+	var spillPath string
+	if store != nil && os.Getenv("ATTEST_CACHE_MODE") != "memory" {
+		spillPath = filepath.Join(cacheDirectory(), "dead_letter.ndjson")
+	}
+	deadLetters := newDeadLetterQueue(historyStore, s.writeLine, spillPath, s.logger)
+	s.deadLetters = deadLetters
+	s.OnStop(deadLetters.close)
 
-	s.RegisterHandler("initialize", handleInitialize(caps))
+	s.auth = buildAuthPolicy(s.logger)
+	s.RegisterHandler("initialize", handleInitialize(caps, checks, limits, store, s.auth))
 	s.RegisterHandler("shutdown", handleShutdown)
-	s.RegisterHandler("evaluate_batch", handleEvaluateBatch(pipeline, historyStore, budget, s.writeNotification))
-	s.RegisterHandler("submit_plugin_result", handleSubmitPluginResult(historyStore))
-	s.RegisterHandler("validate_trace_tree", handleValidateTraceTree())
+	s.RegisterHandler("describe_capabilities", handleDescribeCapabilities(s, registry, caps, checks, limits, providers))
+	recent := newRecentBatches(envInt("ATTEST_DEBUG_RECENT_TRACES", 0))
+	uploads := newTraceUploads(limits)
+	admit := buildAdmission(s.logger)
+
+	s.RegisterHandler("evaluate_batch", handleEvaluateBatch(pipeline, templates, limits, rates, uploads, admit, historyStore, deadLetters, budget, recent, newDriftAlerter(s)))
+	var dedupEmbedder textEmbedder
+	if eval, err := registry.Get(types.TypeEmbedding); err == nil {
+		if e, ok := eval.(*assertion.EmbeddingEvaluator); ok {
+			dedupEmbedder = e
+		}
+	}
+	s.RegisterHandler("evaluate_dataset", handleEvaluateDataset(pipeline, templates, limits, rates, dedupEmbedder))
+	s.RegisterHandler("begin_trace", handleBeginTrace(uploads))
+	s.RegisterHandler("append_trace_chunk", handleAppendTraceChunk(uploads))
+	s.RegisterHandler("end_trace", handleEndTrace(uploads))
+	s.RegisterHandler("register_template", handleRegisterTemplate(templates))
+	s.RegisterHandler("submit_plugin_result", handleSubmitPluginResult(historyStore, deadLetters))
+	s.RegisterHandler("get_metrics", handleGetMetrics(deadLetters, admit, s.startedAt))
+	s.RegisterHandler("validate_trace_tree", handleValidateTraceTree(limits))
 	s.RegisterHandler("query_drift", handleQueryDrift(historyStore))
+	s.RegisterHandler("query_flaky", handleQueryFlaky(historyStore))
+	s.RegisterHandler("update_quarantine", handleUpdateQuarantine(historyStore))
+	s.RegisterHandler("debug_dump", handleDebugDump(recent, s.logBuffer, store, s.startedAt))
+	prof := newProfiler(filepath.Join(cacheDirectory(), "profiles"), s.logger)
+	s.OnStop(prof.stop)
+	s.RegisterHandler("debug_profile", handleDebugProfile(prof))
 	if judgeProvider != nil {
 		s.RegisterHandler("generate_user_message", handleGenerateUserMessage(judgeProvider))
+		s.RegisterHandler("run_simulation", handleRunSimulation(judgeProvider, pipeline, templates, s.Call))
+		s.RegisterHandler("run_simulation_batch", handleRunSimulationBatch(judgeProvider, pipeline, templates, s.Call))
 	}
 }
 
 // buildRegistryOptions reads env vars and constructs RegistryOption values
-// for Layer 5 (embedding) and Layer 6 (judge) evaluators. Returns the
-// options, the list of supported capabilities, the judge provider (may be nil),
-// and the HistoryStore (may be nil on failure).
-func buildRegistryOptions(logger *slog.Logger) ([]assertion.RegistryOption, []string, llm.Provider, *cache.HistoryStore) {
-	caps := []string{"layers_1_4", "trace_tree", "continuous_eval", "plugins"}
+// for Layer 5 (embedding) and Layer 6 (judge) evaluators, backed by the shared
+// cache store (nil disables caching and history). Returns the options, the list
+// of supported capabilities, the judge provider (may be nil), the
+// HistoryStore (may be nil), the model checks for the configured providers,
+// and the configured providers themselves.
+func buildRegistryOptions(logger *slog.Logger, store *cache.Store) ([]assertion.RegistryOption, []string, llm.Provider, *cache.HistoryStore, []modelProbe, []types.ProviderInfo) {
+	caps := []string{"layers_1_4", "trace_tree", "temporal_logic", "expressions", "continuous_eval", "plugins"}
 	var opts []assertion.RegistryOption
+	var probes []modelProbe
+	var configured []types.ProviderInfo
+
+	// ── Layer 4: regex time budget (ATTEST_REGEX_TIMEOUT_MS; 0 disables) ──
+	if ms := envInt("ATTEST_REGEX_TIMEOUT_MS", -1); ms > 0 {
+		opts = append(opts, assertion.WithRegexBudget(time.Duration(ms)*time.Millisecond))
+	} else if ms == 0 {
+		opts = append(opts, assertion.WithRegexBudget(-1))
+	}
+
+	// ── Layer 4: link liveness (opt-in with ATTEST_LINK_CHECK=1) ──
+	if os.Getenv("ATTEST_LINK_CHECK") == "1" {
+		if offline() {
+			logger.Warn("link liveness checks disabled by ATTEST_OFFLINE=1")
+		} else {
+			opts = append(opts, assertion.WithLinkCheck(assertion.LinkCheckConfig{
+				Timeout:     time.Duration(envInt("ATTEST_LINK_CHECK_TIMEOUT_MS", 3000)) * time.Millisecond,
+				MaxPerBatch: envInt("ATTEST_LINK_CHECK_MAX_PER_BATCH", assertion.DefaultLinkChecksPerBatch),
+			}))
+			logger.Info("link liveness checks enabled")
+		}
+	}
 
 	// ── Layer 5: Embedding ──
-	openAIKey := os.Getenv("ATTEST_OPENAI_API_KEY")
-	embeddingProvider := os.Getenv("ATTEST_EMBEDDING_PROVIDER") // "openai" or "auto" (default)
+	providers := builtinProviders()
+	embeddingProvider := selectedProvider(providers, "ATTEST_EMBEDDING_PROVIDER", llm.CapabilityEmbed)
 	if embeddingProvider == "" {
 		embeddingProvider = "auto"
 	}
@@ -74,23 +158,34 @@ func buildRegistryOptions(logger *slog.Logger) ([]assertion.RegistryOption, []st
 	var embedder embedding.Embedder
 	var embProviderName string
 
-	if openAIKey != "" && (embeddingProvider == "auto" || embeddingProvider == "openai") {
-		e, err := embedding.NewOpenAIEmbedder(embedding.EmbedderConfig{
-			APIKey: openAIKey,
-		})
-		if err != nil {
-			logger.Warn("failed to create OpenAI embedder", "err", err)
-		} else {
-			embedder = e
-			embProviderName = "openai"
+	name := embeddingProvider
+	if name == "auto" {
+		name = configuredProvider(providers, llm.CapabilityEmbed)
+	}
+	if name != "" && name != "onnx" {
+		if !providers.Supports(name, llm.CapabilityEmbed) {
+			logger.Warn("unknown embedding provider", "provider", name, "supported", providers.Names(llm.CapabilityEmbed))
+		} else if cfg := providerConfig(name); cfg.APIKey != "" || cfg.BaseURL != "" {
+			e, err := providers.Embedder(name, cfg)
+			if err != nil {
+				logger.Warn("failed to create embedder", "provider", name, "err", err)
+			} else {
+				embedder = e
+				embProviderName = name
+			}
 		}
 	}
 
-	// ONNX fallback: explicit "onnx" provider or auto-detect when no OpenAI key
-	if embedder == nil && (embeddingProvider == "onnx" || (embeddingProvider == "auto" && openAIKey == "")) {
+	// ONNX fallback: explicit "onnx" provider or auto-detect when no remote
+	// embedding provider is configured
+	if embedder == nil && (embeddingProvider == "onnx" || (embeddingProvider == "auto" && name == "")) {
 		if embedding.ONNXAvailable {
-			modelDir := os.Getenv("ATTEST_ONNX_MODEL_DIR")
-			e, err := embedding.NewONNXEmbedder(embedding.EmbedderConfig{ModelDir: modelDir})
+			e, err := embedding.NewONNXEmbedder(embedding.EmbedderConfig{
+				ModelDir:          modelDir(),
+				Offline:           offline(),
+				ExecutionProvider: onnxExecutionProvider(logger),
+				Logger:            logger,
+			})
 			if err != nil {
 				logger.Warn("failed to create ONNX embedder", "err", err)
 			} else {
@@ -104,55 +199,41 @@ func buildRegistryOptions(logger *slog.Logger) ([]assertion.RegistryOption, []st
 
 	if embedder != nil {
 		var embCache *cache.EmbeddingCache
-		cacheDir := cacheDirectory()
-		maxMB := envInt("ATTEST_EMBEDDING_CACHE_MAX_MB", 500)
-		dbPath := filepath.Join(cacheDir, "attest.db")
-		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
-			logger.Warn("failed to create cache dir", "dir", cacheDir, "err", err)
-		} else {
-			c, err := cache.NewEmbeddingCache(dbPath, maxMB)
-			if err != nil {
-				logger.Warn("failed to create embedding cache", "err", err)
-			} else {
-				embCache = c
-			}
+		if store != nil {
+			embCache = store.Embeddings()
 		}
 		opts = append(opts, assertion.WithEmbedding(embedder, embCache))
 		caps = append(caps, "embedding")
+		configured = append(configured, types.ProviderInfo{Capability: "embedding", Provider: embProviderName, Model: embedder.Model()})
+		if v, ok := embedder.(llm.ModelValidator); ok {
+			probes = append(probes, modelProbe{capability: "embedding", provider: embProviderName, model: embedder.Model(), validator: v})
+		}
 		logger.Info("layer 5 (embedding) enabled", "provider", embProviderName)
 	}
 
 	// ── Layer 6: LLM Judge ──
-	judgeProvider, providerName, judgeErr := buildJudgeProvider(logger)
+	judgeProvider, providerName, judgeErr := buildJudgeProvider(logger, providers)
 	if judgeErr != nil {
 		logger.Error("judge provider configuration error", "err", judgeErr)
 		fmt.Fprintf(os.Stderr, "fatal: %v\n", judgeErr)
 		os.Exit(1)
 	}
 	if judgeProvider != nil {
-		rubrics := judge.NewRubricRegistry()
+		rubrics := buildRubricRegistry(logger)
 
 		var jCache *cache.JudgeCache
-		cacheDir := cacheDirectory()
-		dbPath := filepath.Join(cacheDir, "attest.db")
-		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
-			logger.Warn("failed to create cache dir", "dir", cacheDir, "err", err)
-		} else {
-			judgeCacheMaxMB := envInt("ATTEST_JUDGE_CACHE_MAX_MB", 100)
-		if judgeCacheMaxMB < 10 {
-			judgeCacheMaxMB = 10
-		} else if judgeCacheMaxMB > 10000 {
-			judgeCacheMaxMB = 10000
-		}
-		c, err := cache.NewJudgeCache(dbPath, judgeCacheMaxMB)
-			if err != nil {
-				logger.Warn("failed to create judge cache", "err", err)
-			} else {
-				jCache = c
-			}
+		if store != nil {
+			jCache = store.Judge()
 		}
 		opts = append(opts, assertion.WithJudge(judgeProvider, rubrics, jCache))
+		if store != nil {
+			opts = append(opts, assertion.WithJudgeCalibrations(store.Calibrations()))
+		}
 		caps = append(caps, "llm_judge", "simulation")
+		configured = append(configured, types.ProviderInfo{Capability: "llm_judge", Provider: providerName, Model: judgeProvider.DefaultModel()})
+		if v, ok := judgeProvider.(llm.ModelValidator); ok {
+			probes = append(probes, modelProbe{capability: "llm_judge", provider: providerName, model: judgeProvider.DefaultModel(), validator: v})
+		}
 		logger.Info("layer 6 (judge) enabled", "provider", providerName)
 	}
 
@@ -162,86 +243,168 @@ func buildRegistryOptions(logger *slog.Logger) ([]assertion.RegistryOption, []st
 
 	// ── History Store ──
 	var historyStore *cache.HistoryStore
-	{
-		cacheDir := cacheDirectory()
-		dbPath := filepath.Join(cacheDir, "attest.db")
-		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
-			logger.Warn("failed to create cache dir for history store", "dir", cacheDir, "err", err)
-		} else {
-			db, err := openHistoryDB(dbPath)
-			if err != nil {
-				logger.Warn("failed to open history db", "err", err)
-			} else {
-				hs, err := cache.NewHistoryStore(db)
-				if err != nil {
-					logger.Warn("failed to create history store", "err", err)
-					db.Close()
-				} else {
-					// Configure retention from env vars.
-					maxRows := envInt("ATTEST_HISTORY_MAX_ROWS", 0)
-					maxDays := envInt("ATTEST_HISTORY_MAX_AGE_DAYS", 0)
-					if maxRows > 0 || maxDays > 0 {
-						if maxRows <= 0 {
-							maxRows = 10000
-						}
-						if maxDays <= 0 {
-							maxDays = 30
-						}
-						hs.SetPruneConfig(maxRows, maxDays)
-					}
-					historyStore = hs
-					logger.Info("history store enabled", "db", dbPath)
-				}
+	if store != nil {
+		hs := store.History()
+		// Configure retention from env vars.
+		maxRows := envInt("ATTEST_HISTORY_MAX_ROWS", 0)
+		maxDays := envInt("ATTEST_HISTORY_MAX_AGE_DAYS", 0)
+		if maxRows > 0 || maxDays > 0 {
+			if maxRows <= 0 {
+				maxRows = 10000
 			}
+			if maxDays <= 0 {
+				maxDays = 30
+			}
+			hs.SetPruneConfig(maxRows, maxDays)
 		}
+		historyStore = hs
+		opts = append(opts, assertion.WithHistory(hs))
+		logger.Info("history store enabled")
 	}
 
-	return opts, caps, judgeProvider, historyStore
+	return opts, caps, judgeProvider, historyStore, probes, configured
 }
 
-// openHistoryDB opens the SQLite database at dbPath for the history store.
-func openHistoryDB(dbPath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", dbPath)
+// cacheStoreConfig sizes the caches from ATTEST_EMBEDDING_CACHE_MAX_MB
+// (default 500) and ATTEST_JUDGE_CACHE_MAX_MB (default 100, clamped to
+// 10-10000), and enables encryption at rest when ATTEST_CACHE_KEY is set: to a
+// 32-byte key in base64 or hex, or to "keychain" to read the key from the OS
+// keychain. A key that cannot be loaded is an error rather than a fallback to
+// plaintext.
+func cacheStoreConfig() (cache.StoreConfig, error) {
+	judgeCacheMaxMB := envInt("ATTEST_JUDGE_CACHE_MAX_MB", 100)
+	if judgeCacheMaxMB < 10 {
+		judgeCacheMaxMB = 10
+	} else if judgeCacheMaxMB > 10000 {
+		judgeCacheMaxMB = 10000
+	}
+	cfg := cache.StoreConfig{
+		EmbeddingMaxMB: envInt("ATTEST_EMBEDDING_CACHE_MAX_MB", 500),
+		JudgeMaxMB:     judgeCacheMaxMB,
+	}
+
+	raw := os.Getenv("ATTEST_CACHE_KEY")
+	if raw == "" {
+		return cfg, nil
+	}
+	var key []byte
+	var err error
+	if raw == "keychain" {
+		key, err = cache.KeychainKey()
+	} else {
+		key, err = cache.ParseKey(raw)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite: %w", err)
+		return cfg, fmt.Errorf("ATTEST_CACHE_KEY: %w", err)
+	}
+	if cfg.Cipher, err = cache.NewCipher(key); err != nil {
+		return cfg, fmt.Errorf("ATTEST_CACHE_KEY: %w", err)
 	}
-	return db, nil
+	return cfg, nil
 }
 
-// buildJudgeProvider selects and constructs an LLM provider for judging.
-// Reads ATTEST_JUDGE_PROVIDER and corresponding API keys.
-// Returns an error if the provider is explicitly set to an unimplemented or unknown value.
-func buildJudgeProvider(logger *slog.Logger) (llm.Provider, string, error) {
-	preferred := os.Getenv("ATTEST_JUDGE_PROVIDER")
-	model := os.Getenv("ATTEST_JUDGE_MODEL")
-
-	// If explicitly set, validate before attempting construction.
-	if preferred != "" {
-		switch preferred {
-		case "openai":
-			// handled below
-		case "anthropic", "gemini", "ollama":
-			return nil, "", fmt.Errorf(
-				"ATTEST_JUDGE_PROVIDER=%q is not yet implemented; supported: openai",
-				preferred,
-			)
-		default:
-			return nil, "", fmt.Errorf(
-				"ATTEST_JUDGE_PROVIDER=%q is unknown; supported: openai",
-				preferred,
-			)
+// openCacheStore opens the shared attest.db in the cache directory, migrating
+// it to the current schema. With ATTEST_CACHE_MODE=memory the caches and history
+// live in an in-memory database instead and nothing is written to disk, and
+// ATTEST_REMOTE_CACHE_URL adds a shared tier in front of either.
+// Returns nil (caching and history disabled) on failure.
+func openCacheStore(logger *slog.Logger) *cache.Store {
+	cfg, err := cacheStoreConfig()
+	if err != nil {
+		logger.Warn("cache encryption key unavailable, caching disabled", "err", err)
+		return nil
+	}
+
+	switch mode := os.Getenv("ATTEST_CACHE_MODE"); mode {
+	case "memory":
+		cfg.Remote = openRemoteCache(logger)
+		store, err := cache.OpenMemoryStore(cfg)
+		if err != nil {
+			closeRemote(cfg.Remote)
+			logger.Warn("failed to open in-memory cache database", "err", err)
+			return nil
 		}
+		logger.Info("cache database opened", "mode", "memory")
+		return store
+	case "", "disk":
+	default:
+		logger.Warn("unknown ATTEST_CACHE_MODE, using disk", "mode", mode)
 	}
 
-	// Try OpenAI (the only implemented provider).
-	key := os.Getenv("ATTEST_OPENAI_API_KEY")
-	if key == "" {
+	cacheDir := cacheDirectory()
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		logger.Warn("failed to create cache dir", "dir", cacheDir, "err", err)
+		return nil
+	}
+
+	dbPath := filepath.Join(cacheDir, "attest.db")
+	cfg.Remote = openRemoteCache(logger)
+	store, err := cache.OpenStore(dbPath, cfg)
+	if err != nil {
+		closeRemote(cfg.Remote)
+		logger.Warn("failed to open cache database", "db", dbPath, "err", err)
+		return nil
+	}
+	logger.Info("cache database opened", "db", dbPath)
+	return store
+}
+
+// openRemoteCache connects to the shared cache tier named by
+// ATTEST_REMOTE_CACHE_URL (redis:// or rediss://). Entries expire after
+// ATTEST_REMOTE_CACHE_TTL_HOURS (default 168; 0 disables expiry). Returns nil
+// when unset or unreachable, leaving the local cache as the only tier.
+func openRemoteCache(logger *slog.Logger) cache.RemoteCache {
+	rawURL := os.Getenv("ATTEST_REMOTE_CACHE_URL")
+	if rawURL == "" {
+		return nil
+	}
+	ttl := time.Duration(envInt("ATTEST_REMOTE_CACHE_TTL_HOURS", 168)) * time.Hour
+	rc, err := cache.NewRedisCache(rawURL, ttl)
+	if err != nil {
+		logger.Warn("remote cache unavailable, using local cache only", "err", err)
+		return nil
+	}
+	logger.Info("remote cache enabled", "ttl", ttl)
+	return rc
+}
+
+func closeRemote(rc cache.RemoteCache) {
+	if rc != nil {
+		_ = rc.Close()
+	}
+}
+
+// buildJudgeProvider selects and constructs an LLM provider for judging.
+// Reads ATTEST_JUDGE_PROVIDER (or ATTEST_PROVIDER) and the provider's
+// ATTEST_<NAME>_* settings. With neither set, the first configured chat
+// provider is used.
+// Returns an error if the provider is explicitly set to an unknown value or
+// one without chat support.
+func buildJudgeProvider(logger *slog.Logger, providers *llm.Registry) (llm.Provider, string, error) {
+	name := os.Getenv("ATTEST_JUDGE_PROVIDER")
+	if name != "" && !providers.Supports(name, llm.CapabilityChat) {
+		return nil, "", fmt.Errorf(
+			"ATTEST_JUDGE_PROVIDER=%q is not available; supported: %s",
+			name, strings.Join(providers.Names(llm.CapabilityChat), ", "),
+		)
+	}
+	if name == "" {
+		name = selectedProvider(providers, "ATTEST_JUDGE_PROVIDER", llm.CapabilityChat)
+	}
+	if name == "" {
+		name = configuredProvider(providers, llm.CapabilityChat)
+	}
+	if name == "" {
 		return nil, "", nil
 	}
 
-	p, err := llm.NewOpenAIProvider(key, model, "")
+	cfg := providerConfig(name)
+	if cfg.APIKey == "" && cfg.BaseURL == "" {
+		return nil, "", nil
+	}
+	p, err := providers.Chat(name, cfg)
 	if err != nil {
-		logger.Warn("failed to create OpenAI judge provider", "err", err)
+		logger.Warn("failed to create judge provider", "provider", name, "err", err)
 		return nil, "", nil
 	}
 
@@ -250,10 +413,10 @@ func buildJudgeProvider(logger *slog.Logger) (llm.Provider, string, error) {
 	rlp, rlErr := llm.NewRateLimitedProvider(p, rlCfg)
 	if rlErr != nil {
 		logger.Warn("rate limiter init failed, using bare provider", "err", rlErr)
-		return p, "openai", nil
+		return p, name, nil
 	}
 	logger.Info("judge provider rate limiter configured", "rpm", rlCfg.RequestsPerMinute, "burst", rlCfg.Burst)
-	return rlp, "openai", nil
+	return rlp, name, nil
 }
 
 // buildRateLimiterConfig reads ATTEST_JUDGE_RPM and ATTEST_JUDGE_BURST env vars,
@@ -269,13 +432,9 @@ func buildRateLimiterConfig() llm.RateLimiterConfig {
 	return cfg
 }
 
-// cacheDirectory returns the cache directory from env or default.
+// cacheDirectory returns the cache directory from env or the per-OS default.
 func cacheDirectory() string {
-	if dir := os.Getenv("ATTEST_CACHE_DIR"); dir != "" {
-		return dir
-	}
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".attest", "cache")
+	return paths.CacheDir()
 }
 
 // envInt reads an int from an env var with a fallback default.
@@ -291,6 +450,49 @@ func envInt(key string, fallback int) int {
 	return n
 }
 
+// buildTraceLimits reads the trace limits from ATTEST_MAX_TRACE_SIZE,
+// ATTEST_MAX_STEPS_PER_TRACE, ATTEST_MAX_STEP_PAYLOAD,
+// ATTEST_MAX_SUB_TRACE_DEPTH, and ATTEST_MAX_JSON_DEPTH. Unset or invalid values keep the protocol
+// defaults; values above the trace package ceilings are capped with a warning.
+func buildTraceLimits(logger *slog.Logger) trace.Limits {
+	limits, capped := trace.Limits{
+		MaxTraceSize:     envInt("ATTEST_MAX_TRACE_SIZE", 0),
+		MaxStepsPerTrace: envInt("ATTEST_MAX_STEPS_PER_TRACE", 0),
+		MaxStepPayload:   envInt("ATTEST_MAX_STEP_PAYLOAD", 0),
+		MaxSubTraceDepth: envInt("ATTEST_MAX_SUB_TRACE_DEPTH", 0),
+		MaxJSONDepth:     envInt("ATTEST_MAX_JSON_DEPTH", 0),
+	}.Clamp()
+	for _, name := range capped {
+		logger.Warn("trace limit above its ceiling was capped", "limit", name)
+	}
+	if limits != trace.DefaultLimits {
+		logger.Info("trace limits configured",
+			"max_trace_size", limits.MaxTraceSize,
+			"max_steps_per_trace", limits.MaxStepsPerTrace,
+			"max_step_payload", limits.MaxStepPayload,
+			"max_sub_trace_depth", limits.MaxSubTraceDepth,
+			"max_json_depth", limits.MaxJSONDepth)
+	}
+	return limits
+}
+
+// buildCurrencyRates reads the exchange rates for trace costs from
+// ATTEST_CURRENCY_RATES, a comma-separated list of CODE=RATE pairs giving the
+// USD value of one unit. Unset or invalid values convert only USD.
+func buildCurrencyRates(logger *slog.Logger) trace.CurrencyRates {
+	v := os.Getenv("ATTEST_CURRENCY_RATES")
+	if v == "" {
+		return trace.DefaultCurrencyRates
+	}
+	rates, err := trace.ParseCurrencyRates(v)
+	if err != nil {
+		logger.Warn("ATTEST_CURRENCY_RATES is invalid, only USD costs are counted", "err", err)
+		return trace.DefaultCurrencyRates
+	}
+	logger.Info("currency rates configured", "currencies", rates.Codes())
+	return rates
+}
+
 // buildBudgetTracker constructs a BudgetTracker from ATTEST_BUDGET_MAX_COST.
 // Returns nil when the env var is unset, preserving backward-compatible behavior.
 // The env var is interpreted as a maximum number of soft failures allowed per batch
@@ -313,8 +515,11 @@ func buildBudgetTracker(logger *slog.Logger) *assertion.BudgetTracker {
 	return assertion.NewBudgetTracker(limit)
 }
 
-func handleInitialize(caps []string) Handler {
-	return func(session *Session, params json.RawMessage) (any, *types.RPCError) {
+// handleInitialize negotiates the session. store, when non-nil, is scoped to
+// the requested namespace; auth, when non-nil, requires an auth token and
+// sets the session's role from it.
+func handleInitialize(caps []string, checks *modelChecks, limits trace.Limits, store *cache.Store, auth *authPolicy) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
 		if session.State() != StateUninitialized {
 			return nil, types.NewRPCError(
 				types.ErrSessionError,
@@ -355,6 +560,36 @@ func handleInitialize(caps []string) Handler {
 			)
 		}
 
+		role := RoleAdmin
+		if auth != nil {
+			if role = auth.authenticate(p.AuthToken); role == "" {
+				return nil, types.NewRPCError(
+					types.ErrPermissionDenied,
+					"invalid auth token",
+					types.ErrTypePermissionDenied,
+					false,
+					"this engine requires an auth_token from ATTEST_AUTH_TOKENS",
+				)
+			}
+		}
+
+		if err := cache.ValidateNamespace(p.Namespace); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"invalid namespace",
+				types.ErrTypeSessionError,
+				false,
+				err.Error(),
+			)
+		}
+		if store != nil {
+			store.SetNamespace(p.Namespace)
+		}
+
+		// Capabilities backed by a model that failed validation are unavailable.
+		providerErrors := checks.results(ctx)
+		caps := withoutFailed(caps, providerErrors)
+
 		// Compute missing capabilities.
 		supported := make(map[string]bool, len(caps))
 		for _, c := range caps {
@@ -373,6 +608,13 @@ func handleInitialize(caps []string) Handler {
 			missing = []string{}
 		}
 
+		// Compression is opt-in; any other preference falls back to plain JSON.
+		encoding := types.EncodingJSON
+		if p.PreferredEncoding == types.EncodingJSONGzip {
+			encoding = types.EncodingJSONGzip
+		}
+		session.SetEncoding(encoding)
+		session.SetRole(role)
 		session.SetState(StateInitialized)
 
 		return &types.InitializeResult{
@@ -381,15 +623,20 @@ func handleInitialize(caps []string) Handler {
 			Capabilities:          caps,
 			Missing:               missing,
 			Compatible:            compatible,
-			Encoding:              "json",
+			Encoding:              encoding,
 			MaxConcurrentRequests: 1,
-			MaxTraceSizeBytes:     10 * 1024 * 1024,
-			MaxStepsPerTrace:      10000,
+			MaxTraceSizeBytes:     limits.MaxTraceSize,
+			MaxStepsPerTrace:      limits.MaxStepsPerTrace,
+			MaxStepPayloadBytes:   limits.MaxStepPayload,
+			MaxSubTraceDepth:      limits.MaxSubTraceDepth,
+			ProviderErrors:        providerErrors,
+			Namespace:             p.Namespace,
+			Role:                  authRole(auth, role),
 		}, nil
 	}
 }
 
-func handleShutdown(session *Session, _ json.RawMessage) (any, *types.RPCError) {
+func handleShutdown(_ context.Context, session *Session, _ json.RawMessage) (any, *types.RPCError) {
 	if session.State() != StateInitialized {
 		return nil, types.NewRPCError(
 			types.ErrSessionError,
@@ -415,8 +662,31 @@ func handleShutdown(session *Session, _ json.RawMessage) (any, *types.RPCError)
 	}, nil
 }
 
-func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.HistoryStore, budget *assertion.BudgetTracker, writeNotification func(any)) Handler {
-	return func(session *Session, params json.RawMessage) (any, *types.RPCError) {
+// handleGetMetrics reports engine counters, including writes waiting in the
+// dead-letter queue.
+func handleGetMetrics(deadLetters *deadLetterQueue, admit *admission, startedAt time.Time) Handler {
+	return func(_ context.Context, session *Session, _ json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"get_metrics called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+		_, evaluated := session.Stats()
+		return &types.GetMetricsResult{
+			UptimeS:             time.Since(startedAt).Seconds(),
+			AssertionsEvaluated: int(evaluated),
+			DeadLetter:          deadLetters.metrics(),
+			Memory:              admit.metrics(),
+		}, nil
+	}
+}
+
+func handleEvaluateBatch(pipeline *assertion.Pipeline, templates *assertion.TemplateRegistry, limits trace.Limits, rates trace.CurrencyRates, uploads *traceUploads, admit *admission, historyStore *cache.HistoryStore, deadLetters *deadLetterQueue, budget *assertion.BudgetTracker, recent *recentBatches, alerts *driftAlerter) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
 		if session.State() != StateInitialized {
 			return nil, types.NewRPCError(
 				types.ErrSessionError,
@@ -427,6 +697,59 @@ func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.Histo
 			)
 		}
 
+		// A trace_ref names a trace uploaded in chunks; it stands in for trace.
+		rawTrace, ok := trace.RawField(params, "trace")
+		uploaded := false
+		if rawRef, hasRef := trace.RawField(params, "trace_ref"); hasRef {
+			if ok {
+				return nil, types.NewRPCError(
+					types.ErrInvalidTrace,
+					"evaluate_batch params set both trace and trace_ref",
+					types.ErrTypeInvalidTrace,
+					false,
+					"Send the trace inline in trace or upload it with begin_trace and pass the trace_ref string, not both.",
+				)
+			}
+			var ref string
+			if err := json.Unmarshal(rawRef, &ref); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrInvalidTrace,
+					fmt.Sprintf("invalid evaluate_batch params: trace_ref: %v", err),
+					types.ErrTypeInvalidTrace,
+					false,
+					"Pass the trace_ref string returned by end_trace.",
+				)
+			}
+			raw, rpcErr := uploads.get(ref)
+			if rpcErr != nil {
+				return nil, rpcErr
+			}
+			rawTrace, ok, uploaded = raw, true, true
+		}
+
+		// Large batches wait their turn rather than decode concurrently and
+		// exhaust memory.
+		weight := len(params)
+		if uploaded {
+			weight += len(rawTrace)
+		}
+		release, admitErr := admit.acquire(ctx, weight)
+		if admitErr != nil {
+			return nil, admitErr
+		}
+		defer release()
+
+		// Enforce trace limits on the raw bytes first so oversized traces are
+		// rejected before being decoded; the measured sizes are reused by Validate.
+		var scanned *trace.ScanStats
+		if ok {
+			stats, rpcErr := limits.Scan(rawTrace)
+			if rpcErr != nil {
+				return nil, rpcErr
+			}
+			scanned = stats
+		}
+
 		var p types.EvaluateBatchParams
 		if err := json.Unmarshal(params, &p); err != nil {
 			return nil, types.NewRPCError(
@@ -438,6 +761,35 @@ func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.Histo
 			)
 		}
 
+		if p.TraceRef != "" {
+			if err := json.Unmarshal(rawTrace, &p.Trace); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrInvalidTrace,
+					fmt.Sprintf("invalid uploaded trace: %v", err),
+					types.ErrTypeInvalidTrace,
+					false,
+					"Upload the compact JSON of a single trace object.",
+				)
+			}
+		}
+
+		if len(p.Traces) > 0 {
+			if scanned != nil {
+				return nil, types.NewRPCError(
+					types.ErrInvalidTrace,
+					"evaluate_batch params set both trace and traces",
+					types.ErrTypeInvalidTrace,
+					false,
+					"Send a nested trace in trace or a flat list linked by parent_trace_id in traces, not both.",
+				)
+			}
+			root, rpcErr := stitchTraces(p.Traces)
+			if rpcErr != nil {
+				return nil, rpcErr
+			}
+			p.Trace = *root
+		}
+
 		// E6: Validate assertion ID lengths before processing.
 		for _, a := range p.Assertions {
 			if len(a.AssertionID) > MaxAssertionIDLength {
@@ -449,31 +801,57 @@ func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.Histo
 					fmt.Sprintf("assertion_id must be at most %d characters", MaxAssertionIDLength),
 				)
 			}
+			if a.SampleRate != nil && (*a.SampleRate < 0 || *a.SampleRate > 1) {
+				return nil, types.NewRPCError(
+					types.ErrAssertionError,
+					fmt.Sprintf("assertion %s: sample_rate %g outside [0, 1]", a.AssertionID, *a.SampleRate),
+					types.ErrTypeAssertionError,
+					false,
+					"Set sample_rate to a fraction between 0 and 1, or omit it to evaluate every trace.",
+				)
+			}
 		}
-
-		trace.Normalize(&p.Trace)
-		// Compute trace size once to avoid re-serialization in Validate.
-		traceBytes, marshalErr := json.Marshal(&p.Trace)
-		if marshalErr != nil {
+		if err := templates.ExpandAll(p.Assertions); err != nil {
 			return nil, types.NewRPCError(
-				types.ErrInvalidTrace,
-				"trace could not be serialized for size check",
-				types.ErrTypeInvalidTrace,
+				types.ErrAssertionError,
+				fmt.Sprintf("template expansion failed: %v", err),
+				types.ErrTypeAssertionError,
 				false,
-				"Ensure all trace fields contain valid JSON-serializable values.",
+				"Register the template with register_template or ATTEST_TEMPLATES, and pass every required param.",
 			)
 		}
-		if rpcErr := trace.Validate(&p.Trace, len(traceBytes)); rpcErr != nil {
+
+		trace.Normalize(&p.Trace)
+		// Sizes come from the raw request bytes; Validate only re-marshals when
+		// the scan could not measure them.
+		var rpcErr *types.RPCError
+		if scanned != nil {
+			rpcErr = limits.ValidateScanned(&p.Trace, scanned)
+		} else {
+			rpcErr = limits.Validate(&p.Trace, 0)
+		}
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if rpcErr := trace.CheckStrict(p.Strict); rpcErr != nil {
+			return nil, rpcErr
+		}
+		warnings := append(trace.Warnings(&p.Trace, scanned), rates.Convert(&p.Trace)...)
+		if p.Trace.SchemaVersion < trace.CurrentSchemaVersion {
+			deprecation.Report(ctx, deprecation.SchemaVersionNotice(p.Trace.SchemaVersion, trace.CurrentSchemaVersion))
+		}
+		if rpcErr := trace.Promote(warnings, p.Strict); rpcErr != nil {
 			return nil, rpcErr
 		}
 
 		type assertionMeta struct {
 			assertionType string
 			dynamic       bool
+			sampleRate    float64
 		}
 		assertionMap := make(map[string]assertionMeta, len(p.Assertions))
 		for _, a := range p.Assertions {
-			meta := assertionMeta{assertionType: a.Type}
+			meta := assertionMeta{assertionType: a.Type, sampleRate: assertion.SampleRate(&a)}
 			var spec struct {
 				Threshold string `json:"threshold"`
 			}
@@ -485,7 +863,13 @@ func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.Histo
 			assertionMap[a.AssertionID] = meta
 		}
 
-		result, err := pipeline.EvaluateBatchWithBudget(&p.Trace, p.Assertions, budget)
+		recent.add(&p.Trace, p.Assertions)
+
+		result, err := pipeline.EvaluateBatchWithOptions(&p.Trace, p.Assertions, assertion.BatchOptions{
+			Budget:  budget,
+			Seed:    p.Seed,
+			Context: ctx,
+		})
 		if err != nil {
 			return nil, types.NewRPCError(
 				types.ErrEngineError,
@@ -499,32 +883,19 @@ func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.Histo
 		if historyStore != nil {
 			for i := range result.Results {
 				ar := &result.Results[i]
+				if ar.Status == types.StatusSkipped {
+					continue
+				}
 				meta := assertionMap[ar.AssertionID]
-				// E3: Log history store record errors instead of silently discarding.
-				if recErr := historyStore.Record(p.Trace.TraceID, ar.AssertionID, meta.assertionType, ar.Score, ar.Status); recErr != nil {
-					slog.Error("history store record error", "assertion_id", ar.AssertionID, "err", recErr)
+				// Shadow assertions record their real outcome.
+				status := ar.Status
+				if ar.ShadowStatus != "" {
+					status = ar.ShadowStatus
 				}
+				deadLetters.recordHistory(p.Trace.TraceID, ar.AssertionID, meta.assertionType, ar.Score, status, ar.Cost, meta.sampleRate)
 
-				// Emit drift_alert notification when dynamic assertion hard-fails.
-				// E2: Use writeNotification (mutex-protected) instead of bare os.Stdout encoder.
-				if meta.dynamic && ar.Status == types.StatusHardFail {
-					mean, stddev, count, statsErr := historyStore.Stats(ar.AssertionID)
-					if statsErr == nil {
-						notification := types.DriftAlertNotification{
-							JSONRPC: "2.0",
-							Method:  "drift_alert",
-							Params: types.DriftReport{
-								AssertionID: ar.AssertionID,
-								Mean:        mean,
-								Stddev:      stddev,
-								Count:       count,
-								LatestScore: ar.Score,
-								Deviation:   ar.Score - mean,
-								Status:      "drift_detected",
-							},
-						}
-						writeNotification(notification)
-					}
+				if meta.dynamic {
+					alerts.observe(ctx, historyStore, p.Trace.TraceID, ar)
 				}
 			}
 		}
@@ -535,12 +906,15 @@ func handleEvaluateBatch(pipeline *assertion.Pipeline, historyStore *cache.Histo
 			Results:         result.Results,
 			TotalCost:       result.TotalCost,
 			TotalDurationMS: result.TotalDurationMS,
+			Timings:         result.Timings,
+			Summary:         result.Summary,
+			Warnings:        warnings,
 		}, nil
 	}
 }
 
 func handleQueryDrift(historyStore *cache.HistoryStore) Handler {
-	return func(session *Session, params json.RawMessage) (any, *types.RPCError) {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
 		if session.State() != StateInitialized {
 			return nil, types.NewRPCError(
 				types.ErrSessionError,
@@ -623,8 +997,187 @@ func handleQueryDrift(historyStore *cache.HistoryStore) Handler {
 	}
 }
 
-func handleSubmitPluginResult(historyStore *cache.HistoryStore) Handler {
-	return func(session *Session, params json.RawMessage) (any, *types.RPCError) {
+func handleQueryFlaky(historyStore *cache.HistoryStore) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"query_flaky called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.QueryFlakyParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrAssertionError,
+					"invalid query_flaky params",
+					types.ErrTypeAssertionError,
+					false,
+					err.Error(),
+				)
+			}
+		}
+		// Quarantining changes what later runs report, so it is for admins.
+		if role := session.Role(); p.Quarantine && role == RoleEvaluate {
+			return nil, permissionDenied(role, "query_flaky with quarantine")
+		}
+
+		if historyStore == nil {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				"history store not available",
+				types.ErrTypeEngineError,
+				false,
+				"history store failed to initialize at startup",
+			)
+		}
+
+		cfg := assertion.DefaultFlakinessConfig
+		if p.WindowSize > 0 {
+			cfg.WindowSize = p.WindowSize
+		}
+
+		ids := p.AssertionIDs
+		if len(ids) == 0 {
+			var err error
+			if ids, err = historyStore.AssertionIDs(); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrEngineError,
+					fmt.Sprintf("query_flaky failed: %v", err),
+					types.ErrTypeEngineError,
+					false,
+					"error listing assertion history",
+				)
+			}
+		}
+
+		reports := make([]types.FlakinessReport, 0, len(ids))
+		for _, id := range ids {
+			statuses, err := historyStore.QueryStatusWindow(id, cfg.WindowSize)
+			if err != nil {
+				return nil, types.NewRPCError(
+					types.ErrEngineError,
+					fmt.Sprintf("query_flaky failed: %v", err),
+					types.ErrTypeEngineError,
+					false,
+					"error querying assertion history",
+				)
+			}
+			report := assertion.AnalyzeFlakiness(id, statuses, cfg)
+
+			if report.Flaky && p.Quarantine {
+				reason := fmt.Sprintf("flaky: %d switches in %d runs", report.SwitchCount, report.Runs)
+				if err := historyStore.Quarantine(id, reason); err != nil {
+					return nil, types.NewRPCError(
+						types.ErrEngineError,
+						fmt.Sprintf("query_flaky quarantine failed: %v", err),
+						types.ErrTypeEngineError,
+						false,
+						"error updating quarantine list",
+					)
+				}
+			}
+			if report.Quarantined, err = historyStore.IsQuarantined(id); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrEngineError,
+					fmt.Sprintf("query_flaky failed: %v", err),
+					types.ErrTypeEngineError,
+					false,
+					"error reading quarantine list",
+				)
+			}
+			reports = append(reports, report)
+		}
+
+		return &types.QueryFlakyResult{Reports: reports}, nil
+	}
+}
+
+func handleUpdateQuarantine(historyStore *cache.HistoryStore) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"update_quarantine called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.UpdateQuarantineParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrAssertionError,
+					"invalid update_quarantine params",
+					types.ErrTypeAssertionError,
+					false,
+					err.Error(),
+				)
+			}
+		}
+
+		if historyStore == nil {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				"history store not available",
+				types.ErrTypeEngineError,
+				false,
+				"history store failed to initialize at startup",
+			)
+		}
+
+		reason := p.Reason
+		if reason == "" {
+			reason = "manual"
+		}
+		for _, id := range p.Add {
+			if err := historyStore.Quarantine(id, reason); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrEngineError,
+					fmt.Sprintf("update_quarantine failed: %v", err),
+					types.ErrTypeEngineError,
+					false,
+					"error updating quarantine list",
+				)
+			}
+		}
+		for _, id := range p.Remove {
+			if err := historyStore.Unquarantine(id); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrEngineError,
+					fmt.Sprintf("update_quarantine failed: %v", err),
+					types.ErrTypeEngineError,
+					false,
+					"error updating quarantine list",
+				)
+			}
+		}
+
+		ids, err := historyStore.QuarantinedIDs()
+		if err != nil {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("update_quarantine failed: %v", err),
+				types.ErrTypeEngineError,
+				false,
+				"error reading quarantine list",
+			)
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		return &types.UpdateQuarantineResult{Quarantined: ids}, nil
+	}
+}
+
+func handleSubmitPluginResult(historyStore *cache.HistoryStore, deadLetters *deadLetterQueue) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
 		if session.State() != StateInitialized {
 			return nil, types.NewRPCError(
 				types.ErrSessionError,
@@ -648,9 +1201,7 @@ func handleSubmitPluginResult(historyStore *cache.HistoryStore) Handler {
 
 		// E1: Record plugin result in history store.
 		if historyStore != nil {
-			if recErr := historyStore.Record(p.TraceID, p.AssertionID, "plugin", p.Result.Score, p.Result.Status); recErr != nil {
-				slog.Error("history store record error", "assertion_id", p.AssertionID, "err", recErr)
-			}
+			deadLetters.recordHistory(p.TraceID, p.AssertionID, "plugin", p.Result.Score, p.Result.Status, 0, 1)
 		}
 
 		session.IncrementAssertions(1)
@@ -659,8 +1210,8 @@ func handleSubmitPluginResult(historyStore *cache.HistoryStore) Handler {
 	}
 }
 
-func handleValidateTraceTree() Handler {
-	return func(session *Session, params json.RawMessage) (any, *types.RPCError) {
+func handleValidateTraceTree(limits trace.Limits) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
 		if session.State() != StateInitialized {
 			return nil, types.NewRPCError(
 				types.ErrSessionError,
@@ -684,13 +1235,26 @@ func handleValidateTraceTree() Handler {
 
 		result := &types.ValidateTraceTreeResult{}
 
-		if err := trace.ValidateTraceTree(&p.Trace); err != nil {
+		if len(p.Traces) > 0 {
+			root, err := trace.BuildTree(p.Traces)
+			if err != nil {
+				result.Errors = []string{err.Error()}
+				return result, nil
+			}
+			p.Trace = *root
+		}
+
+		if err := limits.ValidateTraceTree(&p.Trace); err != nil {
 			result.Valid = false
 			result.Errors = []string{err.Error()}
 		} else {
 			result.Valid = true
 		}
 
+		result.Warnings = trace.TemporalWarnings(&p.Trace)
+		size, sizeWarnings := limits.MeasureSize(&p.Trace)
+		result.Size = size
+		result.Warnings = append(result.Warnings, sizeWarnings...)
 		result.Depth = trace.TreeDepth(&p.Trace)
 		agentIDs := trace.AgentIDs(&p.Trace)
 		result.AgentIDs = agentIDs
@@ -706,7 +1270,7 @@ func handleValidateTraceTree() Handler {
 }
 
 func handleGenerateUserMessage(provider llm.Provider) Handler {
-	return func(session *Session, params json.RawMessage) (any, *types.RPCError) {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
 		if session.State() != StateInitialized {
 			return nil, types.NewRPCError(
 				types.ErrSessionError,
@@ -728,13 +1292,10 @@ func handleGenerateUserMessage(provider llm.Provider) Handler {
 			)
 		}
 
-		persona := simulation.Persona{
-			Name:         p.Persona.Name,
-			SystemPrompt: p.Persona.SystemPrompt,
-			Style:        p.Persona.Style,
-			Temperature:  p.Persona.Temperature,
-			MaxTokens:    p.Persona.MaxTokens,
+		if rpcErr := validatePersonaActions(p.Persona); rpcErr != nil {
+			return nil, rpcErr
 		}
+		persona := simulationPersona(p.Persona)
 
 		var prov llm.Provider = provider
 		if p.FaultConfig != nil {
@@ -744,17 +1305,59 @@ func handleGenerateUserMessage(provider llm.Provider) Handler {
 				ContentCorruption: p.FaultConfig.ContentCorruption,
 				TimeoutAfter:      time.Duration(p.FaultConfig.TimeoutAfterMS) * time.Millisecond,
 			}
-			prov = simulation.NewFaultInjector(prov, fc)
+			if p.Seed != nil {
+				prov = simulation.NewFaultInjectorWithSeed(prov, fc, *p.Seed)
+			} else {
+				prov = simulation.NewFaultInjector(prov, fc)
+			}
 		}
 
 		user := simulation.NewSimulatedUser(persona, prov)
+		if p.Seed != nil {
+			user = simulation.NewSimulatedUserWithSeed(persona, prov, *p.Seed)
+		}
 
 		messages := make([]llm.Message, 0, len(p.ConversationHistory))
 		for _, m := range p.ConversationHistory {
 			messages = append(messages, llm.Message{Role: m.Role, Content: m.Content})
 		}
 
-		msg, err := user.GenerateMessage(context.Background(), messages)
+		n := p.N
+		if n == 0 {
+			n = 1
+		}
+		if n < 1 || n > simulation.MaxCandidates {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("n must be between 1 and %d, got %d", simulation.MaxCandidates, p.N),
+				types.ErrTypeAssertionError,
+				false,
+				"request fewer candidates per call",
+			)
+		}
+		switch p.Rank {
+		case "", simulation.RankAdversarial:
+		case simulation.RankGoal:
+			if p.Goal == "" {
+				return nil, types.NewRPCError(
+					types.ErrAssertionError,
+					`rank "goal" requires goal`,
+					types.ErrTypeAssertionError,
+					false,
+					"describe the simulated user's goal in the goal field",
+				)
+			}
+		default:
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("unsupported rank %q", p.Rank),
+				types.ErrTypeAssertionError,
+				false,
+				fmt.Sprintf("rank must be %q or %q", simulation.RankGoal, simulation.RankAdversarial),
+			)
+		}
+
+		candidates, err := user.GenerateCandidates(ctx, messages, n)
 		if err != nil {
 			return nil, types.NewRPCError(
 				types.ErrEngineError,
@@ -764,7 +1367,427 @@ func handleGenerateUserMessage(provider llm.Provider) Handler {
 				"check LLM provider availability and retry",
 			)
 		}
+		if p.Rank != "" {
+			// Rank with the undecorated provider so fault injection only
+			// affects the simulated user.
+			if err := simulation.RankCandidates(ctx, provider, p.Rank, p.Goal, messages, candidates); err != nil {
+				return nil, types.NewRPCError(
+					types.ErrAssertionError,
+					fmt.Sprintf("rank candidates: %v", err),
+					types.ErrTypeAssertionError,
+					false,
+					"check the rank and goal fields",
+				)
+			}
+		}
+
+		events := make([]simulation.UserEvent, len(candidates))
+		for i, c := range candidates {
+			events[i] = simulation.UserEvent{Message: c.Message}
+			if len(persona.Actions) > 0 {
+				events[i] = simulation.ParseUserEvent(c.Message, persona.Actions)
+			}
+		}
+		result := &types.GenerateUserMessageResult{Message: events[0].Message, Actions: events[0].Actions}
+		for _, c := range candidates {
+			result.Cost += c.Cost
+		}
+		if p.N > 1 || p.Rank != "" {
+			result.Candidates = make([]types.UserMessageCandidate, len(candidates))
+			for i, c := range candidates {
+				result.Candidates[i] = types.UserMessageCandidate{Message: events[i].Message, Actions: events[i].Actions, Score: c.Score, Explanation: c.Explanation}
+			}
+		}
+		return result, nil
+	}
+}
+
+// Limits for run_simulation and run_simulation_batch.
+const (
+	maxSimulationTurns    = 50
+	defaultAgentTimeoutMS = 60000
+	maxSimulationRuns     = 1000
+	maxBatchConcurrency   = 16
+)
+
+// simulationConfig validates p and builds the orchestrator config for it.
+func simulationConfig(method string, provider llm.Provider, p *types.RunSimulationParams) (simulation.SimulationConfig, *types.RPCError) {
+	if p.InitialPrompt == "" {
+		return simulation.SimulationConfig{}, types.NewRPCError(
+			types.ErrAssertionError,
+			method+" missing required field: initial_prompt",
+			types.ErrTypeAssertionError,
+			false,
+			"initial_prompt is the simulated user's first message",
+		)
+	}
+	if p.MaxTurns < 1 || p.MaxTurns > maxSimulationTurns {
+		return simulation.SimulationConfig{}, types.NewRPCError(
+			types.ErrAssertionError,
+			fmt.Sprintf("max_turns must be between 1 and %d, got %d", maxSimulationTurns, p.MaxTurns),
+			types.ErrTypeAssertionError,
+			false,
+			"set max_turns to the simulation's turn budget",
+		)
+	}
+	if p.AgentTimeoutMS < 0 {
+		return simulation.SimulationConfig{}, types.NewRPCError(
+			types.ErrAssertionError,
+			fmt.Sprintf("agent_timeout_ms must not be negative, got %d", p.AgentTimeoutMS),
+			types.ErrTypeAssertionError,
+			false,
+			"omit agent_timeout_ms for the 60000 ms default",
+		)
+	}
+	if w := p.ContextWindow; w != nil && w.KeepTurns < 1 {
+		return simulation.SimulationConfig{}, types.NewRPCError(
+			types.ErrAssertionError,
+			fmt.Sprintf("context_window.keep_turns must be at least 1, got %d", w.KeepTurns),
+			types.ErrTypeAssertionError,
+			false,
+			"omit context_window to send the full history",
+		)
+	}
+	if rpcErr := validatePersonaActions(p.Persona); rpcErr != nil {
+		return simulation.SimulationConfig{}, rpcErr
+	}
+	agentTimeout := p.AgentTimeoutMS
+	if agentTimeout == 0 {
+		agentTimeout = defaultAgentTimeoutMS
+	}
+
+	config := simulation.SimulationConfig{
+		Persona:      simulationPersona(p.Persona),
+		MaxTurns:     p.MaxTurns,
+		Provider:     provider,
+		Seed:         p.Seed,
+		AgentTimeout: time.Duration(agentTimeout) * time.Millisecond,
+	}
+	if len(p.StopKeywords) > 0 {
+		config.StopConditions = []simulation.StopCondition{simulation.KeywordStopCondition{Keywords: p.StopKeywords}}
+	}
+	if w := p.ContextWindow; w != nil {
+		config.ContextWindow = simulation.ContextWindow{
+			KeepTurns:        w.KeepTurns,
+			Summarize:        w.Summarize,
+			SummaryMaxTokens: w.SummaryMaxTokens,
+		}
+	}
+	return config, nil
+}
+
+func simulationPersona(p types.SimulatePersona) simulation.Persona {
+	return simulation.Persona{
+		Name:         p.Name,
+		SystemPrompt: p.SystemPrompt,
+		Style:        p.Style,
+		Temperature:  p.Temperature,
+		MaxTokens:    p.MaxTokens,
+		Actions:      p.Actions,
+	}
+}
+
+// validatePersonaActions rejects personas with unsupported action types.
+func validatePersonaActions(personas ...types.SimulatePersona) *types.RPCError {
+	for _, persona := range personas {
+		if err := simulation.ValidateActionTypes(persona.Actions); err != nil {
+			return types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("persona %q: %v", persona.Name, err),
+				types.ErrTypeAssertionError,
+				false,
+				"remove the unsupported action type from persona.actions",
+			)
+		}
+	}
+	return nil
+}
+
+// sdkAgent returns an agent callback that asks the SDK for each response
+// with an agent_invoke call.
+func sdkAgent(call func(ctx context.Context, method string, params, result any) error, simulationID string, agentContext json.RawMessage) func(ctx context.Context, event simulation.UserEvent) (string, error) {
+	turn := 0
+	return func(ctx context.Context, event simulation.UserEvent) (string, error) {
+		turn++
+		var r types.AgentInvokeResult
+		err := call(ctx, "agent_invoke", &types.AgentInvokeParams{
+			SimulationID: simulationID,
+			Turn:         turn,
+			Message:      event.Message,
+			Actions:      event.Actions,
+			AgentContext: agentContext,
+		}, &r)
+		return r.Response, err
+	}
+}
+
+// prepareTurnAssertions validates p's per-turn assertions and expands their
+// templates in place.
+func prepareTurnAssertions(templates *assertion.TemplateRegistry, p *types.RunSimulationParams) *types.RPCError {
+	for _, a := range p.TurnAssertions {
+		if len(a.AssertionID) > MaxAssertionIDLength {
+			return types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("assertion_id exceeds maximum length: %d > %d", len(a.AssertionID), MaxAssertionIDLength),
+				types.ErrTypeAssertionError,
+				false,
+				fmt.Sprintf("assertion_id must be at most %d characters", MaxAssertionIDLength),
+			)
+		}
+	}
+	if err := templates.ExpandAll(p.TurnAssertions); err != nil {
+		return types.NewRPCError(
+			types.ErrAssertionError,
+			fmt.Sprintf("template expansion failed: %v", err),
+			types.ErrTypeAssertionError,
+			false,
+			"Register the template with register_template or ATTEST_TEMPLATES, and pass every required param.",
+		)
+	}
+	return nil
+}
+
+// turnCheck evaluates assertions after each simulation turn against a trace
+// whose input and output are the turn's user message and agent response and
+// whose transcript is the conversation so far.
+func turnCheck(pipeline *assertion.Pipeline, assertions []types.Assertion, simulationID string, seed *int64) func(ctx context.Context, turn int, transcript []types.Message) ([]types.AssertionResult, error) {
+	return func(ctx context.Context, turn int, transcript []types.Message) ([]types.AssertionResult, error) {
+		input, err := json.Marshal(map[string]any{"message": transcript[len(transcript)-2].Content})
+		if err != nil {
+			return nil, err
+		}
+		output, err := json.Marshal(map[string]any{"message": transcript[len(transcript)-1].Content})
+		if err != nil {
+			return nil, err
+		}
+		tr := &types.Trace{
+			SchemaVersion: trace.CurrentSchemaVersion,
+			TraceID:       fmt.Sprintf("%s-turn-%d", simulationID, turn),
+			Input:         input,
+			Output:        output,
+			Transcript:    transcript,
+		}
+		result, err := pipeline.EvaluateBatchWithOptions(tr, assertions, assertion.BatchOptions{Seed: seed, Context: ctx})
+		if err != nil {
+			return nil, err
+		}
+		return result.Results, nil
+	}
+}
+
+// simulationTurnResults lists the per-turn assertion results of sim.
+func simulationTurnResults(sim *simulation.SimulationResult) []types.SimulationTurnResult {
+	var turns []types.SimulationTurnResult
+	for _, t := range sim.Turns {
+		if len(t.Assertions) > 0 {
+			turns = append(turns, types.SimulationTurnResult{Turn: t.TurnNumber, Results: t.Assertions})
+		}
+	}
+	return turns
+}
+
+// handleRunSimulation drives a simulated user against the SDK-hosted agent,
+// asking the SDK for each agent response with an agent_invoke call.
+func handleRunSimulation(provider llm.Provider, pipeline *assertion.Pipeline, templates *assertion.TemplateRegistry, call func(ctx context.Context, method string, params, result any) error) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"run_simulation called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.RunSimulationParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				"invalid run_simulation params",
+				types.ErrTypeAssertionError,
+				false,
+				err.Error(),
+			)
+		}
+		config, rpcErr := simulationConfig("run_simulation", provider, &p)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if rpcErr := prepareTurnAssertions(templates, &p); rpcErr != nil {
+			return nil, rpcErr
+		}
 
-		return &types.GenerateUserMessageResult{Message: msg}, nil
+		simulationID := logging.RequestID(ctx)
+		if len(p.TurnAssertions) > 0 {
+			config.TurnCheck = turnCheck(pipeline, p.TurnAssertions, simulationID, p.Seed)
+		}
+		initial := simulation.UserEvent{Message: p.InitialPrompt, Actions: p.InitialActions}
+		sim, err := simulation.NewOrchestrator(config).RunSimulationEvents(ctx, initial, sdkAgent(call, simulationID, p.AgentContext))
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, types.NewRPCError(
+					types.ErrTimeout,
+					fmt.Sprintf("run_simulation: %v", err),
+					types.ErrTypeTimeout,
+					true,
+					"raise agent_timeout_ms or speed up the agent",
+				)
+			}
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("run_simulation failed: %v", err),
+				types.ErrTypeEngineError,
+				false,
+				"answer each agent_invoke request with {\"response\": ...} or a JSON-RPC error",
+			)
+		}
+		return &types.RunSimulationResult{
+			SimulationID:  simulationID,
+			Transcript:    sim.Transcript(),
+			TotalTurns:    sim.TotalTurns,
+			StoppedBy:     sim.StoppedBy,
+			Cost:          sim.Cost,
+			SummaryCost:   sim.SummaryCost,
+			TurnResults:   simulationTurnResults(sim),
+			AssertionCost: sim.AssertionCost,
+		}, nil
+	}
+}
+
+// handleRunSimulationBatch runs one scenario many times against the
+// SDK-hosted agent and reports aggregate statistics.
+func handleRunSimulationBatch(provider llm.Provider, pipeline *assertion.Pipeline, templates *assertion.TemplateRegistry, call func(ctx context.Context, method string, params, result any) error) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"run_simulation_batch called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.RunSimulationBatchParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				"invalid run_simulation_batch params",
+				types.ErrTypeAssertionError,
+				false,
+				err.Error(),
+			)
+		}
+		config, rpcErr := simulationConfig("run_simulation_batch", provider, &p.RunSimulationParams)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if rpcErr := prepareTurnAssertions(templates, &p.RunSimulationParams); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if p.Runs < 1 || p.Runs > maxSimulationRuns {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("runs must be between 1 and %d, got %d", maxSimulationRuns, p.Runs),
+				types.ErrTypeAssertionError,
+				false,
+				"split larger experiments across several calls",
+			)
+		}
+		if p.Concurrency < 0 || p.Concurrency > maxBatchConcurrency {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("concurrency must be between 1 and %d, got %d", maxBatchConcurrency, p.Concurrency),
+				types.ErrTypeAssertionError,
+				false,
+				fmt.Sprintf("omit concurrency for the default of %d", simulation.DefaultBatchConcurrency),
+			)
+		}
+
+		if rpcErr := validatePersonaActions(p.Personas...); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		batch := simulation.BatchConfig{
+			Base:        config,
+			Initial:     simulation.UserEvent{Message: p.InitialPrompt, Actions: p.InitialActions},
+			Runs:        p.Runs,
+			Concurrency: p.Concurrency,
+		}
+		for _, persona := range p.Personas {
+			batch.Personas = append(batch.Personas, simulationPersona(persona))
+		}
+		batchID := logging.RequestID(ctx)
+		if len(p.TurnAssertions) > 0 {
+			batch.Base.TurnCheck = turnCheck(pipeline, p.TurnAssertions, batchID, p.Seed)
+		}
+		runID := func(run int) string { return fmt.Sprintf("%s-%d", batchID, run) }
+		report := simulation.RunBatch(ctx, batch, func(run int) func(ctx context.Context, event simulation.UserEvent) (string, error) {
+			return sdkAgent(call, runID(run), p.AgentContext)
+		})
+
+		result := &types.RunSimulationBatchResult{
+			Runs:        make([]types.SimulationRunSummary, len(report.Runs)),
+			Succeeded:   report.Succeeded,
+			Failed:      report.Failed,
+			Errored:     report.Errored,
+			SuccessRate: report.SuccessRate,
+			Turns: types.SimulationTurnStats{
+				Min:    report.Turns.Min,
+				Max:    report.Turns.Max,
+				Mean:   report.Turns.Mean,
+				P50:    report.Turns.P50,
+				P90:    report.Turns.P90,
+				Counts: report.Turns.Counts,
+			},
+			TotalCost: report.TotalCost,
+		}
+		for i, r := range report.Runs {
+			summary := types.SimulationRunSummary{
+				Run:          r.Run,
+				SimulationID: runID(r.Run),
+				Persona:      r.Persona,
+				Seed:         r.Seed,
+				Succeeded:    r.Succeeded,
+			}
+			if r.Err != nil {
+				summary.Error = r.Err.Error()
+			} else {
+				summary.TotalTurns = r.Result.TotalTurns
+				summary.StoppedBy = r.Result.StoppedBy
+				summary.Cost = r.Result.Cost + r.Result.AssertionCost
+			}
+			result.Runs[i] = summary
+		}
+		if report.Worst >= 0 {
+			worst := report.Runs[report.Worst]
+			result.Worst = &types.RunSimulationResult{
+				SimulationID:  runID(worst.Run),
+				Transcript:    worst.Result.Transcript(),
+				TotalTurns:    worst.Result.TotalTurns,
+				StoppedBy:     worst.Result.StoppedBy,
+				Cost:          worst.Result.Cost,
+				SummaryCost:   worst.Result.SummaryCost,
+				TurnResults:   simulationTurnResults(worst.Result),
+				AssertionCost: worst.Result.AssertionCost,
+			}
+		}
+		return result, nil
+	}
+}
+
+// stitchTraces nests a flat evaluate_batch trace list into one tree.
+func stitchTraces(traces []types.Trace) (*types.Trace, *types.RPCError) {
+	root, err := trace.BuildTree(traces)
+	if err != nil {
+		return nil, types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("cannot stitch traces: %v", err),
+			types.ErrTypeInvalidTrace,
+			false,
+			"Every trace except one root must set parent_trace_id to the trace_id of another trace in the list.",
+		)
 	}
+	return root, nil
 }
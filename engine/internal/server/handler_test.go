@@ -2,6 +2,12 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/attest-ai/attest/engine/pkg/types"
@@ -127,6 +133,39 @@ func TestHandler_ValidateTraceTree_WithSubTrace(t *testing.T) {
 	}
 }
 
+func TestHandler_ValidateTraceTree_FlatTraces(t *testing.T) {
+	send, recv := initServer(t)
+
+	root := "trace-root"
+	flat := []types.Trace{
+		{TraceID: "trace-child", AgentID: "child", ParentTraceID: &root, Output: json.RawMessage(`{"ok":true}`)},
+		{TraceID: root, AgentID: "root", Output: json.RawMessage(`{"ok":true}`)},
+	}
+	send(2, "validate_trace_tree", types.ValidateTraceTreeParams{Traces: flat})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %+v", resp.Error)
+	}
+	var result types.ValidateTraceTreeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !result.Valid || result.Depth != 1 || result.AgentCount != 2 {
+		t.Errorf("result = %+v, want valid two-agent tree of depth 1", result)
+	}
+
+	missing := "trace-gone"
+	flat[0].ParentTraceID = &missing
+	send(3, "validate_trace_tree", types.ValidateTraceTreeParams{Traces: flat})
+	resp = recv()
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if result.Valid || len(result.Errors) == 0 {
+		t.Errorf("orphan accepted: %+v", result)
+	}
+}
+
 func TestHandler_ValidateTraceTree_InvalidParams(t *testing.T) {
 	send, recv := initServer(t)
 
@@ -402,6 +441,166 @@ func TestHandler_EvaluateBatch_AssertionIDTooLong(t *testing.T) {
 	}
 }
 
+func TestHandler_EvaluateBatch_StrictWarnings(t *testing.T) {
+	send, recv := initServer(t)
+
+	params := types.EvaluateBatchParams{
+		Trace: types.Trace{
+			SchemaVersion: 1,
+			TraceID:       "trace-1",
+			Output:        json.RawMessage(`{"message":"world"}`),
+		},
+		Assertions: []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"world"}`),
+		}},
+	}
+	send(2, "evaluate_batch", params)
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("lenient evaluate_batch error: %+v", resp.Error)
+	}
+	var result types.EvaluateBatchResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	codes := map[string]bool{}
+	for _, w := range result.Warnings {
+		codes[w.Code] = true
+	}
+	if !codes["missing_metadata"] || !codes["empty_steps"] {
+		t.Errorf("warnings = %+v, want missing_metadata and empty_steps", result.Warnings)
+	}
+	// schema_version 1 is also reported as a deprecation on the response.
+	if len(resp.Warnings) != 1 || resp.Warnings[0].Code != "deprecated_schema_version" || resp.Warnings[0].SunsetVersion != "1.0.0" {
+		t.Errorf("response warnings = %+v, want a deprecated_schema_version notice", resp.Warnings)
+	}
+
+	params.Strict = []string{"empty_steps"}
+	send(3, "evaluate_batch", params)
+	resp = recv()
+	if resp.Error == nil || resp.Error.Code != types.ErrInvalidTrace {
+		t.Fatalf("strict evaluate_batch: error = %+v, want INVALID_TRACE", resp.Error)
+	}
+
+	params.Strict = nil
+	params.Trace.SchemaVersion = 2
+	send(4, "evaluate_batch", params)
+	if resp = recv(); resp.Error != nil || len(resp.Warnings) != 0 {
+		t.Errorf("schema_version 2: error %+v, warnings %+v; want neither", resp.Error, resp.Warnings)
+	}
+}
+
+func TestHandler_EvaluateBatch_ConvertsCurrency(t *testing.T) {
+	t.Setenv("ATTEST_CURRENCY_RATES", "EUR=1.5")
+	send, recv := initServer(t)
+
+	cost, eur := 2.0, "EUR"
+	params := types.EvaluateBatchParams{
+		Trace: types.Trace{
+			SchemaVersion: 2,
+			TraceID:       "trace-1",
+			Output:        json.RawMessage(`{"message":"world"}`),
+			Metadata:      &types.TraceMetadata{Cost: &cost, Currency: &eur},
+			Steps: []types.Step{{
+				Type:     types.StepTypeLLMCall,
+				Name:     "credits",
+				Metadata: json.RawMessage(`{"cost":10,"currency":"CREDIT"}`),
+			}},
+		},
+		Assertions: []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeConstraint,
+			Spec:        json.RawMessage(`{"field":"metadata.cost_usd","operator":"eq","value":3}`),
+		}},
+	}
+	send(2, "evaluate_batch", params)
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("evaluate_batch error: %+v", resp.Error)
+	}
+	var result types.EvaluateBatchResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Status != types.StatusPass {
+		t.Errorf("results = %+v, want cost_usd converted to 3", result.Results)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != "unknown_currency" || result.Warnings[0].Step != "credits" {
+		t.Errorf("warnings = %+v, want unknown_currency for step credits", result.Warnings)
+	}
+}
+
+func TestHandler_RegisterTemplate_ExpandsInEvaluateBatch(t *testing.T) {
+	send, recv := initServer(t)
+
+	send(2, "register_template", types.RegisterTemplateParams{
+		Name:   "says",
+		Type:   types.TypeContent,
+		Spec:   json.RawMessage(`{"target":"output.message","check":"contains","value":"{{text}}"}`),
+		Params: []types.TemplateParam{{Name: "text"}},
+	})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("register_template error: %+v", resp.Error)
+	}
+	var reg types.RegisterTemplateResult
+	if err := json.Unmarshal(resp.Result, &reg); err != nil || reg.Name != "says" || reg.Replaced {
+		t.Fatalf("register_template result = %+v, err %v", reg, err)
+	}
+
+	params := types.EvaluateBatchParams{
+		Trace: types.Trace{TraceID: "trace-1", Output: json.RawMessage(`{"message":"hello world"}`)},
+		Assertions: []types.Assertion{
+			{AssertionID: "a1", Template: "says", Params: json.RawMessage(`{"text":"world"}`)},
+			{AssertionID: "a2", Template: "says", Params: json.RawMessage(`{"text":"moon"}`)},
+		},
+	}
+	send(3, "evaluate_batch", params)
+	resp = recv()
+	if resp.Error != nil {
+		t.Fatalf("evaluate_batch error: %+v", resp.Error)
+	}
+	var result types.EvaluateBatchResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(result.Results) != 2 || result.Results[0].Status != types.StatusPass || result.Results[1].Status != types.StatusHardFail {
+		t.Errorf("results = %+v", result.Results)
+	}
+
+	params.Assertions = []types.Assertion{{AssertionID: "a3", Template: "says"}}
+	send(4, "evaluate_batch", params)
+	resp = recv()
+	if resp.Error == nil || resp.Error.Code != types.ErrAssertionError {
+		t.Fatalf("missing param: error = %+v, want ASSERTION_ERROR", resp.Error)
+	}
+
+	send(5, "register_template", types.RegisterTemplateParams{Name: "bad", Type: "content", Spec: json.RawMessage(`{"value":"{{x}}"}`)})
+	resp = recv()
+	if resp.Error == nil || resp.Error.Code != types.ErrAssertionError {
+		t.Fatalf("undeclared param: error = %+v, want ASSERTION_ERROR", resp.Error)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	data := `{"templates":[{"name":"cost_under","type":"constraint","spec":{"field":"metadata.cost_usd","operator":"lte","value":"{{usd}}"},"params":[{"name":"usd","default":0.1}]}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ATTEST_TEMPLATES", path)
+	registry := buildTemplateRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if names := registry.Names(); len(names) != 1 || names[0] != "cost_under" {
+		t.Fatalf("names = %v", names)
+	}
+	a := types.Assertion{AssertionID: "a", Template: "cost_under"}
+	if err := registry.Expand(&a); err != nil || string(a.Spec) != `{"field":"metadata.cost_usd","operator":"lte","value":0.1}` {
+		t.Errorf("expand = %s, %v", a.Spec, err)
+	}
+}
+
 // ── shutdown stats tracking ──
 
 func TestHandler_Shutdown_TracksAssertionCount(t *testing.T) {
@@ -434,3 +633,243 @@ func TestHandler_Shutdown_TracksAssertionCount(t *testing.T) {
 		t.Errorf("AssertionsEvaluated = %d, want >= 1 after submit_plugin_result", result.AssertionsEvaluated)
 	}
 }
+
+// ── query_flaky / update_quarantine ──
+
+func TestHandler_UpdateQuarantineAndQueryFlaky(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_DIR", t.TempDir())
+	send, recv := initServer(t)
+
+	send(2, "update_quarantine", types.UpdateQuarantineParams{Add: []string{"flaky_a", "flaky_b"}})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("update_quarantine: %+v", resp.Error)
+	}
+	var upd types.UpdateQuarantineResult
+	if err := json.Unmarshal(resp.Result, &upd); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(upd.Quarantined) != 2 {
+		t.Fatalf("Quarantined = %v, want 2 entries", upd.Quarantined)
+	}
+
+	send(3, "update_quarantine", types.UpdateQuarantineParams{Remove: []string{"flaky_b"}})
+	resp = recv()
+	if resp.Error != nil {
+		t.Fatalf("update_quarantine: %+v", resp.Error)
+	}
+	if err := json.Unmarshal(resp.Result, &upd); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(upd.Quarantined) != 1 || upd.Quarantined[0] != "flaky_a" {
+		t.Errorf("Quarantined = %v, want [flaky_a]", upd.Quarantined)
+	}
+
+	send(4, "query_flaky", types.QueryFlakyParams{AssertionIDs: []string{"flaky_a", "unknown"}})
+	resp = recv()
+	if resp.Error != nil {
+		t.Fatalf("query_flaky: %+v", resp.Error)
+	}
+	var flaky types.QueryFlakyResult
+	if err := json.Unmarshal(resp.Result, &flaky); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(flaky.Reports) != 2 {
+		t.Fatalf("Reports = %d, want 2", len(flaky.Reports))
+	}
+	if !flaky.Reports[0].Quarantined || flaky.Reports[1].Quarantined {
+		t.Errorf("quarantine flags = %v/%v, want true/false", flaky.Reports[0].Quarantined, flaky.Reports[1].Quarantined)
+	}
+	if flaky.Reports[1].Runs != 0 || flaky.Reports[1].Flaky {
+		t.Errorf("unknown assertion report = %+v, want zero runs and not flaky", flaky.Reports[1])
+	}
+}
+
+func TestHandler_QueryFlaky_BeforeInitialize(t *testing.T) {
+	stdin, stdout, _ := newTestServer(t)
+
+	sendRequest(t, stdin, 1, "query_flaky", types.QueryFlakyParams{})
+	resp := readResponse(t, stdout)
+
+	if resp.Error == nil {
+		t.Fatal("expected SESSION_ERROR before initialize")
+	}
+	if resp.Error.Code != types.ErrSessionError {
+		t.Errorf("Error.Code = %d, want %d", resp.Error.Code, types.ErrSessionError)
+	}
+}
+
+func TestHandler_MemoryCacheMode(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	t.Setenv("ATTEST_CACHE_DIR", cacheDir)
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	send, recv := initServer(t)
+
+	send(2, "update_quarantine", types.UpdateQuarantineParams{Add: []string{"mem_a"}})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("update_quarantine: %+v", resp.Error)
+	}
+	var upd types.UpdateQuarantineResult
+	if err := json.Unmarshal(resp.Result, &upd); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(upd.Quarantined) != 1 || upd.Quarantined[0] != "mem_a" {
+		t.Errorf("Quarantined = %v, want [mem_a]", upd.Quarantined)
+	}
+
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Errorf("cache dir %s exists in memory mode (stat err = %v)", cacheDir, err)
+	}
+}
+
+// ── debug_dump ──
+
+func TestHandler_DebugDump_RequiresConfirmation(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_DIR", t.TempDir())
+	t.Setenv("ATTEST_OPENAI_API_KEY", "")
+	t.Setenv("ATTEST_DEBUG_RECENT_TRACES", "10")
+	dumpDir := filepath.Join(t.TempDir(), "dumps")
+	t.Setenv("ATTEST_DEBUG_DUMP_DIR", dumpDir)
+	send, recv := initServer(t)
+
+	send(2, "evaluate_batch", types.EvaluateBatchParams{
+		Trace: types.Trace{
+			TraceID: "trc_dump",
+			Output:  json.RawMessage(`{"message":"private reply"}`),
+		},
+		Assertions: []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"reply"}`),
+		}},
+	})
+	if resp := recv(); resp.Error != nil {
+		t.Fatalf("evaluate_batch error: %+v", resp.Error)
+	}
+
+	out := filepath.Join(dumpDir, "bundle.tar.gz")
+	send(3, "debug_dump", types.DebugDumpParams{OutputPath: "bundle.tar.gz"})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("debug_dump error: %+v", resp.Error)
+	}
+	var preview types.DebugDumpResult
+	if err := json.Unmarshal(resp.Result, &preview); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if preview.Written {
+		t.Error("bundle written without confirm")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("bundle file exists before confirmation: %v", err)
+	}
+	names := map[string]bool{}
+	for _, e := range preview.Manifest {
+		names[e.Name] = true
+	}
+	for _, want := range []string{"version.json", "config.json", "assertions.json", "traces/001_trc_dump.json"} {
+		if !names[want] {
+			t.Errorf("manifest missing %s: %+v", want, preview.Manifest)
+		}
+	}
+
+	send(4, "debug_dump", types.DebugDumpParams{OutputPath: "bundle.tar.gz", Confirm: true})
+	resp = recv()
+	if resp.Error != nil {
+		t.Fatalf("debug_dump confirm error: %+v", resp.Error)
+	}
+	var written types.DebugDumpResult
+	if err := json.Unmarshal(resp.Result, &written); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !written.Written || written.Path != out {
+		t.Errorf("result = %+v, want written to %s", written, out)
+	}
+	if fi, err := os.Stat(out); err != nil || fi.Size() == 0 {
+		t.Errorf("bundle not written: %v", err)
+	}
+}
+
+func TestHandler_DebugDump_ConfinedToDumpDir(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_DIR", t.TempDir())
+	t.Setenv("ATTEST_DEBUG_DUMP_DIR", t.TempDir())
+	send, recv := initServer(t)
+
+	outside := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	for i, path := range []string{outside, "../bundle.tar.gz", "sub/bundle.tar.gz", ".."} {
+		send(int64(2+i), "debug_dump", types.DebugDumpParams{OutputPath: path, Confirm: true})
+		if resp := recv(); resp.Error == nil {
+			t.Errorf("output_path %q accepted", path)
+		}
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("bundle written outside the dump directory: %v", err)
+	}
+}
+
+func TestHandler_DebugDump_RetentionOptIn(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_DIR", t.TempDir())
+	t.Setenv("ATTEST_OPENAI_API_KEY", "")
+	send, recv := initServer(t)
+
+	send(2, "evaluate_batch", types.EvaluateBatchParams{
+		Trace: types.Trace{TraceID: "trc_kept", Output: json.RawMessage(`{"message":"hi"}`)},
+		Assertions: []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hi"}`),
+		}},
+	})
+	if resp := recv(); resp.Error != nil {
+		t.Fatalf("evaluate_batch error: %+v", resp.Error)
+	}
+	send(3, "debug_dump", types.DebugDumpParams{})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("debug_dump error: %+v", resp.Error)
+	}
+	var preview types.DebugDumpResult
+	if err := json.Unmarshal(resp.Result, &preview); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, e := range preview.Manifest {
+		if strings.HasPrefix(e.Name, "traces/") {
+			t.Errorf("trace %s retained without ATTEST_DEBUG_RECENT_TRACES", e.Name)
+		}
+	}
+}
+
+// ── trace limits ──
+
+func TestHandler_ConfiguredTraceLimits(t *testing.T) {
+	t.Setenv("ATTEST_MAX_STEPS_PER_TRACE", "2")
+	t.Setenv("ATTEST_MAX_SUB_TRACE_DEPTH", "99")
+	stdin, stdout, _ := newTestServer(t)
+
+	sendRequest(t, stdin, 1, "initialize", initializeParams())
+	resp := readResponse(t, stdout)
+	if resp.Error != nil {
+		t.Fatalf("initialize failed: %+v", resp.Error)
+	}
+	var init types.InitializeResult
+	if err := json.Unmarshal(resp.Result, &init); err != nil {
+		t.Fatalf("unmarshal initialize result: %v", err)
+	}
+	if init.MaxStepsPerTrace != 2 || init.MaxSubTraceDepth != 16 || init.MaxTraceSizeBytes != 10485760 || init.MaxStepPayloadBytes != 1048576 {
+		t.Errorf("advertised limits = %d steps, depth %d, %d bytes, %d step bytes; want 2, 16 (ceiling), defaults",
+			init.MaxStepsPerTrace, init.MaxSubTraceDepth, init.MaxTraceSizeBytes, init.MaxStepPayloadBytes)
+	}
+
+	steps := make([]types.Step, 3)
+	for i := range steps {
+		steps[i] = types.Step{Type: types.StepTypeLLMCall, Name: fmt.Sprintf("s%d", i)}
+	}
+	sendRequest(t, stdin, 2, "evaluate_batch", types.EvaluateBatchParams{
+		Trace: types.Trace{TraceID: "t", Output: json.RawMessage(`{"ok":true}`), Steps: steps},
+	})
+	resp = readResponse(t, stdout)
+	if resp.Error == nil || resp.Error.Message != "trace exceeds max steps: 3 > 2" {
+		t.Fatalf("evaluate_batch error = %+v, want configured step limit", resp.Error)
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
+	"github.com/attest-ai/attest/engine/internal/paths"
+)
+
+// modelDir returns the ONNX model directory: ATTEST_ONNX_MODEL_DIR, or the
+// per-OS default when unset.
+func modelDir() string {
+	return paths.ModelDir()
+}
+
+// offline reports whether ATTEST_OFFLINE forbids network downloads.
+func offline() bool {
+	return os.Getenv("ATTEST_OFFLINE") == "1"
+}
+
+// DownloadModels downloads the local embedding model files that are missing
+// or do not match their pinned checksums. It backs `attest-engine models
+// download`.
+func DownloadModels(ctx context.Context) ([]embedding.ModelStatus, error) {
+	if offline() {
+		return nil, errors.New("downloads are disabled by ATTEST_OFFLINE=1")
+	}
+	return embedding.DownloadModels(ctx, modelDir())
+}
+
+// VerifyModels checks the local embedding model files against the
+// checksums pinned when they were downloaded. It backs
+// `attest-engine models verify`.
+func VerifyModels() ([]embedding.ModelStatus, error) {
+	return embedding.VerifyModels(modelDir())
+}
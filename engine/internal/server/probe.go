@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// modelCheckTimeout bounds each provider's model validation call.
+const modelCheckTimeout = 10 * time.Second
+
+// modelProbe validates one configured layer's model.
+type modelProbe struct {
+	// capability is the capability the provider backs ("embedding", "llm_judge").
+	capability string
+	provider   string
+	model      string
+	validator  llm.ModelValidator
+}
+
+// modelChecks validates the configured judge and embedding models once per
+// process, so a misspelled model surfaces at initialize instead of mid-batch.
+// Set ATTEST_SKIP_MODEL_CHECK=1 to disable.
+type modelChecks struct {
+	logger *slog.Logger
+	probes []modelProbe
+	once   sync.Once
+	errs   []types.ProviderError
+}
+
+func newModelChecks(logger *slog.Logger, probes []modelProbe) *modelChecks {
+	if os.Getenv("ATTEST_SKIP_MODEL_CHECK") == "1" {
+		probes = nil
+	}
+	return &modelChecks{logger: logger, probes: probes}
+}
+
+// results runs the checks on first use and returns the definitive failures.
+// Transient failures, such as the provider being unreachable, are logged and
+// not reported: they say nothing about the configuration.
+func (c *modelChecks) results(ctx context.Context) []types.ProviderError {
+	c.once.Do(func() {
+		for _, p := range c.probes {
+			checkCtx, cancel := context.WithTimeout(ctx, modelCheckTimeout)
+			err := p.validator.ValidateModel(checkCtx)
+			cancel()
+			switch {
+			case err == nil:
+				c.logger.Info("model validated", "capability", p.capability, "provider", p.provider, "model", p.model)
+			case errors.Is(err, llm.ErrModelUnavailable):
+				c.logger.Error("model validation failed; capability disabled",
+					"capability", p.capability, "provider", p.provider, "model", p.model, "err", err)
+				c.errs = append(c.errs, types.ProviderError{
+					Capability: p.capability,
+					Provider:   p.provider,
+					Model:      p.model,
+					Message:    err.Error(),
+				})
+			default:
+				c.logger.Warn("model validation inconclusive",
+					"capability", p.capability, "provider", p.provider, "model", p.model, "err", err)
+			}
+		}
+	})
+	return c.errs
+}
+
+// withoutFailed returns caps minus the capabilities that errs disable:
+// a failed judge also takes simulation, and layers_5_6 goes once neither
+// layer is left.
+func withoutFailed(caps []string, errs []types.ProviderError) []string {
+	if len(errs) == 0 {
+		return caps
+	}
+	drop := make(map[string]bool)
+	for _, e := range errs {
+		drop[e.Capability] = true
+		if e.Capability == "llm_judge" {
+			drop["simulation"] = true
+		}
+	}
+	has := make(map[string]bool, len(caps))
+	for _, c := range caps {
+		has[c] = true
+	}
+	if (!has["embedding"] || drop["embedding"]) && (!has["llm_judge"] || drop["llm_judge"]) {
+		drop["layers_5_6"] = true
+	}
+
+	kept := make([]string, 0, len(caps))
+	for _, c := range caps {
+		if !drop[c] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
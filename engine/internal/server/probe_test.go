@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+type stubValidator struct{ err error }
+
+func (v stubValidator) ValidateModel(context.Context) error { return v.err }
+
+func TestHandleInitialize_ReportsInvalidModel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	checks := newModelChecks(logger, []modelProbe{
+		{capability: "llm_judge", provider: "openai", model: "gpt-9", validator: stubValidator{fmt.Errorf("model %q not found: %w", "gpt-9", llm.ErrModelUnavailable)}},
+		{capability: "embedding", provider: "openai", model: "text-embedding-3-small", validator: stubValidator{errors.New("connection refused")}},
+	})
+	caps := []string{"layers_1_4", "embedding", "llm_judge", "simulation", "layers_5_6"}
+
+	params, _ := json.Marshal(types.InitializeParams{ProtocolVersion: 1, RequiredCapabilities: []string{"llm_judge"}})
+	result, rpcErr := handleInitialize(caps, checks, trace.DefaultLimits, nil, nil)(context.Background(), NewSession(), params)
+	if rpcErr != nil {
+		t.Fatalf("initialize: %+v", rpcErr)
+	}
+	init := result.(*types.InitializeResult)
+
+	// The unreachable embedding provider is inconclusive, so only the judge is dropped.
+	if want := []string{"layers_1_4", "embedding", "layers_5_6"}; !reflect.DeepEqual(init.Capabilities, want) {
+		t.Errorf("capabilities = %v, want %v", init.Capabilities, want)
+	}
+	if init.Compatible || !reflect.DeepEqual(init.Missing, []string{"llm_judge"}) {
+		t.Errorf("compatible = %v, missing = %v", init.Compatible, init.Missing)
+	}
+	if len(init.ProviderErrors) != 1 || init.ProviderErrors[0].Model != "gpt-9" || init.ProviderErrors[0].Capability != "llm_judge" {
+		t.Errorf("provider_errors = %+v", init.ProviderErrors)
+	}
+}
+
+func TestWithoutFailed(t *testing.T) {
+	caps := []string{"layers_1_4", "embedding", "llm_judge", "simulation", "layers_5_6"}
+	errs := []types.ProviderError{{Capability: "embedding"}, {Capability: "llm_judge"}}
+	if got := withoutFailed(caps, errs); !reflect.DeepEqual(got, []string{"layers_1_4"}) {
+		t.Errorf("withoutFailed = %v", got)
+	}
+	if got := withoutFailed(caps, nil); !reflect.DeepEqual(got, caps) {
+		t.Errorf("withoutFailed(nil) = %v", got)
+	}
+}
+
+func TestHandleInitialize_Namespace(t *testing.T) {
+	store, err := cache.OpenMemoryStore(cache.StoreConfig{EmbeddingMaxMB: 10, JudgeMaxMB: 10})
+	if err != nil {
+		t.Fatalf("OpenMemoryStore: %v", err)
+	}
+	defer store.Close()
+	init := handleInitialize(nil, &modelChecks{}, trace.DefaultLimits, store, nil)
+
+	params, _ := json.Marshal(types.InitializeParams{ProtocolVersion: 1, Namespace: "../etc"})
+	if _, rpcErr := init(context.Background(), NewSession(), params); rpcErr == nil || rpcErr.Code != types.ErrSessionError {
+		t.Fatalf("invalid namespace: err = %+v, want a session error", rpcErr)
+	}
+
+	params, _ = json.Marshal(types.InitializeParams{ProtocolVersion: 1, Namespace: "team-a"})
+	result, rpcErr := init(context.Background(), NewSession(), params)
+	if rpcErr != nil {
+		t.Fatalf("initialize: %+v", rpcErr)
+	}
+	if got := result.(*types.InitializeResult).Namespace; got != "team-a" {
+		t.Errorf("result namespace = %q", got)
+	}
+	if got := store.History().Namespace(); got != "team-a" {
+		t.Errorf("history namespace = %q, want team-a", got)
+	}
+}
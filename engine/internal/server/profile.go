@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+const (
+	defaultCPUProfileSeconds = 10
+	maxProfileSeconds        = 300
+)
+
+// profiler captures one CPU or heap profile at a time into dir, in the
+// background so the requests being diagnosed keep running.
+type profiler struct {
+	dir    string
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // non-nil while a capture runs
+	done   chan struct{}
+}
+
+func newProfiler(dir string, logger *slog.Logger) *profiler {
+	return &profiler{dir: dir, logger: logger}
+}
+
+// start begins a capture and returns the path it will be written to. The
+// file appears under that name only once complete.
+func (p *profiler) start(kind string, seconds int) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return "", fmt.Errorf("a profile is already being captured")
+	}
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(p.dir, fmt.Sprintf("%s-%s.pprof", kind, time.Now().UTC().Format("20060102T150405.000Z")))
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return "", err
+	}
+	if kind == types.ProfileCPU {
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+	p.cancel, p.done = cancel, make(chan struct{})
+	go p.capture(ctx, kind, f, path)
+	return path, nil
+}
+
+func (p *profiler) capture(ctx context.Context, kind string, f *os.File, path string) {
+	defer func() {
+		p.mu.Lock()
+		p.cancel()
+		close(p.done)
+		p.cancel = nil
+		p.mu.Unlock()
+	}()
+	<-ctx.Done()
+
+	var err error
+	if kind == types.ProfileCPU {
+		pprof.StopCPUProfile()
+	} else {
+		runtime.GC() // report live objects as of now
+		err = pprof.WriteHeapProfile(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		p.logger.Error("profile capture failed", "kind", kind, "err", err)
+		return
+	}
+	p.logger.Info("profile written", "kind", kind, "path", path)
+}
+
+// stop ends a running capture early, writing what was collected.
+func (p *profiler) stop() {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// handleDebugProfile starts a CPU or heap profile capture. It is not
+// advertised in capabilities: it exists to diagnose slow evaluations in
+// the field without a rebuild.
+func handleDebugProfile(prof *profiler) Handler {
+	return func(_ context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"debug_profile called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.DebugProfileParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				"invalid debug_profile params",
+				types.ErrTypeEngineError,
+				false,
+				err.Error(),
+			)
+		}
+		seconds := p.Seconds
+		switch {
+		case p.Kind != types.ProfileCPU && p.Kind != types.ProfileHeap:
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("unknown profile kind %q", p.Kind),
+				types.ErrTypeEngineError,
+				false,
+				`set kind to "cpu" or "heap"`,
+			)
+		case seconds < 0 || seconds > maxProfileSeconds:
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("profile seconds %d outside [0, %d]", seconds, maxProfileSeconds),
+				types.ErrTypeEngineError,
+				false,
+				"",
+			)
+		case seconds == 0 && p.Kind == types.ProfileCPU:
+			seconds = defaultCPUProfileSeconds
+		}
+
+		path, err := prof.start(p.Kind, seconds)
+		if err != nil {
+			return nil, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("debug_profile failed: %v", err),
+				types.ErrTypeEngineError,
+				false,
+				"wait for the running capture to finish, and check that the cache directory is writable",
+			)
+		}
+		return &types.DebugProfileResult{
+			Kind:    p.Kind,
+			Seconds: seconds,
+			Path:    path,
+			ReadyAt: time.Now().Add(time.Duration(seconds) * time.Second).UTC().Format(time.RFC3339),
+		}, nil
+	}
+}
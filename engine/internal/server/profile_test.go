@@ -0,0 +1,82 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func waitForFile(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if info, err := os.Stat(path); err == nil {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s was not written", path)
+	return nil
+}
+
+func TestProfiler_CPUStopsEarly(t *testing.T) {
+	prof := newProfiler(t.TempDir(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	path, err := prof.start(types.ProfileCPU, maxProfileSeconds)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, err := prof.start(types.ProfileHeap, 0); err == nil {
+		t.Error("second capture started while one was running")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("profile visible before the capture finished")
+	}
+
+	prof.stop()
+	if info := waitForFile(t, path); info.Size() == 0 {
+		t.Error("cpu profile is empty")
+	}
+	if _, err := prof.start(types.ProfileHeap, 0); err != nil {
+		t.Errorf("start after stop: %v", err)
+	}
+	prof.stop()
+}
+
+func TestHandler_DebugProfile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ATTEST_CACHE_DIR", dir)
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	send, recv := initServer(t)
+
+	send(2, "debug_profile", map[string]any{"kind": "heap"})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("debug_profile: %+v", resp.Error)
+	}
+	var result types.DebugProfileResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Kind != "heap" || result.Seconds != 0 || filepath.Dir(result.Path) != filepath.Join(dir, "profiles") {
+		t.Errorf("result = %+v", result)
+	}
+	if !strings.HasPrefix(filepath.Base(result.Path), "heap-") || filepath.Ext(result.Path) != ".pprof" {
+		t.Errorf("path = %s", result.Path)
+	}
+	waitForFile(t, result.Path)
+
+	for _, params := range []map[string]any{{"kind": "trace"}, {"kind": "cpu", "seconds": 301}} {
+		send(3, "debug_profile", params)
+		if resp := recv(); resp.Error == nil || resp.Error.Code != types.ErrEngineError {
+			t.Errorf("debug_profile %v = %+v, want ENGINE_ERROR", params, resp)
+		}
+	}
+}
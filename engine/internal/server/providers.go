@@ -0,0 +1,82 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/embedding"
+	"github.com/attest-ai/attest/engine/internal/llm"
+)
+
+// builtinProviders returns the providers selectable through
+// ATTEST_PROVIDER, ATTEST_JUDGE_PROVIDER, and ATTEST_EMBEDDING_PROVIDER.
+func builtinProviders() *llm.Registry {
+	r := llm.NewRegistry()
+	r.Register("openai", llm.ProviderFactory{
+		Chat: func(cfg llm.ProviderConfig) (llm.Provider, error) {
+			return llm.NewOpenAIProvider(cfg.APIKey, cfg.ChatModel, cfg.BaseURL)
+		},
+		Embed: func(cfg llm.ProviderConfig) (llm.Embedder, error) {
+			return embedding.NewOpenAIEmbedder(embedding.EmbedderConfig{
+				APIKey:  cfg.APIKey,
+				Model:   cfg.EmbedModel,
+				BaseURL: cfg.BaseURL,
+			})
+		},
+	})
+	return r
+}
+
+// providerConfig reads the settings for the named provider, shared by every
+// layer it serves: ATTEST_<NAME>_API_KEY and ATTEST_<NAME>_BASE_URL, plus
+// ATTEST_JUDGE_MODEL and ATTEST_EMBEDDING_MODEL.
+func providerConfig(name string) llm.ProviderConfig {
+	prefix := "ATTEST_" + strings.ToUpper(name) + "_"
+	return llm.ProviderConfig{
+		APIKey:     os.Getenv(prefix + "API_KEY"),
+		BaseURL:    os.Getenv(prefix + "BASE_URL"),
+		ChatModel:  os.Getenv("ATTEST_JUDGE_MODEL"),
+		EmbedModel: os.Getenv("ATTEST_EMBEDDING_MODEL"),
+	}
+}
+
+// selectedProvider returns the provider named by the per-layer env var, or
+// by ATTEST_PROVIDER when that provider supports c. Empty and "auto" leave
+// the choice to ATTEST_PROVIDER; "" means neither is set.
+func selectedProvider(providers *llm.Registry, key string, c llm.Capability) string {
+	if name := os.Getenv(key); name != "" && name != "auto" {
+		return name
+	}
+	if name := os.Getenv("ATTEST_PROVIDER"); providers.Supports(name, c) {
+		return name
+	}
+	return ""
+}
+
+// configuredProvider returns the first provider, by name, that supports c and
+// has an API key or base URL set, or "" when there is none.
+func configuredProvider(providers *llm.Registry, c llm.Capability) string {
+	for _, name := range providers.Names(c) {
+		if cfg := providerConfig(name); cfg.APIKey != "" || cfg.BaseURL != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// onnxExecutionProvider returns the ONNX execution provider named by
+// ATTEST_ONNX_EP, defaulting to CPU. An unknown value is logged and
+// treated as CPU.
+func onnxExecutionProvider(logger *slog.Logger) string {
+	ep := strings.ToLower(strings.TrimSpace(os.Getenv("ATTEST_ONNX_EP")))
+	if ep == "" {
+		return embedding.ExecutionProviderCPU
+	}
+	if !slices.Contains(embedding.ExecutionProviders, ep) {
+		logger.Warn("unknown ONNX execution provider, using cpu", "ATTEST_ONNX_EP", ep, "supported", embedding.ExecutionProviders)
+		return embedding.ExecutionProviderCPU
+	}
+	return ep
+}
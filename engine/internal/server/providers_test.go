@@ -0,0 +1,26 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestONNXExecutionProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"", "cpu"},
+		{"cuda", "cuda"},
+		{" CoreML ", "coreml"},
+		{"tpu", "cpu"},
+	}
+	for _, tt := range tests {
+		t.Setenv("ATTEST_ONNX_EP", tt.env)
+		if got := onnxExecutionProvider(logger); got != tt.want {
+			t.Errorf("ATTEST_ONNX_EP=%q: got %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/report"
+)
+
+// Webhook formats accepted by RunSummaryPayload.
+const (
+	WebhookSlack   = "slack"
+	WebhookGeneric = "generic"
+)
+
+// publishTimeout bounds one webhook post.
+const publishTimeout = 15 * time.Second
+
+// RunSummaryPayload builds the webhook body for a run summary. A Slack
+// payload is {"text": ...} with the text rendered from tmpl, or from
+// report.DefaultSlackTemplate when tmpl is empty. A generic payload is the
+// summary as JSON, or tmpl rendered verbatim when given.
+func RunSummaryPayload(s *report.RunSummary, format, tmpl string) ([]byte, error) {
+	switch format {
+	case WebhookSlack:
+		if tmpl == "" {
+			tmpl = report.DefaultSlackTemplate
+		}
+		text, err := report.RenderSummary(tmpl, s)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]string{"text": text})
+	case WebhookGeneric:
+		if tmpl == "" {
+			return json.Marshal(s)
+		}
+		text, err := report.RenderSummary(tmpl, s)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(text), nil
+	}
+	return nil, fmt.Errorf("unknown webhook format %q: want %s or %s", format, WebhookSlack, WebhookGeneric)
+}
+
+// PublishRunSummary posts body to a webhook as JSON. A response other than
+// 2xx is an error that includes the start of the response body, which is
+// where Slack explains a rejected payload. Errors name the webhook by scheme
+// and host only: a Slack webhook's path is its secret.
+func PublishRunSummary(ctx context.Context, client *http.Client, webhookURL string, body []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("publish: %w", redactURLError(err, webhookURL))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("publish: %w", redactURLError(err, webhookURL))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("publish: webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// redactURLError replaces the URL in err, when it is a *url.Error, with
+// webhookURL's scheme and host. An error about a URL that does not parse
+// carries the URL in its message as well, so only the operation is kept.
+func redactURLError(err error, webhookURL string) error {
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		return err
+	}
+	u, perr := url.Parse(webhookURL)
+	if perr != nil || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	return &url.Error{Op: ue.Op, URL: u.Scheme + "://" + u.Host, Err: ue.Err}
+}
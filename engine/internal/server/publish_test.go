@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/report"
+)
+
+func TestRunSummaryPayload(t *testing.T) {
+	s := &report.RunSummary{Title: "nightly", Total: 2, Passed: 1, PassRate: 0.5}
+
+	slack, err := RunSummaryPayload(s, WebhookSlack, "")
+	if err != nil {
+		t.Fatalf("slack payload: %v", err)
+	}
+	var msg struct{ Text string }
+	if err := json.Unmarshal(slack, &msg); err != nil || !strings.HasPrefix(msg.Text, "*nightly*: 50.0% passed") {
+		t.Errorf("slack payload = %s (%v)", slack, err)
+	}
+
+	generic, err := RunSummaryPayload(s, WebhookGeneric, "")
+	if err != nil {
+		t.Fatalf("generic payload: %v", err)
+	}
+	var got report.RunSummary
+	if err := json.Unmarshal(generic, &got); err != nil || got.Title != "nightly" || got.Total != 2 {
+		t.Errorf("generic payload = %s (%v)", generic, err)
+	}
+
+	custom, err := RunSummaryPayload(s, WebhookGeneric, `{"run": {{json .Title}}, "rate": "{{percent .PassRate}}"}`)
+	if err != nil || string(custom) != `{"run": "nightly", "rate": "50.0%"}` {
+		t.Errorf("templated payload = %s (%v)", custom, err)
+	}
+
+	if _, err := RunSummaryPayload(s, "teams", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestPublishRunSummary(t *testing.T) {
+	var body, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body, contentType = string(b), r.Header.Get("Content-Type")
+		if strings.Contains(body, "reject") {
+			http.Error(w, "invalid_payload", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	if err := PublishRunSummary(context.Background(), srv.Client(), srv.URL, []byte(`{"text":"ok"}`)); err != nil {
+		t.Fatalf("PublishRunSummary: %v", err)
+	}
+	if body != `{"text":"ok"}` || contentType != "application/json" {
+		t.Errorf("posted %q as %q", body, contentType)
+	}
+
+	err := PublishRunSummary(context.Background(), srv.Client(), srv.URL, []byte(`{"text":"reject"}`))
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "invalid_payload") {
+		t.Errorf("error = %v, want the status and response body", err)
+	}
+}
+
+// failingTransport fails every request, as an unreachable webhook does.
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestPublishRunSummary_HidesWebhookPath(t *testing.T) {
+	const secret = "T000/B000/XXXXSECRET"
+	client := &http.Client{Transport: failingTransport{}}
+
+	err := PublishRunSummary(context.Background(), client, "https://hooks.slack.com/services/"+secret, []byte(`{}`))
+	if err == nil || strings.Contains(err.Error(), secret) || !strings.Contains(err.Error(), "https://hooks.slack.com") ||
+		!strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("error = %v, want the host and cause without the path", err)
+	}
+
+	err = PublishRunSummary(context.Background(), client, "https://hooks.slack.com/services/"+secret+"%zz", []byte(`{}`))
+	if err == nil || strings.Contains(err.Error(), secret) {
+		t.Errorf("error = %v, want an invalid URL error without the path", err)
+	}
+}
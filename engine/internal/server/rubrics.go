@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+	"github.com/attest-ai/attest/engine/internal/paths"
+)
+
+// rubricConfig is one entry of the ATTEST_RUBRICS file. An entry naming an
+// existing rubric without a system prompt sets that rubric's examples; an
+// entry with a system prompt defines or replaces a rubric.
+type rubricConfig struct {
+	Name         string                 `json:"name"`
+	SystemPrompt string                 `json:"system_prompt,omitempty"`
+	Examples     []judge.FewShotExample `json:"examples,omitempty"`
+}
+
+// buildRubricRegistry returns the built-in rubrics with the custom rubrics and
+// few-shot examples from the JSON file named by ATTEST_RUBRICS, or
+// rubrics.json in the config directory, applied. A file
+// that fails to load is logged and ignored.
+func buildRubricRegistry(logger *slog.Logger) *judge.RubricRegistry {
+	registry := judge.NewRubricRegistry()
+	path := os.Getenv("ATTEST_RUBRICS")
+	if path == "" {
+		path = paths.ConfigFile("rubrics.json")
+	}
+	if path == "" {
+		return registry
+	}
+	count, err := loadRubrics(registry, path)
+	if err != nil {
+		logger.Error("failed to load rubrics", "path", path, "err", err)
+		return judge.NewRubricRegistry()
+	}
+	logger.Info("rubrics loaded", "path", path, "count", count)
+	return registry
+}
+
+// loadRubrics applies the rubrics in path, a JSON array of rubric entries or
+// an object with a "rubrics" array, and returns how many were applied.
+func loadRubrics(registry *judge.RubricRegistry, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read rubrics: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+
+	var rubrics []rubricConfig
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &rubrics)
+	} else {
+		var file struct {
+			Rubrics []rubricConfig `json:"rubrics"`
+		}
+		err = json.Unmarshal(data, &file)
+		rubrics = file.Rubrics
+	}
+	if err != nil {
+		return 0, fmt.Errorf("parse rubrics %s: expected JSON array of rubrics or {\"rubrics\": [...]}: %w", path, err)
+	}
+
+	for _, rc := range rubrics {
+		if rc.SystemPrompt == "" {
+			err = registry.SetExamples(rc.Name, rc.Examples)
+		} else {
+			err = registry.Register(&judge.Rubric{Name: rc.Name, SystemPrompt: rc.SystemPrompt, Examples: rc.Examples})
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(rubrics), nil
+}
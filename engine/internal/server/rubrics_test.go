@@ -0,0 +1,33 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/assertion/judge"
+)
+
+func TestLoadRubrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rubrics.json")
+	writeFile(t, path, `{"rubrics": [
+		{"name": "safety", "examples": [{"input": "Here is how to pick a lock...", "score": 0.2, "explanation": "Harmful how-to."}]},
+		{"name": "tone", "system_prompt": "Judge the tone.", "examples": [{"input": "Thanks for waiting!", "score": 1, "explanation": "Warm."}]}
+	]}`)
+
+	reg := judge.NewRubricRegistry()
+	n, err := loadRubrics(reg, path)
+	if err != nil || n != 2 {
+		t.Fatalf("loadRubrics = %d, %v", n, err)
+	}
+	if rb, _ := reg.Get("safety"); len(rb.Examples) != 1 || rb.SystemPrompt == "" {
+		t.Errorf("safety = %+v, want builtin prompt with one example", rb)
+	}
+	if rb, err := reg.Get("tone"); err != nil || rb.SystemPrompt != "Judge the tone." {
+		t.Errorf("tone = %+v, %v", rb, err)
+	}
+
+	writeFile(t, path, `[{"name": "nonexistent", "examples": []}]`)
+	if _, err := loadRubrics(judge.NewRubricRegistry(), path); err == nil {
+		t.Error("expected error for examples on an unknown rubric")
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/cache"
+	"github.com/attest-ai/attest/engine/internal/report"
+)
+
+// HistoryScorecard builds a scorecard from the assertion history in
+// attest.db over the window ending at now, with trends against the window
+// of equal length before it. label names the window in the report, and
+// namespace selects whose history is read ("" for the default namespace).
+// The scorecard is empty when the database does not exist yet.
+func HistoryScorecard(window time.Duration, label, namespace string, now time.Time) (*report.Scorecard, error) {
+	if err := cache.ValidateNamespace(namespace); err != nil {
+		return nil, err
+	}
+	from, prevFrom := now.Add(-window), now.Add(-2*window)
+	store, _, err := openDiskStore()
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return report.BuildScorecard(nil, nil, nil, label, from, now), nil
+	}
+	defer store.Close()
+
+	history := store.History()
+	history.SetNamespace(namespace)
+	current, err := history.Summaries(from, now)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := history.Summaries(prevFrom, from)
+	if err != nil {
+		return nil, err
+	}
+	quarantined, err := history.QuarantinedIDs()
+	if err != nil {
+		return nil, err
+	}
+	return report.BuildScorecard(scorecardStats(current), scorecardStats(previous), quarantined, label, from, now), nil
+}
+
+func scorecardStats(summaries []cache.HistorySummary) []report.ScorecardStats {
+	stats := make([]report.ScorecardStats, len(summaries))
+	for i, s := range summaries {
+		stats[i] = report.ScorecardStats{
+			AssertionID:   s.AssertionID,
+			AssertionType: s.AssertionType,
+			Runs:          s.Runs,
+			PassRate:      s.PassRate,
+			MeanScore:     s.MeanScore,
+			Cost:          s.Cost,
+		}
+	}
+	return stats
+}
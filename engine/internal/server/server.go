@@ -3,30 +3,71 @@ package server
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"github.com/segmentio/encoding/json"
 	"io"
 	"log/slog"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/attest-ai/attest/engine/internal/deprecation"
+	"github.com/attest-ai/attest/engine/internal/logging"
+	"github.com/attest-ai/attest/engine/internal/trace"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
-// Handler is the function signature for JSON-RPC method handlers.
-type Handler func(session *Session, params json.RawMessage) (any, *types.RPCError)
+// Handler is the function signature for JSON-RPC method handlers. ctx carries
+// the call's request ID (see logging.FromContext) and is canceled when the
+// server stops.
+type Handler func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError)
 
 // defaultMaxConcurrent is the default value for maxConcurrent (sequential behavior).
 const defaultMaxConcurrent = 1
 
+// maxScanBuf is the default longest request line the server reads.
+const maxScanBuf = 10 * 1024 * 1024
+
 // Server reads NDJSON requests from an io.Reader and writes NDJSON responses to an io.Writer.
 type Server struct {
 	reader         *bufio.Scanner
+	maxLineSize    int
+	maxJSONDepth   int
+	maxResponse    int
 	writer         *bufio.Writer
+	out            io.Writer
 	mu             sync.Mutex // protects writer
 	session        *Session
 	handlers       map[string]Handler
 	logger         *slog.Logger
 	maxConcurrent  int
 	semaphore      chan struct{}
+	logBuffer      *logging.RingWriter
+	startedAt      time.Time
+
+	// Idle tracking: lastActive is the UnixNano time the last request
+	// arrived or finished; inFlight counts requests being handled.
+	idleTimeout time.Duration
+	idleHooks   []*idleHook
+	lastActive  atomic.Int64
+	inFlight    atomic.Int32
+
+	// auth restricts methods by the session's role; nil allows all.
+	auth *authPolicy
+
+	// deadLetters queues notifications whose write failed; nil drops them.
+	deadLetters *deadLetterQueue
+	stopHooks   []func()
+
+	// Engine-initiated calls awaiting an SDK response (see Call).
+	callsMu     sync.Mutex
+	calls       map[int64]chan *types.Response
+	nextCallID  int64
+	callsClosed bool
+	// callStarted wakes a reader waiting to hand a request to a busy
+	// dispatcher, so it goes back to reading the new call's response.
+	callStarted chan struct{}
 }
 
 // New creates a new Server reading from in and writing to out.
@@ -46,20 +87,121 @@ func NewWithConcurrency(in io.Reader, out io.Writer, logger *slog.Logger, maxCon
 	}
 	scanner := bufio.NewScanner(in)
 	// 10 MB buffer for large traces.
-	const maxScanBuf = 10 * 1024 * 1024
 	scanner.Buffer(make([]byte, maxScanBuf), maxScanBuf)
 
 	return &Server{
 		reader:        scanner,
+		maxLineSize:   maxScanBuf,
+		maxJSONDepth:  trace.MaxJSONDepth,
+		maxResponse:   defaultMaxResponseSize,
 		writer:        bufio.NewWriter(out),
+		out:           out,
 		session:       NewSession(),
 		handlers:      make(map[string]Handler),
 		logger:        logger,
 		maxConcurrent: maxConcurrent,
 		semaphore:     make(chan struct{}, maxConcurrent),
+		startedAt:     time.Now(),
+		calls:         make(map[int64]chan *types.Response),
+		callStarted:   make(chan struct{}, 1),
+	}
+}
+
+// SetLogBuffer gives the server the buffer of recent log lines that debug
+// bundles include. Call it before RegisterBuiltinHandlers.
+func (s *Server) SetLogBuffer(r *logging.RingWriter) {
+	s.logBuffer = r
+}
+
+// SetMaxLineSize raises the longest request line the server reads to n
+// bytes, so traces up to a configured size limit fit in one request. It never
+// lowers the default and must be called before Run.
+func (s *Server) SetMaxLineSize(n int) {
+	if n > maxScanBuf {
+		s.reader.Buffer(make([]byte, maxScanBuf), n)
+		s.maxLineSize = n
 	}
 }
 
+// SetMaxJSONDepth sets how deeply arrays and objects may nest in a request.
+// Deeper requests are answered with a parse error before they are decoded.
+func (s *Server) SetMaxJSONDepth(n int) {
+	if n > 0 {
+		s.maxJSONDepth = n
+	}
+}
+
+// SetMaxResponseSize sets the largest result, in bytes of JSON, the server
+// sends. Larger evaluate_batch results are truncated to fit (see
+// fitBatchResult); any other oversized result fails with ENGINE_ERROR.
+func (s *Server) SetMaxResponseSize(n int) {
+	if n > 0 {
+		s.maxResponse = n
+	}
+}
+
+// SetIdleTimeout makes Run return once no request has arrived or been in
+// flight for d, so an engine orphaned by its SDK exits. 0 disables it.
+func (s *Server) SetIdleTimeout(d time.Duration) {
+	s.idleTimeout = d
+}
+
+// idleHook is maintenance run once per idle period, after the server has
+// been idle for after.
+type idleHook struct {
+	after   time.Duration
+	fn      func()
+	ranAt   int64 // lastActive when fn last ran
+	running atomic.Bool
+}
+
+// OnIdle registers fn to run in the background each time the server has been
+// idle for after. It runs at most once per idle period.
+func (s *Server) OnIdle(after time.Duration, fn func()) {
+	s.idleHooks = append(s.idleHooks, &idleHook{after: after, fn: fn})
+}
+
+// idleCheckInterval returns how often Run checks for idleness: often enough
+// to act within a quarter of the shortest idle period, and at least every second.
+func (s *Server) idleCheckInterval() time.Duration {
+	interval := time.Second
+	periods := []time.Duration{s.idleTimeout}
+	for _, h := range s.idleHooks {
+		periods = append(periods, h.after)
+	}
+	for _, d := range periods {
+		if d > 0 && d/4 < interval {
+			interval = max(d/4, time.Millisecond)
+		}
+	}
+	return interval
+}
+
+// checkIdle runs due idle hooks and reports whether the idle timeout has passed.
+func (s *Server) checkIdle(now time.Time) bool {
+	if s.inFlight.Load() > 0 {
+		return false
+	}
+	last := s.lastActive.Load()
+	idle := now.Sub(time.Unix(0, last))
+	for _, h := range s.idleHooks {
+		if idle < h.after || h.ranAt == last || !h.running.CompareAndSwap(false, true) {
+			continue
+		}
+		h.ranAt = last
+		go func() {
+			defer h.running.Store(false)
+			h.fn()
+		}()
+	}
+	return s.idleTimeout > 0 && idle >= s.idleTimeout
+}
+
+// OnStop registers fn to run when Run returns.
+func (s *Server) OnStop(fn func()) {
+	s.stopHooks = append(s.stopHooks, fn)
+}
+
 // RegisterHandler registers a handler for the given JSON-RPC method name.
 func (s *Server) RegisterHandler(method string, h Handler) {
 	s.handlers[method] = h
@@ -68,29 +210,55 @@ func (s *Server) RegisterHandler(method string, h Handler) {
 // Run reads NDJSON lines from the reader, dispatches to handlers, and writes responses until
 // stdin is closed or the context is canceled.
 func (s *Server) Run(ctx context.Context) error {
-	lines := make(chan []byte)
+	defer func() {
+		for _, fn := range s.stopHooks {
+			fn()
+		}
+	}()
+	queue := newLineQueue()
 	scanErr := make(chan error, 1)
 
+	// Responses to engine-initiated calls are routed here rather than
+	// dispatched, so a handler blocked in Call still receives them. Other
+	// lines are queued for dispatch; reading waits for the queue to drain,
+	// except while a Call is pending, when requests the SDK pipelines are
+	// buffered so its response can still be read.
 	go func() {
+		defer queue.close()
 		for s.reader.Scan() {
+			if s.routeResponse(s.reader.Bytes()) {
+				continue
+			}
 			line := make([]byte, len(s.reader.Bytes()))
 			copy(line, s.reader.Bytes())
-			lines <- line
+			queue.push(line)
+			for queue.len() > 0 && !s.callsPending() {
+				select {
+				case <-queue.taken:
+				case <-s.callStarted:
+				}
+			}
 		}
+		s.closeCalls()
 		if err := s.reader.Err(); err != nil {
 			scanErr <- err
 		}
-		close(lines)
 	}()
 
 	// dispatchOne acquires a semaphore slot, dispatches the request, writes the
 	// response, then releases the slot. When maxConcurrent == 1 it is called
 	// synchronously so behavior is identical to the previous sequential loop.
 	dispatchOne := func(line []byte) {
+		s.inFlight.Add(1)
+		s.lastActive.Store(time.Now().UnixNano())
 		s.semaphore <- struct{}{}
 		handle := func() {
-			defer func() { <-s.semaphore }()
-			resp := s.dispatch(line)
+			defer func() {
+				<-s.semaphore
+				s.lastActive.Store(time.Now().UnixNano())
+				s.inFlight.Add(-1)
+			}()
+			resp := s.dispatch(ctx, line)
 			s.writeResponse(resp)
 		}
 		if s.maxConcurrent > 1 {
@@ -100,29 +268,151 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 	}
 
+	var idleTick <-chan time.Time
+	if s.idleTimeout > 0 || len(s.idleHooks) > 0 {
+		s.lastActive.Store(time.Now().UnixNano())
+		ticker := time.NewTicker(s.idleCheckInterval())
+		defer ticker.Stop()
+		idleTick = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case err := <-scanErr:
 			return err
-		case line, ok := <-lines:
-			if !ok {
+		case now := <-idleTick:
+			if s.checkIdle(now) {
+				s.logger.Info("idle timeout reached, shutting down", "idle_timeout", s.idleTimeout)
 				return nil
 			}
-			dispatchOne(line)
-			if s.session.State() == StateShuttingDown {
-				return nil
+		case <-queue.ready:
+			for {
+				line, ok, closed := queue.pop()
+				if closed {
+					return nil
+				}
+				if !ok {
+					break
+				}
+				dispatchOne(line)
+				if s.session.State() == StateShuttingDown {
+					return nil
+				}
 			}
 		}
 	}
 }
 
-// dispatch parses a raw JSON line into a Request and routes it to the appropriate handler.
-func (s *Server) dispatch(line []byte) *types.Response {
+// lineQueue hands lines from the reader to the dispatch loop in order.
+type lineQueue struct {
+	mu     sync.Mutex
+	lines  [][]byte
+	closed bool
+	// ready is signaled when lines are added or the queue is closed, taken
+	// when a line is removed.
+	ready chan struct{}
+	taken chan struct{}
+}
+
+func newLineQueue() *lineQueue {
+	return &lineQueue{ready: make(chan struct{}, 1), taken: make(chan struct{}, 1)}
+}
+
+func (q *lineQueue) push(line []byte) {
+	q.mu.Lock()
+	q.lines = append(q.lines, line)
+	q.mu.Unlock()
+	signal(q.ready)
+}
+
+func (q *lineQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	signal(q.ready)
+}
+
+func (q *lineQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.lines)
+}
+
+// pop removes the oldest line. ok is false when the queue is empty; closed
+// is true once it is also closed.
+func (q *lineQueue) pop() (line []byte, ok, closed bool) {
+	q.mu.Lock()
+	if len(q.lines) == 0 {
+		closed = q.closed
+		q.mu.Unlock()
+		return nil, false, closed
+	}
+	line = q.lines[0]
+	q.lines[0] = nil
+	q.lines = q.lines[1:]
+	q.mu.Unlock()
+	signal(q.taken)
+	return line, true, false
+}
+
+// signal wakes the receiver of ch, a channel with a buffer of one, without
+// blocking if a wakeup is already pending.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch parses a raw JSON line into a Request, routes it to the appropriate
+// handler, and tags the response and every log entry for the call with a
+// request ID.
+//
+// A panic anywhere in the call is recovered into an ENGINE_ERROR response,
+// with its stack logged, so one bad request cannot take down the engine and
+// the session stays usable.
+func (s *Server) dispatch(ctx context.Context, line []byte) (resp *types.Response) {
 	var req types.Request
-	if err := json.Unmarshal(line, &req); err != nil {
-		s.logger.Error("parse error", "err", err)
+	requestID := ""
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("request panicked", logging.RequestIDKey, requestID,
+				"method", req.Method, "id", req.ID, "panic", r, "stack", string(debug.Stack()))
+			resp = types.NewErrorResponse(req.ID, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("internal error handling %s: %v", req.Method, r),
+				types.ErrTypeEngineError,
+				false,
+				"The engine recovered and the session is still usable. Please report this with the engine logs.",
+			))
+			resp.RequestID = requestID
+		}
+	}()
+	var err error
+	if trace.ExceedsNesting(line, s.maxJSONDepth) {
+		// Decoding would recurse past the stack limit, which no recover
+		// survives.
+		err = fmt.Errorf("JSON nesting exceeds maximum depth %d", s.maxJSONDepth)
+	} else {
+		err = json.Unmarshal(line, &req)
+	}
+	requestID = req.RequestID
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+	}
+	ctx, notices := deprecation.With(logging.WithRequestID(ctx, requestID))
+	resp = s.handle(ctx, &req, err)
+	resp.RequestID = requestID
+	resp.Warnings = notices.List()
+	return resp
+}
+
+func (s *Server) handle(ctx context.Context, req *types.Request, parseErr error) *types.Response {
+	logger := s.logger.With(logging.RequestIDKey, logging.RequestID(ctx))
+	if err := parseErr; err != nil {
+		logger.Error("parse error", "err", err)
 		return types.NewErrorResponse(0, &types.RPCError{
 			Code:    -32700,
 			Message: "parse error",
@@ -135,7 +425,7 @@ func (s *Server) dispatch(line []byte) *types.Response {
 	}
 
 	if req.JSONRPC != "2.0" || req.Method == "" {
-		s.logger.Error("invalid request", "req", req)
+		logger.Error("invalid request", "req", *req)
 		return types.NewErrorResponse(req.ID, &types.RPCError{
 			Code:    -32600,
 			Message: "invalid request",
@@ -149,7 +439,7 @@ func (s *Server) dispatch(line []byte) *types.Response {
 
 	h, ok := s.handlers[req.Method]
 	if !ok {
-		s.logger.Warn("method not found", "method", req.Method)
+		logger.Warn("method not found", "method", req.Method)
 		return types.NewErrorResponse(req.ID, &types.RPCError{
 			Code:    -32601,
 			Message: "method not found",
@@ -161,14 +451,48 @@ func (s *Server) dispatch(line []byte) *types.Response {
 		})
 	}
 
-	result, rpcErr := h(s.session, req.Params)
+	if role := s.session.Role(); !s.auth.allows(role, req.Method) {
+		logger.Warn("method not permitted", "method", req.Method, "role", role)
+		return types.NewErrorResponse(req.ID, permissionDenied(role, req.Method))
+	}
+
+	// The encoding is read before the handler runs, so the initialize
+	// response that negotiates compression is itself plain JSON.
+	params := req.Params
+	encoding := s.session.Encoding()
+	if encoding == types.EncodingJSONGzip {
+		var err error
+		params, err = decodeParams(params, s.maxLineSize)
+		if err == nil && trace.ExceedsNesting(params, s.maxJSONDepth) {
+			err = fmt.Errorf("JSON nesting exceeds maximum depth %d", s.maxJSONDepth)
+		}
+		if err != nil {
+			logger.Error("invalid compressed params", "method", req.Method, "err", err)
+			return types.NewErrorResponse(req.ID, &types.RPCError{
+				Code:    -32700,
+				Message: "parse error",
+				Data: &types.ErrorData{
+					ErrorType: "PARSE_ERROR",
+					Retryable: false,
+					Detail:    err.Error(),
+				},
+			})
+		}
+	}
+
+	start := time.Now()
+	logger.Debug("request started", "method", req.Method, "id", req.ID)
+	result, rpcErr := h(ctx, s.session, params)
 	if rpcErr != nil {
+		logger.Info("request failed", "method", req.Method, "id", req.ID,
+			"code", rpcErr.Code, "message", rpcErr.Message, "duration_ms", time.Since(start).Milliseconds())
 		return types.NewErrorResponse(req.ID, rpcErr)
 	}
+	logger.Debug("request completed", "method", req.Method, "id", req.ID, "duration_ms", time.Since(start).Milliseconds())
 
 	resp, err := types.NewSuccessResponse(req.ID, result)
 	if err != nil {
-		s.logger.Error("failed to marshal result", "method", req.Method, "err", err)
+		logger.Error("failed to marshal result", "method", req.Method, "err", err)
 		return types.NewErrorResponse(req.ID, types.NewRPCError(
 			types.ErrEngineError,
 			"failed to marshal result",
@@ -177,6 +501,33 @@ func (s *Server) dispatch(line []byte) *types.Response {
 			err.Error(),
 		))
 	}
+	if size := len(resp.Result); size > s.maxResponse {
+		fitted, ok := fitResult(result, s.maxResponse)
+		if !ok {
+			logger.Error("result exceeds max response size", "method", req.Method, "size", size, "max", s.maxResponse)
+			return types.NewErrorResponse(req.ID, types.NewRPCError(
+				types.ErrEngineError,
+				fmt.Sprintf("response exceeds max size: %d > %d bytes", size, s.maxResponse),
+				types.ErrTypeEngineError,
+				false,
+				"Request less data per call, or raise ATTEST_MAX_RESPONSE_SIZE.",
+			))
+		}
+		logger.Warn("result truncated to max response size", "method", req.Method, "size", size, "max", s.maxResponse)
+		resp.Result = fitted
+	}
+	if encoding == types.EncodingJSONGzip {
+		if resp.Result, err = encodeResult(resp.Result); err != nil {
+			logger.Error("failed to compress result", "method", req.Method, "err", err)
+			return types.NewErrorResponse(req.ID, types.NewRPCError(
+				types.ErrEngineError,
+				"failed to compress result",
+				types.ErrTypeEngineError,
+				false,
+				err.Error(),
+			))
+		}
+	}
 	return resp
 }
 
@@ -188,11 +539,34 @@ func (s *Server) writeResponse(resp *types.Response) {
 		return
 	}
 
+	if err := s.writeLine(data); err != nil {
+		s.logger.Error("failed to write response", "err", err)
+	}
+}
+
+// writeLine writes data and a newline to the transport and flushes it. After
+// an error the buffer is reset, since bufio.Writer fails every later write
+// once one has failed.
+func (s *Server) writeLine(data []byte) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	_, _ = s.writer.Write(data)
 	_ = s.writer.WriteByte('\n')
-	_ = s.writer.Flush()
+	if err := s.writer.Flush(); err != nil {
+		s.writer.Reset(s.out)
+		return err
+	}
+	return nil
+}
+
+// Notifier delivers engine-initiated notifications to the SDK.
+type Notifier interface {
+	Notify(method string, params any)
+}
+
+// Notify sends a JSON-RPC notification over the server's transport.
+func (s *Server) Notify(method string, params any) {
+	s.writeNotification(&types.Notification{JSONRPC: "2.0", Method: method, Params: params})
 }
 
 // writeNotification serializes an arbitrary value as compact JSON followed by a newline,
@@ -203,10 +577,11 @@ func (s *Server) writeNotification(v any) {
 		s.logger.Error("failed to marshal notification", "err", err)
 		return
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	_, _ = s.writer.Write(data)
-	_ = s.writer.WriteByte('\n')
-	_ = s.writer.Flush()
+	if err := s.writeLine(data); err != nil {
+		if s.deadLetters != nil {
+			s.deadLetters.addNotification(data, err)
+			return
+		}
+		s.logger.Error("failed to write notification", "err", err)
+	}
 }
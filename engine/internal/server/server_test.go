@@ -2,14 +2,20 @@ package server
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/attest-ai/attest/engine/internal/logging"
 	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
@@ -227,3 +233,176 @@ func TestServer_IncompatibleProtocolVersion(t *testing.T) {
 		t.Errorf("Error.Code = %d, want %d", resp.Error.Code, types.ErrSessionError)
 	}
 }
+
+func TestServer_GzipEncoding(t *testing.T) {
+	stdin, stdout, _ := newTestServer(t)
+
+	init := initializeParams()
+	init.PreferredEncoding = types.EncodingJSONGzip
+	sendRequest(t, stdin, 1, "initialize", init)
+	resp := readResponse(t, stdout)
+	var result types.InitializeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("initialize result is not plain JSON: %v", err)
+	}
+	if result.Encoding != types.EncodingJSONGzip {
+		t.Fatalf("Encoding = %q, want %q", result.Encoding, types.EncodingJSONGzip)
+	}
+
+	params, _ := json.Marshal(types.EvaluateBatchParams{
+		Trace: types.Trace{TraceID: "trc_gzip", Output: json.RawMessage(`{"message":"hello"}`)},
+		Assertions: []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hello"}`),
+		}},
+	})
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(params)
+	zw.Close()
+	sendRequest(t, stdin, 2, "evaluate_batch", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	resp = readResponse(t, stdout)
+	if resp.Error != nil {
+		t.Fatalf("evaluate_batch: %+v", resp.Error)
+	}
+
+	var encoded string
+	if err := json.Unmarshal(resp.Result, &encoded); err != nil {
+		t.Fatalf("result is not an encoded string: %s", resp.Result)
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decode base64: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	var batch types.EvaluateBatchResult
+	if err := json.NewDecoder(zr).Decode(&batch); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if len(batch.Results) != 1 || batch.Results[0].Status != types.StatusPass {
+		t.Errorf("results = %+v, want one pass", batch.Results)
+	}
+
+	// Plain JSON params are still accepted.
+	sendRequest(t, stdin, 3, "get_metrics", map[string]any{})
+	if resp := readResponse(t, stdout); resp.Error != nil || resp.Result[0] != '"' {
+		t.Errorf("get_metrics with plain params = %s, %+v; want encoded result", resp.Result, resp.Error)
+	}
+}
+
+func TestServer_RequestIDCorrelation(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	srv := New(strings.NewReader(""), io.Discard, logger)
+
+	var seen string
+	srv.RegisterHandler("echo", func(ctx context.Context, _ *Session, _ json.RawMessage) (any, *types.RPCError) {
+		seen = logging.RequestID(ctx)
+		return map[string]string{}, nil
+	})
+
+	resp := srv.dispatch(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":{}}`))
+	if !strings.HasPrefix(resp.RequestID, "req_") {
+		t.Fatalf("generated request_id = %q, want req_ prefix", resp.RequestID)
+	}
+	if seen != resp.RequestID {
+		t.Errorf("handler saw request_id %q, response has %q", seen, resp.RequestID)
+	}
+
+	resp = srv.dispatch(context.Background(), []byte(`{"jsonrpc":"2.0","id":2,"method":"missing","request_id":"client-42"}`))
+	if resp.RequestID != "client-42" {
+		t.Errorf("request_id = %q, want client-supplied client-42", resp.RequestID)
+	}
+	if !strings.Contains(logs.String(), `"msg":"method not found","request_id":"client-42"`) {
+		t.Errorf("method-not-found log lacks request_id:\n%s", logs.String())
+	}
+
+	resp = srv.dispatch(context.Background(), []byte(`{not json`))
+	if resp.RequestID == "" || resp.Error == nil {
+		t.Errorf("parse error response = %+v, want error with request_id", resp)
+	}
+}
+
+func TestServer_RecoversHandlerPanic(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	stdinR, stdinW := io.Pipe()
+	defer stdinW.Close()
+	stdoutR, stdoutW := io.Pipe()
+	defer stdoutR.Close()
+	srv := NewWithConcurrency(stdinR, stdoutW, logger, 2)
+	srv.RegisterHandler("boom", func(context.Context, *Session, json.RawMessage) (any, *types.RPCError) {
+		var m map[string]int
+		m["x"] = 1
+		return nil, nil
+	})
+	srv.RegisterHandler("ping", func(context.Context, *Session, json.RawMessage) (any, *types.RPCError) {
+		return map[string]string{}, nil
+	})
+	go func() { _ = srv.Run(context.Background()) }()
+
+	// The panic happens on a concurrent handler goroutine, which would
+	// otherwise kill the process.
+	sendRequest(t, stdinW, 1, "boom", map[string]any{})
+	resp := readResponse(t, stdoutR)
+	if resp.Error == nil || resp.Error.Code != types.ErrEngineError {
+		t.Fatalf("boom response = %+v, want ENGINE_ERROR", resp)
+	}
+	if !strings.Contains(resp.Error.Message, "internal error handling boom") || resp.RequestID == "" {
+		t.Errorf("error = %+v, request_id %q", resp.Error, resp.RequestID)
+	}
+	if !strings.Contains(logs.String(), `"msg":"request panicked"`) || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("panic was not logged with its stack:\n%s", logs.String())
+	}
+
+	sendRequest(t, stdinW, 2, "ping", map[string]any{})
+	if resp := readResponse(t, stdoutR); resp.Error != nil || resp.ID != 2 {
+		t.Errorf("ping after panic = %+v, want success", resp)
+	}
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	defer stdinW.Close()
+	stdoutR, stdoutW := io.Pipe()
+	defer stdoutR.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(stdinR, stdoutW, logger)
+	srv.RegisterHandler("ping", func(context.Context, *Session, json.RawMessage) (any, *types.RPCError) {
+		return map[string]string{}, nil
+	})
+	srv.SetIdleTimeout(150 * time.Millisecond)
+	var trims atomic.Int32
+	srv.OnIdle(50*time.Millisecond, func() { trims.Add(1) })
+
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- srv.Run(context.Background()) }()
+
+	// Requests keep the engine alive past the timeout.
+	for i := int64(1); i <= 4; i++ {
+		time.Sleep(80 * time.Millisecond)
+		sendRequest(t, stdinW, i, "ping", map[string]any{})
+		_ = readResponse(t, stdoutR)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not exit after the idle timeout")
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("exited after %v, before the requests stopped", elapsed)
+	}
+	// One idle period between each request and after the last at most.
+	if n := trims.Load(); n < 1 || n > 5 {
+		t.Errorf("idle hook ran %d times", n)
+	}
+}
@@ -1,6 +1,10 @@
 package server
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
 
 // SessionState represents the lifecycle state of a session.
 type SessionState int
@@ -15,6 +19,8 @@ const (
 type Session struct {
 	mu                  sync.Mutex
 	state               SessionState
+	encoding            string
+	role                string
 	assertionsEvaluated int64
 	sessionsCompleted   int64
 }
@@ -40,6 +46,37 @@ func (s *Session) SetState(state SessionState) {
 	s.state = state
 }
 
+// Encoding returns the payload encoding negotiated by initialize.
+func (s *Session) Encoding() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.encoding == "" {
+		return types.EncodingJSON
+	}
+	return s.encoding
+}
+
+// SetEncoding sets the payload encoding for requests after initialize.
+func (s *Session) SetEncoding(encoding string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoding = encoding
+}
+
+// Role returns the role set by initialize, "" before it.
+func (s *Session) Role() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.role
+}
+
+// SetRole sets the role that decides which methods the session may call.
+func (s *Session) SetRole(role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.role = role
+}
+
 // IncrementAssertions adds count to the total assertions evaluated.
 func (s *Session) IncrementAssertions(count int) {
 	s.mu.Lock()
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/internal/paths"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// buildTemplateRegistry returns a template registry preloaded from the file
+// named by ATTEST_TEMPLATES, or templates.json in the config directory. Load errors are logged and leave the
+// registry empty so that suites referencing the templates fail visibly.
+func buildTemplateRegistry(logger *slog.Logger) *assertion.TemplateRegistry {
+	registry := assertion.NewTemplateRegistry()
+	path := templatesFile()
+	if path == "" {
+		return registry
+	}
+	if err := loadTemplates(registry, path); err != nil {
+		logger.Error("failed to load assertion templates", "path", path, "err", err)
+		return assertion.NewTemplateRegistry()
+	}
+	logger.Info("assertion templates loaded", "path", path, "count", len(registry.Names()))
+	return registry
+}
+
+// templatesFile returns the template file to preload, or "" for none.
+func templatesFile() string {
+	if path := os.Getenv("ATTEST_TEMPLATES"); path != "" {
+		return path
+	}
+	return paths.ConfigFile("templates.json")
+}
+
+// loadTemplates registers the templates in path: a JSON array of templates or
+// an object with a "templates" array.
+func loadTemplates(registry *assertion.TemplateRegistry, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read templates: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+
+	var templates []types.AssertionTemplate
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &templates)
+	} else {
+		var file struct {
+			Templates []types.AssertionTemplate `json:"templates"`
+		}
+		err = json.Unmarshal(data, &file)
+		templates = file.Templates
+	}
+	if err != nil {
+		return fmt.Errorf("parse templates %s: expected JSON array of templates or {\"templates\": [...]}: %w", path, err)
+	}
+	for _, t := range templates {
+		if _, err := registry.Register(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleRegisterTemplate(templates *assertion.TemplateRegistry) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"register_template called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+
+		var p types.RegisterTemplateParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				"invalid register_template params",
+				types.ErrTypeAssertionError,
+				false,
+				err.Error(),
+			)
+		}
+		replaced, err := templates.Register(p)
+		if err != nil {
+			return nil, types.NewRPCError(
+				types.ErrAssertionError,
+				fmt.Sprintf("invalid template: %v", err),
+				types.ErrTypeAssertionError,
+				false,
+				"Declare every {{param}} the spec references in params, and give type and spec.",
+			)
+		}
+		return &types.RegisterTemplateResult{Name: p.Name, Replaced: replaced}, nil
+	}
+}
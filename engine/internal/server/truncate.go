@@ -0,0 +1,90 @@
+package server
+
+import (
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// defaultMaxResponseSize is the default largest result the server sends,
+// matching the longest request line it reads.
+const defaultMaxResponseSize = maxScanBuf
+
+// explanationCaps are the lengths, in characters, that explanations are cut
+// to in turn while an evaluate_batch result is too large.
+var explanationCaps = []int{1000, 200, 0}
+
+// fitResult shrinks an oversized result to at most limit bytes of JSON and
+// returns it. Only evaluate_batch results can be shrunk; for any other result,
+// or one that cannot fit, it returns false.
+func fitResult(result any, limit int) (json.RawMessage, bool) {
+	r, ok := result.(*types.EvaluateBatchResult)
+	if !ok {
+		return nil, false
+	}
+	return fitBatchResult(r, limit)
+}
+
+// fitBatchResult applies the truncation policy: explanations are shortened
+// first, then excerpts, composite children, and judge runs are dropped, and
+// as a last resort results are omitted from the end. Every shortened result
+// is marked truncated, and the summary keeps counting omitted results.
+func fitBatchResult(r *types.EvaluateBatchResult, limit int) (json.RawMessage, bool) {
+	r.Truncated = true
+	for _, n := range explanationCaps {
+		for i := range r.Results {
+			capExplanation(&r.Results[i], n)
+		}
+		if raw, err := json.Marshal(r); err == nil && len(raw) <= limit {
+			return raw, true
+		}
+	}
+
+	for i := range r.Results {
+		dropDetail(&r.Results[i])
+	}
+	for {
+		raw, err := json.Marshal(r)
+		if err != nil {
+			return nil, false
+		}
+		if len(raw) <= limit {
+			return raw, true
+		}
+		if len(r.Results) == 0 {
+			return nil, false
+		}
+		// Drop enough results to cover the excess in one step, measured on
+		// their own encoding, then re-check.
+		excess := len(raw) - limit
+		n := len(r.Results)
+		for n > 0 && excess > 0 {
+			n--
+			if b, err := json.Marshal(&r.Results[n]); err == nil {
+				excess -= len(b) + 1
+			}
+		}
+		r.OmittedResults += len(r.Results) - n
+		r.Results = r.Results[:n]
+	}
+}
+
+// capExplanation shortens the explanations of ar and its children to n
+// characters.
+func capExplanation(ar *types.AssertionResult, n int) {
+	if runes := []rune(ar.Explanation); len(runes) > n {
+		ar.Explanation = string(runes[:n]) + "..."
+		ar.Truncated = true
+	}
+	for i := range ar.Children {
+		capExplanation(&ar.Children[i], n)
+	}
+}
+
+// dropDetail removes the optional breakdowns of ar.
+func dropDetail(ar *types.AssertionResult) {
+	if ar.Excerpts != nil || ar.Children != nil || ar.JudgeRuns != nil {
+		ar.Excerpts, ar.Children, ar.JudgeRuns = nil, nil, nil
+		ar.Truncated = true
+	}
+}
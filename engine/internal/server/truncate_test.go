@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func batchWithExplanations(n, length int) *types.EvaluateBatchResult {
+	r := &types.EvaluateBatchResult{Summary: &types.BatchSummary{}}
+	for i := range n {
+		r.Results = append(r.Results, types.AssertionResult{
+			AssertionID: "a" + string(rune('0'+i%10)),
+			Status:      types.StatusHardFail,
+			Explanation: strings.Repeat("x", length),
+			Excerpts:    []types.Excerpt{{Term: "t", Text: "context"}},
+		})
+		r.Summary.Counts.Add(types.StatusHardFail)
+	}
+	return r
+}
+
+func TestFitBatchResult_TruncatesExplanationsFirst(t *testing.T) {
+	r := batchWithExplanations(10, 5000)
+	raw, ok := fitBatchResult(r, 20000)
+	if !ok || len(raw) > 20000 {
+		t.Fatalf("fitBatchResult = %d bytes, %v; want at most 20000", len(raw), ok)
+	}
+	var got types.EvaluateBatchResult
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !got.Truncated || got.OmittedResults != 0 || len(got.Results) != 10 {
+		t.Fatalf("truncated=%v omitted=%d results=%d; want all 10 results kept", got.Truncated, got.OmittedResults, len(got.Results))
+	}
+	ar := got.Results[0]
+	if !ar.Truncated || len(ar.Explanation) != 1003 || len(ar.Excerpts) != 1 {
+		t.Errorf("result 0: truncated=%v explanation=%d chars excerpts=%d; want 1000-char explanation with excerpts kept",
+			ar.Truncated, len(ar.Explanation), len(ar.Excerpts))
+	}
+}
+
+func TestFitBatchResult_OmitsResultsLast(t *testing.T) {
+	r := batchWithExplanations(200, 5000)
+	raw, ok := fitBatchResult(r, 4000)
+	if !ok || len(raw) > 4000 {
+		t.Fatalf("fitBatchResult = %d bytes, %v; want at most 4000", len(raw), ok)
+	}
+	var got types.EvaluateBatchResult
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.OmittedResults == 0 || len(got.Results)+got.OmittedResults != 200 {
+		t.Errorf("results=%d omitted=%d; want some omitted, 200 in total", len(got.Results), got.OmittedResults)
+	}
+	if got.Summary.Counts.HardFail != 200 {
+		t.Errorf("summary hard_fail = %d, want 200 (omitted results still counted)", got.Summary.Counts.HardFail)
+	}
+	if got.Results[0].Excerpts != nil || got.Results[0].Explanation != "..." {
+		t.Errorf("result 0 = %+v, want details dropped", got.Results[0])
+	}
+}
+
+func TestServer_MaxResponseSize(t *testing.T) {
+	srv := New(strings.NewReader(""), io.Discard, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	srv.SetMaxResponseSize(100)
+	srv.RegisterHandler("big", func(context.Context, *Session, json.RawMessage) (any, *types.RPCError) {
+		return map[string]string{"blob": strings.Repeat("x", 200)}, nil
+	})
+	srv.RegisterHandler("batch", func(context.Context, *Session, json.RawMessage) (any, *types.RPCError) {
+		return batchWithExplanations(1, 500), nil
+	})
+
+	resp := srv.dispatch(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"big"}`))
+	if resp.Error == nil || resp.Error.Code != types.ErrEngineError {
+		t.Errorf("oversized result: error = %+v, want ENGINE_ERROR", resp.Error)
+	}
+
+	srv.SetMaxResponseSize(400)
+	resp = srv.dispatch(context.Background(), []byte(`{"jsonrpc":"2.0","id":2,"method":"batch"}`))
+	if resp.Error != nil || len(resp.Result) > 400 || !strings.Contains(string(resp.Result), `"truncated":true`) {
+		t.Errorf("oversized batch: %s, %+v; want truncated result within 400 bytes", resp.Result, resp.Error)
+	}
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/encoding/json"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+const (
+	// maxUploadChunkBytes bounds one append_trace_chunk. Base64 grows it by a
+	// third, which keeps the request line well under the scanner buffer.
+	maxUploadChunkBytes = 4 << 20 // 4 MB
+	// maxPendingUploads and maxUploadedTraces bound how many uploads in
+	// progress and assembled traces are held; the oldest is dropped first.
+	maxPendingUploads = 4
+	maxUploadedTraces = 4
+)
+
+// traceUploads assembles traces sent in chunks by begin_trace,
+// append_trace_chunk, and end_trace, so a trace is not limited by the
+// longest request line. Assembled traces are held by reference for
+// evaluate_batch until evicted by newer uploads.
+type traceUploads struct {
+	mu       sync.Mutex
+	limits   trace.Limits
+	next     int
+	pending  map[string]*pendingUpload
+	traces   map[string][]byte
+	pendings []string // upload IDs, oldest first
+	order    []string // trace refs, oldest first
+}
+
+type pendingUpload struct {
+	buf     bytes.Buffer
+	hash    hash.Hash
+	chunks  int
+	lastSum string
+}
+
+func newTraceUploads(limits trace.Limits) *traceUploads {
+	return &traceUploads{
+		limits:  limits,
+		pending: make(map[string]*pendingUpload),
+		traces:  make(map[string][]byte),
+	}
+}
+
+// begin starts an upload and returns its ID.
+func (u *traceUploads) begin(sizeBytes int) (string, *types.RPCError) {
+	if sizeBytes > u.limits.MaxTraceSize {
+		return "", uploadTooLarge(sizeBytes, u.limits.MaxTraceSize)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.next++
+	id := fmt.Sprintf("upl_%d", u.next)
+	u.pending[id] = &pendingUpload{hash: sha256.New()}
+	u.pendings = append(u.pendings, id)
+	if len(u.pendings) > maxPendingUploads {
+		delete(u.pending, u.pendings[0])
+		u.pendings = u.pendings[1:]
+	}
+	return id, nil
+}
+
+// appendChunk appends chunk index of upload id after checking its checksum.
+// Resending the last accepted chunk is a no-op, so a client may retry a chunk
+// whose response it lost.
+func (u *traceUploads) appendChunk(p *types.AppendTraceChunkParams) (*types.AppendTraceChunkResult, *types.RPCError) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	up, ok := u.pending[p.UploadID]
+	if !ok {
+		return nil, unknownUpload(p.UploadID)
+	}
+	sum := sha256.Sum256(p.Data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, p.SHA256) {
+		return nil, types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("trace chunk %d checksum mismatch: got sha256 %s", p.Index, got),
+			types.ErrTypeInvalidTrace,
+			true,
+			"Resend the chunk with sha256 set to the hex SHA-256 of its bytes.",
+		)
+	}
+	switch {
+	case p.Index == up.chunks-1 && strings.EqualFold(p.SHA256, up.lastSum):
+		return &types.AppendTraceChunkResult{ReceivedBytes: up.buf.Len(), Chunks: up.chunks}, nil
+	case p.Index != up.chunks:
+		return nil, types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("trace chunk %d out of order: expected chunk %d", p.Index, up.chunks),
+			types.ErrTypeInvalidTrace,
+			false,
+			"Send chunks in order, starting at index 0.",
+		)
+	case len(p.Data) > maxUploadChunkBytes:
+		return nil, types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("trace chunk %d exceeds max chunk size: %d > %d bytes", p.Index, len(p.Data), maxUploadChunkBytes),
+			types.ErrTypeInvalidTrace,
+			false,
+			fmt.Sprintf("Split the trace into chunks of at most %d bytes.", maxUploadChunkBytes),
+		)
+	case up.buf.Len()+len(p.Data) > u.limits.MaxTraceSize:
+		u.dropPending(p.UploadID)
+		return nil, uploadTooLarge(up.buf.Len()+len(p.Data), u.limits.MaxTraceSize)
+	}
+	up.buf.Write(p.Data)
+	up.hash.Write(p.Data)
+	up.chunks++
+	up.lastSum = p.SHA256
+	return &types.AppendTraceChunkResult{ReceivedBytes: up.buf.Len(), Chunks: up.chunks}, nil
+}
+
+// end checks the whole trace's checksum and limits and stores it for
+// evaluate_batch under a trace ref.
+func (u *traceUploads) end(p *types.EndTraceParams) (*types.EndTraceResult, *types.RPCError) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	up, ok := u.pending[p.UploadID]
+	if !ok {
+		return nil, unknownUpload(p.UploadID)
+	}
+	if got := hex.EncodeToString(up.hash.Sum(nil)); !strings.EqualFold(got, p.SHA256) {
+		u.dropPending(p.UploadID)
+		return nil, types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("assembled trace checksum mismatch: got sha256 %s", got),
+			types.ErrTypeInvalidTrace,
+			true,
+			"Upload the trace again; sha256 must be the hex SHA-256 of the whole trace.",
+		)
+	}
+	u.dropPending(p.UploadID)
+
+	raw := up.buf.Bytes()
+	if _, rpcErr := u.limits.Scan(raw); rpcErr != nil {
+		return nil, rpcErr
+	}
+	var head struct {
+		TraceID string `json:"trace_id"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("assembled trace is not a valid trace object: %v", err),
+			types.ErrTypeInvalidTrace,
+			false,
+			"Upload the compact JSON of a single trace object.",
+		)
+	}
+
+	// The upload ID doubles as the trace ref.
+	ref := p.UploadID
+	u.traces[ref] = raw
+	u.order = append(u.order, ref)
+	if len(u.order) > maxUploadedTraces {
+		delete(u.traces, u.order[0])
+		u.order = u.order[1:]
+	}
+	return &types.EndTraceResult{TraceRef: ref, TraceID: head.TraceID, SizeBytes: len(raw), Chunks: up.chunks}, nil
+}
+
+// get returns the raw JSON of an assembled trace.
+func (u *traceUploads) get(ref string) ([]byte, *types.RPCError) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	raw, ok := u.traces[ref]
+	if !ok {
+		return nil, types.NewRPCError(
+			types.ErrSessionError,
+			fmt.Sprintf("unknown trace_ref %q", ref),
+			types.ErrTypeSessionError,
+			false,
+			fmt.Sprintf("Upload the trace again with begin_trace; the engine holds the last %d uploaded traces.", maxUploadedTraces),
+		)
+	}
+	return raw, nil
+}
+
+func (u *traceUploads) dropPending(id string) {
+	delete(u.pending, id)
+	for i, p := range u.pendings {
+		if p == id {
+			u.pendings = append(u.pendings[:i], u.pendings[i+1:]...)
+			break
+		}
+	}
+}
+
+func unknownUpload(id string) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrSessionError,
+		fmt.Sprintf("unknown upload_id %q", id),
+		types.ErrTypeSessionError,
+		false,
+		fmt.Sprintf("Start the upload with begin_trace; the engine holds the last %d unfinished uploads.", maxPendingUploads),
+	)
+}
+
+func uploadTooLarge(size, limit int) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("trace exceeds max size: %d > %d bytes", size, limit),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("Reduce trace size by filtering steps or truncating tool results. Max allowed: %d bytes.", limit),
+	)
+}
+
+func handleBeginTrace(uploads *traceUploads) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"begin_trace called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+		var p types.BeginTraceParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, invalidUploadParams("begin_trace", err)
+			}
+		}
+		id, rpcErr := uploads.begin(p.SizeBytes)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		return &types.BeginTraceResult{
+			UploadID:          id,
+			MaxChunkBytes:     maxUploadChunkBytes,
+			MaxTraceSizeBytes: uploads.limits.MaxTraceSize,
+		}, nil
+	}
+}
+
+func handleAppendTraceChunk(uploads *traceUploads) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"append_trace_chunk called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+		var p types.AppendTraceChunkParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidUploadParams("append_trace_chunk", err)
+		}
+		result, rpcErr := uploads.appendChunk(&p)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		return result, nil
+	}
+}
+
+func handleEndTrace(uploads *traceUploads) Handler {
+	return func(ctx context.Context, session *Session, params json.RawMessage) (any, *types.RPCError) {
+		if session.State() != StateInitialized {
+			return nil, types.NewRPCError(
+				types.ErrSessionError,
+				"end_trace called before initialize",
+				types.ErrTypeSessionError,
+				false,
+				"call initialize first to establish a session",
+			)
+		}
+		var p types.EndTraceParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidUploadParams("end_trace", err)
+		}
+		result, rpcErr := uploads.end(&p)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		return result, nil
+	}
+}
+
+func invalidUploadParams(method string, err error) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("invalid %s params: %v", method, err),
+		types.ErrTypeInvalidTrace,
+		false,
+		"Check the request format matches the protocol spec.",
+	)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHandler_ChunkedTraceUpload(t *testing.T) {
+	send, recv := initServer(t)
+
+	raw := []byte(`{"trace_id":"trc_chunked","output":{"message":"hello world"},"steps":[{"type":"tool_call","name":"search","args":{},"result":{}}]}`)
+	send(2, "begin_trace", types.BeginTraceParams{SizeBytes: len(raw)})
+	resp := recv()
+	if resp.Error != nil {
+		t.Fatalf("begin_trace: %+v", resp.Error)
+	}
+	var begin types.BeginTraceResult
+	if err := json.Unmarshal(resp.Result, &begin); err != nil {
+		t.Fatalf("unmarshal begin_trace: %v", err)
+	}
+
+	id := int64(3)
+	for i, start := 0, 0; start < len(raw); i, start = i+1, start+40 {
+		chunk := raw[start:min(start+40, len(raw))]
+		send(id, "append_trace_chunk", types.AppendTraceChunkParams{UploadID: begin.UploadID, Index: i, Data: chunk, SHA256: sha256Hex(chunk)})
+		id++
+		if resp := recv(); resp.Error != nil {
+			t.Fatalf("append_trace_chunk %d: %+v", i, resp.Error)
+		}
+	}
+
+	send(id, "end_trace", types.EndTraceParams{UploadID: begin.UploadID, SHA256: sha256Hex(raw)})
+	id++
+	resp = recv()
+	if resp.Error != nil {
+		t.Fatalf("end_trace: %+v", resp.Error)
+	}
+	var end types.EndTraceResult
+	if err := json.Unmarshal(resp.Result, &end); err != nil {
+		t.Fatalf("unmarshal end_trace: %v", err)
+	}
+	if end.TraceID != "trc_chunked" || end.SizeBytes != len(raw) || end.Chunks != 4 {
+		t.Errorf("end_trace = %+v, want trc_chunked, %d bytes, 4 chunks", end, len(raw))
+	}
+
+	send(id, "evaluate_batch", map[string]any{
+		"trace_ref": end.TraceRef,
+		"assertions": []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hello"}`),
+		}},
+	})
+	resp = recv()
+	if resp.Error != nil {
+		t.Fatalf("evaluate_batch: %+v", resp.Error)
+	}
+	var result types.EvaluateBatchResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("unmarshal evaluate_batch: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Status != types.StatusPass {
+		t.Errorf("results = %+v, want one pass", result.Results)
+	}
+}
+
+func TestTraceUploads_RejectsBadChunks(t *testing.T) {
+	limits, _ := trace.Limits{MaxTraceSize: 64}.Clamp()
+	u := newTraceUploads(limits)
+	id, rpcErr := u.begin(0)
+	if rpcErr != nil {
+		t.Fatalf("begin: %+v", rpcErr)
+	}
+
+	chunk := []byte(`{"trace_id":"t",`)
+	if _, rpcErr := u.appendChunk(&types.AppendTraceChunkParams{UploadID: id, Data: chunk, SHA256: "00"}); rpcErr == nil || !rpcErr.Data.Retryable {
+		t.Errorf("bad checksum: got %+v, want retryable error", rpcErr)
+	}
+	if _, rpcErr := u.appendChunk(&types.AppendTraceChunkParams{UploadID: id, Index: 1, Data: chunk, SHA256: sha256Hex(chunk)}); rpcErr == nil {
+		t.Error("out-of-order chunk accepted")
+	}
+	for range 2 {
+		// A resent chunk is accepted once.
+		got, rpcErr := u.appendChunk(&types.AppendTraceChunkParams{UploadID: id, Data: chunk, SHA256: sha256Hex(chunk)})
+		if rpcErr != nil || got.Chunks != 1 {
+			t.Fatalf("append chunk 0 = %+v, %+v; want 1 chunk", got, rpcErr)
+		}
+	}
+
+	big := make([]byte, 60)
+	if _, rpcErr := u.appendChunk(&types.AppendTraceChunkParams{UploadID: id, Index: 1, Data: big, SHA256: sha256Hex(big)}); rpcErr == nil {
+		t.Error("chunk past max trace size accepted")
+	}
+	if _, rpcErr := u.end(&types.EndTraceParams{UploadID: id}); rpcErr == nil || rpcErr.Data.ErrorType != types.ErrTypeSessionError {
+		t.Errorf("end after oversized chunk = %+v, want unknown upload", rpcErr)
+	}
+	if _, rpcErr := u.get(id); rpcErr == nil {
+		t.Error("get of unfinished upload succeeded")
+	}
+}
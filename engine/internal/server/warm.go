@@ -0,0 +1,222 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/segmentio/encoding/json"
+	"gopkg.in/yaml.v3"
+
+	"github.com/attest-ai/attest/engine/internal/assertion"
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// WarmCache pre-computes embeddings and judge results for the assertion suite
+// at suitePath against the traces in tracesDir (optional), using the same
+// ATTEST_* provider, cache, and template configuration as the engine. It backs
+// `attest-engine cache warm`.
+func WarmCache(logger *slog.Logger, suitePath, tracesDir string, concurrency int) (*assertion.WarmReport, error) {
+	assertions, err := loadSuite(suitePath)
+	if err != nil {
+		return nil, err
+	}
+	templates := assertion.NewTemplateRegistry()
+	if path := templatesFile(); path != "" {
+		if err := loadTemplates(templates, path); err != nil {
+			return nil, err
+		}
+	}
+	if err := templates.ExpandAll(assertions); err != nil {
+		return nil, fmt.Errorf("expand suite: %w", err)
+	}
+	traces, err := loadTraces(tracesDir, buildTraceLimits(logger))
+	if err != nil {
+		return nil, err
+	}
+
+	store := openCacheStore(logger)
+	if store == nil {
+		return nil, errors.New("cache database unavailable; see log for details")
+	}
+	defer store.Close()
+
+	opts, _, _, _, _, _ := buildRegistryOptions(logger, store)
+	registry := assertion.NewRegistry(opts...)
+	return assertion.WarmCache(registry, assertions, traces, concurrency), nil
+}
+
+// loadSuite reads an assertion suite: an array of assertions or an object
+// with an "assertions" array, in JSON or, for .yaml and .yml files, YAML.
+func loadSuite(path string) ([]types.Assertion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read suite: %w", err)
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("parse suite %s: %w", path, err)
+		}
+	}
+	data = bytes.TrimSpace(data)
+
+	var assertions []types.Assertion
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &assertions)
+	} else {
+		var suite struct {
+			Assertions []types.Assertion `json:"assertions"`
+		}
+		err = json.Unmarshal(data, &suite)
+		assertions = suite.Assertions
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse suite %s: expected JSON array of assertions or {\"assertions\": [...]}: %w", path, err)
+	}
+	return assertions, nil
+}
+
+// yamlToJSON re-encodes a YAML document as JSON, so YAML suites decode into
+// the same types, including json.RawMessage specs, as JSON suites.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, err := jsonValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonValue converts a decoded YAML value to one JSON can encode. Mapping
+// keys must be strings.
+func jsonValue(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+		return v, nil
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("mapping key %v is not a string", k)
+			}
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = e
+		}
+		return m, nil
+	case []any:
+		for i, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// loadTraces reads every *.json (one trace) and *.jsonl (one trace per line)
+// file in dir, normalizing each trace and validating it against limits. An
+// empty dir yields no traces.
+func loadTraces(dir string, limits trace.Limits) ([]*types.Trace, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read traces dir: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var traces []*types.Trace
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".json":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read trace: %w", err)
+			}
+			t, err := parseTrace(data, limits)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			traces = append(traces, t)
+		case ".jsonl":
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("read trace: %w", err)
+			}
+			sc := bufio.NewScanner(f)
+			sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+			line := 0
+			for sc.Scan() {
+				line++
+				if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+					continue
+				}
+				t, err := parseTrace(sc.Bytes(), limits)
+				if err != nil {
+					f.Close()
+					return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+				}
+				traces = append(traces, t)
+			}
+			err = sc.Err()
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", path, err)
+			}
+		}
+	}
+	return traces, nil
+}
+
+func parseTrace(data []byte, limits trace.Limits) (*types.Trace, error) {
+	var t types.Trace
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse trace: %w", err)
+	}
+	trace.Normalize(&t)
+	stats, rpcErr := limits.Scan(data)
+	switch {
+	case rpcErr != nil:
+	case stats != nil:
+		rpcErr = limits.ValidateScanned(&t, stats)
+	default:
+		rpcErr = limits.Validate(&t, 0)
+	}
+	if rpcErr != nil {
+		return nil, fmt.Errorf("invalid trace: %s", rpcErr.Message)
+	}
+	return &t, nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+)
+
+func TestLoadSuite_ArrayAndObjectForms(t *testing.T) {
+	dir := t.TempDir()
+	arrayPath := filepath.Join(dir, "array.json")
+	objectPath := filepath.Join(dir, "suite.yaml")
+	writeFile(t, arrayPath, `[{"assertion_id":"a","type":"embedding","spec":{"target":"output","reference":"r"}}]`)
+	writeFile(t, objectPath, `{"assertions": [{"assertion_id":"a","type":"llm_judge","spec":{}}, {"assertion_id":"b","type":"schema","spec":{}}]}`)
+
+	got, err := loadSuite(arrayPath)
+	if err != nil || len(got) != 1 || got[0].AssertionID != "a" {
+		t.Errorf("array suite = %+v, %v", got, err)
+	}
+	got, err = loadSuite(objectPath)
+	if err != nil || len(got) != 2 || got[1].Type != "schema" {
+		t.Errorf("object suite = %+v, %v", got, err)
+	}
+
+	blockYAML := filepath.Join(dir, "block.yml")
+	writeFile(t, blockYAML, "assertions:\n  - assertion_id: a\n    type: content\n    spec:\n      target: output.message\n      check: contains\n      value: hi\n")
+	got, err = loadSuite(blockYAML)
+	if err != nil || len(got) != 1 || got[0].Type != "content" {
+		t.Fatalf("block YAML suite = %+v, %v", got, err)
+	}
+	var spec map[string]string
+	if err := json.Unmarshal(got[0].Spec, &spec); err != nil || spec["check"] != "contains" {
+		t.Errorf("block YAML spec = %s, %v", got[0].Spec, err)
+	}
+
+	badKeys := filepath.Join(dir, "bad.yaml")
+	writeFile(t, badKeys, "assertions:\n  - {1: a}\n")
+	if _, err := loadSuite(badKeys); err == nil {
+		t.Error("expected error for non-string mapping key")
+	}
+	badJSON := filepath.Join(dir, "block.json")
+	writeFile(t, badJSON, "assertions:\n  - assertion_id: a\n")
+	if _, err := loadSuite(badJSON); err == nil {
+		t.Error("expected error for YAML in a .json suite")
+	}
+}
+
+func TestLoadTraces_JSONAndJSONL(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"schema_version":1,"trace_id":"trc_a","output":{"message":"hi"}}`)
+	writeFile(t, filepath.Join(dir, "b.jsonl"), `{"schema_version":1,"trace_id":"trc_b","output":{"message":"one"}}
+
+{"schema_version":1,"trace_id":"trc_c","output":{"message":"two"}}
+`)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+
+	traces, err := loadTraces(dir, trace.DefaultLimits)
+	if err != nil {
+		t.Fatalf("loadTraces: %v", err)
+	}
+	if len(traces) != 3 || traces[0].TraceID != "trc_a" || traces[2].TraceID != "trc_c" {
+		t.Errorf("traces = %d, want trc_a, trc_b, trc_c in order", len(traces))
+	}
+
+	writeFile(t, filepath.Join(dir, "z.json"), `{"schema_version":99,"trace_id":"trc_bad"}`)
+	if _, err := loadTraces(dir, trace.DefaultLimits); err == nil {
+		t.Error("expected error for invalid trace")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,115 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// UserEvent is one simulated user turn: a text message and any structured
+// actions taken with it.
+type UserEvent struct {
+	Message string
+	Actions []types.UserAction
+}
+
+// ValidateActionTypes reports an error for any action type the simulation
+// does not support.
+func ValidateActionTypes(actionTypes []string) error {
+	for _, t := range actionTypes {
+		switch t {
+		case types.ActionAttachFile, types.ActionFillForm, types.ActionSelectOption:
+		default:
+			return fmt.Errorf("unsupported action type %q (must be %s, %s, or %s)",
+				t, types.ActionAttachFile, types.ActionFillForm, types.ActionSelectOption)
+		}
+	}
+	return nil
+}
+
+var actionDescriptions = map[string]string{
+	types.ActionAttachFile:   `{"type": "attach_file", "file": {"name": "<file name>", "mime_type": "<type>", "content": "<text, or a description for binary files>"}}`,
+	types.ActionFillForm:     `{"type": "fill_form", "form": "<form name>", "fields": {"<field>": "<value>"}}`,
+	types.ActionSelectOption: `{"type": "select_option", "option": "<the option's label>"}`,
+}
+
+// actionInstructions tells the simulated user how to take the allowed actions.
+func actionInstructions(actionTypes []string) string {
+	var b strings.Builder
+	b.WriteString("\n\nBesides typing, you can act on the product's interface. Reply with JSON only:\n")
+	b.WriteString(`{"message": "<what you type, may be empty>", "actions": [<zero or more actions>]}`)
+	b.WriteString("\nAvailable actions:\n")
+	for _, t := range actionTypes {
+		b.WriteString(actionDescriptions[t])
+		b.WriteByte('\n')
+	}
+	b.WriteString("Only act when the conversation calls for it, e.g. the agent asks for a file or offers options.")
+	return b.String()
+}
+
+// ParseUserEvent reads a simulated user's reply. A JSON reply of the form
+// {"message", "actions"} yields its message and the actions of allowed
+// types; anything else is taken as plain text.
+func ParseUserEvent(content string, actionTypes []string) UserEvent {
+	raw := strings.TrimSpace(content)
+	raw = strings.TrimPrefix(raw, "```json")
+	raw = strings.TrimPrefix(raw, "```")
+	raw = strings.TrimSuffix(raw, "```")
+	raw = strings.TrimSpace(raw)
+
+	var reply struct {
+		Message *string            `json:"message"`
+		Actions []types.UserAction `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(raw), &reply); err != nil || (reply.Message == nil && len(reply.Actions) == 0) {
+		return UserEvent{Message: content}
+	}
+	event := UserEvent{}
+	if reply.Message != nil {
+		event.Message = *reply.Message
+	}
+	for _, a := range reply.Actions {
+		if containsString(actionTypes, a.Type) {
+			event.Actions = append(event.Actions, a)
+		}
+	}
+	return event
+}
+
+// historyContent renders e for conversation history, so the simulated user
+// sees the actions it took.
+func (e UserEvent) historyContent() string {
+	if len(e.Actions) == 0 {
+		return e.Message
+	}
+	actions, _ := json.Marshal(e.Actions)
+	return fmt.Sprintf("%s\n[actions: %s]", e.Message, actions)
+}
+
+// GenerateEvent produces the next user turn, with structured actions when the
+// persona allows them.
+func (u *SimulatedUser) GenerateEvent(ctx context.Context, conversationHistory []llm.Message) (UserEvent, error) {
+	event, _, err := u.generateEvent(ctx, conversationHistory)
+	return event, err
+}
+
+func (u *SimulatedUser) generateEvent(ctx context.Context, conversationHistory []llm.Message) (UserEvent, float64, error) {
+	content, cost, err := u.generate(ctx, conversationHistory)
+	if err != nil || len(u.persona.Actions) == 0 {
+		return UserEvent{Message: content}, cost, err
+	}
+	return ParseUserEvent(content, u.persona.Actions), cost, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package simulation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestParseUserEvent(t *testing.T) {
+	allowed := []string{types.ActionAttachFile, types.ActionSelectOption}
+	tests := []struct {
+		name        string
+		content     string
+		wantMessage string
+		wantActions []string
+	}{
+		{"plain text", "Where is my order?", "Where is my order?", nil},
+		{"message only", `{"message": "hi"}`, "hi", nil},
+		{
+			"fenced with actions",
+			"```json\n{\"message\": \"here it is\", \"actions\": [{\"type\": \"attach_file\", \"file\": {\"name\": \"receipt.pdf\"}}]}\n```",
+			"here it is", []string{types.ActionAttachFile},
+		},
+		{
+			"disallowed action dropped",
+			`{"message": "", "actions": [{"type": "fill_form", "fields": {"a": "b"}}, {"type": "select_option", "option": "Refund"}]}`,
+			"", []string{types.ActionSelectOption},
+		},
+		{"unrelated JSON", `{"order": 7}`, `{"order": 7}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := ParseUserEvent(tt.content, allowed)
+			if event.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", event.Message, tt.wantMessage)
+			}
+			var got []string
+			for _, a := range event.Actions {
+				got = append(got, a.Type)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantActions, ",") {
+				t.Errorf("actions = %v, want %v", got, tt.wantActions)
+			}
+		})
+	}
+}
+
+func TestValidateActionTypes(t *testing.T) {
+	if err := ValidateActionTypes([]string{types.ActionFillForm}); err != nil {
+		t.Errorf("fill_form: %v", err)
+	}
+	if err := ValidateActionTypes([]string{"drag_drop"}); err == nil {
+		t.Error("expected error for unsupported action type")
+	}
+}
+
+func TestOrchestratorUserActions(t *testing.T) {
+	mock := newUserMock([]string{`{"message": "attached", "actions": [{"type": "attach_file", "file": {"name": "id.png", "mime_type": "image/png"}}]}`})
+	persona := FriendlyUser
+	persona.Actions = []string{types.ActionAttachFile}
+	orch := NewOrchestrator(SimulationConfig{Persona: persona, MaxTurns: 2, Provider: mock})
+
+	var events []UserEvent
+	result, err := orch.RunSimulationEvents(context.Background(), UserEvent{Message: "verify me"}, func(_ context.Context, e UserEvent) (string, error) {
+		events = append(events, e)
+		return "please upload your ID", nil
+	})
+	if err != nil {
+		t.Fatalf("RunSimulationEvents: %v", err)
+	}
+
+	if len(events) != 2 || len(events[1].Actions) != 1 || events[1].Actions[0].File.Name != "id.png" {
+		t.Fatalf("events = %+v, want an attach_file action on turn 2", events)
+	}
+	if !strings.Contains(mock.LastRequest.SystemPrompt, `"type": "attach_file"`) {
+		t.Error("system prompt does not describe the allowed actions")
+	}
+	transcript := result.Transcript()
+	if got := transcript[2]; got.Content != "attached" || len(got.Actions) != 1 {
+		t.Errorf("transcript[2] = %+v", got)
+	}
+}
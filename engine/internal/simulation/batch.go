@@ -0,0 +1,192 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/attest-ai/attest/engine/internal/logging"
+)
+
+// DefaultBatchConcurrency is the number of batch runs in flight when
+// BatchConfig.Concurrency is zero.
+const DefaultBatchConcurrency = 4
+
+// BatchConfig describes N runs of one scenario.
+type BatchConfig struct {
+	// Base is the scenario; each run uses a copy with its persona and seed.
+	Base    SimulationConfig
+	Initial UserEvent
+	Runs    int
+	// Personas are assigned to runs round-robin in place of Base.Persona.
+	Personas []Persona
+	// Concurrency bounds the runs in flight. Default: DefaultBatchConcurrency.
+	Concurrency int
+	// Succeeded reports whether a finished run reached its goal. Default:
+	// a stop condition ended the run, not the turn budget or a failed
+	// per-turn assertion.
+	Succeeded func(*SimulationResult) bool
+}
+
+// BatchRun is the outcome of one run in a batch.
+type BatchRun struct {
+	Run     int
+	Persona string
+	Seed    *int64
+	// Result is nil when Err is set.
+	Result    *SimulationResult
+	Err       error
+	Succeeded bool
+}
+
+// TurnStats summarizes the turn counts of the runs that finished.
+type TurnStats struct {
+	Min    int
+	Max    int
+	Mean   float64
+	P50    int
+	P90    int
+	Counts map[int]int // turn count → runs
+}
+
+// BatchReport aggregates a batch of simulation runs.
+type BatchReport struct {
+	Runs      []BatchRun
+	Succeeded int
+	Failed    int
+	Errored   int
+	// SuccessRate is Succeeded over all runs, errored runs included.
+	SuccessRate float64
+	Turns       TurnStats
+	TotalCost   float64
+	// Worst is the index in Runs of the worst finished run: a failed run
+	// before a successful one, then more turns, then higher cost. It is -1
+	// when no run finished.
+	Worst int
+}
+
+// RunBatch runs cfg.Runs simulations with at most cfg.Concurrency in flight
+// and aggregates their outcomes. With a Base seed, run i uses seed+i.
+// newAgent returns the agent callback for run i. Runs that fail with an
+// error are reported, not returned; ctx cancellation fails the remaining
+// runs.
+func RunBatch(
+	ctx context.Context,
+	cfg BatchConfig,
+	newAgent func(run int) func(ctx context.Context, event UserEvent) (string, error),
+) *BatchReport {
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	succeeded := cfg.Succeeded
+	if succeeded == nil {
+		succeeded = func(r *SimulationResult) bool {
+			return r.StoppedBy == "condition" || strings.HasPrefix(r.StoppedBy, "keyword:")
+		}
+	}
+
+	runs := make([]BatchRun, cfg.Runs)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range runs {
+		config := cfg.Base
+		if len(cfg.Personas) > 0 {
+			config.Persona = cfg.Personas[i%len(cfg.Personas)]
+		}
+		if cfg.Base.Seed != nil {
+			seed := *cfg.Base.Seed + int64(i)
+			config.Seed = &seed
+		}
+		runs[i] = BatchRun{Run: i, Persona: config.Persona.Name, Seed: config.Seed}
+
+		wg.Add(1)
+		go func(run *BatchRun, config SimulationConfig) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// A panicking run fails alone instead of killing the engine.
+			defer func() {
+				if r := recover(); r != nil {
+					logging.FromContext(ctx).Error("simulation run panicked", "run", run.Run, "panic", r, "stack", string(debug.Stack()))
+					run.Result, run.Err = nil, fmt.Errorf("simulation run panicked: %v", r)
+				}
+			}()
+			if err := ctx.Err(); err != nil {
+				run.Err = err
+				return
+			}
+			run.Result, run.Err = NewOrchestrator(config).RunSimulationEvents(ctx, cfg.Initial, newAgent(run.Run))
+			if run.Err == nil {
+				run.Succeeded = succeeded(run.Result)
+			}
+		}(&runs[i], config)
+	}
+	wg.Wait()
+
+	return summarizeBatch(runs)
+}
+
+func summarizeBatch(runs []BatchRun) *BatchReport {
+	report := &BatchReport{Runs: runs, Worst: -1, Turns: TurnStats{Counts: make(map[int]int)}}
+	var turns []int
+	for i, r := range runs {
+		switch {
+		case r.Err != nil:
+			report.Errored++
+			continue
+		case r.Succeeded:
+			report.Succeeded++
+		default:
+			report.Failed++
+		}
+		report.TotalCost += r.Result.Cost + r.Result.AssertionCost
+		turns = append(turns, r.Result.TotalTurns)
+		report.Turns.Counts[r.Result.TotalTurns]++
+		if report.Worst < 0 || worseRun(r, runs[report.Worst]) {
+			report.Worst = i
+		}
+	}
+	if len(runs) > 0 {
+		report.SuccessRate = float64(report.Succeeded) / float64(len(runs))
+	}
+	if len(turns) == 0 {
+		return report
+	}
+
+	sort.Ints(turns)
+	sum := 0
+	for _, t := range turns {
+		sum += t
+	}
+	report.Turns.Min = turns[0]
+	report.Turns.Max = turns[len(turns)-1]
+	report.Turns.Mean = float64(sum) / float64(len(turns))
+	report.Turns.P50 = nearestRank(turns, 0.5)
+	report.Turns.P90 = nearestRank(turns, 0.9)
+	return report
+}
+
+// worseRun reports whether finished run a is worse than finished run b.
+func worseRun(a, b BatchRun) bool {
+	if a.Succeeded != b.Succeeded {
+		return !a.Succeeded
+	}
+	if a.Result.TotalTurns != b.Result.TotalTurns {
+		return a.Result.TotalTurns > b.Result.TotalTurns
+	}
+	return a.Result.Cost+a.Result.AssertionCost > b.Result.Cost+b.Result.AssertionCost
+}
+
+// nearestRank returns the q-quantile of sorted by the nearest-rank method.
+func nearestRank(sorted []int, q float64) int {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,95 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+)
+
+func TestRunBatch_Aggregates(t *testing.T) {
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{{Content: "still waiting", Cost: 0.01}}, nil)
+	seed := int64(100)
+	cfg := BatchConfig{
+		Base: SimulationConfig{
+			MaxTurns:       4,
+			Provider:       mock,
+			Seed:           &seed,
+			StopConditions: []StopCondition{KeywordStopCondition{Keywords: []string{"resolved"}}},
+		},
+		Initial:     UserEvent{Message: "refund please"},
+		Runs:        6,
+		Personas:    []Persona{FriendlyUser, ConfusedUser},
+		Concurrency: 2,
+	}
+	var inFlight, peak atomic.Int32
+	// Run r resolves on turn r+1, so runs 3 and 4 exhaust the 4-turn budget
+	// (max_turns is checked before stop conditions). Run 5 fails on turn 3.
+	newAgent := func(run int) func(context.Context, UserEvent) (string, error) {
+		turn := 0
+		return func(context.Context, UserEvent) (string, error) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			if run == 5 && turn == 2 {
+				return "", errors.New("agent crashed")
+			}
+			turn++
+			if turn == run+1 {
+				return "resolved", nil
+			}
+			return "working on it", nil
+		}
+	}
+
+	report := RunBatch(context.Background(), cfg, newAgent)
+
+	if report.Succeeded != 3 || report.Failed != 2 || report.Errored != 1 {
+		t.Fatalf("succeeded/failed/errored = %d/%d/%d, want 3/2/1", report.Succeeded, report.Failed, report.Errored)
+	}
+	if got, want := report.SuccessRate, 0.5; got != want {
+		t.Errorf("SuccessRate = %v, want %v", got, want)
+	}
+	if report.Turns.Min != 1 || report.Turns.Max != 4 || report.Turns.P50 != 3 || report.Turns.P90 != 4 {
+		t.Errorf("Turns = %+v", report.Turns)
+	}
+	if report.Turns.Counts[4] != 2 {
+		t.Errorf("Turns.Counts[4] = %d, want 2", report.Turns.Counts[4])
+	}
+	if report.Worst != 3 {
+		t.Errorf("Worst = %d, want 3 (first of the tied failed runs)", report.Worst)
+	}
+	// Run r makes one user generation per turn after the first.
+	if got, want := report.TotalCost, 0.01*(0+1+2+3+3); got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("TotalCost = %v, want %v", got, want)
+	}
+	if report.Runs[1].Persona != ConfusedUser.Name || *report.Runs[3].Seed != 103 {
+		t.Errorf("run 1 persona = %q, run 3 seed = %d", report.Runs[1].Persona, *report.Runs[3].Seed)
+	}
+	if !strings.Contains(report.Runs[5].Err.Error(), "agent crashed") {
+		t.Errorf("run 5 error = %v", report.Runs[5].Err)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", peak.Load())
+	}
+}
+
+func TestRunBatch_NoFinishedRuns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := RunBatch(ctx, BatchConfig{Base: SimulationConfig{MaxTurns: 1, Provider: newUserMock(nil)}, Runs: 2},
+		func(int) func(context.Context, UserEvent) (string, error) {
+			return func(ctx context.Context, e UserEvent) (string, error) { return echoAgent(ctx, e.Message) }
+		})
+	if report.Errored != 2 || report.Worst != -1 || report.SuccessRate != 0 {
+		t.Errorf("report = %+v", report)
+	}
+}
@@ -0,0 +1,119 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+)
+
+func TestGenerateCandidates_DedupAndSeeds(t *testing.T) {
+	mock := llm.NewMockProvider([]*llm.CompletionResponse{
+		{Content: "Where is my order?", Cost: 0.001},
+		{Content: "I want a refund.", Cost: 0.001},
+		{Content: "Where is my order? ", Cost: 0.001},
+	}, nil)
+	user := NewSimulatedUserWithSeed(FriendlyUser, mock, 5)
+
+	candidates, err := user.GenerateCandidates(context.Background(), nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 {
+		t.Errorf("got %d candidates, want 2 after dedup: %+v", len(candidates), candidates)
+	}
+	var cost float64
+	for _, c := range candidates {
+		cost += c.Cost
+	}
+	if math.Abs(cost-0.003) > 1e-12 {
+		t.Errorf("candidate costs sum to %v, want 0.003 including the dropped duplicate", cost)
+	}
+
+	var seeds []int
+	for _, req := range mock.GetRequestHistory() {
+		seeds = append(seeds, int(*req.Seed))
+	}
+	sort.Ints(seeds)
+	if len(seeds) != 3 || seeds[0] != 5 || seeds[1] != 6 || seeds[2] != 7 {
+		t.Errorf("seeds = %v, want [5 6 7]", seeds)
+	}
+}
+
+func TestGenerateCandidates_Errors(t *testing.T) {
+	user := NewSimulatedUser(FriendlyUser, llm.NewMockProvider(nil, nil))
+	if _, err := user.GenerateCandidates(context.Background(), nil, MaxCandidates+1); err == nil {
+		t.Error("expected error for n above MaxCandidates")
+	}
+
+	fail := errors.New("provider down")
+	user = NewSimulatedUser(FriendlyUser, llm.NewMockProvider(nil, []error{fail, fail}))
+	if _, err := user.GenerateCandidates(context.Background(), nil, 2); !errors.Is(err, fail) {
+		t.Errorf("err = %v, want provider error when every candidate fails", err)
+	}
+
+	// A panicking provider counts as a failed generation.
+	panicky := llm.NewMockProvider(nil, nil)
+	panicky.MatchFunc = func(*llm.CompletionRequest) *llm.CompletionResponse { panic("provider bug") }
+	user = NewSimulatedUser(FriendlyUser, panicky)
+	if _, err := user.GenerateCandidates(context.Background(), nil, 2); err == nil || !strings.Contains(err.Error(), "panic: provider bug") {
+		t.Errorf("err = %v, want the recovered panic", err)
+	}
+	if err := RankCandidates(context.Background(), panicky, RankAdversarial, "", nil, []Candidate{{Message: "hi"}}); err != nil {
+		t.Errorf("RankCandidates after panic: %v", err)
+	}
+
+	// One failure out of two still yields a candidate.
+	user = NewSimulatedUser(FriendlyUser, llm.NewMockProvider([]*llm.CompletionResponse{{Content: "hi"}, {Content: "hi"}}, []error{fail}))
+	if c, err := user.GenerateCandidates(context.Background(), nil, 2); err != nil || len(c) != 1 {
+		t.Errorf("candidates = %+v, err = %v", c, err)
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	judge := llm.NewMockProvider(nil, nil)
+	judge.MatchFunc = func(req *llm.CompletionRequest) *llm.CompletionResponse {
+		content := req.Messages[0].Content
+		switch {
+		case !strings.Contains(req.SystemPrompt, "get a refund for order 7"):
+			return &llm.CompletionResponse{Content: "goal missing from prompt"}
+		case !strings.HasSuffix(content, "<<<AGENT_OUTPUT_END>>>"):
+			return &llm.CompletionResponse{Content: "candidate not wrapped"}
+		case strings.Contains(content, "refund for order 7 please"):
+			return &llm.CompletionResponse{Content: `{"score": 0.9, "explanation": "direct"}`, Cost: 0.01}
+		case strings.Contains(content, "what is the weather"):
+			return &llm.CompletionResponse{Content: `{"score": 0.1, "explanation": "off-goal"}`, Cost: 0.01}
+		}
+		return &llm.CompletionResponse{Content: "not json"}
+	}
+	candidates := []Candidate{{Message: "what is the weather"}, {Message: "garbled"}, {Message: "refund for order 7 please"}}
+	history := []llm.Message{{Role: "assistant", Content: "How can I help?"}}
+
+	if err := RankCandidates(context.Background(), judge, RankGoal, "get a refund for order 7", history, candidates); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{candidates[0].Message, candidates[1].Message, candidates[2].Message}
+	want := []string{"refund for order 7 please", "what is the weather", "garbled"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %q, want %q", got, want)
+		}
+	}
+	if candidates[2].Score != nil || !strings.Contains(candidates[2].Explanation, "ranking failed") {
+		t.Errorf("unparseable judge reply: %+v", candidates[2])
+	}
+	if !strings.Contains(judge.GetRequestHistory()[0].Messages[0].Content, "assistant: How can I help?") {
+		t.Error("ranking prompt does not include the conversation")
+	}
+
+	if err := RankCandidates(context.Background(), judge, RankGoal, "", history, candidates); err == nil {
+		t.Error("expected error for goal ranking without a goal")
+	}
+	if err := RankCandidates(context.Background(), judge, "loudest", "", history, candidates); err == nil {
+		t.Error("expected error for unknown rank")
+	}
+}
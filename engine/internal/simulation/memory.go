@@ -0,0 +1,119 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+)
+
+// defaultSummaryMaxTokens bounds a rolling summary when
+// ContextWindow.SummaryMaxTokens is zero.
+const defaultSummaryMaxTokens = 300
+
+const summarizePrompt = `You maintain a running summary of a conversation between a user and an AI agent.
+Merge the existing summary with the new messages into one updated summary.
+Keep facts, requests, commitments, and unresolved issues; drop pleasantries.
+Write plain prose, no preamble.`
+
+// ContextWindow bounds the conversation history a SimulatedUser sends to its
+// provider. A turn is one user message and one agent response.
+type ContextWindow struct {
+	// KeepTurns is the number of most recent turns sent verbatim. Zero keeps
+	// the full history.
+	KeepTurns int
+	// Summarize replaces the dropped turns with a rolling summary generated
+	// by the provider instead of discarding them.
+	Summarize        bool
+	SummaryMaxTokens int
+}
+
+// rollingSummary is the summary of the history prefix a SimulatedUser has
+// dropped. It is shared by copies of the user.
+type rollingSummary struct {
+	mu      sync.Mutex
+	text    string
+	covered int // history messages folded into text
+	cost    float64
+}
+
+// SetContextWindow bounds the history sent on each generation. Summaries roll
+// forward across calls, so a user with Summarize set should serve a single
+// conversation; a history shorter than the summarized prefix resets it.
+func (u *SimulatedUser) SetContextWindow(w ContextWindow) {
+	u.window = w
+	u.memory = &rollingSummary{}
+}
+
+// SummaryCost returns the total cost in USD of the summarization calls made
+// so far.
+func (u *SimulatedUser) SummaryCost() float64 {
+	if u.memory == nil {
+		return 0
+	}
+	u.memory.mu.Lock()
+	defer u.memory.mu.Unlock()
+	return u.memory.cost
+}
+
+// windowHistory returns the messages and system prompt to send for history
+// under the context window, and the cost of any summarization it performed.
+func (u *SimulatedUser) windowHistory(ctx context.Context, history []llm.Message) ([]llm.Message, string, float64, error) {
+	keep := 2 * u.window.KeepTurns
+	if keep <= 0 || len(history) <= keep {
+		return history, u.persona.SystemPrompt, 0, nil
+	}
+	cut := len(history) - keep
+	recent := history[cut:]
+	if !u.window.Summarize {
+		return recent, u.persona.SystemPrompt, 0, nil
+	}
+
+	m := u.memory
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.covered > cut {
+		m.text, m.covered = "", 0
+	}
+	var cost float64
+	if m.covered < cut {
+		summary, c, err := u.summarize(ctx, m.text, history[m.covered:cut])
+		if err != nil {
+			return nil, "", 0, err
+		}
+		m.text, m.covered = summary, cut
+		m.cost += c
+		cost = c
+	}
+	system := u.persona.SystemPrompt + "\n\nSummary of the earlier conversation:\n" + m.text
+	return recent, system, cost, nil
+}
+
+// summarize folds messages into summary with one provider call.
+func (u *SimulatedUser) summarize(ctx context.Context, summary string, messages []llm.Message) (string, float64, error) {
+	var b strings.Builder
+	if summary != "" {
+		fmt.Fprintf(&b, "Existing summary:\n%s\n\n", summary)
+	}
+	b.WriteString("New messages:\n")
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "%s: %s\n", msg.Role, msg.Content)
+	}
+	maxTokens := u.window.SummaryMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultSummaryMaxTokens
+	}
+	resp, err := u.provider.Complete(ctx, &llm.CompletionRequest{
+		Model:        u.provider.DefaultModel(),
+		SystemPrompt: summarizePrompt,
+		Messages:     []llm.Message{{Role: "user", Content: b.String()}},
+		MaxTokens:    maxTokens,
+		Seed:         u.seed,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("simulated user %q: summarize history: %w", u.persona.Name, err)
+	}
+	return strings.TrimSpace(resp.Content), resp.Cost, nil
+}
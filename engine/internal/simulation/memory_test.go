@@ -0,0 +1,122 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/internal/llm"
+)
+
+// summaryMock answers summarization calls with a numbered summary costing
+// 0.002 and every other call with a user message costing 0.001.
+func summaryMock() *llm.MockProvider {
+	mock := llm.NewMockProvider(nil, nil)
+	summaries := 0
+	mock.MatchFunc = func(req *llm.CompletionRequest) *llm.CompletionResponse {
+		if req.SystemPrompt == summarizePrompt {
+			summaries++
+			return &llm.CompletionResponse{Content: fmt.Sprintf("summary %d", summaries), Cost: 0.002}
+		}
+		return &llm.CompletionResponse{Content: "next", Cost: 0.001}
+	}
+	return mock
+}
+
+func conversation(turns int) []llm.Message {
+	var history []llm.Message
+	for i := 1; i <= turns; i++ {
+		history = append(history,
+			llm.Message{Role: "user", Content: fmt.Sprintf("user %d", i)},
+			llm.Message{Role: "assistant", Content: fmt.Sprintf("agent %d", i)},
+		)
+	}
+	return history
+}
+
+func TestContextWindow_Truncates(t *testing.T) {
+	mock := summaryMock()
+	user := NewSimulatedUser(FriendlyUser, mock)
+	user.SetContextWindow(ContextWindow{KeepTurns: 1})
+
+	if _, err := user.GenerateMessage(context.Background(), conversation(3)); err != nil {
+		t.Fatalf("GenerateMessage: %v", err)
+	}
+	reqs := mock.GetRequestHistory()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1 (no summarization)", len(reqs))
+	}
+	if len(reqs[0].Messages) != 2 || reqs[0].Messages[0].Content != "user 3" {
+		t.Errorf("messages = %+v, want the last turn", reqs[0].Messages)
+	}
+	if reqs[0].SystemPrompt != FriendlyUser.SystemPrompt {
+		t.Errorf("system prompt changed without summarization")
+	}
+}
+
+func TestContextWindow_RollingSummary(t *testing.T) {
+	mock := summaryMock()
+	user := NewSimulatedUser(FriendlyUser, mock)
+	user.SetContextWindow(ContextWindow{KeepTurns: 1, Summarize: true})
+	ctx := context.Background()
+
+	// Short history: nothing to summarize.
+	if _, cost, err := user.generate(ctx, conversation(1)); err != nil || cost != 0.001 {
+		t.Fatalf("generate = cost %v, err %v", cost, err)
+	}
+
+	// Turns 1-2 are folded into the summary.
+	_, cost, err := user.generate(ctx, conversation(3))
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if cost != 0.003 {
+		t.Errorf("cost = %v, want 0.003 (summary + message)", cost)
+	}
+	reqs := mock.GetRequestHistory()
+	summarizeReq, userReq := reqs[1], reqs[2]
+	if got := summarizeReq.Messages[0].Content; !strings.Contains(got, "agent 2") || strings.Contains(got, "user 3") {
+		t.Errorf("summarized %q, want turns 1-2 only", got)
+	}
+	if !strings.HasSuffix(userReq.SystemPrompt, "summary 1") || len(userReq.Messages) != 2 {
+		t.Errorf("user request = %q with %d messages", userReq.SystemPrompt, len(userReq.Messages))
+	}
+
+	// The summary rolls forward: only turn 3 is new.
+	if _, _, err := user.generate(ctx, conversation(4)); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	reqs = mock.GetRequestHistory()
+	got := reqs[3].Messages[0].Content
+	if !strings.Contains(got, "Existing summary:\nsummary 1") || strings.Contains(got, "user 2") || !strings.Contains(got, "user 3") {
+		t.Errorf("second summarization input = %q", got)
+	}
+	if !strings.HasSuffix(reqs[4].SystemPrompt, "summary 2") {
+		t.Errorf("system prompt = %q, want updated summary", reqs[4].SystemPrompt)
+	}
+	if got := user.SummaryCost(); got != 0.004 {
+		t.Errorf("SummaryCost = %v, want 0.004", got)
+	}
+}
+
+func TestOrchestrator_ContextWindowCost(t *testing.T) {
+	cfg := SimulationConfig{
+		Persona:       FriendlyUser,
+		MaxTurns:      4,
+		Provider:      summaryMock(),
+		ContextWindow: ContextWindow{KeepTurns: 2, Summarize: true},
+	}
+	result, err := NewOrchestrator(cfg).RunSimulation(context.Background(), "hello", echoAgent)
+	if err != nil {
+		t.Fatalf("RunSimulation: %v", err)
+	}
+	// Generations after turns 1-3 see 1, 2, 3 turns; only the last exceeds
+	// the 2-turn window and triggers one summary.
+	if result.SummaryCost != 0.002 {
+		t.Errorf("SummaryCost = %v, want 0.002", result.SummaryCost)
+	}
+	if want := 0.002 + 3*0.001; result.Cost < want-1e-9 || result.Cost > want+1e-9 {
+		t.Errorf("Cost = %v, want %v", result.Cost, want)
+	}
+}
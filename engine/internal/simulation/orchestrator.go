@@ -2,10 +2,13 @@ package simulation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
 // StopCondition determines whether a simulation should terminate.
@@ -54,13 +57,25 @@ type SimulationConfig struct {
 	MaxTurns       int
 	StopConditions []StopCondition
 	Provider       llm.Provider
+	Seed           *int64 // Optional deterministic seed for simulated user sampling
+	// AgentTimeout bounds each agent call; zero means no limit beyond ctx.
+	AgentTimeout time.Duration
+	// ContextWindow bounds the history the simulated user sees.
+	ContextWindow ContextWindow
+	// TurnCheck, when set, evaluates the transcript after each turn. A
+	// hard_fail result ends the simulation with StoppedBy
+	// "assertion_failed:<assertion_id>".
+	TurnCheck func(ctx context.Context, turn int, transcript []types.Message) ([]types.AssertionResult, error)
 }
 
 // Turn represents one exchange in a simulation.
 type Turn struct {
 	TurnNumber    int
 	UserMessage   string
+	UserActions   []types.UserAction
 	AgentResponse string
+	// Assertions holds the TurnCheck results for this turn.
+	Assertions []types.AssertionResult
 }
 
 // SimulationResult holds the complete record of a finished simulation.
@@ -68,6 +83,25 @@ type SimulationResult struct {
 	Turns      []Turn
 	TotalTurns int
 	StoppedBy  string
+	// Cost is the simulated user's LLM cost in USD, SummaryCost included.
+	Cost float64
+	// SummaryCost is the part of Cost spent summarizing history.
+	SummaryCost float64
+	// AssertionCost is the cost in USD of TurnCheck evaluations.
+	AssertionCost float64
+}
+
+// Transcript returns the simulation as a trace transcript: each turn's user
+// message followed by the agent's response.
+func (r *SimulationResult) Transcript() []types.Message {
+	transcript := make([]types.Message, 0, 2*len(r.Turns))
+	for _, t := range r.Turns {
+		transcript = append(transcript,
+			types.Message{Role: types.RoleUser, Content: t.UserMessage, Actions: t.UserActions},
+			types.Message{Role: types.RoleAssistant, Content: t.AgentResponse},
+		)
+	}
+	return transcript
 }
 
 // Orchestrator runs a multi-turn simulation between a SimulatedUser and an agent callback.
@@ -78,9 +112,16 @@ type Orchestrator struct {
 
 // NewOrchestrator creates an Orchestrator from the given config.
 func NewOrchestrator(config SimulationConfig) *Orchestrator {
+	user := NewSimulatedUser(config.Persona, config.Provider)
+	if config.Seed != nil {
+		user = NewSimulatedUserWithSeed(config.Persona, config.Provider, *config.Seed)
+	}
+	if config.ContextWindow != (ContextWindow{}) {
+		user.SetContextWindow(config.ContextWindow)
+	}
 	return &Orchestrator{
 		config: config,
-		user:   NewSimulatedUser(config.Persona, config.Provider),
+		user:   user,
 	}
 }
 
@@ -89,41 +130,69 @@ func NewOrchestrator(config SimulationConfig) *Orchestrator {
 // Turn 1: initialPrompt → agentFn → agentResponse
 // Turn N: SimulatedUser.GenerateMessage(history) → userMessage → agentFn → agentResponse
 //
-// Stops when MaxTurns is reached or any StopCondition fires.
+// Stops when MaxTurns is reached or any StopCondition fires. Actions the
+// persona takes are recorded in the result but not passed to agentFn; use
+// RunSimulationEvents for agents that handle them.
 func (o *Orchestrator) RunSimulation(
 	ctx context.Context,
 	initialPrompt string,
 	agentFn func(ctx context.Context, userMessage string) (string, error),
+) (*SimulationResult, error) {
+	return o.RunSimulationEvents(ctx, UserEvent{Message: initialPrompt}, func(ctx context.Context, event UserEvent) (string, error) {
+		return agentFn(ctx, event.Message)
+	})
+}
+
+// RunSimulationEvents is RunSimulation with typed user turns: agentFn
+// receives each turn's message together with the structured actions the
+// simulated user took.
+func (o *Orchestrator) RunSimulationEvents(
+	ctx context.Context,
+	initial UserEvent,
+	agentFn func(ctx context.Context, event UserEvent) (string, error),
 ) (*SimulationResult, error) {
 	result := &SimulationResult{}
 
 	// conversationHistory tracks the full exchange for the SimulatedUser's context.
 	var conversationHistory []llm.Message
 
-	currentUserMessage := initialPrompt
+	current := initial
 	maxTurnsCondition := MaxTurnsCondition{MaxTurns: o.config.MaxTurns}
 
 	for turn := 1; ; turn++ {
-		// Call the agent with the current user message.
-		agentResponse, err := agentFn(ctx, currentUserMessage)
+		// Call the agent with the current user turn.
+		agentResponse, err := o.callAgent(ctx, agentFn, current)
 		if err != nil {
 			return nil, fmt.Errorf("simulation turn %d: agent error: %w", turn, err)
 		}
 
 		result.Turns = append(result.Turns, Turn{
 			TurnNumber:    turn,
-			UserMessage:   currentUserMessage,
+			UserMessage:   current.Message,
+			UserActions:   current.Actions,
 			AgentResponse: agentResponse,
 		})
 		result.TotalTurns = turn
 
 		// Update conversation history with this exchange.
 		conversationHistory = append(conversationHistory,
-			llm.Message{Role: "user", Content: currentUserMessage},
+			llm.Message{Role: "user", Content: current.historyContent()},
 			llm.Message{Role: "assistant", Content: agentResponse},
 		)
 
-		// Check max turns first.
+		// Per-turn assertions abort a run that has gone off the rails.
+		if o.config.TurnCheck != nil {
+			failed, err := o.checkTurn(ctx, result)
+			if err != nil {
+				return nil, fmt.Errorf("simulation turn %d: assertion error: %w", turn, err)
+			}
+			if failed != "" {
+				result.StoppedBy = "assertion_failed:" + failed
+				break
+			}
+		}
+
+		// Check max turns next.
 		if maxTurnsCondition.ShouldStop(turn, agentResponse) {
 			result.StoppedBy = "max_turns"
 			break
@@ -148,13 +217,52 @@ func (o *Orchestrator) RunSimulation(
 			break
 		}
 
-		// Generate the next user message.
-		nextUserMessage, err := o.user.GenerateMessage(ctx, conversationHistory)
+		// Generate the next user turn.
+		next, cost, err := o.user.generateEvent(ctx, conversationHistory)
 		if err != nil {
 			return nil, fmt.Errorf("simulation turn %d: user generation error: %w", turn, err)
 		}
-		currentUserMessage = nextUserMessage
+		result.Cost += cost
+		current = next
 	}
 
+	result.SummaryCost = o.user.SummaryCost()
 	return result, nil
 }
+
+// checkTurn runs TurnCheck on the transcript so far, records the results on
+// the last turn, and returns the ID of the first hard failure.
+func (o *Orchestrator) checkTurn(ctx context.Context, result *SimulationResult) (string, error) {
+	last := &result.Turns[len(result.Turns)-1]
+	results, err := o.config.TurnCheck(ctx, last.TurnNumber, result.Transcript())
+	if err != nil {
+		return "", err
+	}
+	last.Assertions = results
+	failed := ""
+	for _, r := range results {
+		result.AssertionCost += r.Cost
+		if failed == "" && r.Status == types.StatusHardFail {
+			failed = r.AssertionID
+		}
+	}
+	return failed, nil
+}
+
+// callAgent calls agentFn under the configured per-call timeout.
+func (o *Orchestrator) callAgent(
+	ctx context.Context,
+	agentFn func(ctx context.Context, event UserEvent) (string, error),
+	event UserEvent,
+) (string, error) {
+	if o.config.AgentTimeout <= 0 {
+		return agentFn(ctx, event)
+	}
+	agentCtx, cancel := context.WithTimeout(ctx, o.config.AgentTimeout)
+	defer cancel()
+	response, err := agentFn(agentCtx, event)
+	if err != nil && ctx.Err() == nil && errors.Is(agentCtx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("no response within %s: %w", o.config.AgentTimeout, context.DeadlineExceeded)
+	}
+	return response, err
+}
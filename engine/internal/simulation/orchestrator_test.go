@@ -2,11 +2,14 @@ package simulation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/attest-ai/attest/engine/internal/llm"
+	"github.com/attest-ai/attest/engine/pkg/types"
 )
 
 // echoAgent returns the user message prefixed with "echo: ".
@@ -175,3 +178,100 @@ func TestOrchestratorAgentError(t *testing.T) {
 		t.Fatal("expected error from failing agent, got nil")
 	}
 }
+
+func TestSimulationResultTranscript(t *testing.T) {
+	orch := NewOrchestrator(SimulationConfig{Persona: FriendlyUser, MaxTurns: 2, Provider: newUserMock([]string{"follow up"})})
+	result, err := orch.RunSimulation(context.Background(), "initial", echoAgent)
+	if err != nil {
+		t.Fatalf("RunSimulation error: %v", err)
+	}
+	got := result.Transcript()
+	want := []string{"user:initial", "assistant:echo: initial", "user:follow up", "assistant:echo: follow up"}
+	if len(got) != len(want) {
+		t.Fatalf("transcript = %+v", got)
+	}
+	for i, m := range got {
+		if m.Role+":"+m.Content != want[i] {
+			t.Errorf("transcript[%d] = %s:%s, want %s", i, m.Role, m.Content, want[i])
+		}
+	}
+}
+
+func TestOrchestratorAgentTimeout(t *testing.T) {
+	cfg := SimulationConfig{
+		Persona:      FriendlyUser,
+		MaxTurns:     2,
+		Provider:     newUserMock([]string{"follow-up"}),
+		AgentTimeout: 10 * time.Millisecond,
+	}
+	slowAgent := func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	_, err := NewOrchestrator(cfg).RunSimulation(context.Background(), "hello", slowAgent)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want DeadlineExceeded", err)
+	}
+	if !strings.Contains(err.Error(), "turn 1") {
+		t.Errorf("error = %q, want turn number", err)
+	}
+}
+
+func TestOrchestratorTurnCheckAbort(t *testing.T) {
+	var checkedTurns []int
+	cfg := SimulationConfig{
+		Persona:  FriendlyUser,
+		MaxTurns: 5,
+		Provider: newUserMock([]string{"next"}),
+		TurnCheck: func(_ context.Context, turn int, transcript []types.Message) ([]types.AssertionResult, error) {
+			checkedTurns = append(checkedTurns, turn)
+			if len(transcript) != 2*turn {
+				t.Errorf("turn %d: transcript has %d messages, want %d", turn, len(transcript), 2*turn)
+			}
+			status := types.StatusPass
+			if turn == 2 {
+				status = types.StatusHardFail
+			}
+			return []types.AssertionResult{
+				{AssertionID: "polite", Status: types.StatusSoftFail, Cost: 0.01},
+				{AssertionID: "on_topic", Status: status, Cost: 0.01},
+			}, nil
+		},
+	}
+
+	result, err := NewOrchestrator(cfg).RunSimulation(context.Background(), "hello", echoAgent)
+	if err != nil {
+		t.Fatalf("RunSimulation error: %v", err)
+	}
+	if result.StoppedBy != "assertion_failed:on_topic" {
+		t.Errorf("StoppedBy = %q, want assertion_failed:on_topic", result.StoppedBy)
+	}
+	if result.TotalTurns != 2 || len(checkedTurns) != 2 {
+		t.Errorf("TotalTurns = %d, checked %v; want 2 turns", result.TotalTurns, checkedTurns)
+	}
+	if len(result.Turns[1].Assertions) != 2 {
+		t.Errorf("turn 2 assertions = %+v", result.Turns[1].Assertions)
+	}
+	if got := result.AssertionCost; got < 0.04-1e-9 || got > 0.04+1e-9 {
+		t.Errorf("AssertionCost = %v, want 0.04", got)
+	}
+}
+
+func TestOrchestratorTurnCheckError(t *testing.T) {
+	cfg := SimulationConfig{
+		Persona:  FriendlyUser,
+		MaxTurns: 3,
+		Provider: newUserMock([]string{"next"}),
+		TurnCheck: func(context.Context, int, []types.Message) ([]types.AssertionResult, error) {
+			return nil, errors.New("pipeline down")
+		},
+	}
+	_, err := NewOrchestrator(cfg).RunSimulation(context.Background(), "hello", echoAgent)
+	if err == nil || !strings.Contains(err.Error(), "assertion error: pipeline down") {
+		t.Errorf("error = %v, want assertion error", err)
+	}
+}
@@ -14,6 +14,10 @@ type Persona struct {
 	Style        string
 	Temperature  float64
 	MaxTokens    int
+	// Actions lists the structured action types (types.ActionAttachFile,
+	// ActionFillForm, ActionSelectOption) the user may take besides typing.
+	// When set, the user replies in JSON; see GenerateEvent.
+	Actions []string
 }
 
 // Built-in personas for common simulation scenarios.
@@ -50,10 +54,14 @@ You may misunderstand the assistant's responses. Keep responses concise (1-3 sen
 )
 
 // SimulatedUser uses an LLM provider to generate user messages in a conversation.
-// It is stateless — all conversation state is passed via conversationHistory.
+// It is stateless — all conversation state is passed via conversationHistory —
+// except for the rolling summary kept under a summarizing ContextWindow.
 type SimulatedUser struct {
 	persona  Persona
 	provider llm.Provider
+	seed     *int64
+	window   ContextWindow
+	memory   *rollingSummary
 }
 
 // NewSimulatedUser creates a SimulatedUser with the given persona and provider.
@@ -64,24 +72,49 @@ func NewSimulatedUser(persona Persona, provider llm.Provider) *SimulatedUser {
 	}
 }
 
+// NewSimulatedUserWithSeed creates a SimulatedUser whose completion requests carry
+// a deterministic sampling seed.
+func NewSimulatedUserWithSeed(persona Persona, provider llm.Provider, seed int64) *SimulatedUser {
+	return &SimulatedUser{
+		persona:  persona,
+		provider: provider,
+		seed:     &seed,
+	}
+}
+
 // GenerateMessage produces the next user message given the current conversation history.
 // It constructs a CompletionRequest using the persona's system prompt and parameters,
 // appends conversationHistory as the messages, and calls the provider.
 func (u *SimulatedUser) GenerateMessage(ctx context.Context, conversationHistory []llm.Message) (string, error) {
+	msg, _, err := u.generate(ctx, conversationHistory)
+	return msg, err
+}
+
+// generate is GenerateMessage that also returns the cost in USD, including
+// any history summarization.
+func (u *SimulatedUser) generate(ctx context.Context, conversationHistory []llm.Message) (string, float64, error) {
 	model := u.provider.DefaultModel()
 
+	messages, systemPrompt, summaryCost, err := u.windowHistory(ctx, conversationHistory)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(u.persona.Actions) > 0 {
+		systemPrompt += actionInstructions(u.persona.Actions)
+	}
 	req := &llm.CompletionRequest{
 		Model:        model,
-		SystemPrompt: u.persona.SystemPrompt,
-		Messages:     conversationHistory,
+		SystemPrompt: systemPrompt,
+		Messages:     messages,
 		Temperature:  u.persona.Temperature,
 		MaxTokens:    u.persona.MaxTokens,
+		Seed:         u.seed,
 	}
 
 	resp, err := u.provider.Complete(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("simulated user %q: %w", u.persona.Name, err)
+		return "", summaryCost, fmt.Errorf("simulated user %q: %w", u.persona.Name, err)
 	}
 
-	return resp.Content, nil
+	return resp.Content, summaryCost + resp.Cost, nil
 }
@@ -85,3 +85,17 @@ func TestSimulatedUserWithPersonas(t *testing.T) {
 		})
 	}
 }
+
+func TestSimulatedUserWithSeed(t *testing.T) {
+	mock := llm.NewMockProvider(nil, nil)
+	user := NewSimulatedUserWithSeed(FriendlyUser, mock, 7)
+
+	if _, err := user.GenerateMessage(context.Background(), nil); err != nil {
+		t.Fatalf("GenerateMessage returned error: %v", err)
+	}
+
+	req := mock.GetRequestHistory()[0]
+	if req.Seed == nil || *req.Seed != 7 {
+		t.Fatalf("expected seed 7 on request, got %v", req.Seed)
+	}
+}
@@ -0,0 +1,175 @@
+// Package timing collects the per-batch time breakdown reported in an
+// evaluate_batch result: time per layer and evaluator type, cache access,
+// rate-limiter waits, and provider round trips.
+package timing
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Recorder accumulates timings for one batch. It is safe for concurrent use,
+// and every method is a no-op on a nil Recorder so call sites need not check.
+type Recorder struct {
+	mu         sync.Mutex
+	layers     map[int]time.Duration
+	evaluators map[string]time.Duration
+	cache      time.Duration
+	lookups    map[string]types.CacheCounts
+	wait       time.Duration
+	providers  map[string]*providerStats
+}
+
+type providerStats struct {
+	calls int
+	total time.Duration
+	max   time.Duration
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		layers:     make(map[int]time.Duration),
+		evaluators: make(map[string]time.Duration),
+		lookups:    make(map[string]types.CacheCounts),
+		providers:  make(map[string]*providerStats),
+	}
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns a copy of ctx carrying r. A nil r leaves ctx unchanged.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the Recorder carried by ctx, or nil.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Evaluation records d spent evaluating one assertion of assertionType in layer.
+func (r *Recorder) Evaluation(layer int, assertionType string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.layers[layer] += d
+	r.evaluators[assertionType] += d
+	r.mu.Unlock()
+}
+
+// Cache records d spent reading or writing the embedding or judge cache.
+func (r *Recorder) Cache(d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.cache += d
+	r.mu.Unlock()
+}
+
+// CacheLookup counts one read of the named cache ("embedding" or "judge").
+func (r *Recorder) CacheLookup(name string, hit bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	c := r.lookups[name]
+	if hit {
+		c.Hits++
+	} else {
+		c.Misses++
+	}
+	r.lookups[name] = c
+	r.mu.Unlock()
+}
+
+// CacheLookups returns the cache reads counted so far by cache name, or nil
+// when there were none.
+func (r *Recorder) CacheLookups() map[string]types.CacheCounts {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.lookups) == 0 {
+		return nil
+	}
+	out := make(map[string]types.CacheCounts, len(r.lookups))
+	for name, c := range r.lookups {
+		out[name] = c
+	}
+	return out
+}
+
+// RateLimitWait records d spent waiting on the rate limiter or retry backoff.
+func (r *Recorder) RateLimitWait(d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.wait += d
+	r.mu.Unlock()
+}
+
+// ProviderCall records one round trip of d to provider, failed or not.
+func (r *Recorder) ProviderCall(provider string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	s := r.providers[provider]
+	if s == nil {
+		s = &providerStats{}
+		r.providers[provider] = s
+	}
+	s.calls++
+	s.total += d
+	if d > s.max {
+		s.max = d
+	}
+	r.mu.Unlock()
+}
+
+// Summary returns the recorded timings with total as the batch wall time.
+func (r *Recorder) Summary(total time.Duration) *types.BatchTimings {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := &types.BatchTimings{
+		TotalMS:         ms(total),
+		Layers:          make(map[string]float64, len(r.layers)),
+		Evaluators:      make(map[string]float64, len(r.evaluators)),
+		CacheMS:         ms(r.cache),
+		RateLimitWaitMS: ms(r.wait),
+	}
+	for layer, d := range r.layers {
+		t.Layers[strconv.Itoa(layer)] = ms(d)
+	}
+	for typ, d := range r.evaluators {
+		t.Evaluators[typ] = ms(d)
+	}
+	if len(r.providers) > 0 {
+		t.Providers = make(map[string]types.ProviderTiming, len(r.providers))
+		for name, s := range r.providers {
+			t.Providers[name] = types.ProviderTiming{Calls: s.calls, TotalMS: ms(s.total), MaxMS: ms(s.max)}
+		}
+	}
+	return t
+}
+
+// ms converts d to fractional milliseconds rounded to microseconds.
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,77 @@
+package timing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecorder_Summary(t *testing.T) {
+	r := NewRecorder()
+	r.Evaluation(4, "content", 2*time.Millisecond)
+	r.Evaluation(4, "content", time.Millisecond)
+	r.Evaluation(6, "llm_judge", 500*time.Millisecond)
+	r.Cache(1500 * time.Microsecond)
+	r.RateLimitWait(20 * time.Millisecond)
+	r.ProviderCall("openai", 300*time.Millisecond)
+	r.ProviderCall("openai", 100*time.Millisecond)
+
+	s := r.Summary(510 * time.Millisecond)
+	if s.TotalMS != 510 {
+		t.Errorf("TotalMS = %v, want 510", s.TotalMS)
+	}
+	if s.Layers["4"] != 3 || s.Layers["6"] != 500 {
+		t.Errorf("Layers = %v", s.Layers)
+	}
+	if s.Evaluators["content"] != 3 {
+		t.Errorf("Evaluators = %v", s.Evaluators)
+	}
+	if s.CacheMS != 1.5 || s.RateLimitWaitMS != 20 {
+		t.Errorf("CacheMS = %v, RateLimitWaitMS = %v", s.CacheMS, s.RateLimitWaitMS)
+	}
+	p := s.Providers["openai"]
+	if p.Calls != 2 || p.TotalMS != 400 || p.MaxMS != 300 {
+		t.Errorf("Providers[openai] = %+v", p)
+	}
+}
+
+func TestRecorder_NilAndContext(t *testing.T) {
+	var r *Recorder
+	r.Evaluation(1, "schema", time.Millisecond)
+	r.ProviderCall("openai", time.Millisecond)
+	if r.Summary(time.Second) != nil {
+		t.Error("nil Recorder Summary should be nil")
+	}
+
+	ctx := context.Background()
+	if FromContext(ctx) != nil {
+		t.Error("FromContext on a bare context should be nil")
+	}
+	if WithRecorder(ctx, nil) != ctx {
+		t.Error("WithRecorder(nil) should return ctx unchanged")
+	}
+	rec := NewRecorder()
+	if FromContext(WithRecorder(ctx, rec)) != rec {
+		t.Error("FromContext did not return the attached Recorder")
+	}
+}
+
+func TestRecorder_CacheLookups(t *testing.T) {
+	r := NewRecorder()
+	if got := r.CacheLookups(); got != nil {
+		t.Errorf("CacheLookups before any lookup = %v, want nil", got)
+	}
+	r.CacheLookup("judge", true)
+	r.CacheLookup("judge", false)
+	r.CacheLookup("embedding", true)
+	got := r.CacheLookups()
+	if got["judge"].Hits != 1 || got["judge"].Misses != 1 || got["embedding"].Hits != 1 {
+		t.Errorf("CacheLookups = %+v", got)
+	}
+
+	var nilRec *Recorder
+	nilRec.CacheLookup("judge", true)
+	if nilRec.CacheLookups() != nil {
+		t.Error("nil Recorder returned lookups")
+	}
+}
@@ -0,0 +1,156 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+	"github.com/segmentio/encoding/json"
+)
+
+// WarnUnknownCurrency is reported by CurrencyRates.Convert for a cost whose
+// currency has no rate; the cost is left out of USD totals.
+const WarnUnknownCurrency = "unknown_currency"
+
+// USD is the currency costs are reported and aggregated in.
+const USD = "USD"
+
+// CurrencyRates maps a currency or internal cost unit, upper-cased, to the
+// USD value of one unit. USD is always 1.
+type CurrencyRates map[string]float64
+
+// DefaultCurrencyRates converts nothing but USD: exchange rates change, so
+// any other currency must be configured.
+var DefaultCurrencyRates = CurrencyRates{USD: 1}
+
+// ParseCurrencyRates parses a comma-separated list of CODE=RATE pairs, such
+// as "EUR=1.08,GBP=1.27,CREDIT=0.002", where RATE is the USD value of one
+// unit. The result always includes USD.
+func ParseCurrencyRates(s string) (CurrencyRates, error) {
+	rates := CurrencyRates{USD: 1}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		code, value, ok := strings.Cut(pair, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok || code == "" {
+			return nil, fmt.Errorf("currency rate %q is not CODE=RATE", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(rate) || rate <= 0 || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("currency rate for %s must be a positive number, got %q", code, value)
+		}
+		if code == USD && rate != 1 {
+			return nil, fmt.Errorf("the USD rate is fixed at 1")
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
+
+// Codes returns the configured currency codes in sorted order.
+func (r CurrencyRates) Codes() []string {
+	codes := make([]string, 0, len(r))
+	for code := range r {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// rate returns the USD value of one unit of currency; an empty currency is
+// USD.
+func (r CurrencyRates) rate(currency string) (float64, bool) {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if code == "" || code == USD {
+		return 1, true
+	}
+	rate, ok := r[code]
+	return rate, ok
+}
+
+// Convert fills the USD costs of the trace tree from costs in other
+// currencies, so budgets and aggregates compare like with like.
+//   - trace metadata: cost in currency sets cost_usd when cost_usd is unset.
+//   - step metadata: a cost with a currency key sets cost_usd when the step
+//     has no cost_usd key; the original cost is kept.
+//
+// A currency without a rate leaves cost_usd unset and is reported with a
+// WarnUnknownCurrency warning.
+func (r CurrencyRates) Convert(t *types.Trace) []types.TraceWarning {
+	var warnings []types.TraceWarning
+	unknown := func(traceID, step string, index *int, currency string) {
+		warnings = append(warnings, types.TraceWarning{
+			Code:      WarnUnknownCurrency,
+			TraceID:   traceID,
+			Step:      step,
+			StepIndex: index,
+			Message: fmt.Sprintf("no exchange rate for currency %q; its cost is excluded from USD totals (configured: %s)",
+				currency, strings.Join(r.Codes(), ", ")),
+		})
+	}
+	WalkTree(t, func(node *types.Trace, _ int) bool {
+		if m := node.Metadata; m != nil && m.Cost != nil && m.CostUSD == nil {
+			currency := ""
+			if m.Currency != nil {
+				currency = *m.Currency
+			}
+			if rate, ok := r.rate(currency); ok {
+				usd := *m.Cost * rate
+				m.CostUSD = &usd
+			} else {
+				unknown(node.TraceID, "", nil, currency)
+			}
+		}
+		for i := range node.Steps {
+			step := &node.Steps[i]
+			currency, converted, ok := r.convertStepCost(step.Metadata)
+			switch {
+			case !ok:
+				unknown(node.TraceID, step.Name, &i, currency)
+			case converted != nil:
+				step.Metadata = converted
+			}
+		}
+		return true
+	})
+	return warnings
+}
+
+// convertStepCost returns step metadata with cost_usd set from cost and
+// currency, or nil when there is nothing to convert. ok is false when the
+// currency has no rate.
+func (r CurrencyRates) convertStepCost(raw json.RawMessage) (currency string, converted json.RawMessage, ok bool) {
+	if len(raw) == 0 || raw[0] != '{' {
+		return "", nil, true
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) != nil {
+		return "", nil, true
+	}
+	if _, has := obj["cost_usd"]; has {
+		return "", nil, true
+	}
+	var cost float64
+	if c, has := obj["cost"]; !has || json.Unmarshal(c, &cost) != nil {
+		return "", nil, true
+	}
+	if c, has := obj["currency"]; !has || json.Unmarshal(c, &currency) != nil {
+		return "", nil, true
+	}
+	rate, ok := r.rate(currency)
+	if !ok {
+		return currency, nil, false
+	}
+	obj["cost_usd"], _ = json.Marshal(cost * rate)
+	converted, err := json.Marshal(obj)
+	if err != nil {
+		return currency, nil, true
+	}
+	return currency, converted, true
+}
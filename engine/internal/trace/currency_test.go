@@ -0,0 +1,71 @@
+package trace
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestParseCurrencyRates(t *testing.T) {
+	rates, err := ParseCurrencyRates(" eur=1.5, credit=0.002 ,")
+	if err != nil {
+		t.Fatalf("ParseCurrencyRates: %v", err)
+	}
+	if rates["EUR"] != 1.5 || rates["CREDIT"] != 0.002 || rates[USD] != 1 {
+		t.Errorf("rates = %v", rates)
+	}
+	for _, bad := range []string{"EUR", "EUR=0", "EUR=-1", "=2", "EUR=abc", "EUR=NaN", "EUR=Inf", "USD=2"} {
+		if _, err := ParseCurrencyRates(bad); err == nil {
+			t.Errorf("ParseCurrencyRates(%q): want error", bad)
+		}
+	}
+}
+
+func TestCurrencyRates_Convert(t *testing.T) {
+	rates := CurrencyRates{USD: 1, "EUR": 1.5}
+	cost, eur, yen, usd := 2.0, "eur", "JPY", 0.25
+	child := &types.Trace{TraceID: "child", Metadata: &types.TraceMetadata{Cost: &cost, Currency: &yen}}
+	tr := &types.Trace{
+		TraceID:  "root",
+		Metadata: &types.TraceMetadata{Cost: &cost, Currency: &eur},
+		Steps: []types.Step{
+			{Type: types.StepTypeLLMCall, Name: "eur", Metadata: json.RawMessage(`{"cost":4,"currency":"EUR"}`)},
+			{Type: types.StepTypeLLMCall, Name: "usd", Metadata: json.RawMessage(`{"cost_usd":1,"cost":4,"currency":"EUR"}`)},
+			{Type: types.StepTypeLLMCall, Name: "yen", Metadata: json.RawMessage(`{"cost":100,"currency":"JPY"}`)},
+			{Type: types.StepTypeAgentCall, Name: "child", SubTrace: child},
+		},
+	}
+
+	warnings := rates.Convert(tr)
+
+	if tr.Metadata.CostUSD == nil || *tr.Metadata.CostUSD != 3 {
+		t.Errorf("root cost_usd = %v, want 3", tr.Metadata.CostUSD)
+	}
+	wantStep := map[string]float64{"eur": 6, "usd": 1}
+	for i := range tr.Steps {
+		meta, err := tr.Steps[i].TypedMetadata()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, converted := wantStep[tr.Steps[i].Name]
+		got, ok := meta.Numeric("cost_usd")
+		if ok != converted || math.Abs(got-want) > 1e-9 {
+			t.Errorf("step %s cost_usd = %v (set %t), want %v (set %t)", tr.Steps[i].Name, got, ok, want, converted)
+		}
+	}
+	if child.Metadata.CostUSD != nil {
+		t.Errorf("child cost_usd = %v, want unset for unknown currency", *child.Metadata.CostUSD)
+	}
+	if len(warnings) != 2 || warnings[0].Code != WarnUnknownCurrency || warnings[0].Step != "yen" || warnings[1].TraceID != "child" {
+		t.Errorf("warnings = %+v, want unknown_currency for step yen and trace child", warnings)
+	}
+
+	// An explicit cost_usd wins over cost.
+	tr.Metadata.CostUSD = &usd
+	rates.Convert(tr)
+	if *tr.Metadata.CostUSD != usd {
+		t.Errorf("cost_usd overwritten: %v", *tr.Metadata.CostUSD)
+	}
+}
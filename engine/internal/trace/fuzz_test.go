@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+	"github.com/segmentio/encoding/json"
+)
+
+// FuzzScanValidate feeds hostile trace JSON through the scan, decode, and
+// validate path evaluate_batch uses. Run with:
+//
+//	go test ./internal/trace -run '^$' -fuzz FuzzScanValidate
+//
+// Crashers are written to testdata/fuzz/FuzzScanValidate and replay as
+// regular tests; attest-engine --fuzz-replay reproduces them outside go test.
+func FuzzScanValidate(f *testing.F) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "traces", "*.json"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, seed := range []string{
+		``,
+		`{`,
+		`{"trace_id":"t","output":{"m":"x"},"steps":[`,
+		`{"trace_id":"t","output":{"m":"` + "\xe2\x82" + `"},"steps":[]}`,
+		`{"trace_id":"t","steps":[{"type":"agent_call","name":"a","sub_trace":null}]}`,
+		`{"trace_id":"t","steps":[{"type":"agent_call","name":"a","sub_trace":{"steps":[{}]}}]}`,
+		`{"trace_id":"t","steps":[{"type":"llm_call","name":"s","started_at_ms":-1,"ended_at_ms":-9223372036854775808}]}`,
+		`{"trace_id":"t","schema_version":2,"transcript":[{"role":"user"}],"tools":[{}]}`,
+		`{"trace_id":"t","output":` + strings.Repeat("[", 10000) + strings.Repeat("]", 10000) + `}`,
+		`{"trace_id":"t","output":` + strings.Repeat("[", 1<<20) + strings.Repeat("]", 1<<20) + `}`,
+		nestedTrace(MaxSubTraceDepth+3, types.StepTypeAgentCall),
+		`{"trace_id":"t","trace_id":"u","steps":[],"steps":[{"type":"tool_call","name":"x"}]}`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		stats, rpcErr := Scan(raw)
+		if stats != nil && rpcErr != nil {
+			t.Fatalf("Scan returned both stats and error %q", rpcErr.Message)
+		}
+		// The engine never decodes JSON nested past the limit.
+		if ExceedsNesting(raw, MaxJSONDepth) {
+			if rpcErr == nil {
+				t.Fatal("Scan accepted JSON nested past MaxJSONDepth")
+			}
+			return
+		}
+
+		var tr types.Trace
+		if err := json.Unmarshal(raw, &tr); err != nil {
+			return
+		}
+		if stats != nil {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err == nil && compact.Len() != stats.Size {
+				t.Fatalf("Scan size %d, compact size %d", stats.Size, compact.Len())
+			}
+			if len(stats.StepSizes) != len(tr.Steps) {
+				t.Fatalf("Scan measured %d steps, decoded %d", len(stats.StepSizes), len(tr.Steps))
+			}
+		}
+
+		Normalize(&tr)
+		if rpcErr == nil && stats != nil {
+			rpcErr = ValidateScanned(&tr, stats)
+		} else if rpcErr == nil {
+			rpcErr = Validate(&tr, 0)
+		}
+		if rpcErr != nil {
+			return
+		}
+
+		// A trace that passed validation must be safe for every consumer.
+		_ = ValidateTraceTree(&tr)
+		MeasureSize(&tr)
+		Warnings(&tr, stats)
+		TemporalWarnings(&tr)
+		NewTreeIndex(&tr).Depth()
+		AggregateMetadata(&tr)
+	})
+}
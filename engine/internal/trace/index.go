@@ -0,0 +1,50 @@
+package trace
+
+import "github.com/attest-ai/attest/engine/pkg/types"
+
+// TreeIndex answers per-agent lookups on a trace tree from a single walk, so
+// repeated queries cost O(1) instead of O(tree) each. It must not be used
+// after the tree is modified.
+type TreeIndex struct {
+	steps  map[string][]types.Step
+	agents map[string]*types.Trace
+	depth  int
+}
+
+// NewTreeIndex walks root once and indexes its steps by agent_id and its
+// traces by agent_id.
+func NewTreeIndex(root *types.Trace) *TreeIndex {
+	ix := &TreeIndex{
+		steps:  make(map[string][]types.Step),
+		agents: make(map[string]*types.Trace),
+	}
+	WalkTree(root, func(t *types.Trace, depth int) bool {
+		if _, ok := ix.agents[t.AgentID]; !ok {
+			ix.agents[t.AgentID] = t
+		}
+		for _, step := range t.Steps {
+			ix.steps[step.AgentID] = append(ix.steps[step.AgentID], step)
+		}
+		if depth > ix.depth {
+			ix.depth = depth
+		}
+		return true
+	})
+	return ix
+}
+
+// StepsByAgentID returns the steps CollectStepsByAgentID would, in the same
+// order. The returned slice is shared and must not be modified.
+func (ix *TreeIndex) StepsByAgentID(agentID string) []types.Step {
+	return ix.steps[agentID]
+}
+
+// AgentByID returns the trace FindAgentByID would, or nil.
+func (ix *TreeIndex) AgentByID(agentID string) *types.Trace {
+	return ix.agents[agentID]
+}
+
+// Depth returns TreeDepth of the indexed tree.
+func (ix *TreeIndex) Depth() int {
+	return ix.depth
+}
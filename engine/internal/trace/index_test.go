@@ -0,0 +1,75 @@
+package trace
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func indexedTree() *types.Trace {
+	step := func(name, agent string) types.Step {
+		return types.Step{Type: types.StepTypeToolCall, Name: name, AgentID: agent}
+	}
+	search := testTrace("search", step("query", "search"), step("rank", "search"))
+	research := testTrace("research", step("plan", "research"), agentStep("search", search))
+	billing := testTrace("billing", step("charge", "billing"))
+	return testTrace("root", step("route", ""), agentStep("research", research), agentStep("billing", billing), step("reply", "root"))
+}
+
+func TestTreeIndex_MatchesWalks(t *testing.T) {
+	root := indexedTree()
+	ix := NewTreeIndex(root)
+	for _, id := range []string{"", "root", "research", "search", "billing", "missing"} {
+		want := CollectStepsByAgentID(root, id)
+		got := ix.StepsByAgentID(id)
+		if len(want) == 0 && len(got) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("StepsByAgentID(%q) = %v, want %v", id, got, want)
+		}
+		if ix.AgentByID(id) != FindAgentByID(root, id) {
+			t.Errorf("AgentByID(%q) differs from FindAgentByID", id)
+		}
+	}
+	if ix.Depth() != TreeDepth(root) {
+		t.Errorf("Depth() = %d, want %d", ix.Depth(), TreeDepth(root))
+	}
+}
+
+// wideTree builds a tree with agents sub-agents of steps steps each.
+func wideTree(agents, steps int) *types.Trace {
+	root := testTrace("root")
+	for a := 0; a < agents; a++ {
+		id := fmt.Sprintf("agent_%d", a)
+		sub := testTrace(id)
+		for s := 0; s < steps; s++ {
+			sub.Steps = append(sub.Steps, types.Step{Type: types.StepTypeToolCall, Name: "t", AgentID: id})
+		}
+		root.Steps = append(root.Steps, agentStep(id, sub))
+	}
+	return root
+}
+
+func BenchmarkCollectStepsByAgentID(b *testing.B) {
+	root := wideTree(50, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for a := 0; a < 50; a++ {
+			CollectStepsByAgentID(root, fmt.Sprintf("agent_%d", a))
+		}
+	}
+}
+
+func BenchmarkTreeIndex(b *testing.B) {
+	root := wideTree(50, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix := NewTreeIndex(root)
+		for a := 0; a < 50; a++ {
+			ix.StepsByAgentID(fmt.Sprintf("agent_%d", a))
+		}
+	}
+}
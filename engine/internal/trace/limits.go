@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"fmt"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Ceilings bound how far an operator may raise each limit. They keep a
+// misconfigured engine from accepting traces it cannot hold in memory.
+const (
+	CeilingTraceSize     = 64 << 20 // 64 MB
+	CeilingStepsPerTrace = 100000
+	CeilingStepPayload   = 16 << 20 // 16 MB
+	CeilingSubTraceDepth = 16
+	CeilingJSONDepth     = 100000
+)
+
+// Limits bounds the traces Validate, Scan, and MeasureSize accept. A zero
+// field means its default, so the zero Limits enforces DefaultLimits.
+type Limits struct {
+	MaxTraceSize     int
+	MaxStepsPerTrace int
+	MaxStepPayload   int
+	MaxSubTraceDepth int
+	// MaxJSONDepth bounds how deeply arrays and objects nest anywhere in a
+	// request, so hostile input is rejected before a recursive decoder can
+	// exhaust the goroutine stack.
+	MaxJSONDepth int
+}
+
+// DefaultLimits are the protocol spec limits, used by the package-level
+// Validate, ValidateScanned, Scan, and MeasureSize.
+var DefaultLimits = Limits{
+	MaxTraceSize:     MaxTraceSize,
+	MaxStepsPerTrace: MaxStepsPerTrace,
+	MaxStepPayload:   MaxStepPayload,
+	MaxSubTraceDepth: MaxSubTraceDepth,
+	MaxJSONDepth:     MaxJSONDepth,
+}
+
+// Clamp returns l with zero or negative fields set to their defaults and
+// every field capped at its ceiling. A step payload larger than the whole
+// trace is capped at the trace size. The names of the capped fields are
+// returned so callers can report them.
+func (l Limits) Clamp() (Limits, []string) {
+	l = l.orDefaults()
+	var capped []string
+	clamp := func(name string, v *int, ceiling int) {
+		if *v > ceiling {
+			*v = ceiling
+			capped = append(capped, name)
+		}
+	}
+	clamp("max_trace_size", &l.MaxTraceSize, CeilingTraceSize)
+	clamp("max_steps_per_trace", &l.MaxStepsPerTrace, CeilingStepsPerTrace)
+	clamp("max_step_payload", &l.MaxStepPayload, CeilingStepPayload)
+	clamp("max_sub_trace_depth", &l.MaxSubTraceDepth, CeilingSubTraceDepth)
+	clamp("max_json_depth", &l.MaxJSONDepth, CeilingJSONDepth)
+	if l.MaxStepPayload > l.MaxTraceSize {
+		l.MaxStepPayload = l.MaxTraceSize
+		capped = append(capped, "max_step_payload")
+	}
+	return l, capped
+}
+
+func (l Limits) orDefaults() Limits {
+	if l.MaxTraceSize <= 0 {
+		l.MaxTraceSize = MaxTraceSize
+	}
+	if l.MaxStepsPerTrace <= 0 {
+		l.MaxStepsPerTrace = MaxStepsPerTrace
+	}
+	if l.MaxStepPayload <= 0 {
+		l.MaxStepPayload = MaxStepPayload
+	}
+	if l.MaxSubTraceDepth <= 0 {
+		l.MaxSubTraceDepth = MaxSubTraceDepth
+	}
+	if l.MaxJSONDepth <= 0 {
+		l.MaxJSONDepth = MaxJSONDepth
+	}
+	return l
+}
+
+func (l Limits) traceSizeError(size int) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("trace exceeds max size: %d > %d bytes", size, l.MaxTraceSize),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("Reduce trace size by filtering steps or truncating tool results. Max allowed: %d bytes%s.", l.MaxTraceSize, inMB(l.MaxTraceSize)),
+	)
+}
+
+func (l Limits) stepCountError(count int) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("trace exceeds max steps: %d > %d", count, l.MaxStepsPerTrace),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("Reduce the number of steps to %d or fewer. Consider batching or summarizing intermediate steps.", l.MaxStepsPerTrace),
+	)
+}
+
+func (l Limits) stepPayloadError(name string, size int) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("trace step '%s' exceeds max payload size: %d > %d bytes", name, size, l.MaxStepPayload),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("Reduce the step payload size to %d bytes%s or fewer by truncating tool results or outputs.", l.MaxStepPayload, inMB(l.MaxStepPayload)),
+	)
+}
+
+func (l Limits) depthError(depth int) *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("trace nesting depth %d exceeds maximum %d", depth, l.MaxSubTraceDepth),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("Reduce the agent_call nesting depth to %d or fewer levels.", l.MaxSubTraceDepth),
+	)
+}
+
+func (l Limits) jsonDepthError() *types.RPCError {
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("JSON nesting exceeds maximum depth %d", l.MaxJSONDepth),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("Flatten nested arrays and objects to %d or fewer levels.", l.MaxJSONDepth),
+	)
+}
+
+// inMB returns " (N MB)" when n bytes is a whole number of megabytes, else "".
+func inMB(n int) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf(" (%d MB)", n>>20)
+	}
+	return ""
+}
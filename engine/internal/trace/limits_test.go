@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestLimits_Clamp(t *testing.T) {
+	got, capped := Limits{MaxStepsPerTrace: 50, MaxSubTraceDepth: 100}.Clamp()
+	want := Limits{MaxTraceSize: MaxTraceSize, MaxStepsPerTrace: 50, MaxStepPayload: MaxStepPayload, MaxSubTraceDepth: CeilingSubTraceDepth, MaxJSONDepth: MaxJSONDepth}
+	if got != want {
+		t.Errorf("Clamp() = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(capped, []string{"max_sub_trace_depth"}) {
+		t.Errorf("capped = %v, want [max_sub_trace_depth]", capped)
+	}
+
+	got, capped = Limits{MaxTraceSize: 1000, MaxStepPayload: 5000}.Clamp()
+	if got.MaxStepPayload != 1000 || !reflect.DeepEqual(capped, []string{"max_step_payload"}) {
+		t.Errorf("step payload above trace size: got %+v, capped %v", got, capped)
+	}
+
+	if got, capped := (Limits{}).Clamp(); got != DefaultLimits || capped != nil {
+		t.Errorf("zero Limits clamp to %+v (capped %v), want DefaultLimits", got, capped)
+	}
+}
+
+func TestLimits_Configured(t *testing.T) {
+	limits, _ := Limits{MaxStepsPerTrace: 2, MaxSubTraceDepth: 1, MaxStepPayload: 200}.Clamp()
+	output := json.RawMessage(`{"ok":true}`)
+	steps := func(n int) []types.Step {
+		s := make([]types.Step, n)
+		for i := range s {
+			s[i] = types.Step{Type: types.StepTypeLLMCall, Name: "s"}
+		}
+		return s
+	}
+
+	tr := &types.Trace{TraceID: "t", Output: output, Steps: steps(3)}
+	if rpcErr := limits.Validate(tr, 0); rpcErr == nil || rpcErr.Message != "trace exceeds max steps: 3 > 2" {
+		t.Errorf("Validate(3 steps) = %v, want max steps error", rpcErr)
+	}
+	if rpcErr := Validate(tr, 0); rpcErr != nil {
+		t.Errorf("default Validate(3 steps) = %v, want nil", rpcErr)
+	}
+
+	nested := &types.Trace{TraceID: "root", Output: output, Steps: []types.Step{
+		{Type: types.StepTypeAgentCall, Name: "delegate", SubTrace: &types.Trace{TraceID: "child", Output: output}},
+	}}
+	if rpcErr := limits.Validate(nested, 0); rpcErr == nil || rpcErr.Message != "trace nesting depth 1 exceeds maximum 1" {
+		t.Errorf("Validate(nested) = %v, want depth error", rpcErr)
+	}
+
+	raw := `{"trace_id":"t","output":{"ok":true},"steps":[{"type":"tool_call","name":"big","result":"` + strings.Repeat("x", 300) + `"}]}`
+	if _, rpcErr := limits.Scan([]byte(raw)); rpcErr == nil || !strings.Contains(rpcErr.Message, "exceeds max payload size") {
+		t.Errorf("Scan(big step) = %v, want payload error", rpcErr)
+	}
+
+	size, _ := limits.MeasureSize(tr)
+	if got := size.LimitPercent["steps"]; got != 150 {
+		t.Errorf("steps percent = %v, want 150", got)
+	}
+}
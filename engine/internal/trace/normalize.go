@@ -8,10 +8,20 @@ import (
 
 const defaultSchemaVersion = 1
 
-// Normalize trims whitespace from TraceID and defaults SchemaVersion to 1 if 0.
+// Normalize trims whitespace from TraceID, defaults SchemaVersion to 1 if 0,
+// and up-converts traces older than schema_version 2 by deriving step
+// messages, declared tools, and step errors from v1 args and results (see
+// upgradeV1). SchemaVersion keeps the submitted version. Metadata timestamps
+// in any form ParseTimestamp accepts are rewritten as RFC 3339 in UTC, and
+// step retries are grouped (see groupRetries).
 func Normalize(t *types.Trace) {
 	t.TraceID = strings.TrimSpace(t.TraceID)
 	if t.SchemaVersion == 0 {
 		t.SchemaVersion = defaultSchemaVersion
 	}
+	if t.SchemaVersion < CurrentSchemaVersion {
+		upgradeV1(t)
+	}
+	normalizeTimestamps(t)
+	groupRetries(t)
 }
@@ -0,0 +1,110 @@
+package trace
+
+import (
+	"fmt"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// groupRetries links the retries in each trace of the tree into groups. A
+// step's retry_of may name any earlier attempt; it is rewritten to the group's
+// first attempt. A step with attempt above 1 and no retry_of retries the
+// closest earlier step with the same type and name. Attempts are then numbered
+// 1, 2, ... in step order within each group. Invalid retry_of values are left
+// for Validate to report.
+func groupRetries(t *types.Trace) {
+	attempts := make(map[int]int)
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		if step.Type == types.StepTypeAgentCall && step.SubTrace != nil {
+			groupRetries(step.SubTrace)
+		}
+		var first int
+		switch {
+		case step.RetryOf != nil:
+			if retryOfError(t.Steps, i) != "" {
+				continue
+			}
+			first = *step.RetryOf
+			if prev := t.Steps[first].RetryOf; prev != nil && *prev >= 0 && *prev < first {
+				first = *prev
+			}
+		case step.Attempt > 1:
+			prev := previousAttempt(t.Steps, i)
+			if prev < 0 {
+				continue
+			}
+			first = prev
+			if p := t.Steps[prev].RetryOf; p != nil && *p >= 0 && *p < prev {
+				first = *p
+			}
+		default:
+			continue
+		}
+		if attempts[first] == 0 {
+			attempts[first] = 1
+			t.Steps[first].Attempt = 1
+		}
+		attempts[first]++
+		step.RetryOf = &first
+		step.Attempt = attempts[first]
+	}
+}
+
+// previousAttempt returns the index of the closest step before i with the
+// same type and name, or -1.
+func previousAttempt(steps []types.Step, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if steps[j].Type == steps[i].Type && steps[j].Name == steps[i].Name {
+			return j
+		}
+	}
+	return -1
+}
+
+// retryOfError describes why steps[i].retry_of is invalid, or returns "".
+func retryOfError(steps []types.Step, i int) string {
+	r := *steps[i].RetryOf
+	switch {
+	case r < 0 || r >= i:
+		return fmt.Sprintf("retry_of %d is not the index of an earlier step", r)
+	case steps[r].Type != steps[i].Type || steps[r].Name != steps[i].Name:
+		return fmt.Sprintf("retry_of %d names step '%s' of type '%s', not a '%s' step named '%s'",
+			r, steps[r].Name, steps[r].Type, steps[i].Type, steps[i].Name)
+	}
+	return ""
+}
+
+// RetryGroup is one logical step: its first attempt and every retry of it,
+// as indexes into the trace's steps in attempt order.
+type RetryGroup struct {
+	Name     string
+	Type     string
+	Attempts []int
+}
+
+// Retries returns the number of retries after the first attempt.
+func (g RetryGroup) Retries() int { return len(g.Attempts) - 1 }
+
+// Last returns the index of the final attempt.
+func (g RetryGroup) Last() int { return g.Attempts[len(g.Attempts)-1] }
+
+// RetryGroups returns the retry groups of the trace's top-level steps in
+// order of their first attempt. A step that is neither retried nor a retry
+// forms a group of one. Call after Normalize.
+func RetryGroups(t *types.Trace) []RetryGroup {
+	var groups []RetryGroup
+	index := make(map[int]int)
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		if step.RetryOf != nil {
+			if g, ok := index[*step.RetryOf]; ok {
+				groups[g].Attempts = append(groups[g].Attempts, i)
+				continue
+			}
+		}
+		index[i] = len(groups)
+		groups = append(groups, RetryGroup{Name: step.Name, Type: step.Type, Attempts: []int{i}})
+	}
+	return groups
+}
@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestNormalize_GroupsRetries(t *testing.T) {
+	zero := 0
+	tool := func(name string) types.Step { return types.Step{Type: types.StepTypeToolCall, Name: name} }
+	steps := []types.Step{
+		tool("search"), // 0: first attempt
+		tool("lookup"), // 1
+		tool("search"), // 2: retry_of 0
+		tool("search"), // 3: retry_of 2, a retry of a retry
+		tool("lookup"), // 4: attempt 2, no retry_of
+		tool("fetch"),  // 5: attempt 2 with no earlier fetch
+	}
+	steps[2].RetryOf = &zero
+	steps[3].RetryOf = new(int)
+	*steps[3].RetryOf = 2
+	steps[4].Attempt = 2
+	steps[5].Attempt = 2
+	child := &types.Trace{TraceID: "child", Steps: []types.Step{tool("x"), tool("x")}}
+	child.Steps[1].RetryOf = &zero
+	steps = append(steps, types.Step{Type: types.StepTypeAgentCall, Name: "sub", SubTrace: child})
+	tr := &types.Trace{TraceID: "root", SchemaVersion: CurrentSchemaVersion, Steps: steps}
+
+	Normalize(tr)
+
+	wantRetryOf := []int{-1, -1, 0, 0, 1, -1, -1}
+	wantAttempt := []int{1, 1, 2, 3, 2, 2, 0}
+	for i, s := range tr.Steps {
+		retryOf := -1
+		if s.RetryOf != nil {
+			retryOf = *s.RetryOf
+		}
+		if retryOf != wantRetryOf[i] || s.Attempt != wantAttempt[i] {
+			t.Errorf("step %d: retry_of %d attempt %d, want %d and %d", i, retryOf, s.Attempt, wantRetryOf[i], wantAttempt[i])
+		}
+	}
+	if c := child.Steps[1]; c.RetryOf == nil || *c.RetryOf != 0 || c.Attempt != 2 || child.Steps[0].Attempt != 1 {
+		t.Errorf("sub-trace retries not grouped: %+v", child.Steps)
+	}
+
+	groups := RetryGroups(tr)
+	if len(groups) != 4 || groups[0].Name != "search" || groups[0].Retries() != 2 || groups[0].Last() != 3 || groups[1].Retries() != 1 {
+		t.Errorf("groups = %+v", groups)
+	}
+}
+
+func TestValidate_RetryOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		retryOf int
+		other   string
+	}{
+		{"forward reference", 1, "search"},
+		{"negative", -1, "search"},
+		{"different step", 0, "lookup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryOf := tt.retryOf
+			tr := &types.Trace{
+				TraceID:       "trc_1",
+				SchemaVersion: CurrentSchemaVersion,
+				Output:        json.RawMessage(`{"message":"ok"}`),
+				Steps: []types.Step{
+					{Type: types.StepTypeToolCall, Name: tt.other},
+					{Type: types.StepTypeToolCall, Name: "search", RetryOf: &retryOf},
+				},
+			}
+			Normalize(tr)
+			if err := Validate(tr, 0); err == nil || err.Code != types.ErrInvalidTrace {
+				t.Errorf("Validate = %v, want INVALID_TRACE", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+	"github.com/segmentio/encoding/json"
+)
+
+// ScanStats describes a raw trace as measured by Scan. Sizes exclude
+// insignificant whitespace so they match the length of the compact encoding.
+type ScanStats struct {
+	// Size is the compact byte length of the whole trace.
+	Size int
+	// StepSizes holds the compact byte length of each top-level step.
+	StepSizes []int
+}
+
+// errScanSyntax marks malformed JSON; Scan leaves reporting it to the decoder.
+var errScanSyntax = errors.New("malformed JSON")
+
+// Scan checks raw trace JSON against DefaultLimits; see Limits.Scan.
+func Scan(raw []byte) (*ScanStats, *types.RPCError) {
+	return DefaultLimits.Scan(raw)
+}
+
+// Scan checks the size, step-count, step-payload, and sub-trace-depth limits
+// directly on raw trace JSON with a single token-level pass, so oversized
+// traces are rejected before they are decoded into a types.Trace. It allocates
+// nothing proportional to payload size. Violations are reported with the same
+// errors as Validate. Malformed JSON is not reported here (stats and error are
+// both nil); the subsequent decode produces the parse error.
+//
+// The pass recurses, so the JSON nesting limit is checked first.
+func (l Limits) Scan(raw []byte) (*ScanStats, *types.RPCError) {
+	l = l.orDefaults()
+	if rpcErr := l.CheckNesting(raw); rpcErr != nil {
+		return nil, rpcErr
+	}
+	s := &scanner{data: raw, limits: l}
+	s.skipWS()
+	stats := &ScanStats{}
+	rpcErr, err := s.scanTrace(0, stats)
+	if err != nil {
+		return nil, nil
+	}
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	stats.Size = len(raw) - s.ws
+	if stats.Size > l.MaxTraceSize {
+		return nil, l.traceSizeError(stats.Size)
+	}
+	return stats, nil
+}
+
+// CheckNesting returns an error when arrays and objects in raw nest deeper
+// than MaxJSONDepth. Callers run it before decoding untrusted JSON: the
+// decoders recurse per level, and a stack overflow cannot be recovered.
+func (l Limits) CheckNesting(raw []byte) *types.RPCError {
+	l = l.orDefaults()
+	if ExceedsNesting(raw, l.MaxJSONDepth) {
+		return l.jsonDepthError()
+	}
+	return nil
+}
+
+// ExceedsNesting reports whether arrays and objects in raw nest deeper than
+// limit. It counts brackets outside strings in one iterative pass and does not
+// otherwise validate raw.
+func ExceedsNesting(raw []byte, limit int) bool {
+	depth := 0
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '[', '{':
+			if depth++; depth > limit {
+				return true
+			}
+		case ']', '}':
+			depth--
+		case '"':
+			for i++; i < len(raw) && raw[i] != '"'; i++ {
+				if raw[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return false
+}
+
+// RawField returns the raw value of key in the top-level JSON object raw,
+// without copying. ok is false when raw is not an object or lacks key.
+func RawField(raw []byte, key string) (value []byte, ok bool) {
+	s := &scanner{data: raw}
+	s.skipWS()
+	found := false
+	err := s.object(func(k []byte) error {
+		if found || string(k) != key {
+			return s.skipValue()
+		}
+		start := s.pos
+		if err := s.skipValue(); err != nil {
+			return err
+		}
+		value, found = bytes.TrimRight(raw[start:s.pos], " \t\r\n"), true
+		return nil
+	})
+	if err != nil || !found {
+		return nil, false
+	}
+	return value, true
+}
+
+// scanTrace scans a trace object at s.pos. stats, when non-nil, receives the
+// step sizes of this (top-level) trace.
+func (s *scanner) scanTrace(depth int, stats *ScanStats) (*types.RPCError, error) {
+	if s.peek() != '{' {
+		return nil, s.skipValue()
+	}
+	var rpcErr *types.RPCError
+	err := s.object(func(k []byte) error {
+		if string(k) != "steps" || s.peek() != '[' {
+			return s.skipValue()
+		}
+		count := 0
+		if stats != nil {
+			// A repeated "steps" key replaces the earlier one, as in decoding.
+			stats.StepSizes = stats.StepSizes[:0]
+		}
+		return s.array(func() error {
+			count++
+			if count > s.limits.MaxStepsPerTrace && rpcErr == nil {
+				rpcErr = s.limits.stepCountError(count)
+			}
+			startPos, startWS := s.pos, s.ws
+			name, stepErr, err := s.scanStep(depth)
+			if err != nil {
+				return err
+			}
+			size := (s.pos - startPos) - (s.ws - startWS)
+			if stats != nil {
+				stats.StepSizes = append(stats.StepSizes, size)
+			}
+			if rpcErr == nil && stepErr != nil {
+				rpcErr = stepErr
+			}
+			if rpcErr == nil && size > s.limits.MaxStepPayload {
+				rpcErr = s.limits.stepPayloadError(name, size)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rpcErr == nil && depth >= s.limits.MaxSubTraceDepth {
+		rpcErr = s.limits.depthError(depth)
+	}
+	return rpcErr, nil
+}
+
+// scanStep scans one step, returning its name and any limit violation in its
+// sub-trace. Like Validate, sub-traces count only on agent_call steps.
+func (s *scanner) scanStep(depth int) (name string, rpcErr *types.RPCError, err error) {
+	if s.peek() != '{' {
+		return "", nil, s.skipValue()
+	}
+	var stepType string
+	var subErr *types.RPCError
+	err = s.object(func(k []byte) error {
+		switch string(k) {
+		case "name", "type":
+			start := s.pos
+			if err := s.skipValue(); err != nil {
+				return err
+			}
+			var v string
+			if json.Unmarshal(s.data[start:s.pos], &v) == nil {
+				if string(k) == "name" {
+					name = v
+				} else {
+					stepType = v
+				}
+			}
+			return nil
+		case "sub_trace":
+			var err error
+			subErr, err = s.scanTrace(depth+1, nil)
+			return err
+		default:
+			return s.skipValue()
+		}
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if stepType == types.StepTypeAgentCall {
+		rpcErr = subErr
+	}
+	return name, rpcErr, nil
+}
+
+// scanner is a minimal JSON tokenizer over a byte slice. It validates structure
+// only as far as needed to find value boundaries and counts the insignificant
+// whitespace it skips in ws. Trace scans enforce limits.
+type scanner struct {
+	data   []byte
+	pos    int
+	ws     int
+	limits Limits
+}
+
+func (s *scanner) peek() byte {
+	if s.pos < len(s.data) {
+		return s.data[s.pos]
+	}
+	return 0
+}
+
+func (s *scanner) skipWS() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+			s.ws++
+		default:
+			return
+		}
+	}
+}
+
+// expect consumes c (after whitespace) or fails.
+func (s *scanner) expect(c byte) error {
+	s.skipWS()
+	if s.peek() != c {
+		return errScanSyntax
+	}
+	s.pos++
+	s.skipWS()
+	return nil
+}
+
+// object iterates the members of the object at s.pos, calling fn with each raw
+// (still escaped) key while s.pos is at the member's value. fn must consume the value.
+func (s *scanner) object(fn func(key []byte) error) error {
+	if err := s.expect('{'); err != nil {
+		return err
+	}
+	if s.peek() == '}' {
+		s.pos++
+		s.skipWS()
+		return nil
+	}
+	for {
+		if s.peek() != '"' {
+			return errScanSyntax
+		}
+		start := s.pos + 1
+		if err := s.skipString(); err != nil {
+			return err
+		}
+		key := s.data[start : s.pos-1]
+		if err := s.expect(':'); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+		s.skipWS()
+		switch s.peek() {
+		case ',':
+			s.pos++
+			s.skipWS()
+		case '}':
+			s.pos++
+			s.skipWS()
+			return nil
+		default:
+			return errScanSyntax
+		}
+	}
+}
+
+// array iterates the elements of the array at s.pos, calling fn while s.pos
+// is at each element. fn must consume the element.
+func (s *scanner) array(fn func() error) error {
+	if err := s.expect('['); err != nil {
+		return err
+	}
+	if s.peek() == ']' {
+		s.pos++
+		s.skipWS()
+		return nil
+	}
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+		s.skipWS()
+		switch s.peek() {
+		case ',':
+			s.pos++
+			s.skipWS()
+		case ']':
+			s.pos++
+			s.skipWS()
+			return nil
+		default:
+			return errScanSyntax
+		}
+	}
+}
+
+// skipValue consumes one value of any type and any whitespace before and after it.
+func (s *scanner) skipValue() error {
+	s.skipWS()
+	switch c := s.peek(); {
+	case c == '{':
+		return s.object(func([]byte) error { return s.skipValue() })
+	case c == '[':
+		return s.array(s.skipValue)
+	case c == '"':
+		if err := s.skipString(); err != nil {
+			return err
+		}
+	case c == '-' || (c >= '0' && c <= '9') || c == 't' || c == 'f' || c == 'n':
+		// Numbers and literals run to the next delimiter.
+		for s.pos < len(s.data) && !isDelimiter(s.data[s.pos]) {
+			s.pos++
+		}
+	default:
+		return errScanSyntax
+	}
+	s.skipWS()
+	return nil
+}
+
+// skipString consumes the string starting at s.pos, including both quotes.
+func (s *scanner) skipString() error {
+	s.pos++ // opening quote
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '"':
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return errScanSyntax
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case ',', '}', ']', ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
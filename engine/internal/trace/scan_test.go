@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// nestedTrace builds a trace with depth levels of agent_call sub-traces.
+func nestedTrace(depth int, stepType string) string {
+	tr := `{"trace_id":"leaf","output":{"m":"x"},"steps":[]}`
+	for i := 0; i < depth; i++ {
+		tr = fmt.Sprintf(`{"trace_id":"t%d","output":{"m":"x"},"steps":[{"type":%q,"name":"call","sub_trace":%s}]}`, i, stepType, tr)
+	}
+	return tr
+}
+
+func TestScan_MatchesValidateLimits(t *testing.T) {
+	bigResult := strings.Repeat("a", MaxStepPayload)
+	manySteps := `{"trace_id":"t","output":{"m":"x"},"steps":[` +
+		strings.TrimSuffix(strings.Repeat(`{"type":"llm_call","name":"s"},`, MaxStepsPerTrace+1), ",") + `]}`
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantMsg string
+	}{
+		{"valid", `{"trace_id":"t","output":{"m":"x"},"steps":[{"type":"llm_call","name":"s","result":{"a":1}}]}`, ""},
+		{"step payload", `{"trace_id":"t","output":{"m":"x"},"steps":[{"result":"` + bigResult + `","type":"tool_call","name":"big"}]}`, "trace step 'big' exceeds max payload size"},
+		{"step count", manySteps, "trace exceeds max steps"},
+		{"depth ok", nestedTrace(MaxSubTraceDepth-1, types.StepTypeAgentCall), ""},
+		{"depth exceeded", nestedTrace(MaxSubTraceDepth, types.StepTypeAgentCall), "trace nesting depth 5 exceeds maximum 5"},
+		{"depth ignored off agent_call", nestedTrace(MaxSubTraceDepth, types.StepTypeToolCall), ""},
+		{"total size", `{"trace_id":"t","output":{"m":"` + strings.Repeat("b", MaxTraceSize) + `"}}`, "trace exceeds max size"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stats, rpcErr := Scan([]byte(tc.raw))
+			if tc.wantMsg == "" {
+				if rpcErr != nil {
+					t.Fatalf("unexpected error: %s", rpcErr.Message)
+				}
+				if stats == nil {
+					t.Fatal("stats = nil for valid trace")
+				}
+				return
+			}
+			if rpcErr == nil || !strings.Contains(rpcErr.Message, tc.wantMsg) {
+				t.Fatalf("error = %v, want message containing %q", rpcErr, tc.wantMsg)
+			}
+			if rpcErr.Code != types.ErrInvalidTrace {
+				t.Errorf("code = %d, want %d", rpcErr.Code, types.ErrInvalidTrace)
+			}
+		})
+	}
+}
+
+func TestScan_SizesIgnoreWhitespace(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "traces", "valid.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	pretty, rpcErr := Scan(data)
+	if rpcErr != nil {
+		t.Fatalf("Scan(pretty): %s", rpcErr.Message)
+	}
+	flat, rpcErr := Scan(compact.Bytes())
+	if rpcErr != nil {
+		t.Fatalf("Scan(compact): %s", rpcErr.Message)
+	}
+	if pretty.Size != flat.Size || pretty.Size != compact.Len() {
+		t.Errorf("sizes = %d (pretty), %d (compact), want %d", pretty.Size, flat.Size, compact.Len())
+	}
+	if len(pretty.StepSizes) == 0 || fmt.Sprint(pretty.StepSizes) != fmt.Sprint(flat.StepSizes) {
+		t.Errorf("step sizes = %v vs %v", pretty.StepSizes, flat.StepSizes)
+	}
+}
+
+func TestScan_MalformedLeftToDecoder(t *testing.T) {
+	for _, raw := range []string{`{"trace_id":`, `{"steps":[{]}`, `"unterminated`, ``} {
+		if stats, rpcErr := Scan([]byte(raw)); stats != nil || rpcErr != nil {
+			t.Errorf("Scan(%q) = %v, %v; want nil, nil", raw, stats, rpcErr)
+		}
+	}
+}
+
+func TestScan_DeepNesting(t *testing.T) {
+	deep := `{"trace_id":"t","output":` + strings.Repeat("[", 3<<20) + strings.Repeat("]", 3<<20) + `}`
+	_, rpcErr := Scan([]byte(deep))
+	if rpcErr == nil || !strings.Contains(rpcErr.Message, "JSON nesting exceeds maximum depth 10000") {
+		t.Fatalf("Scan = %v, want a nesting error", rpcErr)
+	}
+
+	limits := Limits{MaxJSONDepth: 3}
+	for raw, want := range map[string]bool{
+		`{"a":[[1]]}`:          false,
+		`{"a":[[[1]]]}`:        true,
+		`{"a":"[[[[{{{{"}`:     false,
+		`{"a":"\"[[[[","b":1}`: false,
+		`]]]]{"a":[[1]]}`:      false,
+	} {
+		if got := limits.CheckNesting([]byte(raw)) != nil; got != want {
+			t.Errorf("CheckNesting(%s) rejected = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestRawField(t *testing.T) {
+	params := []byte(`{"assertions":[{"x":"}"}], "trace" : {"trace_id":"t","s":"\"trace\""} , "seed":1}`)
+	got, ok := RawField(params, "trace")
+	if !ok || string(got) != `{"trace_id":"t","s":"\"trace\""}` {
+		t.Errorf("RawField(trace) = %q, %v", got, ok)
+	}
+	if _, ok := RawField(params, "missing"); ok {
+		t.Error("RawField(missing) found a value")
+	}
+	if _, ok := RawField([]byte(`[1,2]`), "trace"); ok {
+		t.Error("RawField on array found a value")
+	}
+}
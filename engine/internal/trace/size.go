@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"fmt"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+	"github.com/segmentio/encoding/json"
+)
+
+// WarnNearLimit is the code of the warning MeasureSize reports for a limit
+// more than NearLimitPercent consumed.
+const WarnNearLimit = "near_limit"
+
+// NearLimitPercent is the share of a hard limit above which a trace tree is
+// warned about.
+const NearLimitPercent = 80
+
+// MeasureSize measures a trace tree against DefaultLimits; see
+// Limits.MeasureSize.
+func MeasureSize(root *types.Trace) (*types.TraceSize, []types.TraceWarning) {
+	return DefaultLimits.MeasureSize(root)
+}
+
+// MeasureSize measures a trace tree against the hard limits Validate
+// enforces: the serialized size of the tree, the largest step payload at any
+// depth, the most steps in one trace, and the sub-trace depth. It returns the
+// measurements and a near_limit warning per limit more than NearLimitPercent
+// consumed, so callers can trim a trace before it is rejected.
+func (l Limits) MeasureSize(root *types.Trace) (*types.TraceSize, []types.TraceWarning) {
+	l = l.orDefaults()
+	// maxTreeDepth is the deepest sub-trace Validate accepts; the root is depth 0.
+	maxTreeDepth := l.MaxSubTraceDepth - 1
+	size := &types.TraceSize{}
+	if b, err := json.Marshal(root); err == nil {
+		size.TotalBytes = len(b)
+	}
+	var largestTrace string
+	var largestIndex int
+	WalkTree(root, func(t *types.Trace, depth int) bool {
+		size.MaxSteps = max(size.MaxSteps, len(t.Steps))
+		size.Depth = max(size.Depth, depth)
+		for i := range t.Steps {
+			b, err := json.Marshal(&t.Steps[i])
+			if err == nil && len(b) > size.LargestStepBytes {
+				size.LargestStepBytes = len(b)
+				size.LargestStep = t.Steps[i].Name
+				largestTrace, largestIndex = t.TraceID, i
+			}
+		}
+		return true
+	})
+
+	size.LimitPercent = map[string]float64{
+		"size":         percentOf(size.TotalBytes, l.MaxTraceSize),
+		"step_payload": percentOf(size.LargestStepBytes, l.MaxStepPayload),
+		"steps":        percentOf(size.MaxSteps, l.MaxStepsPerTrace),
+		"depth":        percentOf(size.Depth, maxTreeDepth),
+	}
+
+	var warnings []types.TraceWarning
+	near := func(limit, message string) {
+		if size.LimitPercent[limit] > NearLimitPercent {
+			warnings = append(warnings, types.TraceWarning{
+				Code:    WarnNearLimit,
+				TraceID: root.TraceID,
+				Message: fmt.Sprintf("%s (%.0f%% of the limit)", message, size.LimitPercent[limit]),
+			})
+		}
+	}
+	near("size", fmt.Sprintf("trace tree is %d bytes of the %d byte limit", size.TotalBytes, l.MaxTraceSize))
+	near("steps", fmt.Sprintf("a trace has %d steps of the %d step limit", size.MaxSteps, l.MaxStepsPerTrace))
+	near("depth", fmt.Sprintf("sub-traces nest %d deep of the %d level limit", size.Depth, maxTreeDepth))
+	if size.LimitPercent["step_payload"] > NearLimitPercent {
+		warnings = append(warnings, types.TraceWarning{
+			Code:      WarnNearLimit,
+			TraceID:   largestTrace,
+			Step:      size.LargestStep,
+			StepIndex: &largestIndex,
+			Message: fmt.Sprintf("step %q is %d bytes of the %d byte limit (%.0f%% of the limit)",
+				size.LargestStep, size.LargestStepBytes, l.MaxStepPayload, size.LimitPercent["step_payload"]),
+		})
+	}
+	return size, warnings
+}
+
+// percentOf returns n as a percentage of limit, rounded to one decimal. A
+// limit of 0 (no sub-traces allowed) reports 0.
+func percentOf(n, limit int) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return float64(n*1000/limit) / 10
+}
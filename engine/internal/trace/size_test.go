@@ -0,0 +1,47 @@
+package trace
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestMeasureSize(t *testing.T) {
+	leaf := &types.Trace{TraceID: "trc_leaf", Output: json.RawMessage(`{"ok":true}`), Steps: []types.Step{
+		{Type: types.StepTypeToolCall, Name: "lookup", Result: json.RawMessage(`"` + strings.Repeat("x", MaxStepPayload*9/10) + `"`)},
+	}}
+	root := &types.Trace{TraceID: "trc_root", Output: json.RawMessage(`{"ok":true}`), Steps: []types.Step{
+		{Type: types.StepTypeLLMCall, Name: "plan"},
+		{Type: types.StepTypeAgentCall, Name: "delegate", SubTrace: leaf},
+	}}
+
+	size, warnings := MeasureSize(root)
+	if size.MaxSteps != 2 || size.Depth != 1 {
+		t.Errorf("MaxSteps, Depth = %d, %d; want 2, 1", size.MaxSteps, size.Depth)
+	}
+	// The agent_call step contains the sub-trace, so it is the largest.
+	if size.LargestStep != "delegate" || size.LargestStepBytes <= MaxStepPayload*9/10 {
+		t.Errorf("largest step = %q (%d bytes), want delegate", size.LargestStep, size.LargestStepBytes)
+	}
+	if size.TotalBytes <= size.LargestStepBytes {
+		t.Errorf("TotalBytes %d should exceed the largest step", size.TotalBytes)
+	}
+	if got := size.LimitPercent["depth"]; got != 25 {
+		t.Errorf("depth percent = %v, want 25", got)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarnNearLimit || warnings[0].Step != "delegate" || warnings[0].TraceID != "trc_root" {
+		t.Fatalf("warnings = %+v, want one near_limit warning for step delegate", warnings)
+	}
+}
+
+func TestMeasureSize_Small(t *testing.T) {
+	size, warnings := MeasureSize(&types.Trace{TraceID: "trc", Output: json.RawMessage(`{"ok":true}`)})
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %+v, want none", warnings)
+	}
+	if size.LimitPercent["size"] != 0 || size.LargestStep != "" {
+		t.Errorf("size = %+v", size)
+	}
+}
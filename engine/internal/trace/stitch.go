@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// BuildTree nests a flat list of traces linked by parent_trace_id, as span
+// exporters produce them, into a single tree rooted at the one trace without a
+// parent. Each child becomes the sub_trace of the first agent_call step in its
+// parent that has none and whose agent_id or name equals the child's agent_id;
+// when no such step exists a new agent_call step named after the child's
+// agent_id (or trace_id) is appended. Children are attached in list order.
+//
+// It fails on duplicate trace_ids, orphans (a parent_trace_id naming no
+// trace in the list), zero or several roots, and cycles. The input traces are
+// not modified.
+func BuildTree(traces []types.Trace) (*types.Trace, error) {
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("no traces to stitch")
+	}
+	nodes := make([]*types.Trace, len(traces))
+	byID := make(map[string]*types.Trace, len(traces))
+	for i := range traces {
+		t := traces[i]
+		t.Steps = append([]types.Step(nil), t.Steps...)
+		nodes[i] = &t
+		id := strings.TrimSpace(t.TraceID)
+		if _, dup := byID[id]; dup {
+			return nil, fmt.Errorf("duplicate trace_id %q in trace list", id)
+		}
+		byID[id] = &t
+	}
+
+	var roots []string
+	for _, t := range nodes {
+		if parentID(t) == "" {
+			roots = append(roots, t.TraceID)
+			continue
+		}
+		if _, ok := byID[parentID(t)]; !ok {
+			return nil, fmt.Errorf("orphan trace %q: parent_trace_id %q is not in the trace list", t.TraceID, parentID(t))
+		}
+	}
+	switch {
+	case len(roots) > 1:
+		return nil, fmt.Errorf("multiple root traces without parent_trace_id: %s", strings.Join(roots, ", "))
+	case len(roots) == 0:
+		return nil, fmt.Errorf("no root trace: parent_trace_id links form a cycle through %q", nodes[0].TraceID)
+	}
+
+	for _, t := range nodes {
+		if pid := parentID(t); pid != "" {
+			attach(byID[pid], t)
+		}
+	}
+
+	// With one root and no orphans, traces unreachable from the root sit on a cycle.
+	root := byID[strings.TrimSpace(roots[0])]
+	reached := make(map[*types.Trace]bool, len(nodes))
+	WalkTree(root, func(t *types.Trace, _ int) bool {
+		reached[t] = true
+		return true
+	})
+	for _, t := range nodes {
+		if !reached[t] {
+			return nil, fmt.Errorf("parent_trace_id links form a cycle through %q", t.TraceID)
+		}
+	}
+	return root, nil
+}
+
+func parentID(t *types.Trace) string {
+	if t.ParentTraceID == nil {
+		return ""
+	}
+	return strings.TrimSpace(*t.ParentTraceID)
+}
+
+// attach makes child the sub_trace of an agent_call step in parent.
+func attach(parent, child *types.Trace) {
+	for i := range parent.Steps {
+		s := &parent.Steps[i]
+		if s.Type == types.StepTypeAgentCall && s.SubTrace == nil && child.AgentID != "" &&
+			(s.AgentID == child.AgentID || s.Name == child.AgentID) {
+			s.SubTrace = child
+			return
+		}
+	}
+	name := child.AgentID
+	if name == "" {
+		name = child.TraceID
+	}
+	parent.Steps = append(parent.Steps, types.Step{
+		Type:     types.StepTypeAgentCall,
+		Name:     name,
+		AgentID:  child.AgentID,
+		SubTrace: child,
+	})
+}
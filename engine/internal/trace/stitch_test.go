@@ -0,0 +1,75 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func flatTrace(id, agentID, parent string, steps ...types.Step) types.Trace {
+	t := *testTrace(agentID, steps...)
+	t.TraceID = id
+	if parent != "" {
+		t.ParentTraceID = ptr(parent)
+	}
+	return t
+}
+
+func TestBuildTree(t *testing.T) {
+	flat := []types.Trace{
+		flatTrace("trc_search", "search", "trc_research"),
+		flatTrace("trc_root", "root", "", types.Step{Type: types.StepTypeAgentCall, Name: "research"}),
+		flatTrace("trc_research", "research", "trc_root"),
+		flatTrace("trc_billing", "billing", "trc_root"),
+	}
+	root, err := BuildTree(flat)
+	if err != nil {
+		t.Fatalf("BuildTree: %v", err)
+	}
+	if root.TraceID != "trc_root" {
+		t.Fatalf("root = %q, want trc_root", root.TraceID)
+	}
+	if len(root.Steps) != 2 {
+		t.Fatalf("root steps = %d, want declared research step plus appended billing step", len(root.Steps))
+	}
+	if sub := root.Steps[0].SubTrace; sub == nil || sub.TraceID != "trc_research" {
+		t.Errorf("research step sub_trace = %+v", sub)
+	}
+	if s := root.Steps[1]; s.Name != "billing" || s.SubTrace == nil || s.SubTrace.TraceID != "trc_billing" {
+		t.Errorf("appended step = %+v", s)
+	}
+	if got := root.Steps[0].SubTrace.Steps; len(got) != 1 || got[0].SubTrace.TraceID != "trc_search" {
+		t.Errorf("search not nested under research: %+v", got)
+	}
+	if err := ValidateTraceTree(root); err != nil {
+		t.Errorf("stitched tree invalid: %v", err)
+	}
+	if flat[1].Steps[0].SubTrace != nil {
+		t.Error("BuildTree modified its input")
+	}
+}
+
+func TestBuildTree_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		traces  []types.Trace
+		wantErr string
+	}{
+		{"empty", nil, "no traces"},
+		{"duplicate", []types.Trace{flatTrace("a", "a", ""), flatTrace("a", "b", "")}, "duplicate trace_id"},
+		{"orphan", []types.Trace{flatTrace("a", "a", ""), flatTrace("b", "b", "missing")}, "orphan trace"},
+		{"two roots", []types.Trace{flatTrace("a", "a", ""), flatTrace("b", "b", "")}, "multiple root"},
+		{"all in cycle", []types.Trace{flatTrace("a", "a", "b"), flatTrace("b", "b", "a")}, "cycle"},
+		{"cycle beside root", []types.Trace{flatTrace("r", "r", ""), flatTrace("a", "a", "b"), flatTrace("b", "b", "a")}, "cycle"},
+		{"self parent", []types.Trace{flatTrace("r", "r", ""), flatTrace("a", "a", "a")}, "cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildTree(tt.traces)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Temporal warning codes reported by TemporalWarnings.
+const (
+	WarnStepEndBeforeStart = "step_end_before_start"
+	WarnStepOutsideParent  = "step_outside_parent"
+	WarnNegativeLatency    = "negative_latency"
+	WarnSequentialOverlap  = "sequential_overlap"
+)
+
+// ExecutionSequential is the metadata.execution value declaring that a
+// trace's agent_call children ran one after another.
+const ExecutionSequential = "sequential"
+
+// TemporalWarnings checks the timestamps and latencies of a trace tree for
+// anomalies that do not make the tree structurally invalid:
+//   - a step whose ended_at_ms precedes its started_at_ms
+//   - a sub-trace step whose interval falls outside the interval of the
+//     agent_call step that spawned it
+//   - negative latency_ms or aggregate_latency_ms in trace metadata
+//   - overlapping agent_call children in a trace whose metadata.execution is "sequential"
+//
+// Steps without both timestamps are skipped. Warnings are returned in walk order.
+func TemporalWarnings(root *types.Trace) []types.TraceWarning {
+	var warnings []types.TraceWarning
+	temporalAt(root, nil, &warnings)
+	return warnings
+}
+
+type interval struct {
+	start, end int64
+}
+
+func stepInterval(s *types.Step) (interval, bool) {
+	if s.StartedAtMs == nil || s.EndedAtMs == nil {
+		return interval{}, false
+	}
+	return interval{*s.StartedAtMs, *s.EndedAtMs}, true
+}
+
+// temporalAt checks t; bound, when non-nil, is the interval of the agent_call
+// step that spawned t.
+func temporalAt(t *types.Trace, bound *interval, warnings *[]types.TraceWarning) {
+	if m := t.Metadata; m != nil {
+		if m.LatencyMS != nil && *m.LatencyMS < 0 {
+			*warnings = append(*warnings, types.TraceWarning{
+				Code:    WarnNegativeLatency,
+				TraceID: t.TraceID,
+				Message: fmt.Sprintf("trace %q has negative latency_ms %d", t.TraceID, *m.LatencyMS),
+			})
+		}
+		if m.AggregateLatencyMS != nil && *m.AggregateLatencyMS < 0 {
+			*warnings = append(*warnings, types.TraceWarning{
+				Code:    WarnNegativeLatency,
+				TraceID: t.TraceID,
+				Message: fmt.Sprintf("trace %q has negative aggregate_latency_ms %d", t.TraceID, *m.AggregateLatencyMS),
+			})
+		}
+	}
+
+	type child struct {
+		step int
+		iv   interval
+	}
+	var children []child
+
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		iv, ok := stepInterval(step)
+		if ok && iv.end < iv.start {
+			*warnings = append(*warnings, types.TraceWarning{
+				Code:      WarnStepEndBeforeStart,
+				TraceID:   t.TraceID,
+				Step:      step.Name,
+				StepIndex: &i,
+				Message:   fmt.Sprintf("step %q ended at %d before it started at %d (%d ms)", step.Name, iv.end, iv.start, iv.end-iv.start),
+			})
+			ok = false // an inverted interval cannot bound or order anything
+		}
+		if ok && bound != nil && (iv.start < bound.start || iv.end > bound.end) {
+			*warnings = append(*warnings, types.TraceWarning{
+				Code:      WarnStepOutsideParent,
+				TraceID:   t.TraceID,
+				Step:      step.Name,
+				StepIndex: &i,
+				Message: fmt.Sprintf("step %q runs %d-%d, outside its parent agent_call's %d-%d",
+					step.Name, iv.start, iv.end, bound.start, bound.end),
+			})
+		}
+		if step.Type != types.StepTypeAgentCall || step.SubTrace == nil {
+			continue
+		}
+		if ok {
+			children = append(children, child{step: i, iv: iv})
+			temporalAt(step.SubTrace, &iv, warnings)
+		} else {
+			temporalAt(step.SubTrace, nil, warnings)
+		}
+	}
+
+	if t.Metadata == nil || t.Metadata.Execution == nil || *t.Metadata.Execution != ExecutionSequential {
+		return
+	}
+	sort.SliceStable(children, func(a, b int) bool { return children[a].iv.start < children[b].iv.start })
+	for k := 1; k < len(children); k++ {
+		prev, cur := children[k-1], children[k]
+		if cur.iv.start < prev.iv.end {
+			*warnings = append(*warnings, types.TraceWarning{
+				Code:      WarnSequentialOverlap,
+				TraceID:   t.TraceID,
+				Step:      t.Steps[cur.step].Name,
+				StepIndex: &cur.step,
+				Message: fmt.Sprintf("agent_call %q starts at %d before %q ends at %d, but trace %q is sequential",
+					t.Steps[cur.step].Name, cur.iv.start, t.Steps[prev.step].Name, prev.iv.end, t.TraceID),
+			})
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func timedStep(name string, start, end int64) types.Step {
+	return types.Step{Type: types.StepTypeToolCall, Name: name, StartedAtMs: ptr(start), EndedAtMs: ptr(end)}
+}
+
+func timedAgent(name string, start, end int64, sub *types.Trace) types.Step {
+	s := agentStep(name, sub)
+	s.StartedAtMs, s.EndedAtMs = ptr(start), ptr(end)
+	return s
+}
+
+func warningCodes(ws []types.TraceWarning) []string {
+	codes := make([]string, len(ws))
+	for i, w := range ws {
+		codes[i] = w.Code
+	}
+	return codes
+}
+
+func TestTemporalWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		root *types.Trace
+		want []string
+	}{
+		{
+			name: "consistent tree",
+			root: testTrace("root",
+				timedStep("plan", 0, 10),
+				timedAgent("research", 10, 50, testTrace("research", timedStep("search", 15, 40))),
+			),
+			want: nil,
+		},
+		{
+			name: "untimed steps are skipped",
+			root: testTrace("root", types.Step{Type: types.StepTypeToolCall, Name: "t"}),
+			want: nil,
+		},
+		{
+			name: "end before start",
+			root: testTrace("root", timedStep("plan", 20, 10)),
+			want: []string{WarnStepEndBeforeStart},
+		},
+		{
+			name: "child outside parent interval",
+			root: testTrace("root",
+				timedAgent("research", 10, 50, testTrace("research", timedStep("search", 45, 60))),
+			),
+			want: []string{WarnStepOutsideParent},
+		},
+		{
+			name: "negative latency",
+			root: func() *types.Trace {
+				tr := testTrace("root")
+				tr.Metadata = &types.TraceMetadata{LatencyMS: ptr(-5)}
+				return tr
+			}(),
+			want: []string{WarnNegativeLatency},
+		},
+		{
+			name: "overlap in sequential trace",
+			root: func() *types.Trace {
+				tr := testTrace("root",
+					timedAgent("a", 0, 30, testTrace("a")),
+					timedAgent("b", 20, 40, testTrace("b")),
+				)
+				tr.Metadata = &types.TraceMetadata{Execution: ptr(ExecutionSequential)}
+				return tr
+			}(),
+			want: []string{WarnSequentialOverlap},
+		},
+		{
+			name: "overlap allowed when not sequential",
+			root: testTrace("root",
+				timedAgent("a", 0, 30, testTrace("a")),
+				timedAgent("b", 20, 40, testTrace("b")),
+			),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := warningCodes(TemporalWarnings(tt.root))
+			if len(got) != len(tt.want) {
+				t.Fatalf("warnings = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("warnings = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTemporalWarnings_LocatesStep(t *testing.T) {
+	root := testTrace("root", timedStep("ok", 0, 1), timedStep("bad", 9, 3))
+	ws := TemporalWarnings(root)
+	if len(ws) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(ws))
+	}
+	if ws[0].TraceID != "trc_root" || ws[0].Step != "bad" || ws[0].StepIndex == nil || *ws[0].StepIndex != 1 {
+		t.Errorf("warning = %+v", ws[0])
+	}
+}
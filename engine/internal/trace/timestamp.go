@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// timestampLayouts are the metadata.timestamp forms SDKs and loggers emit,
+// tried in order. Layouts without a zone are read as UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+	"2006-01-02",
+}
+
+// unixMillisThreshold separates epoch seconds from epoch milliseconds: a
+// number above it is read as milliseconds (year 33658 in seconds).
+const unixMillisThreshold = 1e12
+
+// ParseTimestamp parses a trace timestamp: RFC 3339, the common variants
+// with a space separator or no zone offset (read as UTC), RFC 1123, or Unix
+// epoch seconds or milliseconds.
+func ParseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsInf(f, 0) || math.IsNaN(f) || f < 0 {
+			return time.Time{}, fmt.Errorf("timestamp %q is not a valid epoch time", s)
+		}
+		if f > unixMillisThreshold {
+			return time.UnixMilli(int64(f)).UTC(), nil
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	for _, layout := range timestampLayouts {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("timestamp %q is not RFC 3339 or a recognized date-time format", s)
+}
+
+// normalizeTimestamps rewrites parseable metadata timestamps in the trace tree
+// as RFC 3339 in UTC. Unparseable ones are left for Warnings to report.
+func normalizeTimestamps(t *types.Trace) {
+	if t.Metadata != nil && t.Metadata.Timestamp != nil {
+		if ts, err := ParseTimestamp(*t.Metadata.Timestamp); err == nil {
+			s := ts.Format(time.RFC3339Nano)
+			t.Metadata.Timestamp = &s
+		}
+	}
+	for i := range t.Steps {
+		if t.Steps[i].SubTrace != nil {
+			normalizeTimestamps(t.Steps[i].SubTrace)
+		}
+	}
+}
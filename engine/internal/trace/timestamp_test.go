@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2026-02-18T10:30:00Z", "2026-02-18T10:30:00Z"},
+		{"2026-02-18T12:30:00+02:00", "2026-02-18T10:30:00Z"},
+		{"2026-02-18T10:30:00.250-0500", "2026-02-18T15:30:00.25Z"},
+		{"2026-02-18 10:30:00+00:00", "2026-02-18T10:30:00Z"},
+		{"2026-02-18T10:30:00", "2026-02-18T10:30:00Z"},
+		{" 2026-02-18 10:30:00.5 ", "2026-02-18T10:30:00.5Z"},
+		{"Wed, 18 Feb 2026 10:30:00 +0100", "2026-02-18T09:30:00Z"},
+		{"2026-02-18", "2026-02-18T00:00:00Z"},
+		{"1771410600", "2026-02-18T10:30:00Z"},
+		{"1771410600.5", "2026-02-18T10:30:00.5Z"},
+		{"1771410600000", "2026-02-18T10:30:00Z"},
+	}
+	for _, tt := range tests {
+		ts, err := ParseTimestamp(tt.in)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q): %v", tt.in, err)
+			continue
+		}
+		if got := ts.Format("2006-01-02T15:04:05.999999999Z07:00"); got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "yesterday", "18/02/2026", "-5", "NaN"} {
+		if _, err := ParseTimestamp(in); err == nil {
+			t.Errorf("ParseTimestamp(%q): want error", in)
+		}
+	}
+}
+
+func TestNormalize_Timestamps(t *testing.T) {
+	local, bad, nested := "2026-02-18 11:30:00+01:00", "last tuesday", "1771410600"
+	tr := &types.Trace{
+		TraceID:       "trc_1",
+		SchemaVersion: CurrentSchemaVersion,
+		Metadata:      &types.TraceMetadata{Timestamp: &local},
+		Steps: []types.Step{{
+			Type:     types.StepTypeAgentCall,
+			Name:     "child",
+			SubTrace: &types.Trace{TraceID: "trc_2", Metadata: &types.TraceMetadata{Timestamp: &nested}},
+		}},
+	}
+	Normalize(tr)
+	if got := *tr.Metadata.Timestamp; got != "2026-02-18T10:30:00Z" {
+		t.Errorf("timestamp = %s, want 2026-02-18T10:30:00Z", got)
+	}
+	if got := *tr.Steps[0].SubTrace.Metadata.Timestamp; got != "2026-02-18T10:30:00Z" {
+		t.Errorf("sub-trace timestamp = %s, want 2026-02-18T10:30:00Z", got)
+	}
+
+	tr.Metadata.Timestamp = &bad
+	Normalize(tr)
+	if *tr.Metadata.Timestamp != bad {
+		t.Errorf("unparseable timestamp rewritten to %s", *tr.Metadata.Timestamp)
+	}
+}
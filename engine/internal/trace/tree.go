@@ -78,15 +78,21 @@ func AgentIDs(root *types.Trace) []string {
 //   - agent_call steps must have sub_traces
 //   - parent_trace_id consistency (child's parent_trace_id must match parent's trace_id)
 //   - no duplicate trace_ids (cycle detection)
-//   - nesting depth within MaxSubTraceDepth
+//   - nesting depth within DefaultLimits.MaxSubTraceDepth
 func ValidateTraceTree(root *types.Trace) error {
+	return DefaultLimits.ValidateTraceTree(root)
+}
+
+// ValidateTraceTree is ValidateTraceTree with nesting depth bounded by
+// l.MaxSubTraceDepth.
+func (l Limits) ValidateTraceTree(root *types.Trace) error {
 	seen := make(map[string]struct{})
-	return validateTreeAtDepth(root, nil, 0, seen)
+	return l.orDefaults().validateTreeAtDepth(root, nil, 0, seen)
 }
 
-func validateTreeAtDepth(t *types.Trace, parent *types.Trace, depth int, seen map[string]struct{}) error {
-	if depth > MaxSubTraceDepth {
-		return fmt.Errorf("trace nesting depth %d exceeds maximum %d", depth, MaxSubTraceDepth)
+func (l Limits) validateTreeAtDepth(t *types.Trace, parent *types.Trace, depth int, seen map[string]struct{}) error {
+	if depth > l.MaxSubTraceDepth {
+		return fmt.Errorf("trace nesting depth %d exceeds maximum %d", depth, l.MaxSubTraceDepth)
 	}
 
 	if _, exists := seen[t.TraceID]; exists {
@@ -104,7 +110,7 @@ func validateTreeAtDepth(t *types.Trace, parent *types.Trace, depth int, seen ma
 			return fmt.Errorf("agent_call step %q in trace %q is missing sub_trace", step.Name, t.TraceID)
 		}
 		if step.Type == types.StepTypeAgentCall && step.SubTrace != nil {
-			if err := validateTreeAtDepth(step.SubTrace, t, depth+1, seen); err != nil {
+			if err := l.validateTreeAtDepth(step.SubTrace, t, depth+1, seen); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,201 @@
+package trace
+
+import (
+	"strings"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+	"github.com/segmentio/encoding/json"
+)
+
+// upgradeV1 fills the schema_version 2 fields of a v1 trace tree from the
+// conventions v1 SDKs used for them. Fields already present are kept.
+//   - llm_call messages: args.messages (OpenAI/Anthropic style, string or
+//     text-part content) or args.prompt, followed by the assistant reply in
+//     result (a message object, choices[0].message, or a content, completion,
+//     text, or message string).
+//   - trace tools: args.tools on llm_call steps, in OpenAI function format or
+//     as {name, description, parameters|input_schema}, first declaration wins.
+//   - step errors: result.error as a string or {type, message} object.
+func upgradeV1(t *types.Trace) {
+	declared := make(map[string]bool, len(t.Tools))
+	for _, tool := range t.Tools {
+		declared[tool.Name] = true
+	}
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		if step.Error == nil {
+			step.Error = v1StepError(step.Result)
+		}
+		switch step.Type {
+		case types.StepTypeLLMCall:
+			var args struct {
+				Messages []v1Message      `json:"messages"`
+				Prompt   string           `json:"prompt"`
+				Tools    []v1ToolDeclared `json:"tools"`
+			}
+			if len(step.Args) > 0 {
+				_ = json.Unmarshal(step.Args, &args)
+			}
+			if step.Messages == nil {
+				step.Messages = v1Messages(args.Messages, args.Prompt, step.Result)
+			}
+			for _, d := range args.Tools {
+				tool := d.schema()
+				if tool.Name == "" || declared[tool.Name] {
+					continue
+				}
+				declared[tool.Name] = true
+				t.Tools = append(t.Tools, tool)
+			}
+		case types.StepTypeAgentCall:
+			if sub := step.SubTrace; sub != nil && sub.SchemaVersion < CurrentSchemaVersion {
+				upgradeV1(sub)
+			}
+		}
+	}
+}
+
+// v1Message is a chat message as v1 SDKs recorded it; content may be a
+// string or an array of typed parts.
+type v1Message struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+	Name    string          `json:"name"`
+}
+
+// v1Roles maps provider-specific roles onto the v2 roles; messages with other
+// roles are dropped so up-conversion never makes a valid v1 trace invalid.
+var v1Roles = map[string]string{
+	types.RoleSystem:    types.RoleSystem,
+	"developer":         types.RoleSystem,
+	types.RoleUser:      types.RoleUser,
+	"human":             types.RoleUser,
+	types.RoleAssistant: types.RoleAssistant,
+	"model":             types.RoleAssistant,
+	types.RoleTool:      types.RoleTool,
+	"function":          types.RoleTool,
+}
+
+func (m v1Message) message() (types.Message, bool) {
+	role, ok := v1Roles[m.Role]
+	if !ok {
+		return types.Message{}, false
+	}
+	return types.Message{Role: role, Content: v1Content(m.Content), Name: m.Name}, true
+}
+
+// v1Content flattens string or [{type: "text", text}] content to text.
+func v1Content(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func v1Messages(history []v1Message, prompt string, result json.RawMessage) []types.Message {
+	var msgs []types.Message
+	for _, m := range history {
+		if msg, ok := m.message(); ok {
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) == 0 && prompt != "" {
+		msgs = append(msgs, types.Message{Role: types.RoleUser, Content: prompt})
+	}
+	if reply, ok := v1Reply(result); ok {
+		msgs = append(msgs, reply)
+	}
+	return msgs
+}
+
+// v1Reply extracts the assistant message from an llm_call result.
+func v1Reply(result json.RawMessage) (types.Message, bool) {
+	if len(result) == 0 {
+		return types.Message{}, false
+	}
+	var s string
+	if json.Unmarshal(result, &s) == nil {
+		return types.Message{Role: types.RoleAssistant, Content: s}, s != ""
+	}
+	var r struct {
+		v1Message
+		Choices []struct {
+			Message v1Message `json:"message"`
+		} `json:"choices"`
+		Completion string          `json:"completion"`
+		Text       string          `json:"text"`
+		Message    json.RawMessage `json:"message"`
+	}
+	if json.Unmarshal(result, &r) != nil {
+		return types.Message{}, false
+	}
+	if msg, ok := r.v1Message.message(); ok {
+		return msg, true
+	}
+	if len(r.Choices) > 0 {
+		if msg, ok := r.Choices[0].Message.message(); ok {
+			return msg, true
+		}
+	}
+	for _, text := range []string{v1Content(r.Content), r.Completion, r.Text, v1Content(r.Message)} {
+		if text != "" {
+			return types.Message{Role: types.RoleAssistant, Content: text}, true
+		}
+	}
+	return types.Message{}, false
+}
+
+// v1ToolDeclared is a tool declaration in OpenAI function format or flat form.
+type v1ToolDeclared struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+	InputSchema json.RawMessage `json:"input_schema"`
+	Function    *struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+func (d v1ToolDeclared) schema() types.ToolSchema {
+	if f := d.Function; f != nil {
+		return types.ToolSchema{Name: f.Name, Description: f.Description, Parameters: f.Parameters}
+	}
+	params := d.Parameters
+	if params == nil {
+		params = d.InputSchema
+	}
+	return types.ToolSchema{Name: d.Name, Description: d.Description, Parameters: params}
+}
+
+// v1StepError reads result.error as a string or {type, message} object.
+func v1StepError(result json.RawMessage) *types.StepError {
+	raw, ok := RawField(result, "error")
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	var msg string
+	if json.Unmarshal(raw, &msg) == nil {
+		return &types.StepError{Message: msg}
+	}
+	var obj types.StepError
+	if json.Unmarshal(raw, &obj) != nil || obj.Message == "" {
+		return nil
+	}
+	return &obj
+}
@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestNormalize_UpgradesV1(t *testing.T) {
+	tr := &types.Trace{
+		SchemaVersion: 1,
+		TraceID:       "trc_v1",
+		Output:        json.RawMessage(`{"message":"done"}`),
+		Steps: []types.Step{
+			{
+				Type: types.StepTypeLLMCall,
+				Name: "plan",
+				Args: json.RawMessage(`{
+					"messages": [
+						{"role": "developer", "content": "be brief"},
+						{"role": "user", "content": [{"type": "text", "text": "refund order 7"}]},
+						{"role": "critic", "content": "dropped"}
+					],
+					"tools": [
+						{"type": "function", "function": {"name": "lookup", "parameters": {"type": "object"}}},
+						{"name": "refund", "input_schema": {"type": "object"}}
+					]
+				}`),
+				Result: json.RawMessage(`{"choices": [{"message": {"role": "assistant", "content": "calling lookup"}}]}`),
+			},
+			{
+				Type:   types.StepTypeToolCall,
+				Name:   "lookup",
+				Result: json.RawMessage(`{"error": {"type": "timeout", "message": "upstream timed out"}}`),
+			},
+			{
+				Type:     types.StepTypeAgentCall,
+				Name:     "billing",
+				SubTrace: &types.Trace{SchemaVersion: 1, TraceID: "trc_sub", Output: json.RawMessage(`{"total":42}`), Steps: []types.Step{{Type: types.StepTypeLLMCall, Name: "ask", Args: json.RawMessage(`{"prompt":"total?"}`), Result: json.RawMessage(`"42"`)}}},
+			},
+		},
+	}
+	Normalize(tr)
+
+	if tr.SchemaVersion != 1 {
+		t.Errorf("SchemaVersion = %d, want submitted version 1", tr.SchemaVersion)
+	}
+	want := []types.Message{
+		{Role: types.RoleSystem, Content: "be brief"},
+		{Role: types.RoleUser, Content: "refund order 7"},
+		{Role: types.RoleAssistant, Content: "calling lookup"},
+	}
+	if got := tr.Steps[0].Messages; len(got) != len(want) {
+		t.Fatalf("messages = %+v, want %+v", got, want)
+	} else {
+		for i := range want {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Errorf("messages[%d] = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+	if len(tr.Tools) != 2 || tr.Tools[0].Name != "lookup" || tr.Tools[1].Name != "refund" || len(tr.Tools[1].Parameters) == 0 {
+		t.Errorf("tools = %+v", tr.Tools)
+	}
+	if e := tr.Steps[1].Error; e == nil || e.Type != "timeout" || e.Message != "upstream timed out" {
+		t.Errorf("error = %+v", e)
+	}
+	sub := tr.Steps[2].SubTrace.Steps[0].Messages
+	if len(sub) != 2 || sub[0].Content != "total?" || sub[1].Content != "42" {
+		t.Errorf("sub-trace messages = %+v", sub)
+	}
+	if err := Validate(tr, 0); err != nil {
+		t.Errorf("upgraded trace invalid: %v", err.Message)
+	}
+}
+
+func TestNormalize_KeepsV2Fields(t *testing.T) {
+	tr := &types.Trace{
+		SchemaVersion: 2,
+		TraceID:       "trc_v2",
+		Steps: []types.Step{{
+			Type:     types.StepTypeLLMCall,
+			Name:     "plan",
+			Args:     json.RawMessage(`{"prompt":"ignored"}`),
+			Messages: []types.Message{{Role: types.RoleUser, Content: "hi"}},
+		}},
+	}
+	Normalize(tr)
+	if got := tr.Steps[0].Messages; len(got) != 1 || got[0].Content != "hi" {
+		t.Errorf("messages = %+v, want v2 messages untouched", got)
+	}
+}
+
+func TestValidate_Messages(t *testing.T) {
+	base := func() *types.Trace {
+		return &types.Trace{
+			SchemaVersion: 2,
+			TraceID:       "trc_v2",
+			Output:        json.RawMessage(`{"message":"ok"}`),
+			Steps:         []types.Step{{Type: types.StepTypeLLMCall, Name: "plan", Messages: []types.Message{{Role: types.RoleUser, Content: "hi"}}}},
+		}
+	}
+	if err := Validate(base(), 0); err != nil {
+		t.Fatalf("valid v2 trace rejected: %s", err.Message)
+	}
+
+	bad := base()
+	bad.Steps[0].Messages[0].Role = "critic"
+	if err := Validate(bad, 0); err == nil || err.Code != types.ErrInvalidTrace {
+		t.Errorf("invalid role: got %v, want INVALID_TRACE", err)
+	}
+
+	bad = base()
+	bad.Steps[0].Type = types.StepTypeToolCall
+	if err := Validate(bad, 0); err == nil {
+		t.Error("messages on tool_call: got nil, want INVALID_TRACE")
+	}
+
+	bad = base()
+	bad.Tools = []types.ToolSchema{{Name: " "}}
+	if err := Validate(bad, 0); err == nil {
+		t.Error("unnamed tool: got nil, want INVALID_TRACE")
+	}
+
+	bad = base()
+	bad.Transcript = []types.Message{{Role: types.RoleUser, Content: "hi"}, {Role: "narrator", Content: "..."}}
+	if err := Validate(bad, 0); err == nil || err.Code != types.ErrInvalidTrace {
+		t.Errorf("invalid transcript role: got %v, want INVALID_TRACE", err)
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"github.com/segmentio/encoding/json"
 )
 
+// Default trace limits per the protocol spec. Validation reads the
+// configured values from a Limits; see DefaultLimits.
 const (
 	MaxTraceSize         = 10485760 // 10 MB
 	MaxStepsPerTrace     = 10000
 	MaxOutputLength      = 500000
 	MaxStepPayload       = 1048576 // 1 MB
 	MaxSubTraceDepth     = 5
-	CurrentSchemaVersion = 1
+	MaxJSONDepth         = 10000
+	CurrentSchemaVersion = 2
 	MinSchemaVersion     = 0
 )
 
@@ -25,15 +28,42 @@ var validStepTypes = map[string]struct{}{
 	types.StepTypeAgentCall: {},
 }
 
-// Validate validates a trace per the protocol spec section 7.
+// Validate validates a trace against DefaultLimits; see Limits.Validate.
+func Validate(t *types.Trace, traceSize int) *types.RPCError {
+	return DefaultLimits.Validate(t, traceSize)
+}
+
+// ValidateScanned validates a trace against DefaultLimits; see
+// Limits.ValidateScanned.
+func ValidateScanned(t *types.Trace, stats *ScanStats) *types.RPCError {
+	return DefaultLimits.ValidateScanned(t, stats)
+}
+
+// Validate validates a trace per the protocol spec section 7, enforcing l.
 // traceSize is the pre-computed JSON byte length of the trace; pass 0 to
 // have Validate compute it internally (slower, requires re-serialization).
 // Returns nil if the trace is valid, or an RPCError describing the first failure.
-func Validate(t *types.Trace, traceSize int) *types.RPCError {
-	return validateAtDepth(t, 0, traceSize)
+func (l Limits) Validate(t *types.Trace, traceSize int) *types.RPCError {
+	return l.orDefaults().validateAtDepth(t, 0, traceSizes{total: traceSize})
+}
+
+// ValidateScanned validates a trace using the sizes Scan measured on its raw
+// JSON, so nothing is re-marshaled. Sub-trace and nested step sizes are bounded
+// by the enclosing trace and step sizes and need no separate measurement.
+func (l Limits) ValidateScanned(t *types.Trace, stats *ScanStats) *types.RPCError {
+	return l.orDefaults().validateAtDepth(t, 0, traceSizes{total: stats.Size, steps: stats.StepSizes, scanned: true})
 }
 
-func validateAtDepth(t *types.Trace, depth int, traceSize int) *types.RPCError {
+// traceSizes carries pre-computed sizes into validateAtDepth. With scanned
+// set, total and steps come from Scan (steps indexed like t.Steps); a scanned
+// sub-trace has total 0 and nil steps because its parent's limits bound it.
+type traceSizes struct {
+	total   int
+	steps   []int
+	scanned bool
+}
+
+func (l Limits) validateAtDepth(t *types.Trace, depth int, sizes traceSizes) *types.RPCError {
 	// 1. schema_version check
 	if t.SchemaVersion < MinSchemaVersion || t.SchemaVersion > CurrentSchemaVersion {
 		return types.NewRPCError(
@@ -77,9 +107,10 @@ func validateAtDepth(t *types.Trace, depth int, traceSize int) *types.RPCError {
 		)
 	}
 
-	// 3. Size limits: trace JSON size <= 10MB
+	// 3. Size limits: trace JSON size <= l.MaxTraceSize
 	// Use pre-computed traceSize when available to avoid re-serialization.
-	if traceSize <= 0 {
+	traceSize := sizes.total
+	if traceSize <= 0 && !sizes.scanned {
 		traceBytes, err := json.Marshal(t)
 		if err != nil {
 			return types.NewRPCError(
@@ -92,29 +123,17 @@ func validateAtDepth(t *types.Trace, depth int, traceSize int) *types.RPCError {
 		}
 		traceSize = len(traceBytes)
 	}
-	if traceSize > MaxTraceSize {
-		return types.NewRPCError(
-			types.ErrInvalidTrace,
-			fmt.Sprintf("trace exceeds max size: %d > %d bytes", traceSize, MaxTraceSize),
-			types.ErrTypeInvalidTrace,
-			false,
-			fmt.Sprintf("Reduce trace size by filtering steps or truncating tool results. Max allowed: %d bytes (10 MB).", MaxTraceSize),
-		)
+	if traceSize > l.MaxTraceSize {
+		return l.traceSizeError(traceSize)
 	}
 
-	// 3. Size limits: steps count <= 10000
-	if len(t.Steps) > MaxStepsPerTrace {
-		return types.NewRPCError(
-			types.ErrInvalidTrace,
-			fmt.Sprintf("trace exceeds max steps: %d > %d", len(t.Steps), MaxStepsPerTrace),
-			types.ErrTypeInvalidTrace,
-			false,
-			fmt.Sprintf("Reduce the number of steps to %d or fewer. Consider batching or summarizing intermediate steps.", MaxStepsPerTrace),
-		)
+	// 3. Size limits: steps count <= l.MaxStepsPerTrace
+	if len(t.Steps) > l.MaxStepsPerTrace {
+		return l.stepCountError(len(t.Steps))
 	}
 
 	// 4. Step validation
-	for _, step := range t.Steps {
+	for i, step := range t.Steps {
 		if strings.TrimSpace(step.Name) == "" {
 			return types.NewRPCError(
 				types.ErrInvalidTrace,
@@ -133,42 +152,86 @@ func validateAtDepth(t *types.Trace, depth int, traceSize int) *types.RPCError {
 				fmt.Sprintf("Step type must be one of: llm_call, tool_call, retrieval, agent_call. Got '%s' for step '%s'.", step.Type, step.Name),
 			)
 		}
-		// E4: Enforce MaxStepPayload (1 MB) per step.
-		stepBytes, err := json.Marshal(step)
-		if err != nil {
+		if rpcErr := validateMessages(&step); rpcErr != nil {
+			return rpcErr
+		}
+		if step.RetryOf != nil {
+			if msg := retryOfError(t.Steps, i); msg != "" {
+				return types.NewRPCError(
+					types.ErrInvalidTrace,
+					fmt.Sprintf("trace step '%s' has invalid %s", step.Name, msg),
+					types.ErrTypeInvalidTrace,
+					false,
+					"Set retry_of to the index of an earlier attempt of the same step, or omit it and set attempt.",
+				)
+			}
+		}
+		if step.Attempt < 0 {
 			return types.NewRPCError(
 				types.ErrInvalidTrace,
-				fmt.Sprintf("trace step '%s' could not be serialized for size check", step.Name),
+				fmt.Sprintf("trace step '%s' has negative attempt %d", step.Name, step.Attempt),
 				types.ErrTypeInvalidTrace,
 				false,
-				"Ensure all step fields contain valid JSON-serializable values.",
+				"attempt numbers start at 1; omit it for steps that are not retried.",
 			)
 		}
-		if len(stepBytes) > MaxStepPayload {
+		// E4: Enforce l.MaxStepPayload per step.
+		var stepSize int
+		switch {
+		case sizes.scanned && i < len(sizes.steps):
+			stepSize = sizes.steps[i]
+		case sizes.scanned:
+			// Nested step: bounded by the enclosing top-level step.
+		default:
+			stepBytes, err := json.Marshal(step)
+			if err != nil {
+				return types.NewRPCError(
+					types.ErrInvalidTrace,
+					fmt.Sprintf("trace step '%s' could not be serialized for size check", step.Name),
+					types.ErrTypeInvalidTrace,
+					false,
+					"Ensure all step fields contain valid JSON-serializable values.",
+				)
+			}
+			stepSize = len(stepBytes)
+		}
+		if stepSize > l.MaxStepPayload {
+			return l.stepPayloadError(step.Name, stepSize)
+		}
+	}
+
+	for _, tool := range t.Tools {
+		if strings.TrimSpace(tool.Name) == "" {
 			return types.NewRPCError(
 				types.ErrInvalidTrace,
-				fmt.Sprintf("trace step '%s' exceeds max payload size: %d > %d bytes", step.Name, len(stepBytes), MaxStepPayload),
+				"trace tool declaration missing required field: name",
 				types.ErrTypeInvalidTrace,
 				false,
-				fmt.Sprintf("Reduce the step payload size to %d bytes (1 MB) or fewer by truncating tool results or outputs.", MaxStepPayload),
+				"Every entry in tools must include a non-empty name string.",
 			)
 		}
 	}
 
-	// 5. Sub-trace depth: recursively check agent_call sub_traces, max depth 5
-	if depth >= MaxSubTraceDepth {
-		return types.NewRPCError(
-			types.ErrInvalidTrace,
-			fmt.Sprintf("trace nesting depth %d exceeds maximum %d", depth, MaxSubTraceDepth),
-			types.ErrTypeInvalidTrace,
-			false,
-			fmt.Sprintf("Reduce the agent_call nesting depth to %d or fewer levels.", MaxSubTraceDepth),
-		)
+	for i, m := range t.Transcript {
+		if _, ok := validMessageRoles[m.Role]; !ok {
+			return types.NewRPCError(
+				types.ErrInvalidTrace,
+				fmt.Sprintf("trace transcript message %d has invalid role '%s'", i, m.Role),
+				types.ErrTypeInvalidTrace,
+				false,
+				fmt.Sprintf("Message role must be one of: system, user, assistant, tool. Got '%s' at transcript index %d.", m.Role, i),
+			)
+		}
+	}
+
+	// 5. Sub-trace depth: recursively check agent_call sub_traces up to l.MaxSubTraceDepth
+	if depth >= l.MaxSubTraceDepth {
+		return l.depthError(depth)
 	}
 
 	for _, step := range t.Steps {
 		if step.Type == types.StepTypeAgentCall && step.SubTrace != nil {
-			if rpcErr := validateAtDepth(step.SubTrace, depth+1, 0); rpcErr != nil {
+			if rpcErr := l.validateAtDepth(step.SubTrace, depth+1, traceSizes{scanned: sizes.scanned}); rpcErr != nil {
 				return rpcErr
 			}
 		}
@@ -176,3 +239,39 @@ func validateAtDepth(t *types.Trace, depth int, traceSize int) *types.RPCError {
 
 	return nil
 }
+
+var validMessageRoles = map[string]struct{}{
+	types.RoleSystem:    {},
+	types.RoleUser:      {},
+	types.RoleAssistant: {},
+	types.RoleTool:      {},
+}
+
+// validateMessages checks the schema_version 2 messages of a step: only
+// llm_call steps carry them, and every role must be known.
+func validateMessages(step *types.Step) *types.RPCError {
+	if len(step.Messages) == 0 {
+		return nil
+	}
+	if step.Type != types.StepTypeLLMCall {
+		return types.NewRPCError(
+			types.ErrInvalidTrace,
+			fmt.Sprintf("trace step '%s' has messages but type '%s'", step.Name, step.Type),
+			types.ErrTypeInvalidTrace,
+			false,
+			"Only llm_call steps may carry messages. Record tool inputs and outputs in args and result.",
+		)
+	}
+	for _, m := range step.Messages {
+		if _, ok := validMessageRoles[m.Role]; !ok {
+			return types.NewRPCError(
+				types.ErrInvalidTrace,
+				fmt.Sprintf("trace step '%s' has message with invalid role '%s'", step.Name, m.Role),
+				types.ErrTypeInvalidTrace,
+				false,
+				fmt.Sprintf("Message role must be one of: system, user, assistant, tool. Got '%s' for step '%s'.", m.Role, step.Name),
+			)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package trace
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -248,3 +249,46 @@ func TestModel(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateScanned_AgreesWithValidate(t *testing.T) {
+	names := []string{"valid.json", "deep_nesting.json", "invalid_step_type.json", "missing_trace_id.json", "too_many_steps.json"}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", "traces", name))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			tr := loadFixture(t, name)
+			want := Validate(tr, 0)
+
+			stats, got := Scan(raw)
+			if got == nil {
+				got = ValidateScanned(tr, stats)
+			}
+			if (want == nil) != (got == nil) {
+				t.Fatalf("Validate = %v, scanned = %v", want, got)
+			}
+			if want != nil && want.Message != got.Message {
+				t.Errorf("message = %q, want %q", got.Message, want.Message)
+			}
+		})
+	}
+}
+
+func TestValidateScanned_UsesScannedStepSizes(t *testing.T) {
+	tr := &types.Trace{
+		TraceID: "trc_1",
+		Output:  makeOutput(t, map[string]any{"message": "ok"}),
+		Steps:   []types.Step{{Type: types.StepTypeToolCall, Name: "small"}},
+	}
+	stats := &ScanStats{Size: 100, StepSizes: []int{MaxStepPayload + 1}}
+	rpcErr := ValidateScanned(tr, stats)
+	if rpcErr == nil || rpcErr.Message != fmt.Sprintf("trace step 'small' exceeds max payload size: %d > %d bytes", MaxStepPayload+1, MaxStepPayload) {
+		t.Errorf("error = %v, want step payload error from scanned size", rpcErr)
+	}
+
+	stats = &ScanStats{Size: MaxTraceSize + 1, StepSizes: []int{10}}
+	if rpcErr := ValidateScanned(tr, stats); rpcErr == nil {
+		t.Error("expected size error from scanned total")
+	}
+}
@@ -0,0 +1,128 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+	"github.com/segmentio/encoding/json"
+)
+
+// Lenient validation warning codes reported by Warnings.
+const (
+	WarnDeprecatedSchema = "deprecated_schema_version"
+	WarnMissingMetadata  = "missing_metadata"
+	WarnEmptySteps       = "empty_steps"
+	WarnLargeStep        = "large_step_payload"
+	WarnInvalidTimestamp = "invalid_timestamp"
+)
+
+// StrictAll in a strict list promotes every warning to an error.
+const StrictAll = "all"
+
+// LargeStepPayload is the step size above which a step is flagged as
+// suspiciously large; it is a quarter of MaxStepPayload.
+const LargeStepPayload = MaxStepPayload / 4
+
+// Warnings reports conditions in a trace that Validate accepts but that
+// usually indicate an instrumentation problem: a deprecated schema_version,
+// missing metadata, an unparseable metadata timestamp, no steps, and
+// top-level steps larger than LargeStepPayload. stats, when non-nil, supplies step sizes measured by Scan;
+// otherwise steps are re-marshaled to measure them. Call after Validate.
+func Warnings(t *types.Trace, stats *ScanStats) []types.TraceWarning {
+	var warnings []types.TraceWarning
+	if t.SchemaVersion < CurrentSchemaVersion {
+		warnings = append(warnings, types.TraceWarning{
+			Code:    WarnDeprecatedSchema,
+			TraceID: t.TraceID,
+			Message: fmt.Sprintf("schema_version %d is deprecated; upgrade to %d", t.SchemaVersion, CurrentSchemaVersion),
+		})
+	}
+	if t.Metadata == nil {
+		warnings = append(warnings, types.TraceWarning{
+			Code:    WarnMissingMetadata,
+			TraceID: t.TraceID,
+			Message: "trace has no metadata; cost, token, and latency constraints cannot be checked",
+		})
+	} else if ts := t.Metadata.Timestamp; ts != nil {
+		if _, err := ParseTimestamp(*ts); err != nil {
+			warnings = append(warnings, types.TraceWarning{
+				Code:    WarnInvalidTimestamp,
+				TraceID: t.TraceID,
+				Message: fmt.Sprintf("%v; metadata.age_seconds constraints cannot be checked", err),
+			})
+		}
+	}
+	if len(t.Steps) == 0 {
+		warnings = append(warnings, types.TraceWarning{
+			Code:    WarnEmptySteps,
+			TraceID: t.TraceID,
+			Message: "trace has no steps; trace inspection assertions have nothing to check",
+		})
+	}
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		var size int
+		if stats != nil && i < len(stats.StepSizes) {
+			size = stats.StepSizes[i]
+		} else if b, err := json.Marshal(step); err == nil {
+			size = len(b)
+		}
+		if size > LargeStepPayload {
+			warnings = append(warnings, types.TraceWarning{
+				Code:      WarnLargeStep,
+				TraceID:   t.TraceID,
+				Step:      step.Name,
+				StepIndex: &i,
+				Message:   fmt.Sprintf("step %q is %d bytes, over the %d byte warning threshold", step.Name, size, LargeStepPayload),
+			})
+		}
+	}
+	return warnings
+}
+
+// Promote returns an INVALID_TRACE error for the first warning whose code is
+// listed in strict, or nil when none is. StrictAll matches every code.
+func Promote(warnings []types.TraceWarning, strict []string) *types.RPCError {
+	if len(strict) == 0 {
+		return nil
+	}
+	for _, w := range warnings {
+		for _, code := range strict {
+			if code == StrictAll || code == w.Code {
+				return types.NewRPCError(
+					types.ErrInvalidTrace,
+					fmt.Sprintf("strict validation: %s", w.Message),
+					types.ErrTypeInvalidTrace,
+					false,
+					fmt.Sprintf("Fix the trace or remove %q from strict to accept it with a warning.", w.Code),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckStrict rejects strict entries that name no warning code, so a typo
+// does not silently disable promotion.
+func CheckStrict(strict []string) *types.RPCError {
+	var unknown []string
+	for _, code := range strict {
+		switch code {
+		case StrictAll, WarnDeprecatedSchema, WarnMissingMetadata, WarnEmptySteps, WarnLargeStep, WarnInvalidTimestamp, WarnUnknownCurrency:
+		default:
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return types.NewRPCError(
+		types.ErrInvalidTrace,
+		fmt.Sprintf("unknown strict warning codes: %s", strings.Join(unknown, ", ")),
+		types.ErrTypeInvalidTrace,
+		false,
+		fmt.Sprintf("strict accepts %q or any of: %s.", StrictAll,
+			strings.Join([]string{WarnDeprecatedSchema, WarnMissingMetadata, WarnEmptySteps, WarnLargeStep, WarnInvalidTimestamp, WarnUnknownCurrency}, ", ")),
+	)
+}
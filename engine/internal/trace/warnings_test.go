@@ -0,0 +1,112 @@
+package trace
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+func TestWarnings(t *testing.T) {
+	big := json.RawMessage(`"` + strings.Repeat("x", LargeStepPayload) + `"`)
+	tests := []struct {
+		name   string
+		mutate func(tr *types.Trace)
+		want   []string
+	}{
+		{name: "clean", mutate: func(*types.Trace) {}},
+		{
+			name:   "deprecated schema",
+			mutate: func(tr *types.Trace) { tr.SchemaVersion = 0 },
+			want:   []string{WarnDeprecatedSchema},
+		},
+		{
+			name:   "missing metadata",
+			mutate: func(tr *types.Trace) { tr.Metadata = nil },
+			want:   []string{WarnMissingMetadata},
+		},
+		{
+			name:   "invalid timestamp",
+			mutate: func(tr *types.Trace) { ts := "18/02/2026"; tr.Metadata.Timestamp = &ts },
+			want:   []string{WarnInvalidTimestamp},
+		},
+		{
+			name:   "empty steps",
+			mutate: func(tr *types.Trace) { tr.Steps = nil },
+			want:   []string{WarnEmptySteps},
+		},
+		{
+			name:   "large step",
+			mutate: func(tr *types.Trace) { tr.Steps[0].Result = big },
+			want:   []string{WarnLargeStep},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := testTrace("root", types.Step{Type: types.StepTypeToolCall, Name: "lookup"})
+			tr.SchemaVersion = CurrentSchemaVersion
+			tr.Metadata = &types.TraceMetadata{}
+			tt.mutate(tr)
+			got := warningCodes(Warnings(tr, nil))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("warnings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnings_UsesScannedSizes(t *testing.T) {
+	tr := testTrace("root", types.Step{Type: types.StepTypeToolCall, Name: "lookup"})
+	tr.SchemaVersion = CurrentSchemaVersion
+	tr.Metadata = &types.TraceMetadata{}
+	ws := Warnings(tr, &ScanStats{StepSizes: []int{LargeStepPayload + 1}})
+	if len(ws) != 1 || ws[0].Code != WarnLargeStep || ws[0].Step != "lookup" {
+		t.Errorf("warnings = %+v, want one large_step_payload for lookup", ws)
+	}
+}
+
+func TestWarnings_FirstStepIndexIsSent(t *testing.T) {
+	tr := testTrace("root", types.Step{Type: types.StepTypeToolCall, Name: "lookup"})
+	tr.SchemaVersion = CurrentSchemaVersion
+	tr.Metadata = &types.TraceMetadata{}
+	ws := Warnings(tr, &ScanStats{StepSizes: []int{LargeStepPayload + 1}})
+	data, err := json.Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"step_index":0`) {
+		t.Errorf("warnings = %s, want step_index 0", data)
+	}
+
+	data, _ = json.Marshal(types.TraceWarning{Code: WarnMissingMetadata, TraceID: "trc_1"})
+	if strings.Contains(string(data), "step_index") {
+		t.Errorf("trace-level warning = %s, want no step_index", data)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	ws := []types.TraceWarning{{Code: WarnMissingMetadata, TraceID: "trc_1", Message: "no metadata"}}
+	if err := Promote(ws, nil); err != nil {
+		t.Errorf("lenient: got %v, want nil", err)
+	}
+	if err := Promote(ws, []string{WarnEmptySteps}); err != nil {
+		t.Errorf("unrelated code: got %v, want nil", err)
+	}
+	for _, strict := range [][]string{{WarnMissingMetadata}, {StrictAll}} {
+		err := Promote(ws, strict)
+		if err == nil || err.Code != types.ErrInvalidTrace {
+			t.Errorf("strict %v: got %v, want INVALID_TRACE", strict, err)
+		}
+	}
+}
+
+func TestCheckStrict(t *testing.T) {
+	if err := CheckStrict([]string{StrictAll, WarnLargeStep}); err != nil {
+		t.Errorf("known codes: got %v", err)
+	}
+	err := CheckStrict([]string{"empty_step"})
+	if err == nil || !strings.Contains(err.Message, "empty_step") {
+		t.Errorf("unknown code: got %v", err)
+	}
+}
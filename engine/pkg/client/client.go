@@ -0,0 +1,443 @@
+// Package client is a Go client for the attest engine protocol: JSON-RPC 2.0
+// over NDJSON, normally on the stdio of an attest-engine subprocess. It
+// performs the initialize handshake, exposes typed methods for the engine's
+// RPCs, retries retryable errors, answers engine-initiated calls such as
+// agent_invoke, and delivers notifications such as drift_alert to callbacks.
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// ProtocolVersion is the protocol version the client speaks.
+const ProtocolVersion = 1
+
+// maxLineSize bounds a response line; it matches the engine's default
+// response size limit plus room for the envelope.
+const maxLineSize = 16 << 20
+
+// CallHandler answers a request the engine sends to the client, such as
+// agent_invoke during run_simulation. A returned *types.RPCError is sent to
+// the engine as the JSON-RPC error.
+type CallHandler func(ctx context.Context, params json.RawMessage) (any, *types.RPCError)
+
+// Options configures a Client.
+type Options struct {
+	// Command is the engine binary Start runs; default "attest-engine".
+	Command string
+	// Args are passed to the engine, e.g. "--log-level", "debug".
+	Args []string
+	// Env is appended to the current environment of the engine process.
+	Env []string
+	// Stderr receives the engine's log output; nil discards it.
+	Stderr io.Writer
+
+	// SDKName and SDKVersion identify the client in initialize.
+	SDKName    string
+	SDKVersion string
+	// RequiredCapabilities are checked at initialize; Start and New fail
+	// with ErrIncompatible when the engine lacks any.
+	RequiredCapabilities []string
+	// Compress negotiates the json+gzip encoding.
+	Compress bool
+	// Namespace scopes the session's history, drift statistics, quarantine,
+	// and caches to a project; empty selects the default namespace.
+	Namespace string
+	// AuthToken authenticates to an engine started with ATTEST_AUTH_TOKENS.
+	AuthToken string
+
+	// MaxRetries is how many times a call failing with a retryable error is
+	// retried; 0 means no retries. RetryBackoff is the first delay, doubled
+	// on each retry; default 100ms.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// Handlers answer engine-initiated calls by method. Calls to other
+	// methods are answered with a method-not-found error.
+	Handlers map[string]CallHandler
+	// OnNotification receives engine notifications. It runs on the read
+	// loop, so it must not block on calls to the same Client.
+	OnNotification func(method string, params json.RawMessage)
+	// OnDeprecation receives each deprecation notice in a response's
+	// warnings, with the method of the call, so the SDK can show migration
+	// guidance before the deprecated form is removed.
+	OnDeprecation func(method string, d types.Deprecation)
+}
+
+// ErrIncompatible is returned when the engine lacks a required capability
+// or rejects the protocol version.
+var ErrIncompatible = errors.New("engine is incompatible")
+
+// ErrClosed is returned by calls made after the connection ended.
+var ErrClosed = errors.New("engine connection closed")
+
+// Error is a JSON-RPC error returned by the engine.
+type Error struct {
+	Method string
+	*types.RPCError
+}
+
+func (e *Error) Error() string {
+	if e.Data != nil && e.Data.Detail != "" {
+		return fmt.Sprintf("%s: engine error %d: %s: %s", e.Method, e.Code, e.Message, e.Data.Detail)
+	}
+	return fmt.Sprintf("%s: engine error %d: %s", e.Method, e.Code, e.Message)
+}
+
+// Retryable reports whether the engine marked the error retryable.
+func (e *Error) Retryable() bool {
+	return e.Data != nil && e.Data.Retryable
+}
+
+// Client is a connection to an attest engine. It is safe for concurrent use;
+// the engine answers requests in the order it processes them.
+type Client struct {
+	opts Options
+	info *types.InitializeResult
+
+	w       io.Writer
+	closer  io.Closer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *types.Response
+	err     error // set once the read loop ends
+	done    chan struct{}
+
+	cmd *exec.Cmd
+}
+
+// Start runs the engine binary as a subprocess, connects to its stdio, and
+// performs the initialize handshake.
+func Start(ctx context.Context, opts Options) (*Client, error) {
+	command := opts.Command
+	if command == "" {
+		command = "attest-engine"
+	}
+	cmd := exec.Command(command, opts.Args...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Stderr = opts.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start engine: %w", err)
+	}
+	c, err := connect(ctx, stdout, stdin, opts, cmd)
+	if err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	return c, nil
+}
+
+// New connects to an engine already reachable over r and w, such as a
+// socket, and performs the initialize handshake. Close closes w when it is
+// an io.Closer.
+func New(ctx context.Context, r io.Reader, w io.Writer, opts Options) (*Client, error) {
+	return connect(ctx, r, w, opts, nil)
+}
+
+func connect(ctx context.Context, r io.Reader, w io.Writer, opts Options, cmd *exec.Cmd) (*Client, error) {
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 100 * time.Millisecond
+	}
+	c := &Client{
+		opts:    opts,
+		w:       w,
+		pending: make(map[int64]chan *types.Response),
+		done:    make(chan struct{}),
+		cmd:     cmd,
+	}
+	if closer, ok := w.(io.Closer); ok {
+		c.closer = closer
+	}
+	go c.readLoop(r)
+
+	encoding := types.EncodingJSON
+	if opts.Compress {
+		encoding = types.EncodingJSONGzip
+	}
+	var info types.InitializeResult
+	err := c.call(ctx, "initialize", &types.InitializeParams{
+		SDKName:              opts.SDKName,
+		SDKVersion:           opts.SDKVersion,
+		ProtocolVersion:      ProtocolVersion,
+		RequiredCapabilities: opts.RequiredCapabilities,
+		PreferredEncoding:    encoding,
+		Namespace:            opts.Namespace,
+		AuthToken:            opts.AuthToken,
+	}, &info, false)
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) && rpcErr.Code == types.ErrSessionError {
+		return nil, fmt.Errorf("%w: %s", ErrIncompatible, rpcErr.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.Compatible {
+		return nil, fmt.Errorf("%w: missing capabilities %v", ErrIncompatible, info.Missing)
+	}
+	c.info = &info
+	return c, nil
+}
+
+// Info returns the engine's initialize result: its version, capabilities,
+// negotiated encoding, and trace limits.
+func (c *Client) Info() *types.InitializeResult {
+	return c.info
+}
+
+// Call sends a request and decodes its result into result, which may be
+// nil. Retryable engine errors are retried per Options.MaxRetries. Call
+// returns when ctx is done without waiting for the engine, whose late
+// response is dropped.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	return c.call(ctx, method, params, result, c.info != nil && c.info.Encoding == types.EncodingJSONGzip)
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any, gzipped bool) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal %s params: %w", method, err)
+	}
+	if gzipped && len(raw) > compressAbove {
+		if raw, err = encodeParams(raw); err != nil {
+			return fmt.Errorf("compress %s params: %w", method, err)
+		}
+	}
+
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.roundTrip(ctx, method, raw)
+		if err != nil {
+			return err
+		}
+		if c.opts.OnDeprecation != nil {
+			for _, d := range resp.Warnings {
+				c.opts.OnDeprecation(method, d)
+			}
+		}
+		if resp.Error != nil {
+			rpcErr := &Error{Method: method, RPCError: resp.Error}
+			if !rpcErr.Retryable() || attempt >= c.opts.MaxRetries {
+				return rpcErr
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %w", method, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+		if result == nil {
+			return nil
+		}
+		body := resp.Result
+		if gzipped {
+			if body, err = decodeResult(body); err != nil {
+				return fmt.Errorf("decompress %s result: %w", method, err)
+			}
+		}
+		if err := json.Unmarshal(body, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// roundTrip sends one request and waits for its response.
+func (c *Client) roundTrip(ctx context.Context, method string, params json.RawMessage) (*types.Response, error) {
+	ch := make(chan *types.Response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(&types.Request{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", method, ctx.Err())
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", method, c.closedErr())
+		}
+		return resp, nil
+	}
+}
+
+func (c *Client) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
+
+// message is any line the engine sends: a response to a client request, a
+// request of its own, or a notification.
+type message struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *types.RPCError `json:"error"`
+
+	Warnings []types.Deprecation `json:"warnings"`
+}
+
+func (c *Client) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		switch {
+		case msg.Method == "":
+			if msg.ID != nil {
+				c.deliver(&types.Response{JSONRPC: "2.0", ID: *msg.ID, Result: msg.Result, Error: msg.Error, Warnings: msg.Warnings})
+			}
+		case msg.ID == nil:
+			if c.opts.OnNotification != nil {
+				c.opts.OnNotification(msg.Method, msg.Params)
+			}
+		default:
+			// Answer engine-initiated calls concurrently: the engine is
+			// blocked in the handler that made the call.
+			go c.answer(*msg.ID, msg.Method, msg.Params)
+		}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = ErrClosed
+	}
+	c.mu.Lock()
+	c.err = err
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *Client) deliver(resp *types.Response) {
+	c.mu.Lock()
+	ch, ok := c.pending[resp.ID]
+	delete(c.pending, resp.ID)
+	c.mu.Unlock()
+	if ok {
+		ch <- resp
+	}
+}
+
+func (c *Client) answer(id int64, method string, params json.RawMessage) {
+	resp := &types.Response{JSONRPC: "2.0", ID: id}
+	h, ok := c.opts.Handlers[method]
+	if !ok {
+		resp.Error = &types.RPCError{
+			Code:    -32601,
+			Message: "method not found",
+			Data:    &types.ErrorData{ErrorType: "METHOD_NOT_FOUND", Detail: "unknown method: " + method},
+		}
+		c.write(resp)
+		return
+	}
+	result, rpcErr := h(context.Background(), params)
+	if rpcErr != nil {
+		resp.Error = rpcErr
+	} else if resp.Result, rpcErr = marshalResult(result); rpcErr != nil {
+		resp.Error = rpcErr
+	}
+	c.write(resp)
+}
+
+func marshalResult(result any) (json.RawMessage, *types.RPCError) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, types.NewRPCError(types.ErrEngineError, "failed to marshal result", types.ErrTypeEngineError, false, err.Error())
+	}
+	return raw, nil
+}
+
+func (c *Client) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return ErrClosed
+}
+
+// Close sends shutdown, closes the connection, and for a client made by
+// Start waits for the engine to exit, killing it after ctx is done.
+func (c *Client) Close(ctx context.Context) error {
+	var shutdownErr error
+	select {
+	case <-c.done:
+	default:
+		_, shutdownErr = c.Shutdown(ctx)
+	}
+	if c.closer != nil {
+		c.closer.Close()
+	}
+	if c.cmd == nil {
+		return shutdownErr
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- c.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		if shutdownErr != nil {
+			return shutdownErr
+		}
+		return err
+	case <-ctx.Done():
+		c.cmd.Process.Kill()
+		<-exited
+		return ctx.Err()
+	}
+}
+
+// Done is closed when the connection to the engine ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/server"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// startEngine runs an in-process engine on pipes and returns the client's
+// end of them. register adds handlers; nil registers the built-in ones.
+func startEngine(t *testing.T, register func(*server.Server)) (io.Reader, io.WriteCloser) {
+	t.Helper()
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	srv := server.New(stdinR, stdoutW, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if register == nil {
+		server.RegisterBuiltinHandlers(srv)
+	} else {
+		srv.RegisterHandler("initialize", func(context.Context, *server.Session, json.RawMessage) (any, *types.RPCError) {
+			return &types.InitializeResult{Compatible: true, Encoding: types.EncodingJSON}, nil
+		})
+		register(srv)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		srv.Run(ctx)
+		stdoutW.Close()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		stdinW.Close()
+	})
+	return stdoutR, stdinW
+}
+
+func TestClient_EvaluateBatch(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	r, w := startEngine(t, nil)
+	ctx := context.Background()
+	var deprecations []types.Deprecation
+	c, err := New(ctx, r, w, Options{
+		SDKName:              "attest-go-test",
+		RequiredCapabilities: []string{"layers_1_4"},
+		Compress:             true,
+		OnDeprecation: func(method string, d types.Deprecation) {
+			if method == "evaluate_batch" {
+				deprecations = append(deprecations, d)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if c.Info().Encoding != types.EncodingJSONGzip {
+		t.Errorf("Encoding = %q, want %q", c.Info().Encoding, types.EncodingJSONGzip)
+	}
+
+	result, err := c.EvaluateBatch(ctx, &types.EvaluateBatchParams{
+		Trace: types.Trace{TraceID: "trc_client", Output: json.RawMessage(`{"message":"hello"}`)},
+		Assertions: []types.Assertion{{
+			AssertionID: "a1",
+			Type:        types.TypeContent,
+			Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"hello"}`),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Status != types.StatusPass {
+		t.Errorf("results = %+v, want one pass", result.Results)
+	}
+	// The trace has no schema_version, which is read as the deprecated 1.
+	if len(deprecations) != 1 || deprecations[0].Code != "deprecated_schema_version" || deprecations[0].SunsetVersion == "" {
+		t.Errorf("deprecations = %+v, want one deprecated_schema_version notice", deprecations)
+	}
+
+	_, err = c.EvaluateBatch(ctx, &types.EvaluateBatchParams{Trace: types.Trace{TraceID: "trc_bad"}})
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != types.ErrInvalidTrace {
+		t.Errorf("invalid trace: err = %v, want INVALID_TRACE", err)
+	}
+
+	if err := c.Close(ctx); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestClient_IncompatibleEngine(t *testing.T) {
+	t.Setenv("ATTEST_CACHE_MODE", "memory")
+	r, w := startEngine(t, nil)
+	_, err := New(context.Background(), r, w, Options{RequiredCapabilities: []string{"teleportation"}})
+	if !errors.Is(err, ErrIncompatible) {
+		t.Errorf("New = %v, want ErrIncompatible", err)
+	}
+}
+
+func TestClient_CallsNotificationsAndRetries(t *testing.T) {
+	var failures atomic.Int32
+	r, w := startEngine(t, func(srv *server.Server) {
+		srv.RegisterHandler("ask", func(ctx context.Context, _ *server.Session, _ json.RawMessage) (any, *types.RPCError) {
+			if failures.Add(1) == 1 {
+				return nil, types.NewRPCError(types.ErrTimeout, "busy", types.ErrTypeTimeout, true, "")
+			}
+			srv.Notify("progress", map[string]int{"done": 1})
+			var answer types.AgentInvokeResult
+			if err := srv.Call(ctx, "agent_invoke", &types.AgentInvokeParams{Message: "hi"}, &answer); err != nil {
+				return nil, types.NewRPCError(types.ErrEngineError, err.Error(), types.ErrTypeEngineError, false, "")
+			}
+			return &answer, nil
+		})
+	})
+
+	notified := make(chan string, 1)
+	c, err := New(context.Background(), r, w, Options{
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		Handlers: map[string]CallHandler{
+			"agent_invoke": func(_ context.Context, params json.RawMessage) (any, *types.RPCError) {
+				var p types.AgentInvokeParams
+				json.Unmarshal(params, &p)
+				return &types.AgentInvokeResult{Response: "echo: " + p.Message}, nil
+			},
+		},
+		OnNotification: func(method string, _ json.RawMessage) { notified <- method },
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var answer types.AgentInvokeResult
+	if err := c.Call(context.Background(), "ask", struct{}{}, &answer); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if answer.Response != "echo: hi" {
+		t.Errorf("answer = %q, want %q", answer.Response, "echo: hi")
+	}
+	if failures.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2 (one retry)", failures.Load())
+	}
+	select {
+	case method := <-notified:
+		if method != "progress" {
+			t.Errorf("notification = %q, want progress", method)
+		}
+	case <-time.After(time.Second):
+		t.Error("no notification delivered")
+	}
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+)
+
+// compressAbove is the params size, in bytes, above which params are sent
+// compressed once json+gzip is negotiated; smaller params are sent as is.
+const compressAbove = 64 << 10
+
+// encodeParams gzips JSON params into a JSON string of base64.
+func encodeParams(raw []byte) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// decodeResult expands a result sent as a JSON string of gzipped base64.
+func decodeResult(raw json.RawMessage) (json.RawMessage, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// evaluateBatchRequest sends trace only when it is the evaluated trace: the
+// engine rejects trace alongside traces or trace_ref.
+type evaluateBatchRequest struct {
+	Trace *types.Trace `json:"trace,omitempty"`
+	*types.EvaluateBatchParams
+}
+
+// EvaluateBatch evaluates assertions against a trace.
+func (c *Client) EvaluateBatch(ctx context.Context, p *types.EvaluateBatchParams) (*types.EvaluateBatchResult, error) {
+	req := evaluateBatchRequest{EvaluateBatchParams: p}
+	if p.TraceRef == "" && len(p.Traces) == 0 {
+		req.Trace = &p.Trace
+	}
+	var result types.EvaluateBatchResult
+	if err := c.Call(ctx, "evaluate_batch", &req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EvaluateDataset evaluates assertions against every trace in a JSONL file
+// on the engine's filesystem.
+func (c *Client) EvaluateDataset(ctx context.Context, p *types.EvaluateDatasetParams) (*types.EvaluateDatasetResult, error) {
+	var result types.EvaluateDatasetResult
+	if err := c.Call(ctx, "evaluate_dataset", p, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ValidateTraceTree validates a trace tree and reports its aggregates,
+// size, and warnings.
+func (c *Client) ValidateTraceTree(ctx context.Context, p *types.ValidateTraceTreeParams) (*types.ValidateTraceTreeResult, error) {
+	var result types.ValidateTraceTreeResult
+	if err := c.Call(ctx, "validate_trace_tree", p, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// QueryDrift reports score drift for the given assertions.
+func (c *Client) QueryDrift(ctx context.Context, p *types.QueryDriftParams) (*types.QueryDriftResult, error) {
+	var result types.QueryDriftResult
+	if err := c.Call(ctx, "query_drift", p, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// QueryFlaky reports the pass/fail stability of assertions.
+func (c *Client) QueryFlaky(ctx context.Context, p *types.QueryFlakyParams) (*types.QueryFlakyResult, error) {
+	var result types.QueryFlakyResult
+	if err := c.Call(ctx, "query_flaky", p, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetMetrics returns the engine's counters.
+func (c *Client) GetMetrics(ctx context.Context) (*types.GetMetricsResult, error) {
+	var result types.GetMetricsResult
+	if err := c.Call(ctx, "get_metrics", struct{}{}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DescribeCapabilities returns the engine's feature matrix: assertion types
+// with their checks and operators, limits, providers, and callable methods.
+func (c *Client) DescribeCapabilities(ctx context.Context) (*types.DescribeCapabilitiesResult, error) {
+	var result types.DescribeCapabilitiesResult
+	if err := c.Call(ctx, "describe_capabilities", struct{}{}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UploadTrace uploads the JSON of a trace in chunks and returns the
+// trace_ref to evaluate it by, for traces too large for one request line.
+func (c *Client) UploadTrace(ctx context.Context, raw []byte) (*types.EndTraceResult, error) {
+	var begin types.BeginTraceResult
+	if err := c.Call(ctx, "begin_trace", &types.BeginTraceParams{SizeBytes: len(raw)}, &begin); err != nil {
+		return nil, err
+	}
+	for i, start := 0, 0; start < len(raw); i, start = i+1, start+begin.MaxChunkBytes {
+		chunk := raw[start:min(start+begin.MaxChunkBytes, len(raw))]
+		sum := sha256.Sum256(chunk)
+		p := &types.AppendTraceChunkParams{UploadID: begin.UploadID, Index: i, Data: chunk, SHA256: hex.EncodeToString(sum[:])}
+		if err := c.Call(ctx, "append_trace_chunk", p, nil); err != nil {
+			return nil, err
+		}
+	}
+	sum := sha256.Sum256(raw)
+	var end types.EndTraceResult
+	if err := c.Call(ctx, "end_trace", &types.EndTraceParams{UploadID: begin.UploadID, SHA256: hex.EncodeToString(sum[:])}, &end); err != nil {
+		return nil, err
+	}
+	return &end, nil
+}
+
+// Shutdown asks the engine to drain and exit. Close calls it.
+func (c *Client) Shutdown(ctx context.Context) (*types.ShutdownResult, error) {
+	var result types.ShutdownResult
+	if err := c.Call(ctx, "shutdown", struct{}{}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,335 @@
+// Package conformance checks that an SDK speaks the attest engine protocol
+// correctly. It stands in for the engine: it runs an SDK driver program once
+// per scenario, connected to the driver's stdin and stdout exactly as a real
+// engine would be, scripts the engine's side of the exchange, and records
+// every deviation from the protocol spec.
+//
+// A driver is a small program built on the SDK under test. For every
+// scenario it must:
+//
+//  1. Send initialize with required_capabilities ["layers_1_4"]. If the
+//     engine reports compatible false, exit without sending anything else.
+//  2. Evaluate a trace twice, one evaluate_batch after the other, each with
+//     a single content assertion. The trace's output.message is padded to at
+//     least ATTEST_CONFORMANCE_TRACE_BYTES bytes (0 when unset). Each call
+//     gives up after ATTEST_CONFORMANCE_TIMEOUT_MS milliseconds (default
+//     5000); a failed or abandoned call does not stop the driver.
+//  3. Send shutdown and exit 0.
+//
+// The scenario name is in ATTEST_CONFORMANCE_SCENARIO. The driver must
+// answer engine-initiated requests such as agent_invoke, and honor the
+// limits advertised at initialize: a trace larger than max_trace_size_bytes
+// is uploaded with begin_trace, append_trace_chunk, and end_trace, or
+// rejected without being sent.
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Options configures Run.
+type Options struct {
+	// Command is the driver program and its arguments.
+	Command []string
+	// Env is appended to the current environment of the driver.
+	Env []string
+	// Scenarios names the scenarios to run; empty runs all of them.
+	Scenarios []string
+	// Timeout bounds each wait for the driver; default 10s.
+	Timeout time.Duration
+	// Stderr receives the driver's stderr; nil discards it.
+	Stderr io.Writer
+}
+
+// Result is the outcome of one scenario.
+type Result struct {
+	Scenario   string   `json:"scenario"`
+	Passed     bool     `json:"passed"`
+	Failures   []string `json:"failures,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// Report holds the results of a Run, in scenario order.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Passed reports whether every scenario passed.
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if !res.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs the driver through each selected scenario. It returns an error
+// only when a scenario is unknown or the driver cannot be started;
+// protocol violations are reported in the Report.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if len(opts.Command) == 0 {
+		return nil, fmt.Errorf("conformance: no driver command")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	selected, err := selectScenarios(opts.Scenarios)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	for _, sc := range selected {
+		start := time.Now()
+		p, err := startPeer(ctx, opts, sc)
+		if err != nil {
+			return nil, err
+		}
+		sc.run(p)
+		p.stop()
+		report.Results = append(report.Results, Result{
+			Scenario:   sc.Name,
+			Passed:     len(p.failures) == 0,
+			Failures:   p.failures,
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+	}
+	return report, nil
+}
+
+func selectScenarios(names []string) ([]Scenario, error) {
+	if len(names) == 0 {
+		return scenarios, nil
+	}
+	var selected []Scenario
+	for _, name := range names {
+		found := false
+		for _, sc := range scenarios {
+			if sc.Name == name {
+				selected = append(selected, sc)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("conformance: unknown scenario %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// message is one line sent by the driver.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Result  json.RawMessage `json:"result"`
+	Error   *types.RPCError `json:"error"`
+	raw     []byte
+}
+
+// peer is the harness's end of one driver run.
+type peer struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan []byte
+	exited   chan struct{} // closed once the driver exited; exitErr is then set
+	exitErr  error
+	timeout  time.Duration
+	failures []string
+	seenIDs  map[int64]bool
+	nextID   int64
+}
+
+func startPeer(ctx context.Context, opts Options, sc Scenario) (*peer, error) {
+	cmd := exec.CommandContext(ctx, opts.Command[0], opts.Command[1:]...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Env = append(cmd.Env,
+		"ATTEST_CONFORMANCE_SCENARIO="+sc.Name,
+		"ATTEST_CONFORMANCE_TRACE_BYTES="+strconv.Itoa(sc.traceBytes),
+		"ATTEST_CONFORMANCE_TIMEOUT_MS="+strconv.Itoa(sc.callTimeoutMS),
+	)
+	cmd.Stderr = opts.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("conformance: start driver: %w", err)
+	}
+	p := &peer{
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan []byte),
+		exited:  make(chan struct{}),
+		timeout: opts.Timeout,
+		seenIDs: make(map[int64]bool),
+	}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 64<<20)
+		for scanner.Scan() {
+			p.lines <- bytes.Clone(scanner.Bytes())
+		}
+		close(p.lines)
+		p.exitErr = cmd.Wait()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+func (p *peer) fail(format string, args ...any) {
+	p.failures = append(p.failures, fmt.Sprintf(format, args...))
+}
+
+// next returns the driver's next line, checking its framing and envelope.
+// It returns nil when the driver closed stdout or did not write in time.
+func (p *peer) next(waitingFor string) *message {
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			p.fail("driver closed its output while the harness waited for %s", waitingFor)
+			return nil
+		}
+		return p.parse(line)
+	case <-time.After(p.timeout):
+		p.fail("timed out after %s waiting for %s", p.timeout, waitingFor)
+		return nil
+	}
+}
+
+func (p *peer) parse(line []byte) *message {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, line); err != nil {
+		p.fail("line is not valid JSON: %v: %.80s", err, line)
+		return &message{raw: line}
+	}
+	if !bytes.Equal(compact.Bytes(), line) {
+		p.fail("line is not compact JSON: %.80s", line)
+	}
+	var m message
+	if err := json.Unmarshal(line, &m); err != nil {
+		p.fail("line is not a JSON-RPC object: %v", err)
+		return &message{raw: line}
+	}
+	m.raw = line
+	if m.JSONRPC != "2.0" {
+		p.fail("%s: jsonrpc is %q, want \"2.0\"", m.describe(), m.JSONRPC)
+	}
+	if m.Method != "" {
+		switch {
+		case m.ID == nil:
+			p.fail("request %s has no id; the engine does not accept notifications", m.Method)
+		case p.seenIDs[*m.ID]:
+			p.fail("request %s reuses id %d", m.Method, *m.ID)
+		default:
+			p.seenIDs[*m.ID] = true
+		}
+	}
+	return &m
+}
+
+func (m *message) describe() string {
+	if m.Method != "" {
+		return "request " + m.Method
+	}
+	if m.ID != nil {
+		return fmt.Sprintf("response to id %d", *m.ID)
+	}
+	return "message"
+}
+
+func (p *peer) send(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		p.fail("harness: marshal: %v", err)
+		return
+	}
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		p.fail("write to driver: %v", err)
+	}
+}
+
+func (p *peer) respond(id int64, result any) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		p.fail("harness: marshal result: %v", err)
+		return
+	}
+	p.send(&types.Response{JSONRPC: "2.0", ID: id, Result: raw})
+}
+
+func (p *peer) respondError(id int64, rpcErr *types.RPCError) {
+	p.send(&types.Response{JSONRPC: "2.0", ID: id, Error: rpcErr})
+}
+
+func (p *peer) notify(method string, params any) {
+	p.send(&types.Notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// call sends an engine-initiated request and returns its id.
+func (p *peer) call(method string, params any) int64 {
+	raw, _ := json.Marshal(params)
+	p.nextID++
+	p.send(&types.Request{JSONRPC: "2.0", ID: p.nextID, Method: method, Params: raw})
+	return p.nextID
+}
+
+// expectExit waits for the driver to exit and checks its exit status when
+// wantZero is set.
+func (p *peer) expectExit(wantZero bool) {
+	deadline := time.After(p.timeout)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if ok {
+				m := p.parse(line)
+				p.fail("unexpected %s after shutdown", m.describe())
+				continue
+			}
+			select {
+			case <-p.exited:
+				if wantZero && p.exitErr != nil {
+					p.fail("driver exited with %v, want status 0", p.exitErr)
+				}
+			case <-deadline:
+				p.fail("driver did not exit within %s", p.timeout)
+			}
+			return
+		case <-deadline:
+			p.fail("driver did not exit within %s", p.timeout)
+			return
+		}
+	}
+}
+
+// stop ends the driver if it is still running.
+func (p *peer) stop() {
+	p.stdin.Close()
+	go func() {
+		for range p.lines {
+		}
+	}()
+	select {
+	case <-p.exited:
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		<-p.exited
+	}
+}
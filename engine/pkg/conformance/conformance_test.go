@@ -0,0 +1,125 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attest-ai/attest/engine/pkg/client"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// TestMain turns the test binary into a driver built on pkg/client when the
+// harness runs it, so the Go client is held to the same conformance suite
+// as every other SDK.
+func TestMain(m *testing.M) {
+	if os.Getenv("ATTEST_CONFORMANCE_SCENARIO") != "" {
+		os.Exit(runDriver())
+	}
+	os.Exit(m.Run())
+}
+
+func runDriver() int {
+	ctx := context.Background()
+	c, err := client.New(ctx, os.Stdin, os.Stdout, client.Options{
+		SDKName:              "attest-go",
+		SDKVersion:           "conformance",
+		RequiredCapabilities: []string{"layers_1_4"},
+		Handlers: map[string]client.CallHandler{
+			"agent_invoke": func(ctx context.Context, params json.RawMessage) (any, *types.RPCError) {
+				return &types.AgentInvokeResult{Response: "pong"}, nil
+			},
+		},
+	})
+	if errors.Is(err, client.ErrIncompatible) {
+		return 1
+	}
+	if err != nil {
+		return 2
+	}
+
+	traceBytes, _ := strconv.Atoi(os.Getenv("ATTEST_CONFORMANCE_TRACE_BYTES"))
+	timeoutMS, _ := strconv.Atoi(os.Getenv("ATTEST_CONFORMANCE_TIMEOUT_MS"))
+	if timeoutMS <= 0 {
+		timeoutMS = 5000
+	}
+	output, _ := json.Marshal(map[string]string{"message": strings.Repeat("x", traceBytes)})
+	tr := types.Trace{SchemaVersion: 1, TraceID: "trc_conformance", Output: output, Steps: []types.Step{}}
+	raw, _ := json.Marshal(&tr)
+
+	for i := 0; i < 2; i++ {
+		callCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutMS)*time.Millisecond)
+		params := &types.EvaluateBatchParams{
+			Trace: tr,
+			Assertions: []types.Assertion{{
+				AssertionID: "assert_conformance_" + strconv.Itoa(i),
+				Type:        "content",
+				Spec:        json.RawMessage(`{"target":"output.message","check":"contains","value":"x"}`),
+			}},
+		}
+		if len(raw) > c.Info().MaxTraceSizeBytes {
+			end, err := c.UploadTrace(callCtx, raw)
+			if err != nil {
+				cancel()
+				continue
+			}
+			params.TraceRef = end.TraceRef
+		}
+		c.EvaluateBatch(callCtx, params)
+		cancel()
+	}
+
+	if err := c.Close(ctx); err != nil {
+		return 2
+	}
+	return 0
+}
+
+func TestRun_GoClientConforms(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a driver subprocess per scenario")
+	}
+	report, err := Run(context.Background(), Options{
+		Command: []string{os.Args[0]},
+		Timeout: 5 * time.Second,
+		Stderr:  os.Stderr,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Results) != len(Scenarios()) {
+		t.Fatalf("got %d results, want %d", len(report.Results), len(Scenarios()))
+	}
+	for _, res := range report.Results {
+		if !res.Passed {
+			t.Errorf("scenario %s failed:\n  %s", res.Scenario, strings.Join(res.Failures, "\n  "))
+		}
+	}
+}
+
+func TestRun_UnknownScenario(t *testing.T) {
+	_, err := Run(context.Background(), Options{Command: []string{os.Args[0]}, Scenarios: []string{"nope"}})
+	if err == nil || !strings.Contains(err.Error(), `unknown scenario "nope"`) {
+		t.Fatalf("err = %v, want unknown scenario", err)
+	}
+}
+
+func TestRun_ReportsViolations(t *testing.T) {
+	// A driver that writes garbage and exits fails the handshake.
+	report, err := Run(context.Background(), Options{
+		Command:   []string{"sh", "-c", "echo 'not json'"},
+		Scenarios: []string{"handshake"},
+		Timeout:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Passed() {
+		t.Fatal("report passed for a driver that does not speak the protocol")
+	}
+}
@@ -0,0 +1,381 @@
+package conformance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/attest-ai/attest/engine/internal/trace"
+	"github.com/attest-ai/attest/engine/pkg/types"
+)
+
+// Scenario is one scripted protocol exchange.
+type Scenario struct {
+	Name        string
+	Description string
+	// traceBytes and callTimeoutMS are passed to the driver.
+	traceBytes    int
+	callTimeoutMS int
+	run           func(p *peer)
+}
+
+// Scenarios returns every scenario, in the order Run runs them.
+func Scenarios() []Scenario {
+	return scenarios
+}
+
+var scenarios = []Scenario{
+	{
+		Name:          "handshake",
+		Description:   "initialize first with well-formed params, unique ids, compact NDJSON, shutdown last, exit 0",
+		callTimeoutMS: 5000,
+		run: func(p *peer) {
+			if !p.handshake(defaultInit()) {
+				return
+			}
+			if n := p.serve(engineScript{}); n != 2 {
+				p.fail("driver sent %d evaluate_batch requests, want 2", n)
+			}
+		},
+	},
+	{
+		Name:          "incompatible",
+		Description:   "stop without evaluating when initialize reports compatible false",
+		callTimeoutMS: 5000,
+		run: func(p *peer) {
+			init := defaultInit()
+			init.Capabilities = []string{}
+			init.Missing = []string{"layers_1_4"}
+			init.Compatible = false
+			if !p.handshake(init) {
+				return
+			}
+			p.drainUntilExit(func(m *message) {
+				if m.Method == "shutdown" {
+					p.respond(*m.ID, &types.ShutdownResult{})
+					return
+				}
+				p.fail("driver sent %s after an incompatible initialize", m.describe())
+			})
+		},
+	},
+	{
+		Name:          "errors",
+		Description:   "surface a non-retryable INVALID_TRACE error and keep the session usable",
+		callTimeoutMS: 5000,
+		run: func(p *peer) {
+			if !p.handshake(defaultInit()) {
+				return
+			}
+			n := p.serve(engineScript{evaluate: func(p *peer, id int64, n int, assertions []string) {
+				if n > 0 {
+					p.respond(id, passResult(assertions))
+					return
+				}
+				p.respondError(id, types.NewRPCError(
+					types.ErrInvalidTrace,
+					"trace missing required field: trace_id",
+					types.ErrTypeInvalidTrace,
+					false,
+					"Every trace must include a non-empty trace_id string.",
+				))
+			}})
+			if n != 2 {
+				p.fail("driver sent %d evaluate_batch requests, want 2: it must continue after an error", n)
+			}
+		},
+	},
+	{
+		Name:          "notifications",
+		Description:   "accept notifications without answering them and answer engine-initiated agent_invoke calls",
+		callTimeoutMS: 5000,
+		run: func(p *peer) {
+			if !p.handshake(defaultInit()) {
+				return
+			}
+			p.serve(engineScript{evaluate: func(p *peer, id int64, n int, assertions []string) {
+				p.notify("drift_alert", &types.DriftAlert{DriftReport: types.DriftReport{AssertionID: assertions[0], Status: "drift_detected"}, TraceID: "trc_conformance"})
+				callID := p.call("agent_invoke", &types.AgentInvokeParams{SimulationID: "conformance", Turn: n + 1, Message: "ping"})
+				m := p.next("the agent_invoke response")
+				switch {
+				case m == nil:
+					return
+				case m.Method != "":
+					p.fail("driver sent %s instead of answering agent_invoke", m.describe())
+				case m.ID == nil || *m.ID != callID:
+					p.fail("agent_invoke response has id %v, want %d", m.ID, callID)
+				case (m.Result == nil) == (m.Error == nil):
+					p.fail("agent_invoke response must carry exactly one of result and error")
+				}
+				p.respond(id, passResult(assertions))
+			}})
+		},
+	},
+	{
+		Name:          "limits",
+		Description:   "never send a trace over the advertised max_trace_size_bytes inline; upload it in chunks or reject it",
+		traceBytes:    8192,
+		callTimeoutMS: 5000,
+		run: func(p *peer) {
+			init := defaultInit()
+			init.MaxTraceSizeBytes = 4096
+			if !p.handshake(init) {
+				return
+			}
+			p.serve(engineScript{maxTraceSize: init.MaxTraceSizeBytes})
+		},
+	},
+	{
+		Name:          "cancellation",
+		Description:   "abandon an unanswered call after the driver's timeout and ignore its late response",
+		callTimeoutMS: 300,
+		run: func(p *peer) {
+			if !p.handshake(defaultInit()) {
+				return
+			}
+			var abandoned int64
+			var started time.Time
+			n := p.serve(engineScript{evaluate: func(p *peer, id int64, n int, assertions []string) {
+				if n == 0 {
+					abandoned, started = id, time.Now()
+					return
+				}
+				// Allow for the time the request took to arrive.
+				if n == 1 && time.Since(started) < 250*time.Millisecond {
+					p.fail("driver gave up on evaluate_batch after %s, before its 300ms timeout", time.Since(started).Round(time.Millisecond))
+				}
+				// Answer the abandoned call late, then this one.
+				p.respond(abandoned, passResult(assertions))
+				p.respond(id, passResult(assertions))
+			}})
+			if n != 2 {
+				p.fail("driver sent %d evaluate_batch requests, want 2: it must continue after a timeout", n)
+			}
+		},
+	},
+}
+
+func defaultInit() types.InitializeResult {
+	return types.InitializeResult{
+		EngineVersion:         "conformance",
+		ProtocolVersion:       1,
+		Capabilities:          []string{"layers_1_4"},
+		Missing:               []string{},
+		Compatible:            true,
+		Encoding:              types.EncodingJSON,
+		MaxConcurrentRequests: 1,
+		MaxTraceSizeBytes:     trace.DefaultLimits.MaxTraceSize,
+		MaxStepsPerTrace:      trace.DefaultLimits.MaxStepsPerTrace,
+		MaxStepPayloadBytes:   trace.DefaultLimits.MaxStepPayload,
+		MaxSubTraceDepth:      trace.DefaultLimits.MaxSubTraceDepth,
+	}
+}
+
+// handshake expects initialize as the driver's first request, checks its
+// params, and answers with result.
+func (p *peer) handshake(result types.InitializeResult) bool {
+	m := p.next("initialize")
+	if m == nil {
+		return false
+	}
+	if m.Method != "initialize" || m.ID == nil {
+		p.fail("first message is %s, want request initialize", m.describe())
+		return false
+	}
+	var params types.InitializeParams
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		p.fail("initialize params: %v", err)
+	}
+	if params.ProtocolVersion != 1 {
+		p.fail("initialize protocol_version is %d, want 1", params.ProtocolVersion)
+	}
+	if params.SDKName == "" || params.SDKVersion == "" {
+		p.fail("initialize must set sdk_name and sdk_version")
+	}
+	if params.PreferredEncoding == "" {
+		p.fail("initialize must set preferred_encoding")
+	}
+	if !bytes.Contains(m.Params, []byte(`"required_capabilities":[`)) {
+		p.fail("initialize must send required_capabilities as an array")
+	}
+	p.respond(*m.ID, result)
+	return true
+}
+
+// engineScript customizes how serve plays the engine.
+type engineScript struct {
+	// evaluate answers the n-th evaluate_batch (from 0) with request id id;
+	// nil answers every one with pass results.
+	evaluate func(p *peer, id int64, n int, assertions []string)
+	// maxTraceSize is the inline trace size the driver must stay within; 0
+	// means the default limit.
+	maxTraceSize int
+}
+
+// serve answers the driver's requests until it sends shutdown, then expects
+// it to exit 0. It returns the number of evaluate_batch requests.
+func (p *peer) serve(s engineScript) int {
+	if s.maxTraceSize == 0 {
+		s.maxTraceSize = trace.DefaultLimits.MaxTraceSize
+	}
+	up := &harnessUpload{refs: make(map[string]bool)}
+	evaluations := 0
+	for {
+		m := p.next("the next request")
+		if m == nil {
+			return evaluations
+		}
+		if m.Method == "" {
+			p.fail("unexpected %s: the harness made no call", m.describe())
+			continue
+		}
+		if m.ID == nil {
+			continue
+		}
+		id := *m.ID
+		switch m.Method {
+		case "shutdown":
+			p.respond(id, &types.ShutdownResult{})
+			p.expectExit(true)
+			return evaluations
+		case "evaluate_batch":
+			assertions, ok := p.checkEvaluateBatch(m, s.maxTraceSize, up)
+			if !ok {
+				p.respondError(id, types.NewRPCError(types.ErrInvalidTrace, "rejected by conformance harness", types.ErrTypeInvalidTrace, false, ""))
+			} else if s.evaluate != nil {
+				s.evaluate(p, id, evaluations, assertions)
+			} else {
+				p.respond(id, passResult(assertions))
+			}
+			evaluations++
+		case "begin_trace", "append_trace_chunk", "end_trace":
+			up.handle(p, m)
+		default:
+			p.respondError(id, &types.RPCError{
+				Code:    -32601,
+				Message: "method not found",
+				Data:    &types.ErrorData{ErrorType: "METHOD_NOT_FOUND", Detail: "unknown method: " + m.Method},
+			})
+		}
+	}
+}
+
+// drainUntilExit passes each remaining line to handle until the driver exits.
+func (p *peer) drainUntilExit(handle func(m *message)) {
+	deadline := time.After(p.timeout)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return
+			}
+			if m := p.parse(line); m.Method != "" && m.ID != nil {
+				handle(m)
+			}
+		case <-deadline:
+			p.fail("driver did not exit within %s", p.timeout)
+			return
+		}
+	}
+}
+
+// checkEvaluateBatch checks evaluate_batch params and returns the IDs of
+// their assertions.
+func (p *peer) checkEvaluateBatch(m *message, maxTraceSize int, up *harnessUpload) ([]string, bool) {
+	var params struct {
+		Trace      json.RawMessage   `json:"trace"`
+		TraceRef   string            `json:"trace_ref"`
+		Assertions []types.Assertion `json:"assertions"`
+	}
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		p.fail("evaluate_batch params: %v", err)
+		return nil, false
+	}
+	if len(params.Assertions) == 0 {
+		p.fail("evaluate_batch has no assertions")
+		return nil, false
+	}
+	var ids []string
+	for _, a := range params.Assertions {
+		ids = append(ids, a.AssertionID)
+	}
+	switch {
+	case params.TraceRef != "":
+		if !up.refs[params.TraceRef] {
+			p.fail("evaluate_batch trace_ref %q was not returned by end_trace", params.TraceRef)
+			return nil, false
+		}
+		if len(params.Trace) > 0 && string(params.Trace) != "null" {
+			p.fail("evaluate_batch sets both trace and trace_ref")
+			return nil, false
+		}
+	case len(params.Trace) > maxTraceSize:
+		p.fail("evaluate_batch sent a %d-byte trace inline, over the advertised max_trace_size_bytes %d", len(params.Trace), maxTraceSize)
+		return nil, false
+	}
+	return ids, true
+}
+
+func passResult(assertions []string) *types.EvaluateBatchResult {
+	result := &types.EvaluateBatchResult{Results: []types.AssertionResult{}}
+	for _, id := range assertions {
+		result.Results = append(result.Results, types.AssertionResult{AssertionID: id, Status: types.StatusPass, Score: 1})
+	}
+	return result
+}
+
+// harnessUpload plays the engine's side of a chunked trace upload.
+type harnessUpload struct {
+	id     string
+	buf    bytes.Buffer
+	chunks int
+	refs   map[string]bool
+}
+
+func (u *harnessUpload) handle(p *peer, m *message) {
+	id := *m.ID
+	switch m.Method {
+	case "begin_trace":
+		u.id = fmt.Sprintf("upl_%d", len(u.refs)+1)
+		u.buf.Reset()
+		u.chunks = 0
+		p.respond(id, &types.BeginTraceResult{UploadID: u.id, MaxChunkBytes: 2048, MaxTraceSizeBytes: trace.DefaultLimits.MaxTraceSize})
+	case "append_trace_chunk":
+		var params types.AppendTraceChunkParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			p.fail("append_trace_chunk params: %v", err)
+		}
+		sum := sha256.Sum256(params.Data)
+		switch {
+		case params.UploadID != u.id:
+			p.fail("append_trace_chunk upload_id %q, want %q", params.UploadID, u.id)
+		case params.Index != u.chunks:
+			p.fail("append_trace_chunk index %d, want %d", params.Index, u.chunks)
+		case !strings.EqualFold(params.SHA256, hex.EncodeToString(sum[:])):
+			p.fail("append_trace_chunk %d sha256 does not match its data", params.Index)
+		case len(params.Data) > 2048:
+			p.fail("append_trace_chunk %d has %d bytes, over max_chunk_bytes 2048", params.Index, len(params.Data))
+		}
+		u.buf.Write(params.Data)
+		u.chunks++
+		p.respond(id, &types.AppendTraceChunkResult{ReceivedBytes: u.buf.Len(), Chunks: u.chunks})
+	case "end_trace":
+		var params types.EndTraceParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			p.fail("end_trace params: %v", err)
+		}
+		sum := sha256.Sum256(u.buf.Bytes())
+		if !strings.EqualFold(params.SHA256, hex.EncodeToString(sum[:])) {
+			p.fail("end_trace sha256 does not match the uploaded chunks")
+		}
+		if !json.Valid(u.buf.Bytes()) {
+			p.fail("uploaded trace is not valid JSON")
+		}
+		u.refs[u.id] = true
+		p.respond(id, &types.EndTraceResult{TraceRef: u.id, SizeBytes: u.buf.Len(), Chunks: u.chunks})
+	}
+}
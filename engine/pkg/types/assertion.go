@@ -6,6 +6,13 @@ const (
 	StatusPass     = "pass"
 	StatusSoftFail = "soft_fail"
 	StatusHardFail = "hard_fail"
+	// StatusSkipped marks an assertion that was not evaluated; SkipReason
+	// says why.
+	StatusSkipped = "skipped"
+
+	// SkipSampledOut is the SkipReason of an assertion left out by its
+	// sample_rate.
+	SkipSampledOut = "sampled_out"
 
 	TypeSchema     = "schema"
 	TypeConstraint = "constraint"
@@ -14,6 +21,16 @@ const (
 	TypeEmbedding  = "embedding"
 	TypeLLMJudge   = "llm_judge"
 	TypeTraceTree  = "trace_tree"
+	TypeTemporal   = "temporal"
+	TypeExpression = "expression"
+	TypeComposite  = "composite"
+	TypeTranscript = "transcript"
+
+	TypePersonaConsistency = "persona_consistency"
+	TypeReferenceMatch     = "reference_match"
+	TypeConsistency        = "consistency"
+	TypeCodeValid          = "code_valid"
+	TypeSQLSafe            = "sql_safe"
 )
 
 // Assertion defines an assertion to evaluate against a trace.
@@ -22,6 +39,37 @@ type Assertion struct {
 	Type        string          `json:"type"`
 	Spec        json.RawMessage `json:"spec"`
 	RequestID   string          `json:"request_id,omitempty"`
+	// Template names a registered AssertionTemplate to expand into Type and
+	// Spec, with Params supplying its parameter values.
+	Template string          `json:"template,omitempty"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	// SampleRate is the fraction of traces, in [0, 1], on which an
+	// embedding or llm_judge assertion runs; nil runs it on every trace.
+	// Ignored for layers 1-4, which always run.
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	// Shadow evaluates the assertion and records its result without letting
+	// it fail: the result's status is pass and ShadowStatus holds the real one.
+	Shadow bool `json:"shadow,omitempty"`
+	// Tags group assertions in the batch summary.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// AssertionTemplate is a named, parameterized assertion. String values in
+// Spec of the form "{{param}}" are replaced by the parameter's JSON value, and
+// "{{param}}" inside a longer string by its text.
+type AssertionTemplate struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Spec   json.RawMessage `json:"spec"`
+	Params []TemplateParam `json:"params,omitempty"`
+}
+
+// TemplateParam declares a template parameter. A parameter without a
+// Default is required.
+type TemplateParam struct {
+	Name        string          `json:"name"`
+	Default     json.RawMessage `json:"default,omitempty"`
+	Description string          `json:"description,omitempty"`
 }
 
 // AssertionResult holds the result of evaluating a single assertion.
@@ -33,4 +81,87 @@ type AssertionResult struct {
 	Cost        float64 `json:"cost"`
 	DurationMS  int64   `json:"duration_ms"`
 	RequestID   string  `json:"request_id,omitempty"`
+	// SkipReason is set when Status is skipped.
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Quarantined is set when the assertion is on the quarantine list; a
+	// hard_fail is then reported as soft_fail.
+	Quarantined bool `json:"quarantined,omitempty"`
+	// ShadowStatus is the real status of a shadow assertion, whose Status is
+	// always pass.
+	ShadowStatus string `json:"shadow_status,omitempty"`
+	// Excerpts locate a content failure in the target.
+	Excerpts []Excerpt `json:"excerpts,omitempty"`
+	// Children holds the per-child breakdown of a composite assertion.
+	Children []AssertionResult `json:"children,omitempty"`
+	// Calibration is set when a judge score was mapped through a calibration
+	// curve; Score is then the calibrated score.
+	Calibration *CalibrationInfo `json:"calibration,omitempty"`
+	// JudgeRuns holds the individual runs of a meta-evaluated judge assertion.
+	JudgeRuns *JudgeRuns `json:"judge_runs,omitempty"`
+	// TargetSummary is set when a judge target over its token budget was
+	// summarized or truncated before judging.
+	TargetSummary *TargetSummary `json:"target_summary,omitempty"`
+	// Truncated is set when Explanation was shortened, or Excerpts,
+	// Children, and JudgeRuns dropped, to fit the engine's max response size.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Excerpt locates the part of an assertion's target behind a content
+// failure, so a UI can highlight it without searching a large output.
+// Offsets count Unicode code points from the start of the target.
+type Excerpt struct {
+	// Term is the value, keyword, or forbidden term the excerpt concerns.
+	Term string `json:"term"`
+	// Start and End bound the region: the occurrence of Term or, when Term is
+	// Missing, the longest prefix of it that does occur.
+	Start   int  `json:"start"`
+	End     int  `json:"end"`
+	Missing bool `json:"missing,omitempty"`
+	// Text is the region with up to 80 characters of context on each side,
+	// beginning at offset TextStart.
+	Text      string `json:"text"`
+	TextStart int    `json:"text_start"`
+}
+
+// JudgeRuns describes the self-consistency of a meta-evaluated judge
+// assertion's runs.
+type JudgeRuns struct {
+	// Scores holds each successful run's raw score, in run order.
+	Scores []float64 `json:"scores"`
+	// Failed counts runs whose call or response parsing failed.
+	Failed int     `json:"failed"`
+	Spread float64 `json:"spread"`
+	// Confidence is 1 minus Spread: 1.0 when every run agrees.
+	Confidence float64 `json:"confidence"`
+}
+
+// TargetSummary describes how a judge target over its token budget was
+// shortened before the rubric was applied. Token counts are estimates.
+type TargetSummary struct {
+	// Method is "summarize" or "truncate". A failed summarization falls back
+	// to "truncate" and sets Error.
+	Method         string `json:"method"`
+	OriginalTokens int    `json:"original_tokens"`
+	Tokens         int    `json:"tokens"`
+	// Chunks and Rounds count the pieces summarized in the map step and the
+	// summarization passes made, including the map step.
+	Chunks int    `json:"chunks,omitempty"`
+	Rounds int    `json:"rounds,omitempty"`
+	Model  string `json:"model,omitempty"`
+	// Cost is the summarization's share of the result's Cost.
+	Cost  float64 `json:"cost"`
+	Error string  `json:"error,omitempty"`
+}
+
+// CalibrationInfo describes the calibration applied to a judge score.
+type CalibrationInfo struct {
+	RawScore float64 `json:"raw_score"`
+	Rubric   string  `json:"rubric"`
+	Model    string  `json:"model"`
+	// Examples, Pearson, and MAE describe the labeled dataset the curve was
+	// fitted on: its size and the raw judge's agreement with human scores.
+	Examples int     `json:"examples"`
+	Pearson  float64 `json:"pearson"`
+	MAE      float64 `json:"mae"`
+	FittedAt string  `json:"fitted_at"`
 }
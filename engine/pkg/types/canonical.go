@@ -0,0 +1,125 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CanonicalJSON re-encodes data so that semantically identical JSON from
+// different serializers is byte-for-byte equal: object keys are sorted,
+// insignificant whitespace is dropped, strings use the minimal escaping, and
+// numbers take their shortest form (1.0 and 1e0 become 1). Top-level object
+// keys named in omit are removed first.
+func CanonicalJSON(data []byte, omit ...string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("canonical json: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("canonical json: trailing data after value")
+	}
+	if obj, ok := v.(map[string]any); ok {
+		for _, k := range omit {
+			delete(obj, k)
+		}
+	}
+	v, err := canonicalValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("canonical json: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// CanonicalHash returns the hex SHA-256 of CanonicalJSON(data, omit...).
+func CanonicalHash(data []byte, omit ...string) (string, error) {
+	canonical, err := CanonicalJSON(data, omit...)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Fingerprint identifies what an assertion checks: its type, template, and
+// canonical spec and params. Assertions that differ only in ID, request ID,
+// tags, shadow, or sample rate, or in how their spec was serialized, share a
+// fingerprint.
+func (a *Assertion) Fingerprint() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", a.Type, a.Template)
+	for _, raw := range []json.RawMessage{a.Spec, a.Params} {
+		if len(bytes.TrimSpace(raw)) > 0 {
+			canonical, err := CanonicalJSON(raw)
+			if err != nil {
+				return "", fmt.Errorf("assertion %s: %w", a.AssertionID, err)
+			}
+			h.Write(canonical)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// canonicalValue rewrites the numbers in v, decoded with UseNumber, to their
+// shortest form. Maps are left to encoding/json, which sorts their keys.
+func canonicalValue(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			c, err := canonicalValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = c
+		}
+	case []any:
+		for i, e := range v {
+			c, err := canonicalValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = c
+		}
+	case json.Number:
+		return canonicalNumber(v)
+	}
+	return v, nil
+}
+
+// canonicalNumber formats n in its shortest form. Integer literals keep
+// every digit, so IDs beyond float64 precision are not rounded.
+func canonicalNumber(n json.Number) (json.Number, error) {
+	s := string(n)
+	if !strings.ContainsAny(s, ".eE") {
+		if s == "-0" {
+			return "0", nil
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", fmt.Errorf("canonical json: number %s: %w", s, err)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	}
+	return json.Number(strconv.FormatFloat(f, 'e', -1, 64)), nil
+}
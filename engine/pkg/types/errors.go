@@ -9,6 +9,7 @@ const (
 	ErrEngineError    = 3001
 	ErrTimeout        = 3002
 	ErrSessionError   = 3003
+	ErrPermissionDenied = 3004
 
 	ErrTypeInvalidTrace  = "INVALID_TRACE"
 	ErrTypeAssertionError = "ASSERTION_ERROR"
@@ -16,6 +17,7 @@ const (
 	ErrTypeEngineError    = "ENGINE_ERROR"
 	ErrTypeTimeout        = "TIMEOUT"
 	ErrTypeSessionError   = "SESSION_ERROR"
+	ErrTypePermissionDenied = "PERMISSION_DENIED"
 )
 
 // NewRPCError constructs an RPCError with the given fields.
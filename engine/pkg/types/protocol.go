@@ -8,6 +8,9 @@ type Request struct {
 	ID      int64           `json:"id"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
+	// RequestID optionally supplies the correlation ID for this call; the
+	// engine generates one when it is empty.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Response is a JSON-RPC 2.0 response.
@@ -16,6 +19,21 @@ type Response struct {
 	ID      int64           `json:"id"`
 	Result  json.RawMessage `json:"result,omitempty"`
 	Error   *RPCError       `json:"error,omitempty"`
+	// RequestID is the correlation ID attached to every log entry for this call.
+	RequestID string `json:"request_id,omitempty"`
+	// Warnings are deprecation notices for forms the request used that a
+	// later engine version will remove.
+	Warnings []Deprecation `json:"warnings,omitempty"`
+}
+
+// Deprecation is a structured notice that a request used a deprecated form,
+// such as an old trace schema_version, and when it will stop working.
+type Deprecation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// SunsetVersion is the first engine version that no longer accepts the
+	// deprecated form.
+	SunsetVersion string `json:"sunset_version"`
 }
 
 // RPCError represents a JSON-RPC error object.
@@ -32,6 +50,14 @@ type ErrorData struct {
 	Detail    string `json:"detail"`
 }
 
+// Payload encodings negotiated by initialize. With EncodingJSONGzip, params
+// and results after initialize may be sent as a JSON string holding the
+// base64 of the gzipped JSON value.
+const (
+	EncodingJSON     = "json"
+	EncodingJSONGzip = "json+gzip"
+)
+
 // InitializeParams holds parameters for the initialize method (protocol spec section 2.1).
 type InitializeParams struct {
 	SDKName              string   `json:"sdk_name"`
@@ -39,6 +65,13 @@ type InitializeParams struct {
 	ProtocolVersion      int      `json:"protocol_version"`
 	RequiredCapabilities []string `json:"required_capabilities"`
 	PreferredEncoding    string   `json:"preferred_encoding"`
+	// Namespace partitions history, drift statistics, quarantine, and the
+	// judge and embedding caches, so projects sharing a cache directory do
+	// not mix results. Empty selects the default namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// AuthToken authenticates the session when the engine is configured
+	// with ATTEST_AUTH_TOKENS; its role decides which methods it may call.
+	AuthToken string `json:"auth_token,omitempty"`
 }
 
 // InitializeResult holds the result of the initialize method.
@@ -52,12 +85,97 @@ type InitializeResult struct {
 	MaxConcurrentRequests int      `json:"max_concurrent_requests"`
 	MaxTraceSizeBytes     int      `json:"max_trace_size_bytes"`
 	MaxStepsPerTrace      int      `json:"max_steps_per_trace"`
+	MaxStepPayloadBytes   int      `json:"max_step_payload_bytes"`
+	MaxSubTraceDepth      int      `json:"max_sub_trace_depth"`
+	// ProviderErrors lists configured providers whose model failed
+	// validation; the capabilities they back are left out of Capabilities.
+	ProviderErrors []ProviderError `json:"provider_errors,omitempty"`
+	// Namespace echoes the namespace the session reads and writes.
+	Namespace string `json:"namespace,omitempty"`
+	// Role is the session's role when authentication is enabled: "admin"
+	// or "evaluate".
+	Role string `json:"role,omitempty"`
+}
+
+// ProviderError reports a configured provider that cannot serve its layer,
+// e.g. because the model does not exist or the API key lacks access.
+type ProviderError struct {
+	// Capability is the capability the provider backs: "embedding" or "llm_judge".
+	Capability string `json:"capability"`
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Message    string `json:"message"`
+}
+
+// DescribeCapabilitiesResult holds the result of the describe_capabilities
+// RPC method: everything the engine supports, down to individual checks, so
+// SDKs can feature-detect without relying on coarse capability strings.
+type DescribeCapabilitiesResult struct {
+	EngineVersion      string `json:"engine_version"`
+	ProtocolVersion    int    `json:"protocol_version"`
+	MinProtocolVersion int    `json:"min_protocol_version"`
+	// Capabilities is the list initialize reports.
+	Capabilities   []string            `json:"capabilities"`
+	AssertionTypes []AssertionTypeInfo `json:"assertion_types"`
+	Limits         EngineLimits        `json:"limits"`
+	// Providers lists the configured embedding and judge providers.
+	Providers      []ProviderInfo  `json:"providers"`
+	ProviderErrors []ProviderError `json:"provider_errors,omitempty"`
+	// Methods lists the RPC methods the session may call.
+	Methods   []string `json:"methods"`
+	Encodings []string `json:"encodings"`
+}
+
+// AssertionTypeInfo describes one assertion type. Checks, Operators, and
+// Options are empty for types that take none.
+type AssertionTypeInfo struct {
+	Type  string `json:"type"`
+	Layer int    `json:"layer"`
+	// Available is false when the type's layer is not configured or its
+	// provider failed validation.
+	Available bool     `json:"available"`
+	Checks    []string `json:"checks,omitempty"`
+	// Aliases maps deprecated check names, still accepted with a warning, to
+	// the checks that replaced them.
+	Aliases   map[string]string `json:"aliases,omitempty"`
+	Operators []string          `json:"operators,omitempty"`
+	// Options maps a spec field to the values it accepts, e.g. code_valid's
+	// "language".
+	Options map[string][]string `json:"options,omitempty"`
+}
+
+// EngineLimits are the trace size limits the engine enforces.
+type EngineLimits struct {
+	MaxConcurrentRequests int `json:"max_concurrent_requests"`
+	MaxTraceSizeBytes     int `json:"max_trace_size_bytes"`
+	MaxStepsPerTrace      int `json:"max_steps_per_trace"`
+	MaxStepPayloadBytes   int `json:"max_step_payload_bytes"`
+	MaxSubTraceDepth      int `json:"max_sub_trace_depth"`
+	MaxAssertionIDLength  int `json:"max_assertion_id_length"`
+}
+
+// ProviderInfo identifies a configured provider and the capability it backs.
+type ProviderInfo struct {
+	Capability string `json:"capability"`
+	Provider   string `json:"provider"`
+	Model      string `json:"model,omitempty"`
 }
 
 // EvaluateBatchParams holds parameters for the evaluate_batch method.
 type EvaluateBatchParams struct {
-	Trace      Trace       `json:"trace"`
+	Trace Trace `json:"trace"`
+	// Traces is a flat list of traces linked by parent_trace_id, stitched
+	// into the evaluated tree in place of Trace.
+	Traces []Trace `json:"traces,omitempty"`
+	// TraceRef names a trace assembled by begin_trace, append_trace_chunk,
+	// and end_trace, evaluated in place of Trace.
+	TraceRef   string      `json:"trace_ref,omitempty"`
 	Assertions []Assertion `json:"assertions"`
+	// Seed makes stochastic evaluation (e.g. meta-eval judge sampling) reproducible.
+	Seed *int64 `json:"seed,omitempty"`
+	// Strict lists trace warning codes to reject as INVALID_TRACE instead of
+	// reporting; "all" promotes every warning.
+	Strict []string `json:"strict,omitempty"`
 }
 
 // EvaluateBatchResult holds the result of the evaluate_batch method.
@@ -65,6 +183,161 @@ type EvaluateBatchResult struct {
 	Results         []AssertionResult `json:"results"`
 	TotalCost       float64           `json:"total_cost"`
 	TotalDurationMS int64             `json:"total_duration_ms"`
+	Timings         *BatchTimings     `json:"timings,omitempty"`
+	// Summary aggregates Results so clients need not recount them.
+	Summary *BatchSummary `json:"summary,omitempty"`
+	// Warnings report trace conditions accepted by lenient validation.
+	Warnings []TraceWarning `json:"warnings,omitempty"`
+	// Truncated is set when the response was cut to fit the engine's max
+	// response size. OmittedResults counts results dropped from the end of
+	// Results; Summary still counts them.
+	Truncated      bool `json:"truncated,omitempty"`
+	OmittedResults int  `json:"omitted_results,omitempty"`
+}
+
+// BatchSummary aggregates the results of an evaluate_batch call. L5-6
+// assertions gated by an L1-4 hard_fail have no result and are not counted.
+type BatchSummary struct {
+	Counts StatusCounts `json:"counts"`
+	// Layers maps layer number ("1".."6") to the counts of its assertions.
+	Layers map[string]StatusCounts `json:"layers"`
+	// Tags maps each assertion tag to the counts of the assertions carrying it.
+	Tags map[string]StatusCounts `json:"tags,omitempty"`
+	// WorstFailure is the lowest-scoring hard_fail, or soft_fail when nothing
+	// hard failed.
+	WorstFailure *WorstFailure `json:"worst_failure,omitempty"`
+	TotalCost    float64       `json:"total_cost"`
+	// Cache maps "embedding" and "judge" to their cache lookups in the batch.
+	Cache map[string]CacheCounts `json:"cache,omitempty"`
+}
+
+// StatusCounts counts results by status.
+type StatusCounts struct {
+	Total    int `json:"total"`
+	Pass     int `json:"pass"`
+	SoftFail int `json:"soft_fail"`
+	HardFail int `json:"hard_fail"`
+	Skipped  int `json:"skipped,omitempty"`
+}
+
+// Add counts one result with status.
+func (c *StatusCounts) Add(status string) {
+	c.Total++
+	switch status {
+	case StatusPass:
+		c.Pass++
+	case StatusSoftFail:
+		c.SoftFail++
+	case StatusHardFail:
+		c.HardFail++
+	case StatusSkipped:
+		c.Skipped++
+	}
+}
+
+// WorstFailure identifies the worst failing assertion of a batch.
+type WorstFailure struct {
+	AssertionID string  `json:"assertion_id"`
+	Status      string  `json:"status"`
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+}
+
+// CacheCounts counts hits and misses of one cache.
+type CacheCounts struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// EvaluateDatasetParams holds the parameters for the evaluate_dataset method.
+type EvaluateDatasetParams struct {
+	// Dataset is the path of a JSONL file with one trace per line.
+	Dataset    string      `json:"dataset"`
+	Assertions []Assertion `json:"assertions"`
+	// Sink is the path of the JSONL file results are streamed to, one
+	// DatasetResultLine per trace.
+	Sink string `json:"sink"`
+	// Restart discards the checkpoint of an earlier run and starts over.
+	Restart bool   `json:"restart,omitempty"`
+	Seed    *int64 `json:"seed,omitempty"`
+	// Dedup evaluates duplicate traces once: "exact" matches traces that are
+	// identical apart from trace_id, "near" also matches traces whose input
+	// and output embeddings reach NearDupThreshold. Empty disables dedup.
+	Dedup string `json:"dedup,omitempty"`
+	// NearDupThreshold is the cosine similarity for "near" dedup; 0 uses
+	// the default, 0.98.
+	NearDupThreshold float64 `json:"near_dup_threshold,omitempty"`
+}
+
+// EvaluateDatasetResult summarizes an evaluate_dataset run, including traces
+// completed by earlier, interrupted calls.
+type EvaluateDatasetResult struct {
+	// DatasetHash identifies the dataset contents and assertions; it keys the
+	// run's checkpoint.
+	DatasetHash string `json:"dataset_hash"`
+	Sink        string `json:"sink"`
+	// Traces counts traces with a line in the sink.
+	Traces int `json:"traces"`
+	// Resumed counts traces completed by earlier calls and not re-evaluated.
+	Resumed int `json:"resumed"`
+	// Failed counts traces with at least one hard_fail.
+	Failed int `json:"failed"`
+	// Errors counts lines that could not be parsed or validated as traces.
+	Errors    int     `json:"errors"`
+	TotalCost float64 `json:"total_cost"`
+	// Dedup reports what dedup saved; nil when dedup is off.
+	Dedup *DedupSummary `json:"dedup,omitempty"`
+}
+
+// DedupSummary reports the traces evaluate_dataset did not evaluate because
+// they duplicated an earlier one.
+type DedupSummary struct {
+	// Duplicates counts exact and near duplicates; NearDuplicates the latter.
+	Duplicates     int `json:"duplicates"`
+	NearDuplicates int `json:"near_duplicates"`
+	// SavedCost is the cost the duplicates would have incurred, taking each
+	// to cost what its original did.
+	SavedCost float64 `json:"saved_cost"`
+}
+
+// DatasetResultLine is one line of an evaluate_dataset sink.
+type DatasetResultLine struct {
+	// Line is the trace's 1-based line number in the dataset.
+	Line      int               `json:"line"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	Results   []AssertionResult `json:"results,omitempty"`
+	TotalCost float64           `json:"total_cost"`
+	// DuplicateOf is the line of the trace whose results a duplicate reuses.
+	DuplicateOf int `json:"duplicate_of,omitempty"`
+	// Similarity is set for a near duplicate: its embedding's cosine
+	// similarity to the original.
+	Similarity float64 `json:"similarity,omitempty"`
+	// Error describes why the line could not be evaluated.
+	Error string `json:"error,omitempty"`
+}
+
+// BatchTimings breaks down where an evaluate_batch call spent its time.
+// Durations are wall-clock milliseconds. Layer and evaluator times are summed
+// over assertions, so with concurrent L5-6 evaluation they can exceed TotalMS.
+type BatchTimings struct {
+	TotalMS float64 `json:"total_ms"`
+	// Layers maps layer number ("1".."6") to evaluation time.
+	Layers map[string]float64 `json:"layers"`
+	// Evaluators maps assertion type to evaluation time.
+	Evaluators map[string]float64 `json:"evaluators"`
+	// CacheMS is time spent reading and writing the embedding and judge caches.
+	CacheMS float64 `json:"cache_ms"`
+	// RateLimitWaitMS is time spent queued in the rate limiter or backing off between retries.
+	RateLimitWaitMS float64 `json:"rate_limit_wait_ms"`
+	// Providers maps provider name to its round-trip times.
+	Providers map[string]ProviderTiming `json:"providers,omitempty"`
+}
+
+// ProviderTiming summarizes round trips to one LLM or embedding provider.
+type ProviderTiming struct {
+	Calls   int     `json:"calls"`
+	TotalMS float64 `json:"total_ms"`
+	MaxMS   float64 `json:"max_ms"`
 }
 
 // ShutdownResult holds the result of the shutdown method.
@@ -101,6 +374,9 @@ type SimulatePersona struct {
 	Style        string  `json:"style"`
 	Temperature  float64 `json:"temperature"`
 	MaxTokens    int     `json:"max_tokens,omitempty"`
+	// Actions lists the structured action types the user may take besides
+	// typing: attach_file, fill_form, select_option.
+	Actions []string `json:"actions,omitempty"`
 }
 
 // SimulateFaultConfig describes optional fault injection parameters for simulation.
@@ -122,28 +398,258 @@ type GenerateUserMessageParams struct {
 	Persona             SimulatePersona      `json:"persona"`
 	ConversationHistory []ConversationMessage `json:"conversation_history"`
 	FaultConfig         *SimulateFaultConfig  `json:"fault_config,omitempty"`
+	// Seed makes fault injection and persona sampling reproducible.
+	Seed *int64 `json:"seed,omitempty"`
+	// N is the number of candidate messages to generate. Default 1.
+	N int `json:"n,omitempty"`
+	// Rank orders candidates by a judge score: "goal" (progress toward Goal)
+	// or "adversarial" (how hard the message probes the agent).
+	Rank string `json:"rank,omitempty"`
+	Goal string `json:"goal,omitempty"`
 }
 
 // GenerateUserMessageResult holds the result of the generate_user_message RPC method.
 type GenerateUserMessageResult struct {
+	// Message is the top-ranked (or first) candidate.
 	Message string `json:"message"`
+	// Actions are the structured actions of that candidate, when the
+	// persona allows actions.
+	Actions []UserAction `json:"actions,omitempty"`
+	// Candidates lists every distinct candidate, best first, when N > 1 or
+	// Rank is set.
+	Candidates []UserMessageCandidate `json:"candidates,omitempty"`
+	// Cost is the total generation and ranking cost in USD.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// UserMessageCandidate is one generated user message.
+type UserMessageCandidate struct {
+	Message     string       `json:"message"`
+	Actions     []UserAction `json:"actions,omitempty"`
+	Score       *float64     `json:"score,omitempty"`
+	Explanation string       `json:"explanation,omitempty"`
+}
+
+// RunSimulationParams holds parameters for the run_simulation RPC method.
+type RunSimulationParams struct {
+	Persona       SimulatePersona `json:"persona"`
+	InitialPrompt string          `json:"initial_prompt"`
+	// InitialActions are structured actions taken with InitialPrompt.
+	InitialActions []UserAction `json:"initial_actions,omitempty"`
+	MaxTurns       int          `json:"max_turns"`
+	// StopKeywords end the simulation when any appears in an agent response.
+	StopKeywords []string `json:"stop_keywords,omitempty"`
+	Seed         *int64   `json:"seed,omitempty"`
+	// AgentTimeoutMS bounds each agent_invoke call. Default 60000.
+	AgentTimeoutMS int `json:"agent_timeout_ms,omitempty"`
+	// AgentContext is passed unchanged in every agent_invoke call so the SDK
+	// can route the call to the agent under test.
+	AgentContext json.RawMessage `json:"agent_context,omitempty"`
+	// ContextWindow bounds the history the simulated user sees.
+	ContextWindow *SimulationContextWindow `json:"context_window,omitempty"`
+	// TurnAssertions are evaluated after every turn; a hard_fail ends the
+	// simulation early.
+	TurnAssertions []Assertion `json:"turn_assertions,omitempty"`
+}
+
+// SimulationContextWindow bounds a simulated user's conversation history.
+type SimulationContextWindow struct {
+	// KeepTurns is the number of most recent turns sent verbatim.
+	KeepTurns int `json:"keep_turns"`
+	// Summarize replaces older turns with a rolling summary.
+	Summarize        bool `json:"summarize,omitempty"`
+	SummaryMaxTokens int  `json:"summary_max_tokens,omitempty"`
+}
+
+// RunSimulationResult holds the result of the run_simulation RPC method.
+type RunSimulationResult struct {
+	SimulationID string `json:"simulation_id"`
+	// Transcript alternates user messages and agent responses, ready for a
+	// trace's transcript field.
+	Transcript []Message `json:"transcript"`
+	TotalTurns int       `json:"total_turns"`
+	StoppedBy  string    `json:"stopped_by"`
+	// Cost is the simulated user's LLM cost in USD, SummaryCost included.
+	Cost float64 `json:"cost"`
+	// SummaryCost is the part of Cost spent summarizing history.
+	SummaryCost float64 `json:"summary_cost,omitempty"`
+	// TurnResults holds the turn_assertions results of each evaluated turn.
+	TurnResults []SimulationTurnResult `json:"turn_results,omitempty"`
+	// AssertionCost is the cost in USD of evaluating turn_assertions.
+	AssertionCost float64 `json:"assertion_cost,omitempty"`
+}
+
+// SimulationTurnResult holds the per-turn assertion results of one turn.
+type SimulationTurnResult struct {
+	Turn    int               `json:"turn"`
+	Results []AssertionResult `json:"results"`
+}
+
+// RunSimulationBatchParams holds parameters for the run_simulation_batch RPC
+// method: the run_simulation scenario plus the number of runs.
+type RunSimulationBatchParams struct {
+	RunSimulationParams
+	Runs int `json:"runs"`
+	// Personas are assigned to runs round-robin in place of Persona.
+	Personas []SimulatePersona `json:"personas,omitempty"`
+	// Concurrency bounds the runs in flight. Default 4.
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// RunSimulationBatchResult holds the result of the run_simulation_batch RPC method.
+type RunSimulationBatchResult struct {
+	Runs      []SimulationRunSummary `json:"runs"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Errored   int                    `json:"errored"`
+	// SuccessRate is Succeeded over all runs, errored runs included.
+	SuccessRate float64             `json:"success_rate"`
+	Turns       SimulationTurnStats `json:"turns"`
+	TotalCost   float64             `json:"total_cost"`
+	// Worst is the worst finished run with its transcript; nil when every
+	// run errored.
+	Worst *RunSimulationResult `json:"worst,omitempty"`
+}
+
+// SimulationRunSummary is the outcome of one run_simulation_batch run. Cost
+// includes the cost of evaluating turn_assertions.
+type SimulationRunSummary struct {
+	Run          int     `json:"run"`
+	SimulationID string  `json:"simulation_id"`
+	Persona      string  `json:"persona"`
+	Seed         *int64  `json:"seed,omitempty"`
+	Succeeded    bool    `json:"succeeded"`
+	TotalTurns   int     `json:"total_turns"`
+	StoppedBy    string  `json:"stopped_by,omitempty"`
+	Cost         float64 `json:"cost"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// SimulationTurnStats summarizes the turn counts of finished runs.
+type SimulationTurnStats struct {
+	Min  int     `json:"min"`
+	Max  int     `json:"max"`
+	Mean float64 `json:"mean"`
+	P50  int     `json:"p50"`
+	P90  int     `json:"p90"`
+	// Counts maps a turn count to the number of runs that took it.
+	Counts map[int]int `json:"counts"`
+}
+
+// AgentInvokeParams holds parameters for agent_invoke, the request the engine
+// sends to the SDK to get the SDK-hosted agent's response to one simulated
+// user message.
+type AgentInvokeParams struct {
+	SimulationID string          `json:"simulation_id"`
+	Turn         int             `json:"turn"`
+	Message      string          `json:"message"`
+	Actions      []UserAction    `json:"actions,omitempty"`
+	AgentContext json.RawMessage `json:"agent_context,omitempty"`
+}
+
+// AgentInvokeResult is the SDK's result for an agent_invoke call.
+type AgentInvokeResult struct {
+	Response string `json:"response"`
 }
 
 // ValidateTraceTreeParams holds parameters for the validate_trace_tree RPC method.
 type ValidateTraceTreeParams struct {
 	Trace Trace `json:"trace"`
+	// Traces is a flat list of traces linked by parent_trace_id, stitched
+	// into the validated tree in place of Trace.
+	Traces []Trace `json:"traces,omitempty"`
 }
 
 // ValidateTraceTreeResult holds the result of the validate_trace_tree RPC method.
 type ValidateTraceTreeResult struct {
-	Valid              bool     `json:"valid"`
-	Errors             []string `json:"errors,omitempty"`
-	Depth              int      `json:"depth"`
-	AgentCount         int      `json:"agent_count"`
-	AgentIDs           []string `json:"agent_ids"`
-	AggregateTokens    int      `json:"aggregate_tokens"`
-	AggregateCostUSD   float64  `json:"aggregate_cost_usd"`
-	AggregateLatencyMS int      `json:"aggregate_latency_ms"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+	// Warnings report temporal anomalies and nearly reached limits; they do
+	// not affect Valid.
+	Warnings           []TraceWarning `json:"warnings,omitempty"`
+	Depth              int            `json:"depth"`
+	AgentCount         int            `json:"agent_count"`
+	AgentIDs           []string       `json:"agent_ids"`
+	AggregateTokens    int            `json:"aggregate_tokens"`
+	AggregateCostUSD   float64        `json:"aggregate_cost_usd"`
+	AggregateLatencyMS int            `json:"aggregate_latency_ms"`
+	// Size measures the tree against the limits that make a trace invalid.
+	Size *TraceSize `json:"size,omitempty"`
+}
+
+// TraceSize measures a trace tree against the engine's hard limits.
+type TraceSize struct {
+	// TotalBytes is the serialized size of the whole tree.
+	TotalBytes int `json:"total_bytes"`
+	// LargestStepBytes is the serialized size of the largest step at any
+	// depth, named by LargestStep.
+	LargestStepBytes int    `json:"largest_step_bytes"`
+	LargestStep      string `json:"largest_step,omitempty"`
+	// MaxSteps is the most steps in any one trace of the tree.
+	MaxSteps int `json:"max_steps"`
+	// Depth is the sub-trace nesting depth; the root is 0.
+	Depth int `json:"depth"`
+	// LimitPercent maps "size", "step_payload", "steps", and "depth" to the
+	// percentage of that limit consumed.
+	LimitPercent map[string]float64 `json:"limit_percent"`
+}
+
+// TraceWarning describes a non-fatal anomaly found in a trace tree.
+type TraceWarning struct {
+	Code    string `json:"code"`
+	TraceID string `json:"trace_id"`
+	// Step and StepIndex locate the step within TraceID; empty for trace-level warnings.
+	// StepIndex is a pointer so that the first step's index, 0, is sent.
+	Step      string `json:"step,omitempty"`
+	StepIndex *int   `json:"step_index,omitempty"`
+	Message   string `json:"message"`
+}
+
+// BeginTraceParams holds parameters for the begin_trace RPC method.
+type BeginTraceParams struct {
+	// SizeBytes is the expected size of the whole trace, if known, so an
+	// oversized trace is rejected before any chunk is sent.
+	SizeBytes int `json:"size_bytes,omitempty"`
+}
+
+// BeginTraceResult holds the result of the begin_trace RPC method.
+type BeginTraceResult struct {
+	UploadID          string `json:"upload_id"`
+	MaxChunkBytes     int    `json:"max_chunk_bytes"`
+	MaxTraceSizeBytes int    `json:"max_trace_size_bytes"`
+}
+
+// AppendTraceChunkParams holds parameters for the append_trace_chunk RPC
+// method. Chunks are appended in order starting at index 0.
+type AppendTraceChunkParams struct {
+	UploadID string `json:"upload_id"`
+	Index    int    `json:"index"`
+	// Data is the chunk's bytes, base64-encoded on the wire.
+	Data []byte `json:"data"`
+	// SHA256 is the hex SHA-256 of Data.
+	SHA256 string `json:"sha256"`
+}
+
+// AppendTraceChunkResult holds the result of the append_trace_chunk RPC method.
+type AppendTraceChunkResult struct {
+	ReceivedBytes int `json:"received_bytes"`
+	Chunks        int `json:"chunks"`
+}
+
+// EndTraceParams holds parameters for the end_trace RPC method.
+type EndTraceParams struct {
+	UploadID string `json:"upload_id"`
+	// SHA256 is the hex SHA-256 of the whole trace.
+	SHA256 string `json:"sha256"`
+}
+
+// EndTraceResult holds the result of the end_trace RPC method.
+type EndTraceResult struct {
+	// TraceRef is passed as trace_ref to evaluate_batch.
+	TraceRef  string `json:"trace_ref"`
+	TraceID   string `json:"trace_id"`
+	SizeBytes int    `json:"size_bytes"`
+	Chunks    int    `json:"chunks"`
 }
 
 // QueryDriftParams holds parameters for the query_drift RPC method.
@@ -168,9 +674,170 @@ type DriftReport struct {
 	Status      string  `json:"status"`
 }
 
-// DriftAlertNotification is a JSON-RPC 2.0 notification emitted when drift is detected.
-type DriftAlertNotification struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Method  string      `json:"method"`
-	Params  DriftReport `json:"params"`
+// QueryFlakyParams holds parameters for the query_flaky RPC method.
+type QueryFlakyParams struct {
+	// AssertionIDs limits the analysis; empty means every assertion with history.
+	AssertionIDs []string `json:"assertion_ids,omitempty"`
+	WindowSize   int      `json:"window_size"`
+	// Quarantine adds assertions detected as flaky to the quarantine list.
+	Quarantine bool `json:"quarantine,omitempty"`
+}
+
+// QueryFlakyResult holds the result of the query_flaky RPC method.
+type QueryFlakyResult struct {
+	Reports []FlakinessReport `json:"reports"`
+}
+
+// FlakinessReport describes the pass/fail stability of a single assertion.
+type FlakinessReport struct {
+	AssertionID string  `json:"assertion_id"`
+	Runs        int     `json:"runs"`
+	PassRate    float64 `json:"pass_rate"`
+	SwitchCount int     `json:"switch_count"`
+	SwitchRate  float64 `json:"switch_rate"`
+	Entropy     float64 `json:"entropy"`
+	Flaky       bool    `json:"flaky"`
+	Quarantined bool    `json:"quarantined"`
+}
+
+// UpdateQuarantineParams holds parameters for the update_quarantine RPC method.
+type UpdateQuarantineParams struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// UpdateQuarantineResult holds the quarantine list after an update_quarantine call.
+type UpdateQuarantineResult struct {
+	Quarantined []string `json:"quarantined"`
+}
+
+// RegisterTemplateParams holds parameters for the register_template RPC method.
+type RegisterTemplateParams = AssertionTemplate
+
+// RegisterTemplateResult holds the result of the register_template RPC method.
+type RegisterTemplateResult struct {
+	Name     string `json:"name"`
+	Replaced bool   `json:"replaced"`
+}
+
+// GetMetricsResult holds the result of the get_metrics RPC method.
+type GetMetricsResult struct {
+	UptimeS             float64 `json:"uptime_s"`
+	AssertionsEvaluated int     `json:"assertions_evaluated"`
+	// DeadLetter reports writes that failed and were queued for retry.
+	DeadLetter DeadLetterMetrics `json:"dead_letter"`
+	// Memory reports memory use and the admission queue for large traces.
+	Memory MemoryMetrics `json:"memory"`
+}
+
+// MemoryMetrics reports the engine's memory use and its admission control:
+// evaluations whose traces would push in-flight trace bytes over the cap, or
+// arrive while memory use is above the watermark, wait in a queue.
+type MemoryMetrics struct {
+	// UsedBytes is the memory counted against LimitBytes.
+	UsedBytes int64 `json:"used_bytes"`
+	HeapBytes int64 `json:"heap_bytes"`
+	// LimitBytes is the runtime's soft memory limit; 0 when unset.
+	LimitBytes     int64 `json:"limit_bytes"`
+	WatermarkBytes int64 `json:"watermark_bytes"`
+	GCCycles       int64 `json:"gc_cycles"`
+
+	InFlightTraceBytes    int64 `json:"inflight_trace_bytes"`
+	MaxInFlightTraceBytes int64 `json:"max_inflight_trace_bytes"`
+	// Queued is the number of requests waiting now; QueuedTotal counts every
+	// request that had to wait, and TimedOut those that gave up.
+	Queued      int   `json:"queued"`
+	QueuedTotal int64 `json:"queued_total"`
+	TimedOut    int64 `json:"timed_out"`
+}
+
+// DeadLetterMetrics counts failed history writes and notifications.
+type DeadLetterMetrics struct {
+	History       DeadLetterCounts `json:"history"`
+	Notifications DeadLetterCounts `json:"notifications"`
+	// SpillBytes is the size of the on-disk spillover file.
+	SpillBytes int64 `json:"spill_bytes"`
+}
+
+// DeadLetterCounts tracks one kind of queued write. Failed counts first
+// failures; each later ends Recovered, Spilled (history only, retried on
+// the next start), or Dropped, or is still Pending.
+type DeadLetterCounts struct {
+	Failed    int64 `json:"failed"`
+	Recovered int64 `json:"recovered"`
+	Pending   int   `json:"pending"`
+	Spilled   int64 `json:"spilled"`
+	Dropped   int64 `json:"dropped"`
+}
+
+// Notification is a JSON-RPC 2.0 notification sent by the engine.
+type Notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// DriftAlert is the params of a drift_alert notification, sent when an
+// assertion with a dynamic threshold hard-fails.
+type DriftAlert struct {
+	DriftReport
+	TraceID string `json:"trace_id"`
+	// ZScore is the deviation in standard deviations of the history; 0 when
+	// the history has no spread.
+	ZScore float64 `json:"z_score"`
+	// Suppressed counts alerts for this assertion dropped by rate limiting
+	// since the previous one was sent.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// DebugDumpParams holds parameters for the debug_dump method.
+type DebugDumpParams struct {
+	// OutputPath is where the tar.gz bundle is written. Required when Confirm is true.
+	OutputPath string `json:"output_path,omitempty"`
+	// MaxTraces caps how many recent traces are included (default 10; 0 uses the default).
+	MaxTraces int `json:"max_traces,omitempty"`
+	// Confirm must be true to write the bundle. Without it the engine only
+	// returns the manifest so the user can review the contents first.
+	Confirm bool `json:"confirm"`
+}
+
+// DebugDumpEntry describes one file in a debug bundle.
+type DebugDumpEntry struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Bytes       int    `json:"bytes"`
+}
+
+// DebugDumpResult holds the result of the debug_dump method.
+type DebugDumpResult struct {
+	Manifest []DebugDumpEntry `json:"manifest"`
+	// Written is true when the bundle was written to Path.
+	Written bool   `json:"written"`
+	Path    string `json:"path,omitempty"`
+}
+
+// Profile kinds for debug_profile.
+const (
+	ProfileCPU  = "cpu"
+	ProfileHeap = "heap"
+)
+
+// DebugProfileParams holds parameters for the debug_profile method.
+type DebugProfileParams struct {
+	// Kind is ProfileCPU or ProfileHeap.
+	Kind string `json:"kind"`
+	// Seconds is how long a CPU profile samples (default 10), or how long to
+	// wait before a heap profile is taken (default 0). At most 300.
+	Seconds int `json:"seconds,omitempty"`
+}
+
+// DebugProfileResult holds the result of the debug_profile method. The
+// capture runs in the background; the file exists at Path once it is done.
+type DebugProfileResult struct {
+	Kind    string `json:"kind"`
+	Seconds int    `json:"seconds"`
+	Path    string `json:"path"`
+	// ReadyAt is when the capture completes, in RFC 3339.
+	ReadyAt string `json:"ready_at"`
 }
@@ -0,0 +1,148 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// StepMetadata is the typed view of the well-known keys in Step.Metadata.
+// All fields are optional; unknown keys are ignored.
+type StepMetadata struct {
+	TokensIn   *int     `json:"tokens_in,omitempty"`
+	TokensOut  *int     `json:"tokens_out,omitempty"`
+	Model      *string  `json:"model,omitempty"`
+	CostUSD    *float64 `json:"cost_usd,omitempty"`
+	RetryCount *int     `json:"retry_count,omitempty"`
+}
+
+// Key spellings accepted for each StepMetadata field, in priority order.
+// Covers the canonical names plus the spellings emitted by common SDKs
+// (OpenAI, Anthropic, LangChain, camelCase JS SDKs).
+var (
+	stepMetaTokensInKeys   = []string{"tokens_in", "input_tokens", "prompt_tokens", "inputTokens", "promptTokens"}
+	stepMetaTokensOutKeys  = []string{"tokens_out", "output_tokens", "completion_tokens", "outputTokens", "completionTokens"}
+	stepMetaModelKeys      = []string{"model", "model_name", "modelName"}
+	stepMetaCostKeys       = []string{"cost_usd", "cost", "costUSD", "costUsd"}
+	stepMetaRetryCountKeys = []string{"retry_count", "retries", "retryCount"}
+)
+
+// ParseStepMetadata normalizes raw step metadata into a StepMetadata.
+// Token counts nested under a "usage" object are used when no top-level key is present.
+// Returns an empty StepMetadata for empty or null input, and an error when the
+// metadata is not a JSON object or a recognized key has the wrong type.
+func ParseStepMetadata(raw json.RawMessage) (*StepMetadata, error) {
+	meta := &StepMetadata{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return meta, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("step metadata is not a JSON object: %w", err)
+	}
+
+	var usage map[string]json.RawMessage
+	if u, ok := obj["usage"]; ok {
+		// A non-object usage value is ignored rather than rejected.
+		_ = json.Unmarshal(u, &usage)
+	}
+
+	var err error
+	if meta.TokensIn, err = lookupInt(obj, usage, stepMetaTokensInKeys); err != nil {
+		return nil, err
+	}
+	if meta.TokensOut, err = lookupInt(obj, usage, stepMetaTokensOutKeys); err != nil {
+		return nil, err
+	}
+	if meta.RetryCount, err = lookupInt(obj, nil, stepMetaRetryCountKeys); err != nil {
+		return nil, err
+	}
+	if meta.CostUSD, err = lookupFloat(obj, stepMetaCostKeys); err != nil {
+		return nil, err
+	}
+	// A cost in another currency counts only once converted into cost_usd.
+	if _, usd := obj["cost_usd"]; !usd && meta.CostUSD != nil {
+		var currency string
+		if c, ok := obj["currency"]; ok && json.Unmarshal(c, &currency) == nil && !strings.EqualFold(currency, "USD") {
+			meta.CostUSD = nil
+		}
+	}
+	for _, k := range stepMetaModelKeys {
+		v, ok := obj[k]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return nil, fmt.Errorf("step metadata %q must be a string", k)
+		}
+		meta.Model = &s
+		break
+	}
+	return meta, nil
+}
+
+// TypedMetadata parses the step's metadata into a normalized StepMetadata.
+func (s *Step) TypedMetadata() (*StepMetadata, error) {
+	return ParseStepMetadata(s.Metadata)
+}
+
+// Numeric returns the value of a numeric StepMetadata field by canonical name
+// (tokens_in, tokens_out, cost_usd, retry_count). ok is false when the field
+// is unknown or unset.
+func (m *StepMetadata) Numeric(field string) (value float64, ok bool) {
+	switch field {
+	case "tokens_in":
+		if m.TokensIn != nil {
+			return float64(*m.TokensIn), true
+		}
+	case "tokens_out":
+		if m.TokensOut != nil {
+			return float64(*m.TokensOut), true
+		}
+	case "cost_usd":
+		if m.CostUSD != nil {
+			return *m.CostUSD, true
+		}
+	case "retry_count":
+		if m.RetryCount != nil {
+			return float64(*m.RetryCount), true
+		}
+	}
+	return 0, false
+}
+
+func lookupInt(obj, usage map[string]json.RawMessage, keys []string) (*int, error) {
+	for _, src := range []map[string]json.RawMessage{obj, usage} {
+		for _, k := range keys {
+			v, ok := src[k]
+			if !ok {
+				continue
+			}
+			var f float64
+			if err := json.Unmarshal(v, &f); err != nil || f != math.Trunc(f) {
+				return nil, fmt.Errorf("step metadata %q must be an integer", k)
+			}
+			n := int(f)
+			return &n, nil
+		}
+	}
+	return nil, nil
+}
+
+func lookupFloat(obj map[string]json.RawMessage, keys []string) (*float64, error) {
+	for _, k := range keys {
+		v, ok := obj[k]
+		if !ok {
+			continue
+		}
+		var f float64
+		if err := json.Unmarshal(v, &f); err != nil {
+			return nil, fmt.Errorf("step metadata %q must be a number", k)
+		}
+		return &f, nil
+	}
+	return nil, nil
+}
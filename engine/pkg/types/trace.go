@@ -19,6 +19,11 @@ type Trace struct {
 	Output        json.RawMessage  `json:"output"`
 	Metadata      *TraceMetadata   `json:"metadata,omitempty"`
 	ParentTraceID *string          `json:"parent_trace_id,omitempty"`
+	// Tools declares the tools available to the agent (schema_version 2).
+	Tools []ToolSchema `json:"tools,omitempty"`
+	// Transcript is the user-visible conversation of a multi-turn session,
+	// in order (schema_version 2). Each user message starts a turn.
+	Transcript []Message `json:"transcript,omitempty"`
 }
 
 // Step represents a single step within a trace.
@@ -33,6 +38,76 @@ type Step struct {
 	EndedAtMs   *int64          `json:"ended_at_ms,omitempty"`
 	AgentID     string          `json:"agent_id,omitempty"`
 	AgentRole   string          `json:"agent_role,omitempty"`
+	// Messages is the chat exchange of an llm_call step (schema_version 2).
+	Messages []Message `json:"messages,omitempty"`
+	// Error records why the step failed (schema_version 2).
+	Error *StepError `json:"error,omitempty"`
+	// RetryOf is the index of the earlier step this one retries, and Attempt
+	// its 1-based attempt number (schema_version 2). SDKs may set either;
+	// the engine links every retry to its group's first attempt.
+	RetryOf *int `json:"retry_of,omitempty"`
+	Attempt int  `json:"attempt,omitempty"`
+}
+
+// Message roles on llm_call steps.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is one chat message of an llm_call step.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Name identifies the tool for role "tool" messages.
+	Name string `json:"name,omitempty"`
+	// Actions are structured UI actions a simulated user took alongside
+	// Content (transcript user messages only).
+	Actions []UserAction `json:"actions,omitempty"`
+}
+
+// User action types.
+const (
+	ActionAttachFile   = "attach_file"
+	ActionFillForm     = "fill_form"
+	ActionSelectOption = "select_option"
+)
+
+// UserAction is a structured action by a simulated user: attaching a file,
+// filling form fields, or selecting an option.
+type UserAction struct {
+	Type string `json:"type"`
+	// File is set for attach_file.
+	File *ActionFile `json:"file,omitempty"`
+	// Form and Fields are set for fill_form.
+	Form   string            `json:"form,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+	// Option is set for select_option.
+	Option string `json:"option,omitempty"`
+}
+
+// ActionFile is a file attached by a simulated user. Content is the file's
+// text, or a description of it for binary files.
+type ActionFile struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// ToolSchema declares a tool the agent can call. Parameters is a JSON Schema
+// for the tool's arguments.
+type ToolSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// StepError describes a step failure.
+type StepError struct {
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message"`
 }
 
 // TraceMetadata holds optional metadata about a trace execution.
@@ -42,6 +117,12 @@ type TraceMetadata struct {
 	LatencyMS   *int     `json:"latency_ms,omitempty"`
 	Model       *string  `json:"model,omitempty"`
 	Timestamp   *string  `json:"timestamp,omitempty"`
+	// Execution declares how agent_call children ran: "sequential" or "parallel".
+	Execution *string `json:"execution,omitempty"`
+	// Cost is in Currency (USD when unset). The engine fills CostUSD from it
+	// when CostUSD is not sent.
+	Cost     *float64 `json:"cost,omitempty"`
+	Currency *string  `json:"currency,omitempty"`
 
 	// Aggregate fields for multi-agent trace trees.
 	AggregateTokens    *int     `json:"aggregate_tokens,omitempty"`
@@ -214,3 +214,119 @@ func TestNewRPCError(t *testing.T) {
 		t.Errorf("Data.Detail: got %q, want %q", err.Data.Detail, "upstream timeout")
 	}
 }
+
+func TestParseStepMetadata_Normalization(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantIn  int
+		wantOut int
+	}{
+		{"canonical", `{"tokens_in":10,"tokens_out":20}`, 10, 20},
+		{"openai", `{"prompt_tokens":10,"completion_tokens":20}`, 10, 20},
+		{"anthropic usage", `{"usage":{"input_tokens":10,"output_tokens":20}}`, 10, 20},
+		{"camelCase", `{"inputTokens":10,"outputTokens":20}`, 10, 20},
+		{"top-level wins over usage", `{"tokens_in":10,"tokens_out":20,"usage":{"input_tokens":99}}`, 10, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := types.ParseStepMetadata(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if meta.TokensIn == nil || *meta.TokensIn != tt.wantIn {
+				t.Errorf("TokensIn: got %v, want %d", meta.TokensIn, tt.wantIn)
+			}
+			if meta.TokensOut == nil || *meta.TokensOut != tt.wantOut {
+				t.Errorf("TokensOut: got %v, want %d", meta.TokensOut, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestParseStepMetadata_ModelCostRetries(t *testing.T) {
+	meta, err := types.ParseStepMetadata(json.RawMessage(`{"model_name":"gpt-4.1","cost":0.002,"retries":2}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.Model == nil || *meta.Model != "gpt-4.1" {
+		t.Errorf("Model: got %v, want gpt-4.1", meta.Model)
+	}
+	if meta.CostUSD == nil || *meta.CostUSD != 0.002 {
+		t.Errorf("CostUSD: got %v, want 0.002", meta.CostUSD)
+	}
+	if v, ok := meta.Numeric("retry_count"); !ok || v != 2 {
+		t.Errorf("Numeric(retry_count): got %v, %v; want 2, true", v, ok)
+	}
+}
+
+func TestParseStepMetadata_Invalid(t *testing.T) {
+	if _, err := types.ParseStepMetadata(json.RawMessage(`[1,2]`)); err == nil {
+		t.Error("expected error for non-object metadata")
+	}
+	if _, err := types.ParseStepMetadata(json.RawMessage(`{"tokens_in":1.5}`)); err == nil {
+		t.Error("expected error for fractional token count")
+	}
+	meta, err := types.ParseStepMetadata(nil)
+	if err != nil || meta.TokensIn != nil {
+		t.Errorf("expected empty metadata for nil input, got %+v, %v", meta, err)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"key order and whitespace", `{ "b": 1, "a": {"d": [1, 2], "c": true} }`, `{"a":{"c":true,"d":[1,2]},"b":1}`},
+		{"numbers", `[1.0, 1e0, 0.50, -0, -0.0, 1e21, 0.0000001, 12345678901234567890]`, `[1,1,0.5,0,0,1e+21,1e-07,12345678901234567890]`},
+		{"string escapes", `{"s": "café <b> & \"q\""}`, `{"s":"café <b> & \"q\""}`},
+		{"scalar", ` "x" `, `"x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := types.CanonicalJSON([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("CanonicalJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalJSON = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := types.CanonicalJSON([]byte(`{"target":"output","threshold":0.8,"criteria":"polite"}`), "target", "threshold"); string(got) != `{"criteria":"polite"}` {
+		t.Errorf("CanonicalJSON with omit = %s", got)
+	}
+	for _, bad := range []string{``, `{"a":}`, `{} {}`} {
+		if _, err := types.CanonicalJSON([]byte(bad)); err == nil {
+			t.Errorf("CanonicalJSON(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestCanonicalHash_IgnoresSerialization(t *testing.T) {
+	python, _ := types.CanonicalHash([]byte(`{"check": "contains", "value": "refund", "threshold": 1.0}`))
+	typescript, _ := types.CanonicalHash([]byte(`{"threshold":1,"value":"refund","check":"contains"}`))
+	if python != typescript || len(python) != 64 {
+		t.Errorf("hashes differ: %s vs %s", python, typescript)
+	}
+}
+
+func TestAssertion_Fingerprint(t *testing.T) {
+	a := types.Assertion{AssertionID: "a", Type: "content", Spec: json.RawMessage(`{"check":"contains","value":"x"}`), Tags: []string{"smoke"}}
+	b := types.Assertion{AssertionID: "b", Type: "content", Spec: json.RawMessage(`{ "value": "x", "check": "contains" }`)}
+	c := types.Assertion{AssertionID: "c", Type: "content", Spec: json.RawMessage(`{"check":"contains","value":"y"}`)}
+	fa, _ := a.Fingerprint()
+	fb, _ := b.Fingerprint()
+	fc, _ := c.Fingerprint()
+	if fa != fb {
+		t.Error("fingerprints differ for the same check")
+	}
+	if fa == fc {
+		t.Error("fingerprints match for different values")
+	}
+	bad := types.Assertion{AssertionID: "bad", Type: "content", Spec: json.RawMessage(`{`)}
+	if _, err := bad.Fingerprint(); err == nil {
+		t.Error("Fingerprint of an invalid spec succeeded")
+	}
+}
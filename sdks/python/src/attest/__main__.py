@@ -8,12 +8,35 @@ import sys
 from pathlib import Path
 
 
+def _default_cache_dir() -> Path:
+    """Return the engine's per-OS cache directory."""
+    home = Path.home()
+    if sys.platform == "win32":
+        base = os.environ.get("LOCALAPPDATA", "")
+        root = Path(base) if os.path.isabs(base) else home / "AppData" / "Local"
+    elif sys.platform == "darwin":
+        root = home / "Library" / "Caches"
+    else:
+        base = os.environ.get("XDG_CACHE_HOME", "")
+        root = Path(base) if os.path.isabs(base) else home / ".cache"
+    return root / "attest" / "cache"
+
+
 def _cache_dir() -> Path:
-    """Return the cache directory, respecting ATTEST_CACHE_DIR env override."""
+    """Return the cache directory the engine uses.
+
+    ATTEST_CACHE_DIR overrides it. Until the engine migrates it, an existing
+    ~/.attest/cache from earlier releases is used instead of the per-OS
+    default.
+    """
     env_override = os.environ.get("ATTEST_CACHE_DIR")
     if env_override:
         return Path(env_override)
-    return Path.home() / ".attest" / "cache"
+    default = _default_cache_dir()
+    legacy = Path.home() / ".attest" / "cache"
+    if not default.exists() and legacy.exists():
+        return legacy
+    return default
 
 
 def _cache_db_path() -> Path:
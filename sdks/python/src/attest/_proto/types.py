@@ -19,6 +19,8 @@ STEP_AGENT_CALL: str = "agent_call"
 STATUS_PASS: str = "pass"
 STATUS_SOFT_FAIL: str = "soft_fail"
 STATUS_HARD_FAIL: str = "hard_fail"
+# Sampled out by the engine; neither passes nor fails.
+STATUS_SKIPPED: str = "skipped"
 
 # ---------------------------------------------------------------------------
 # Assertion type constants
@@ -43,6 +45,7 @@ ERR_PROVIDER_ERROR: int = 2001
 ERR_ENGINE_ERROR: int = 3001
 ERR_TIMEOUT: int = 3002
 ERR_SESSION_ERROR: int = 3003
+ERR_PERMISSION_DENIED: int = 3004
 
 
 # ---------------------------------------------------------------------------
@@ -255,15 +258,22 @@ class InitializeParams:
     protocol_version: int
     required_capabilities: list[str]
     preferred_encoding: str = "json"
+    namespace: str = ""
+    auth_token: str = ""
 
     def to_dict(self) -> dict[str, Any]:
-        return {
+        d: dict[str, Any] = {
             "sdk_name": self.sdk_name,
             "sdk_version": self.sdk_version,
             "protocol_version": self.protocol_version,
             "required_capabilities": list(self.required_capabilities),
             "preferred_encoding": self.preferred_encoding,
         }
+        if self.namespace:
+            d["namespace"] = self.namespace
+        if self.auth_token:
+            d["auth_token"] = self.auth_token
+        return d
 
 
 @dataclass
@@ -277,6 +287,8 @@ class InitializeResult:
     max_concurrent_requests: int = 64
     max_trace_size_bytes: int = 10485760
     max_steps_per_trace: int = 10000
+    namespace: str = ""
+    role: str = ""
 
     def to_dict(self) -> dict[str, Any]:
         return {
@@ -289,6 +301,8 @@ class InitializeResult:
             "max_concurrent_requests": self.max_concurrent_requests,
             "max_trace_size_bytes": self.max_trace_size_bytes,
             "max_steps_per_trace": self.max_steps_per_trace,
+            "namespace": self.namespace,
+            "role": self.role,
         }
 
     @classmethod
@@ -303,6 +317,8 @@ class InitializeResult:
             max_concurrent_requests=data.get("max_concurrent_requests", 64),
             max_trace_size_bytes=data.get("max_trace_size_bytes", 10485760),
             max_steps_per_trace=data.get("max_steps_per_trace", 10000),
+            namespace=data.get("namespace", ""),
+            role=data.get("role", ""),
         )
 
 
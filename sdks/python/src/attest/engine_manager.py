@@ -113,9 +113,13 @@ class EngineManager:
         self,
         engine_path: str | None = None,
         log_level: str = "warn",
+        namespace: str = "",
+        auth_token: str = "",
     ) -> None:
         self._engine_path = engine_path or _find_engine_binary()
         self._log_level = log_level
+        self._namespace = namespace
+        self._auth_token = auth_token
         self._process: asyncio.subprocess.Process | None = None
         self._initialized = False
         self._request_id = 0
@@ -137,6 +141,8 @@ class EngineManager:
             sdk_version=__version__,
             protocol_version=1,
             required_capabilities=["layers_1_4"],
+            namespace=self._namespace,
+            auth_token=self._auth_token,
         ).to_dict())
 
         self._init_result = InitializeResult.from_dict(result)
@@ -433,14 +433,21 @@ class ExpectChain:
         )
 
     def follows_transitions(
-        self, transitions: list[tuple[str, str]], *, soft: bool = False
+        self,
+        transitions: list[tuple[str, str] | dict[str, Any]],
+        *,
+        soft: bool = False,
     ) -> ExpectChain:
-        """Assert agent delegations follow the specified transitions."""
+        """Assert agent delegations follow the specified transitions.
+
+        Each transition is a (parent, child) pair or a {"from", "to", "max_count"}
+        dict. Either side may be "*", "re:<regex>", "role:<role>", or an agent ID.
+        """
         return self._add(
             TYPE_TRACE_TREE,
             {
                 "check": "follows_transitions",
-                "transitions": [list(pair) for pair in transitions],
+                "transitions": [t if isinstance(t, dict) else list(t) for t in transitions],
                 "soft": soft,
             },
         )
@@ -490,13 +497,28 @@ class ExpectChain:
         )
 
     def ordered_agents(
-        self, groups: list[list[str]], *, soft: bool = False
+        self,
+        groups: list[list[str]],
+        *,
+        allow_missing: bool = False,
+        strict: bool = True,
+        min_durations_ms: list[int | float | str] | None = None,
+        soft: bool = False,
     ) -> ExpectChain:
-        """Assert agents ran in ordered groups (parallel within, sequential across)."""
-        return self._add(
-            TYPE_TRACE_TREE,
-            {"check": "ordered_agents", "groups": groups, "soft": soft},
-        )
+        """Assert agents ran in ordered groups (parallel within, sequential across).
+
+        allow_missing skips agents without steps instead of failing; strict=False
+        accepts a group ending at the same millisecond the next one starts;
+        min_durations_ms gives each group, by index, a minimum span.
+        """
+        spec: dict[str, Any] = {"check": "ordered_agents", "groups": groups, "soft": soft}
+        if allow_missing:
+            spec["allow_missing"] = True
+        if not strict:
+            spec["strict"] = False
+        if min_durations_ms is not None:
+            spec["min_durations_ms"] = min_durations_ms
+        return self._add(TYPE_TRACE_TREE, spec)
 
     # ── Layer 7+: TraceTree Analytics ──
 
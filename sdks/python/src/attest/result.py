@@ -8,6 +8,7 @@ from typing import TYPE_CHECKING
 from attest._proto.types import (
     STATUS_HARD_FAIL,
     STATUS_PASS,
+    STATUS_SKIPPED,
     STATUS_SOFT_FAIL,
     AssertionResult,
     Trace,
@@ -28,13 +29,13 @@ class AgentResult:
 
     @property
     def passed(self) -> bool:
-        """True if all assertions passed."""
-        return all(r.status == STATUS_PASS for r in self.assertion_results)
+        """True if every assertion that was not skipped passed."""
+        return all(r.status in (STATUS_PASS, STATUS_SKIPPED) for r in self.assertion_results)
 
     @property
     def failed_assertions(self) -> list[AssertionResult]:
         """Return list of failed assertions (hard_fail or soft_fail)."""
-        return [r for r in self.assertion_results if r.status != STATUS_PASS]
+        return [r for r in self.assertion_results if r.status not in (STATUS_PASS, STATUS_SKIPPED)]
 
     @property
     def hard_failures(self) -> list[AssertionResult]:
@@ -46,6 +47,11 @@ class AgentResult:
         """Return list of soft failures only."""
         return [r for r in self.assertion_results if r.status == STATUS_SOFT_FAIL]
 
+    @property
+    def skipped(self) -> list[AssertionResult]:
+        """Return list of assertions the engine sampled out."""
+        return [r for r in self.assertion_results if r.status == STATUS_SKIPPED]
+
     @property
     def pass_count(self) -> int:
         """Number of passing assertions."""
@@ -54,7 +60,7 @@ class AgentResult:
     @property
     def fail_count(self) -> int:
         """Number of failing assertions."""
-        return len(self.assertion_results) - self.pass_count
+        return len(self.failed_assertions)
 
     def trace_tree(self) -> TraceTree:
         """Build a TraceTree from this result's trace."""
@@ -66,16 +66,28 @@ class TestCacheStats:
         assert "path" in data
         assert "attest.db" in data["path"]
 
-    def test_cache_stats_default_dir(self) -> None:
-        """Stats without ATTEST_CACHE_DIR uses ~/.attest/cache/attest.db."""
+    def test_cache_stats_default_dir(self, tmp_path: Path) -> None:
+        """Stats without ATTEST_CACHE_DIR uses the engine's per-OS cache dir."""
         env = {k: v for k, v in os.environ.items() if k != "ATTEST_CACHE_DIR"}
-        with patch.dict(os.environ, env, clear=True):
+        env["XDG_CACHE_HOME"] = str(tmp_path)
+        with patch.dict(os.environ, env, clear=True), patch.object(Path, "home", return_value=tmp_path):
             output, code = _run_main("cache", "stats")
 
         assert code == 0
         data = json.loads(output)
-        assert ".attest" in data["path"]
-        assert "attest.db" in data["path"]
+        assert data["path"].endswith(os.path.join("attest", "cache", "attest.db"))
+        assert ".attest" not in data["path"]
+
+    def test_cache_stats_legacy_dir(self, tmp_path: Path) -> None:
+        """An unmigrated ~/.attest/cache is used until the engine moves it."""
+        (tmp_path / ".attest" / "cache").mkdir(parents=True)
+        env = {k: v for k, v in os.environ.items() if k != "ATTEST_CACHE_DIR"}
+        env["XDG_CACHE_HOME"] = str(tmp_path / "xdg")
+        with patch.dict(os.environ, env, clear=True), patch.object(Path, "home", return_value=tmp_path):
+            output, code = _run_main("cache", "stats")
+
+        assert code == 0
+        assert json.loads(output)["path"] == str(tmp_path / ".attest" / "cache" / "attest.db")
 
 
 class TestCacheClear:
@@ -4,6 +4,7 @@ from attest._proto.types import (
     ERR_ASSERTION_ERROR,
     ERR_ENGINE_ERROR,
     ERR_INVALID_TRACE,
+    ERR_PERMISSION_DENIED,
     ERR_PROVIDER_ERROR,
     ERR_SESSION_ERROR,
     ERR_TIMEOUT,
@@ -144,6 +145,23 @@ def test_initialize_params_to_dict() -> None:
     assert d["protocol_version"] == 1
     assert d["required_capabilities"] == ["layers_1_4", "soft_failures"]
     assert d["preferred_encoding"] == "json"
+    assert "namespace" not in d
+    assert "auth_token" not in d
+
+
+def test_initialize_params_namespace_and_auth_token() -> None:
+    params = InitializeParams(
+        sdk_name="attest-python",
+        sdk_version="0.1.0",
+        protocol_version=1,
+        required_capabilities=["layers_1_4"],
+        namespace="team-a",
+        auth_token="ci-token",
+    )
+    d = params.to_dict()
+
+    assert d["namespace"] == "team-a"
+    assert d["auth_token"] == "ci-token"
 
 
 def test_evaluate_batch_result_from_dict() -> None:
@@ -210,3 +228,4 @@ def test_constants() -> None:
     assert ERR_ENGINE_ERROR == 3001
     assert ERR_TIMEOUT == 3002
     assert ERR_SESSION_ERROR == 3003
+    assert ERR_PERMISSION_DENIED == 3004
@@ -8,6 +8,7 @@ from attest._proto.types import (
     Trace,
     STATUS_PASS,
     STATUS_HARD_FAIL,
+    STATUS_SKIPPED,
     STATUS_SOFT_FAIL,
 )
 
@@ -73,3 +74,23 @@ def test_agent_result_all_fail() -> None:
     assert ar.fail_count == 2
     assert len(ar.hard_failures) == 2
     assert ar.soft_failures == []
+
+
+def test_agent_result_skipped_does_not_gate() -> None:
+    trace = Trace(trace_id="trc_6", output={"message": "ok"})
+    results = [
+        AssertionResult(assertion_id="a1", status=STATUS_PASS, score=1.0, explanation="ok"),
+        AssertionResult(assertion_id="a2", status=STATUS_SKIPPED, score=0.0, explanation="sampled out"),
+    ]
+    ar = AgentResult(trace=trace, assertion_results=results)
+    assert ar.passed is True
+    assert ar.failed_assertions == []
+    assert ar.fail_count == 0
+    assert ar.pass_count == 1
+    assert [r.assertion_id for r in ar.skipped] == ["a2"]
+
+    results.append(AssertionResult(assertion_id="a3", status=STATUS_HARD_FAIL, score=0.0, explanation="fail"))
+    ar = AgentResult(trace=trace, assertion_results=results)
+    assert ar.passed is False
+    assert [r.assertion_id for r in ar.failed_assertions] == ["a3"]
+    assert ar.fail_count == 1
@@ -10,10 +10,33 @@ import * as path from "node:path";
 import * as os from "node:os";
 import { VERSION } from "./version.js";
 
+function defaultCacheDir(): string {
+  const home = os.homedir();
+  let root: string;
+  if (process.platform === "win32") {
+    const base = process.env["LOCALAPPDATA"] ?? "";
+    root = path.isAbsolute(base) ? base : path.join(home, "AppData", "Local");
+  } else if (process.platform === "darwin") {
+    root = path.join(home, "Library", "Caches");
+  } else {
+    const base = process.env["XDG_CACHE_HOME"] ?? "";
+    root = path.isAbsolute(base) ? base : path.join(home, ".cache");
+  }
+  return path.join(root, "attest", "cache");
+}
+
+/**
+ * The cache directory the engine uses: ATTEST_CACHE_DIR, else the per-OS
+ * default, unless that does not exist yet and an unmigrated ~/.attest/cache
+ * from earlier releases does.
+ */
 function cacheDir(): string {
   const envOverride = process.env["ATTEST_CACHE_DIR"];
   if (envOverride) return envOverride;
-  return path.join(os.homedir(), ".attest", "cache");
+  const dir = defaultCacheDir();
+  const legacy = path.join(os.homedir(), ".attest", "cache");
+  if (!fs.existsSync(dir) && fs.existsSync(legacy)) return legacy;
+  return dir;
 }
 
 function cacheDbPath(): string {
@@ -69,15 +69,19 @@ async function findEngineBinary(): Promise<string> {
 export class EngineManager {
   private enginePath: string;
   private readonly logLevel: string;
+  private readonly namespace: string;
+  private readonly authToken: string;
   private process: ChildProcess | null = null;
   private reader: ReadlineInterface | null = null;
   private initialized = false;
   private requestId = 0;
   private initResult: InitializeResult | null = null;
 
-  constructor(options?: { enginePath?: string; logLevel?: string }) {
+  constructor(options?: { enginePath?: string; logLevel?: string; namespace?: string; authToken?: string }) {
     this.enginePath = options?.enginePath ?? "";
     this.logLevel = options?.logLevel ?? "warn";
+    this.namespace = options?.namespace ?? "";
+    this.authToken = options?.authToken ?? "";
   }
 
   async start(): Promise<InitializeResult> {
@@ -97,6 +101,8 @@ export class EngineManager {
       protocol_version: 1,
       required_capabilities: ["layers_1_4"],
       preferred_encoding: "json",
+      ...(this.namespace ? { namespace: this.namespace } : {}),
+      ...(this.authToken ? { auth_token: this.authToken } : {}),
     });
 
     this.initResult = result as InitializeResult;
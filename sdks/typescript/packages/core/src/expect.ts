@@ -722,7 +722,8 @@ export class ExpectChain {
   /**
    * Assert agent delegations follow the specified transitions.
    *
-   * @param transitions - Array of `[fromAgent, toAgent]` pairs.
+   * @param transitions - Array of `[fromAgent, toAgent]` pairs or `{ from, to, max_count }` rules.
+   *   Either side may be `"*"`, `"re:<regex>"`, `"role:<role>"`, or an agent ID.
    * @param opts.soft - When `true` the failure is non-blocking.
    * @returns The chain for fluent composition.
    *
@@ -731,7 +732,10 @@ export class ExpectChain {
    * expect(result).followsTransitions([["root", "planner"], ["planner", "executor"]]);
    * ```
    */
-  followsTransitions(transitions: [string, string][], opts?: { soft?: boolean }): this {
+  followsTransitions(
+    transitions: ([string, string] | { from: string; to: string; max_count?: number })[],
+    opts?: { soft?: boolean },
+  ): this {
     return this.add(TYPE_TRACE_TREE, {
       check: "follows_transitions",
       transitions,
@@ -851,6 +855,9 @@ export class ExpectChain {
    * Assert agents ran in ordered groups (parallel within each group, sequential across groups).
    *
    * @param groups - Array of agent-ID arrays. Each sub-array is a parallel group.
+   * @param opts.allowMissing - Skip agents without steps instead of failing.
+   * @param opts.strict - When `false`, a group may end at the same millisecond the next starts.
+   * @param opts.minDurationsMs - Minimum span of each group, by index.
    * @param opts.soft - When `true` the failure is non-blocking.
    * @returns The chain for fluent composition.
    *
@@ -859,12 +866,24 @@ export class ExpectChain {
    * expect(result).orderedAgents([["planner"], ["worker-1", "worker-2"], ["aggregator"]]);
    * ```
    */
-  orderedAgents(groups: string[][], opts?: { soft?: boolean }): this {
-    return this.add(TYPE_TRACE_TREE, {
+  orderedAgents(
+    groups: string[][],
+    opts?: {
+      allowMissing?: boolean;
+      strict?: boolean;
+      minDurationsMs?: (number | string)[];
+      soft?: boolean;
+    },
+  ): this {
+    const spec: Record<string, unknown> = {
       check: "ordered_agents",
       groups,
       soft: opts?.soft ?? false,
-    });
+    };
+    if (opts?.allowMissing) spec.allow_missing = true;
+    if (opts?.strict === false) spec.strict = false;
+    if (opts?.minDurationsMs !== undefined) spec.min_durations_ms = opts.minDurationsMs;
+    return this.add(TYPE_TRACE_TREE, spec);
   }
 
   // -- Layer 8: Plugin --
@@ -8,6 +8,8 @@ export const STEP_AGENT_CALL = "agent_call" as const;
 export const STATUS_PASS = "pass" as const;
 export const STATUS_SOFT_FAIL = "soft_fail" as const;
 export const STATUS_HARD_FAIL = "hard_fail" as const;
+/** Sampled out by the engine; neither passes nor fails. */
+export const STATUS_SKIPPED = "skipped" as const;
 
 // Assertion type constants
 export const TYPE_SCHEMA = "schema" as const;
@@ -26,3 +28,4 @@ export const ERR_PROVIDER_ERROR = 2001 as const;
 export const ERR_ENGINE_ERROR = 3001 as const;
 export const ERR_TIMEOUT = 3002 as const;
 export const ERR_SESSION_ERROR = 3003 as const;
+export const ERR_PERMISSION_DENIED = 3004 as const;
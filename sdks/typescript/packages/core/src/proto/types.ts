@@ -187,6 +187,8 @@ export interface InitializeParams {
   readonly protocol_version: number;
   readonly required_capabilities: readonly string[];
   readonly preferred_encoding?: string;
+  readonly namespace?: string;
+  readonly auth_token?: string;
 }
 
 export interface InitializeResult {
@@ -199,6 +201,8 @@ export interface InitializeResult {
   readonly max_concurrent_requests?: number;
   readonly max_trace_size_bytes?: number;
   readonly max_steps_per_trace?: number;
+  readonly namespace?: string;
+  readonly role?: string;
 }
 
 export interface EvaluateBatchParams {
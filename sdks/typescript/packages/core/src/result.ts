@@ -3,6 +3,7 @@ import {
   STATUS_PASS,
   STATUS_SOFT_FAIL,
   STATUS_HARD_FAIL,
+  STATUS_SKIPPED,
 } from "./proto/constants.js";
 
 export class AgentResult {
@@ -24,11 +25,15 @@ export class AgentResult {
   }
 
   get passed(): boolean {
-    return this.assertionResults.every((r) => r.status === STATUS_PASS);
+    return this.assertionResults.every(
+      (r) => r.status === STATUS_PASS || r.status === STATUS_SKIPPED,
+    );
   }
 
   get failedAssertions(): readonly AssertionResult[] {
-    return this.assertionResults.filter((r) => r.status !== STATUS_PASS);
+    return this.assertionResults.filter(
+      (r) => r.status !== STATUS_PASS && r.status !== STATUS_SKIPPED,
+    );
   }
 
   get hardFailures(): readonly AssertionResult[] {
@@ -39,11 +44,15 @@ export class AgentResult {
     return this.assertionResults.filter((r) => r.status === STATUS_SOFT_FAIL);
   }
 
+  get skipped(): readonly AssertionResult[] {
+    return this.assertionResults.filter((r) => r.status === STATUS_SKIPPED);
+  }
+
   get passCount(): number {
     return this.assertionResults.filter((r) => r.status === STATUS_PASS).length;
   }
 
   get failCount(): number {
-    return this.assertionResults.length - this.passCount;
+    return this.failedAssertions.length;
   }
 }
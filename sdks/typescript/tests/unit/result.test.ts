@@ -0,0 +1,35 @@
+import { describe, it, expect } from "vitest";
+import { AgentResult } from "../../packages/core/src/result.js";
+import {
+  STATUS_HARD_FAIL,
+  STATUS_PASS,
+  STATUS_SKIPPED,
+} from "../../packages/core/src/proto/constants.js";
+import type { AssertionResult, Trace } from "../../packages/core/src/proto/types.js";
+
+const trace: Trace = { trace_id: "trc_1", output: { message: "ok" }, steps: [] };
+
+function result(id: string, status: string): AssertionResult {
+  return { assertion_id: id, status, score: 0, explanation: "" };
+}
+
+describe("AgentResult", () => {
+  it("does not gate on skipped assertions", () => {
+    const ar = new AgentResult(trace, [result("a1", STATUS_PASS), result("a2", STATUS_SKIPPED)]);
+    expect(ar.passed).toBe(true);
+    expect(ar.failedAssertions).toEqual([]);
+    expect(ar.failCount).toBe(0);
+    expect(ar.passCount).toBe(1);
+    expect(ar.skipped.map((r) => r.assertion_id)).toEqual(["a2"]);
+  });
+
+  it("still fails on a failure beside skipped assertions", () => {
+    const ar = new AgentResult(trace, [
+      result("a1", STATUS_SKIPPED),
+      result("a2", STATUS_HARD_FAIL),
+    ]);
+    expect(ar.passed).toBe(false);
+    expect(ar.failedAssertions.map((r) => r.assertion_id)).toEqual(["a2"]);
+    expect(ar.failCount).toBe(1);
+  });
+});